@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"golang.org/x/net/html"
+)
+
+// apiPathPrefix is the prefix of the paths of the read-only JSON API over the archive served by the serve subcommand.
+const apiPathPrefix = "/api/"
+
+type apiPage struct {
+	Number    uint      `json:"number"`
+	URL       string    `json:"url,omitempty"`
+	Link      string    `json:"link"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	// WaybackURL is the URL of the snapshot of the page in the Wayback Machine, if it has been saved there.
+	WaybackURL string `json:"waybackURL,omitempty"`
+}
+
+type apiTopic struct {
+	URL      string     `json:"url,omitempty"`
+	Engine   string     `json:"engine,omitempty"`
+	PostStep uint       `json:"postStep,omitempty"`
+	Pages    []*apiPage `json:"pages"`
+}
+
+type apiPost struct {
+	Page   uint       `json:"page"`
+	ID     string     `json:"id,omitempty"`
+	Anchor string     `json:"anchor,omitempty"`
+	Link   string     `json:"link"`
+	Author string     `json:"author,omitempty"`
+	Time   *time.Time `json:"time,omitempty"`
+	Text   string     `json:"text"`
+	// Signature is the signature of the author appended to the post, unless the signatures are stripped.
+	Signature string `json:"signature,omitempty"`
+	// DuplicateOf is the link to the post which the post duplicates, if the duplicate posts are flagged.
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+}
+
+type apiSearchResult struct {
+	Page    uint   `json:"page"`
+	PostID  string `json:"postID,omitempty"`
+	Anchor  string `json:"anchor,omitempty"`
+	Link    string `json:"link"`
+	Author  string `json:"author,omitempty"`
+	Score   int    `json:"score"`
+	Snippet string `json:"snippet"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func (s *archiveServer) newAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/topic", s.serveAPITopic)
+	mux.HandleFunc("GET /api/pages/{number}/posts", s.serveAPIPagePosts)
+	mux.HandleFunc("GET /api/search", s.serveAPISearch)
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "no such endpoint")
+	})
+	return mux
+}
+
+func writeAPIResponse(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	err := encoder.Encode(payload)
+	if err != nil {
+		logErrorf("could not write API response: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIResponse(w, status, &apiError{Error: message})
+}
+
+// postLink returns the path under which the post with the anchor on the archived page with the given number is served.
+func (s *archiveServer) postLink(pageNumber uint, anchor string) (link string) {
+	link = s.pageLink(pageNumber)
+	if anchor != "" {
+		link += "#" + anchor
+	}
+	return
+}
+
+func (s *archiveServer) serveAPITopic(w http.ResponseWriter, r *http.Request) {
+	pageNumbers, err := archive.PageNumbers(targetDir)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "could not list the archived pages")
+		return
+	}
+
+	snapshotURLs, err := archive.LoadWaybackSnapshots(targetDir)
+	if err != nil {
+		logWarningf("could not read the manifest of Wayback Machine snapshots: %v", err)
+	}
+
+	topic := &apiTopic{
+		Pages: []*apiPage{},
+	}
+	if s.settings != nil {
+		topic.URL = s.settings.URL
+		topic.Engine = forumEngine
+		topic.PostStep = s.settings.PostStep
+	}
+	for _, pageNumber := range fetch.SortPageNumbers(pageNumbers) {
+		page := &apiPage{
+			Number: pageNumber,
+			Link:   s.pageLink(pageNumber),
+		}
+		page.FetchedAt, _ = archive.PageModTime(archive.PageDir(targetDir, pageNumber))
+		if s.settings != nil {
+			page.URL = getFetcher().PageURL(pageNumber)
+			page.WaybackURL = snapshotURLs[page.URL]
+		}
+
+		topic.Pages = append(topic.Pages, page)
+	}
+
+	writeAPIResponse(w, http.StatusOK, topic)
+}
+
+func (s *archiveServer) serveAPIPagePosts(w http.ResponseWriter, r *http.Request) {
+	pageNumber, err := strconv.ParseUint(r.PathValue("number"), 10, 0)
+	if err != nil || pageNumber < uint64(fetch.MinPageNumber) {
+		writeAPIError(w, http.StatusBadRequest, "invalid page number")
+		return
+	}
+	if s.settings == nil {
+		writeAPIError(w, http.StatusNotFound, "the archive has no topic settings")
+		return
+	}
+
+	entry, ok := s.urlMap.LookupFilename(getFetcher().PageURL(uint(pageNumber)))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "page not archived")
+		return
+	}
+
+	file, err := os.Open(filepath.Join(targetDir, filepath.FromSlash(entry.Filename)))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "page not archived")
+		return
+	}
+	defer file.Close()
+
+	doc, err := html.Parse(file)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "could not parse the archived page")
+		return
+	}
+
+	// the duplicates are only looked for among the posts on the same page
+	extractedPosts, originals := newPostDeduplicator().filter(getForumEngine().ExtractPosts(doc))
+	posts := []*apiPost{}
+	for _, post := range extractedPosts {
+		apiPost := &apiPost{
+			Page:   uint(pageNumber),
+			ID:     post.ID,
+			Anchor: post.Anchor,
+			Link:   s.postLink(uint(pageNumber), post.Anchor),
+			Author: post.Author,
+			Text:   post.Text,
+		}
+		if !shouldStripSignatures {
+			apiPost.Signature = post.Signature
+		}
+		if shouldReplaceSmileysWithEmoji {
+			apiPost.Text = engine.ReplaceSmileyCodes(apiPost.Text)
+			apiPost.Signature = engine.ReplaceSmileyCodes(apiPost.Signature)
+		}
+		if !post.Time.IsZero() {
+			apiPost.Time = &post.Time
+		}
+		if original, ok := originals[post]; ok {
+			apiPost.DuplicateOf = s.postLink(uint(pageNumber), original.Anchor)
+		}
+
+		posts = append(posts, apiPost)
+	}
+
+	writeAPIResponse(w, http.StatusOK, posts)
+}
+
+func (s *archiveServer) serveAPISearch(w http.ResponseWriter, r *http.Request) {
+	if s.searchIndex == nil {
+		writeAPIError(w, http.StatusNotFound, "the archive has no search index")
+		return
+	}
+
+	query := r.URL.Query().Get(searchQueryParameter)
+	if query == "" {
+		writeAPIError(w, http.StatusBadRequest, "no search query given in parameter "+searchQueryParameter)
+		return
+	}
+
+	results := []*apiSearchResult{}
+	for _, result := range s.searchIndex.Search(query) {
+		results = append(results, &apiSearchResult{
+			Page:    result.Page,
+			PostID:  result.PostID,
+			Anchor:  result.Anchor,
+			Link:    s.postLink(result.Page, result.Anchor),
+			Author:  result.Author,
+			Score:   result.Score,
+			Snippet: result.Snippet,
+		})
+	}
+
+	writeAPIResponse(w, http.StatusOK, results)
+}
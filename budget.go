@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// byteCount is a flag type for a number of bytes, which may be suffixed with a binary unit (e.g. 500M or 2GiB).
+type byteCount int64
+
+func (count *byteCount) String() string {
+	return formatByteCount(int64(*count))
+}
+
+func (count *byteCount) Set(value string) error {
+	parsedCount, err := parseByteCount(value)
+	if err != nil {
+		return err
+	}
+
+	*count = byteCount(parsedCount)
+	return nil
+}
+
+func parseByteCount(value string) (count int64, err error) {
+	numberStr := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(value), "B"), "i")
+
+	multiplier := int64(1)
+	if numberStr != "" {
+		if exponent := strings.IndexByte("KMGTPE", strings.ToUpper(numberStr[len(numberStr)-1:])[0]); exponent >= 0 {
+			numberStr = numberStr[:len(numberStr)-1]
+			for ; exponent >= 0; exponent-- {
+				multiplier *= 1024
+			}
+		}
+	}
+
+	number, err := strconv.ParseFloat(numberStr, 64)
+	if err != nil || number < 0 {
+		return 0, errorf("invalid size %q: expected a non-negative number optionally followed by K, M, G, T, P or E", value)
+	}
+
+	count = int64(number * float64(multiplier))
+	return
+}
+
+// maxTotalSize and maxResourceCount limit the number of bytes downloaded and the number of resources fetched during
+// a run; 0 means unlimited. Once either is exhausted, no more pages are started, while the one in flight is finished.
+var maxTotalSize byteCount
+var maxResourceCount uint
+
+// maxResourceSize is the size of the largest resource which is fetched; 0 means unlimited.
+var maxResourceSize byteCount
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+)
+
+// postChangeKinds are the kinds of the changes of the tracked posts.
+var postChangeKinds = []string{archive.PostEdited, archive.PostDeleted, archive.PostRestored}
+
+// postChangeKindFilter and shouldShowPostTexts are set by the flags of the changes command.
+var postChangeKindFilter string
+var shouldShowPostTexts bool
+
+// readPostChanges returns the change log of the posts tracked with -track-posts in the target directory.
+func readPostChanges() (changes []*archive.PostChange, err error) {
+	state, err := archive.OpenState(targetDir)
+	if err != nil {
+		return nil, errorf("could not open the state database (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+	defer state.Close()
+
+	changes, err = state.PostChanges()
+	if err != nil {
+		return nil, errorf("could not read the change log of the posts: %v", err)
+	}
+	return
+}
+
+// printPostChange prints the change, followed by the versions of the text of the post, prefixed like in a diff, if
+// -text has been given.
+func printPostChange(change *archive.PostChange) {
+	fmt.Println(translatef("%s: page %d: post %s by %s: %s", change.Time.Local().Format(time.DateTime), change.Page, change.Post, change.Author, change.Kind))
+	if !shouldShowPostTexts {
+		return
+	}
+
+	if change.OldText != "" {
+		fmt.Printf("- %s\n", change.OldText)
+	}
+	if change.NewText != "" {
+		fmt.Printf("+ %s\n", change.NewText)
+	}
+}
+
+var changesCommand = &command{
+	name:      "changes",
+	synopsis:  "list the edits and deletions of the posts noticed across the runs",
+	arguments: "",
+	description: "List the change log of the posts in the target directory which have been tracked with -track-posts: the posts whose text " +
+		"differed from the one seen during an earlier run (edited), which vanished from the page on which they were last seen (deleted) " +
+		"and which reappeared after they had been deemed deleted (restored), in the order in which the changes were noticed",
+	setFlags: func(flags *flag.FlagSet) {
+		postChangeKindFilter = ""
+		flags.StringVar(&postChangeKindFilter, "kind", postChangeKindFilter, translatef("only list the changes of the comma-separated `kinds` (any of: %s)", strings.Join(postChangeKinds, ", ")))
+
+		shouldShowPostTexts = false
+		flags.BoolVar(&shouldShowPostTexts, "text", shouldShowPostTexts, "also print the text of the posts before (prefixed with -) and after (prefixed with +) every change")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		var kinds []string
+		for _, kind := range strings.Split(postChangeKindFilter, ",") {
+			kind = strings.TrimSpace(kind)
+			if kind == "" {
+				continue
+			}
+			if !slices.Contains(postChangeKinds, kind) {
+				return usageErrorf("unknown kind of change %q (it has to be one of: %s)", kind, strings.Join(postChangeKinds, ", "))
+			}
+
+			kinds = append(kinds, kind)
+		}
+
+		_, err := loadTopicSettings()
+		if err != nil {
+			return err
+		}
+
+		changes, err := readPostChanges()
+		if err != nil {
+			return err
+		}
+
+		changeCount := 0
+		for _, change := range changes {
+			if len(kinds) > 0 && !slices.Contains(kinds, change.Kind) {
+				continue
+			}
+			printPostChange(change)
+			changeCount++
+		}
+		if changeCount == 0 {
+			logSuccessf("No changes of the posts have been recorded.")
+		}
+		return nil
+	},
+}
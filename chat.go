@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+)
+
+// slackWebhookURL and discordWebhookURL are the incoming webhooks of the Slack and Discord channels to which the
+// summary of the run and its critical failures are posted. Since they grant posting to the channels, they may also be
+// given as secret references of the form `env:NAME` or `keyring:NAME`.
+var slackWebhookURL string
+var discordWebhookURL string
+
+// The colors of the attachments and embeds in which the messages are posted, which tell at a glance how the run went.
+const (
+	chatColorSuccess = 0x2eb67d
+	chatColorWarning = 0xecb22e
+	chatColorFailure = 0xe01e5a
+)
+
+// chatMessage is a message posted to the chat channels, which is formatted for each of them by its webhook formatter.
+type chatMessage struct {
+	title  string
+	text   string
+	color  int
+	fields [][2]string
+}
+
+type slackAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color    string                  `json:"color"`
+	Title    string                  `json:"title"`
+	Text     string                  `json:"text,omitempty"`
+	Fields   []*slackAttachmentField `json:"fields,omitempty"`
+	Fallback string                  `json:"fallback"`
+}
+
+type slackMessage struct {
+	Text        string             `json:"text"`
+	Attachments []*slackAttachment `json:"attachments"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string               `json:"title"`
+	Description string               `json:"description,omitempty"`
+	Color       int                  `json:"color"`
+	Fields      []*discordEmbedField `json:"fields,omitempty"`
+	Timestamp   string               `json:"timestamp"`
+}
+
+type discordMessage struct {
+	Username string          `json:"username"`
+	Embeds   []*discordEmbed `json:"embeds"`
+}
+
+// formatSlackMessage formats the message as the payload of a Slack incoming webhook.
+func formatSlackMessage(message *chatMessage) *slackMessage {
+	attachment := &slackAttachment{
+		Color:    fmt.Sprintf("#%06x", message.color),
+		Title:    message.title,
+		Text:     message.text,
+		Fallback: message.title,
+	}
+	for _, field := range message.fields {
+		attachment.Fields = append(attachment.Fields, &slackAttachmentField{Title: field[0], Value: field[1], Short: true})
+	}
+	return &slackMessage{Text: message.title, Attachments: []*slackAttachment{attachment}}
+}
+
+// formatDiscordMessage formats the message as the payload of a Discord webhook.
+func formatDiscordMessage(message *chatMessage) *discordMessage {
+	embed := &discordEmbed{
+		Title:       message.title,
+		Description: message.text,
+		Color:       message.color,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, field := range message.fields {
+		embed.Fields = append(embed.Fields, &discordEmbedField{Name: field[0], Value: field[1], Inline: true})
+	}
+	return &discordMessage{Username: "fetch-forum-topic", Embeds: []*discordEmbed{embed}}
+}
+
+// resolveWebhookURL returns the URL of the webhook, which is either given as it is or as a secret reference.
+func resolveWebhookURL(urlOrReference string) (string, error) {
+	if strings.Contains(urlOrReference, "://") {
+		return urlOrReference, nil
+	}
+	return fetch.ResolveSecret(urlOrReference)
+}
+
+// postChatMessage posts the message to the Slack and Discord channels whose webhooks have been given.
+func postChatMessage(message *chatMessage) {
+	webhooks := []struct {
+		name           string
+		urlOrReference string
+		format         func(*chatMessage) interface{}
+	}{
+		{"Slack", slackWebhookURL, func(message *chatMessage) interface{} { return formatSlackMessage(message) }},
+		{"Discord", discordWebhookURL, func(message *chatMessage) interface{} { return formatDiscordMessage(message) }},
+	}
+	for _, webhook := range webhooks {
+		if webhook.urlOrReference == "" {
+			continue
+		}
+
+		webhookURL, err := resolveWebhookURL(webhook.urlOrReference)
+		if err == nil {
+			err = postJSON(webhookURL, webhook.format(message))
+		}
+		if err != nil {
+			logErrorf("could not post to the %s webhook: %v", webhook.name, redact.Text(err.Error()))
+		}
+	}
+}
+
+// newRunSummaryChatMessage returns the message with the summary of the run, which ended with the error, if any.
+func newRunSummaryChatMessage(fetcher *fetch.Fetcher, report *fetch.Report, err error) *chatMessage {
+	message := &chatMessage{
+		title: formatEmailReportSubject(report),
+		text:  redact.URLString(fetcher.PageURL(fetch.MinPageNumber)),
+		color: chatColorSuccess,
+		fields: [][2]string{
+			{"Pages", fmt.Sprintf("%d succeeded, %d failed, %d skipped, %d pending", report.PagesSucceeded, report.PagesFailed, report.PagesSkipped, report.PagesPending)},
+			{"Resources", fmt.Sprintf("%d fetched, %d failed", report.ResourcesFetched, report.ResourcesFailed)},
+			{"Downloaded", formatByteCount(report.BytesDownloaded)},
+			{"Duration", time.Duration(report.DurationSeconds * float64(time.Second)).Round(time.Second).String()},
+		},
+	}
+	if report.PagesFailed > 0 {
+		message.color = chatColorWarning
+	}
+	// the pages which failed are fetched again by the next run, but the other errors need attention
+	if exitCode := getExitCode(err); exitCode != exitCodeOK && exitCode != exitCodePartialFailure {
+		message.color = chatColorFailure
+		message.fields = append(message.fields, [2]string{"Error", redact.Text(err.Error())})
+	}
+	return message
+}
+
+// postChatRunSummary posts the summary of the run, which ended with the error, if any, to the chat channels.
+func postChatRunSummary(fetcher *fetch.Fetcher, report *fetch.Report, err error) {
+	if slackWebhookURL == "" && discordWebhookURL == "" {
+		return
+	}
+
+	postChatMessage(newRunSummaryChatMessage(fetcher, report, err))
+}
+
+// criticalFailureChatNotifier posts a message to the chat channels as soon as the run encounters a failure which
+// needs attention before the next run can succeed, i.e. the credentials having been rejected or expired and the disk
+// having filled up. Every kind of failure is only posted once.
+type criticalFailureChatNotifier struct {
+	fetch.NopObserver
+	fetcher *fetch.Fetcher
+
+	hasPostedAuthFailure     atomic.Bool
+	hasPostedDiskFullFailure atomic.Bool
+}
+
+func (n *criticalFailureChatNotifier) postFailure(title, text string) {
+	postChatMessage(&chatMessage{
+		title: title,
+		text:  text,
+		color: chatColorFailure,
+		fields: [][2]string{
+			{"Topic", redact.URLString(n.fetcher.PageURL(fetch.MinPageNumber))},
+			{"Target directory", targetDir},
+		},
+	})
+}
+
+func (n *criticalFailureChatNotifier) check() {
+	if n.fetcher == nil {
+		return
+	}
+
+	if n.fetcher.HasAuthFailure() && !n.hasPostedAuthFailure.Swap(true) {
+		n.postFailure("fetch-forum-topic: authentication with the forum failed",
+			"The forum rejected the credentials or the session expired; the pages are failing until the credentials are renewed.")
+	}
+	if n.fetcher.HasDiskFullFailure() && !n.hasPostedDiskFullFailure.Swap(true) {
+		n.postFailure("fetch-forum-topic: no space left on device",
+			"The archive could not be written because the disk of the target directory is full.")
+	}
+}
+
+func (n *criticalFailureChatNotifier) OnPageDone(event *fetch.PageEvent) {
+	if event.Err != nil {
+		n.check()
+	}
+}
+
+func (n *criticalFailureChatNotifier) OnError(event *fetch.ErrorEvent) {
+	n.check()
+}
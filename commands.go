@@ -0,0 +1,560 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+)
+
+// command is a subcommand of the CLI, which is invoked after the global flags as `command [flags] [arguments]`.
+type command struct {
+	name        string
+	synopsis    string
+	arguments   string
+	description string
+	setFlags    func(flags *flag.FlagSet)
+	run         func(flags *flag.FlagSet, args []string) error
+}
+
+// usageError is returned by commands when they have been invoked incorrectly.
+type usageError struct {
+	message string
+}
+
+func (err *usageError) Error() string {
+	return err.message
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{translatef(format, args...)}
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		initCommand,
+		keyringCommand,
+		fetchCommand,
+		resumeCommand,
+		retryCommand,
+		estimateCommand,
+		verifyCommand,
+		repairCommand,
+		exportCommand,
+		serveCommand,
+		recordCommand,
+		searchCommand,
+		diffCommand,
+		linksCommand,
+		statsCommand,
+		changesCommand,
+		injectCSSCommand,
+		systemdUnitCommand,
+		versionCommand,
+		selfUpdateCommand,
+	}
+}
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+const usageHeader = `usage: %s [-config file] [-lang language] [-log-file file] [-log-file-max-size size] [-log-format format] [-log-level level] [-no-color] [-q] [-t directory] [-v] command [flags] [arguments]
+
+The purpose of this script is to download all pages in the specified ranges from the desired forum topic according to the provided base template URL
+and to maintain the resulting archive.
+Run '%s command -h' for the usage of a particular command.
+
+Commands:
+`
+
+func printUsage() {
+	output := flag.CommandLine.Output()
+	fmt.Fprintf(output, translate(usageHeader), os.Args[0], os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(output, "  %-13s%s\n", cmd.name, translate(cmd.synopsis))
+	}
+	fmt.Fprintln(output, "\n"+translate("Global flags:"))
+	printTranslatedDefaults(flag.CommandLine)
+	fmt.Fprint(output, "\n"+translate(exitCodesDescription))
+}
+
+func newCommandFlagSet(cmd *command) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.Usage = func() {
+		output := flags.Output()
+		fmt.Fprintf(output, translate("usage: %s [global flags] %s [flags] %s\n\n%s.\n"), os.Args[0], cmd.name, cmd.arguments, translate(cmd.description))
+		if cmd.setFlags != nil {
+			fmt.Fprintln(output, "\n"+translate("Flags:"))
+			printTranslatedDefaults(flags)
+		}
+	}
+	if cmd.setFlags != nil {
+		cmd.setFlags(flags)
+	}
+
+	return flags
+}
+
+// runCommand runs the named command with the given arguments and returns the exit code of the process.
+func runCommand(name string, args []string) int {
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		fmt.Fprintln(os.Stderr, translate("error: unknown command:"), name)
+		fmt.Fprintf(os.Stderr, translate("Run '%s -h' for usage.\n"), os.Args[0])
+		return exitCodeUsage
+	}
+
+	flags := newCommandFlagSet(cmd)
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return exitCodeOK
+	}
+	if err != nil {
+		return exitCodeUsage
+	}
+
+	err = cmd.run(flags, flags.Args())
+	if err == nil {
+		return exitCodeOK
+	}
+
+	fmt.Fprintln(os.Stderr, colorize(colorRed, redact.Text(fmt.Sprint(translate("error: "), err))))
+	exitCode := getExitCode(err)
+	if exitCode == exitCodeUsage {
+		fmt.Fprintf(os.Stderr, translate("Run '%s %s -h' for usage.\n"), os.Args[0], cmd.name)
+	}
+
+	return exitCode
+}
+
+func setRequestFlags(flags *flag.FlagSet) {
+	requestRateLimit = 0
+	flags.Float64Var(&requestRateLimit, "rate-limit", requestRateLimit, "maximum number of `requests` per second (0 means unlimited)")
+
+	requestRetryCount = 0
+	flags.IntVar(&requestRetryCount, "retries", requestRetryCount, "number of `times` a request failing due to a network error or a 429 or 5xx status is retried, with exponential backoff starting at one second")
+
+	shouldUseTor = false
+	flags.BoolVar(&shouldUseTor, "tor", shouldUseTor, "make all the requests through the SOCKS5 proxy of a local Tor daemon (see -tor-proxy), which resolves the names of the hosts itself, so that onion services can be archived and neither the lookups nor the address of the machine leak; the requests for every page go over a separate circuit, and the videos are downloaded through Tor too")
+
+	torProxyAddress = transport.DefaultTorProxyAddress
+	flags.StringVar(&torProxyAddress, "tor-proxy", torProxyAddress, "`address` (host:port) of the SOCKS5 proxy of the Tor daemon used by -tor")
+
+	unixSocketPath = ""
+	flags.StringVar(&unixSocketPath, "unix-socket", unixSocketPath, "make all the connections of the requests to the Unix socket at `path` instead of to the hosts, e.g. to reach the forum through a local reverse proxy, a socket forwarded over SSH or a test server; the requests still name the hosts, and HTTPS is still spoken over the socket for https:// URLs")
+
+	shouldUseHTTP3 = false
+	flags.BoolVar(&shouldUseHTTP3, "http3", shouldUseHTTP3, "send the requests to the hosts which advertise HTTP/3 (in the Alt-Svc header of their responses) over QUIC, falling back to HTTP/1.1 or HTTP/2 for a while whenever a request over HTTP/3 fails")
+
+	shouldFetchFileTransferLinks = false
+	flags.BoolVar(&shouldFetchFileTransferLinks, "ftp", shouldFetchFileTransferLinks, "fetch the files linked from the posts on FTP servers (anonymously unless the URL names a user) and SFTP servers (as the user named by the URL, with its password or the keys of the SSH agent, verifying the server against ~/.ssh/known_hosts) and rewrite the links to the local copies")
+
+	resolvedHostOverrides = nil
+	flags.Var(&resolvedHostOverrides, "resolve", "connect to `host:ip` address ip instead of resolving the name of host, e.g. for a forum whose domain has expired but whose server is still reachable; TLS certificates are still verified for host; may be repeated")
+
+	dnsServer = ""
+	flags.StringVar(&dnsServer, "dns-server", dnsServer, "look up the names of the hosts at the DNS server at `address` (host[:port]) instead of the one of the system")
+
+	dnsCacheTTL = 0
+	flags.DurationVar(&dnsCacheTTL, "dns-cache", dnsCacheTTL, "reuse the addresses of a host for `duration` before looking its name up again, and keep using the last ones found while the lookups fail (0 means every connection looks it up)")
+
+	httpCacheDir = ""
+	flags.StringVar(&httpCacheDir, "http-cache", httpCacheDir, "keep the responses for resources other than pages in `directory` and reuse them in later runs and for other topics from the same forum for as long as their Cache-Control and Expires headers allow, revalidating them with the server afterwards")
+
+	shouldThrottleAdaptively = false
+	flags.BoolVar(&shouldThrottleAdaptively, "adaptive-throttling", shouldThrottleAdaptively, "slow down the requests to every host which responds with errors or much slower than usual, and speed them back up once it recovers, in addition to -rate-limit")
+
+	forumCredentials = ""
+	flags.StringVar(&forumCredentials, "credentials", forumCredentials, "`reference` to the credentials for HTTP basic authentication with the forum, of the form env:NAME or keyring:NAME where the environment variable or keyring entry NAME contains user:password")
+
+	forumAPIKey = ""
+	flags.StringVar(&forumAPIKey, "api-key", forumAPIKey, "`reference` to the API key sent with the requests to the JSON endpoints of the forum (currently supported by the discourse engines), of the form env:NAME or keyring:NAME")
+
+	forumAPIUsername = ""
+	flags.StringVar(&forumAPIUsername, "api-username", forumAPIUsername, "`name` of the user on whose behalf the API key is used")
+
+	forumBearerToken = ""
+	flags.StringVar(&forumBearerToken, "bearer-token", forumBearerToken, "`reference` to an OAuth2 access token sent in the Authorization header of the requests to the JSON endpoints of the forum instead of the API key, of the form env:NAME or keyring:NAME")
+
+	forumLogin = ""
+	flags.StringVar(&forumLogin, "login", forumLogin, "`reference` to the user name and password with which to log in to the forum through its login form (currently supported by the phpbb and xenforo engines), of the form env:NAME or keyring:NAME where the environment variable or keyring entry NAME contains user:password; the session is renewed by logging in again whenever it expires and the affected pages are retried")
+
+	forumClearanceCookies = nil
+	flags.Var(&forumClearanceCookies, "clearance-cookie", "present the cookie `name=value` (e.g. cf_clearance=...), copied from a browser which has passed the challenge of an anti-bot service such as Cloudflare, to the forum, or a reference of the form env:NAME or keyring:NAME to a bundle of such cookies separated by semicolons; use with -user-agent set to the User-Agent of that browser; may be repeated")
+
+	requestUserAgent = ""
+	flags.StringVar(&requestUserAgent, "user-agent", requestUserAgent, "send `string` as the User-Agent header of every request")
+
+	shouldOmitReferer = false
+	flags.BoolVar(&shouldOmitReferer, "no-referer", shouldOmitReferer, "do not send the URL of the page or stylesheet which refers to a resource as the Referer of the request for it, which is otherwise sent since many image hosts reject requests without it")
+}
+
+func setPageFetchingFlags(flags *flag.FlagSet) {
+	setRequestFlags(flags)
+
+	runReportFilename = ""
+	flags.StringVar(&runReportFilename, "report", runReportFilename, "write a JSON summary of the run (pages attempted, succeeded and failed, resources fetched, bytes downloaded, durations and retries) to `file` (- for the standard output)")
+
+	notificationURL = ""
+	flags.StringVar(&notificationURL, "notify-url", notificationURL, "`URL` of a webhook to which a JSON payload with the run report is POSTed when the run completes")
+
+	shouldNotifyPageFailures = false
+	flags.BoolVar(&shouldNotifyPageFailures, "notify-page-failures", shouldNotifyPageFailures, "also POST a JSON payload to the webhook given by -notify-url whenever the fetching of a page fails")
+
+	slackWebhookURL = ""
+	flags.StringVar(&slackWebhookURL, "slack-webhook", slackWebhookURL, "post the summary of the run and its critical failures (rejected or expired credentials, full disk) to the Slack incoming webhook at `URL`, which may also be given as a reference of the form env:NAME or keyring:NAME")
+
+	discordWebhookURL = ""
+	flags.StringVar(&discordWebhookURL, "discord-webhook", discordWebhookURL, "post the summary of the run and its critical failures to the Discord webhook at `URL`, which may also be given as a reference of the form env:NAME or keyring:NAME")
+
+	shouldNotifyDesktop = false
+	flags.BoolVar(&shouldNotifyDesktop, "notify-desktop", shouldNotifyDesktop, "show a desktop notification when the run finishes or fails (through notify-send or D-Bus on Linux, osascript on macOS and a toast on Windows)")
+
+	shouldSubmitToWayback = false
+	flags.BoolVar(&shouldSubmitToWayback, "wayback", shouldSubmitToWayback, "submit the URL of every fetched page to the Save Page Now API of the Wayback Machine of the Internet Archive and record the URLs of the snapshots in "+archive.WaybackSnapshotsFilename+" in the target directory")
+
+	waybackSubmissionInterval = 10 * time.Second
+	flags.DurationVar(&waybackSubmissionInterval, "wayback-interval", waybackSubmissionInterval, "minimum `duration` between two submissions to the Wayback Machine")
+
+	eventBrokerURL = ""
+	flags.StringVar(&eventBrokerURL, "publish", eventBrokerURL, "publish a JSON event whenever a page is done and when the run completes to the MQTT (mqtt://, mqtts://) or NATS (nats://, tls://) broker at `URL`, which may contain credentials")
+
+	eventTopic = "fetch-forum-topic"
+	flags.StringVar(&eventTopic, "publish-topic", eventTopic, "MQTT `topic` or NATS subject to which the events given by -publish are published")
+
+	emailReportRecipients = ""
+	flags.StringVar(&emailReportRecipients, "email-report", emailReportRecipients, "email a summary of the run, with the list of the failed pages attached, to the comma-separated `addresses` when it completes, through the mail server given by the smtp section of the configuration file")
+
+	activeHours = fetch.TimeWindow{}
+	flags.Var(&activeHours, "active-hours", "only start fetching pages within the daily `window` of the local time of the form HH:MM-HH:MM (e.g. 01:00-06:00), pausing outside it and resuming when it opens again; the pages being fetched when it closes are finished")
+
+	pageOrder = fetch.OldestFirst
+	flags.Var(&pageOrder, "order", translatef("`order` in which the pages are started (one of: %s); newest-first archives the most recent pages of an active topic, whose posts are the likeliest to be edited or deleted, before the older ones in case the run is interrupted or runs out of its budget", strings.Join(fetch.PageOrders, ", ")))
+
+	pageConcurrency = fetch.DefaultPageConcurrency
+	flags.IntVar(&pageConcurrency, "page-concurrency", pageConcurrency, "maximum `number` of pages fetched at once; the others wait in the order given by -order. This bounds the throughput on topics with many pages, which used to be fetched all at once; raise it to fetch them faster at the cost of a looser order and a higher load on the forum")
+
+	afterRunHookCommand = ""
+	flags.StringVar(&afterRunHookCommand, "exec-after", afterRunHookCommand, "run the `command` when the run completes, with its outcome in the environment variables FETCH_FORUM_TOPIC_EXIT_CODE, FETCH_FORUM_TOPIC_PAGES_SUCCEEDED, FETCH_FORUM_TOPIC_PAGES_FAILED, FETCH_FORUM_TOPIC_FAILED_PAGES, FETCH_FORUM_TOPIC_ERROR and so on and the JSON report of the run on its standard input")
+
+	perPageHookCommand = ""
+	flags.StringVar(&perPageHookCommand, "exec-per-page", perPageHookCommand, "run the `command` whenever the fetching of a page has finished, with its outcome in the environment variables FETCH_FORUM_TOPIC_PAGE_NUMBER, FETCH_FORUM_TOPIC_PAGE_URL, FETCH_FORUM_TOPIC_PAGE_DIR, FETCH_FORUM_TOPIC_PAGE_STATUS (ok or failed) and FETCH_FORUM_TOPIC_PAGE_ERROR")
+
+	mirrorDestination = ""
+	flags.StringVar(&mirrorDestination, "mirror", mirrorDestination, "push the files of the archive which have changed since the last push, by their checksums, to the `destination` when the run completes: an sftp:// URL, a remote of rclone prefixed with rclone: or a destination of rsync")
+
+	otlpEndpointURL = ""
+	flags.StringVar(&otlpEndpointURL, "otlp-endpoint", otlpEndpointURL, "export OpenTelemetry traces of the fetching of the pages and resources, the rewriting, the writing and every HTTP request to the OTLP/HTTP endpoint at `URL` (e.g. http://localhost:4318)")
+
+	isTUIMode = false
+	flags.BoolVar(&isTUIMode, "tui", isTUIMode, "show a terminal UI with the pages being fetched, the throughput and the failures, which can be requeued interactively")
+
+	acceptedResourcePatterns = nil
+	flags.Var(&acceptedResourcePatterns, "accept", "fetch only the resources whose URL matches the `pattern`, which is a glob (matched against the file name if it contains no /, otherwise against the whole URL) or a regular expression prefixed with re:; may be repeated")
+
+	rejectedResourcePatterns = nil
+	flags.Var(&rejectedResourcePatterns, "reject", "do not fetch the resources whose URL matches the `pattern` (see -accept), leaving links to them pointing at their original URL; may be repeated")
+
+	allowedResourceDomains = nil
+	flags.Var(&allowedResourceDomains, "allow-domain", "fetch the resources from third-party hosts only if they are in the `domain` (which matches its subdomains too and may contain the wildcards * and ?), leaving links to the resources from other hosts pointing at their original URL; may be repeated")
+
+	blockedResourceDomains = nil
+	flags.Var(&blockedResourceDomains, "block-domain", "drop the references to the resources from third-party hosts in the `domain` (see -allow-domain) altogether instead of fetching them or linking to them; may be repeated")
+
+	shouldStripTrackingParameters = false
+	flags.BoolVar(&shouldStripTrackingParameters, "strip-tracking", shouldStripTrackingParameters, translatef("remove the common tracking query parameters (%s) from the URLs of resources before fetching them and before rewriting the references to them", strings.Join(fetch.TrackingParameters, ", ")))
+
+	strippedQueryParameters = nil
+	flags.Var(&strippedQueryParameters, "strip-param", "remove the query parameters whose name matches the glob `pattern` from the URLs of resources (see -strip-tracking); may be repeated")
+
+	hostAliases = nil
+	flags.Var(&hostAliases, "host-alias", "treat the `locations` separated by = (hosts optionally followed by a path, e.g. cdn1.example.com=cdn2.example.com=example.com/static) as serving the same assets, which are fetched from the first location and stored and referenced once whichever location they are linked from; may be repeated")
+
+	shouldUseImageFallbacks = false
+	flags.BoolVar(&shouldUseImageFallbacks, "image-fallbacks", shouldUseImageFallbacks, "fetch the images which cannot be fetched from their URL by retrying with the Referer sent or omitted (see -no-referer) the other way around, then from their snapshot in the Wayback Machine and finally as the thumbnail served by the forum, recording the source which was used in the metadata and the summary of the run")
+
+	shouldSkipImages = false
+	flags.BoolVar(&shouldSkipImages, "no-images", shouldSkipImages, "do not fetch images, leaving links to them pointing at their original URL")
+
+	shouldSkipFonts = false
+	flags.BoolVar(&shouldSkipFonts, "no-fonts", shouldSkipFonts, "do not fetch web fonts, leaving links to them pointing at their original URL")
+
+	shouldSkipMedia = false
+	flags.BoolVar(&shouldSkipMedia, "no-media", shouldSkipMedia, "do not fetch audio and video, leaving links to them pointing at their original URL")
+
+	isPageRequisitesOnlyMode = false
+	flags.BoolVar(&isPageRequisitesOnlyMode, "page-requisites-only", isPageRequisitesOnlyMode, "fetch only the resources needed to render the pages (stylesheets, scripts, images and fonts), leaving links to media, embedded objects, frames and other resources pointing at their original URL")
+
+	maxTotalSize = 0
+	flags.Var(&maxTotalSize, "max-total-size", "stop starting new pages once `size` bytes (optionally suffixed with K, M, G or T) have been downloaded during the run; the remaining pages are left pending for the next run (0 means unlimited)")
+
+	maxResourceCount = 0
+	flags.UintVar(&maxResourceCount, "max-resources", maxResourceCount, "stop starting new pages once `count` resources have been fetched during the run; the remaining pages are left pending for the next run (0 means unlimited)")
+
+	maxResourceSize = 0
+	flags.Var(&maxResourceSize, "max-resource-size", "do not fetch resources larger than `size` bytes (optionally suffixed with K, M, G or T), leaving links to them pointing at their original URL; the size is learned through a HEAD request before the content is downloaded (0 means unlimited)")
+
+	pageTimeout = 0
+	flags.DurationVar(&pageTimeout, "page-timeout", pageTimeout, "give up on a page, along with the resources embedded in it, which has not been fetched within `duration` (e.g. 10m) and record it in the list of failed downloads, so that a pathological page (e.g. with a huge attachment or on a tarpit host) cannot stall the run (0 means unlimited)")
+
+	runDeadline = 0
+	flags.DurationVar(&runDeadline, "run-deadline", runDeadline, "stop the run once `duration` (e.g. 6h) has passed since its start: the pages in flight give up and, like the ones which have not been started, are recorded in the list of failed downloads for the next run (0 means unlimited)")
+
+	postProcessingCommand = ""
+	flags.StringVar(&postProcessingCommand, "exec-per-resource", postProcessingCommand, "run `command` on every stored page and resource once it has been written, e.g. to scan or optimize it, with {} standing for the path of the file (which is appended if there is no {}); the arguments are split at whitespace outside of quotes and nothing is expanded by a shell, and failures are reported in the summary of the run")
+
+	shouldConvertModernImages = false
+	flags.BoolVar(&shouldConvertModernImages, "convert-images", shouldConvertModernImages, "convert the WebP and AVIF images to PNG (if they are lossless or have transparency) or JPEG and make the pages refer to the converted copies, keeping the originals next to them, so that the archive can be viewed in old browsers and exported to e-reader formats which do not support the modern codecs; animated images are stored as they are")
+
+	pageThumbnailSize = 0
+	flags.IntVar(&pageThumbnailSize, "thumbnail-size", pageThumbnailSize, "store a JPEG thumbnail, at most `pixels` wide and high, next to every image embedded in the pages which is larger than that and show the thumbnail in its place, linking to the full-size copy unless the image is already a link, so that the pages with many large images are quick to open (0 means that the images are shown as they are)")
+
+	shouldStripEXIF = false
+	flags.BoolVar(&shouldStripEXIF, "strip-exif", shouldStripEXIF, "remove the EXIF and XMP metadata (such as the GPS coordinates at which a photo was taken and the details of the camera) from the JPEG images before storing them, without encoding them again, so that the archive can be republished without leaking the locations of the posters; the stripping is recorded in the metadata of every image affected (see -save-metadata) and in the summary of the run, and the orientation recorded in the metadata is lost as well")
+
+	shouldRecompressImages = false
+	flags.BoolVar(&shouldRecompressImages, "recompress-images", shouldRecompressImages, "recompress the stored PNG images losslessly with the best compression and the JPEG images at the quality given by -jpeg-quality (before running the command given by -exec-per-resource, if any), keeping the result only if it is smaller, to shrink archives dominated by uncompressed screenshots; animated PNG images and JPEG images with EXIF metadata are left as they are")
+
+	jpegQualityCap = defaultJPEGQualityCap
+	flags.IntVar(&jpegQualityCap, "jpeg-quality", jpegQualityCap, "`quality` (1-100) at which -recompress-images encodes the JPEG images again")
+
+	shouldKeepOriginalImages = false
+	flags.BoolVar(&shouldKeepOriginalImages, "keep-original-images", shouldKeepOriginalImages, "keep the original of every image recompressed by -recompress-images next to it, with "+originalImageFilenameSuffix+" appended to its name")
+
+	postProcessingConcurrency = runtime.NumCPU()
+	flags.IntVar(&postProcessingConcurrency, "exec-concurrency", postProcessingConcurrency, "maximum `number` of instances of the command given by -exec-per-resource (and of the recompressions of -recompress-images) running at once")
+
+	shouldCaptureLinkPreviews = false
+	flags.BoolVar(&shouldCaptureLinkPreviews, "link-previews", shouldCaptureLinkPreviews, "put a card with a preview of the target of every bare external link (whose text is its URL) after it, made from the OpenGraph and oEmbed metadata of the target and with its thumbnail fetched locally, so that the archive retains the context of links whose targets later disappear")
+
+	shouldShareSmileys = false
+	flags.BoolVar(&shouldShareSmileys, "share-smileys", shouldShareSmileys, "store the images of the smileys recognized by the forum engine only once, in the smileys directory of the archive, instead of along with every page, and record their codes in smileys.tsv")
+
+	shouldSkipSiteIcons = false
+	flags.BoolVar(&shouldSkipSiteIcons, "no-site-icons", shouldSkipSiteIcons, "do not store the favicon.ico, apple-touch-icon.png and browserconfig.xml at the root of the host of the pages, which browsers look for even if the pages do not link to them and which are otherwise stored once in the icons directory of the archive, so that the serve command shows the icons of the site")
+
+	shouldDownloadVideos = false
+	flags.BoolVar(&shouldDownloadVideos, "download-videos", shouldDownloadVideos, "download the videos embedded in the pages through the players of YouTube, Vimeo and Streamable with the command given by -video-command and replace the players with video elements which play the local copies; videos which cannot be downloaded are left embedded")
+
+	videoDownloadCommand = defaultVideoDownloadCommand
+	flags.StringVar(&videoDownloadCommand, "video-command", videoDownloadCommand, "yt-dlp `command` (or one of a compatible program) with which -download-videos downloads the videos, to which the options selecting the output file and the URL of the video are appended; the arguments are split like the ones of -exec-per-resource")
+
+	shouldTransliterateFilenames = false
+	flags.BoolVar(&shouldTransliterateFilenames, "transliterate-filenames", shouldTransliterateFilenames, "transliterate the Latin, Greek and Cyrillic letters in the names of the stored files to ASCII and percent-encode the rest of the non-ASCII characters, for archives which are copied to systems or media which mishandle such names")
+
+	shouldNameByTitle = false
+	flags.BoolVar(&shouldNameByTitle, "name-by-title", shouldNameByTitle, "name the fetched pages after the slug of the title of the topic and their number (e.g. great-debate-p017.html) instead of the last segment of their URL, so that the archive can be navigated in a file manager")
+
+	shouldTrackPosts = false
+	flags.BoolVar(&shouldTrackPosts, "track-posts", shouldTrackPosts, "compare the posts extracted from the fetched pages by ID with the ones seen during the earlier runs and record their edits (with both versions kept) and deletions in the change log of the archive, which is listed by the changes command, so that the moderation and stealth edits of a live topic are preserved across its snapshots")
+
+	shouldFetchFirstPostOnly = false
+	flags.BoolVar(&shouldFetchFirstPostOnly, "first-post-only", shouldFetchFirstPostOnly, "only archive the opening post of the topic along with its images and attachments (e.g. of announcement or tutorial boards): fetch just the first page, whatever the page ranges, and remove the other posts from it, if the engine can tell the posts apart")
+
+	referencedTopicCount = 0
+	flags.UintVar(&referencedTopicCount, "referenced-topics", referencedTopicCount, "also archive the first page of up to `count` other topics of the same forum which are linked from the posts, one hop away, and make the links to them point at the local copies, whose resources are loaded from the forum (the phpbb, xenforo and discourse engines recognize the links to topics; 0 disables it)")
+
+	injectedStylesheetSource = ""
+	flags.StringVar(&injectedStylesheetSource, "inject-css", injectedStylesheetSource, translatef("store the user stylesheet in the `file` (or the built-in one with the given name: %s) as "+archive.InjectedStylesheetFilename+" in the archive and link it from the end of the head of every fetched page, so that e.g. a dark theme applies to them (see the inject-css command for the pages fetched before)", strings.Join(getInjectedStylesheetPresetNames(), ", ")))
+
+	shouldInjectPrintStylesheet = false
+	flags.BoolVar(&shouldInjectPrintStylesheet, "print-css", shouldInjectPrintStylesheet, "generate a print-ready stylesheet for the forum engine (which hides the navigation, prints every post on a new sheet, expands the quotes and uses a legible font), store it as "+archive.PrintStylesheetFilename+" in the archive and link it from every fetched page for printing")
+
+	shouldHardenOffline = false
+	flags.BoolVar(&shouldHardenOffline, "harden-offline", shouldHardenOffline, "make the stored pages never contact the original site when they are opened, by injecting a restrictive Content-Security-Policy into them and removing the external targets of forms, the sources of external scripts and the external resource hints")
+
+	shouldSaveResourceMetadata = false
+	flags.BoolVar(&shouldSaveResourceMetadata, "save-metadata", shouldSaveResourceMetadata, "store the HTTP response metadata (status, Content-Type, Last-Modified, ETag and the rest of the headers except Set-Cookie) of every fetched page and resource in a sidecar .meta.json file next to it")
+}
+
+var force bool
+var refreshOlderThan archiveAge
+
+var fetchCommand = &command{
+	name:      "fetch",
+	synopsis:  "fetch pages of a forum topic",
+	arguments: "URL|profile [page ranges]",
+	description: "Fetch the pages in the specified ranges from the forum topic at the base template URL. " +
+		"Before doing anything else, pages which could not be downloaded successfully during the last run are fetched again.\n" +
+		"Instead of the URL, the name of a profile in the configuration file may be given, whose settings serve as defaults for the flags.\n" +
+		"If the URL is `-`, the full URLs of the pages are read from the standard input, one per line, and numbered in order starting from 1; " +
+		"without page ranges, all of them are fetched.\n" +
+		"A page range specification looks like this: `first..last`, where `first` is the number of the first page and `last` is the number of the last one. " +
+		"Either of them may be omitted, meaning the first or the last page of the topic respectively; `all` also stands for the last page, " +
+		"while negative numbers count from the end of the topic (e.g. `-5..` means the final five pages). " +
+		"Appending `:step` selects only every step-th page of the range (e.g. `1..100:5`), a single number `N` selects only that page within a comma-separated list but means `1..N` when it makes up a whole argument, " +
+		"and several specifications may be joined with commas in one argument.\n" +
+		"If no page ranges are specified, no new pages will be fetched; nevertheless, failed downloads will still be re-attempted.\n" +
+		"Pages which have already been fetched are skipped unless -f is given or their archived copy is older than the age passed to -refresh-older-than.\n" +
+		"With -n, the plan for every page is printed as tab-separated lines of the form `page  action  URL` and nothing is fetched or written",
+	setFlags: func(flags *flag.FlagSet) {
+		force = false
+		flags.BoolVar(&force, "f", force, "enable overwriting of already fetched pages")
+
+		flags.Var(&refreshOlderThan, "refresh-older-than", "re-fetch already fetched pages whose archived copy is older than the given `age` (e.g. 12h, 30d or 2w) even without -f")
+
+		//spanHosts := false
+		//flags.BoolVar(&spanHosts, "H", spanHosts, "enable spanning across hosts when doing recursive fetching of a page")
+
+		forumTopicPostStep = 15
+		flags.UintVar(&forumTopicPostStep, "s", forumTopicPostStep, "number of `posts` contained on a single page; used for determining the offset of the current page in the URL parameters")
+
+		forumEngine = "generic"
+		flags.StringVar(&forumEngine, "engine", forumEngine, translatef("`name` of the forum software which serves the topic, which determines how the URLs of the pages are derived and how the last page is detected (one of: %s)", strings.Join(engine.Names(), ", ")))
+
+		sitemapSource = ""
+		flags.StringVar(&sitemapSource, "sitemap", sitemapSource, "discover the URLs of the pages of the topic in the sitemaps of the forum instead of deriving them from the base URL, which finds the pages of topics with irregular pagination; the value is either auto, which reads the sitemaps declared in robots.txt or else /sitemap.xml, or the comma-separated `URLs` of the sitemaps (indexes of sitemaps are followed)")
+
+		isDryRunMode = false
+		flags.BoolVar(&isDryRunMode, "n", isDryRunMode, "dry run: print the URL of every page in the specified ranges together with whether it would be fetched, re-fetched or skipped, without writing anything (with -v, also probe each page for its size)")
+		flags.BoolVar(&isDryRunMode, "dry-run", isDryRunMode, "same as -n")
+
+		setPageFetchingFlags(flags)
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) == 0 {
+			return usageErrorf("no base URL or profile specified for forum topic pages")
+		}
+
+		err := resolveForumTopicURL(flags, args[0])
+		if err != nil {
+			return err
+		}
+		if sitemapSource != "" {
+			err = discoverForumTopicPageURLs()
+			if err != nil {
+				return err
+			}
+		}
+
+		pageRanges := args[1:]
+		if forumTopicPageURLs != nil && len(pageRanges) == 0 {
+			pageRanges = []string{fmt.Sprint(fetch.MinPageNumber) + ".."}
+		}
+		if shouldFetchFirstPostOnly {
+			pageRanges = []string{fmt.Sprintf("%d..%d", fetch.MinPageNumber, fetch.MinPageNumber)}
+		}
+		requestedPageNumbers, err := getFetcher().ParsePageRanges(interruptContext, pageRanges)
+		if err != nil {
+			return &usageError{err.Error()}
+		}
+
+		if !isDryRunMode {
+			err = archive.SaveTopicSettings(targetDir, &archive.TopicSettings{
+				URL:         forumTopicPageURLBase,
+				PageURLs:    forumTopicPageURLs,
+				PostStep:    forumTopicPostStep,
+				Engine:      forumEngine,
+				PageRanges:  pageRanges,
+				Credentials: forumCredentials,
+				APIKey:      forumAPIKey,
+				APIUsername: forumAPIUsername,
+				BearerToken: forumBearerToken,
+				Login:       forumLogin,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return fetchForumTopicPages(requestedPageNumbers, force, time.Duration(refreshOlderThan))
+	},
+}
+
+// applyTopicCredentials makes the fetch use the credentials recorded with the settings of the last fetch in the target
+// directory, unless others have been given by flags.
+func applyTopicCredentials(settings *archive.TopicSettings) {
+	if forumCredentials == "" {
+		forumCredentials = settings.Credentials
+	}
+	if forumAPIKey == "" {
+		forumAPIKey = settings.APIKey
+	}
+	if forumAPIUsername == "" {
+		forumAPIUsername = settings.APIUsername
+	}
+	if forumBearerToken == "" {
+		forumBearerToken = settings.BearerToken
+	}
+	if forumLogin == "" {
+		forumLogin = settings.Login
+	}
+}
+
+var resumeCommand = &command{
+	name:      "resume",
+	synopsis:  "continue the last fetch in the target directory",
+	arguments: "",
+	description: "Continue the last fetch in the target directory with the same URL and page ranges, " +
+		"fetching again the pages which failed and the ones which have not been fetched yet",
+	setFlags: setPageFetchingFlags,
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		settings, err := loadTopicSettings()
+		if err != nil {
+			return err
+		}
+		applyTopicCredentials(settings)
+
+		requestedPageNumbers, err := getFetcher().ParsePageRanges(interruptContext, settings.PageRanges)
+		if err != nil {
+			return err
+		}
+
+		return fetchForumTopicPages(requestedPageNumbers, false, 0)
+	},
+}
+
+var estimateCommand = &command{
+	name:      "estimate",
+	synopsis:  "estimate the size and duration of a fetch",
+	arguments: "URL|profile page ranges",
+	description: "Fetch only the first, the middle and the last page in the specified ranges and report the expected size and " +
+		"duration of fetching all of them, without writing anything",
+	setFlags: func(flags *flag.FlagSet) {
+		forumTopicPostStep = 15
+		flags.UintVar(&forumTopicPostStep, "s", forumTopicPostStep, "number of `posts` contained on a single page; used for determining the offset of the current page in the URL parameters")
+
+		forumEngine = "generic"
+		flags.StringVar(&forumEngine, "engine", forumEngine, translatef("`name` of the forum software which serves the topic, which determines how the URLs of the pages are derived and how the last page is detected (one of: %s)", strings.Join(engine.Names(), ", ")))
+
+		setRequestFlags(flags)
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) < 2 {
+			return usageErrorf("no base URL or page ranges specified for the estimate")
+		}
+
+		err := resolveForumTopicURL(flags, args[0])
+		if err != nil {
+			return err
+		}
+
+		estimatePageNumbers, err := getFetcher().ParsePageRanges(interruptContext, args[1:])
+		if err != nil {
+			return &usageError{err.Error()}
+		}
+
+		return estimateForumTopicFetch(fetch.SortPageNumbers(estimatePageNumbers))
+	},
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"gopkg.in/yaml.v3"
+)
+
+// profile describes a forum topic (or a whole forum) whose settings can be referred to by name instead of being passed as flags.
+type profile struct {
+	URL         string  `yaml:"url"`
+	Engine      string  `yaml:"engine,omitempty"`
+	PostStep    uint    `yaml:"step,omitempty"`
+	Credentials string  `yaml:"credentials,omitempty"`
+	APIKey      string  `yaml:"apiKey,omitempty"`
+	APIUsername string  `yaml:"apiUsername,omitempty"`
+	BearerToken string  `yaml:"bearerToken,omitempty"`
+	Login       string  `yaml:"login,omitempty"`
+	RateLimit   float64 `yaml:"rateLimit,omitempty"`
+	TargetDir   string  `yaml:"targetDir,omitempty"`
+	// SlackWebhook and DiscordWebhook are the webhooks (or secret references to them) of the channels to which the
+	// summaries of the runs and their critical failures are posted.
+	SlackWebhook   string `yaml:"slackWebhook,omitempty"`
+	DiscordWebhook string `yaml:"discordWebhook,omitempty"`
+	// ClearanceCookies is a reference to the bundle of the clearance cookies presented to the forum.
+	ClearanceCookies string `yaml:"clearanceCookies,omitempty"`
+	// Mirror is the remote location to which the archive is pushed after every run.
+	Mirror string `yaml:"mirror,omitempty"`
+}
+
+// smtpSettings describe the mail server through which the email reports about runs are sent.
+type smtpSettings struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	// Credentials is a reference to the user name and password for authenticating with the server, of the same form as
+	// the one given by -credentials; no authentication is done if it is empty.
+	Credentials string `yaml:"credentials"`
+	From        string `yaml:"from"`
+}
+
+type config struct {
+	Profiles map[string]*profile `yaml:"profiles"`
+	SMTP     *smtpSettings       `yaml:"smtp"`
+}
+
+var configFilename string
+
+// forumEngine is the name of the forum software which serves the topic.
+var forumEngine = "generic"
+
+func getDefaultConfigFilename() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, "fetch-forum-topic", "config.yaml")
+}
+
+func loadConfig() (cfg *config, err error) {
+	cfg = &config{}
+	if configFilename == "" {
+		return
+	}
+
+	content, err := os.ReadFile(configFilename)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, errorf("could not read configuration file %s", configFilename)
+	}
+
+	err = yaml.Unmarshal(content, cfg)
+	if err != nil {
+		return nil, errorf("could not parse configuration file %s: %v", configFilename, err)
+	}
+
+	return
+}
+
+func isFlagSet(flags *flag.FlagSet, name string) (isSet bool) {
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			isSet = true
+		}
+	})
+	return
+}
+
+// resolveForumTopicURL sets the base URL of the forum topic pages from the argument, which is either the URL itself,
+// `-` for reading the URLs of the pages from the standard input or the name of a profile in the configuration file;
+// in the latter case, the settings of the profile which have not been overridden by flags are applied as well.
+func resolveForumTopicURL(flags *flag.FlagSet, urlOrProfileName string) error {
+	if urlOrProfileName == pageURLListFromStdin {
+		err := readForumTopicPageURLs(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		return validateForumEngine()
+	}
+	if strings.Contains(urlOrProfileName, "://") {
+		forumTopicPageURLBase = fetch.ASCIIURL(urlOrProfileName)
+		return validateForumEngine()
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	prof, ok := cfg.Profiles[urlOrProfileName]
+	if !ok {
+		return usageErrorf("%q is neither a URL nor the name of a profile in %s", urlOrProfileName, configFilename)
+	}
+	if prof.URL == "" {
+		return errorf("profile %s does not specify a URL", urlOrProfileName)
+	}
+
+	prof.expandKeyringReferences(urlOrProfileName)
+	forumTopicPageURLBase = fetch.ASCIIURL(prof.URL)
+	if prof.Engine != "" && !isFlagSet(flags, "engine") {
+		forumEngine = prof.Engine
+	}
+	if prof.PostStep != 0 && flags.Lookup("s") != nil && !isFlagSet(flags, "s") {
+		forumTopicPostStep = prof.PostStep
+	}
+	if prof.RateLimit != 0 && flags.Lookup("rate-limit") != nil && !isFlagSet(flags, "rate-limit") {
+		requestRateLimit = prof.RateLimit
+	}
+	if prof.Credentials != "" && forumCredentials == "" {
+		forumCredentials = prof.Credentials
+	}
+	if prof.APIKey != "" && forumAPIKey == "" {
+		forumAPIKey = prof.APIKey
+	}
+	if prof.APIUsername != "" && forumAPIUsername == "" {
+		forumAPIUsername = prof.APIUsername
+	}
+	if prof.BearerToken != "" && forumBearerToken == "" {
+		forumBearerToken = prof.BearerToken
+	}
+	if prof.Login != "" && forumLogin == "" {
+		forumLogin = prof.Login
+	}
+	if prof.ClearanceCookies != "" && len(forumClearanceCookies) == 0 {
+		err = forumClearanceCookies.Set(prof.ClearanceCookies)
+		if err != nil {
+			return errorf("could not read the clearance cookies of profile %s: %v", urlOrProfileName, err)
+		}
+	}
+	if prof.SlackWebhook != "" && slackWebhookURL == "" {
+		slackWebhookURL = prof.SlackWebhook
+	}
+	if prof.DiscordWebhook != "" && discordWebhookURL == "" {
+		discordWebhookURL = prof.DiscordWebhook
+	}
+	if prof.Mirror != "" && mirrorDestination == "" {
+		mirrorDestination = prof.Mirror
+	}
+	if prof.TargetDir != "" && !isFlagSet(flag.CommandLine, "t") {
+		targetDir = os.ExpandEnv(prof.TargetDir)
+
+		// a relative log file belongs in the target directory of the profile
+		err = setUpLogger()
+		if err != nil {
+			return err
+		}
+	}
+
+	return validateForumEngine()
+}
+
+func validateForumEngine() error {
+	if _, ok := engine.Lookup(forumEngine); !ok {
+		return usageErrorf("unsupported forum engine: %s (supported engines: %s)", forumEngine, strings.Join(engine.Names(), ", "))
+	}
+
+	return nil
+}
+
+// getForumEngine returns the adapter for the forum engine, which has been validated before.
+func getForumEngine() engine.Engine {
+	forumEngineAdapter, _ := engine.Lookup(forumEngine)
+	return forumEngineAdapter
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// pageTimeout is the longest time the fetching of a page may take, and runDeadline the longest time the whole run may
+// take; 0 means unlimited.
+var pageTimeout time.Duration
+var runDeadline time.Duration
+
+// errRunDeadlineExceeded is the cause of the cancellation of the run once runDeadline has passed.
+var errRunDeadlineExceeded = errors.New("the run deadline passed")
+
+// withRunDeadline returns a copy of the context which is cancelled once runDeadline has passed, if it is set.
+func withRunDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if runDeadline <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, runDeadline, errRunDeadlineExceeded)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// desktopNotificationTitle is the title of the desktop notifications, which tells which program they come from.
+const desktopNotificationTitle = "fetch-forum-topic"
+
+// desktopNotificationTimeout is how long the command showing a desktop notification may take.
+const desktopNotificationTimeout = 10 * time.Second
+
+// shouldNotifyDesktop tells whether a desktop notification is shown when the run finishes or fails.
+var shouldNotifyDesktop bool
+
+// windowsToastScript shows the notification whose title and message are given in the environment as a toast through
+// the notification APIs of Windows Runtime, which PowerShell can call without any module.
+const windowsToastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$texts = $template.GetElementsByTagName('text')
+$texts.Item(0).AppendChild($template.CreateTextNode($env:FETCH_FORUM_TOPIC_NOTIFICATION_TITLE)) | Out-Null
+$texts.Item(1).AppendChild($template.CreateTextNode($env:FETCH_FORUM_TOPIC_NOTIFICATION_MESSAGE)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('fetch-forum-topic').Show($toast)
+`
+
+// quoteAppleScriptString returns the string as a literal of AppleScript.
+func quoteAppleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// newDesktopNotificationCommand returns the command which shows a desktop notification on the current platform:
+// notify-send or, if it is not installed, a call to the notification service on D-Bus through gdbus on Linux and the
+// other Unix-like systems, osascript on macOS and a toast shown through PowerShell on Windows.
+func newDesktopNotificationCommand(ctx context.Context, title, message string, isFailure bool) (cmd *exec.Cmd, err error) {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", quoteAppleScriptString(message), quoteAppleScriptString(title))
+		return exec.CommandContext(ctx, "osascript", "-e", script), nil
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		// the texts are passed in the environment so that they do not have to be quoted for PowerShell
+		cmd.Env = append(os.Environ(), "FETCH_FORUM_TOPIC_NOTIFICATION_TITLE="+title, "FETCH_FORUM_TOPIC_NOTIFICATION_MESSAGE="+message)
+		return cmd, nil
+	}
+
+	// the urgency levels of the notification specification are 0 (low), 1 (normal) and 2 (critical)
+	urgency, urgencyLevel := "normal", 1
+	if isFailure {
+		urgency, urgencyLevel = "critical", 2
+	}
+	if _, err := exec.LookPath("notify-send"); err == nil {
+		return exec.CommandContext(ctx, "notify-send", "--app-name", desktopNotificationTitle, "--urgency", urgency, "--", title, message), nil
+	}
+	if _, err := exec.LookPath("gdbus"); err == nil {
+		return exec.CommandContext(ctx, "gdbus", "call", "--session",
+			"--dest", "org.freedesktop.Notifications",
+			"--object-path", "/org/freedesktop/Notifications",
+			"--method", "org.freedesktop.Notifications.Notify",
+			desktopNotificationTitle, "0", "", title, message, "[]", fmt.Sprintf("{'urgency': <byte %d>}", urgencyLevel), "-1"), nil
+	}
+	return nil, errorf("neither notify-send nor gdbus is installed")
+}
+
+// notifyDesktop shows a desktop notification with the title and message.
+func notifyDesktop(title, message string, isFailure bool) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), desktopNotificationTimeout)
+	defer cancel()
+
+	cmd, err := newDesktopNotificationCommand(ctx, title, message, isFailure)
+	if err != nil {
+		return
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil && len(output) > 0 {
+		err = errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return
+}
+
+// formatDesktopNotificationMessage returns the message of the desktop notification about the run, which ended with the
+// error, if any.
+func formatDesktopNotificationMessage(report *fetch.Report, err error) string {
+	switch {
+	case report.PagesFailed > 0:
+		return fmt.Sprintf("The run in %s finished with %d of %d pages failed.", targetDir, report.PagesFailed, report.PagesAttempted)
+	case err != nil:
+		return fmt.Sprintf("The run in %s failed: %v", targetDir, err)
+	default:
+		return fmt.Sprintf("The run in %s finished: %d pages fetched, %d skipped.", targetDir, report.PagesSucceeded, report.PagesSkipped)
+	}
+}
+
+// notifyDesktopRunDone shows a desktop notification about the run of the fetcher, which ended with the error, if any,
+// when requested with -notify-desktop.
+func notifyDesktopRunDone(fetcher *fetch.Fetcher, err error) {
+	if !shouldNotifyDesktop {
+		return
+	}
+
+	report := fetcher.Report()
+	isFailure := err != nil || report.PagesFailed > 0
+	notifyErr := notifyDesktop(desktopNotificationTitle, formatDesktopNotificationMessage(report, err), isFailure)
+	if notifyErr != nil {
+		logWarningf("could not show a desktop notification: %v", notifyErr)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+)
+
+func hashArchiveFiles(dir string) (hashes map[string][]byte, err error) {
+	hashes = map[string][]byte{}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		switch relativePath {
+		case archive.StateFilename, archive.StateFilename + "-wal", archive.StateFilename + "-shm", archive.TopicSettingsFilename:
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		hash := sha256.New()
+		_, err = io.Copy(hash, file)
+		if err != nil {
+			return err
+		}
+
+		hashes[filepath.ToSlash(relativePath)] = hash.Sum(nil)
+		return nil
+	})
+	return
+}
+
+var diffCommand = &command{
+	name:      "diff",
+	synopsis:  "compare the archive with another one",
+	arguments: "directory",
+	description: "Compare the archive in the target directory with the one in the given directory, " +
+		"printing the files which were added (`+`), removed (`-`) or modified (`M`) in the latter",
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("exactly one directory to compare with must be specified")
+		}
+
+		oldHashes, err := hashArchiveFiles(targetDir)
+		if err != nil {
+			return errorf("could not read archive in %s: %v", targetDir, err)
+		}
+		newHashes, err := hashArchiveFiles(args[0])
+		if err != nil {
+			return errorf("could not read archive in %s: %v", args[0], err)
+		}
+
+		var lines []string
+		for path, oldHash := range oldHashes {
+			newHash, ok := newHashes[path]
+			if !ok {
+				lines = append(lines, "-\t"+path)
+			} else if !bytes.Equal(oldHash, newHash) {
+				lines = append(lines, "M\t"+path)
+			}
+		}
+		for path := range newHashes {
+			if _, ok := oldHashes[path]; !ok {
+				lines = append(lines, "+\t"+path)
+			}
+		}
+
+		sort.Slice(lines, func(i, j int) bool {
+			return lines[i][2:] < lines[j][2:]
+		})
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+)
+
+// Ways in which the posts which duplicate earlier ones are treated in the reports and the posts served by the API.
+const (
+	duplicatePostsKeep     = "keep"
+	duplicatePostsFlag     = "flag"
+	duplicatePostsCollapse = "collapse"
+)
+
+// duplicatePostsMode is how the double posts and the duplicates created by glitches of the forum are treated: they are
+// kept like any other post, flagged as duplicates of the original posts or collapsed into them.
+var duplicatePostsMode string
+
+// setDuplicatePostsFlag registers the flag which sets how the duplicate posts are treated.
+func setDuplicatePostsFlag(flags *flag.FlagSet) {
+	duplicatePostsMode = duplicatePostsKeep
+	flags.StringVar(&duplicatePostsMode, "duplicates", duplicatePostsMode, "`mode` of treating the posts which duplicate earlier ones of the same author made shortly before them (double posts and posts submitted twice by the forum): keep, flag or collapse")
+}
+
+// checkDuplicatePostsMode returns a usage error if the mode of treating the duplicate posts is not known.
+func checkDuplicatePostsMode() error {
+	switch duplicatePostsMode {
+	case duplicatePostsKeep, duplicatePostsFlag, duplicatePostsCollapse:
+		return nil
+	}
+
+	return usageErrorf("invalid mode of treating duplicate posts: %s", duplicatePostsMode)
+}
+
+// postDeduplicator applies the mode of treating the duplicate posts to the posts extracted from the archived pages,
+// which have to be passed to it in order.
+type postDeduplicator struct {
+	detector *engine.DuplicateDetector
+}
+
+// newPostDeduplicator returns a deduplicator for the mode of treating the duplicate posts given by the flags.
+func newPostDeduplicator() *postDeduplicator {
+	if duplicatePostsMode == duplicatePostsKeep {
+		return &postDeduplicator{}
+	}
+
+	return &postDeduplicator{detector: engine.NewDuplicateDetector()}
+}
+
+// filter returns the posts which are kept, leaving the duplicates out if they are collapsed, along with the originals
+// of the duplicates among them if they are flagged or of the ones left out if they are collapsed.
+func (d *postDeduplicator) filter(posts []*engine.Post) (keptPosts []*engine.Post, originals map[*engine.Post]*engine.Post) {
+	if d.detector == nil {
+		return posts, nil
+	}
+
+	originals = map[*engine.Post]*engine.Post{}
+	for _, post := range posts {
+		original, ok := d.detector.Check(post)
+		if ok {
+			originals[post] = original
+			if duplicatePostsMode == duplicatePostsCollapse {
+				continue
+			}
+		}
+		keptPosts = append(keptPosts, post)
+	}
+	return
+}
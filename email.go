@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+)
+
+// emailReportRecipients is a comma-separated list of the addresses to which a summary of the run is emailed when it
+// completes, through the mail server given in the configuration file.
+var emailReportRecipients string
+
+// failedPagesAttachmentFilename is the name of the attachment listing the pages which could not be fetched.
+const failedPagesAttachmentFilename = "failed-pages.tsv"
+
+const defaultSMTPPort = 587
+
+// loadSMTPSettings reads the settings of the mail server from the configuration file.
+func loadSMTPSettings() (settings *smtpSettings, err error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return
+	}
+
+	settings = cfg.SMTP
+	if settings == nil || settings.Host == "" {
+		return nil, errorf("no mail server (smtp.host) specified in configuration file %s", configFilename)
+	}
+	if settings.From == "" {
+		return nil, errorf("no sender address (smtp.from) specified in configuration file %s", configFilename)
+	}
+	if settings.Port == 0 {
+		settings.Port = defaultSMTPPort
+	}
+
+	return
+}
+
+func getEmailReportRecipients() (recipients []string) {
+	for _, recipient := range strings.Split(emailReportRecipients, ",") {
+		recipient = strings.TrimSpace(recipient)
+		if recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	return
+}
+
+// formatEmailReportSubject returns the subject of the email report, which tells at a glance whether the run went well.
+func formatEmailReportSubject(report *fetch.Report) string {
+	outcome := "completed"
+	if report.PagesFailed > 0 {
+		outcome = fmt.Sprintf("completed with %d failed pages", report.PagesFailed)
+	}
+
+	return fmt.Sprintf("fetch-forum-topic: run in %s %s", targetDir, outcome)
+}
+
+func formatEmailReportBody(fetcher *fetch.Fetcher, report *fetch.Report) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Topic: %s\n", redact.URLString(fetcher.PageURL(fetch.MinPageNumber)))
+	fmt.Fprintf(&body, "Target directory: %s\n", targetDir)
+	fmt.Fprintf(&body, "Started: %s\n", report.StartedAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "Finished: %s\n", report.FinishedAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "Duration: %s\n\n", time.Duration(report.DurationSeconds*float64(time.Second)).Round(time.Second))
+	fmt.Fprintf(&body, "Pages: %d attempted, %d succeeded, %d failed, %d skipped, %d pending\n",
+		report.PagesAttempted, report.PagesSucceeded, report.PagesFailed, report.PagesSkipped, report.PagesPending)
+	fmt.Fprintf(&body, "Resources: %d fetched, %d failed\n", report.ResourcesFetched, report.ResourcesFailed)
+	fmt.Fprintf(&body, "Downloaded: %s\n", formatByteCount(report.BytesDownloaded))
+	if report.PagesFailed > 0 {
+		fmt.Fprintf(&body, "\nThe pages which could not be fetched are listed in the attached %s; they will be fetched again on the next run.\n", failedPagesAttachmentFilename)
+	}
+	return body.String()
+}
+
+// formatFailedPages returns the list of the pages which could not be fetched as tab-separated lines of the form
+// `page  URL  error`.
+func formatFailedPages(fetcher *fetch.Fetcher, report *fetch.Report) string {
+	var list strings.Builder
+	for _, page := range report.Pages {
+		if page.Error == "" {
+			continue
+		}
+
+		fmt.Fprintf(&list, "%d\t%s\t%s\n", page.Number, redact.URLString(fetcher.PageURL(page.Number)), page.Error)
+	}
+	return list.String()
+}
+
+func writeQuotedPrintablePart(writer *multipart.Writer, header textproto.MIMEHeader, content string) (err error) {
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return
+	}
+
+	encoder := quotedprintable.NewWriter(part)
+	_, err = encoder.Write([]byte(content))
+	if err != nil {
+		return
+	}
+
+	return encoder.Close()
+}
+
+// composeEmailReport returns the email report as a MIME message with the summary of the run as its body and the list
+// of the failed pages, if any, as an attachment.
+func composeEmailReport(from string, recipients []string, fetcher *fetch.Fetcher, report *fetch.Report) (message []byte, err error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", formatEmailReportSubject(report)))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buffer, "Message-ID: <%d.%d@%s>\r\n", time.Now().UnixNano(), os.Getpid(), hostname)
+	fmt.Fprintf(&buffer, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buffer, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	err = writeQuotedPrintablePart(writer, textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	}, formatEmailReportBody(fetcher, report))
+	if err != nil {
+		return
+	}
+
+	if report.PagesFailed > 0 {
+		err = writeQuotedPrintablePart(writer, textproto.MIMEHeader{
+			"Content-Type":        {"text/tab-separated-values; charset=utf-8"},
+			"Content-Disposition": {mime.FormatMediaType("attachment", map[string]string{"filename": failedPagesAttachmentFilename})},
+		}, formatFailedPages(fetcher, report))
+		if err != nil {
+			return
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// sendEmailReport emails the summary of the run to the recipients given by -email-report through the mail server,
+// which is upgraded to TLS with STARTTLS if it supports it.
+func sendEmailReport(settings *smtpSettings, fetcher *fetch.Fetcher, report *fetch.Report) {
+	recipients := getEmailReportRecipients()
+	message, err := composeEmailReport(settings.From, recipients, fetcher, report)
+	if err != nil {
+		logErrorf("could not compose the email report: %v", err)
+		return
+	}
+
+	var auth smtp.Auth
+	if settings.Credentials != "" {
+		username, password, err := fetch.ResolveCredentials(settings.Credentials)
+		if err != nil {
+			logErrorf("could not resolve the credentials for the mail server: %v", err)
+			return
+		}
+
+		auth = smtp.PlainAuth("", username, password, settings.Host)
+	}
+
+	address := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+	err = smtp.SendMail(address, auth, settings.From, recipients, message)
+	if err != nil {
+		logErrorf("could not send the email report through %s: %v", address, err)
+		return
+	}
+
+	logInfof("Emailed the report to %s.", strings.Join(recipients, ", "))
+}
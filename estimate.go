@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"golang.org/x/net/html"
+)
+
+// maxEstimatedAssetProbes limits the number of HEAD requests issued while estimating the size of the assets of the sample pages.
+const maxEstimatedAssetProbes = 200
+
+type pageSample struct {
+	pageNumber uint
+	size       int64
+	elapsed    time.Duration
+	assetURIs  map[string]struct{}
+}
+
+func getEstimateSamplePageNumbers(pageNumbers []uint) (samplePageNumbers []uint) {
+	if len(pageNumbers) == 0 {
+		return
+	}
+
+	samplePageNumbers = append(samplePageNumbers, pageNumbers[0])
+	if len(pageNumbers) > 2 {
+		samplePageNumbers = append(samplePageNumbers, pageNumbers[len(pageNumbers)/2])
+	}
+	if len(pageNumbers) > 1 {
+		samplePageNumbers = append(samplePageNumbers, pageNumbers[len(pageNumbers)-1])
+	}
+	return
+}
+
+func samplePage(pageNumber uint) (sample *pageSample, err error) {
+	pageURLStr := getFetcher().PageURL(pageNumber)
+	pageURL, err := url.Parse(pageURLStr)
+	if err != nil {
+		logErrorf("could not parse URL of page %d", pageNumber)
+		return
+	}
+
+	startTime := time.Now()
+	contentReader, _, err := getFetcher().Get(interruptContext, pageURLStr, fmt.Sprint("page ", pageNumber))
+	if err != nil {
+		return
+	}
+	defer contentReader.Close()
+
+	sample = &pageSample{
+		pageNumber: pageNumber,
+		assetURIs:  map[string]struct{}{},
+	}
+
+	countingReader := &byteCountingReader{reader: contentReader}
+	contentTokenizer := html.NewTokenizer(countingReader)
+	for contentTokenizer.Next() != html.ErrorToken {
+		token := contentTokenizer.Token()
+		if token.Type != html.SelfClosingTagToken && token.Type != html.StartTagToken {
+			continue
+		}
+
+		linkAttrs := rewrite.GetLinkAttrs(&token)
+		if !linkAttrs.HasURI || !linkAttrs.IsEmbeddedResource(&token) {
+			continue
+		}
+
+		linkURI, err := url.Parse(linkAttrs.URI)
+		if err != nil || linkURI.Opaque == "" && linkURI.Path == "" {
+			continue
+		}
+
+		sample.assetURIs[pageURL.ResolveReference(linkURI).String()] = struct{}{}
+	}
+	if contentTokenizer.Err() != io.EOF {
+		err = contentTokenizer.Err()
+		logErrorf("could not read the content of page %d successfully", pageNumber)
+		return nil, err
+	}
+
+	sample.size = countingReader.count
+	sample.elapsed = time.Since(startTime)
+	return
+}
+
+type byteCountingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (reader *byteCountingReader) Read(p []byte) (n int, err error) {
+	n, err = reader.reader.Read(p)
+	reader.count += int64(n)
+	return
+}
+
+func estimateForumTopicFetch(pageNumbers []uint) error {
+	var samples []*pageSample
+	for _, pageNumber := range getEstimateSamplePageNumbers(pageNumbers) {
+		logDebugf("Sampling page %d...", pageNumber)
+
+		sample, err := samplePage(pageNumber)
+		if err != nil {
+			continue
+		}
+
+		samples = append(samples, sample)
+	}
+	if len(samples) == 0 {
+		return errorf("could not fetch any of the sample pages")
+	}
+
+	var totalSampleSize int64
+	var totalSampleElapsed time.Duration
+	assetOccurrences := map[string]int{}
+	for _, sample := range samples {
+		totalSampleSize += sample.size
+		totalSampleElapsed += sample.elapsed
+		for assetURI := range sample.assetURIs {
+			assetOccurrences[assetURI]++
+		}
+	}
+
+	// assets which appear on every sample page (theme images, stylesheets and scripts) will be fetched only once,
+	// while the rest are assumed to be specific to the page on which they appear
+	var sharedAssetCount, perPageAssetCount int
+	var sharedAssetSize, perPageAssetSize int64
+	probedAssetCount := 0
+	for assetURI, occurrences := range assetOccurrences {
+		var assetSize int64
+		if probedAssetCount < maxEstimatedAssetProbes {
+			probedAssetCount++
+
+			size, err := getFetcher().ResourceSize(interruptContext, assetURI)
+			if err == nil && size > 0 {
+				assetSize = size
+			} else {
+				logDebugf("could not determine the size of asset %s", assetURI)
+			}
+		}
+
+		if occurrences == len(samples) && len(samples) > 1 {
+			sharedAssetCount++
+			sharedAssetSize += assetSize
+		} else {
+			perPageAssetCount++
+			perPageAssetSize += assetSize
+		}
+	}
+
+	pageCount := int64(len(pageNumbers))
+	sampleCount := int64(len(samples))
+	averagePageSize := totalSampleSize / sampleCount
+	estimatedAssetCount := int64(sharedAssetCount) + int64(perPageAssetCount)*pageCount/sampleCount
+	estimatedTotalSize := averagePageSize*pageCount + sharedAssetSize + perPageAssetSize*pageCount/sampleCount
+
+	fmt.Println(translate("Sampled pages:"), len(samples))
+	fmt.Println(translate("Expected page count:"), pageCount)
+	fmt.Println(translate("Average page weight:"), formatByteCount(averagePageSize))
+	fmt.Println(translate("Estimated unique assets:"), estimatedAssetCount)
+	if probedAssetCount < len(assetOccurrences) {
+		fmt.Println(translatef("(sizes of only %d out of %d sampled assets were probed)", probedAssetCount, len(assetOccurrences)))
+	}
+	fmt.Println(translate("Estimated total download size:"), formatByteCount(estimatedTotalSize))
+	if totalSampleElapsed > 0 {
+		throughput := float64(totalSampleSize) / totalSampleElapsed.Seconds()
+		projectedDuration := time.Duration(float64(estimatedTotalSize) / throughput * float64(time.Second))
+		if requestRateLimit > 0 {
+			rateLimitedDuration := time.Duration(float64(pageCount+estimatedAssetCount) / requestRateLimit * float64(time.Second))
+			if rateLimitedDuration > projectedDuration {
+				projectedDuration = rateLimitedDuration
+			}
+		}
+		fmt.Println(translate("Projected duration:"), projectedDuration.Round(time.Second), translatef("(at %s/s)", formatByteCount(int64(throughput))))
+	}
+
+	return nil
+}
+
+func formatByteCount(count int64) string {
+	const unit = 1024
+	if count < unit {
+		return fmt.Sprintf("%d B", count)
+	}
+
+	divisor, exponent := int64(unit), 0
+	for n := count / unit; n >= unit; n /= unit {
+		divisor *= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(count)/float64(divisor), "KMGTPE"[exponent])
+}
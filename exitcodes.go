@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// Exit codes of the process, which let cron jobs and service managers tell transient failures from configuration errors.
+const (
+	// exitCodeOK means that everything which was requested has been done successfully.
+	exitCodeOK = 0
+	// exitCodeUsage means that the flags, arguments or configuration are invalid; retrying will not help.
+	exitCodeUsage = 1
+	// exitCodeFatal means that the run could not be carried out at all due to some other error.
+	exitCodeFatal = 2
+	// exitCodePartialFailure means that some pages could not be fetched and have been recorded for the next run.
+	exitCodePartialFailure = 3
+	// exitCodeAuthFailure means that the forum rejected the credentials or that they could not be obtained, or that an
+	// anti-bot service challenged the requests.
+	exitCodeAuthFailure = 4
+	// exitCodeDiskFull means that there is no space left in the target directory.
+	exitCodeDiskFull = 5
+	// exitCodeInterrupted means that the run was interrupted by a signal before all pages were fetched.
+	exitCodeInterrupted = 130
+)
+
+const exitCodesDescription = `Exit status:
+  0    all requested work was done successfully
+  1    invalid flags, arguments or configuration
+  2    fatal error
+  3    some pages could not be fetched (or the run deadline passed); they were recorded in the list of failed downloads and will be retried by the next run
+  4    authentication with the forum failed or an anti-bot service challenged the requests
+  5    no space left on the device of the target directory
+  130  interrupted by a signal
+`
+
+// exitCodeError carries the exit code with which the process should terminate due to an error.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (err *exitCodeError) Error() string {
+	return err.err.Error()
+}
+
+func (err *exitCodeError) Unwrap() error {
+	return err.err
+}
+
+// interruptContext is cancelled by the first SIGINT or SIGTERM.
+var interruptContext, interrupt = context.WithCancel(context.Background())
+
+// handleInterrupts makes the first SIGINT or SIGTERM stop the issuing of new requests, so that the run ends as soon as
+// the pages in flight give up; a second one terminates the process immediately.
+func handleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		interrupt()
+		logWarningf("interrupted; waiting for the pages being fetched to give up (interrupt again to exit immediately)...")
+
+		<-signals
+		os.Exit(exitCodeInterrupted)
+	}()
+}
+
+// getRunExitError returns the error corresponding to the most severe condition encountered during the run with the
+// context, if any.
+func getRunExitError(runContext context.Context, fetcher *fetch.Fetcher, failedPageCount int) error {
+	switch {
+	case interruptContext.Err() != nil:
+		return &exitCodeError{exitCodeInterrupted, errors.New(translate(fetch.ErrInterrupted.Error()))}
+	case context.Cause(runContext) == errRunDeadlineExceeded && failedPageCount > 0:
+		return &exitCodeError{exitCodePartialFailure, errorf("%v; the remaining pages will be fetched by the next run (see the verify command)", translate(errRunDeadlineExceeded.Error()))}
+	case fetcher.HasDiskFullFailure():
+		return &exitCodeError{exitCodeDiskFull, errorf("no space left on device")}
+	case fetcher.HasAuthFailure():
+		return &exitCodeError{exitCodeAuthFailure, errorf("authentication with the forum failed")}
+	case fetcher.HasChallengeFailure():
+		return &exitCodeError{exitCodeAuthFailure, errorf("challenged by an anti-bot service; pass the challenge in a browser and supply its clearance cookies with -clearance-cookie and its User-Agent with -user-agent")}
+	case failedPageCount > 0:
+		return &exitCodeError{exitCodePartialFailure, errorf("some pages could not be fetched; they will be fetched again by the next run (see the verify command)")}
+	}
+
+	return nil
+}
+
+func getExitCode(err error) int {
+	if err == nil {
+		return exitCodeOK
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitCodeUsage
+	}
+
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	return exitCodeFatal
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// exporter converts the archive in the target directory into another format.
+type exporter struct {
+	description      string
+	defaultExtension string
+	export           func(outputPath string) error
+}
+
+var exporters = map[string]*exporter{
+	"tar": {
+		description:      "gzip-compressed tarball of the whole archive",
+		defaultExtension: ".tar.gz",
+		export:           exportTarball,
+	},
+	"gallery": {
+		description:      "directory with every image posted in the topic once, named by its page, post and author, with thumbnails and an HTML page showing them (or a single HTML page with the images embedded, if the output path ends with .html)",
+		defaultExtension: "-gallery",
+		export:           exportGallery,
+	},
+	"org": {
+		description:      "Emacs Org document with a heading for every page and a subheading for every post, with the author and date of the post as properties and links to the archived images",
+		defaultExtension: ".org",
+		export:           exportOrg,
+	},
+	"ia": {
+		description:      "gzip-compressed tarball of the whole archive, which is then uploaded as an item to the Internet Archive with the title, source URL and capture date of the topic as its metadata (see -ia-identifier, -ia-collection and -ia-credentials)",
+		defaultExtension: ".tar.gz",
+		export:           exportInternetArchive,
+	},
+}
+
+func getExporterNames() (names []string) {
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func exportTarball(outputPath string) (err error) {
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return errorf("could not create output file %s", outputPath)
+	}
+	defer outputFile.Close()
+
+	gzipWriter := gzip.NewWriter(outputFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	// the description of the archive goes into a global header, which applies to the whole tarball
+	metadata, err := collectExportMetadata()
+	if err != nil {
+		return
+	}
+	err = tarWriter.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeXGlobalHeader,
+		Name:       "pax_global_header",
+		PAXRecords: map[string]string{"comment": metadata.description()},
+		Format:     tar.FormatPAX,
+	})
+	if err != nil {
+		return errorf("could not write tarball %s: %v", outputPath, err)
+	}
+
+	absOutputPath, _ := filepath.Abs(outputPath)
+	rootDir := filepath.Base(targetDir)
+	err = filepath.Walk(targetDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if absPath, _ := filepath.Abs(path); absPath == absOutputPath {
+			return nil
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(targetDir, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(rootDir, relativePath))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return errorf("could not write tarball %s: %v", outputPath, err)
+	}
+
+	err = tarWriter.Close()
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err != nil {
+		return errorf("could not write tarball %s: %v", outputPath, err)
+	}
+
+	return
+}
+
+var exportOutputPath string
+
+var exportCommand = &command{
+	name:        "export",
+	synopsis:    "export the archive in another format",
+	arguments:   "format",
+	description: "Export the archive in the target directory in the given format (one of: " + strings.Join(getExporterNames(), ", ") + ")",
+	setFlags: func(flags *flag.FlagSet) {
+		exportOutputPath = ""
+		flags.StringVar(&exportOutputPath, "o", exportOutputPath, "`path` of the exported output (default: the name of the target directory with an extension suitable for the format)")
+
+		internetArchiveIdentifier = ""
+		flags.StringVar(&internetArchiveIdentifier, "ia-identifier", internetArchiveIdentifier, "`identifier` of the Internet Archive item which the ia format uploads the archive as (default: the slug of the title of the topic)")
+
+		internetArchiveCollection = "opensource"
+		flags.StringVar(&internetArchiveCollection, "ia-collection", internetArchiveCollection, "Internet Archive `collection` in which the ia format creates the item")
+
+		internetArchiveCredentials = "env:IA_S3_KEYS"
+		flags.StringVar(&internetArchiveCredentials, "ia-credentials", internetArchiveCredentials, "`reference` of the form env:NAME to the access and secret keys of the S3-compatible API of the Internet Archive, given as access:secret")
+
+		setDuplicatePostsFlag(flags)
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("exactly one export format must be specified")
+		}
+		err := checkDuplicatePostsMode()
+		if err != nil {
+			return err
+		}
+
+		exp, ok := exporters[args[0]]
+		if !ok {
+			return usageErrorf("unknown export format: %s", args[0])
+		}
+
+		outputPath := exportOutputPath
+		if outputPath == "" {
+			absTargetDir, err := filepath.Abs(targetDir)
+			if err != nil {
+				return err
+			}
+			outputPath = filepath.Base(absTargetDir) + exp.defaultExtension
+		}
+
+		return exp.export(outputPath)
+	},
+}
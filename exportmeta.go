@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// exportMetadata describes the archived topic in the exports, so that they are self-describing.
+type exportMetadata struct {
+	// Title is the title of the first archived page, or the name of the target directory if it has none.
+	Title string
+	// SourceURL is the URL of the topic, if the settings of the archive are known.
+	SourceURL string
+	// FirstCaptureTime and LastCaptureTime are the times at which the least and the most recently updated pages were
+	// archived.
+	FirstCaptureTime, LastCaptureTime time.Time
+	// PageRanges are the numbers of the archived pages in the syntax of the page range specifications, e.g. `1..5,7..7`.
+	PageRanges string
+}
+
+// formatPageRanges returns the page numbers, which have to be sorted, as page range specifications joined with commas.
+func formatPageRanges(pageNumbers []uint) string {
+	var ranges []string
+	for i := 0; i < len(pageNumbers); {
+		j := i
+		for j+1 < len(pageNumbers) && pageNumbers[j+1] == pageNumbers[j]+1 {
+			j++
+		}
+
+		// a lone page number would stand for all the pages up to it
+		ranges = append(ranges, fmt.Sprintf("%d..%d", pageNumbers[i], pageNumbers[j]))
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// documentTitle returns the text of the title of the HTML document with the whitespace collapsed.
+func documentTitle(filename string) string {
+	file, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	tokenizer := html.NewTokenizer(file)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if tagName, _ := tokenizer.TagName(); atom.Lookup(tagName) != atom.Title {
+				continue
+			}
+			if tokenizer.Next() != html.TextToken {
+				return ""
+			}
+			return strings.Join(strings.Fields(html.UnescapeString(string(tokenizer.Text()))), " ")
+		}
+	}
+}
+
+// collectExportMetadata returns the metadata of the archive in the target directory, whose settings are loaded if
+// they exist.
+func collectExportMetadata() (metadata *exportMetadata, err error) {
+	absTargetDir, err := filepath.Abs(targetDir)
+	if err != nil {
+		return
+	}
+	metadata = &exportMetadata{Title: filepath.Base(absTargetDir)}
+
+	archivedPageNumbers, err := archive.PageNumbers(targetDir)
+	if err != nil {
+		return nil, errorf("could not list the contents of target directory %s", targetDir)
+	}
+	pageNumbers := fetch.SortPageNumbers(archivedPageNumbers)
+	metadata.PageRanges = formatPageRanges(pageNumbers)
+	for _, pageNumber := range pageNumbers {
+		modTime, err := archive.PageModTime(archive.PageDir(targetDir, pageNumber))
+		if err != nil || modTime.IsZero() {
+			continue
+		}
+		if metadata.FirstCaptureTime.IsZero() || modTime.Before(metadata.FirstCaptureTime) {
+			metadata.FirstCaptureTime = modTime
+		}
+		if modTime.After(metadata.LastCaptureTime) {
+			metadata.LastCaptureTime = modTime
+		}
+	}
+
+	// archives without settings can still be exported, just not described by their origin
+	settings, err := loadTopicSettings()
+	if err != nil {
+		logDebugf("could not read the settings of the topic: %v", err)
+		return metadata, nil
+	}
+	metadata.SourceURL = settings.URL
+	if metadata.SourceURL == "" && len(pageNumbers) > 0 {
+		metadata.SourceURL = getFetcher().PageURL(pageNumbers[0])
+	}
+
+	if len(pageNumbers) > 0 {
+		urlMap, err := archive.LoadURLMap(targetDir)
+		if err != nil {
+			return metadata, nil
+		}
+		if entry, ok := urlMap.LookupFilename(getFetcher().PageURL(pageNumbers[0])); ok {
+			if title := documentTitle(filepath.Join(targetDir, filepath.FromSlash(entry.Filename))); title != "" {
+				metadata.Title = title
+			}
+		}
+	}
+
+	return metadata, nil
+}
+
+// captureDate returns the date of the latest capture of the archive in the format of ISO 8601, or an empty one if it
+// is unknown.
+func (metadata *exportMetadata) captureDate() string {
+	if metadata.LastCaptureTime.IsZero() {
+		return ""
+	}
+	return metadata.LastCaptureTime.UTC().Format(time.RFC3339)
+}
+
+// dublinCoreHTML returns the HTML of the meta tags with the metadata in the Dublin Core vocabulary, which go in the
+// head of an HTML page.
+func (metadata *exportMetadata) dublinCoreHTML() template.HTML {
+	var tags strings.Builder
+	tags.WriteString(`<link rel="schema.DC" href="http://purl.org/dc/elements/1.1/">` + "\n")
+	for _, element := range []struct{ name, content string }{
+		{"DC.title", metadata.Title},
+		{"DC.source", metadata.SourceURL},
+		{"DC.date", metadata.captureDate()},
+		{"DC.coverage", metadata.PageRanges},
+		{"DC.type", "Text"},
+		{"DC.format", "text/html"},
+	} {
+		if element.content != "" {
+			fmt.Fprintf(&tags, "<meta name=\"%s\" content=\"%s\">\n", element.name, html.EscapeString(element.content))
+		}
+	}
+	return template.HTML(tags.String())
+}
+
+// description returns the metadata as a line of plain text.
+func (metadata *exportMetadata) description() string {
+	parts := []string{metadata.Title}
+	if metadata.SourceURL != "" {
+		parts = append(parts, metadata.SourceURL)
+	}
+	if metadata.PageRanges != "" {
+		parts = append(parts, "pages "+metadata.PageRanges)
+	}
+	if date := metadata.captureDate(); date != "" {
+		parts = append(parts, "captured "+date)
+	}
+	return strings.Join(parts, ", ")
+}
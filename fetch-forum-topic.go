@@ -1,650 +1,508 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"flag"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"net/url"
+	"log/slog"
 	"os"
-	"path/filepath"
-	"regexp"
-	"strings"
-	"sync"
+	"time"
 
-	"golang.org/x/net/html"
-	"golang.org/x/net/html/atom"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
 )
 
-type resourceFetcherContext struct {
-	baseURL                  *url.URL
-	targetHostDir            string
-	dirpath                  string
-	fetchedResources         map[string]string // map from the resource URI to the content type of the resource
-	replaceResourceReference func(reference string)
-}
-
-const failureListFileBasename = "failures.lst"
-
-var cssURLMatcher = regexp.MustCompile(`(url\s*\(["'])(.*?)(["']\))`)
-
 var forumTopicPostStep uint
 var forumTopicPageURLBase string
+
+// forumTopicPageURLs is the list of the URLs of the pages of the topic, if they have been given explicitly instead of
+// being derived from the base URL.
+var forumTopicPageURLs []string
 var targetDir string
 var isVerboseMode bool
+var isDryRunMode bool
+var shouldSaveResourceMetadata bool
+var shouldHardenOffline bool
+var shouldCaptureLinkPreviews bool
+var shouldShareSmileys bool
+var shouldSkipSiteIcons bool
+var shouldOmitReferer bool
+var shouldUseImageFallbacks bool
+var shouldStripEXIF bool
+var shouldTransliterateFilenames bool
+var shouldNameByTitle bool
+var shouldTrackPosts bool
+var shouldFetchFirstPostOnly bool
+
+// referencedTopicCount is the number of the other topics of the forum linked from the posts whose first page is
+// archived along with the topic.
+var referencedTopicCount uint
+
+// activeHours is the daily window of the local time within which the pages are fetched.
+var activeHours fetch.TimeWindow
+
+// pageOrder is the order in which the pages are started.
+var pageOrder fetch.PageOrder
+
+// pageConcurrency is the maximum number of pages fetched at once.
+var pageConcurrency int
+
+// archiveAge is a duration flag value which additionally accepts day (`d`) and week (`w`) units, e.g. `30d`.
+type archiveAge time.Duration
+
+func (age *archiveAge) String() string {
+	return time.Duration(*age).String()
+}
 
-var failureListFilename string
-var failureListFile *os.File
-var failureListFileMutex sync.Mutex
-
-var workers sync.WaitGroup
-
-func getFailedDownloads(targetDir string) (failedPageNumbers []uint) {
-	failedPageNumbers = []uint{}
-
-	failureListFile, err := os.Open(failureListFilename)
-	if os.IsNotExist(err) {
-		return
-	}
+func (age *archiveAge) Set(value string) error {
+	duration, err := parseArchiveAge(value)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: could not open list of failed downloads (%s) for reading", failureListFilename)
-		return
-	}
-
-	failureListScanner := bufio.NewScanner(failureListFile)
-	for failureListScanner.Scan() {
-		var failedPageNumber uint
-		_, err := fmt.Sscanf(failureListScanner.Text(), "%d", &failedPageNumber)
-		if err != nil {
-			continue
-		}
-
-		failedPageNumbers = append(failedPageNumbers, failedPageNumber)
+		return err
 	}
 
-	failureListFile.Close()
+	*age = archiveAge(duration)
+	return nil
+}
 
-	if len(failedPageNumbers) > 0 {
-		fmt.Printf("Found a list of failed downloads (%s); will reattempt them...\n", failureListFilename)
-		fmt.Print("Pages for which download will be reattempted: ")
-		for i := 0; i < len(failedPageNumbers)-1; i++ {
-			fmt.Printf("%d, ", failedPageNumbers[i])
+func parseArchiveAge(value string) (duration time.Duration, err error) {
+	var amount uint
+	var unit string
+	_, err = fmt.Sscanf(value, "%d%s", &amount, &unit)
+	if err == nil {
+		switch unit {
+		case "d":
+			duration = time.Duration(amount) * 24 * time.Hour
+			return
+		case "w":
+			duration = time.Duration(amount) * 7 * 24 * time.Hour
+			return
 		}
-		fmt.Println(failedPageNumbers[len(failedPageNumbers)-1])
-	}
-
-	i := 0
-	archivedFailureListFilename := fmt.Sprintf("%s.%d", failureListFilename, i)
-	for ; err == nil; _, err = os.Stat(archivedFailureListFilename) {
-		i++
-	}
-	if err != nil && !os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "error: could not stat archived list %s of failed downloads\n", archivedFailureListFilename)
-		return
 	}
 
-	latestFailureListFilename := fmt.Sprintf("%s.%d", failureListFilename, i)
-	err = os.Rename(failureListFilename, latestFailureListFilename)
+	duration, err = time.ParseDuration(value)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "error: could not rename latest list of failed downloads to", latestFailureListFilename)
-		return
+		err = errorf("invalid age %q: expected a Go duration or a number of days (`d`) or weeks (`w`)", value)
 	}
-
 	return
 }
 
-func getResource(urlStr, description string) (contentReader io.ReadCloser, contentType string, err error) {
-	response, err := http.Get(urlStr)
-	if err != nil {
-		log.Printf("error: could not fetch %s: HTTP GET request failed\n", description)
-		return
-	}
-	if response.StatusCode != http.StatusOK {
-		err = fmt.Errorf("HTTP response received with a non-OK status code")
-		log.Printf("error: could not fetch %s: %v\n", description, err)
-		return
-	}
-
-	contentReader = response.Body
-	contentType = response.Header.Get("Content-Type")
-
-	return
+// newFetcher returns a fetcher configured by the flags and the given options, which overwrites or refreshes already
+// fetched pages as requested.
+func newFetcher(force bool, refreshOlderThan time.Duration, options ...fetch.Option) *fetch.Fetcher {
+	config := fetch.Config{
+		URL:                    forumTopicPageURLBase,
+		PageURLs:               forumTopicPageURLs,
+		PostStep:               forumTopicPostStep,
+		Engine:                 getForumEngine(),
+		TargetDir:              targetDir,
+		Force:                  force,
+		RefreshOlderThan:       refreshOlderThan,
+		OnlyRequestedPages:     shouldFetchOnlyRequestedPages,
+		SaveMetadata:           shouldSaveResourceMetadata,
+		Accept:                 acceptedResourcePatterns,
+		Reject:                 rejectedResourcePatterns,
+		AllowDomains:           allowedResourceDomains,
+		BlockDomains:           blockedResourceDomains,
+		StripParameters:        getStrippedQueryParameters(),
+		HostAliases:            hostAliases,
+		HardenOffline:          shouldHardenOffline,
+		FileTransferLinks:      shouldFetchFileTransferLinks,
+		LinkPreviews:           shouldCaptureLinkPreviews,
+		SharedSmileys:          shouldShareSmileys,
+		SkipSiteIcons:          shouldSkipSiteIcons,
+		InjectedStylesheet:     injectedStylesheet,
+		PrintStylesheet:        getPrintStylesheet(),
+		OmitReferer:            shouldOmitReferer,
+		ImageFallbacks:         shouldUseImageFallbacks,
+		StripEXIF:              shouldStripEXIF,
+		TransliterateFilenames: shouldTransliterateFilenames,
+		NameByTitle:            shouldNameByTitle,
+		TrackPosts:             shouldTrackPosts,
+		FirstPostOnly:          shouldFetchFirstPostOnly,
+		ReferencedTopics:       referencedTopicCount,
+		SkipImages:             shouldSkipImages,
+		SkipFonts:              shouldSkipFonts,
+		SkipMedia:              shouldSkipMedia,
+		PageRequisitesOnly:     isPageRequisitesOnlyMode,
+		MaxTotalSize:           int64(maxTotalSize),
+		MaxResources:           maxResourceCount,
+		MaxResourceSize:        int64(maxResourceSize),
+		PageTimeout:            pageTimeout,
+		ActiveHours:            activeHours,
+		Order:                  pageOrder,
+		PageConcurrency:        pageConcurrency,
+		Credentials:            forumCredentials,
+		APIKey:                 forumAPIKey,
+		APIUsername:            forumAPIUsername,
+		BearerToken:            forumBearerToken,
+		Login:                  forumLogin,
+		ClearanceCookies:       forumClearanceCookies,
+		UserAgent:              requestUserAgent,
+	}
+
+	if client := newHTTPClient(); client != nil {
+		options = append(options, fetch.WithClient(client))
+	}
+	if unixSocketPath != "" {
+		options = append(options, fetch.WithDial(transport.UnixSocketDial(unixSocketPath)))
+	}
+	if shouldFetchFileTransferLinks {
+		options = append(options, fetch.WithMiddleware(transport.FTP(newFileTransferDial())))
+	}
+	if httpCacheDir != "" {
+		options = append(options, fetch.WithMiddleware(transport.DiskCache(httpCacheDir)))
+	}
+	options = append(options,
+		fetch.WithRateLimit(requestRateLimit),
+		fetch.WithMiddleware(transport.Retry(requestRetryCount, requestRetryBackoff)),
+		fetch.WithLogger(slog.New(currentLogHandler{})),
+		fetch.WithTranslation(translate),
+	)
+	if shouldThrottleAdaptively {
+		options = append(options, fetch.WithMiddleware(transport.Throttle(slog.New(currentLogHandler{}))))
+	}
+	if notificationURL != "" && shouldNotifyPageFailures {
+		options = append(options, fetch.WithObserver(pageFailureNotifier{}))
+	}
+	var chatNotifier *criticalFailureChatNotifier
+	if slackWebhookURL != "" || discordWebhookURL != "" {
+		chatNotifier = &criticalFailureChatNotifier{}
+		options = append(options, fetch.WithObserver(chatNotifier))
+	}
+
+	fetcher := fetch.New(config, options...)
+	if chatNotifier != nil {
+		chatNotifier.fetcher = fetcher
+	}
+	return fetcher
 }
 
-func adjustResourceFilenameExtension(filename, contentType string) string {
-	if strings.HasPrefix(contentType, "text/html") || strings.HasPrefix(contentType, "application/xhtml+xml") {
-		filenameEndsWithHTML, _ := filepath.Match("*.[Hh][Tt][Mm][Ll]", filename)
-		filenameEndsWithHTM, _ := filepath.Match("*.[Hh][Tt][Mm]", filename)
-		if !filenameEndsWithHTML && !filenameEndsWithHTM {
-			filename += ".html"
-		}
-	} else if strings.HasPrefix(contentType, "text/css") {
-		filenameEndsWithCSS, _ := filepath.Match("*.[Cc][Ss][Ss]", filename)
-		if !filenameEndsWithCSS {
-			filename += ".css"
-		}
-	} else if strings.HasPrefix(contentType, "application/atom+xml") {
-		filenameEndsWithAtom, _ := filepath.Match("*.[Aa][Tt][Oo][Mm]", filename)
-		if !filenameEndsWithAtom {
-			filename += ".atom"
-		}
-	} else if strings.HasPrefix(contentType, "application/rss+xml") {
-		filenameEndsWithRSS, _ := filepath.Match("*.[Rr][Ss][Ss]", filename)
-		if !filenameEndsWithRSS {
-			filename += ".rss"
-		}
+// forumTopicFetcher is used for parsing page ranges and deriving page URLs. It is created from the flags on first use,
+// so that the settings of the topic have been loaded by then.
+var forumTopicFetcher *fetch.Fetcher
+
+func getFetcher() *fetch.Fetcher {
+	if forumTopicFetcher == nil {
+		forumTopicFetcher = newFetcher(false, 0)
 	}
 
-	return filename
+	return forumTopicFetcher
 }
 
-func getLocalResourceRelativeReference(uri *url.URL, contentType string) (relativeReference string) {
-	relativeURIReference := url.URL{
-		Opaque:   uri.Opaque,
-		Path:     uri.Path,
-		RawQuery: uri.RawQuery,
+func printPageFetchPlan(fetcher *fetch.Fetcher, page *fetch.PagePlan) {
+	if !isVerboseMode || page.Action == fetch.PageActionSkip {
+		fmt.Printf("%d\t%s\t%s\n", page.Number, page.Action, page.URL)
+		return
 	}
-	relativeReference = relativeURIReference.String()
-	relativeReference = adjustResourceFilenameExtension(relativeReference, contentType)
-	return
-}
 
-func openFileForResourceContent(resourceURI *url.URL, resourceDescription, contentType, targetHostDir string) (file *os.File, filename string, err error) {
-	resourcePath := getLocalResourceRelativeReference(resourceURI, contentType)
-	filename = filepath.Join(targetHostDir, filepath.FromSlash(resourcePath))
-
-	dirname := filepath.Dir(filename)
-	err = os.MkdirAll(dirname, os.ModePerm)
+	size, err := fetcher.ResourceSize(interruptContext, page.URL)
 	if err != nil {
-		log.Printf("error: could not create target directory %s for %s\n", dirname, resourceDescription)
+		fmt.Printf("%d\t%s\t%s\t%s\n", page.Number, page.Action, page.URL, translatef("(HEAD request failed: %v)", err))
 		return
 	}
-
-	file, err = os.Create(filename)
-	if err != nil {
-		log.Printf("error: could not create file %s in which to write the content of %s\n", filename, resourceDescription)
+	if size < 0 {
+		fmt.Printf("%d\t%s\t%s\t%s\n", page.Number, page.Action, page.URL, translate("(size unknown)"))
 		return
 	}
 
-	return
+	fmt.Printf("%d\t%s\t%s\t%s\n", page.Number, page.Action, page.URL, translatef("%d bytes", size))
 }
 
-func fetchResourceFromLinkIfNecessary(linkURI *url.URL, context *resourceFetcherContext) (ok bool) {
-	var err error
-
-	resourceDescription := "resource " + linkURI.String()
-
-	if linkURI.Opaque == "" {
-		if linkURI.Path == "" {
-			return
+// fetchForumTopicPages fetches the given pages, as well as the pages whose download failed during the last run.
+func fetchForumTopicPages(requestedPageNumbers map[uint]struct{}, force bool, refreshOlderThan time.Duration) (err error) {
+	if isDryRunMode {
+		fetcher := newFetcher(force, refreshOlderThan)
+		plan, err := fetcher.Plan(requestedPageNumbers)
+		if err == fetch.ErrNoPages {
+			return usageErrorf("%v", err)
 		}
-
-		linkURI = context.baseURL.ResolveReference(linkURI)
-		contentType, wasResourceFetched := context.fetchedResources[linkURI.String()]
-		if !wasResourceFetched {
-			contentType, err = getAndWriteResourceToFile(linkURI, resourceDescription, context.targetHostDir, context.fetchedResources)
-			if err != nil {
-				return
-			}
-
-			context.fetchedResources[linkURI.String()] = contentType
-		}
-
-		relativeLinkPath, err := filepath.Rel(context.dirpath, filepath.FromSlash(linkURI.Path))
 		if err != nil {
-			log.Println("error: could not determine relative path to resource", linkURI.String())
-			return
+			return err
 		}
 
-		relativeReference := filepath.ToSlash(relativeLinkPath)
-		if linkURI.RawQuery != "" {
-			relativeReference += "%3F" + linkURI.RawQuery
+		for _, page := range plan {
+			printPageFetchPlan(fetcher, page)
 		}
-		relativeReference = adjustResourceFilenameExtension(relativeReference, contentType)
-		context.replaceResourceReference(relativeReference)
-	} else {
-		contentType, wasResourceFetched := context.fetchedResources[linkURI.String()]
-		if wasResourceFetched {
-			contentType, err = getAndWriteResourceToFile(linkURI, resourceDescription, context.targetHostDir, context.fetchedResources)
-			if err != nil {
-				return
-			}
+		return nil
+	}
 
-			context.fetchedResources[linkURI.String()] = contentType
+	var mailServerSettings *smtpSettings
+	if emailReportRecipients != "" {
+		if len(getEmailReportRecipients()) == 0 {
+			return usageErrorf("no recipients of the email report specified")
 		}
 
-		relativeReference := linkURI.Opaque
-		if linkURI.RawQuery != "" {
-			relativeReference += "%3F" + linkURI.RawQuery
+		mailServerSettings, err = loadSMTPSettings()
+		if err != nil {
+			return
 		}
-		relativeReference = adjustResourceFilenameExtension(relativeReference, contentType)
-		context.replaceResourceReference(relativeReference)
 	}
 
-	return true
-}
-
-func fetchLinkedResourcesInCSS(css []byte, context *resourceFetcherContext) (rewrittenCSS []byte, err error) {
-	var rewrittenCSSBuffer bytes.Buffer
-
-	for urlMatch := cssURLMatcher.FindSubmatchIndex(css); urlMatch != nil; urlMatch = cssURLMatcher.FindSubmatchIndex(css) {
-		linkURIStr := string(css[urlMatch[4]:urlMatch[5]])
-
-		linkURI, err := url.Parse(linkURIStr)
+	if injectedStylesheetSource != "" {
+		injectedStylesheet, err = loadInjectedStylesheet(injectedStylesheetSource)
 		if err != nil {
-			log.Println("error: could not parse URL of resource", linkURIStr)
-			rewrittenCSSBuffer.Write(css[:urlMatch[1]])
-			css = css[urlMatch[1]:]
-			continue
+			return
 		}
+	}
 
-		fullContext := *context
-		fullContext.replaceResourceReference = func(reference string) {
-			rewrittenCSSBuffer.Write(css[urlMatch[2]:urlMatch[3]])
-			rewrittenCSSBuffer.Write([]byte(reference))
-			rewrittenCSSBuffer.Write(css[urlMatch[6]:urlMatch[7]])
+	var ui *terminalUI
+	var options []fetch.Option
+	if isTUIMode {
+		ui, err = startTUI()
+		if err != nil {
+			return
 		}
 
-		rewrittenCSSBuffer.Write(css[:urlMatch[0]])
-		if !fetchResourceFromLinkIfNecessary(linkURI, &fullContext) {
-			rewrittenCSSBuffer.Write(css[urlMatch[0]:urlMatch[1]])
-		}
-		css = css[urlMatch[1]:]
+		options = append(options, fetch.WithObserver(ui.progress))
 	}
-
-	rewrittenCSSBuffer.Write(css)
-	rewrittenCSS = rewrittenCSSBuffer.Bytes()
-	return
-}
-
-func getAndWriteResourceToFile(resourceURL *url.URL, resourceDescription, targetHostDir string, fetchedResources map[string]string) (contentType string, err error) {
-	contentBody, contentType, err := getResource(resourceURL.String(), resourceDescription)
+	tracingOption, shutdownTracing, err := startTracing()
 	if err != nil {
-		return
+		if ui != nil {
+			ui.stop()
+		}
+		return errorf("could not set up the export of traces to %s: %v", otlpEndpointURL, err)
+	}
+	if tracingOption != nil {
+		defer shutdownTracing()
+		options = append(options, tracingOption)
 	}
-	defer contentBody.Close()
-
-	file, filename, err := openFileForResourceContent(resourceURL, resourceDescription, contentType, targetHostDir)
-	defer file.Close()
 
-	if strings.HasPrefix(contentType, "text/css") {
-		content, err := ioutil.ReadAll(contentBody)
-		if err != nil {
-			log.Printf("error: could not read the content of %s successfully\n", resourceDescription)
-			return contentType, err
+	var postProcessors []fetch.PostProcessor
+	if shouldRecompressImages {
+		if jpegQualityCap < 1 || jpegQualityCap > 100 {
+			if ui != nil {
+				ui.stop()
+			}
+			return usageErrorf("invalid JPEG quality: %d (it has to be between 1 and 100)", jpegQualityCap)
 		}
 
-		context := &resourceFetcherContext{
-			baseURL:          resourceURL,
-			targetHostDir:    targetHostDir,
-			dirpath:          filepath.Dir(filepath.FromSlash(resourceURL.Path)),
-			fetchedResources: fetchedResources,
-		}
-		content, err = fetchLinkedResourcesInCSS(content, context)
+		// the images are recompressed before the command sees them
+		postProcessors = append(postProcessors, recompressStoredImage)
+	}
+	if postProcessingCommand != "" {
+		process, err := newFilePostProcessor(postProcessingCommand)
 		if err != nil {
-			log.Printf("warning: could not rewrite the links in the content of %s successfully\n", resourceDescription)
+			if ui != nil {
+				ui.stop()
+			}
+			return err
 		}
 
-		_, err = file.Write(content)
-	} else {
-		contentBodyReader := bufio.NewReader(contentBody)
-		_, err = contentBodyReader.WriteTo(file)
+		postProcessors = append(postProcessors, process)
 	}
-	if err != nil {
-		log.Printf("error: could not write the content of %s in file %s successfully\n", resourceDescription, filename)
-		return
+	if len(postProcessors) > 0 {
+		options = append(options, fetch.WithPostProcessor(chainPostProcessors(postProcessors...), postProcessingConcurrency))
 	}
 
-	return
-}
-
-type writer interface {
-	io.Writer
-	io.ByteWriter
-	WriteString(string) (int, error)
-}
-
-const escapedChars = "&'<>\"\r"
-
-// shamelessly stolen from "golang.org/x/net/html"
-func escape(w writer, s string) error {
-	i := strings.IndexAny(s, escapedChars)
-	for i != -1 {
-		if _, err := w.WriteString(s[:i]); err != nil {
-			return err
-		}
-		var esc string
-		switch s[i] {
-		case '&':
-			esc = "&amp;"
-		case '\'':
-			// "&#39;" is shorter than "&apos;" and apos was not in HTML until HTML5.
-			esc = "&#39;"
-		case '<':
-			esc = "&lt;"
-		case '>':
-			esc = "&gt;"
-		case '"':
-			// "&#34;" is shorter than "&quot;".
-			esc = "&#34;"
-		case '\r':
-			esc = "&#13;"
-		default:
-			panic("unrecognized escape character")
+	var afterRunHook *hook
+	if afterRunHookCommand != "" {
+		afterRunHook, err = newHook("-exec-after", afterRunHookCommand)
+		if err != nil {
+			if ui != nil {
+				ui.stop()
+			}
+			return
 		}
-		s = s[i+1:]
-		if _, err := w.WriteString(esc); err != nil {
+	}
+	if perPageHookCommand != "" {
+		perPageHook, err := newHook("-exec-per-page", perPageHookCommand)
+		if err != nil {
+			if ui != nil {
+				ui.stop()
+			}
 			return err
 		}
-		i = strings.IndexAny(s, escapedChars)
+
+		options = append(options, fetch.WithObserver(&perPageHookRunner{hook: perPageHook}))
 	}
-	_, err := w.WriteString(s)
-	return err
-}
 
-func tagStringWithStyleDataPreserved(token *html.Token) string {
-	if len(token.Attr) == 0 {
-		return token.Data
-	}
-	buffer := bytes.NewBufferString(token.Data)
-	for _, attr := range token.Attr {
-		buffer.WriteByte(' ')
-		buffer.WriteString(attr.Key)
-		buffer.WriteString(`="`)
-		if atom.Lookup([]byte(attr.Key)) == atom.Style || strings.HasPrefix(attr.Key, "on") {
-			buffer.WriteString(attr.Val)
-		} else {
-			escape(buffer, attr.Val)
+	if shouldDownloadVideos {
+		download, err := newVideoDownloader(videoDownloadCommand)
+		if err != nil {
+			if ui != nil {
+				ui.stop()
+			}
+			return err
 		}
-		buffer.WriteByte('"')
-	}
-	return buffer.String()
-}
 
-func tokenStringWithScriptAndStyleDataPreserved(token *html.Token, prevToken *html.Token) string {
-	switch token.Type {
-	case html.TextToken:
-		if prevToken != nil && prevToken.Type == html.StartTagToken && prevToken.DataAtom == atom.Script {
-			return token.Data
+		options = append(options, fetch.WithVideoDownloader(download))
+	}
+	if shouldConvertModernImages {
+		options = append(options, fetch.WithImageConverter(convertModernImage, modernImageTypes...))
+	}
+	if pageThumbnailSize < 0 {
+		if ui != nil {
+			ui.stop()
 		}
-	case html.StartTagToken:
-		return "<" + tagStringWithStyleDataPreserved(token) + ">"
-	case html.SelfClosingTagToken:
-		return "<" + tagStringWithStyleDataPreserved(token) + "/>"
+		return usageErrorf("invalid thumbnail size: %d (it has to be positive, or 0 to show the images as they are)", pageThumbnailSize)
+	}
+	if pageThumbnailSize > 0 {
+		options = append(options, fetch.WithThumbnails(makePageThumbnail))
 	}
 
-	return token.String()
-}
-
-func fetchForumTopicPage(pageNumber uint, targetDir string) {
-	var err error
-	defer func() {
+	var publisher *eventPublisher
+	if eventBrokerURL != "" {
+		broker, err := connectEventBroker(eventBrokerURL)
 		if err != nil {
-			failureListFileMutex.Lock()
-			failureListFile.WriteString(fmt.Sprintln(pageNumber))
-			failureListFileMutex.Unlock()
+			if ui != nil {
+				ui.stop()
+			}
+			return errorf("could not connect to event broker %s: %v", getRedactedEventBrokerURL(), err)
 		}
 
-		workers.Done()
+		publisher = &eventPublisher{broker: broker}
+		options = append(options, fetch.WithObserver(publisher))
+	}
+
+	var submitter *waybackSubmitter
+	if shouldSubmitToWayback {
+		submitter = startWaybackSubmitter()
+		options = append(options, fetch.WithObserver(submitter))
+	}
+	fetcher := newFetcher(force, refreshOlderThan, options...)
+
+	defer func() {
+		notifyDesktopRunDone(fetcher, err)
 	}()
 
-	postOffset := forumTopicPostStep * (pageNumber - 1)
-	pageURLStr := fmt.Sprintf("%s%d", forumTopicPageURLBase, postOffset)
+	runContext, cancelRun := withRunDeadline(interruptContext)
+	defer cancelRun()
 
-	if isVerboseMode {
-		log.Printf("Starting the fetching of page %d into directory %s...\n", pageNumber, targetDir)
-		log.Println("URL:", pageURLStr)
+	scheduledPageCount, err := fetcher.Start(runContext, requestedPageNumbers)
+	if err == fetch.ErrNoPages {
+		err = usageErrorf("%v", err)
 	}
-
-	pageURL, err := url.Parse(pageURLStr)
 	if err != nil {
-		log.Println("error: could not parse URL of page", pageNumber)
+		if ui != nil {
+			ui.stop()
+		}
+		if submitter != nil {
+			submitter.wait()
+		}
+		if publisher != nil {
+			publisher.broker.close()
+		}
 		return
 	}
 
-	targetHostDir := filepath.Join(targetDir, pageURL.Hostname())
-
-	pageDescription := fmt.Sprint("page", pageNumber)
-
-	contentReader, contentType, err := getResource(pageURL.String(), pageDescription)
-	contentTokenizer := html.NewTokenizer(contentReader)
-	contentTokenizer.AllowCDATA(true)
-
-	contentFile, contentFilename, err := openFileForResourceContent(pageURL, pageDescription, contentType, targetHostDir)
-
-	pageDirpath := filepath.Dir(filepath.FromSlash(pageURL.Path))
-
-	fetchedResources := map[string]string{}
-
-	var prevToken *html.Token
-
-	for contentTokenizer.Next() != html.ErrorToken {
-		func() {
-			token := contentTokenizer.Token()
-
-			defer func() {
-				_, err := contentFile.WriteString(tokenStringWithScriptAndStyleDataPreserved(&token, prevToken))
-				if err != nil {
-					log.Printf("error: could not write part of the content of page %d in file %s successfully\n", pageNumber, contentFilename)
-				}
-				prevToken = &token
-			}()
+	sdNotify("READY=1")
+	supervisionContext, stopSupervision := context.WithCancel(runContext)
+	superviseUnderSystemd(supervisionContext, func() string {
+		bytesDownloaded, resourcesFetched := fetcher.Report().Usage()
+		return fmt.Sprintf("fetching %d pages; %d resources (%s) fetched so far", scheduledPageCount, resourcesFetched, formatByteCount(bytesDownloaded))
+	}, isFetchMakingProgress(fetcher))
 
-			if token.Type != html.SelfClosingTagToken && token.Type != html.StartTagToken {
-				return
-			}
-
-			if prevToken.DataAtom == atom.Style {
-				context := &resourceFetcherContext{
-					baseURL:          pageURL,
-					targetHostDir:    targetHostDir,
-					dirpath:          pageDirpath,
-					fetchedResources: fetchedResources,
-				}
-				styleData := []byte(token.Data)
-				styleData, err = fetchLinkedResourcesInCSS(styleData, context)
-				if err != nil {
-					log.Printf("error: could not rewrite the links in the content of the `style` element successfully\n")
-				}
-
-				token.Data = string(styleData)
-			} else {
-				var linkURIAttrAtom atom.Atom
-				var linkURIAttrIndex, styleIndex int
-				var linkURIStr, rel, style string
-				var hasLinkURIAttr, hasRel, hasStyle bool
-				for index, attr := range token.Attr {
-					if hasLinkURIAttr && hasRel {
-						break
-					}
-
-					attrKeyAtom := atom.Lookup([]byte(attr.Key))
-					switch attrKeyAtom {
-					case atom.Action, atom.Code, atom.Cite, atom.Data, atom.Formaction, atom.Href, atom.Icon, atom.Manifest, atom.Poster, atom.Src, atom.Srcset, atom.Usemap:
-						linkURIAttrAtom, linkURIAttrIndex, linkURIStr, hasLinkURIAttr = attrKeyAtom, index, attr.Val, true
-
-					case atom.Rel:
-						rel, hasRel = attr.Val, true
-
-					case atom.Style:
-						styleIndex, style, hasStyle = index, attr.Val, true
-
-					default:
-						switch attr.Key {
-						case "archive", "background", "codebase", "classid", "lowsrc", "longdesc", "profile":
-							linkURIAttrIndex, linkURIStr, hasLinkURIAttr = index, attr.Val, true
-						}
-					}
-				}
-
-				if hasStyle {
-					context := &resourceFetcherContext{
-						baseURL:          pageURL,
-						targetHostDir:    targetHostDir,
-						dirpath:          pageDirpath,
-						fetchedResources: fetchedResources,
-					}
-					styleData := []byte(style)
-					styleData, err = fetchLinkedResourcesInCSS(styleData, context)
-					if err != nil {
-						log.Printf("error: could not rewrite the links in the content of the `style` attribute successfully\n")
-					}
-
-					token.Attr[styleIndex].Val = string(styleData)
-				}
-
-				if !hasLinkURIAttr {
-					return
-				}
-
-				linkURI, err := url.Parse(linkURIStr)
-				if err != nil {
-					log.Println("error: could not parse URL of resource", linkURIStr)
-					return
-				}
-
-				isRelInline := strings.Contains(rel, "stylesheet") || strings.Contains(rel, "icon") || strings.Contains(rel, "shortcut")
-				if linkURIAttrAtom != atom.Action && linkURIAttrAtom != atom.Formaction && (linkURIAttrAtom != atom.Href || token.DataAtom != atom.A && token.DataAtom != atom.Area && token.DataAtom != atom.Embed && (token.DataAtom != atom.Link || hasRel && isRelInline)) {
-					context := &resourceFetcherContext{
-						baseURL:          pageURL,
-						targetHostDir:    targetHostDir,
-						dirpath:          pageDirpath,
-						fetchedResources: fetchedResources,
-						replaceResourceReference: func(reference string) {
-							token.Attr[linkURIAttrIndex].Val = reference
-						},
-					}
-					fetchResourceFromLinkIfNecessary(linkURI, context)
-				} else {
-					linkURI = pageURL.ResolveReference(linkURI)
-
-					token.Attr[linkURIAttrIndex].Val = linkURI.String()
-				}
-			}
-		}()
+	if ui != nil {
+		err = ui.run(fetcher, scheduledPageCount)
+		if err != nil {
+			logErrorf("%v", err)
+		}
 	}
-
-	contentFile.Close()
-	contentReader.Close()
-
-	if isVerboseMode {
-		log.Printf("Finished the fetching of page %d.\n", pageNumber)
+	err = fetcher.Wait()
+	if err != nil {
+		logErrorf("%v", err)
 	}
-}
-
-func main() {
-	const forumTopicMinPageNumber uint = 1
+	stopSupervision()
+	sdNotify("STOPPING=1")
 
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), `usage: %s [-f] [-s posts] [-t directory] [-v] URL [page ranges]
-
-Before doing anything else, this script tries to fetch again pages which could not be downloaded successfully during its last run.
-The purpose of this script is to download all pages in the specified ranges from the desired forum topic according to the provided base template URL.
-A page range specification looks like this: `+"`"+`first..last`+"`"+`, where `+"`"+`first`+"`"+` is the number of the first page and
-`+"`"+`last`+"`"+` is the number of the last one.
-If no page ranges are specified, no new pages will be fetched; nevertheless, failed downloads will still be re-attempted.
-
-Flags:
-`, os.Args[0])
-		flag.PrintDefaults()
+	if submitter != nil {
+		submitter.wait()
 	}
 
-	force := false
-	flag.BoolVar(&force, "f", force, "enable overwriting of already fetched pages")
+	report := fetcher.Report()
+	if report.PagesSucceeded > 0 {
+		updateSearchIndex()
+	}
+	err = writeRunReport(report)
+	if mirrorDestination != "" && interruptContext.Err() == nil {
+		if mirrorErr := mirrorArchive(interruptContext); mirrorErr != nil {
+			logErrorf("%v", mirrorErr)
+		}
+	}
+	notifyRunCompleted(report)
+	postChatRunSummary(fetcher, report, getRunExitError(runContext, fetcher, report.PagesFailed))
+	if publisher != nil {
+		publisher.publishRunCompleted(report)
+	}
+	if mailServerSettings != nil {
+		sendEmailReport(mailServerSettings, fetcher, report)
+	}
+	if err == nil {
+		err = getRunExitError(runContext, fetcher, report.PagesFailed)
+	}
+	if afterRunHook != nil {
+		runAfterRunHook(afterRunHook, report, err)
+	}
 
-	//spanHosts := false
-	//flag.BoolVar(&spanHosts, "H", spanHosts, "enable spanning across hosts when doing recursive fetching of a page")
+	return
+}
 
-	forumTopicPostStep = 15
-	flag.UintVar(&forumTopicPostStep, "s", forumTopicPostStep, "number of `posts` contained on a single page; used for determining the offset of the current page in the URL parameters")
+func main() {
+	flag.Usage = printUsage
 
-	targetDir, err := os.Getwd()
+	var err error
+	targetDir, err = os.Getwd()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error: could not get current working directory")
-		os.Exit(3)
+		os.Exit(exitCodeFatal)
 	}
 	flag.StringVar(&targetDir, "t", targetDir, "`directory` where the pages will be downloaded")
 
+	configFilename = getDefaultConfigFilename()
+	flag.StringVar(&configFilename, "config", configFilename, "configuration `file` with named profiles of forum topics and the settings of the mail server used by -email-report")
+
 	isVerboseMode = false
-	flag.BoolVar(&isVerboseMode, "v", isVerboseMode, "enable outputting of verbose messages")
+	flag.BoolVar(&isVerboseMode, "v", isVerboseMode, "enable outputting of verbose messages (same as -log-level debug)")
 
-	flag.Parse()
+	isQuietMode := false
+	flag.BoolVar(&isQuietMode, "q", isQuietMode, "suppress all messages except errors (same as -log-level error)")
 
-	args := flag.Args()
-	if len(args) == 0 {
-		fmt.Fprintln(os.Stderr, "error: no base URL specified for forum topic pages")
-		fmt.Fprintf(os.Stderr, "Run '%s -h' for usage.\n", os.Args[0])
-		os.Exit(1)
-	}
+	flag.Var(&currentLogLevel, "log-level", "`level` of the least severe messages to output: error, warn, info or debug")
 
-	forumTopicPageURLBase = args[0]
+	flag.StringVar(&logFormat, "log-format", logFormat, "`format` of the log messages: text or json (one object per line)")
 
-	failureListFilename := filepath.Join(targetDir, failureListFileBasename)
+	flag.StringVar(&logFilename, "log-file", logFilename, "also write all messages, including the debug ones, to `file` (relative to the target directory), which is rotated when it grows larger than the size passed to -log-file-max-size")
 
-	failedPageNumbers := map[uint]struct{}{}
-	for _, failedPageNumber := range getFailedDownloads(targetDir) {
-		failedPageNumbers[failedPageNumber] = struct{}{}
-	}
+	flag.Var(&logFileMaxSize, "log-file-max-size", "`size` beyond which the log file is rotated, keeping the last five rotated files (0 means unlimited)")
 
-	forumTopicPageNumbers := map[uint]struct{}{}
-	for failedPageNumber := range failedPageNumbers {
-		forumTopicPageNumbers[failedPageNumber] = struct{}{}
-	}
+	flag.BoolVar(&shouldDisableColor, "no-color", shouldDisableColor, "do not color the messages even if the output is a terminal")
 
-	for i := 1; i < len(args); i++ {
-		forumTopicPageRange := args[i]
-		var forumTopicPageRangeStart, forumTopicPageRangeEnd uint
-		_, err := fmt.Sscanf(forumTopicPageRange, "%d..%d", &forumTopicPageRangeStart, &forumTopicPageRangeEnd)
-		if err != nil {
-			forumTopicPageRangeStart = forumTopicMinPageNumber
-			_, err = fmt.Sscanf(forumTopicPageRange, "%d", &forumTopicPageRangeEnd)
-		}
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "error: invalid page range specification:", forumTopicPageRange)
-			fmt.Fprintf(os.Stderr, "Run '%s -h' for usage.\n", os.Args[0])
-			os.Exit(1)
-		}
+	flag.StringVar(&messageLanguage, "lang", messageLanguage, "`language` of the messages, e.g. en or bg, instead of the one of the locale given by LC_ALL, LC_MESSAGES or LANG")
 
-		for j := forumTopicPageRangeStart; j <= forumTopicPageRangeEnd; j++ {
-			forumTopicPageNumbers[j] = struct{}{}
+	// the usage is already printed in the language of the locale
+	setMessageLanguage(getLocaleLanguage())
+	flag.Parse()
+	if messageLanguage != "" {
+		err = setMessageLanguage(messageLanguage)
+		if err != nil {
+			fmt.Fprint(os.Stderr, translate("error: "), err, "\n")
+			os.Exit(exitCodeUsage)
 		}
 	}
 
-	if len(forumTopicPageNumbers) == 0 {
-		fmt.Fprintln(os.Stderr, "error: no range of forum topic pages specified")
-		fmt.Fprintf(os.Stderr, "Run '%s -h' for usage.\n", os.Args[0])
-		os.Exit(1)
+	if !isFlagSet(flag.CommandLine, "log-level") {
+		if isVerboseMode {
+			currentLogLevel = logLevelDebug
+		} else if isQuietMode {
+			currentLogLevel = logLevelError
+		}
 	}
+	isVerboseMode = isLogLevelEnabled(logLevelDebug)
 
-	failureListFile, err = os.Create(failureListFilename)
+	err = setUpLogger()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: could not create file %s in which to log failed downloads\n", failureListFilename)
-		return
+		fmt.Fprint(os.Stderr, translate("error: "), err, "\n")
+		fmt.Fprintf(os.Stderr, translate("Run '%s -h' for usage.\n"), os.Args[0])
+		os.Exit(exitCodeUsage)
 	}
-	defer failureListFile.Close()
-
-	for forumTopicPageNumber := range forumTopicPageNumbers {
-		forumTopicPageTargetDir := filepath.Join(targetDir, fmt.Sprint(forumTopicPageNumber))
-
-		if !force {
-			forumTopicPageTargetDirStat, err := os.Stat(forumTopicPageTargetDir)
-			if err != nil && !os.IsNotExist(err) {
-				log.Printf("error: could not stat target directory %s for page %d\n", forumTopicPageTargetDir, forumTopicPageNumber)
-				continue
-			} else if err == nil && forumTopicPageTargetDirStat.IsDir() {
-				_, ok := failedPageNumbers[forumTopicPageNumber]
-				if !ok {
-					continue
-				}
-			}
-		}
-		workers.Add(1)
-		go fetchForumTopicPage(forumTopicPageNumber, forumTopicPageTargetDir)
+
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, translate("error: no command specified"))
+		fmt.Fprintf(os.Stderr, translate("Run '%s -h' for usage.\n"), os.Args[0])
+		os.Exit(exitCodeUsage)
 	}
 
-	workers.Wait()
+	handleInterrupts()
+	os.Exit(runCommand(args[0], args[1:]))
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// resourceURLPatterns is a repeatable flag holding patterns which are matched against the URLs of resources.
+// See fetch.URLPattern for their syntax.
+type resourceURLPatterns []*fetch.URLPattern
+
+func (patterns *resourceURLPatterns) String() string {
+	var sources []string
+	for _, pattern := range *patterns {
+		sources = append(sources, pattern.String())
+	}
+
+	return strings.Join(sources, ",")
+}
+
+func (patterns *resourceURLPatterns) Set(value string) error {
+	pattern, err := fetch.ParseURLPattern(value)
+	if err != nil {
+		return err
+	}
+
+	*patterns = append(*patterns, pattern)
+	return nil
+}
+
+// acceptedResourcePatterns and rejectedResourcePatterns restrict which resources are fetched: if any accepted patterns
+// are given, a resource has to match one of them, and it must not match any of the rejected ones.
+var acceptedResourcePatterns resourceURLPatterns
+var rejectedResourcePatterns resourceURLPatterns
+
+// resourceDomainPatterns is a repeatable flag holding patterns which are matched against the hostnames of resources.
+// See fetch.DomainPattern for their syntax.
+type resourceDomainPatterns []*fetch.DomainPattern
+
+func (patterns *resourceDomainPatterns) String() string {
+	var sources []string
+	for _, pattern := range *patterns {
+		sources = append(sources, pattern.String())
+	}
+
+	return strings.Join(sources, ",")
+}
+
+func (patterns *resourceDomainPatterns) Set(value string) error {
+	pattern, err := fetch.ParseDomainPattern(value)
+	if err != nil {
+		return err
+	}
+
+	*patterns = append(*patterns, pattern)
+	return nil
+}
+
+// allowedResourceDomains and blockedResourceDomains restrict the third-party hosts whose resources are fetched: if any
+// allowed domains are given, the resources from other hosts are left pointing at their original URL, while the
+// references to the resources from blocked domains are dropped.
+var allowedResourceDomains resourceDomainPatterns
+var blockedResourceDomains resourceDomainPatterns
+
+// hostAliasGroups is a repeatable flag holding groups of locations which serve the same assets. See fetch.HostAliases
+// for their syntax.
+type hostAliasGroups []*fetch.HostAliases
+
+func (groups *hostAliasGroups) String() string {
+	var sources []string
+	for _, aliases := range *groups {
+		sources = append(sources, aliases.String())
+	}
+
+	return strings.Join(sources, ",")
+}
+
+func (groups *hostAliasGroups) Set(value string) error {
+	aliases, err := fetch.ParseHostAliases(value)
+	if err != nil {
+		return err
+	}
+
+	*groups = append(*groups, aliases)
+	return nil
+}
+
+// hostAliases are the groups of hosts (e.g. of a CDN) whose resources are fetched from the first one of the group.
+var hostAliases hostAliasGroups
+
+// queryParameterPatterns is a repeatable flag holding glob patterns (see path.Match) of the names of query parameters.
+type queryParameterPatterns []string
+
+func (patterns *queryParameterPatterns) String() string {
+	return strings.Join(*patterns, ",")
+}
+
+func (patterns *queryParameterPatterns) Set(value string) error {
+	_, err := path.Match(value, "")
+	if err != nil {
+		return errorf("invalid parameter pattern %q: %v", value, err)
+	}
+
+	*patterns = append(*patterns, value)
+	return nil
+}
+
+// strippedQueryParameters are the patterns of the names of the query parameters which are removed from the URLs of
+// resources, and shouldStripTrackingParameters adds the patterns of the common tracking parameters to them.
+var strippedQueryParameters queryParameterPatterns
+var shouldStripTrackingParameters bool
+
+func getStrippedQueryParameters() (patterns []string) {
+	patterns = append(patterns, strippedQueryParameters...)
+	if shouldStripTrackingParameters {
+		patterns = append(patterns, fetch.TrackingParameters...)
+	}
+	return
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/net/html"
+)
+
+// galleryThumbnailSize is the largest width and height of the thumbnails in the gallery.
+const galleryThumbnailSize = 240
+
+// Subdirectories of an exported gallery in which the images and their thumbnails are stored.
+const (
+	galleryImagesDirname     = "images"
+	galleryThumbnailsDirname = "thumbnails"
+)
+
+// galleryImage is an image posted in the topic.
+type galleryImage struct {
+	// Name is the name of the image in the gallery, made of the page, post and author of its first occurrence.
+	Name   string
+	Page   uint
+	PostID string
+	Author string
+	// ImageURL and ThumbnailURL are the references to the image and its thumbnail from the gallery page.
+	ImageURL, ThumbnailURL template.URL
+
+	// filename is the path of the archived image.
+	filename string
+}
+
+// galleryNameSegment returns the text with the characters which are not letters, digits, `-` or `_` replaced with `_`,
+// so that it is safe to use in a filename.
+func galleryNameSegment(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, text)
+}
+
+// galleryImageExtension returns the extension of the image, as it is known from its content type or else from its
+// filename.
+func galleryImageExtension(filename, contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/bmp":
+		return ".bmp"
+	}
+
+	return strings.ToLower(filepath.Ext(filename))
+}
+
+// makeThumbnail returns the JPEG thumbnail of the image, scaled down to fit the size (as its largest width and height)
+// and put on a white background, or false if the image cannot be decoded, in which case the image serves as its own
+// thumbnail.
+func makeThumbnail(content []byte, size int) (thumbnail []byte, ok bool) {
+	source, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return
+	}
+
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return
+	}
+	if scale := float64(size) / float64(max(width, height)); scale < 1 {
+		width, height = max(int(float64(width)*scale), 1), max(int(float64(height)*scale), 1)
+	}
+
+	destination := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(destination, destination.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(destination, destination.Bounds(), source, bounds, draw.Over, nil)
+
+	var buffer bytes.Buffer
+	err = jpeg.Encode(&buffer, destination, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return
+	}
+	return buffer.Bytes(), true
+}
+
+// collectGalleryImages returns the images posted in the topic in the target directory, whose settings have to be
+// loaded, in the order in which they first appear; the images which were not archived are left out and the ones which
+// were posted more than once (as told by their content) are included once.
+func collectGalleryImages() (images []*galleryImage, err error) {
+	hashes := map[[sha256.Size]byte]struct{}{}
+	_, err = walkArchivedPages(func(pageNumber uint, filename string, doc *html.Node) {
+		for _, post := range getForumEngine().ExtractPosts(doc) {
+			for _, src := range post.Images {
+				srcURL, err := url.Parse(src)
+				// the archived images are referred to by relative references
+				if err != nil || srcURL.IsAbs() || srcURL.Host != "" || srcURL.Path == "" {
+					continue
+				}
+
+				// the full-size copies of the images shown as thumbnails are exported
+				imagePath := strings.TrimSuffix(srcURL.Path, fetch.ThumbnailFilenameSuffix)
+				imageFilename := filepath.Join(filepath.Dir(filename), filepath.FromSlash(imagePath))
+				content, err := os.ReadFile(imageFilename)
+				if err != nil {
+					logDebugf("skipping image %s: %v", imageFilename, err)
+					continue
+				}
+				contentType := http.DetectContentType(content)
+				if !strings.HasPrefix(contentType, "image/") {
+					continue
+				}
+
+				hash := sha256.Sum256(content)
+				if _, ok := hashes[hash]; ok {
+					continue
+				}
+				hashes[hash] = struct{}{}
+
+				nameParts := []string{fmt.Sprintf("page%04d", pageNumber)}
+				if post.ID != "" {
+					nameParts = append(nameParts, "post"+galleryNameSegment(post.ID))
+				}
+				if post.Author != "" {
+					nameParts = append(nameParts, galleryNameSegment(post.Author))
+				}
+				nameParts = append(nameParts, fmt.Sprint(len(images)+1))
+
+				images = append(images, &galleryImage{
+					Name:     strings.Join(nameParts, "-") + galleryImageExtension(imageFilename, contentType),
+					Page:     pageNumber,
+					PostID:   post.ID,
+					Author:   post.Author,
+					filename: imageFilename,
+				})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+{{.DublinCore}}<style>
+body { font-family: sans-serif; margin: 1em; }
+.gallery { display: flex; flex-wrap: wrap; gap: 1em; }
+figure { margin: 0; width: {{.ThumbnailSize}}px; }
+figure img { max-width: {{.ThumbnailSize}}px; max-height: {{.ThumbnailSize}}px; }
+figcaption { font-size: small; overflow-wrap: anywhere; }
+.lightbox { display: none; position: fixed; inset: 0; background: rgba(0, 0, 0, 0.9); align-items: center; justify-content: center; }
+.lightbox:target { display: flex; }
+.lightbox img { max-width: 100%; max-height: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Images}} images</p>
+<div class="gallery">
+{{- range $index, $image := .Images}}
+<figure><a href="{{if $.IsEmbedded}}#image-{{$index}}{{else}}{{$image.ImageURL}}{{end}}"><img src="{{$image.ThumbnailURL}}" alt="{{$image.Name}}" loading="lazy"></a><figcaption>Page {{$image.Page}}{{if $image.PostID}}, post {{$image.PostID}}{{end}}{{if $image.Author}} by {{$image.Author}}{{end}}</figcaption></figure>
+{{- end}}
+</div>
+{{- if .IsEmbedded}}
+{{- range $index, $image := .Images}}
+<div class="lightbox" id="image-{{$index}}"><a href="#"><img src="{{$image.ImageURL}}" alt="{{$image.Name}}" loading="lazy"></a></div>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// writeGalleryPage writes the HTML page of the gallery of the images in the described archive to the file. If the images
+// are embedded in the page, which browsers do not navigate to, they are shown over the gallery instead of being linked
+// to.
+func writeGalleryPage(filename string, images []*galleryImage, isEmbedded bool, metadata *exportMetadata) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return galleryTemplate.Execute(file, struct {
+		Title         string
+		DublinCore    template.HTML
+		ThumbnailSize int
+		Images        []*galleryImage
+		IsEmbedded    bool
+	}{
+		Title:         "Images posted in " + metadata.Title,
+		DublinCore:    metadata.dublinCoreHTML(),
+		ThumbnailSize: galleryThumbnailSize,
+		Images:        images,
+		IsEmbedded:    isEmbedded,
+	})
+}
+
+// dataURL returns the `data:` URL with the content.
+func dataURL(content []byte) template.URL {
+	return template.URL("data:" + http.DetectContentType(content) + ";base64," + base64.StdEncoding.EncodeToString(content))
+}
+
+// exportGallery exports the images posted in the topic as a directory with the images, their thumbnails and an HTML
+// page showing the thumbnails, or, if the output path ends with `.html`, as a single HTML page with the images and the
+// thumbnails embedded in it.
+func exportGallery(outputPath string) (err error) {
+	_, err = loadTopicSettings()
+	if err != nil {
+		return
+	}
+
+	images, err := collectGalleryImages()
+	if err != nil {
+		return
+	}
+	metadata, err := collectExportMetadata()
+	if err != nil {
+		return
+	}
+
+	if strings.EqualFold(filepath.Ext(outputPath), ".html") {
+		for _, galleryImage := range images {
+			content, err := os.ReadFile(galleryImage.filename)
+			if err != nil {
+				return errorf("could not read image %s", galleryImage.filename)
+			}
+
+			galleryImage.ImageURL = dataURL(content)
+			galleryImage.ThumbnailURL = galleryImage.ImageURL
+			if thumbnail, ok := makeThumbnail(content, galleryThumbnailSize); ok {
+				galleryImage.ThumbnailURL = dataURL(thumbnail)
+			}
+		}
+
+		err = writeGalleryPage(outputPath, images, true, metadata)
+		if err != nil {
+			return errorf("could not write gallery page %s: %v", outputPath, err)
+		}
+		logSuccessf("Exported %d images to %s.", len(images), outputPath)
+		return
+	}
+
+	for _, dirname := range []string{galleryImagesDirname, galleryThumbnailsDirname} {
+		err = os.MkdirAll(filepath.Join(outputPath, dirname), os.ModePerm)
+		if err != nil {
+			return errorf("could not create gallery directory %s", filepath.Join(outputPath, dirname))
+		}
+	}
+
+	for _, galleryImage := range images {
+		content, err := os.ReadFile(galleryImage.filename)
+		if err != nil {
+			return errorf("could not read image %s", galleryImage.filename)
+		}
+
+		imagePath := galleryImagesDirname + "/" + galleryImage.Name
+		err = os.WriteFile(filepath.Join(outputPath, filepath.FromSlash(imagePath)), content, 0666)
+		if err != nil {
+			return errorf("could not write image %s: %v", imagePath, err)
+		}
+		galleryImage.ImageURL = template.URL((&url.URL{Path: imagePath}).String())
+		galleryImage.ThumbnailURL = galleryImage.ImageURL
+
+		if thumbnail, ok := makeThumbnail(content, galleryThumbnailSize); ok {
+			thumbnailPath := galleryThumbnailsDirname + "/" + strings.TrimSuffix(galleryImage.Name, filepath.Ext(galleryImage.Name)) + ".jpg"
+			err = os.WriteFile(filepath.Join(outputPath, filepath.FromSlash(thumbnailPath)), thumbnail, 0666)
+			if err != nil {
+				return errorf("could not write thumbnail %s: %v", thumbnailPath, err)
+			}
+			galleryImage.ThumbnailURL = template.URL((&url.URL{Path: thumbnailPath}).String())
+		}
+	}
+
+	indexFilename := filepath.Join(outputPath, "index.html")
+	err = writeGalleryPage(indexFilename, images, false, metadata)
+	if err != nil {
+		return errorf("could not write gallery page %s: %v", indexFilename, err)
+	}
+	logSuccessf("Exported %d images to %s.", len(images), outputPath)
+	return
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// afterRunHookCommand is the command which is run when the run completes and perPageHookCommand is the one which is
+// run whenever the fetching of a page has finished; both are told the outcome through environment variables prefixed
+// with hookEnvPrefix.
+var afterRunHookCommand string
+var perPageHookCommand string
+
+const hookEnvPrefix = "FETCH_FORUM_TOPIC_"
+
+// hook is a command split into its arguments which is run with additional environment variables.
+type hook struct {
+	name string
+	args []string
+}
+
+func newHook(name, commandLine string) (*hook, error) {
+	args, err := splitCommandLine(commandLine)
+	if err != nil {
+		return nil, usageErrorf("%v", err)
+	}
+	if len(args) == 0 {
+		return nil, usageErrorf("empty %s command", name)
+	}
+
+	return &hook{name: name, args: args}, nil
+}
+
+// run runs the command with the variables, which are given without the prefix, added to the environment and the
+// input, if any, on its standard input.
+func (h *hook) run(ctx context.Context, variables map[string]string, input []byte) error {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.args[0], h.args[1:]...)
+	cmd.Env = os.Environ()
+	for name, value := range variables {
+		cmd.Env = append(cmd.Env, hookEnvPrefix+name+"="+value)
+	}
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return errorf("%v: %s", err, message)
+		}
+		return err
+	}
+
+	if message := strings.TrimSpace(output.String()); message != "" {
+		logDebugf("Output of the %s command: %s", h.name, message)
+	}
+	return nil
+}
+
+// formatPageNumbers returns the page numbers separated by spaces, so that they can be iterated over by a shell.
+func formatPageNumbers(pageNumbers []uint) string {
+	numbers := make([]string, len(pageNumbers))
+	for i, pageNumber := range pageNumbers {
+		numbers[i] = fmt.Sprint(pageNumber)
+	}
+	return strings.Join(numbers, " ")
+}
+
+// runAfterRunHook runs the -exec-after command with the outcome of the run, whose report is also written as JSON to
+// its standard input.
+func runAfterRunHook(h *hook, report *fetch.Report, runErr error) {
+	variables := map[string]string{
+		"TARGET_DIR":        targetDir,
+		"URL":               forumTopicPageURLBase,
+		"EXIT_CODE":         fmt.Sprint(getExitCode(runErr)),
+		"PAGES_ATTEMPTED":   fmt.Sprint(report.PagesAttempted),
+		"PAGES_SUCCEEDED":   fmt.Sprint(report.PagesSucceeded),
+		"PAGES_FAILED":      fmt.Sprint(report.PagesFailed),
+		"PAGES_SKIPPED":     fmt.Sprint(report.PagesSkipped),
+		"PAGES_PENDING":     fmt.Sprint(report.PagesPending),
+		"FAILED_PAGES":      formatPageNumbers(report.FailedPages),
+		"RESOURCES_FETCHED": fmt.Sprint(report.ResourcesFetched),
+		"RESOURCES_FAILED":  fmt.Sprint(report.ResourcesFailed),
+		"BYTES_DOWNLOADED":  fmt.Sprint(report.BytesDownloaded),
+		"DURATION_SECONDS":  fmt.Sprintf("%.3f", report.DurationSeconds),
+		"ERROR":             "",
+	}
+	if runErr != nil {
+		variables["ERROR"] = runErr.Error()
+	}
+
+	input, err := json.Marshal(report)
+	if err != nil {
+		logErrorf("could not encode the run report for the %s command: %v", h.name, err)
+		return
+	}
+	// the command is run even if the run has been interrupted, so that it learns about it
+	err = h.run(context.Background(), variables, input)
+	if err != nil {
+		logErrorf("the %s command failed: %v", h.name, err)
+	}
+}
+
+// perPageHookRunner runs the -exec-per-page command whenever the fetching of a page has finished.
+type perPageHookRunner struct {
+	fetch.NopObserver
+
+	hook *hook
+}
+
+func (runner *perPageHookRunner) OnPageDone(event *fetch.PageEvent) {
+	variables := map[string]string{
+		"TARGET_DIR":            targetDir,
+		"PAGE_NUMBER":           fmt.Sprint(event.Number),
+		"PAGE_URL":              event.URL,
+		"PAGE_DIR":              archive.PageDir(targetDir, event.Number),
+		"PAGE_ATTEMPT":          fmt.Sprint(event.Attempt),
+		"PAGE_DURATION_SECONDS": fmt.Sprintf("%.3f", event.Duration.Seconds()),
+		"PAGE_STATUS":           "ok",
+		"PAGE_ERROR":            "",
+	}
+	if event.Err != nil {
+		variables["PAGE_STATUS"] = "failed"
+		variables["PAGE_ERROR"] = event.Err.Error()
+	}
+
+	err := runner.hook.run(interruptContext, variables, nil)
+	if err != nil && interruptContext.Err() == nil {
+		logWarningf("the %s command failed for page %d: %v", runner.hook.name, event.Number, err)
+	}
+}
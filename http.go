@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+	"golang.org/x/net/proxy"
+)
+
+// requestRateLimit is the maximum number of requests per second issued to the forum and the hosts of its resources; 0 means unlimited.
+var requestRateLimit float64
+
+// requestRetryCount is the number of times a request which fails due to a network error or a transient condition on the
+// server is retried, waiting for requestRetryBackoff before the first retry and twice as long before every next one.
+var requestRetryCount int
+
+const requestRetryBackoff = time.Second
+
+// httpCacheDir is the directory in which the responses for the resources other than pages are cached across runs and
+// topics according to their Cache-Control and Expires headers; empty means no such cache.
+var httpCacheDir string
+
+// shouldThrottleAdaptively makes the fetcher slow down the requests to the hosts which show signs of strain.
+var shouldThrottleAdaptively bool
+
+// hostOverrides is a repeatable flag holding overrides of the resolution of hostnames of the form `host:ip`.
+type hostOverrides map[string]string
+
+func (overrides *hostOverrides) String() string {
+	var pairs []string
+	for host, ip := range *overrides {
+		pairs = append(pairs, host+":"+ip)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (overrides *hostOverrides) Set(value string) error {
+	host, ip, ok := strings.Cut(value, ":")
+	if !ok || host == "" || net.ParseIP(ip) == nil {
+		return errorf("invalid override %q: expected `host:ip`", value)
+	}
+
+	if *overrides == nil {
+		*overrides = make(hostOverrides)
+	}
+	(*overrides)[fetch.ASCIIHostname(host)] = ip
+	return nil
+}
+
+// resolvedHostOverrides are the IP addresses to which the connections to the hosts are made instead of resolving their
+// names, dnsServer is the DNS server at which the other hostnames are looked up instead of the one of the system, and
+// dnsCacheTTL is how long the addresses which have been looked up are reused.
+var resolvedHostOverrides hostOverrides
+var dnsServer string
+var dnsCacheTTL time.Duration
+
+// shouldUseTor makes all the requests go through the SOCKS5 proxy of the Tor daemon at torProxyAddress, with the
+// requests of every page isolated on their own circuit.
+var shouldUseTor bool
+var torProxyAddress string
+
+// unixSocketPath is the Unix socket to which all the connections of the HTTP requests are made instead of the hosts.
+var unixSocketPath string
+
+// shouldUseHTTP3 makes the requests to the hosts which advertise HTTP/3 go over QUIC.
+var shouldUseHTTP3 bool
+
+// newHTTPClient returns the HTTP client which connects through Tor, resolves the hostnames or speaks HTTP/3 as
+// configured by the flags, or nil if the default one is used.
+func newHTTPClient() *http.Client {
+	if unixSocketPath != "" {
+		if shouldUseTor || shouldUseHTTP3 || newResolver() != nil {
+			logWarningf("ignoring -tor, -http3, -resolve, -dns-server and -dns-cache, since all the connections are made to the Unix socket")
+		}
+		return nil
+	}
+	if shouldUseTor {
+		if len(resolvedHostOverrides) > 0 || dnsServer != "" || dnsCacheTTL != 0 {
+			logWarningf("ignoring -resolve, -dns-server and -dns-cache, since Tor resolves the names of the hosts itself")
+		}
+
+		if shouldUseHTTP3 {
+			logWarningf("ignoring -http3, since Tor only carries TCP connections")
+		}
+
+		return &http.Client{Transport: transport.TorTransport(torProxyAddress, func(request *http.Request) string {
+			if pageNumber, ok := fetch.PageNumberFromContext(request.Context()); ok {
+				return fmt.Sprint("page-", pageNumber)
+			}
+			return ""
+		})}
+	}
+
+	resolver := newResolver()
+	if shouldUseHTTP3 {
+		var fallback http.RoundTripper
+		if resolver != nil {
+			fallback = resolver.Transport()
+		}
+		return &http.Client{Transport: transport.HTTP3(fallback, resolver)}
+	}
+	if resolver == nil {
+		return nil
+	}
+
+	return &http.Client{Transport: resolver.Transport()}
+}
+
+// newResolver returns the resolver of the hostnames configured by the flags, or nil if the one of the system is used.
+func newResolver() *transport.Resolver {
+	if len(resolvedHostOverrides) == 0 && dnsServer == "" && dnsCacheTTL == 0 {
+		return nil
+	}
+
+	return &transport.Resolver{
+		Overrides: resolvedHostOverrides,
+		Server:    dnsServer,
+		CacheTTL:  dnsCacheTTL,
+	}
+}
+
+// shouldFetchFileTransferLinks enables the fetching of the files linked from the posts on FTP and SFTP servers.
+var shouldFetchFileTransferLinks bool
+
+// newFileTransferDial returns the function through which the connections to FTP and SFTP servers are made, which go
+// through Tor or the resolver configured by the flags like the HTTP requests, or nil if they are made directly.
+func newFileTransferDial() transport.DialFunc {
+	if shouldUseTor {
+		dialer, err := proxy.SOCKS5("tcp", torProxyAddress, nil, proxy.Direct)
+		if err != nil {
+			// the connections must never bypass Tor
+			return func(context.Context, string, string) (net.Conn, error) {
+				return nil, err
+			}
+		}
+
+		return dialer.(proxy.ContextDialer).DialContext
+	}
+
+	if resolver := newResolver(); resolver != nil {
+		return resolver.DialContext
+	}
+	return nil
+}
+
+// forumCredentials is a reference to the credentials used for HTTP basic authentication with the host of the forum,
+// of the form `env:NAME` or `keyring:NAME`, where the environment variable or the keyring entry `NAME` contains
+// `user:password`.
+var forumCredentials string
+
+// forumAPIKey and forumBearerToken are references of the same form to the API key and OAuth2 access token with which
+// the requests to the JSON endpoints of the forum are authenticated, the former on behalf of forumAPIUsername.
+var forumAPIKey, forumAPIUsername, forumBearerToken string
+
+// forumLogin is a reference of the same form to the user name and password with which the fetcher logs in to the forum
+// through its login form, logging in again whenever the session expires.
+var forumLogin string
+
+// clearanceCookies is a repeatable flag holding cookies of the form `name=value`, which are presented to the forum to
+// get past the challenge of an anti-bot service. A reference of the form `env:NAME` or `keyring:NAME` may be given
+// instead, whose value is a bundle of cookies in the form of a Cookie header, i.e. `name=value; name=value`.
+type clearanceCookies []*http.Cookie
+
+func (cookies *clearanceCookies) String() string {
+	var pairs []string
+	for _, cookie := range *cookies {
+		pairs = append(pairs, cookie.Name+"=...")
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (cookies *clearanceCookies) Set(value string) error {
+	if strings.HasPrefix(value, "env:") || strings.HasPrefix(value, keyringReferencePrefix) {
+		bundle, err := fetch.ResolveSecret(value)
+		if err != nil {
+			return err
+		}
+
+		for _, pair := range strings.Split(bundle, ";") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			err = cookies.add(pair)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return cookies.add(value)
+}
+
+func (cookies *clearanceCookies) add(pair string) error {
+	name, cookieValue, ok := strings.Cut(pair, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return errorf("invalid cookie %q: expected `name=value`", pair)
+	}
+
+	*cookies = append(*cookies, &http.Cookie{Name: name, Value: strings.TrimSpace(cookieValue)})
+	return nil
+}
+
+// forumClearanceCookies are the cookies obtained by passing the challenge of an anti-bot service (e.g. cf_clearance
+// for Cloudflare) in a browser, and requestUserAgent is the User-Agent of that browser, to which they are bound.
+var forumClearanceCookies clearanceCookies
+var requestUserAgent string
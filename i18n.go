@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// messageCatalogs map the user-facing messages (format strings, command synopses and descriptions, flag usages) to
+// their translations into the supported languages other than English, in which the messages are written. Messages
+// without a translation are output in English.
+var messageCatalogs = map[language.Tag]map[string]string{}
+
+// supportedLanguages are the languages of the messages; English comes first, so that it is the fallback.
+var supportedLanguages = []language.Tag{language.English, language.Bulgarian}
+
+var languageMatcher = language.NewMatcher(supportedLanguages)
+
+// currentMessageCatalog is the catalog of the language of the messages, which is nil for English.
+var currentMessageCatalog map[string]string
+
+// messageLanguage is the language of the messages given by -lang, which overrides the one of the locale.
+var messageLanguage string
+
+// getLocaleLanguage returns the language of the locale of the environment, as given by the first non-empty one of the
+// variables which POSIX consults for the language of messages.
+func getLocaleLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// setMessageLanguage makes the messages be output in the supported language which best matches the given one, which
+// is either a BCP 47 tag (e.g. bg) or a POSIX locale (e.g. bg_BG.UTF-8).
+func setMessageLanguage(name string) error {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	if name == "" || name == "C" || name == "POSIX" {
+		currentMessageCatalog = nil
+		return nil
+	}
+
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return errorf("invalid language %q: %v", name, err)
+	}
+	_, index, confidence := languageMatcher.Match(tag)
+	if confidence == language.No {
+		index = 0
+	}
+	currentMessageCatalog = messageCatalogs[supportedLanguages[index]]
+	return nil
+}
+
+// translate returns the translation of the message into the language of the messages.
+func translate(message string) string {
+	if translation, ok := currentMessageCatalog[message]; ok {
+		return translation
+	}
+	return message
+}
+
+// translatef formats the translation of the format string into the language of the messages. The arguments are
+// formatted as they are, so that numbers and paths are output the same way regardless of the language.
+func translatef(format string, args ...interface{}) string {
+	return fmt.Sprintf(translate(format), args...)
+}
+
+// errorf formats an error like fmt.Errorf, but with the translation of the format string, so that the errors returned
+// by the commands are output in the language of the messages.
+func errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(translate(format), args...)
+}
+
+// printTranslatedDefaults prints the default values of the flags like flag.PrintDefaults, with their usages translated.
+func printTranslatedDefaults(flags *flag.FlagSet) {
+	flags.VisitAll(func(f *flag.Flag) {
+		f.Usage = translate(f.Usage)
+	})
+	flags.PrintDefaults()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// internetArchiveS3URL is the endpoint of the S3-compatible API of the Internet Archive, to which the identifier of an
+// item and the name of a file in it are appended.
+const internetArchiveS3URL = "https://s3.us.archive.org/"
+
+// internetArchiveDetailsURL is the base URL of the pages of the items of the Internet Archive.
+const internetArchiveDetailsURL = "https://archive.org/details/"
+
+// internetArchiveIdentifierPattern matches the identifiers which the Internet Archive accepts for items.
+var internetArchiveIdentifierPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{4,99}$`)
+
+var internetArchiveIdentifier string
+var internetArchiveCollection string
+
+// internetArchiveCredentials is the reference of the form `env:NAME` to the access and secret keys of the S3-compatible
+// API of the Internet Archive, given as `access:secret`.
+var internetArchiveCredentials string
+
+var internetArchiveClient = &http.Client{}
+
+// getInternetArchiveIdentifier returns the identifier of the item to upload the archive as, which defaults to the slug
+// of the title of the topic.
+func getInternetArchiveIdentifier(metadata *exportMetadata) (identifier string, err error) {
+	identifier = internetArchiveIdentifier
+	if identifier == "" {
+		identifier = rewrite.Slug(rewrite.TopicTitle(metadata.Title))
+	}
+	if !internetArchiveIdentifierPattern.MatchString(identifier) {
+		if internetArchiveIdentifier == "" {
+			return "", usageErrorf("no identifier of the Internet Archive item could be derived from the title of the topic; specify one with -ia-identifier")
+		}
+		return "", usageErrorf("invalid identifier of an Internet Archive item: %q (it has to consist of 5 to 100 letters, digits, dots, hyphens and underscores)", identifier)
+	}
+	return
+}
+
+// internetArchiveMetadataValue returns the value of a metadata header of an upload, encoding the values which are not
+// ASCII the way the Internet Archive expects them to be.
+func internetArchiveMetadataValue(value string) string {
+	for _, r := range value {
+		if r < ' ' || r > '~' {
+			return "uri(" + url.PathEscape(value) + ")"
+		}
+	}
+	return value
+}
+
+// uploadToInternetArchive uploads the file as the item with the identifier, creating the item with the metadata of the
+// archive if it does not exist yet.
+func uploadToInternetArchive(identifier, filename string, metadata *exportMetadata) (err error) {
+	accessKey, secretKey, err := fetch.ResolveCredentials(internetArchiveCredentials)
+	if err != nil {
+		return errorf("could not resolve the keys for the Internet Archive: %v", err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return errorf("could not open %s: %v", filename, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return errorf("could not open %s: %v", filename, err)
+	}
+
+	uploadURL := internetArchiveS3URL + url.PathEscape(identifier) + "/" + url.PathEscape(filepath.Base(filename))
+	request, err := http.NewRequestWithContext(interruptContext, http.MethodPut, uploadURL, file)
+	if err != nil {
+		return
+	}
+	request.ContentLength = info.Size()
+	request.Header.Set("Authorization", "LOW "+accessKey+":"+secretKey)
+	request.Header.Set("Content-Type", "application/gzip")
+	request.Header.Set("X-Amz-Auto-Make-Bucket", "1")
+	request.Header.Set("X-Archive-Size-Hint", fmt.Sprint(info.Size()))
+	// there is nothing in a tarball for the Internet Archive to derive other formats from
+	request.Header.Set("X-Archive-Queue-Derive", "0")
+	for name, value := range map[string]string{
+		"mediatype":   "texts",
+		"collection":  internetArchiveCollection,
+		"title":       metadata.Title,
+		"source":      metadata.SourceURL,
+		"description": metadata.description(),
+		"date":        metadata.captureDate(),
+		"subject":     "forum",
+	} {
+		if value != "" {
+			request.Header.Set("X-Archive-Meta-"+name, internetArchiveMetadataValue(value))
+		}
+	}
+
+	response, err := internetArchiveClient.Do(request)
+	if err != nil {
+		return errorf("could not upload %s to the Internet Archive: %v", filename, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return errorf("could not upload %s to the Internet Archive: status %s: %s", filename, response.Status, strings.TrimSpace(string(body)))
+	}
+	return
+}
+
+// exportInternetArchive exports the archive as a tarball and uploads it as an item to the Internet Archive.
+func exportInternetArchive(outputPath string) (err error) {
+	metadata, err := collectExportMetadata()
+	if err != nil {
+		return
+	}
+	identifier, err := getInternetArchiveIdentifier(metadata)
+	if err != nil {
+		return
+	}
+
+	err = exportTarball(outputPath)
+	if err != nil {
+		return
+	}
+
+	logInfof("Uploading %s to the Internet Archive as item %s...", outputPath, identifier)
+	err = uploadToInternetArchive(identifier, outputPath, metadata)
+	if err != nil {
+		return
+	}
+	logSuccessf("Uploaded the archive to %s.", internetArchiveDetailsURL+identifier)
+	return
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPostStep is the number of posts on a page which is suggested if it cannot be detected.
+const defaultPostStep = 15
+
+// wizard asks the questions of the init command on the standard error and reads the answers from the standard input.
+type wizard struct {
+	input *bufio.Reader
+}
+
+// ask asks the question, which is already translated into the language of the messages, and returns the answer, or
+// the default answer if the answer is empty.
+func (w *wizard) ask(question, defaultAnswer string) (answer string, err error) {
+	if defaultAnswer != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, defaultAnswer)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+
+	line, err := w.input.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	answer = strings.TrimSpace(line)
+	if answer == "" {
+		answer = defaultAnswer
+	}
+	return
+}
+
+// confirm asks the yes-or-no question, which is already translated into the language of the messages.
+func (w *wizard) confirm(question string, defaultAnswer bool) (bool, error) {
+	choices := "y/N"
+	if defaultAnswer {
+		choices = "Y/n"
+	}
+
+	answer, err := w.ask(question+" ("+choices+")", "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return defaultAnswer, nil
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// probeForumTopic fetches the page of the topic at the URL and returns its document.
+func probeForumTopic(pageURL string) (doc *html.Node, err error) {
+	forumTopicPageURLBase = pageURL
+	forumEngine = "generic"
+	forumTopicFetcher = nil
+
+	contentReader, _, err := getFetcher().Get(interruptContext, pageURL, "the page of the topic")
+	if err != nil {
+		return
+	}
+	defer contentReader.Close()
+
+	return html.Parse(contentReader)
+}
+
+// parsedDocumentTitle returns the title of the parsed document, if any.
+func parsedDocumentTitle(doc *html.Node) string {
+	var title func(node *html.Node) string
+	title = func(node *html.Node) string {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Title && node.FirstChild != nil {
+			return strings.TrimSpace(node.FirstChild.Data)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if found := title(child); found != "" {
+				return found
+			}
+		}
+		return ""
+	}
+	return title(doc)
+}
+
+// saveProfile adds the profile with the name to the configuration file, replacing the one with the same name, if any,
+// while keeping the rest of the file as it is, comments included.
+func saveProfile(name string, prof *profile) (err error) {
+	var document yaml.Node
+	content, err := os.ReadFile(configFilename)
+	if err == nil {
+		err = yaml.Unmarshal(content, &document)
+		if err != nil {
+			return errorf("could not parse configuration file %s: %v", configFilename, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return errorf("could not read configuration file %s", configFilename)
+	}
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return errorf("configuration file %s is not a mapping", configFilename)
+	}
+
+	var profileNode yaml.Node
+	err = profileNode.Encode(prof)
+	if err != nil {
+		return
+	}
+	profiles := yamlMappingValue(root, "profiles")
+	if profiles == nil {
+		profiles = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "profiles"}, profiles)
+	}
+	if existingProfileNode := yamlMappingValue(profiles, name); existingProfileNode != nil {
+		*existingProfileNode = profileNode
+	} else {
+		profiles.Content = append(profiles.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, &profileNode)
+	}
+
+	content, err = yaml.Marshal(&document)
+	if err != nil {
+		return
+	}
+	err = os.MkdirAll(filepath.Dir(configFilename), 0700)
+	if err == nil {
+		// the profiles may refer to credentials, so the file is only readable by the user
+		err = os.WriteFile(configFilename, content, 0600)
+	}
+	if err != nil {
+		return errorf("could not write configuration file %s: %v", configFilename, err)
+	}
+	return
+}
+
+// yamlMappingValue returns the value of the key in the mapping, or nil if it is not in it.
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// runInitWizard asks for the URL of a topic and the settings for fetching it and saves them as a profile.
+func runInitWizard(w *wizard) (err error) {
+	if configFilename == "" {
+		return usageErrorf("no configuration file to write the profile to; specify one with -config")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return
+	}
+
+	topicURLStr, err := w.ask(translate("URL of a page of the forum topic"), "")
+	if err != nil {
+		return
+	}
+	topicURL, err := url.Parse(fetch.ASCIIURL(topicURLStr))
+	if err != nil || (topicURL.Scheme != "http" && topicURL.Scheme != "https") || topicURL.Host == "" {
+		return usageErrorf("%q is not the URL of a web page", topicURLStr)
+	}
+
+	logInfof("Probing %s...", topicURL.Host)
+	prof := &profile{Engine: "generic", URL: topicURL.String()}
+	doc, err := probeForumTopic(topicURL.String())
+	if err != nil {
+		logWarningf("could not probe the topic (%v); its settings have to be entered by hand", err)
+	}
+
+	var detectedEngine engine.Recognizer
+	var isEngineDetected bool
+	if doc != nil {
+		detectedEngine, isEngineDetected = engine.Detect(doc)
+	}
+	if isEngineDetected {
+		logSuccessf("Detected the %s engine.", detectedEngine.Name())
+		prof.Engine = detectedEngine.Name()
+		prof.URL, err = w.ask(translate("Base URL of the topic"), detectedEngine.TopicURL(topicURL))
+		if err != nil {
+			return
+		}
+	} else {
+		prof.Engine, err = w.ask(translatef("The forum software could not be detected; engine (one of: %s)", strings.Join(engine.Names(), ", ")), prof.Engine)
+		if err != nil {
+			return
+		}
+		forumEngine = prof.Engine
+		err = validateForumEngine()
+		if err != nil {
+			return
+		}
+		question := "Base URL of the topic"
+		if prof.Engine == "generic" || prof.Engine == "phpbb" {
+			question = "Base URL of the pages, to which the offset of the first post on a page is appended (e.g. https://forum.example.com/topic?id=1&offset=)"
+		}
+		prof.URL, err = w.ask(translate(question), prof.URL)
+		if err != nil {
+			return
+		}
+	}
+
+	// the post step is only used by the engines whose pages are addressed by the offset of their first post
+	if prof.Engine == "generic" || prof.Engine == "phpbb" {
+		postStep := defaultPostStep
+		if isEngineDetected {
+			if postCount := len(detectedEngine.ExtractPosts(doc)); postCount > 0 {
+				postStep = postCount
+			}
+		}
+		postStepStr, err := w.ask(translate("Number of posts on a full page"), strconv.Itoa(postStep))
+		if err != nil {
+			return err
+		}
+		postStep, err = strconv.Atoi(postStepStr)
+		if err != nil || postStep <= 0 {
+			return usageErrorf("%q is not a positive number of posts", postStepStr)
+		}
+		prof.PostStep = uint(postStep)
+	}
+
+	prof.Credentials, err = w.ask(translate("Credentials for HTTP basic authentication, of the form env:NAME or keyring:NAME (empty for none)"), "")
+	if err != nil {
+		return
+	}
+	if adapter, _ := engine.Lookup(prof.Engine); adapter != nil {
+		if _, ok := adapter.(engine.Loginer); ok {
+			prof.Login, err = w.ask(translate("Credentials for logging in through the login form, of the form env:NAME or keyring:NAME (empty for none)"), "")
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	forumTopicPageURLBase = prof.URL
+	forumEngine = prof.Engine
+	forumTopicPostStep = prof.PostStep
+	forumCredentials = prof.Credentials
+	forumLogin = prof.Login
+	forumTopicFetcher = nil
+	logInfof("Testing access to the topic...")
+	lastPageNumber, err := getFetcher().LastPageNumber(interruptContext)
+	switch {
+	case getFetcher().HasAuthFailure():
+		logErrorf("authentication with the forum failed")
+	case err != nil:
+		logErrorf("%v", err)
+	default:
+		logSuccessf("The topic has %d pages.", lastPageNumber)
+	}
+	if err != nil || getFetcher().HasAuthFailure() {
+		ok, err := w.confirm(translate("Save the profile anyway?"), false)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	name := "topic"
+	if doc != nil {
+		if slug := rewrite.Slug(rewrite.TopicTitle(parsedDocumentTitle(doc))); slug != "" {
+			name = slug
+		}
+	}
+	name, err = w.ask(translate("Name of the profile"), name)
+	if err != nil {
+		return
+	}
+	if _, ok := cfg.Profiles[name]; ok {
+		ok, err := w.confirm(translatef("Profile %s already exists; replace it?", name), false)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	prof.TargetDir, err = w.ask(translate("Target directory of the archive (empty for the current directory of every run)"), "")
+	if err != nil {
+		return
+	}
+
+	err = saveProfile(name, prof)
+	if err != nil {
+		return
+	}
+	logSuccessf("Saved profile %s to %s; fetch the topic with: fetch-forum-topic fetch %s 1-all", name, configFilename, name)
+	return nil
+}
+
+var initCommand = &command{
+	name:      "init",
+	synopsis:  "create a profile for a forum topic interactively",
+	arguments: "",
+	description: "Ask for the URL of a forum topic, probe it to detect the software of the forum and the number of posts on a page, " +
+		"test access to it with the credentials, if any, and save the settings as a profile in the configuration file, " +
+		"whose name can then be given to the other commands in place of the URL",
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		err := runInitWizard(&wizard{input: bufio.NewReader(os.Stdin)})
+		if errors.Is(err, io.EOF) {
+			return usageErrorf("the questions were not answered")
+		}
+		return err
+	},
+}
@@ -0,0 +1,139 @@
+package forumtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+)
+
+// GoldenHost replaces the host of the mock forum, whose port changes on every start, in the paths and contents of
+// golden copies of archives.
+const GoldenHost = "forum.test"
+
+// portableNameReplacer escapes the characters which cannot appear in filenames on every operating system, such as the
+// question mark of the query strings of pages, in the names of golden files, so that they can be checked out anywhere.
+// The percent sign is escaped as well so that distinct names stay distinct.
+var portableNameReplacer = strings.NewReplacer(
+	"%", "%25",
+	"?", "%3F",
+	"*", "%2A",
+	":", "%3A",
+	"<", "%3C",
+	">", "%3E",
+	"|", "%7C",
+	`"`, "%22",
+	`\`, "%5C",
+)
+
+// CompareGolden compares the pages fetched into the archive in dir, together with their resources, to the golden copy
+// in goldenDir and returns a description of every mismatch. The bookkeeping of the archive and the metadata sidecars
+// are not compared, since they depend on the circumstances of the fetch. If update is set, the golden copy is
+// replaced with the archive instead.
+func (s *Server) CompareGolden(dir, goldenDir string, update bool) (mismatches []string, err error) {
+	actual, err := s.readPageFiles(dir)
+	if err != nil {
+		return
+	}
+
+	if update {
+		err = os.RemoveAll(goldenDir)
+		if err != nil {
+			return nil, fmt.Errorf("could not remove golden directory %s", goldenDir)
+		}
+
+		for name, content := range actual {
+			filename := filepath.Join(goldenDir, filepath.FromSlash(name))
+			err = os.MkdirAll(filepath.Dir(filename), os.ModePerm)
+			if err == nil {
+				err = os.WriteFile(filename, content, 0o644)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not write golden file %s", filename)
+			}
+		}
+		return
+	}
+
+	expected, err := readFiles(goldenDir)
+	if err != nil {
+		return
+	}
+
+	for name, expectedContent := range expected {
+		actualContent, ok := actual[name]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s: missing", name))
+		case !bytes.Equal(actualContent, expectedContent):
+			mismatches = append(mismatches, fmt.Sprintf("%s: content differs:\n--- expected\n%s\n--- actual\n%s", name, expectedContent, actualContent))
+		}
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s: unexpected", name))
+		}
+	}
+	return
+}
+
+// readPageFiles reads the files in the page directories of the archive in dir with the host of the mock forum
+// replaced in their slash-separated names and contents and the names made portable by portableNameReplacer.
+func (s *Server) readPageFiles(dir string) (files map[string][]byte, err error) {
+	serverURL, err := url.Parse(s.URL)
+	if err != nil {
+		return
+	}
+
+	allFiles, err := readFiles(dir)
+	if err != nil {
+		return
+	}
+
+	files = map[string][]byte{}
+	for name, content := range allFiles {
+		pageDir, _, _ := strings.Cut(name, "/")
+		if _, err := strconv.ParseUint(pageDir, 10, 0); err != nil || strings.HasSuffix(name, archive.MetadataFilenameSuffix) {
+			continue
+		}
+
+		name = strings.ReplaceAll(name, "/"+serverURL.Hostname()+"/", "/"+GoldenHost+"/")
+		content = bytes.ReplaceAll(content, []byte(serverURL.Host), []byte(GoldenHost))
+		files[portableNameReplacer.Replace(name)] = content
+	}
+	return
+}
+
+// readFiles reads the files under dir, keyed by their slash-separated paths relative to it.
+func readFiles(dir string) (files map[string][]byte, err error) {
+	files = map[string][]byte{}
+	err = filepath.WalkDir(dir, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(name)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %s: %v", dir, err)
+	}
+
+	return
+}
@@ -0,0 +1,205 @@
+// Package forumtest provides a mock forum serving a synthetic multi-page topic together with the resources embedded in
+// its pages, and utilities for comparing fetched archives to golden copies of them.
+package forumtest
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+)
+
+// TopicPath is the path under which the pages of the topic are served; the offset of the first post on a page is
+// passed in the `start` query parameter.
+const TopicPath = "/viewtopic.php"
+
+// Topic describes the synthetic topic served by the mock forum.
+type Topic struct {
+	// Pages is the number of pages of the topic.
+	Pages uint
+	// PostStep is the number of posts on a single page.
+	PostStep uint
+}
+
+// DefaultTopic is a small topic which exercises every kind of reference the fetcher rewrites.
+var DefaultTopic = Topic{
+	Pages:    3,
+	PostStep: 5,
+}
+
+// failure is an injected failure of the requests for a path.
+type failure struct {
+	status    int
+	remaining int
+}
+
+// Server is a mock forum. Its pages link to a stylesheet, which in turn refers to an image and a font, to images
+// (one of them behind a redirect), to an attachment and to the other pages of the topic.
+type Server struct {
+	*httptest.Server
+	Topic Topic
+
+	mutex    sync.Mutex
+	failures map[string]*failure
+	requests map[string]int
+}
+
+// NewServer starts a mock forum serving the topic. It has to be closed once it is no longer needed.
+func NewServer(topic Topic) *Server {
+	server := &Server{
+		Topic:    topic,
+		failures: map[string]*failure{},
+		requests: map[string]int{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(TopicPath, server.servePage)
+	mux.HandleFunc("/styles/forum.css", serveContent("text/css", stylesheet))
+	mux.HandleFunc("/styles/background.png", serveContent("image/png", pixelPNG))
+	mux.HandleFunc("/styles/forum.woff2", serveContent("font/woff2", "wOF2"))
+	mux.HandleFunc("/images/avatar.png", serveContent("image/png", pixelPNG))
+	mux.HandleFunc("/images/smiley.gif", serveContent("image/gif", pixelGIF))
+	mux.HandleFunc("/images/logo", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/images/avatar.png", http.StatusFound)
+	})
+	mux.HandleFunc("/download/file.php", serveContent("application/octet-stream", "attachment"))
+	mux.HandleFunc("/favicon.ico", serveContent("image/png", pixelPNG))
+	server.Server = httptest.NewServer(server.countAndInjectFailures(mux))
+
+	return server
+}
+
+// TopicURL returns the base URL of the pages of the topic, to which the offset of the first post on a page is appended.
+func (s *Server) TopicURL() string {
+	return s.URL + TopicPath + "?start="
+}
+
+// PageURL returns the URL of the page of the topic with the given number, starting from 1.
+func (s *Server) PageURL(pageNumber uint) string {
+	return fmt.Sprintf("%s%d", s.TopicURL(), s.Topic.PostStep*(pageNumber-1))
+}
+
+// FailNext makes the next given number of requests for the path fail with the status code.
+func (s *Server) FailNext(path string, times int, status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures[path] = &failure{
+		status:    status,
+		remaining: times,
+	}
+}
+
+// Requests returns the number of requests for the path which the mock forum has received, including the failed ones.
+func (s *Server) Requests(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.requests[path]
+}
+
+func (s *Server) countAndInjectFailures(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		s.requests[r.URL.Path]++
+		injectedFailure, ok := s.failures[r.URL.Path]
+		if ok && injectedFailure.remaining > 0 {
+			injectedFailure.remaining--
+		} else {
+			ok = false
+		}
+		s.mutex.Unlock()
+
+		if ok {
+			http.Error(w, http.StatusText(injectedFailure.status), injectedFailure.status)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) servePage(w http.ResponseWriter, r *http.Request) {
+	postOffset, err := strconv.ParseUint(r.URL.Query().Get("start"), 10, 0)
+	if err != nil || s.Topic.PostStep == 0 || uint(postOffset)%s.Topic.PostStep != 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	pageNumber := uint(postOffset)/s.Topic.PostStep + 1
+	if pageNumber > s.Topic.Pages {
+		http.NotFound(w, r)
+		return
+	}
+
+	page := &pageData{
+		Number: pageNumber,
+	}
+	for number := uint(1); number <= s.Topic.Pages; number++ {
+		page.Pages = append(page.Pages, pageLink{
+			Number: number,
+			Offset: s.Topic.PostStep * (number - 1),
+		})
+	}
+	for number := postOffset + 1; number <= postOffset+uint64(s.Topic.PostStep); number++ {
+		page.Posts = append(page.Posts, uint(number))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = pageTemplate.Execute(w, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func serveContent(contentType, content string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write([]byte(content))
+	}
+}
+
+type pageLink struct {
+	Number uint
+	Offset uint
+}
+
+type pageData struct {
+	Number uint
+	Pages  []pageLink
+	Posts  []uint
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Synthetic topic - page {{.Number}}</title>
+<link rel="stylesheet" href="styles/forum.css">
+<style>.logo { background: url("/images/smiley.gif"); }</style>
+</head>
+<body>
+<div class="pagination">{{range .Pages}}<a href="viewtopic.php?start={{.Offset}}">{{.Number}}</a> {{end}}</div>
+<img class="logo" src="/images/logo" alt="logo">
+{{range .Posts}}<div class="post" id="p{{.}}">
+<p class="author"><img src="images/avatar.png" alt=""> <span class="username">user{{.}}</span></p>
+<div class="content">Post number {{.}}. <img src="images/smiley.gif" alt=":)" style="border: 0"></div>
+</div>
+{{end}}<a href="download/file.php?id=1">attachment</a>
+</body>
+</html>
+`))
+
+const stylesheet = `@font-face { font-family: "Forum"; src: url(forum.woff2) format("woff2"); }
+body { background: url('background.png'); font-family: "Forum", sans-serif; }
+`
+
+// pixelPNG and pixelGIF are transparent images of a single pixel.
+const (
+	pixelPNG = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89" +
+		"\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82"
+	pixelGIF = "GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff!\xf9\x04\x01\x00\x00\x00\x00," +
+		"\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;"
+)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+// keyringReferencePrefix starts the references to the entries of the keyring of the operating system. In a profile, the
+// prefix alone refers to the entry named after the profile and the setting, e.g. `topic/login` for the login setting
+// of the profile topic.
+const keyringReferencePrefix = "keyring:"
+
+// expandKeyringReference completes a reference to the keyring without the name of the entry to the entry of the
+// setting of the profile.
+func expandKeyringReference(reference, profileName, setting string) string {
+	if reference != keyringReferencePrefix {
+		return reference
+	}
+	return keyringReferencePrefix + profileName + "/" + setting
+}
+
+// expandKeyringReferences completes the references to the keyring without the name of the entry in the settings of the
+// profile with the name.
+func (prof *profile) expandKeyringReferences(name string) {
+	prof.Credentials = expandKeyringReference(prof.Credentials, name, "credentials")
+	prof.APIKey = expandKeyringReference(prof.APIKey, name, "apiKey")
+	prof.BearerToken = expandKeyringReference(prof.BearerToken, name, "bearerToken")
+	prof.Login = expandKeyringReference(prof.Login, name, "login")
+	prof.ClearanceCookies = expandKeyringReference(prof.ClearanceCookies, name, "clearanceCookies")
+	prof.SlackWebhook = expandKeyringReference(prof.SlackWebhook, name, "slackWebhook")
+	prof.DiscordWebhook = expandKeyringReference(prof.DiscordWebhook, name, "discordWebhook")
+}
+
+// readSecret reads a secret from the terminal without echoing it or, if the standard input is not a terminal, reads
+// the first line of the standard input.
+func readSecret(entry string) (secret string, err error) {
+	stdinFd := int(os.Stdin.Fd())
+	if term.IsTerminal(stdinFd) {
+		fmt.Fprint(os.Stderr, translatef("Secret for %s: ", entry))
+		secretBytes, err := term.ReadPassword(stdinFd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(secretBytes), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+var keyringCommand = &command{
+	name:      "keyring",
+	synopsis:  "store secrets in the keyring of the operating system",
+	arguments: "set|delete entry",
+	description: "Store the secret read from the terminal (or the standard input) in the keyring of the operating system " +
+		"(the Secret Service on Linux, the Keychain on macOS and the Credential Manager on Windows) as the entry, or delete the entry, " +
+		"which can then be referenced as keyring:entry by the flags and the settings of the profiles which take references to secrets " +
+		"(credentials and logins as user:password, API keys, tokens, webhooks and bundles of clearance cookies as name=value; name=value); " +
+		"in a profile, the reference keyring: alone refers to the entry named after the profile and the setting, e.g. topic/login",
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) != 2 {
+			return usageErrorf("an action (set or delete) and the name of an entry must be specified")
+		}
+		action, entry := args[0], args[1]
+
+		switch action {
+		case "set":
+			secret, err := readSecret(entry)
+			if err != nil {
+				return errorf("could not read the secret: %v", err)
+			}
+			if secret == "" {
+				return usageErrorf("empty secret")
+			}
+			err = keyring.Set(fetch.KeyringService, entry, secret)
+			if err != nil {
+				return errorf("could not store keyring entry %s: %v", entry, err)
+			}
+			logSuccessf("Stored keyring entry %s; refer to it as %s%s.", entry, keyringReferencePrefix, entry)
+		case "delete":
+			err := keyring.Delete(fetch.KeyringService, entry)
+			if err == keyring.ErrNotFound {
+				return usageErrorf("keyring entry %s does not exist", entry)
+			}
+			if err != nil {
+				return errorf("could not delete keyring entry %s: %v", entry, err)
+			}
+			logSuccessf("Deleted keyring entry %s.", entry)
+		default:
+			return usageErrorf("unknown keyring action: %s (it has to be one of: set, delete)", action)
+		}
+		return nil
+	},
+}
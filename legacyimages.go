@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	_ "github.com/gen2brain/avif"
+	_ "golang.org/x/image/webp"
+)
+
+// shouldConvertModernImages enables the conversion of the WebP and AVIF images to PNG or JPEG.
+var shouldConvertModernImages bool
+
+// modernImageTypes are the content types of the images which are converted by -convert-images.
+var modernImageTypes = []string{"image/webp", "image/avif"}
+
+// convertedJPEGQuality is the quality at which the opaque lossy images are encoded as JPEG.
+const convertedJPEGQuality = 90
+
+// isLosslessWebP reports whether the content is a WebP image in the lossless format.
+func isLosslessWebP(content []byte) bool {
+	return len(content) >= 16 && string(content[0:4]) == "RIFF" && string(content[8:12]) == "WEBP" && string(content[12:16]) == "VP8L"
+}
+
+// convertModernImage converts the WebP or AVIF image to PNG if it is lossless or has transparency and to JPEG
+// otherwise, so that it can be viewed in old browsers and by e-readers. Animated images cannot be decoded, so they are
+// left as they are.
+func convertModernImage(contentType string, content []byte) (convertedContentType string, convertedContent []byte, err error) {
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return "", nil, errorf("could not decode %s image: %v", contentType, err)
+	}
+
+	var converted bytes.Buffer
+	if opaqueImage, ok := img.(interface{ Opaque() bool }); ok && opaqueImage.Opaque() && !isLosslessWebP(content) {
+		convertedContentType = "image/jpeg"
+		err = jpeg.Encode(&converted, img, &jpeg.Options{Quality: convertedJPEGQuality})
+	} else {
+		convertedContentType = "image/png"
+		err = png.Encode(&converted, img)
+	}
+	if err != nil {
+		return "", nil, errorf("could not encode %s image: %v", convertedContentType, err)
+	}
+	return convertedContentType, converted.Bytes(), nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// linkCheckConcurrency is the number of the targets of links which are checked at once.
+const linkCheckConcurrency = 8
+
+// Statuses of the targets of external links as determined by the check.
+const (
+	linkStatusLive    = "live"
+	linkStatusDead    = "dead"
+	linkStatusUnknown = "unknown"
+)
+
+// externalLink is a link in a post of the archive to a target which has not been archived.
+type externalLink struct {
+	URL    string `json:"url"`
+	Text   string `json:"text,omitempty"`
+	Page   uint   `json:"page"`
+	PostID string `json:"postID,omitempty"`
+	// Status is the status of the target of the link if it has been checked: live, dead or unknown (e.g. if the
+	// request timed out or the server refused to tell), in which case StatusCode is the HTTP status code of the
+	// response, if any.
+	Status     string `json:"status,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+}
+
+// isArchivableLinkScheme reports whether the links with the scheme refer to resources on the network, unlike e.g.
+// `mailto:` links.
+func isArchivableLinkScheme(scheme string) bool {
+	switch scheme {
+	case "http", "https", "ftp", "sftp":
+		return true
+	}
+
+	return false
+}
+
+// findExternalLinks returns the links in the posts on the archived pages of the topic in the target directory, whose
+// settings have to be loaded, whose targets have not been archived along with them; the links to the local copies of
+// the archived resources are relative, while the rest were made absolute when the pages were fetched.
+func findExternalLinks() (links []*externalLink, err error) {
+	// the links are filtered once the map of the original URLs of the archive is known
+	var candidateLinks []*externalLink
+	var candidateURLs []string
+	deduplicator := newPostDeduplicator()
+	urlMap, err := walkArchivedPages(func(pageNumber uint, filename string, doc *html.Node) {
+		posts, _ := deduplicator.filter(getForumEngine().ExtractPosts(doc))
+		for _, post := range posts {
+			for _, link := range post.Links {
+				linkURL, err := url.Parse(link.URL)
+				if err != nil || !linkURL.IsAbs() || !isArchivableLinkScheme(linkURL.Scheme) {
+					continue
+				}
+				linkURL.Fragment = ""
+
+				candidateLinks = append(candidateLinks, &externalLink{
+					URL:    link.URL,
+					Text:   link.Text,
+					Page:   pageNumber,
+					PostID: post.ID,
+				})
+				candidateURLs = append(candidateURLs, linkURL.String())
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	links = []*externalLink{}
+	for i, link := range candidateLinks {
+		if _, ok := urlMap.LookupFilename(candidateURLs[i]); !ok {
+			links = append(links, link)
+		}
+	}
+	return
+}
+
+// linkCheckResult is the outcome of the check of the target of a link.
+type linkCheckResult struct {
+	status     string
+	statusCode int
+}
+
+// checkExternalLinks determines whether the targets of the links are still live, checking every URL once.
+func checkExternalLinks(links []*externalLink) {
+	results := map[string]*linkCheckResult{}
+	var urlStrs []string
+	for _, link := range links {
+		if _, ok := results[link.URL]; !ok {
+			results[link.URL] = nil
+			urlStrs = append(urlStrs, link.URL)
+		}
+	}
+
+	fetcher := getFetcher()
+	var mutex sync.Mutex
+	var workers sync.WaitGroup
+	slots := make(chan struct{}, linkCheckConcurrency)
+	for _, urlStr := range urlStrs {
+		if interruptContext.Err() != nil {
+			break
+		}
+
+		slots <- struct{}{}
+		workers.Add(1)
+		go func(urlStr string) {
+			defer func() {
+				<-slots
+				workers.Done()
+			}()
+
+			result := &linkCheckResult{status: linkStatusUnknown}
+			statusCode, err := fetcher.LinkStatus(interruptContext, urlStr)
+			var dnsErr *net.DNSError
+			switch {
+			case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+				result.status = linkStatusDead
+			case err != nil:
+			case statusCode < http.StatusBadRequest:
+				result.status = linkStatusLive
+			case statusCode == http.StatusNotFound || statusCode == http.StatusGone:
+				result.status = linkStatusDead
+			}
+			result.statusCode = statusCode
+			logDebugf("%s: %s", urlStr, result.status)
+
+			mutex.Lock()
+			results[urlStr] = result
+			mutex.Unlock()
+		}(urlStr)
+	}
+	workers.Wait()
+
+	for _, link := range links {
+		if result := results[link.URL]; result != nil {
+			link.Status, link.StatusCode = result.status, result.statusCode
+		}
+	}
+}
+
+var shouldCheckLinks bool
+var isLinkReportJSON bool
+
+var linksCommand = &command{
+	name:      "links",
+	synopsis:  "list the external links in the posts of the archive",
+	arguments: "",
+	description: "List every link in the posts of the archive in the target directory whose target has not been archived, " +
+		"with its anchor text, page and post, so as to show what context the archive still depends on externally. " +
+		"The lines of the list are tab-separated: page, post, status (if checked), URL and anchor text",
+	setFlags: func(flags *flag.FlagSet) {
+		shouldCheckLinks = false
+		flags.BoolVar(&shouldCheckLinks, "check", shouldCheckLinks, "check whether the target of every link is still live with a HEAD request (or a GET request for its first byte, if the server does not allow HEAD requests), which is dead if it is not found or its host does not exist and unknown if the check is inconclusive")
+
+		isLinkReportJSON = false
+		flags.BoolVar(&isLinkReportJSON, "json", isLinkReportJSON, "write the list as a JSON array of objects instead")
+
+		setDuplicatePostsFlag(flags)
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		err := checkDuplicatePostsMode()
+		if err != nil {
+			return err
+		}
+
+		_, err = loadTopicSettings()
+		if err != nil {
+			return err
+		}
+
+		links, err := findExternalLinks()
+		if err != nil {
+			return err
+		}
+		if shouldCheckLinks {
+			checkExternalLinks(links)
+		}
+
+		if isLinkReportJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "\t")
+			err = encoder.Encode(links)
+			if err != nil {
+				return errorf("could not write the list of links: %v", err)
+			}
+			return nil
+		}
+
+		for _, link := range links {
+			status := link.Status
+			if link.StatusCode != 0 {
+				status = fmt.Sprint(status, " (", link.StatusCode, ")")
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\t%s\n", link.Page, link.PostID, status, link.URL, strings.ReplaceAll(link.Text, "\t", " "))
+		}
+		return nil
+	},
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"golang.org/x/text/language"
+)
+
+func init() {
+	messageCatalogs[language.Bulgarian] = map[string]string{
+		// usage
+		usageHeader: `употреба: %s [-config файл] [-lang език] [-log-file файл] [-log-file-max-size размер] [-log-format формат] [-log-level ниво] [-no-color] [-q] [-t директория] [-v] команда [флагове] [аргументи]
+
+Предназначението на този скрипт е да изтегли всички страници в зададените диапазони от желаната тема във форум според дадения базов шаблон на URL
+и да поддържа получения архив.
+Изпълнете '%s команда -h' за употребата на определена команда.
+
+Команди:
+`,
+		"Global flags:": "Глобални флагове:",
+		"Flags:":        "Флагове:",
+		"usage: %s [global flags] %s [flags] %s\n\n%s.\n": "употреба: %s [глобални флагове] %s [флагове] %s\n\n%s.\n",
+		exitCodesDescription: `Код на изход:
+  0    цялата поискана работа е извършена успешно
+  1    невалидни флагове, аргументи или конфигурация
+  2    фатална грешка
+  3    някои страници не можаха да бъдат изтеглени (или срокът на изпълнението изтече); те са записани в списъка с неуспешни изтегляния и ще бъдат изтеглени отново при следващото изпълнение
+  4    удостоверяването пред форума е неуспешно или услуга против ботове е поставила заявките под съмнение
+  5    няма свободно място на устройството на целевата директория
+  130  прекъснато от сигнал
+`,
+		"error: ":                     "грешка: ",
+		"warning: ":                   "предупреждение: ",
+		"error: unknown command:":     "грешка: непозната команда:",
+		"error: no command specified": "грешка: не е зададена команда",
+		"Run '%s -h' for usage.\n":    "Изпълнете '%s -h' за употребата.\n",
+		"Run '%s %s -h' for usage.\n": "Изпълнете '%s %s -h' за употребата.\n",
+
+		// global flags
+		"`directory` where the pages will be downloaded":                                                                     "`директория`, в която ще бъдат изтеглени страниците",
+		"configuration `file` with named profiles of forum topics and the settings of the mail server used by -email-report": "конфигурационен `файл` с именувани профили на теми във форуми и настройките на пощенския сървър, използван от -email-report",
+		"enable outputting of verbose messages (same as -log-level debug)":                                                   "извеждане на подробни съобщения (същото като -log-level debug)",
+		"suppress all messages except errors (same as -log-level error)":                                                     "потискане на всички съобщения освен грешките (същото като -log-level error)",
+		"`format` of the log messages: text or json (one object per line)":                                                   "`формат` на съобщенията: text или json (по един обект на ред)",
+		"also write all messages, including the debug ones, to `file` (relative to the target directory), which is rotated when it grows larger than the size passed to -log-file-max-size": "записване на всички съобщения, включително тези за отстраняване на грешки, и във `файл` (спрямо целевата директория), който се ротира, когато стане по-голям от размера, подаден на -log-file-max-size",
+		"do not color the messages even if the output is a terminal":                                                       "без оцветяване на съобщенията дори ако изходът е терминал",
+		"`language` of the messages, e.g. en or bg, instead of the one of the locale given by LC_ALL, LC_MESSAGES or LANG": "`език` на съобщенията, напр. en или bg, вместо този на локала, зададен от LC_ALL, LC_MESSAGES или LANG",
+		"`level` of the least severe messages to output: error, warn, info or debug":                                       "`ниво` на най-малко сериозните съобщения за извеждане: error, warn, info или debug",
+		"`size` beyond which the log file is rotated, keeping the last five rotated files (0 means unlimited)":             "`размер`, над който файлът на дневника се ротира, като се пазят последните пет ротирани файла (0 означава без ограничение)",
+
+		// command synopses
+		"fetch pages of a forum topic":                                        "изтегляне на страници от тема във форум",
+		"continue the last fetch in the target directory":                     "продължаване на последното изтегляне в целевата директория",
+		"estimate the size and duration of a fetch":                           "оценка на размера и продължителността на изтегляне",
+		"compare the archive with another one":                                "сравняване на архива с друг",
+		"export the archive in another format":                                "експортиране на архива в друг формат",
+		"create a profile for a forum topic interactively":                    "интерактивно създаване на профил за тема във форум",
+		"list the external links in the posts of the archive":                 "изброяване на външните връзки в мненията от архива",
+		"archive the pages browsed through a local proxy":                     "архивиране на страниците, разглеждани през локален прокси сървър",
+		"list the failures of the last run and fetch again the selected ones": "изброяване на неуспехите от последното изпълнение и повторно изтегляне на избраните",
+		"search the posts on the archived pages":                              "търсене в мненията на архивираните страници",
+		"update the program to its latest release":                            "обновяване на програмата до последното ѝ издание",
+		"serve the archive over HTTP":                                         "обслужване на архива по HTTP",
+		"report statistics about the posts in the archive":                    "отчитане на статистика за мненията в архива",
+		"list the edits and deletions of the posts noticed across the runs":   "извеждане на редакциите и изтриванията на мненията, забелязани при изпълненията",
+		"link a user or print-ready stylesheet from the archived pages":       "свързване на потребителски стил или стил за печат от архивираните страници",
+		"generate a hardened systemd service unit running a command":          "генериране на защитен systemd модул на услуга, изпълняващ команда",
+		"check the integrity of the archive":                                  "проверка на целостта на архива",
+		"fetch again the broken pages of the archive":                         "повторно изтегляне на повредените страници от архива",
+		"store secrets in the keyring of the operating system":                "съхраняване на тайни в ключодържателя на операционната система",
+		"print the version of the program":                                    "извеждане на версията на програмата",
+
+		// command descriptions
+		"Ask for the URL of a forum topic, probe it to detect the software of the forum and the number of posts on a page, test access to it with the credentials, if any, and save the settings as a profile in the configuration file, whose name can then be given to the other commands in place of the URL":                                                         "Пита за URL на тема във форум, проучва я, за да открие софтуера на форума и броя на мненията на страница, проверява достъпа до нея с данните за удостоверяване, ако има такива, и записва настройките като профил в конфигурационния файл, чието име след това може да бъде подадено на другите команди вместо URL",
+		"Check that every page of the archive in the target directory and every resource it references have been stored successfully":                                                                                                                                                                                                                                    "Проверка дали всяка страница от архива в целевата директория и всеки ресурс, към който тя препраща, са съхранени успешно",
+		"Check the releases of the program on GitHub and, if a newer one than the running version is available, download its binary for the current platform, verify it against the SHA-256 checksums of the release (and the Ed25519 signature of the checksums, if the program has been built with the public key of the releases) and replace the executable with it": "Проверка на изданията на програмата в GitHub и, ако е налично по-ново от изпълняваната версия, изтегляне на изпълнимия ѝ файл за текущата платформа, проверката му спрямо контролните суми SHA-256 на изданието (и подписа Ed25519 на контролните суми, ако програмата е компилирана с публичния ключ на изданията) и замяна на изпълнимия файл с него",
+		"Compare the archive in the target directory with the one in the given directory, printing the files which were added (`+`), removed (`-`) or modified (`M`) in the latter":                                                                                                                                                                                      "Сравняване на архива в целевата директория с този в дадената директория, като се извеждат файловете, които са добавени (`+`), премахнати (`-`) или променени (`M`) във втория",
+		"Continue the last fetch in the target directory with the same URL and page ranges, fetching again the pages which failed and the ones which have not been fetched yet":                                                                                                                                                                                          "Продължаване на последното изтегляне в целевата директория със същия URL и диапазони от страници, като отново се изтеглят страниците, които не са успели, и тези, които все още не са изтеглени",
+		"Fetch again every page of the archive in the target directory for which the `verify` command reports problems":                                                                                                                                                                                                                                                  "Повторно изтегляне на всяка страница от архива в целевата директория, за която командата `verify` съобщава за проблеми",
+		"Fetch only the first, the middle and the last page in the specified ranges and report the expected size and duration of fetching all of them, without writing anything":                                                                                                                                                                                         "Изтегляне само на първата, средната и последната страница в зададените диапазони и отчитане на очаквания размер и продължителност на изтеглянето на всички тях, без да се записва нищо",
+		"Fetch the pages in the specified ranges from the forum topic at the base template URL. Before doing anything else, pages which could not be downloaded successfully during the last run are fetched again.\nInstead of the URL, the name of a profile in the configuration file may be given, whose settings serve as defaults for the flags.\nIf the URL is `-`, the full URLs of the pages are read from the standard input, one per line, and numbered in order starting from 1; without page ranges, all of them are fetched.\nA page range specification looks like this: `first..last`, where `first` is the number of the first page and `last` is the number of the last one. Either of them may be omitted, meaning the first or the last page of the topic respectively; `all` also stands for the last page, while negative numbers count from the end of the topic (e.g. `-5..` means the final five pages). Appending `:step` selects only every step-th page of the range (e.g. `1..100:5`), a single number `N` selects only that page within a comma-separated list but means `1..N` when it makes up a whole argument, and several specifications may be joined with commas in one argument.\nIf no page ranges are specified, no new pages will be fetched; nevertheless, failed downloads will still be re-attempted.\nPages which have already been fetched are skipped unless -f is given or their archived copy is older than the age passed to -refresh-older-than.\nWith -n, the plan for every page is printed as tab-separated lines of the form `page  action  URL` and nothing is fetched or written": "Изтегляне на страниците в зададените диапазони от темата във форума на базовия шаблонен URL. Преди всичко друго отново се изтеглят страниците, които не са могли да бъдат изтеглени успешно при последното изпълнение.\nВместо URL може да бъде подадено името на профил в конфигурационния файл, чиито настройки служат за стойности по подразбиране на флаговете.\nАко URL е `-`, пълните URL адреси на страниците се четат от стандартния вход, по един на ред, и се номерират поред, започвайки от 1; без диапазони от страници се изтеглят всички.\nДиапазонът от страници се задава така: `first..last`, където `first` е номерът на първата страница, а `last` е номерът на последната. Всеки от тях може да бъде пропуснат, което означава съответно първата или последната страница на темата; `all` също означава последната страница, а отрицателните числа се броят от края на темата (напр. `-5..` означава последните пет страници). Добавянето на `:step` избира само всяка step-та страница от диапазона (напр. `1..100:5`), едно число `N` избира само тази страница в списък, разделен със запетаи, но означава `1..N`, когато съставлява цял аргумент, а няколко диапазона могат да бъдат обединени със запетаи в един аргумент.\nАко не са зададени диапазони от страници, нови страници няма да бъдат изтеглени; въпреки това неуспешните изтегляния ще бъдат опитани отново.\nСтраниците, които вече са изтеглени, се пропускат, освен ако не е зададен -f или архивираното им копие е по-старо от възрастта, подадена на -refresh-older-than.\nС -n планът за всяка страница се извежда като редове, разделени с табулации, във вида `page  action  URL`, и нищо не се изтегля или записва",
+		"List every link in the posts of the archive in the target directory whose target has not been archived, with its anchor text, page and post, so as to show what context the archive still depends on externally. The lines of the list are tab-separated: page, post, status (if checked), URL and anchor text":                                                                                                                                                                                                                                                                                                                                                                                 "Изброяване на всяка връзка в мненията от архива в целевата директория, чиято цел не е архивирана, с нейния текст, страница и мнение, за да се покаже от какъв външен контекст все още зависи архивът. Редовете на списъка са разделени с табулации: страница, мнение, състояние (ако е проверено), URL и текст на връзката",
+		"List the change log of the posts in the target directory which have been tracked with -track-posts: the posts whose text differed from the one seen during an earlier run (edited), which vanished from the page on which they were last seen (deleted) and which reappeared after they had been deemed deleted (restored), in the order in which the changes were noticed":                                                                                                                                                                                                                                                                                                                     "Извеждане на дневника на промените на мненията в целевата директория, които са проследявани с -track-posts: мненията, чийто текст се е различавал от видения при по-ранно изпълнение (редактирани), които са изчезнали от страницата, на която са били видени за последно (изтрити), и които са се появили отново, след като са били сметнати за изтрити (възстановени), в реда, в който са забелязани промените",
+		"List the pages and the resources embedded in them which could not be fetched during the last run in the target directory, along with the kinds of and reasons for the failures, and fetch again the pages which failed or whose resources failed: all of them, only the ones whose failures are of the kinds given by -kind, or only the given pages and the pages of the given resources. Unlike resume, which retries every failed page, the failures which have not been selected are kept for a later run":                                                                                                                                                                                  "Изброяване на страниците и вградените в тях ресурси, които не са могли да бъдат изтеглени при последното изпълнение в целевата директория, заедно с видовете и причините за неуспехите, и повторно изтегляне на страниците, които не са успели или чиито ресурси не са успели: всички, само тези, чиито неуспехи са от видовете, зададени с -kind, или само дадените страници и страниците на дадените ресурси. За разлика от resume, която опитва отново всяка неуспешна страница, неуспехите, които не са избрани, се запазват за следващо изпълнение",
+		"Print a systemd service unit which runs the given command line (resume by default) of this program in the target directory, along with the global flags -config and -log-level if they have been set, for unattended archiving. The service is sandboxed so that it can only connect to the network and write to the target directory, and it notifies systemd when it is ready, reports its progress as its status and pings the watchdog of systemd, which restarts the long-running commands (record and serve) if they stop responding and stops the fetches which stop making progress. With -on-calendar, a timer unit which runs the service on the given schedule is generated as well": "Извеждане на модул на услуга на systemd, който изпълнява дадения команден ред (по подразбиране resume) на тази програма в целевата директория, заедно с глобалните флагове -config и -log-level, ако са зададени, за архивиране без надзор. Услугата е изолирана така, че да може само да се свързва с мрежата и да пише в целевата директория, и известява systemd, когато е готова, съобщава напредъка си като свое състояние и сигнализира на наблюдателя (watchdog) на systemd, който рестартира дълго работещите команди (record и serve), ако спрат да отговарят, и спира изтеглянията, които спрат да напредват. С -on-calendar се генерира и модул на таймер, който изпълнява услугата по дадения график",
+		"Print the posts on the archived pages in the target directory which contain all words of the query, best matches first. The posts are looked up in a search index, which is built on the first search and updated after every fetch into the archive.\nWith -regexp, the query is a regular expression instead, and the runs of text on the archived pages which it matches are printed in order":                                                                                                                                                                                                                                                                                               "Извеждане на мненията от архивираните страници в целевата директория, които съдържат всички думи от заявката, като най-добрите съвпадения са първи. Мненията се търсят в индекс за търсене, който се изгражда при първото търсене и се обновява след всяко изтегляне в архива.\nС -regexp заявката е регулярен израз, а откъсите от текста на архивираните страници, които му съответстват, се извеждат поред",
+		"Print the version of the program along with the commit it has been built from, the time of the commit, and the version of Go and the platform it has been built with":                                                             "Извеждане на версията на програмата заедно с комита, от който е компилирана, времето на комита и версията на Go и платформата, с които е компилирана",
+		"Report statistics about the posts in the archive in the target directory as extracted by the forum engine (posts per author, activity over time, average post length, most quoted authors and attachment counts) as JSON or HTML": "Отчитане на статистика за мненията в архива в целевата директория, както са извлечени от двигателя на форума (мнения по автор, активност във времето, средна дължина на мненията, най-цитирани автори и брой прикачени файлове), като JSON или HTML",
+		"Run an HTTP(S) proxy and archive every page and resource from the given hosts which is browsed through it into the target directory, for forums whose pages cannot be derived from a URL template. Every HTML document becomes a page of the archive, numbered in the order of browsing, and the other resources are stored along with the page which referred to them. The archive can be viewed with the serve subcommand.\nHTTPS connections to the given hosts are intercepted with certificates issued by a local certificate authority, which is created next to the default configuration file (proxy-ca.pem) on first use and has to be trusted by the browser; connections to other hosts are passed through untouched": "Пускане на HTTP(S) прокси сървър и архивиране в целевата директория на всяка страница и ресурс от дадените хостове, разгледани през него, за форуми, чиито страници не могат да бъдат получени от шаблон на URL. Всеки HTML документ става страница от архива, номерирана по реда на разглеждане, а другите ресурси се съхраняват заедно със страницата, която препраща към тях. Архивът може да бъде разгледан с подкомандата serve.\nHTTPS връзките към дадените хостове се прихващат със сертификати, издадени от локален удостоверяващ орган, който се създава до конфигурационния файл по подразбиране (proxy-ca.pem) при първото използване и трябва да бъде удостоверен като доверен в браузъра; връзките към други хостове се пропускат непроменени",
+		"Serve the archive in the target directory over HTTP. The root lists the archived pages of the topic and offers searching their posts. Files are served both under their paths in the archive and under the paths and query strings of their original URLs, with the content types under which they were originally served if their metadata has been saved.\nA read-only JSON API is served as well: /api/topic describes the topic and its archived pages, /api/pages/{n}/posts lists the posts on page n and /api/search?q=query searches them":                                                                                                                                                                                "Обслужване на архива в целевата директория по HTTP. Коренът изброява архивираните страници на темата и предлага търсене в мненията им. Файловете се обслужват както под пътищата си в архива, така и под пътищата и низовете на заявката на оригиналните си URL адреси, с типовете съдържание, с които са били обслужени първоначално, ако метаданните им са записани.\nОбслужва се и JSON API само за четене: /api/topic описва темата и архивираните ѝ страници, /api/pages/{n}/posts изброява мненията на страница n, а /api/search?q=заявка търси в тях",
+		"Store the secret read from the terminal (or the standard input) in the keyring of the operating system (the Secret Service on Linux, the Keychain on macOS and the Credential Manager on Windows) as the entry, or delete the entry, which can then be referenced as keyring:entry by the flags and the settings of the profiles which take references to secrets (credentials and logins as user:password, API keys, tokens, webhooks and bundles of clearance cookies as name=value; name=value); in a profile, the reference keyring: alone refers to the entry named after the profile and the setting, e.g. topic/login":                                                                                                    "Съхраняване на тайната, прочетена от терминала (или от стандартния вход), в ключодържателя на операционната система (Secret Service в Linux, Keychain в macOS и Credential Manager в Windows) като записа или изтриване на записа, към който след това може да се препраща като keyring:запис от флаговете и настройките на профилите, които приемат препратки към тайни (данни за удостоверяване и влизане като потребител:парола, API ключове, токени, уеб куки и набори от бисквитки за преминаване като име=стойност; име=стойност); в профил препратката keyring: сама по себе си се отнася до записа, наречен на профила и настройката, напр. topic/login",
+		"Export the archive in the target directory in the given format (one of: " + strings.Join(getExporterNames(), ", ") + ")": "Експортиране на архива в целевата директория в дадения формат (един от: " + strings.Join(getExporterNames(), ", ") + ")",
+		"Store the given user stylesheet, or the built-in one with the given name (one of: " + strings.Join(getInjectedStylesheetPresetNames(), ", ") + "), " +
+			"as " + archive.InjectedStylesheetFilename + " in the archive in the target directory and link it from the end of the head of every archived page, " +
+			"after the stylesheets of the forum, so that e.g. a dark theme can be applied to all of them. " +
+			"The pages are changed only once: afterwards, restyling them only takes replacing the stylesheet, e.g. by running the command again. " +
+			"Without an argument, the stylesheet which is already stored is linked, unless only the print-ready stylesheet is injected with -print. " +
+			"Pages fetched with -inject-css and -print-css link the stylesheets from the start": "Съхраняване на дадения потребителски стил или на вградения стил с даденото име (един от: " + strings.Join(getInjectedStylesheetPresetNames(), ", ") + ") " +
+			"като " + archive.InjectedStylesheetFilename + " в архива в целевата директория и свързването му от края на заглавната част на всяка архивирана страница, " +
+			"след стиловете на форума, така че напр. тъмна тема да може да бъде приложена към всички тях. " +
+			"Страниците се променят само веднъж: след това смяната на стила им изисква само замяна на стила, напр. чрез повторно изпълнение на командата. " +
+			"Без аргумент се свързва вече съхраненият стил, освен ако с -print не се вмъква само стилът за печат. " +
+			"Страниците, изтеглени с -inject-css и -print-css, препращат към стиловете от самото начало",
+
+		// command flags
+		"Internet Archive `collection` in which the ia format creates the item":                                                                                                                         "`колекция` в Internet Archive, в която форматът ia създава елемента",
+		"MQTT `topic` or NATS subject to which the events given by -publish are published":                                                                                                              "MQTT `тема` или NATS субект, в който се публикуват събитията, зададени с -publish",
+		"`URL` of a webhook to which a JSON payload with the run report is POSTed when the run completes":                                                                                               "`URL` на уеб кука, към която се изпраща с POST JSON съдържание с отчета за изпълнението, когато то завърши",
+		"`address` (host:port) of the SOCKS5 proxy of the Tor daemon used by -tor":                                                                                                                      "`адрес` (хост:порт) на SOCKS5 прокси сървъра на демона на Tor, използван от -tor",
+		"`address` on which to listen for HTTP requests":                                                                                                                                                "`адрес`, на който да се слушат HTTP заявки",
+		"`address` on which to listen for proxy requests":                                                                                                                                               "`адрес`, на който да се слушат заявки към прокси сървъра",
+		"`file` with the environment variables referenced by the credentials (e.g. -credentials env:NAME) which is read by the service":                                                                 "`файл` с променливите на средата, към които препращат данните за удостоверяване (напр. -credentials env:ИМЕ), който се чете от услугата",
+		"`identifier` of the Internet Archive item which the ia format uploads the archive as (default: the slug of the title of the topic)":                                                            "`идентификатор` на елемента в Internet Archive, като който форматът ia качва архива (по подразбиране: кратко име, получено от заглавието на темата)",
+		"`mode` of treating the posts which duplicate earlier ones of the same author made shortly before them (double posts and posts submitted twice by the forum): keep, flag or collapse":           "`режим` на третиране на мненията, които повтарят по-ранни мнения на същия автор, публикувани малко преди тях (двойни мнения и мнения, изпратени два пъти от форума): keep, flag или collapse",
+		"`name` of the user on whose behalf the API key is used":                                                                                                                                        "`име` на потребителя, от чието име се използва API ключът",
+		"`path` of the exported output (default: the name of the target directory with an extension suitable for the format)":                                                                           "`път` на експортирания резултат (по подразбиране: името на целевата директория с разширение, подходящо за формата)",
+		"`path` of the file to which to write the report; - stands for the standard output":                                                                                                             "`път` на файла, в който да се запише отчетът; - означава стандартния изход",
+		"`quality` (1-100) at which -recompress-images encodes the JPEG images again":                                                                                                                   "`качество` (1-100), с което -recompress-images кодира отново JPEG изображенията",
+		"`reference` of the form env:NAME to the access and secret keys of the S3-compatible API of the Internet Archive, given as access:secret":                                                       "`препратка` във вида env:ИМЕ към ключовете за достъп и тайните ключове на S3-съвместимия API на Internet Archive, зададени като достъп:тайна",
+		"`reference` to an OAuth2 access token sent in the Authorization header of the requests to the JSON endpoints of the forum instead of the API key, of the form env:NAME or keyring:NAME":        "`препратка` към OAuth2 токен за достъп, изпращан в заглавката Authorization на заявките към JSON крайните точки на форума вместо API ключа, във вида env:ИМЕ или keyring:ИМЕ",
+		"`reference` to the API key sent with the requests to the JSON endpoints of the forum (currently supported by the discourse engines), of the form env:NAME or keyring:NAME":                     "`препратка` към API ключа, изпращан със заявките към JSON крайните точки на форума (засега се поддържа от двигателите discourse), във вида env:ИМЕ или keyring:ИМЕ",
+		"`reference` to the credentials for HTTP basic authentication with the forum, of the form env:NAME or keyring:NAME where the environment variable or keyring entry NAME contains user:password": "`препратка` към данните за HTTP basic удостоверяване пред форума във вида env:ИМЕ или keyring:ИМЕ, където променливата на средата или записът в ключодържателя ИМЕ съдържа потребител:парола",
+		"`reference` to the user name and password with which to log in to the forum through its login form (currently supported by the phpbb and xenforo engines), of the form env:NAME or keyring:NAME where the environment variable or keyring entry NAME contains user:password; the session is renewed by logging in again whenever it expires and the affected pages are retried": "`препратка` към потребителското име и паролата, с които да се влезе във форума през формата му за вход (засега се поддържа от двигателите phpbb и xenforo), във вида env:ИМЕ или keyring:ИМЕ, където променливата на средата или записът в ключодържателя ИМЕ съдържа потребител:парола; сесията се подновява чрез повторно влизане всеки път, когато изтече, а засегнатите страници се опитват отново",
+		"also POST a JSON payload to the webhook given by -notify-url whenever the fetching of a page fails": "изпращане с POST на JSON съдържание и към уеб куката, зададена с -notify-url, всеки път, когато изтеглянето на страница е неуспешно",
+		"also archive the first page of up to `count` other topics of the same forum which are linked from the posts, one hop away, and make the links to them point at the local copies, whose resources are loaded from the forum (the phpbb, xenforo and discourse engines recognize the links to topics; 0 disables it)": "архивиране и на първата страница на до `брой` други теми от същия форум, към които има връзки от мненията, на една стъпка разстояние, като връзките към тях сочат към локалните копия, чиито ресурси се зареждат от форума (двигателите phpbb, xenforo и discourse разпознават връзките към теми; 0 го изключва)",
+		"also generate a timer unit which runs the service on the `schedule` given as a calendar event of systemd (e.g. daily or Mon *-*-* 03:00), instead of starting it at boot":                                                                                                                                           "генериране и на модул на таймер, който изпълнява услугата по `график`, зададен като календарно събитие на systemd (напр. daily или Mon *-*-* 03:00), вместо да я стартира при зареждане на системата",
+		"also print the text of the posts before (prefixed with -) and after (prefixed with +) every change":                                                                                                                                                                                                                 "извеждане и на текста на мненията преди (с префикс -) и след (с префикс +) всяка промяна",
+		"check whether the target of every link is still live with a HEAD request (or a GET request for its first byte, if the server does not allow HEAD requests), which is dead if it is not found or its host does not exist and unknown if the check is inconclusive":                                                   "проверка дали целта на всяка връзка все още е жива чрез HEAD заявка (или GET заявка за първия ѝ байт, ако сървърът не позволява HEAD заявки); целта е мъртва, ако не е намерена или хостът ѝ не съществува, и неизвестна, ако проверката е неубедителна",
+		"comma-separated `list` of the hosts whose pages and resources are archived": "разделен със запетаи `списък` от хостовете, чиито страници и ресурси се архивират",
+		"compare the posts extracted from the fetched pages by ID with the ones seen during the earlier runs and record their edits (with both versions kept) and deletions in the change log of the archive, which is listed by the changes command, so that the moderation and stealth edits of a live topic are preserved across its snapshots":                         "сравняване на мненията, извлечени от изтеглените страници, по идентификатор с видените при по-ранните изпълнения и записване на редакциите им (като се пазят и двете версии) и изтриванията им в дневника на промените на архива, който се извежда от командата changes, така че модерациите и скритите редакции на жива тема да се запазят в снимките ѝ",
+		"connect to `host:ip` address ip instead of resolving the name of host, e.g. for a forum whose domain has expired but whose server is still reachable; TLS certificates are still verified for host; may be repeated":                                                                                                                                              "свързване към адреса ip вместо разрешаване на името на host, зададени като `host:ip`, напр. за форум, чийто домейн е изтекъл, но чийто сървър все още е достъпен; TLS сертификатите все още се проверяват за host; може да се повтаря",
+		"convert the WebP and AVIF images to PNG (if they are lossless or have transparency) or JPEG and make the pages refer to the converted copies, keeping the originals next to them, so that the archive can be viewed in old browsers and exported to e-reader formats which do not support the modern codecs; animated images are stored as they are":              "преобразуване на изображенията WebP и AVIF в PNG (ако са без загуби или имат прозрачност) или JPEG и насочване на страниците към преобразуваните копия, като оригиналите се пазят до тях, така че архивът да може да бъде разглеждан в стари браузъри и експортиран във формати за електронни четци, които не поддържат модерните кодеци; анимираните изображения се съхраняват както са",
+		"discover the URLs of the pages of the topic in the sitemaps of the forum instead of deriving them from the base URL, which finds the pages of topics with irregular pagination; the value is either auto, which reads the sitemaps declared in robots.txt or else /sitemap.xml, or the comma-separated `URLs` of the sitemaps (indexes of sitemaps are followed)": "откриване на URL адресите на страниците на темата в картите на сайта на форума, вместо те да бъдат получени от базовия URL, което намира страниците на теми с нередовно разделяне на страници; стойността е или auto, което чете картите на сайта, обявени в robots.txt, или иначе /sitemap.xml, или разделените със запетаи `URL адреси` на картите на сайта (индексите на карти на сайта се следват)",
+		"do not fetch audio and video, leaving links to them pointing at their original URL": "без изтегляне на аудио и видео, като връзките към тях остават да сочат към оригиналния им URL",
+		"do not fetch images, leaving links to them pointing at their original URL":          "без изтегляне на изображения, като връзките към тях остават да сочат към оригиналния им URL",
+		"do not fetch resources larger than `size` bytes (optionally suffixed with K, M, G or T), leaving links to them pointing at their original URL; the size is learned through a HEAD request before the content is downloaded (0 means unlimited)":                                                                "без изтегляне на ресурси, по-големи от `размер` байта (с незадължителна наставка K, M, G или T), като връзките към тях остават да сочат към оригиналния им URL; размерът се научава чрез HEAD заявка, преди съдържанието да бъде изтеглено (0 означава без ограничение)",
+		"do not fetch the resources whose URL matches the `pattern` (see -accept), leaving links to them pointing at their original URL; may be repeated":                                                                                                                                                               "без изтегляне на ресурсите, чийто URL съответства на `шаблона` (вижте -accept), като връзките към тях остават да сочат към оригиналния им URL; може да се повтаря",
+		"do not fetch web fonts, leaving links to them pointing at their original URL":                                                                                                                                                                                                                                  "без изтегляне на уеб шрифтове, като връзките към тях остават да сочат към оригиналния им URL",
+		"do not send the URL of the page or stylesheet which refers to a resource as the Referer of the request for it, which is otherwise sent since many image hosts reject requests without it":                                                                                                                      "без изпращане на URL на страницата или стила, който препраща към ресурс, като Referer на заявката за него, който иначе се изпраща, тъй като много хостове на изображения отхвърлят заявки без него",
+		"do not store the favicon.ico, apple-touch-icon.png and browserconfig.xml at the root of the host of the pages, which browsers look for even if the pages do not link to them and which are otherwise stored once in the icons directory of the archive, so that the serve command shows the icons of the site": "без съхраняване на favicon.ico, apple-touch-icon.png и browserconfig.xml от корена на хоста на страниците, които браузърите търсят дори ако страниците не препращат към тях и които иначе се съхраняват веднъж в директорията с иконите на архива, така че командата serve да показва иконите на сайта",
+		"download the videos embedded in the pages through the players of YouTube, Vimeo and Streamable with the command given by -video-command and replace the players with video elements which play the local copies; videos which cannot be downloaded are left embedded":                                          "изтегляне на видеоклиповете, вградени в страниците чрез плейърите на YouTube, Vimeo и Streamable, с командата, зададена с -video-command, и замяна на плейърите с елементи video, които възпроизвеждат локалните копия; видеоклиповете, които не могат да бъдат изтеглени, остават вградени",
+		"drop the references to the resources from third-party hosts in the `domain` (see -allow-domain) altogether instead of fetching them or linking to them; may be repeated":                                                                                                                                       "пълно премахване на препратките към ресурсите от хостове на трети страни в `домейна` (вижте -allow-domain), вместо те да бъдат изтеглени или да се препраща към тях; може да се повтаря",
+		"dry run: print the URL of every page in the specified ranges together with whether it would be fetched, re-fetched or skipped, without writing anything (with -v, also probe each page for its size)":                                                                                                          "пробно изпълнение: извеждане на URL на всяка страница в зададените диапазони заедно с това дали би била изтеглена, изтеглена повторно или пропусната, без да се записва нищо (с -v всяка страница се проучва и за размера ѝ)",
+		"email a summary of the run, with the list of the failed pages attached, to the comma-separated `addresses` when it completes, through the mail server given by the smtp section of the configuration file":                                                                                                     "изпращане по имейл на обобщение на изпълнението, с прикачен списък на неуспешните страници, до разделените със запетаи `адреси`, когато то завърши, през пощенския сървър, зададен в раздела smtp на конфигурационния файл",
+		"enable overwriting of already fetched pages": "разрешаване на презаписването на вече изтеглени страници",
+		"export OpenTelemetry traces of the fetching of the pages and resources, the rewriting, the writing and every HTTP request to the OTLP/HTTP endpoint at `URL` (e.g. http://localhost:4318)":                                                                                                                                     "експортиране на OpenTelemetry трасировки на изтеглянето на страниците и ресурсите, пренаписването, записването и всяка HTTP заявка към OTLP/HTTP крайната точка на `URL` (напр. http://localhost:4318)",
+		"fetch only the resources needed to render the pages (stylesheets, scripts, images and fonts), leaving links to media, embedded objects, frames and other resources pointing at their original URL":                                                                                                                             "изтегляне само на ресурсите, необходими за изобразяването на страниците (стилове, скриптове, изображения и шрифтове), като връзките към медии, вградени обекти, рамки и други ресурси остават да сочат към оригиналния им URL",
+		"fetch only the resources whose URL matches the `pattern`, which is a glob (matched against the file name if it contains no /, otherwise against the whole URL) or a regular expression prefixed with re:; may be repeated":                                                                                                     "изтегляне само на ресурсите, чийто URL съответства на `шаблона`, който е глоб (сравняван с името на файла, ако не съдържа /, а иначе с целия URL) или регулярен израз с префикс re:; може да се повтаря",
+		"fetch the files linked from the posts on FTP servers (anonymously unless the URL names a user) and SFTP servers (as the user named by the URL, with its password or the keys of the SSH agent, verifying the server against ~/.ssh/known_hosts) and rewrite the links to the local copies":                                     "изтегляне на файловете, към които има връзки от мненията, от FTP сървъри (анонимно, освен ако URL не назовава потребител) и SFTP сървъри (като потребителя, назован от URL, с паролата му или ключовете на SSH агента, като сървърът се проверява спрямо ~/.ssh/known_hosts), и пренасочване на връзките към локалните копия",
+		"fetch the images which cannot be fetched from their URL by retrying with the Referer sent or omitted (see -no-referer) the other way around, then from their snapshot in the Wayback Machine and finally as the thumbnail served by the forum, recording the source which was used in the metadata and the summary of the run": "изтегляне на изображенията, които не могат да бъдат изтеглени от своя URL, чрез повторен опит с Referer, изпратен или пропуснат (вижте -no-referer) обратно на обичайното, след това от снимката им в Wayback Machine и накрая като миниатюрата, обслужвана от форума, като използваният източник се записва в метаданните и в обобщението на изпълнението",
+		"fetch the resources from third-party hosts only if they are in the `domain` (which matches its subdomains too and may contain the wildcards * and ?), leaving links to the resources from other hosts pointing at their original URL; may be repeated":                                                                         "изтегляне на ресурсите от хостове на трети страни само ако са в `домейна` (който съответства и на поддомейните си и може да съдържа заместващите символи * и ?), като връзките към ресурсите от други хостове остават да сочат към оригиналния им URL; може да се повтаря",
+		"give up on a page, along with the resources embedded in it, which has not been fetched within `duration` (e.g. 10m) and record it in the list of failed downloads, so that a pathological page (e.g. with a huge attachment or on a tarpit host) cannot stall the run (0 means unlimited)":                                     "отказване от страница, заедно с вградените в нея ресурси, която не е изтеглена в рамките на `продължителност` (напр. 10m), и записването ѝ в списъка с неуспешни изтегляния, така че патологична страница (напр. с огромен прикачен файл или на хост капан) да не може да спре изпълнението (0 означава без ограничение)",
+		"install the latest release even if it is not newer than the running version or the running version is unknown":                                                                                                                                                                                                                 "инсталиране на последното издание дори ако не е по-ново от изпълняваната версия или изпълняваната версия е неизвестна",
+		"intercept HTTPS connections to the given hosts in order to archive them":                                         "прихващане на HTTPS връзките към дадените хостове, за да бъдат архивирани",
+		"keep the original of every image recompressed by -recompress-images next to it, with .orig appended to its name": "запазване на оригинала на всяко изображение, прекомпресирано от -recompress-images, до него, с добавено .orig към името му",
+		"keep the responses for resources other than pages in `directory` and reuse them in later runs and for other topics from the same forum for as long as their Cache-Control and Expires headers allow, revalidating them with the server afterwards":                                                                                              "запазване на отговорите за ресурси, различни от страници, в `директория` и повторното им използване при следващи изпълнения и за други теми от същия форум, докато заглавките им Cache-Control и Expires позволяват, като след това се проверяват отново пред сървъра",
+		"leave the signatures of the posts, as recognized by the forum engine, out of the posts served by the API and the searched text":                                                                                                                                                                                                                 "пропускане на подписите на мненията, както са разпознати от двигателя на форума, в мненията, обслужвани от API, и в текста, в който се търси",
+		"leave the signatures of the posts, as recognized by the forum engine, out of the searched text (the index is rebuilt if it was built otherwise)":                                                                                                                                                                                                "пропускане на подписите на мненията, както са разпознати от двигателя на форума, в текста, в който се търси (индексът се изгражда наново, ако е бил изграден иначе)",
+		"look up the names of the hosts at the DNS server at `address` (host[:port]) instead of the one of the system":                                                                                                                                                                                                                                   "търсене на имената на хостовете в DNS сървъра на `адрес` (хост[:порт]) вместо в този на системата",
+		"make all the connections of the requests to the Unix socket at `path` instead of to the hosts, e.g. to reach the forum through a local reverse proxy, a socket forwarded over SSH or a test server; the requests still name the hosts, and HTTPS is still spoken over the socket for https:// URLs":                                             "осъществяване на всички връзки на заявките към Unix сокета на `път` вместо към хостовете, напр. за да се достигне форумът през локален обратен прокси сървър, сокет, препратен през SSH, или тестов сървър; заявките все още назовават хостовете, а за URL адресите https:// през сокета все още се използва HTTPS",
+		"make all the requests through the SOCKS5 proxy of a local Tor daemon (see -tor-proxy), which resolves the names of the hosts itself, so that onion services can be archived and neither the lookups nor the address of the machine leak; the requests for every page go over a separate circuit, and the videos are downloaded through Tor too": "изпращане на всички заявки през SOCKS5 прокси сървъра на локален демон на Tor (вижте -tor-proxy), който сам разрешава имената на хостовете, така че да могат да бъдат архивирани onion услуги и нито търсенията, нито адресът на машината да изтичат; заявките за всяка страница минават през отделна верига, а видеоклиповете също се изтеглят през Tor",
+		"make the stored pages never contact the original site when they are opened, by injecting a restrictive Content-Security-Policy into them and removing the external targets of forms, the sources of external scripts and the external resource hints":                                                                                           "съхранените страници никога да не се свързват с оригиналния сайт, когато бъдат отворени, чрез вмъкване на ограничителна Content-Security-Policy в тях и премахване на външните цели на формите, източниците на външни скриптове и външните подсказки за ресурси",
+		"match the regular expression case-insensitively (word searches always are)":                                                                 "сравняване с регулярния израз без значение на малки и главни букви (търсенето на думи винаги е такова)",
+		"maximum `number` of instances of the command given by -exec-per-resource (and of the recompressions of -recompress-images) running at once": "максимален `брой` екземпляри на командата, зададена с -exec-per-resource (и на прекомпресиранията на -recompress-images), които се изпълняват едновременно",
+		"maximum `number` of pages fetched at once; the others wait in the order given by -order. This bounds the throughput on topics with many pages, which used to be fetched all at once; raise it to fetch them faster at the cost of a looser order and a higher load on the forum": "максимален `брой` страници, които се изтеглят едновременно; останалите изчакват в реда, зададен с -order. Това ограничава пропускателната способност при теми с много страници, които преди се изтегляха всички едновременно; увеличете го, за да ги изтеглите по-бързо с цената на по-слабо спазван ред и по-голямо натоварване на форума",
+		"maximum number of `requests` per second (0 means unlimited)":       "максимален брой `заявки` в секунда (0 означава без ограничение)",
+		"minimum `duration` between two submissions to the Wayback Machine": "минимална `продължителност` между две изпращания към Wayback Machine",
+		"name the fetched pages after the slug of the title of the topic and their number (e.g. great-debate-p017.html) instead of the last segment of their URL, so that the archive can be navigated in a file manager":                                             "именуване на изтеглените страници по краткото име, получено от заглавието на темата, и номера им (напр. great-debate-p017.html) вместо по последния сегмент на техния URL, така че архивът да може да бъде обхождан с файлов мениджър",
+		"number of `posts` contained on a single page; used for determining the offset of the current page in the URL parameters":                                                                                                                                     "брой `мнения`, съдържащи се на една страница; използва се за определяне на отместването на текущата страница в параметрите на URL",
+		"number of `times` a request failing due to a network error or a 429 or 5xx status is retried, with exponential backoff starting at one second":                                                                                                               "брой `пъти`, които се опитва отново заявка, неуспешна поради мрежова грешка или състояние 429 или 5xx, с експоненциално нарастващо изчакване, започващо от една секунда",
+		"only archive the opening post of the topic along with its images and attachments (e.g. of announcement or tutorial boards): fetch just the first page, whatever the page ranges, and remove the other posts from it, if the engine can tell the posts apart": "архивиране само на началното мнение на темата заедно с изображенията и прикачените му файлове (напр. в раздели за обяви или ръководства): изтегля се само първата страница, независимо от диапазоните от страници, и от нея се премахват другите мнения, ако двигателят може да различи мненията",
+		"only list the selected failures without fetching them again":             "само изброяване на избраните неуспехи, без те да бъдат изтеглени отново",
+		"only report whether a newer release is available, without installing it": "само съобщаване дали е налично по-ново издание, без то да бъде инсталирано",
+		"only start fetching pages within the daily `window` of the local time of the form HH:MM-HH:MM (e.g. 01:00-06:00), pausing outside it and resuming when it opens again; the pages being fetched when it closes are finished":                                                                                                                                  "започване на изтеглянето на страници само в рамките на дневния `прозорец` от местното време във вида ЧЧ:ММ-ЧЧ:ММ (напр. 01:00-06:00), като извън него се прави пауза и се продължава, когато той се отвори отново; страниците, които се изтеглят, когато той се затвори, се довършват",
+		"post the summary of the run and its critical failures (rejected or expired credentials, full disk) to the Slack incoming webhook at `URL`, which may also be given as a reference of the form env:NAME or keyring:NAME":                                                                                                                                      "публикуване на обобщението на изпълнението и критичните му неуспехи (отхвърлени или изтекли данни за удостоверяване, пълен диск) във входящата уеб кука на Slack на `URL`, който може да бъде зададен и като препратка във вида env:ИМЕ или keyring:ИМЕ",
+		"post the summary of the run and its critical failures to the Discord webhook at `URL`, which may also be given as a reference of the form env:NAME or keyring:NAME":                                                                                                                                                                                          "публикуване на обобщението на изпълнението и критичните му неуспехи в уеб куката на Discord на `URL`, който може да бъде зададен и като препратка във вида env:ИМЕ или keyring:ИМЕ",
+		"present the cookie `name=value` (e.g. cf_clearance=...), copied from a browser which has passed the challenge of an anti-bot service such as Cloudflare, to the forum, or a reference of the form env:NAME or keyring:NAME to a bundle of such cookies separated by semicolons; use with -user-agent set to the User-Agent of that browser; may be repeated": "представяне пред форума на бисквитката `име=стойност` (напр. cf_clearance=...), копирана от браузър, който е преминал проверката на услуга против ботове като Cloudflare, или на препратка във вида env:ИМЕ или keyring:ИМЕ към набор от такива бисквитки, разделени с точка и запетая; използвайте с -user-agent, зададен на User-Agent на този браузър; може да се повтаря",
+		"publish a JSON event whenever a page is done and when the run completes to the MQTT (mqtt://, mqtts://) or NATS (nats://, tls://) broker at `URL`, which may contain credentials":                                                                                                                                                                            "публикуване на JSON събитие всеки път, когато страница е готова, и когато изпълнението завърши, в MQTT (mqtt://, mqtts://) или NATS (nats://, tls://) брокера на `URL`, който може да съдържа данни за удостоверяване",
+		"push the files of the archive which have changed since the last push, by their checksums, to the `destination` when the run completes: an sftp:// URL, a remote of rclone prefixed with rclone: or a destination of rsync":                                                                                                                                   "изпращане на файловете от архива, които са се променили след последното изпращане, според контролните им суми, към `местоназначението`, когато изпълнението завърши: sftp:// URL, отдалечено хранилище на rclone с префикс rclone: или местоназначение на rsync",
+		"put a card with a preview of the target of every bare external link (whose text is its URL) after it, made from the OpenGraph and oEmbed metadata of the target and with its thumbnail fetched locally, so that the archive retains the context of links whose targets later disappear":                                                                      "поставяне след всяка гола външна връзка (чийто текст е нейният URL) на карта с преглед на целта ѝ, съставена от метаданните OpenGraph и oEmbed на целта и с локално изтеглена миниатюра, така че архивът да запази контекста на връзките, чиито цели по-късно изчезнат",
+		"re-fetch already fetched pages whose archived copy is older than the given `age` (e.g. 12h, 30d or 2w) even without -f":                                                                                                                                                                                                                                      "повторно изтегляне на вече изтеглени страници, чието архивирано копие е по-старо от дадената `възраст` (напр. 12h, 30d или 2w), дори без -f",
+		"rebuild the search index before searching": "изграждане наново на индекса за търсене преди търсенето",
+		"recompress the stored PNG images losslessly with the best compression and the JPEG images at the quality given by -jpeg-quality (before running the command given by -exec-per-resource, if any), keeping the result only if it is smaller, to shrink archives dominated by uncompressed screenshots; animated PNG images and JPEG images with EXIF metadata are left as they are":                                                                                      "прекомпресиране на съхранените PNG изображения без загуби с най-добрата компресия и на JPEG изображенията с качеството, зададено с -jpeg-quality (преди изпълнението на командата, зададена с -exec-per-resource, ако има такава), като резултатът се запазва само ако е по-малък, за да се смалят архивите, преобладавани от некомпресирани екранни снимки; анимираните PNG изображения и JPEG изображенията с EXIF метаданни се оставят както са",
+		"remove the EXIF and XMP metadata (such as the GPS coordinates at which a photo was taken and the details of the camera) from the JPEG images before storing them, without encoding them again, so that the archive can be republished without leaking the locations of the posters; the stripping is recorded in the metadata of every image affected (see -save-metadata) and in the summary of the run, and the orientation recorded in the metadata is lost as well": "премахване на метаданните EXIF и XMP (като GPS координатите, на които е направена снимката, и данните на фотоапарата) от JPEG изображенията, преди те да бъдат съхранени, без да бъдат кодирани отново, така че архивът да може да бъде публикуван повторно, без да се издават местоположенията на авторите; премахването се записва в метаданните на всяко засегнато изображение (вижте -save-metadata) и в обобщението на изпълнението, като ориентацията, записана в метаданните, също се губи",
+		"remove the query parameters whose name matches the glob `pattern` from the URLs of resources (see -strip-tracking); may be repeated":                                                                                                                                                                                                     "премахване на параметрите на заявката, чието име съответства на глоб `шаблона`, от URL адресите на ресурсите (вижте -strip-tracking); може да се повтаря",
+		"replace the codes of the smileys in the posts served by the API and the searched text, including the ones of the images of smileys recognized by the forum engine, with the Unicode emoji which look like them":                                                                                                                          "замяна на кодовете на емотиконите в мненията, обслужвани от API, и в текста, в който се търси, включително тези на изображенията на емотикони, разпознати от двигателя на форума, с емоджитата в Unicode, които приличат на тях",
+		"replace the codes of the smileys in the searched text, including the ones of the images of smileys recognized by the forum engine, with the Unicode emoji which look like them (the index is rebuilt if it was built otherwise)":                                                                                                         "замяна на кодовете на емотиконите в текста, в който се търси, включително тези на изображенията на емотикони, разпознати от двигателя на форума, с емоджитата в Unicode, които приличат на тях (индексът се изгражда наново, ако е бил изграден иначе)",
+		"reuse the addresses of a host for `duration` before looking its name up again, and keep using the last ones found while the lookups fail (0 means every connection looks it up)":                                                                                                                                                         "повторно използване на адресите на хост за `продължителност`, преди името му да бъде потърсено отново, и използване на последните намерени, докато търсенията са неуспешни (0 означава, че всяка връзка го търси)",
+		"run `command` on every stored page and resource once it has been written, e.g. to scan or optimize it, with {} standing for the path of the file (which is appended if there is no {}); the arguments are split at whitespace outside of quotes and nothing is expanded by a shell, and failures are reported in the summary of the run": "изпълнение на `команда` върху всяка съхранена страница и ресурс, след като бъдат записани, напр. за да бъдат сканирани или оптимизирани, като {} замества пътя на файла (който се добавя накрая, ако няма {}); аргументите се разделят по празните места извън кавичките и нищо не се разгъва от обвивка, а неуспехите се отчитат в обобщението на изпълнението",
+		"run the `command` when the run completes, with its outcome in the environment variables FETCH_FORUM_TOPIC_EXIT_CODE, FETCH_FORUM_TOPIC_PAGES_SUCCEEDED, FETCH_FORUM_TOPIC_PAGES_FAILED, FETCH_FORUM_TOPIC_FAILED_PAGES, FETCH_FORUM_TOPIC_ERROR and so on and the JSON report of the run on its standard input":                          "изпълнение на `команда`, когато изпълнението завърши, с резултата му в променливите на средата FETCH_FORUM_TOPIC_EXIT_CODE, FETCH_FORUM_TOPIC_PAGES_SUCCEEDED, FETCH_FORUM_TOPIC_PAGES_FAILED, FETCH_FORUM_TOPIC_FAILED_PAGES, FETCH_FORUM_TOPIC_ERROR и т.н. и JSON отчета за изпълнението на стандартния ѝ вход",
+		"run the `command` whenever the fetching of a page has finished, with its outcome in the environment variables FETCH_FORUM_TOPIC_PAGE_NUMBER, FETCH_FORUM_TOPIC_PAGE_URL, FETCH_FORUM_TOPIC_PAGE_DIR, FETCH_FORUM_TOPIC_PAGE_STATUS (ok or failed) and FETCH_FORUM_TOPIC_PAGE_ERROR":                                                      "изпълнение на `команда` всеки път, когато изтеглянето на страница завърши, с резултата му в променливите на средата FETCH_FORUM_TOPIC_PAGE_NUMBER, FETCH_FORUM_TOPIC_PAGE_URL, FETCH_FORUM_TOPIC_PAGE_DIR, FETCH_FORUM_TOPIC_PAGE_STATUS (ok или failed) и FETCH_FORUM_TOPIC_PAGE_ERROR",
+		"same as -n": "същото като -n",
+		"send `string` as the User-Agent header of every request": "изпращане на `низ` като заглавка User-Agent на всяка заявка",
+		"send the requests to the hosts which advertise HTTP/3 (in the Alt-Svc header of their responses) over QUIC, falling back to HTTP/1.1 or HTTP/2 for a while whenever a request over HTTP/3 fails":                                                                                                                                               "изпращане на заявките към хостовете, които обявяват HTTP/3 (в заглавката Alt-Svc на отговорите си), през QUIC, като за известно време се преминава към HTTP/1.1 или HTTP/2 всеки път, когато заявка през HTTP/3 е неуспешна",
+		"show a desktop notification when the run finishes or fails (through notify-send or D-Bus on Linux, osascript on macOS and a toast on Windows)":                                                                                                                                                                                                 "показване на известие на работния плот, когато изпълнението завърши или е неуспешно (чрез notify-send или D-Bus в Linux, osascript в macOS и изскачащо известие в Windows)",
+		"show a terminal UI with the pages being fetched, the throughput and the failures, which can be requeued interactively":                                                                                                                                                                                                                         "показване на терминален потребителски интерфейс със страниците, които се изтеглят, скоростта и неуспехите, които могат да бъдат пуснати отново интерактивно",
+		"slow down the requests to every host which responds with errors or much slower than usual, and speed them back up once it recovers, in addition to -rate-limit":                                                                                                                                                                                "забавяне на заявките към всеки хост, който отговаря с грешки или много по-бавно от обикновено, и ускоряването им отново, след като той се възстанови, в допълнение към -rate-limit",
+		"stop starting new pages once `count` resources have been fetched during the run; the remaining pages are left pending for the next run (0 means unlimited)":                                                                                                                                                                                    "спиране на започването на нови страници, след като по време на изпълнението бъдат изтеглени `брой` ресурси; останалите страници остават чакащи за следващото изпълнение (0 означава без ограничение)",
+		"stop starting new pages once `size` bytes (optionally suffixed with K, M, G or T) have been downloaded during the run; the remaining pages are left pending for the next run (0 means unlimited)":                                                                                                                                              "спиране на започването на нови страници, след като по време на изпълнението бъдат изтеглени `размер` байта (с незадължителна наставка K, M, G или T); останалите страници остават чакащи за следващото изпълнение (0 означава без ограничение)",
+		"stop the run once `duration` (e.g. 6h) has passed since its start: the pages in flight give up and, like the ones which have not been started, are recorded in the list of failed downloads for the next run (0 means unlimited)":                                                                                                              "спиране на изпълнението, след като от началото му изтече `продължителност` (напр. 6h): страниците в процес на изтегляне се отказват и, както тези, които не са започнати, се записват в списъка с неуспешни изтегляния за следващото изпълнение (0 означава без ограничение)",
+		"store a JPEG thumbnail, at most `pixels` wide and high, next to every image embedded in the pages which is larger than that and show the thumbnail in its place, linking to the full-size copy unless the image is already a link, so that the pages with many large images are quick to open (0 means that the images are shown as they are)": "съхраняване на JPEG миниатюра, най-много `пиксела` широка и висока, до всяко вградено в страниците изображение, което е по-голямо от това, и показване на миниатюрата на негово място, с връзка към копието в пълен размер, освен ако изображението вече не е връзка, така че страниците с много големи изображения да се отварят бързо (0 означава, че изображенията се показват както са)",
+		"store the HTTP response metadata (status, Content-Type, Last-Modified, ETag and the rest of the headers except Set-Cookie) of every fetched page and resource in a sidecar .meta.json file next to it":                                                                                                                                         "съхраняване на метаданните на HTTP отговора (състояние, Content-Type, Last-Modified, ETag и останалите заглавки без Set-Cookie) на всяка изтеглена страница и ресурс в придружаващ файл .meta.json до нея",
+		"store the HTTP response metadata of every recorded page and resource in a sidecar .meta.json file next to it":                                                                                                                                                                                                                                  "съхраняване на метаданните на HTTP отговора на всяка записана страница и ресурс в придружаващ файл .meta.json до нея",
+		"store the images of the smileys recognized by the forum engine only once, in the smileys directory of the archive, instead of along with every page, and record their codes in smileys.tsv":                                                                                                                                                    "съхраняване на изображенията на емотиконите, разпознати от двигателя на форума, само веднъж, в директорията smileys на архива, вместо заедно с всяка страница, и записване на кодовете им в smileys.tsv",
+		"transliterate the Latin, Greek and Cyrillic letters in the names of the stored files to ASCII and percent-encode the rest of the non-ASCII characters, for archives which are copied to systems or media which mishandle such names":                                                                                                           "транслитериране на латинските, гръцките и кирилските букви в имената на съхранените файлове в ASCII и процентно кодиране на останалите знаци извън ASCII, за архиви, които се копират на системи или носители, които не боравят правилно с такива имена",
+		"treat the `locations` separated by = (hosts optionally followed by a path, e.g. cdn1.example.com=cdn2.example.com=example.com/static) as serving the same assets, which are fetched from the first location and stored and referenced once whichever location they are linked from; may be repeated":                                           "третиране на `местоположенията`, разделени с = (хостове, по желание последвани от път, напр. cdn1.example.com=cdn2.example.com=example.com/static), като обслужващи едни и същи ресурси, които се изтеглят от първото местоположение и се съхраняват и препращат веднъж, от което и местоположение да има връзка към тях; може да се повтаря",
+		"treat the query as a regular expression matched against the text of the pages":                                                                                                                                                                                                                                                                 "третиране на заявката като регулярен израз, сравняван с текста на страниците",
+		"write a JSON summary of the run (pages attempted, succeeded and failed, resources fetched, bytes downloaded, durations and retries) to `file` (- for the standard output)":                                                                                                                                                                     "записване на JSON обобщение на изпълнението (опитани, успешни и неуспешни страници, изтеглени ресурси, изтеглени байтове, продължителности и повторни опити) във `файл` (- за стандартния изход)",
+		"write the list as a JSON array of objects instead":                                                                            "записване на списъка вместо това като JSON масив от обекти",
+		"write the report as an HTML page with tables and bar charts instead of JSON":                                                  "записване на отчета като HTML страница с таблици и стълбовидни диаграми вместо JSON",
+		"write the units to files named after the target directory in `directory` (e.g. /etc/systemd/system) instead of printing them": "записване на модулите във файлове, наречени на целевата директория, в `директория` (напр. /etc/systemd/system), вместо те да бъдат изведени",
+		"yt-dlp `command` (or one of a compatible program) with which -download-videos downloads the videos, to which the options selecting the output file and the URL of the video are appended; the arguments are split like the ones of -exec-per-resource":      "`команда` на yt-dlp (или на съвместима програма), с която -download-videos изтегля видеоклиповете и към която се добавят опциите, избиращи изходния файл и URL на видеоклипа; аргументите се разделят като тези на -exec-per-resource",
+		"`name` of the forum software which serves the topic, which determines how the URLs of the pages are derived and how the last page is detected (one of: %s)":                                                                                                 "`име` на софтуера на форума, който обслужва темата, което определя как се получават URL адресите на страниците и как се открива последната страница (едно от: %s)",
+		"`name` of the forum software which serves the topic, which determines how the posts on the pages are extracted for searching (one of: %s)":                                                                                                                  "`име` на софтуера на форума, който обслужва темата, което определя как се извличат мненията от страниците за търсенето (едно от: %s)",
+		"`order` in which the pages are started (one of: %s); newest-first archives the most recent pages of an active topic, whose posts are the likeliest to be edited or deleted, before the older ones in case the run is interrupted or runs out of its budget": "`ред`, в който се започват страниците (един от: %s); newest-first архивира най-новите страници на активна тема, чиито мнения е най-вероятно да бъдат редактирани или изтрити, преди по-старите, в случай че изпълнението бъде прекъснато или изчерпи бюджета си",
+		"only list the changes of the comma-separated `kinds` (any of: %s)":                                                                                                                                                     "изброяване само на промените от разделените със запетаи `видове` (кои да е от: %s)",
+		"only retry the failures of the comma-separated `kinds` (any of: %s)":                                                                                                                                                   "повторен опит само за неуспехите от разделените със запетаи `видове` (кои да е от: %s)",
+		"remove the common tracking query parameters (%s) from the URLs of resources before fetching them and before rewriting the references to them":                                                                          "премахване на честите параметри за проследяване в заявките (%s) от URL адресите на ресурсите, преди те да бъдат изтеглени и преди препратките към тях да бъдат пренаписани",
+		"submit the URL of every fetched page to the Save Page Now API of the Wayback Machine of the Internet Archive and record the URLs of the snapshots in " + archive.WaybackSnapshotsFilename + " in the target directory": "изпращане на URL на всяка изтеглена страница към API Save Page Now на Wayback Machine на Internet Archive и записване на URL адресите на снимките в " + archive.WaybackSnapshotsFilename + " в целевата директория",
+		"store the user stylesheet in the `file` (or the built-in one with the given name: %s) as " + archive.InjectedStylesheetFilename + " in the archive and link it from the end of the head of every fetched page, so that e.g. a dark theme applies to them (see the inject-css command for the pages fetched before)": "съхраняване на потребителския стил от `файл` (или на вградения стил с даденото име: %s) като " + archive.InjectedStylesheetFilename + " в архива и свързването му от края на заглавната част на всяка изтеглена страница, така че напр. тъмна тема да се прилага към тях (вижте командата inject-css за страниците, изтеглени преди това)",
+		"generate a print-ready stylesheet for the forum engine (which hides the navigation, prints every post on a new sheet, expands the quotes and uses a legible font), store it as " + archive.PrintStylesheetFilename + " in the archive and link it from every fetched page for printing":                             "генериране на стил за печат за двигателя на форума (който скрива навигацията, отпечатва всяко мнение на нов лист, разгъва цитатите и използва четлив шрифт), съхраняването му като " + archive.PrintStylesheetFilename + " в архива и свързването му от всяка изтеглена страница за печат",
+		"generate a print-ready stylesheet for the forum engine (which hides the navigation, prints every post on a new sheet, expands the quotes and uses a legible font) as " + archive.PrintStylesheetFilename + " and link it from the pages for printing":                                                               "генериране на стил за печат за двигателя на форума (който скрива навигацията, отпечатва всяко мнение на нов лист, разгъва цитатите и използва четлив шрифт) като " + archive.PrintStylesheetFilename + " и свързването му от страниците за печат",
+
+		// usage errors
+		"unexpected arguments: %v":                                                                         "неочаквани аргументи: %v",
+		"unsupported forum engine: %s (supported engines: %s)":                                             "неподдържан двигател на форум: %s (поддържани двигатели: %s)",
+		"unknown export format: %s":                                                                        "непознат формат за експортиране: %s",
+		"exactly one export format must be specified":                                                      "трябва да бъде зададен точно един формат за експортиране",
+		"exactly one search query must be specified":                                                       "трябва да бъде зададена точно една заявка за търсене",
+		"exactly one directory to compare with must be specified":                                          "трябва да бъде зададена точно една директория за сравнение",
+		"invalid search pattern: %v":                                                                       "невалиден шаблон за търсене: %v",
+		"no base URL or profile specified for forum topic pages":                                           "не е зададен базов URL или профил за страниците на темата",
+		"no base URL or page ranges specified for the estimate":                                            "не са зададени базов URL или диапазони от страници за оценката",
+		"%q is neither a URL nor the name of a profile in %s":                                              "%q не е нито URL, нито име на профил в %s",
+		"%q is not the URL of a web page":                                                                  "%q не е URL на уеб страница",
+		"%q is not a positive number of posts":                                                             "%q не е положителен брой мнения",
+		"the questions were not answered":                                                                  "на въпросите не беше отговорено",
+		"no configuration file to write the profile to; specify one with -config":                          "няма конфигурационен файл, в който да бъде записан профилът; задайте такъв с -config",
+		"unknown kind of failure %q (it has to be one of: %s)":                                             "непознат вид неуспех %q (трябва да бъде един от: %s)",
+		"an action (set or delete) and the name of an entry must be specified":                             "трябва да бъдат зададени действие (set или delete) и име на запис",
+		"cannot generate a service running command %s (it has to be one of: fetch, resume, record, serve)": "не може да бъде генерирана услуга, изпълняваща командата %s (тя трябва да бъде една от: fetch, resume, record, serve)",
+		"could not read stylesheet %s to inject (nor is it one of the presets: %s)":                        "стилът %s за вмъкване не можа да бъде прочетен (и не е от готовите: %s)",
+		"empty %s command":              "празна команда %s",
+		"empty post-processing command": "празна команда за последваща обработка",
+		"empty secret":                  "празна тайна",
+		"empty video download command":  "празна команда за изтегляне на видеоклипове",
+		"invalid JPEG quality: %d (it has to be between 1 and 100)":                                                                         "невалидно качество на JPEG: %d (то трябва да бъде между 1 и 100)",
+		"invalid identifier of an Internet Archive item: %q (it has to consist of 5 to 100 letters, digits, dots, hyphens and underscores)": "невалиден идентификатор на елемент в Internet Archive: %q (той трябва да се състои от 5 до 100 букви, цифри, точки, тирета и долни черти)",
+		"invalid mirror destination %s: %v":                                                       "невалидно местоназначение за огледално копие %s: %v",
+		"invalid mode of treating duplicate posts: %s":                                            "невалиден режим на третиране на повтарящите се мнения: %s",
+		"invalid thumbnail size: %d (it has to be positive, or 0 to show the images as they are)": "невалиден размер на миниатюрите: %d (той трябва да бъде положителен или 0, за да се показват изображенията както са)",
+		"keyring entry %s does not exist":                                                         "записът %s в ключодържателя не съществува",
+		"line %d of the standard input is not an absolute URL: %s":                                "ред %d от стандартния вход не е абсолютен URL: %s",
+		"no hosts to record specified":                                                            "не са зададени хостове за записване",
+		"no identifier of the Internet Archive item could be derived from the title of the topic; specify one with -ia-identifier": "от заглавието на темата не можа да бъде получен идентификатор на елемента в Internet Archive; задайте такъв с -ia-identifier",
+		"no page URLs given on the standard input":                                "на стандартния вход не са подадени URL адреси на страници",
+		"no recipients of the email report specified":                             "не са зададени получатели на отчета по имейл",
+		"no stylesheet specified and none is stored in the archive yet":           "не е зададен стил и в архива все още не е съхранен такъв",
+		"the pages of the topic cannot be both listed and discovered in sitemaps": "страниците на темата не могат едновременно да бъдат изброени и открити в картите на сайта",
+		"unknown keyring action: %s (it has to be one of: set, delete)":           "непознато действие с ключодържателя: %s (то трябва да бъде едно от: set, delete)",
+		"unknown kind of change %q (it has to be one of: %s)":                     "непознат вид промяна %q (той трябва да бъде един от: %s)",
+
+		// errors
+		"%d pages are broken; run the `repair` command to fetch them again":                         "%d страници са повредени; изпълнете командата `repair`, за да бъдат изтеглени отново",
+		"%s did not download any file":                                                              "%s не изтегли нито един файл",
+		"%s is empty":                                                                               "%s е празен",
+		"%s is larger than %d bytes":                                                                "%s е по-голям от %d байта",
+		"%s is missing":                                                                             "%s липсва",
+		"%s is not a regular file":                                                                  "%s не е обикновен файл",
+		"%s responded with status %s":                                                               "%s отговори със състояние %s",
+		"Save Page Now did not report the URL of the snapshot":                                      "Save Page Now не съобщи URL на снимката",
+		"Save Page Now responded with status %s":                                                    "Save Page Now отговори със състояние %s",
+		"configuration file %s is not a mapping":                                                    "конфигурационният файл %s не е YAML речник (mapping)",
+		"could not check the latest release of %s: %v":                                              "не можа да бъде проверено последното издание на %s: %v",
+		"could not compute the checksums of the archive: %v":                                        "контролните суми на архива не можаха да бъдат изчислени: %v",
+		"could not connect to %s: %v":                                                               "не можа да бъде осъществена връзка с %s: %v",
+		"could not connect to event broker %s: %v":                                                  "не можа да бъде осъществена връзка с брокера на събития %s: %v",
+		"could not create gallery directory %s":                                                     "директорията на галерията %s не можа да бъде създадена",
+		"could not create mirror manifest %s":                                                       "манифестът на огледалното копие %s не можа да бъде създаден",
+		"could not create output file %s":                                                           "изходният файл %s не можа да бъде създаден",
+		"could not create remote directory %s: %v":                                                  "отдалечената директория %s не можа да бъде създадена: %v",
+		"could not create report file %s":                                                           "файлът за отчета %s не можа да бъде създаден",
+		"could not create the new executable in %s: %v":                                             "новият изпълним файл не можа да бъде създаден в %s: %v",
+		"could not decode %s image: %v":                                                             "изображението от тип %s не можа да бъде декодирано: %v",
+		"could not delete keyring entry %s: %v":                                                     "записът %s в ключодържателя не можа да бъде изтрит: %v",
+		"could not determine the path of the executable: %v":                                        "пътят на изпълнимия файл не можа да бъде определен: %v",
+		"could not discover the pages of the topic: %v":                                             "страниците на темата не можаха да бъдат открити: %v",
+		"could not encode %s image: %v":                                                             "изображението от тип %s не можа да бъде кодирано: %v",
+		"could not fetch any of the sample pages":                                                   "нито една от примерните страници не можа да бъде изтеглена",
+		"could not list the contents of target directory %s":                                        "съдържанието на целевата директория %s не можа да бъде изброено",
+		"could not move the executable %s out of the way: %v":                                       "изпълнимият файл %s не можа да бъде преместен настрана: %v",
+		"could not open %s: %v":                                                                     "%s не можа да бъде отворен: %v",
+		"could not open log file %s: %v":                                                            "файлът на дневника %s не можа да бъде отворен: %v",
+		"could not open the map of original URLs (%s)":                                              "картата на оригиналните URL адреси не можа да бъде отворена (%s)",
+		"could not open the state database (%s)":                                                    "базата данни на състоянието не можа да бъде отворена (%s)",
+		"could not parse configuration file %s: %v":                                                 "конфигурационният файл %s не можа да бъде анализиран: %v",
+		"could not parse mirror manifest %s: %v":                                                    "манифестът на огледалното копие %s не можа да бъде анализиран: %v",
+		"could not push the archive to the mirror at %s: %v":                                        "архивът не можа да бъде изпратен към огледалното копие на %s: %v",
+		"could not put the terminal into raw mode: %v":                                              "терминалът не можа да бъде превключен в суров режим: %v",
+		"could not read archive in %s: %v":                                                          "архивът в %s не можа да бъде прочетен: %v",
+		"could not read configuration file %s":                                                      "конфигурационният файл %s не можа да бъде прочетен",
+		"could not read image %s":                                                                   "изображението %s не можа да бъде прочетено",
+		"could not read mirror manifest %s: %v":                                                     "манифестът на огледалното копие %s не можа да бъде прочетен: %v",
+		"could not read page URLs from the standard input: %v":                                      "URL адресите на страниците не можаха да бъдат прочетени от стандартния вход: %v",
+		"could not read the change log of the posts: %v":                                            "дневникът на промените на мненията не можа да бъде прочетен: %v",
+		"could not read the clearance cookies of profile %s: %v":                                    "бисквитките за преминаване на профила %s не можаха да бъдат прочетени: %v",
+		"could not read the failed downloads of the last run: %v":                                   "неуспешните изтегляния от последното изпълнение не можаха да бъдат прочетени: %v",
+		"could not read the map of original URLs (%s)":                                              "картата на оригиналните URL адреси не можа да бъде прочетена (%s)",
+		"could not read the secret: %v":                                                             "тайната не можа да бъде прочетена: %v",
+		"could not replace the executable %s: %v":                                                   "изпълнимият файл %s не можа да бъде заменен: %v",
+		"could not resolve the keys for the Internet Archive: %v":                                   "ключовете за Internet Archive не можаха да бъдат разрешени: %v",
+		"could not resolve the path of the executable: %v":                                          "пътят на изпълнимия файл не можа да бъде разрешен: %v",
+		"could not set up the certificate authority of the proxy: %v":                               "удостоверяващият орган на прокси сървъра не можа да бъде подготвен: %v",
+		"could not set up the export of traces to %s: %v":                                           "експортирането на трасировки към %s не можа да бъде подготвено: %v",
+		"could not stat %s":                                                                         "%s не можа да бъде проверен (stat)",
+		"could not store keyring entry %s: %v":                                                      "записът %s в ключодържателя не можа да бъде съхранен: %v",
+		"could not store the injected stylesheet (%s)":                                              "вмъкнатият стил не можа да бъде съхранен (%s)",
+		"could not store the print-ready stylesheet (%s)":                                           "стилът за печат не можа да бъде съхранен (%s)",
+		"could not upload %s to the Internet Archive: %v":                                           "%s не можа да бъде качен в Internet Archive: %v",
+		"could not upload %s to the Internet Archive: status %s: %s":                                "%s не можа да бъде качен в Internet Archive: състояние %s: %s",
+		"could not upload %s: %v":                                                                   "%s не можа да бъде качен: %v",
+		"could not write Org document %s: %v":                                                       "Org документът %s не можа да бъде записан: %v",
+		"could not write configuration file %s: %v":                                                 "конфигурационният файл %s не можа да бъде записан: %v",
+		"could not write gallery page %s: %v":                                                       "страницата на галерията %s не можа да бъде записана: %v",
+		"could not write image %s: %v":                                                              "изображението %s не можа да бъде записано: %v",
+		"could not write mirror manifest %s":                                                        "манифестът на огледалното копие %s не можа да бъде записан",
+		"could not write report: %v":                                                                "отчетът не можа да бъде записан: %v",
+		"could not write tarball %s: %v":                                                            "архивът tar %s не можа да бъде записан: %v",
+		"could not write the list of links: %v":                                                     "списъкът с връзки не можа да бъде записан: %v",
+		"could not write the new executable %s: %v":                                                 "новият изпълним файл %s не можа да бъде записан: %v",
+		"could not write thumbnail %s: %v":                                                          "миниатюрата %s не можа да бъде записана: %v",
+		"could not write unit %s: %v":                                                               "модулът %s не можа да бъде записан: %v",
+		"invalid age %q: expected a Go duration or a number of days (`d`) or weeks (`w`)":           "невалидна възраст %q: очаква се продължителност във формата на Go или брой дни (`d`) или седмици (`w`)",
+		"invalid broker URL %s":                                                                     "невалиден URL на брокер %s",
+		"invalid cookie %q: expected `name=value`":                                                  "невалидна бисквитка %q: очаква се `име=стойност`",
+		"invalid language %q: %v":                                                                   "невалиден език %q: %v",
+		"invalid log format %q: expected %s or %s":                                                  "невалиден формат на дневника %q: очаква се %s или %s",
+		"invalid log level %q: expected one of %s":                                                  "невалидно ниво на дневника %q: очаква се едно от %s",
+		"invalid override %q: expected `host:ip`":                                                   "невалидно пренасочване %q: очаква се `host:ip`",
+		"invalid parameter pattern %q: %v":                                                          "невалиден шаблон за параметри %q: %v",
+		"invalid size %q: expected a non-negative number optionally followed by K, M, G, T, P or E": "невалиден размер %q: очаква се неотрицателно число, по желание последвано от K, M, G, T, P или E",
+		"no checksum of %s in %s":                                                                   "няма контролна сума на %s в %s",
+		"no mail server (smtp.host) specified in configuration file %s":                             "в конфигурационния файл %s не е зададен пощенски сървър (smtp.host)",
+		"no sender address (smtp.from) specified in configuration file %s":                          "в конфигурационния файл %s не е зададен адрес на подателя (smtp.from)",
+		"profile %s does not specify a URL":                                                         "профилът %s не задава URL",
+		"release %s has no binary for %s/%s (%s)":                                                   "изданието %s няма изпълним файл за %s/%s (%s)",
+		"release %s has no checksums (%s); refusing to install an unverified binary":                "изданието %s няма контролни суми (%s); отказва се инсталирането на непроверен изпълним файл",
+		"release %s has no signature (%s); refusing to install an unverified binary":                "изданието %s няма подпис (%s); отказва се инсталирането на непроверен изпълним файл",
+		"the archive in %s was fetched from a URL template; record into another directory":          "архивът в %s е изтеглен от шаблон на URL; записвайте в друга директория",
+		"the checksum of %s does not match the one in %s":                                           "контролната сума на %s не съвпада с тази в %s",
+		"the running version (%s) is unknown, so it cannot be compared with the latest release %s; pass -force to install it anyway": "изпълняваната версия (%s) е неизвестна, така че не може да бъде сравнена с последното издание %s; подайте -force, за да бъде инсталирано въпреки това",
+		"the signature of the checksums of release %s is invalid":                                                                    "подписът на контролните суми на изданието %s е невалиден",
+		"the terminal UI requires both the standard input and the standard output to be a terminal":                                  "терминалният потребителски интерфейс изисква и стандартният вход, и стандартният изход да бъдат терминал",
+		"timed out connecting to %s": "времето за свързване с %s изтече",
+		"unsupported broker URL scheme %s (use nats, tls, mqtt, mqtts, tcp, ssl, ws or wss)": "неподдържана схема на URL на брокер %s (използвайте nats, tls, mqtt, mqtts, tcp, ssl, ws или wss)",
+		"unterminated quote or escape in command %q":                                         "незатворени кавички или екраниране в командата %q",
+		"webhook responded with status %s":                                                   "уеб куката отговори със състояние %s",
+		"%v; the remaining pages will be fetched by the next run (see the verify command)":   "%v; останалите страници ще бъдат изтеглени при следващото изпълнение (вижте командата verify)",
+		"challenged by an anti-bot service; pass the challenge in a browser and supply its clearance cookies with -clearance-cookie and its User-Agent with -user-agent": "засечена е проверка от услуга за защита от ботове; преминете проверката в браузър и подайте бисквитките му за достъп с -clearance-cookie и неговия User-Agent с -user-agent",
+		"interrupted": "прекъснато",
+		"neither notify-send nor gdbus is installed": "нито notify-send, нито gdbus е инсталиран",
+		"no page has been recorded yet":              "все още не е записана нито една страница",
+		"no space left on device":                    "няма свободно място на устройството",
+		"some pages could not be fetched; they will be fetched again by the next run (see the verify command)": "някои страници не можаха да бъдат изтеглени; те ще бъдат изтеглени отново при следващото изпълнение (вижте командата verify)",
+		"the public key of the releases built into the program is invalid":                                     "вграденият в програмата публичен ключ на изданията е невалиден",
+		"the run deadline passed": "крайният срок на изпълнението изтече",
+
+		// log messages
+		"interrupted; waiting for the pages being fetched to give up (interrupt again to exit immediately)...": "прекъснато; изчакване страниците, които се изтеглят, да се откажат (прекъснете отново за незабавен изход)...",
+		"authentication with the forum failed":                               "удостоверяването пред форума е неуспешно",
+		"could not show a desktop notification: %v":                          "не можа да бъде показано известие на работния плот: %v",
+		"could not post to the %s webhook: %v":                               "не можа да бъде изпратено съобщение към уеб куката на %s: %v",
+		"All pages are OK.":                                                  "Всички страници са наред.",
+		"All pages are OK; nothing to repair.":                               "Всички страници са наред; няма нищо за поправяне.",
+		"No failures to retry.":                                              "Няма неуспехи за повторение.",
+		"No changes of the posts have been recorded.":                        "Не са записани промени на мненията.",
+		"Retrying %d pages...":                                               "Повторно изтегляне на %d страници...",
+		"Serving %s on http://%s/...":                                        "Обслужване на %s на http://%s/...",
+		"Recording %s through the proxy on %s...":                            "Записване на %s през прокси сървъра на %s...",
+		"Recorded %d pages.":                                                 "Записани са %d страници.",
+		"Discovering the pages of the topic in the sitemaps of the forum...": "Откриване на страниците на темата в картите на сайта на форума...",
+		"Discovered %d pages.":                                               "Открити са %d страници.",
+		"Building the search index of %s...":                                 "Изграждане на индекса за търсене на %s...",
+		"Exported %d posts to %s.":                                           "Експортирани са %d мнения в %s.",
+		"Exported %d images to %s.":                                          "Експортирани са %d изображения в %s.",
+		"Emailed the report to %s.":                                          "Отчетът е изпратен по имейл до %s.",
+		"Wrote unit %s.":                                                     "Записан е модулът %s.",
+		"Probing %s...":                                                      "Проучване на %s...",
+		"Detected the %s engine.":                                            "Открит е двигателят %s.",
+		"could not probe the topic (%v); its settings have to be entered by hand":        "темата не можа да бъде проучена (%v); настройките ѝ трябва да бъдат въведени ръчно",
+		"Testing access to the topic...":                                                 "Проверка на достъпа до темата...",
+		"The topic has %d pages.":                                                        "Темата има %d страници.",
+		"Saved profile %s to %s; fetch the topic with: fetch-forum-topic fetch %s 1-all": "Профилът %s е записан в %s; изтеглете темата с: fetch-forum-topic fetch %s 1-all",
+		"fetch-forum-topic %s is up to date (the latest release is %s).":                 "fetch-forum-topic %s е актуална (последното издание е %s).",
+		"fetch-forum-topic %s is available (running %s): %s":                             "Налична е fetch-forum-topic %s (изпълнява се %s): %s",
+		"Downloading fetch-forum-topic %s...":                                            "Изтегляне на fetch-forum-topic %s...",
+		"Updated %s from %s to %s.":                                                      "%s е обновена от %s до %s.",
+		"%v; searching will not be available":                                            "%v; търсенето няма да бъде налично",
+		"%v; the topic index will not link to the pages":                                 "%v; индексът на темата няма да съдържа връзки към страниците",
+		"Deleted keyring entry %s.":                                                      "Записът %s в ключодържателя е изтрит.",
+		"Downloaded %s into %s":                                                          "%s е изтеглен във файла %s",
+		"EXIF metadata stripped from %d images.":                                         "EXIF метаданните са премахнати от %d изображения.",
+		"Enable it with: systemctl daemon-reload && systemctl enable --now %s.service":   "Разрешете го с: systemctl daemon-reload && systemctl enable --now %s.service",
+		"Enable it with: systemctl daemon-reload && systemctl enable --now %s.timer":     "Разрешете го с: systemctl daemon-reload && systemctl enable --now %s.timer",
+		"Images recovered from fallback sources: %d by retrying with the Referer changed, %d from the Wayback Machine, %d as thumbnails.": "Изображения, възстановени от резервни източници: %d чрез повторен опит с променен Referer, %d от Wayback Machine, %d като миниатюри.",
+		"Linked the injected stylesheet from %d pages.":    "Вмъкнатият стил е свързан от %d страници.",
+		"Linked the print-ready stylesheet from %d pages.": "Стилът за печат е свързан от %d страници.",
+		"Output of the %s command: %s":                     "Изход на командата %s: %s",
+		"Post-processed %s":                                "%s е обработен",
+		"Pushed %d changed files to the mirror at %s.":     "%d променени файла са изпратени към огледалното копие на %s.",
+		"Pushing %d changed files to the mirror at %s...":  "Изпращане на %d променени файла към огледалното копие на %s...",
+		"Recompressed %s from %d to %d bytes":              "%s е прекомпресиран от %d до %d байта",
+		"Recorded %s for page %d":                          "%s е записан за страница %d",
+		"Recorded page %d (%s)":                            "Страница %d е записана (%s)",
+		"Sampling page %d...":                              "Изтегляне на примерна страница %d...",
+		"Saved %d pages in the Wayback Machine.":           "%d страници са запазени в Wayback Machine.",
+		"Saved %s in the Wayback Machine as %s":            "%s е запазен в Wayback Machine като %s",
+		"Stored keyring entry %s; refer to it as %s%s.":    "Записът %s е съхранен в ключодържателя; препращайте към него като %s%s.",
+		"The mirror at %s is up to date.":                  "Огледалното копие на %s е актуално.",
+		"Trust %s in the browser to record HTTPS pages.":   "Удостоверете %s като доверен в браузъра, за да записвате HTTPS страници.",
+		"Uploaded %s":                 "%s е качен",
+		"Uploaded the archive to %s.": "Архивът е качен в %s.",
+		"Uploading %s to the Internet Archive as item %s...":                                                                 "Качване на %s в Internet Archive като елемента %s...",
+		"Waiting for %d pages to be saved in the Wayback Machine...":                                                         "Изчакване %d страници да бъдат запазени в Wayback Machine...",
+		"could not compose the email report: %v":                                                                             "отчетът по имейл не можа да бъде съставен: %v",
+		"could not connect to the notification socket of systemd: %v":                                                        "не можа да бъде осъществена връзка със сокета за известия на systemd: %v",
+		"could not create file %s in which to record %s":                                                                     "файлът %s, в който да бъде записан %s, не можа да бъде създаден",
+		"could not determine relative path to the injected stylesheet %s from page %d":                                       "относителният път до вмъкнатия стил %s от страница %d не можа да бъде определен",
+		"could not determine the size of asset %s":                                                                           "размерът на ресурса %s не можа да бъде определен",
+		"could not encode the run report for the %s command: %v":                                                             "отчетът за изпълнението не можа да бъде кодиран за командата %s: %v",
+		"could not export the remaining traces to %s: %v":                                                                    "останалите трасировки не можаха да бъдат експортирани към %s: %v",
+		"could not flush the events published to %s: %v":                                                                     "събитията, публикувани в %s, не можаха да бъдат изпратени: %v",
+		"could not notify %s about the completion of the run: %v":                                                            "%s не можа да бъде известен за завършването на изпълнението: %v",
+		"could not notify %s about the failure of page %d: %v":                                                               "%s не можа да бъде известен за неуспеха на страница %d: %v",
+		"could not notify systemd: %v":                                                                                       "systemd не можа да бъде известен: %v",
+		"could not open archived page %s":                                                                                    "архивираната страница %s не можа да бъде отворена",
+		"could not parse URL of page %d":                                                                                     "URL на страница %d не можа да бъде анализиран",
+		"could not parse archived page %s":                                                                                   "архивираната страница %s не можа да бъде анализирана",
+		"could not publish the completion of the run to %s: %v":                                                              "завършването на изпълнението не можа да бъде публикувано в %s: %v",
+		"could not publish the event about page %d to %s: %v":                                                                "събитието за страница %d не можа да бъде публикувано в %s: %v",
+		"could not read archived page %s":                                                                                    "архивираната страница %s не можа да бъде прочетена",
+		"could not read the content of page %d successfully":                                                                 "съдържанието на страница %d не можа да бъде прочетено успешно",
+		"could not read the manifest of Wayback Machine snapshots: %v":                                                       "манифестът на снимките в Wayback Machine не можа да бъде прочетен: %v",
+		"could not read the settings of the topic: %v":                                                                       "настройките на темата не можаха да бъдат прочетени: %v",
+		"could not record %s in file %s":                                                                                     "%s не можа да бъде записан във файла %s",
+		"could not record the Wayback Machine snapshot of %s: %v":                                                            "снимката в Wayback Machine на %s не можа да бъде записана: %v",
+		"could not record the original URL of %s":                                                                            "оригиналният URL на %s не можа да бъде записан",
+		"could not render the topic index: %v":                                                                               "индексът на темата не можа да бъде изобразен: %v",
+		"could not resolve the credentials for the mail server: %v":                                                          "данните за удостоверяване пред пощенския сървър не можаха да бъдат разрешени: %v",
+		"could not save %s in the Wayback Machine: %v":                                                                       "%s не можа да бъде запазен в Wayback Machine: %v",
+		"could not send the email report through %s: %v":                                                                     "отчетът по имейл не можа да бъде изпратен през %s: %v",
+		"could not update the search index: %v":                                                                              "индексът за търсене не можа да бъде обновен: %v",
+		"could not write API response: %v":                                                                                   "отговорът на API не можа да бъде записан: %v",
+		"could not write archived page %s":                                                                                   "архивираната страница %s не можа да бъде записана",
+		"could not write metadata file %s":                                                                                   "файлът с метаданни %s не можа да бъде записан",
+		"ignoring -http3, since Tor only carries TCP connections":                                                            "-http3 се пренебрегва, тъй като Tor пренася само TCP връзки",
+		"ignoring -resolve, -dns-server and -dns-cache, since Tor resolves the names of the hosts itself":                    "-resolve, -dns-server и -dns-cache се пренебрегват, тъй като Tor сам разрешава имената на хостовете",
+		"ignoring -tor, -http3, -resolve, -dns-server and -dns-cache, since all the connections are made to the Unix socket": "-tor, -http3, -resolve, -dns-server и -dns-cache се пренебрегват, тъй като всички връзки се осъществяват към Unix сокета",
+		"not recording %s: %v":                                                                                               "%s не се записва: %v",
+		"page %d already links an injected stylesheet like %s":                                                               "страница %d вече препраща към вмъкнат стил като %s",
+		"skipping image %s: %v":                                                                                              "изображението %s се пропуска: %v",
+		"the %s command failed for page %d: %v":                                                                              "командата %s е неуспешна за страница %d: %v",
+		"the %s command failed: %v":                                                                                          "командата %s е неуспешна: %v",
+		"the program has been built without the public key of the releases; only verifying the checksum of the binary":       "програмата е компилирана без публичния ключ на изданията; проверява се само контролната сума на изпълнимия файл",
+		"Pages: %d attempted, %d succeeded, %d failed, %d skipped, %d pending; resources: %d fetched, %d failed; %s downloaded in %s.": "Страници: %d опитани, %d успешни, %d неуспешни, %d пропуснати, %d чакащи; ресурси: %d изтеглени, %d неуспешни; %s изтеглени за %s.",
+		"Post-processing: %d files processed, %d failed.": "Последваща обработка: %d обработени файла, %d неуспешни.",
+
+		// prompts and output
+		" by %s":                         " от %s",
+		"%d bytes":                       "%d байта",
+		"%s: page %d: post %s by %s: %s": "%s: страница %d: мнение %s от %s: %s",
+		"(HEAD request failed: %v)":      "(HEAD заявката е неуспешна: %v)",
+		"(at %s/s)":                      "(при %s/s)",
+		"(size unknown)":                 "(неизвестен размер)",
+		"(sizes of only %d out of %d sampled assets were probed)": "(проучени са размерите само на %d от %d примерни ресурса)",
+		", post %s":             ", мнение %s",
+		"Average page weight:":  "Среден обем на страница:",
+		"Base URL of the topic": "Базов URL на темата",
+		"Credentials for HTTP basic authentication, of the form env:NAME or keyring:NAME (empty for none)":         "Данни за HTTP basic удостоверяване във вида env:ИМЕ или keyring:ИМЕ (празно, ако няма)",
+		"Credentials for logging in through the login form, of the form env:NAME or keyring:NAME (empty for none)": "Данни за влизане през формата за вход във вида env:ИМЕ или keyring:ИМЕ (празно, ако няма)",
+		"Estimated total download size:":         "Очакван общ размер за изтегляне:",
+		"Estimated unique assets:":               "Очакван брой уникални ресурси:",
+		"Expected page count:":                   "Очакван брой страници:",
+		"Name of the profile":                    "Име на профила",
+		"Number of posts on a full page":         "Брой мнения на пълна страница",
+		"Profile %s already exists; replace it?": "Профилът %s вече съществува; да бъде ли заменен?",
+		"Projected duration:":                    "Очаквана продължителност:",
+		"Sampled pages:":                         "Примерни страници:",
+		"Save the profile anyway?":               "Да бъде ли записан профилът въпреки това?",
+		"Secret for %s: ":                        "Тайна за %s: ",
+		"Target directory of the archive (empty for the current directory of every run)": "Целева директория на архива (празно за текущата директория при всяко изпълнение)",
+		"The forum software could not be detected; engine (one of: %s)":                  "Софтуерът на форума не можа да бъде открит; двигател (един от: %s)",
+		"URL of a page of the forum topic":                                               "URL на страница от темата във форума",
+		"download failed during the last run":                                            "изтеглянето е неуспешно при последното изпълнение",
+		"page %d":                                                                        "страница %d",
+		"page %d: %s":                                                                    "страница %d: %s",
+		"page %d: %s: %s":                                                                "страница %d: %s: %s",
+		"page %d: resource %s: %s: %s":                                                   "страница %d: ресурс %s: %s: %s",
+		"page has not been fetched":                                                      "страницата не е изтеглена",
+		"page is not recorded in the map of original URLs":                               "страницата не е записана в картата на оригиналните URL адреси",
+		"Base URL of the pages, to which the offset of the first post on a page is appended (e.g. https://forum.example.com/topic?id=1&offset=)": "Базов URL на страниците, към който се добавя отместването на първото мнение на страница (напр. https://forum.example.com/topic?id=1&offset=)",
+		"elapsed %s | pages: %d/%d done, %d running, %d failed | resources: %d | %s (%s/s)":                                                      "изминало време %s | страници: %d/%d готови, %d в ход, %d неуспешни | ресурси: %d | %s (%s/с)",
+		"PAGE":      "СТРАНИЦА",
+		"ATTEMPT":   "ОПИТ",
+		"RESOURCES": "РЕСУРСИ",
+		"ELAPSED":   "ВРЕМЕ",
+		"Failures (j/k: select, r: requeue selected, R: requeue all, q: quit):": "Неуспехи (j/k: избор, r: повторение на избрания, R: повторение на всички, q: изход):",
+		"%s %6d  attempt %d: %v":            "%s %6d  опит %d: %v",
+		"Waiting for %d pages to finish...": "Изчакване %d страници да завършат...",
+		"Log:":                              "Дневник:",
+
+		// messages of the fetch library
+		"%s from %s": "%s от %s",
+		"Archived %d referenced topics; linking to the others as they are":    "Архивирани са %d теми, към които има препратки; към останалите се препраща както са",
+		"Archived copy of page %d was last updated at %s; will refresh it...": "Архивираното копие на страница %d е обновено за последно в %s; то ще бъде опреснено...",
+		"Captured preview of %s":                                               "Заснет е преглед на %s",
+		"Converted %s from %s to %s":                                           "Успешно преобразуване на %s от %s в %s",
+		"Detected last page: %d":                                               "Открита е последната страница: %d",
+		"Discovered %d pages of the topic in %d sitemaps.":                     "Открити са %d страници на темата в %d карти на сайта.",
+		"Downloading %s...":                                                    "Изтегляне на %s...",
+		"Fetched %s from its fallback source %s":                               "Успешно изтегляне на %s от резервния източник %s",
+		"Finished the fetching of page %d.":                                    "Изтеглянето на страница %d завърши.",
+		"Found failed downloads of the last run; will reattempt them...":       "Открити са неуспешни изтегляния от последното изпълнение; те ще бъдат опитани отново...",
+		"Found pages left pending by the last run; will fetch them...":         "Открити са страници, оставени чакащи от последното изпълнение; те ще бъдат изтеглени...",
+		"Leaving page %d pending since the download budget has been exhausted": "Страница %d остава чакаща, тъй като бюджетът за изтегляне е изчерпан",
+		"Leaving page %d pending since the run ended outside the active hours": "Страница %d остава чакаща, тъй като изпълнението приключи извън активните часове",
+		"Leaving page %d pending since the run has ended":                      "Страница %d остава чакаща, тъй като изпълнението приключи",
+		"Loaded %d posts of the topic so far":                                  "Заредени са %d мнения от темата досега",
+		"Logged in to %s as %s.":                                               "Успешно влизане в %s като %s.",
+		"Outside the active hours %s; pausing until %s":                        "Извън активните часове %s; пауза до %s",
+		"Pages for which download will be reattempted: %s":                     "Страници, чието изтегляне ще бъде опитано отново: %s",
+		"Post %s by %s on page %d has been deleted":                            "Мнението %s от %s на страница %d е изтрито",
+		"Post %s by %s on page %d has been edited":                             "Мнението %s от %s на страница %d е редактирано",
+		"Post %s by %s on page %d has been restored":                           "Мнението %s от %s на страница %d е възстановено",
+		"Skipping %s, which has already been stored":                           "Пропускане на %s: вече има съхранено копие",
+		"Skipping %s, which is filtered out or beyond the download budget":     "Пропускане на %s: изключено е от филтрите или надхвърля ограничението за изтегляне",
+		"Skipping %s, which the host does not serve":                           "Пропускане на %s: не се обслужва от хоста",
+		"Starting the fetching of page %d...":                                  "Започване на изтеглянето на страница %d...",
+		"Stored %s in file %s":                                                 "Успешно съхраняване на %s във файла %s",
+		"Stored the thumbnail of %s in file %s":                                "Успешно съхраняване на миниатюрата на %s във файла %s",
+		"Stripped %d bytes of EXIF metadata from %s":                           "Премахнати са %d байта EXIF метаданни от %s",
+		"The session has expired; logging in to %s again...":                   "Сесията е изтекла; повторно влизане в %s...",
+		"Trying to fetch %s from its fallback source %s (%s)":                  "Опит за изтегляне на %s от резервния източник %s (%s)",
+		"batch of posts": "порция мнения",
+		"could not carry over the failed downloads of the last run: %v":                                 "неуспешно пренасяне на неуспешните изтегляния от последното изпълнение: %v",
+		"could not clear the pages left pending by the last run: %v":                                    "неуспешно изчистване на страниците, оставени чакащи от последното изпълнение: %v",
+		"could not convert %s, so it is stored as it is: %v":                                            "неуспешно преобразуване на %s, така че се съхранява както е: %v",
+		"could not count the failed downloads of the pages: %v":                                         "неуспешно преброяване на неуспешните изтегляния на страниците: %v",
+		"could not create file %s in which to write the content of %s":                                  "неуспешно създаване на файла %s, в който да бъде записано съдържанието на %s",
+		"could not create metadata file %s":                                                             "неуспешно създаване на файла с метаданни %s",
+		"could not create temporary directory for %s":                                                   "неуспешно създаване на временна директория за %s",
+		"could not determine relative path to resource %s":                                              "неуспешно определяне на относителния път до ресурса %s",
+		"could not determine relative path to smiley %s":                                                "неуспешно определяне на относителния път до емотикона %s",
+		"could not determine relative path to the thumbnail of %s":                                      "неуспешно определяне на относителния път до миниатюрата на %s",
+		"could not determine relative path to video %s":                                                 "неуспешно определяне на относителния път до видеоклипа %s",
+		"could not determine the URL of the login page of the forum; the login credentials are ignored": "URL на страницата за вход във форума не можа да бъде определен; данните за влизане се пренебрегват",
+		"could not determine the age of the archived copy of page %d in %s":                             "неуспешно определяне на възрастта на архивираното копие на страница %d в %s",
+		"could not download %s: %v":                                                                     "неуспешно изтегляне на %s: %v",
+		"could not fetch %s for its preview: %v":                                                        "неуспешно изтегляне на %s за преглед: %v",
+		"could not fetch %s: %v":                                                                        "неуспешно изтегляне на %s: %v",
+		"could not fetch %s: HTTP GET request failed":                                                   "неуспешно изтегляне на %s: HTTP GET заявката е неуспешна",
+		"could not find the posts on page %d with the %s engine; keeping the whole page":                "мненията на страница %d не можаха да бъдат намерени с двигателя %s; запазва се цялата страница",
+		"could not load the posts of page %d: %v":                                                       "неуспешно зареждане на мненията на страница %d: %v",
+		"could not log in to %s: %v":                                                                    "неуспешно влизане в %s: %v",
+		"could not materialize page %d: %v":                                                             "неуспешно съставяне на страница %d: %v",
+		"could not open the downloaded %s":                                                              "неуспешно отваряне на изтегления файл на %s",
+		"could not parse URL of resource %s":                                                            "неуспешен анализ на URL на ресурса %s",
+		"could not parse oEmbed response %s: %v":                                                        "неуспешен анализ на oEmbed отговора %s: %v",
+		"could not parse page %d to track its posts: %v":                                                "неуспешен анализ на страница %d за проследяване на мненията ѝ: %v",
+		"could not read %s for its preview: %v":                                                         "неуспешно прочитане на %s за преглед: %v",
+		"could not read sitemap %s: %v":                                                                 "неуспешно прочитане на картата на сайта %s: %v",
+		"could not read the content of %s successfully":                                                 "неуспешно прочитане на съдържанието на %s",
+		"could not read the map of smileys (%s): %v":                                                    "неуспешно прочитане на картата на емотиконите (%s): %v",
+		"could not read the pages left pending by the last run: %v":                                     "неуспешно прочитане на страниците, оставени чакащи от последното изпълнение: %v",
+		"could not record smiley %s in the map of smileys":                                              "неуспешно записване на емотикона %s в картата на емотиконите",
+		"could not record the deleted posts: %v":                                                        "неуспешно записване на изтритите мнения: %v",
+		"could not record the failed download of %s of page %d: %v":                                     "неуспешно записване на неуспешното изтегляне на %s от страница %d: %v",
+		"could not record the failed download of page %d: %v":                                           "неуспешно записване на неуспешното изтегляне на страница %d: %v",
+		"could not record the pages left pending: %v":                                                   "неуспешно записване на страниците, оставени чакащи: %v",
+		"could not record the posts of page %d: %v":                                                     "неуспешно записване на мненията на страница %d: %v",
+		"could not stat target directory %s for page %d":                                                "неуспешна проверка на целевата директория %s за страница %d",
+		"could not store %s: %v":                                                                        "неуспешно съхраняване на %s: %v",
+		"could not tell the title of the topic from page %d; keeping its usual name":                    "заглавието на темата не можа да бъде определено от страница %d; запазва се обичайното ѝ име",
+		"could not write part of the content of page %d in file %s successfully":                        "неуспешно записване на част от съдържанието на страница %d във файла %s",
+		"could not write the content of %s in file %s successfully":                                     "неуспешно записване на съдържанието на %s във файла %s",
+		"could not write the thumbnail of %s in file %s successfully":                                   "неуспешно записване на миниатюрата на %s във файла %s",
+		"download budget exhausted; %d pages were left pending and will be fetched by the next run":     "бюджетът за изтегляне е изчерпан; %d страници останаха чакащи и ще бъдат изтеглени при следващото изпълнение",
+		"dropping %s from a domain blocked by -block-domain":                                            "премахване на %s от домейн, блокиран с -block-domain",
+		"fetching of page %d failed":                                                                    "изтеглянето на страница %d е неуспешно",
+		"page %d did not finish within %s":                                                              "страница %d не завърши в рамките на %s",
+		"page %d is not listed in the sitemaps; assuming %s":                                            "страница %d не е изброена в картите на сайта; приема се %s",
+		"post-processing of file %s failed: %v":                                                         "последващата обработка на файла %s е неуспешна: %v",
+		"refusing to write the content of %s: %v":                                                       "отказ от записване на съдържанието на %s: %v",
+		"resource %s":                           "ресурс %s",
+		"rewrite of the reference to %s vetoed": "пренаписването на препратката към %s е отхвърлено",
+		"site icon %s":                          "икона на сайта %s",
+		"sitemap %s":                            "карта на сайта %s",
+		"skipping %s filtered out by -accept or -reject":                               "пропускане на %s поради -accept или -reject",
+		"skipping %s from a domain not allowed by -allow-domain":                       "пропускане на %s от домейн, който не е разрешен с -allow-domain",
+		"skipping %s of %d bytes, which is larger than the maximum size of resources":  "пропускане на %s с размер %d байта, който е по-голям от максималния размер на ресурсите",
+		"skipping %s of class %s":                                                      "пропускане на %s от класа %s",
+		"smiley %s":                                                                    "емотикон %s",
+		"stopped discovering the pages of the topic after reading %d sitemaps":         "откриването на страниците на темата спря след прочитането на %d карти на сайта",
+		"the %s engine does not support logging in; the login credentials are ignored": "двигателят %s не поддържа влизане; данните за влизане се пренебрегват",
+		"the thumbnail of %s":                                                          "миниатюрата на %s",
+		"video %s":                                                                     "видеоклип %s",
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+	"golang.org/x/term"
+)
+
+// logLevel is the severity of a log message; messages less severe than the current log level are suppressed.
+type logLevel int
+
+const (
+	logLevelError logLevel = iota
+	logLevelWarning
+	logLevelInfo
+	logLevelDebug
+)
+
+var logLevelNames = []string{"error", "warn", "info", "debug"}
+
+var slogLevels = []slog.Level{slog.LevelError, slog.LevelWarn, slog.LevelInfo, slog.LevelDebug}
+
+// slogLevelSuccess is used for informational messages which report that something has been completed successfully,
+// so that they can be highlighted.
+const slogLevelSuccess = slog.LevelInfo + 2
+
+var currentLogLevel = logLevelInfo
+
+func (level *logLevel) String() string {
+	if *level < logLevelError || *level > logLevelDebug {
+		return fmt.Sprint(int(*level))
+	}
+
+	return logLevelNames[*level]
+}
+
+func (level *logLevel) Set(value string) error {
+	for index, name := range logLevelNames {
+		if strings.EqualFold(value, name) {
+			*level = logLevel(index)
+			return nil
+		}
+	}
+	if strings.EqualFold(value, "warning") {
+		*level = logLevelWarning
+		return nil
+	}
+
+	return errorf("invalid log level %q: expected one of %s", value, strings.Join(logLevelNames, ", "))
+}
+
+func (level logLevel) slogLevel() slog.Level {
+	return slogLevels[level]
+}
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+var logFormat = logFormatText
+
+var logOutput io.Writer = os.Stderr
+
+// shouldDisableColor turns off the coloring of the console output, which is otherwise enabled when the log output is a
+// terminal and the NO_COLOR environment variable is not set.
+var shouldDisableColor bool
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorGreen  = "\x1b[32m"
+	colorDim    = "\x1b[2m"
+)
+
+func isColorEnabled() bool {
+	if shouldDisableColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	file, ok := logOutput.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// colorize wraps the message in the given color if colors are enabled.
+func colorize(color, message string) string {
+	if !isColorEnabled() {
+		return message
+	}
+
+	return color + message + colorReset
+}
+
+var logger = slog.New(redact.Handler(newConsoleHandler(os.Stderr, logLevelInfo.slogLevel())))
+
+// setUpLogger makes the logger output messages in the current log format and at the current log level.
+func setUpLogger() error {
+	options := &slog.HandlerOptions{
+		Level: currentLogLevel.slogLevel(),
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.LevelKey && len(groups) == 0 && attr.Value.Any() == slogLevelSuccess {
+				attr.Value = slog.StringValue("SUCCESS")
+			}
+			return attr
+		},
+	}
+
+	var handler slog.Handler
+	switch logFormat {
+	case logFormatText:
+		consoleHandler := newConsoleHandler(logOutput, options.Level)
+		consoleHandler.useColor = isColorEnabled()
+		handler = consoleHandler
+	case logFormatJSON:
+		handler = slog.NewJSONHandler(logOutput, options)
+	default:
+		return errorf("invalid log format %q: expected %s or %s", logFormat, logFormatText, logFormatJSON)
+	}
+
+	if logFilename != "" {
+		filename := logFilename
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(targetDir, filename)
+		}
+		// the target directory may have been changed by a profile since the log file was opened
+		if logFile != nil && logFile.filename != filename {
+			closeLogFile()
+		}
+		if logFile == nil {
+			var err error
+			logFile, err = openRotatingFile(filename, int64(logFileMaxSize))
+			if err != nil {
+				return errorf("could not open log file %s: %v", filename, err)
+			}
+		}
+
+		fileOptions := *options
+		fileOptions.Level = slog.LevelDebug
+		var fileHandler slog.Handler
+		if logFormat == logFormatJSON {
+			fileHandler = slog.NewJSONHandler(logFile, &fileOptions)
+		} else {
+			fileHandler = newConsoleHandler(logFile, fileOptions.Level)
+		}
+		handler = multiHandler{handler, fileHandler}
+	}
+
+	// credentials must not end up in the logs, even the verbose ones
+	logger = slog.New(redact.Handler(handler))
+
+	slog.SetDefault(logger)
+	return nil
+}
+
+// currentLogHandler forwards the records to the handler of the logger as it is set up at the time of logging, so that
+// loggers created before the logger is set up again (e.g. by the terminal UI) still follow it.
+type currentLogHandler struct{}
+
+func (currentLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return logger.Handler().Enabled(ctx, level)
+}
+
+func (currentLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	return logger.Handler().Handle(ctx, record)
+}
+
+func (currentLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return logger.Handler().WithAttrs(attrs)
+}
+
+func (currentLogHandler) WithGroup(name string) slog.Handler {
+	return logger.Handler().WithGroup(name)
+}
+
+func isLogLevelEnabled(level logLevel) bool {
+	return level <= currentLogLevel
+}
+
+func logErrorf(format string, args ...interface{}) {
+	logger.Error(translatef(format, args...))
+}
+
+func logWarningf(format string, args ...interface{}) {
+	logger.Warn(translatef(format, args...))
+}
+
+func logInfof(format string, args ...interface{}) {
+	logger.Info(translatef(format, args...))
+}
+
+func logSuccessf(format string, args ...interface{}) {
+	logger.Log(context.Background(), slogLevelSuccess, translatef(format, args...))
+}
+
+func logDebugf(format string, args ...interface{}) {
+	logger.Debug(translatef(format, args...))
+}
+
+// consoleHandler formats log records for humans the way the standard `log` package does, prefixing errors and warnings
+// and appending the attributes as `key=value` pairs; if colors are used, the messages are colored according to their level.
+type consoleHandler struct {
+	level    slog.Leveler
+	output   io.Writer
+	mutex    *sync.Mutex
+	prefix   string
+	attrs    string
+	useColor bool
+}
+
+func newConsoleHandler(output io.Writer, level slog.Leveler) *consoleHandler {
+	return &consoleHandler{
+		level:  level,
+		output: output,
+		mutex:  &sync.Mutex{},
+	}
+}
+
+func (handler *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= handler.level.Level()
+}
+
+func (handler *consoleHandler) formatAttr(builder *strings.Builder, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		groupHandler := *handler
+		groupHandler.prefix += attr.Key + "."
+		for _, groupAttr := range attr.Value.Group() {
+			groupHandler.formatAttr(builder, groupAttr)
+		}
+		return
+	}
+
+	fmt.Fprintf(builder, " %s%s=%v", handler.prefix, attr.Key, attr.Value)
+}
+
+func (handler *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var builder strings.Builder
+	if !record.Time.IsZero() {
+		builder.WriteString(record.Time.Format("2006/01/02 15:04:05 "))
+	}
+
+	color := ""
+	switch {
+	case record.Level >= slog.LevelError:
+		color = colorRed
+	case record.Level >= slog.LevelWarn:
+		color = colorYellow
+	case record.Level >= slogLevelSuccess:
+		color = colorGreen
+	case record.Level < slog.LevelInfo:
+		color = colorDim
+	}
+	if handler.useColor && color != "" {
+		builder.WriteString(color)
+	}
+
+	switch {
+	case record.Level >= slog.LevelError:
+		builder.WriteString(translate("error: "))
+	case record.Level >= slog.LevelWarn:
+		builder.WriteString(translate("warning: "))
+	}
+	builder.WriteString(record.Message)
+	builder.WriteString(handler.attrs)
+
+	record.Attrs(func(attr slog.Attr) bool {
+		handler.formatAttr(&builder, attr)
+		return true
+	})
+	if handler.useColor && color != "" {
+		builder.WriteString(colorReset)
+	}
+	builder.WriteByte('\n')
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	_, err := io.WriteString(handler.output, builder.String())
+	return err
+}
+
+func (handler *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var builder strings.Builder
+	for _, attr := range attrs {
+		handler.formatAttr(&builder, attr)
+	}
+
+	newHandler := *handler
+	newHandler.attrs += builder.String()
+	return &newHandler
+}
+
+func (handler *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return handler
+	}
+
+	newHandler := *handler
+	newHandler.prefix += name + "."
+	return &newHandler
+}
+
+// logFilename is the path of the file to which all messages, including the debug ones, are written in addition to the
+// console; a relative path is resolved against the target directory.
+var logFilename string
+
+// logFileMaxSize is the size beyond which the log file is rotated.
+var logFileMaxSize = byteCount(10 << 20)
+
+// logFileBackupCount is the number of rotated log files which are kept besides the current one.
+const logFileBackupCount = 5
+
+var logFile *rotatingFile
+
+// rotatingFile is a file which is renamed to `name.1` (shifting the older ones to `name.2` and so on) and started anew
+// whenever writing to it would make it larger than its maximum size.
+type rotatingFile struct {
+	mutex    sync.Mutex
+	filename string
+	maxSize  int64
+	size     int64
+	file     *os.File
+}
+
+func openRotatingFile(filename string, maxSize int64) (file *rotatingFile, err error) {
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return
+	}
+
+	file = &rotatingFile{
+		filename: filename,
+		maxSize:  maxSize,
+	}
+	err = file.open()
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// closeLogFile closes the log file, removing it if nothing has been written to it, so that opening it again in another
+// directory leaves no empty file behind.
+func closeLogFile() {
+	file := logFile
+	logFile = nil
+
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	file.file.Close()
+	if file.size == 0 {
+		os.Remove(file.filename)
+	}
+}
+
+func (file *rotatingFile) open() (err error) {
+	file.file, err = os.OpenFile(file.filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+
+	info, err := file.file.Stat()
+	if err != nil {
+		file.file.Close()
+		return
+	}
+
+	file.size = info.Size()
+	return
+}
+
+func (file *rotatingFile) rotate() (err error) {
+	file.file.Close()
+
+	for i := logFileBackupCount - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", file.filename, i), fmt.Sprintf("%s.%d", file.filename, i+1))
+	}
+	err = os.Rename(file.filename, file.filename+".1")
+	if err != nil {
+		return
+	}
+
+	return file.open()
+}
+
+func (file *rotatingFile) Write(p []byte) (n int, err error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if file.maxSize > 0 && file.size > 0 && file.size+int64(len(p)) > file.maxSize {
+		err = file.rotate()
+		if err != nil {
+			return
+		}
+	}
+
+	n, err = file.file.Write(p)
+	file.size += int64(n)
+	return
+}
+
+// multiHandler passes log records to several handlers, each of which decides on its own whether to handle them.
+type multiHandler []slog.Handler
+
+func (handlers multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (handlers multiHandler) Handle(ctx context.Context, record slog.Record) (err error) {
+	for _, handler := range handlers {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+
+		handlerErr := handler.Handle(ctx, record.Clone())
+		if handlerErr != nil {
+			err = handlerErr
+		}
+	}
+
+	return
+}
+
+func (handlers multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newHandlers := make(multiHandler, len(handlers))
+	for i, handler := range handlers {
+		newHandlers[i] = handler.WithAttrs(attrs)
+	}
+
+	return newHandlers
+}
+
+func (handlers multiHandler) WithGroup(name string) slog.Handler {
+	newHandlers := make(multiHandler, len(handlers))
+	for i, handler := range handlers {
+		newHandlers[i] = handler.WithGroup(name)
+	}
+
+	return newHandlers
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+)
+
+// mirrorDestination is the remote location to which the archive is pushed after every run: an sftp:// URL, to which
+// the archive is uploaded by the program itself, a remote of rclone prefixed with rclone: (e.g. rclone:backup:forum),
+// or anything else which rsync accepts as a destination (e.g. user@host:forum).
+var mirrorDestination string
+
+// mirrorManifestFilename is the name of the file in the archive with the checksums of the files as they were last
+// pushed to every destination, by which only the changed files are transferred.
+const mirrorManifestFilename = ".mirror.json"
+
+// mirroredFile is the state of a file of the archive when it was last pushed to a destination. The size and the
+// modification time let the checksum be reused as long as the file has not been touched.
+type mirroredFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256"`
+}
+
+// mirrorManifest maps every destination, with the credentials in it masked, to the files pushed to it by their path
+// relative to the target directory.
+type mirrorManifest map[string]map[string]*mirroredFile
+
+func loadMirrorManifest() (manifest mirrorManifest, err error) {
+	filename := filepath.Join(targetDir, mirrorManifestFilename)
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return mirrorManifest{}, nil
+	}
+	if err != nil {
+		return nil, errorf("could not read mirror manifest %s: %v", filename, err)
+	}
+
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, errorf("could not parse mirror manifest %s: %v", filename, err)
+	}
+	return
+}
+
+func (manifest mirrorManifest) save() (err error) {
+	filename := filepath.Join(targetDir, mirrorManifestFilename)
+	file, err := archive.Create(filename)
+	if err != nil {
+		return errorf("could not create mirror manifest %s", filename)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "\t")
+	err = encoder.Encode(manifest)
+	if err != nil {
+		return errorf("could not write mirror manifest %s", filename)
+	}
+	return
+}
+
+func computeFileChecksum(filename string) (checksum string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// collectChangedFiles returns the state of the files of the archive, reusing the checksums of the previously pushed
+// files which have not been touched since, and the sorted paths of the ones which differ from the previously pushed
+// ones.
+func collectChangedFiles(previousFiles map[string]*mirroredFile) (files map[string]*mirroredFile, changedPaths []string, err error) {
+	files = map[string]*mirroredFile{}
+	err = filepath.Walk(targetDir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(targetDir, filename)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if relativePath == mirrorManifestFilename {
+			return nil
+		}
+
+		file := &mirroredFile{Size: info.Size(), ModTime: info.ModTime().UTC()}
+		previousFile := previousFiles[relativePath]
+		if previousFile != nil && previousFile.Size == file.Size && previousFile.ModTime.Equal(file.ModTime) {
+			file.SHA256 = previousFile.SHA256
+		} else {
+			file.SHA256, err = computeFileChecksum(filename)
+			if err != nil {
+				return err
+			}
+		}
+		files[relativePath] = file
+
+		if previousFile == nil || previousFile.SHA256 != file.SHA256 {
+			changedPaths = append(changedPaths, relativePath)
+		}
+		return nil
+	})
+	sort.Strings(changedPaths)
+	return
+}
+
+// runMirrorCommand runs the rsync or rclone command with the list of the changed files, one per line, written to a
+// temporary file, whose name is substituted for `{}` in the arguments.
+func runMirrorCommand(ctx context.Context, changedPaths []string, name string, args ...string) (err error) {
+	listFile, err := os.CreateTemp("", "fetch-forum-topic-mirror-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(listFile.Name())
+	_, err = listFile.WriteString(strings.Join(changedPaths, "\n") + "\n")
+	if closeErr := listFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, "{}", listFile.Name())
+	}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err = cmd.Run()
+	if err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return errorf("%s: %v: %s", name, err, message)
+		}
+		return errorf("%s: %v", name, err)
+	}
+	return
+}
+
+// uploadMirrorFiles uploads the files to the directory at the sftp:// URL, creating the directories along their paths.
+func uploadMirrorFiles(ctx context.Context, destinationURL *url.URL, changedPaths []string) (err error) {
+	client, err := transport.DialSFTP(ctx, destinationURL, newFileTransferDial())
+	if err != nil {
+		return errorf("could not connect to %s: %v", destinationURL.Host, err)
+	}
+	defer client.Close()
+
+	for _, relativePath := range changedPaths {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		remotePath := path.Join(destinationURL.Path, relativePath)
+		err = client.MkdirAll(path.Dir(remotePath))
+		if err != nil {
+			return errorf("could not create remote directory %s: %v", path.Dir(remotePath), err)
+		}
+		err = uploadMirrorFile(client, filepath.Join(targetDir, filepath.FromSlash(relativePath)), remotePath)
+		if err != nil {
+			return errorf("could not upload %s: %v", relativePath, err)
+		}
+		logDebugf("Uploaded %s", relativePath)
+	}
+	return
+}
+
+func uploadMirrorFile(client *transport.SFTPClient, filename, remotePath string) (err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	remoteFile, err := client.Create(remotePath)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(remoteFile, file)
+	if closeErr := remoteFile.Close(); err == nil {
+		err = closeErr
+	}
+	return
+}
+
+// pushMirrorFiles transfers the changed files of the archive to the destination.
+func pushMirrorFiles(ctx context.Context, destination string, changedPaths []string) error {
+	source := targetDir + string(filepath.Separator)
+	if strings.HasPrefix(destination, "sftp://") {
+		destinationURL, err := url.Parse(destination)
+		if err != nil {
+			return usageErrorf("invalid mirror destination %s: %v", redact.URLString(destination), err)
+		}
+		return uploadMirrorFiles(ctx, destinationURL, changedPaths)
+	}
+	if remote, ok := strings.CutPrefix(destination, "rclone:"); ok {
+		return runMirrorCommand(ctx, changedPaths, "rclone", "copy", "--files-from-raw", "{}", "--no-traverse", source, remote)
+	}
+	// the paths of the files are taken relative to the source and recreated under the destination
+	return runMirrorCommand(ctx, changedPaths, "rsync", "--archive", "--files-from={}", "--", source, destination)
+}
+
+// mirrorArchive pushes the files of the archive which have changed since the last push to the mirror destination.
+func mirrorArchive(ctx context.Context) (err error) {
+	manifest, err := loadMirrorManifest()
+	if err != nil {
+		return
+	}
+	destinationKey := redact.URLString(mirrorDestination)
+	files, changedPaths, err := collectChangedFiles(manifest[destinationKey])
+	if err != nil {
+		return errorf("could not compute the checksums of the archive: %v", err)
+	}
+	if len(changedPaths) == 0 {
+		logInfof("The mirror at %s is up to date.", destinationKey)
+		return
+	}
+
+	logInfof("Pushing %d changed files to the mirror at %s...", len(changedPaths), destinationKey)
+	err = pushMirrorFiles(ctx, mirrorDestination, changedPaths)
+	if err != nil {
+		return errorf("could not push the archive to the mirror at %s: %v", destinationKey, err)
+	}
+
+	manifest[destinationKey] = files
+	err = manifest.save()
+	if err != nil {
+		return
+	}
+	logSuccessf("Pushed %d changed files to the mirror at %s.", len(changedPaths), destinationKey)
+	return
+}
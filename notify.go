@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+)
+
+const (
+	notificationEventRunCompleted = "run.completed"
+	notificationEventPageFetched  = "page.fetched"
+	notificationEventPageFailed   = "page.failed"
+)
+
+// notificationURL is the URL of a webhook to which notifications about the run are POSTed as JSON.
+var notificationURL string
+var shouldNotifyPageFailures bool
+
+var notificationClient = &http.Client{Timeout: 30 * time.Second}
+
+type runCompletedNotification struct {
+	Event     string        `json:"event"`
+	TargetDir string        `json:"targetDir"`
+	URL       string        `json:"url"`
+	Report    *fetch.Report `json:"report"`
+}
+
+type pageNotification struct {
+	Event     string `json:"event"`
+	TargetDir string `json:"targetDir"`
+	Page      uint   `json:"page"`
+	URL       string `json:"url"`
+	Attempt   int    `json:"attempt"`
+	Error     string `json:"error,omitempty"`
+}
+
+func newRunCompletedNotification(report *fetch.Report) *runCompletedNotification {
+	return &runCompletedNotification{
+		Event:     notificationEventRunCompleted,
+		TargetDir: targetDir,
+		URL:       redact.URLString(getFetcher().PageURL(fetch.MinPageNumber)),
+		Report:    report,
+	}
+}
+
+func newPageNotification(event *fetch.PageEvent) *pageNotification {
+	notification := &pageNotification{
+		Event:     notificationEventPageFetched,
+		TargetDir: targetDir,
+		Page:      event.Number,
+		URL:       redact.URLString(event.URL),
+		Attempt:   event.Attempt,
+	}
+	if event.Err != nil {
+		notification.Event = notificationEventPageFailed
+		notification.Error = redact.Text(event.Err.Error())
+	}
+	return notification
+}
+
+func postNotification(payload interface{}) error {
+	return postJSON(notificationURL, payload)
+}
+
+// postJSON POSTs the payload as JSON to the webhook at the URL.
+func postJSON(webhookURL string, payload interface{}) (err error) {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	response, err := notificationClient.Post(webhookURL, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		err = errorf("webhook responded with status %s", response.Status)
+	}
+	return
+}
+
+func notifyRunCompleted(report *fetch.Report) {
+	if notificationURL == "" {
+		return
+	}
+
+	err := postNotification(newRunCompletedNotification(report))
+	if err != nil {
+		logErrorf("could not notify %s about the completion of the run: %v", notificationURL, err)
+	}
+}
+
+// pageFailureNotifier posts a notification about every page which could not be fetched.
+type pageFailureNotifier struct {
+	fetch.NopObserver
+}
+
+func (pageFailureNotifier) OnPageDone(event *fetch.PageEvent) {
+	if event.Err == nil {
+		return
+	}
+
+	err := postNotification(newPageNotification(event))
+	if err != nil {
+		logErrorf("could not notify %s about the failure of page %d: %v", notificationURL, event.Number, err)
+	}
+}
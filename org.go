@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"golang.org/x/net/html"
+)
+
+// orgFillColumn is the width to which the text of the posts is filled in the Org document, as Emacs does by default.
+const orgFillColumn = 70
+
+// orgTimestampLayout is the layout of the inactive timestamps of the times of the posts.
+const orgTimestampLayout = "[2006-01-02 Mon 15:04]"
+
+// orgLine returns the line with a space prepended if it would otherwise be taken for a headline, a keyword or a comment
+// by Org.
+func orgLine(line string) string {
+	if strings.HasPrefix(line, "*") || strings.HasPrefix(line, "#") {
+		return " " + line
+	}
+	return line
+}
+
+// fillOrgText returns the lines of the text, which has its whitespace collapsed, filled to the fill column.
+func fillOrgText(text string) (lines []string) {
+	var line strings.Builder
+	for _, word := range strings.Fields(text) {
+		if line.Len() > 0 && line.Len()+1+len(word) > orgFillColumn {
+			lines = append(lines, orgLine(line.String()))
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(word)
+	}
+	if line.Len() > 0 {
+		lines = append(lines, orgLine(line.String()))
+	}
+	return
+}
+
+// orgPropertyValue returns the text as the value of a property, which has to fit on a single line.
+func orgPropertyValue(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// orgLinkPart returns the target or the description of a link with the brackets which would end it early escaped.
+func orgLinkPart(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(orgPropertyValue(text))
+}
+
+// orgPostTarget returns the identifier by which the post is linked to within the document, or an empty one if the
+// engine exposes neither the ID nor the anchor of the post.
+func orgPostTarget(post *engine.Post) string {
+	if post.Anchor != "" {
+		return post.Anchor
+	}
+	if post.ID != "" {
+		return "post-" + post.ID
+	}
+	return ""
+}
+
+// orgArchivedTarget returns the target of the link to the archived file at the reference relative to the archived page,
+// relative to the directory of the Org document, or false if the reference is not to an archived file.
+func orgArchivedTarget(reference, pageFilename, outputDir string) (target string, ok bool) {
+	referenceURL, err := url.Parse(reference)
+	if err != nil || referenceURL.IsAbs() || referenceURL.Host != "" || referenceURL.Path == "" {
+		return
+	}
+
+	filename := filepath.Join(filepath.Dir(pageFilename), filepath.FromSlash(referenceURL.Path))
+	relativePath, err := filepath.Rel(outputDir, filename)
+	if err != nil {
+		return
+	}
+	return "file:" + filepath.ToSlash(relativePath), true
+}
+
+// writeOrgPost writes the post as a subheading of the page with its properties, its text and the links and images in it.
+func writeOrgPost(writer io.Writer, post *engine.Post, original *engine.Post, pageFilename, outputDir string) {
+	headline := post.Author
+	if headline == "" {
+		headline = "Post"
+	}
+	if post.ID != "" {
+		headline += " (#" + post.ID + ")"
+	}
+	fmt.Fprintf(writer, "** %s\n:PROPERTIES:\n", orgPropertyValue(headline))
+	if target := orgPostTarget(post); target != "" {
+		fmt.Fprintf(writer, ":CUSTOM_ID: %s\n", orgPropertyValue(target))
+	}
+	if post.ID != "" {
+		fmt.Fprintf(writer, ":POST_ID: %s\n", orgPropertyValue(post.ID))
+	}
+	if post.Author != "" {
+		fmt.Fprintf(writer, ":AUTHOR: %s\n", orgPropertyValue(post.Author))
+	}
+	if !post.Time.IsZero() {
+		fmt.Fprintf(writer, ":DATE: %s\n", post.Time.Format(orgTimestampLayout))
+	}
+	if post.Signature != "" {
+		fmt.Fprintf(writer, ":SIGNATURE: %s\n", orgPropertyValue(post.Signature))
+	}
+	if original != nil {
+		if target := orgPostTarget(original); target != "" {
+			fmt.Fprintf(writer, ":DUPLICATE_OF: [[#%s]]\n", orgLinkPart(target))
+		}
+	}
+	fmt.Fprintln(writer, ":END:")
+
+	for _, line := range fillOrgText(post.Text) {
+		fmt.Fprintln(writer, line)
+	}
+
+	if len(post.Links) > 0 {
+		fmt.Fprintln(writer)
+		for _, link := range post.Links {
+			target := link.URL
+			if archivedTarget, ok := orgArchivedTarget(link.URL, pageFilename, outputDir); ok {
+				target = archivedTarget
+			}
+			if link.Text != "" {
+				fmt.Fprintf(writer, "- [[%s][%s]]\n", orgLinkPart(target), orgLinkPart(link.Text))
+			} else {
+				fmt.Fprintf(writer, "- [[%s]]\n", orgLinkPart(target))
+			}
+		}
+	}
+
+	// the images which were not archived are linked to rather than shown, so that Emacs does not try to fetch them
+	for _, src := range post.Images {
+		fmt.Fprintln(writer)
+		if target, ok := orgArchivedTarget(src, pageFilename, outputDir); ok {
+			fmt.Fprintf(writer, "[[%s]]\n", orgLinkPart(target))
+		} else {
+			fmt.Fprintf(writer, "[[%s][image]]\n", orgLinkPart(src))
+		}
+	}
+}
+
+// exportOrg exports the posts of the topic as an Emacs Org document with a heading for every page and a subheading
+// with the properties of every post under it, linking to the archived images by their paths relative to the document.
+func exportOrg(outputPath string) (err error) {
+	_, err = loadTopicSettings()
+	if err != nil {
+		return
+	}
+
+	outputDir, err := filepath.Abs(filepath.Dir(outputPath))
+	if err != nil {
+		return
+	}
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return errorf("could not create output file %s", outputPath)
+	}
+	defer outputFile.Close()
+
+	metadata, err := collectExportMetadata()
+	if err != nil {
+		return
+	}
+
+	// the front matter describes the topic with the export keywords of Org, along with a few of its own
+	writer := bufio.NewWriter(outputFile)
+	fmt.Fprintf(writer, "#+TITLE: %s\n", orgPropertyValue(metadata.Title))
+	if !metadata.LastCaptureTime.IsZero() {
+		fmt.Fprintf(writer, "#+DATE: %s\n", metadata.LastCaptureTime.Format(orgTimestampLayout))
+	}
+	fmt.Fprintf(writer, "#+DESCRIPTION: %s\n", orgPropertyValue("Archive of "+metadata.description()))
+	if metadata.SourceURL != "" {
+		fmt.Fprintf(writer, "#+SOURCE_URL: %s\n", orgPropertyValue(metadata.SourceURL))
+	}
+	if !metadata.FirstCaptureTime.IsZero() {
+		fmt.Fprintf(writer, "#+CAPTURED: %s--%s\n", metadata.FirstCaptureTime.Format(orgTimestampLayout), metadata.LastCaptureTime.Format(orgTimestampLayout))
+	}
+	if metadata.PageRanges != "" {
+		fmt.Fprintf(writer, "#+PAGES: %s\n", metadata.PageRanges)
+	}
+
+	postCount := 0
+	deduplicator := newPostDeduplicator()
+	_, err = walkArchivedPages(func(pageNumber uint, filename string, doc *html.Node) {
+		absFilename, err := filepath.Abs(filename)
+		if err != nil {
+			absFilename = filename
+		}
+
+		fmt.Fprintf(writer, "\n* Page %d\n:PROPERTIES:\n", pageNumber)
+		fmt.Fprintf(writer, ":URL: %s\n", orgPropertyValue(getFetcher().PageURL(pageNumber)))
+		if relativePath, err := filepath.Rel(outputDir, absFilename); err == nil {
+			fmt.Fprintf(writer, ":ARCHIVED: [[file:%s]]\n", orgLinkPart(filepath.ToSlash(relativePath)))
+		}
+		fmt.Fprintln(writer, ":END:")
+
+		posts, originals := deduplicator.filter(getForumEngine().ExtractPosts(doc))
+		for _, post := range posts {
+			fmt.Fprintln(writer)
+			writeOrgPost(writer, post, originals[post], absFilename, outputDir)
+			postCount++
+		}
+	})
+	if err != nil {
+		return
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return errorf("could not write Org document %s: %v", outputPath, err)
+	}
+
+	logSuccessf("Exported %d posts to %s.", postCount, outputPath)
+	return
+}
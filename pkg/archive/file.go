@@ -0,0 +1,38 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrSymlink is returned instead of writing through a symbolic link in an archive, which could otherwise redirect the
+// write to a file elsewhere on the system if the directory of the archive has been tampered with.
+var ErrSymlink = errors.New("refusing to write through a symbolic link")
+
+// OpenFile opens the file in an archive like os.OpenFile, but fails with ErrSymlink instead of following the file if it
+// is a symbolic link.
+func OpenFile(filename string, flag int, perm os.FileMode) (file *os.File, err error) {
+	if IsSymlink(filename) {
+		return nil, fmt.Errorf("%w: %s", ErrSymlink, filename)
+	}
+
+	file, err = os.OpenFile(filename, flag|openNoFollowFlag, perm)
+	if err != nil && IsSymlink(filename) {
+		// the file has been replaced by a symbolic link after it was checked
+		return nil, fmt.Errorf("%w: %s", ErrSymlink, filename)
+	}
+	return
+}
+
+// Create creates or truncates the file in an archive like os.Create, but fails with ErrSymlink instead of following
+// the file if it is a symbolic link.
+func Create(filename string) (*os.File, error) {
+	return OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// IsSymlink reports whether the file exists and is a symbolic link.
+func IsSymlink(filename string) bool {
+	fileInfo, err := os.Lstat(filename)
+	return err == nil && fileInfo.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package archive
+
+// openNoFollowFlag is not available on this platform, so only the check done before opening a file applies.
+const openNoFollowFlag = 0
@@ -0,0 +1,8 @@
+//go:build unix
+
+package archive
+
+import "syscall"
+
+// openNoFollowFlag makes opening a symbolic link fail (with ELOOP).
+const openNoFollowFlag = syscall.O_NOFOLLOW
@@ -0,0 +1,6 @@
+package archive
+
+// IconsDirname is the name of the directory of the archive in which the icons of the hosts of the pages (the ones which
+// browsers look for at the root of a site even if the pages do not link to them) are stored, in a subdirectory for
+// every host.
+const IconsDirname = "icons"
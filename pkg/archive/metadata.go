@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// MetadataFilenameSuffix is appended to the name of a file in the archive to get the name of its metadata sidecar file.
+const MetadataFilenameSuffix = ".meta.json"
+
+// ResourceMetadata is the subset of an HTTP response which is needed to replay it faithfully or to refresh it conditionally.
+type ResourceMetadata struct {
+	URL           string   `json:"url"`
+	RedirectChain []string `json:"redirectChain,omitempty"`
+	Status        int      `json:"status"`
+	// ContentType is the content type declared by the server or, if it was missing or generic, the one detected from
+	// the content; the declared one is kept in Header.
+	ContentType  string      `json:"contentType,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	ETag         string      `json:"etag,omitempty"`
+	FetchedAt    time.Time   `json:"fetchedAt"`
+	Header       http.Header `json:"header"`
+	// Source is where the content was obtained from if the resource could not be fetched from its URL (one of the
+	// ResourceSource constants), in which case SourceURL is the URL from which it was fetched, while the rest of the
+	// metadata describes the response served from there.
+	Source    string `json:"source,omitempty"`
+	SourceURL string `json:"sourceURL,omitempty"`
+	// StrippedEXIF tells that the EXIF and XMP metadata (e.g. the location at which a photo was taken) has been removed
+	// from the stored image, which hence differs from the one served from the URL.
+	StrippedEXIF bool `json:"strippedEXIF,omitempty"`
+}
+
+const (
+	// ResourceSourceRefererRetry means that the resource was fetched from its URL by retrying with the Referer header
+	// sent if it had been omitted, or omitted if it had been sent.
+	ResourceSourceRefererRetry = "referer-retry"
+	// ResourceSourceWayback means that the resource was fetched from its snapshot in the Wayback Machine.
+	ResourceSourceWayback = "wayback"
+	// ResourceSourceThumbnail means that the thumbnail of the image which the forum serves was fetched instead.
+	ResourceSourceThumbnail = "thumbnail"
+)
+
+// NewResourceMetadata extracts the metadata of the resource from the response to the request for it.
+func NewResourceMetadata(response *http.Response) *ResourceMetadata {
+	header := response.Header.Clone()
+	header.Del("Set-Cookie")
+
+	// every request issued due to a redirect refers to the response which caused it
+	originalRequest := response.Request
+	var redirectChain []string
+	for originalRequest.Response != nil {
+		redirectChain = append([]string{originalRequest.URL.String()}, redirectChain...)
+		originalRequest = originalRequest.Response.Request
+	}
+
+	return &ResourceMetadata{
+		URL:           originalRequest.URL.String(),
+		RedirectChain: redirectChain,
+		Status:        response.StatusCode,
+		ContentType:   header.Get("Content-Type"),
+		LastModified:  header.Get("Last-Modified"),
+		ETag:          header.Get("ETag"),
+		FetchedAt:     time.Now().UTC(),
+		Header:        header,
+	}
+}
+
+// sniffLength is the number of bytes at the start of the content which are considered when detecting its content type.
+const sniffLength = 512
+
+// IsGenericContentType reports whether the content type says nothing about the content, so that it should be detected
+// from the content instead.
+func IsGenericContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+
+	switch mediaType {
+	case "application/octet-stream", "binary/octet-stream", "application/unknown", "application/x-download":
+		return true
+	}
+	return false
+}
+
+// DetectContentType replaces the content type of the resource with the one detected from the first bytes of its
+// content if the server did not declare it or declared a generic one, and reports whether it did.
+func (metadata *ResourceMetadata) DetectContentType(content []byte) bool {
+	if !IsGenericContentType(metadata.ContentType) {
+		return false
+	}
+
+	if len(content) > sniffLength {
+		content = content[:sniffLength]
+	}
+	metadata.ContentType = http.DetectContentType(content)
+	return true
+}
+
+// SniffContentType detects the content type of the resource as DetectContentType does from the first bytes of the
+// content read from the reader and returns a reader which yields the whole content.
+func (metadata *ResourceMetadata) SniffContentType(reader io.Reader) io.Reader {
+	if !IsGenericContentType(metadata.ContentType) {
+		return reader
+	}
+
+	bufferedReader := bufio.NewReaderSize(reader, sniffLength)
+	content, _ := bufferedReader.Peek(sniffLength)
+	metadata.DetectContentType(content)
+	return bufferedReader
+}
+
+// WriteMetadata encodes the metadata of a resource in the format of its sidecar file, whose name is that of the file
+// of the resource with MetadataFilenameSuffix appended.
+func WriteMetadata(w io.Writer, metadata *ResourceMetadata) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(metadata)
+}
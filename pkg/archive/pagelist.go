@@ -0,0 +1,155 @@
+// Package archive manages the on-disk layout of an archive of a forum topic: the directories of the pages, the state
+// database with the failed and pending pages and the map of original URLs, the metadata of the resources and the
+// settings of the topic.
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// The names of the legacy lists of pages, which are migrated into the state database (see OpenState).
+const (
+	// FailureListFilename is the name of the list of pages whose download failed during the last run; the lists of the
+	// earlier runs were rotated to `failures.lst.N`.
+	FailureListFilename = "failures.lst"
+	// PendingListFilename is the name of the list of pages which were left pending by the last run.
+	PendingListFilename = "pending.lst"
+)
+
+// legacyFailureListFilenames returns the names of the legacy lists of failed downloads in the directory in the order of
+// the runs which wrote them: the rotated ones (`failures.lst.N`) and then the latest one.
+func legacyFailureListFilenames(dir string) (failureListFilenames []string) {
+	latestFailureListFilename := filepath.Join(dir, FailureListFilename)
+	for i := 0; ; i++ {
+		rotatedFailureListFilename := fmt.Sprintf("%s.%d", latestFailureListFilename, i)
+		if _, err := os.Stat(rotatedFailureListFilename); err != nil {
+			break
+		}
+		failureListFilenames = append(failureListFilenames, rotatedFailureListFilename)
+	}
+	if _, err := os.Stat(latestFailureListFilename); err == nil {
+		failureListFilenames = append(failureListFilenames, latestFailureListFilename)
+	}
+	return
+}
+
+// LegacyState reads the failed and pending pages from the legacy lists of an archive whose state database has not
+// been created yet, as OpenState would migrate them, without migrating them.
+type LegacyState struct {
+	dir string
+}
+
+// ReadLegacyState returns the legacy lists of the archive in the directory.
+func ReadLegacyState(dir string) *LegacyState {
+	return &LegacyState{dir: dir}
+}
+
+// FailedPages returns the pages whose download failed during the last run.
+func (state *LegacyState) FailedPages() (pageNumbers []uint, err error) {
+	failureListFilenames := legacyFailureListFilenames(state.dir)
+	if len(failureListFilenames) == 0 {
+		return []uint{}, nil
+	}
+
+	return ReadPageList(failureListFilenames[len(failureListFilenames)-1])
+}
+
+// CountPageFailures counts how many times the download of each page has failed during all the runs.
+func (state *LegacyState) CountPageFailures() (pageFailureCounts map[uint]int, err error) {
+	pageFailureCounts = map[uint]int{}
+	for _, failureListFilename := range legacyFailureListFilenames(state.dir) {
+		failedPageNumbers, err := ReadPageList(failureListFilename)
+		if err != nil {
+			return nil, err
+		}
+
+		// a page is listed at most once per run
+		for _, pageNumber := range slices.Compact(slices.Sorted(slices.Values(failedPageNumbers))) {
+			pageFailureCounts[pageNumber]++
+		}
+	}
+	return
+}
+
+// PendingPages returns the pages which were left pending by the last run.
+func (state *LegacyState) PendingPages() (pageNumbers []uint, err error) {
+	return ReadPageList(filepath.Join(state.dir, PendingListFilename))
+}
+
+// PageDir returns the directory in which the page with the given number is stored.
+func PageDir(dir string, pageNumber uint) string {
+	return filepath.Join(dir, fmt.Sprint(pageNumber))
+}
+
+// ReadPageList reads a list of page numbers, one per line; a missing list is empty.
+func ReadPageList(filename string) (pageNumbers []uint, err error) {
+	pageNumbers = []uint{}
+
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return pageNumbers, nil
+	}
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var pageNumber uint
+		_, err := fmt.Sscanf(scanner.Text(), "%d", &pageNumber)
+		if err != nil {
+			continue
+		}
+
+		pageNumbers = append(pageNumbers, pageNumber)
+	}
+
+	return pageNumbers, scanner.Err()
+}
+
+// PageNumbers returns the numbers of the pages which have a directory in the archive.
+func PageNumbers(dir string) (pageNumbers map[uint]struct{}, err error) {
+	pageNumbers = map[uint]struct{}{}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		pageNumber, err := strconv.ParseUint(dirEntry.Name(), 10, 0)
+		if err != nil || pageNumber == 0 {
+			continue
+		}
+
+		pageNumbers[uint(pageNumber)] = struct{}{}
+	}
+	return
+}
+
+// PageModTime returns the time at which the archived copy of a page was last updated, i.e. the latest modification
+// time of the files in its directory.
+func PageModTime(pageDir string) (modTime time.Time, err error) {
+	err = filepath.Walk(pageDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return
+}
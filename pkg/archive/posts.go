@@ -0,0 +1,223 @@
+package archive
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// The kinds of the changes of the tracked posts.
+const (
+	PostEdited   = "edited"
+	PostDeleted  = "deleted"
+	PostRestored = "restored"
+)
+
+// TrackedPost is a post extracted from a page, as it is tracked across the runs.
+type TrackedPost struct {
+	ID     string
+	Author string
+	Text   string
+}
+
+// PostChange is a change of a tracked post noticed during a run: an edit, with both versions of the text kept, a
+// deletion, with the last known text kept, or the reappearance of a post which was deemed deleted.
+type PostChange struct {
+	// Run is the ID of the run during which the change was noticed and Time is when that run started.
+	Run  int64
+	Time time.Time
+	Post string
+	Page uint
+	// Kind is one of PostEdited, PostDeleted and PostRestored.
+	Kind    string
+	Author  string
+	OldText string
+	NewText string
+}
+
+// trackedPostRecord is a tracked post as it was last seen.
+type trackedPostRecord struct {
+	id       string
+	page     uint
+	position int
+	author   string
+	text     string
+	deleted  bool
+}
+
+// recordPostChange appends the change to the log of the changes of the posts.
+func recordPostChange(db execer, change *PostChange) (err error) {
+	_, err = db.Exec("INSERT INTO post_changes (run, post, page, kind, author, old_text, new_text) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		change.Run, change.Post, change.Page, change.Kind, change.Author, change.OldText, change.NewText)
+	return
+}
+
+// markPostDeleted records that the post has been deleted during the run.
+func markPostDeleted(db execer, run int64, post *trackedPostRecord) (change *PostChange, err error) {
+	_, err = db.Exec("UPDATE posts SET deleted = 1 WHERE id = ?", post.id)
+	if err != nil {
+		return
+	}
+
+	change = &PostChange{Run: run, Post: post.id, Page: post.page, Kind: PostDeleted, Author: post.author, OldText: post.text}
+	err = recordPostChange(db, change)
+	return
+}
+
+// RecordPosts records the posts extracted, in their order, from the page during the run which has been started by
+// StartRun, and returns the changes noticed by comparing them by ID with the posts seen during the earlier runs: the
+// posts whose text differs have been edited, the ones which were deemed deleted have been restored, and the ones which
+// used to be on the page after one that still is, but are no longer there, have been deleted (since the posts only
+// move to earlier pages when earlier ones are deleted, the missing posts at the start of the page may have moved to the
+// previous page instead, which is settled by RecordDeletedPosts).
+func (state *State) RecordPosts(pageNumber uint, posts []*TrackedPost) (changes []*PostChange, err error) {
+	if state.run == 0 {
+		return nil, errors.New("no run has been started")
+	}
+
+	tx, err := state.db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	previousPosts, err := queryTrackedPosts(tx, "SELECT id, page, position, author, text, deleted FROM posts WHERE page = ? AND deleted = 0 ORDER BY position", pageNumber)
+	if err != nil {
+		return
+	}
+
+	seenPostIDs := map[string]struct{}{}
+	for position, post := range posts {
+		seenPostIDs[post.ID] = struct{}{}
+
+		var previousPost trackedPostRecord
+		err = tx.QueryRow("SELECT author, text, deleted FROM posts WHERE id = ?", post.ID).Scan(&previousPost.author, &previousPost.text, &previousPost.deleted)
+		switch {
+		case err == sql.ErrNoRows:
+			err = nil
+		case err != nil:
+			return nil, err
+		case previousPost.deleted:
+			changes = append(changes, &PostChange{Run: state.run, Post: post.ID, Page: pageNumber, Kind: PostRestored, Author: post.Author, OldText: previousPost.text, NewText: post.Text})
+		case previousPost.text != post.Text:
+			changes = append(changes, &PostChange{Run: state.run, Post: post.ID, Page: pageNumber, Kind: PostEdited, Author: post.Author, OldText: previousPost.text, NewText: post.Text})
+		}
+
+		_, err = tx.Exec(`INSERT INTO posts (id, page, position, author, text, last_seen_run) VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET page = excluded.page, position = excluded.position, author = excluded.author, text = excluded.text,
+last_seen_run = excluded.last_seen_run, deleted = 0`, post.ID, pageNumber, position, post.Author, post.Text, state.run)
+		if err != nil {
+			return
+		}
+	}
+	for _, change := range changes {
+		err = recordPostChange(tx, change)
+		if err != nil {
+			return
+		}
+	}
+
+	// a missing post which came after a post that is still on the page cannot have moved to the previous page
+	hasRemainingPredecessor := false
+	for _, previousPost := range previousPosts {
+		if _, ok := seenPostIDs[previousPost.id]; ok {
+			hasRemainingPredecessor = true
+			continue
+		}
+		if !hasRemainingPredecessor {
+			continue
+		}
+
+		change, err := markPostDeleted(tx, state.run, previousPost)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, tx.Commit()
+}
+
+// RecordDeletedPosts records as deleted the posts which have not been seen during the run which has been started by
+// StartRun, although the page on which they were last seen has been tracked during it, along with the previous page
+// (where they would have moved if earlier posts had been deleted), and returns the changes.
+func (state *State) RecordDeletedPosts(trackedPageNumbers map[uint]struct{}) (changes []*PostChange, err error) {
+	if state.run == 0 {
+		return nil, errors.New("no run has been started")
+	}
+
+	tx, err := state.db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	missingPosts, err := queryTrackedPosts(tx, "SELECT id, page, position, author, text, deleted FROM posts WHERE deleted = 0 AND last_seen_run < ? ORDER BY page, position", state.run)
+	if err != nil {
+		return
+	}
+	for _, post := range missingPosts {
+		if _, ok := trackedPageNumbers[post.page]; !ok {
+			continue
+		}
+		// the posts of the first page have nowhere earlier to move
+		if _, ok := trackedPageNumbers[post.page-1]; !ok && post.page > 1 {
+			continue
+		}
+
+		change, err := markPostDeleted(tx, state.run, post)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, tx.Commit()
+}
+
+// queryer is implemented by both databases and transactions.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func queryTrackedPosts(db queryer, query string, args ...any) (posts []*trackedPostRecord, err error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post := &trackedPostRecord{}
+		err = rows.Scan(&post.id, &post.page, &post.position, &post.author, &post.text, &post.deleted)
+		if err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// PostChanges returns the log of the changes of the tracked posts in the order in which they were noticed.
+func (state *State) PostChanges() (changes []*PostChange, err error) {
+	rows, err := state.db.Query(`SELECT post_changes.run, runs.started_at, post, page, kind, author, old_text, new_text
+FROM post_changes JOIN runs ON runs.id = post_changes.run ORDER BY seq`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		change := &PostChange{}
+		var startTime string
+		err = rows.Scan(&change.Run, &startTime, &change.Post, &change.Page, &change.Kind, &change.Author, &change.OldText, &change.NewText)
+		if err != nil {
+			return nil, err
+		}
+		change.Time, _ = time.Parse(time.RFC3339, startTime)
+
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SmileysDirname is the name of the directory of the archive in which the images of the smileys are stored once for all
+// the pages which use them.
+const SmileysDirname = "smileys"
+
+// SmileyMapFilename is the name of the map of the smileys of the forum which were stored in the archive.
+const SmileyMapFilename = "smileys.tsv"
+
+// Smiley is a smiley of the forum whose image is stored in the archive.
+type Smiley struct {
+	// URL is the URL of the image of the smiley.
+	URL string
+	// Code is the text which is typed to insert the smiley, e.g. `:)`.
+	Code string
+	// Filename is the slash-separated path of the stored image, relative to the directory of the archive.
+	Filename string
+}
+
+// RecordSmiley appends the smiley to the map of the archive in the directory. The map consists of tab-separated lines
+// of the form `URL  code  filename`.
+func RecordSmiley(dir string, smiley *Smiley) (err error) {
+	file, err := OpenFile(filepath.Join(dir, SmileyMapFilename), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\t%s\n", smiley.URL, smiley.Code, smiley.Filename)
+	return
+}
+
+// LoadSmileys reads the map of the archive in the directory and returns the latest smiley recorded for every URL; a
+// missing map is empty.
+func LoadSmileys(dir string) (smileys map[string]*Smiley, err error) {
+	smileys = map[string]*Smiley{}
+
+	file, err := os.Open(filepath.Join(dir, SmileyMapFilename))
+	if os.IsNotExist(err) {
+		return smileys, nil
+	}
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) == 3 {
+			smileys[fields[0]] = &Smiley{URL: fields[0], Code: fields[1], Filename: fields[2]}
+		}
+	}
+
+	err = scanner.Err()
+	return
+}
@@ -0,0 +1,523 @@
+package archive
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// StateFilename is the name of the database with the bookkeeping of the archive: the pages whose download failed
+// during every run, the pages left pending by the last run, the map of original URLs and the tracked posts along with
+// the log of their changes. It is an SQLite database in
+// WAL mode, so that several processes (e.g. a run and the server of the archive) can use it at once.
+const StateFilename = "state.db"
+
+// stateSchemaVersion is the version of the schema of the state database, which is stored as its user_version.
+const stateSchemaVersion = 3
+
+// stateBusyTimeout is how long an access to the state database waits for another process to finish writing to it.
+const stateBusyTimeout = 10 * time.Second
+
+const stateSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY,
+	started_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS failures (
+	run INTEGER NOT NULL REFERENCES runs (id),
+	page INTEGER NOT NULL,
+	kind TEXT NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	carried INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (run, page)
+);
+CREATE TABLE IF NOT EXISTS resource_failures (
+	run INTEGER NOT NULL REFERENCES runs (id),
+	page INTEGER NOT NULL,
+	url TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	PRIMARY KEY (run, page, url)
+);
+CREATE TABLE IF NOT EXISTS pending (
+	page INTEGER PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS resources (
+	filename TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	redirect_chain TEXT NOT NULL,
+	seq INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS posts (
+	id TEXT PRIMARY KEY,
+	page INTEGER NOT NULL,
+	position INTEGER NOT NULL,
+	author TEXT NOT NULL,
+	text TEXT NOT NULL,
+	last_seen_run INTEGER NOT NULL REFERENCES runs (id),
+	deleted INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS post_changes (
+	seq INTEGER PRIMARY KEY,
+	run INTEGER NOT NULL REFERENCES runs (id),
+	post TEXT NOT NULL,
+	page INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	author TEXT NOT NULL,
+	old_text TEXT NOT NULL,
+	new_text TEXT NOT NULL
+);
+`
+
+// stateSchemaUpgrade1 upgrades the schema of version 1, which did not record the kinds of and reasons for the failures
+// nor the failures of the resources, to version 2 (the missing tables are then created by stateSchema, as are the
+// tables of the tracked posts added by version 3).
+const stateSchemaUpgrade1 = `
+ALTER TABLE failures ADD COLUMN kind TEXT NOT NULL DEFAULT '';
+ALTER TABLE failures ADD COLUMN reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE failures ADD COLUMN carried INTEGER NOT NULL DEFAULT 0;
+`
+
+// migratedFilenameSuffix is appended to the names of the legacy files whose content has been migrated into the state
+// database.
+const migratedFilenameSuffix = ".migrated"
+
+// State is the bookkeeping of an archive, kept in its state database. Its methods are safe for concurrent use.
+type State struct {
+	dir string
+	db  *sql.DB
+	// run is the ID of the run started by StartRun, whose failures are recorded by RecordFailure.
+	run int64
+	// previousRun is the ID of the run before the one started by StartRun, if any.
+	previousRun int64
+}
+
+// Failure is a recorded failure to fetch a page or a resource embedded in it.
+type Failure struct {
+	Page uint
+	// URL is the URL of the resource, or empty if the page itself could not be fetched.
+	URL string
+	// Kind is the kind of the failure, e.g. `timeout` (see fetch.FailureKind).
+	Kind   string
+	Reason string
+}
+
+// OpenState opens the state database of the archive in the directory, creating it if it does not exist yet, in which
+// case the legacy lists of failed and pending pages and map of original URLs, if any, are migrated into it (see
+// migrateLegacyFiles).
+func OpenState(dir string) (state *State, err error) {
+	filename := filepath.Join(dir, StateFilename)
+	if IsSymlink(filename) {
+		return nil, fmt.Errorf("%w: %s", ErrSymlink, filename)
+	}
+
+	db, err := sql.Open("sqlite", filename+fmt.Sprintf("?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", stateBusyTimeout.Milliseconds()))
+	if err != nil {
+		return
+	}
+	// the accesses of a process are serialized, which is plenty for bookkeeping and spares it from contending for the
+	// lock of the database with itself
+	db.SetMaxOpenConns(1)
+
+	state = &State{dir: dir, db: db}
+	err = state.migrate()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return
+}
+
+// OpenStateReadOnly opens the state database of the archive in the directory for reading only, so that it is neither
+// created nor migrated nor written to, e.g. for planning a dry run, or returns nil if it does not exist.
+func OpenStateReadOnly(dir string) (state *State, err error) {
+	filename := filepath.Join(dir, StateFilename)
+	if IsSymlink(filename) {
+		return nil, fmt.Errorf("%w: %s", ErrSymlink, filename)
+	}
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	// even a read-only connection creates the write-ahead log and its shared memory file, unless the database is
+	// opened as immutable, which is safe only as long as no other process is writing to it, in which case the log
+	// exists already
+	mode := "ro"
+	if _, err := os.Stat(filename + "-wal"); os.IsNotExist(err) {
+		mode += "&immutable=1"
+	}
+	db, err := sql.Open("sqlite", "file:"+filename+fmt.Sprintf("?mode=%s&_pragma=busy_timeout(%d)&_pragma=query_only(1)", mode, stateBusyTimeout.Milliseconds()))
+	if err != nil {
+		return
+	}
+	db.SetMaxOpenConns(1)
+
+	return &State{dir: dir, db: db}, nil
+}
+
+// migrate creates the schema of the state database if it has not been created yet, along with migrating the legacy
+// files into it, or upgrades it if it is older.
+func (state *State) migrate() (err error) {
+	var version int
+	err = state.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil || version >= stateSchemaVersion {
+		return
+	}
+
+	tx, err := state.db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	if version == 1 {
+		_, err = tx.Exec(stateSchemaUpgrade1)
+		if err != nil {
+			return
+		}
+	}
+	_, err = tx.Exec(stateSchema)
+	if err != nil {
+		return
+	}
+	var migratedFilenames []string
+	if version == 0 {
+		migratedFilenames, err = migrateLegacyFiles(tx, state.dir)
+		if err != nil {
+			return fmt.Errorf("could not migrate the legacy bookkeeping of the archive: %w", err)
+		}
+	}
+	_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", stateSchemaVersion))
+	if err != nil {
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		return
+	}
+
+	// the legacy files are kept for reference, but renamed so that it is clear that they are no longer used
+	for _, filename := range migratedFilenames {
+		os.Rename(filename, filename+migratedFilenameSuffix)
+	}
+	return nil
+}
+
+// migrateLegacyFiles imports the rotated lists of failed downloads (`failures.lst.N`) and the latest one as the
+// failures of successive runs, the list of pending pages and the map of original URLs of the archive in the directory
+// into the state database, and returns the names of the files which were imported.
+func migrateLegacyFiles(tx *sql.Tx, dir string) (migratedFilenames []string, err error) {
+	for _, failureListFilename := range legacyFailureListFilenames(dir) {
+		failedPageNumbers, err := ReadPageList(failureListFilename)
+		if err != nil {
+			return nil, err
+		}
+
+		startTime := time.Now()
+		if info, err := os.Stat(failureListFilename); err == nil {
+			startTime = info.ModTime()
+		}
+		result, err := tx.Exec("INSERT INTO runs (started_at) VALUES (?)", startTime.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, err
+		}
+		run, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		for _, failedPageNumber := range failedPageNumbers {
+			_, err = tx.Exec("INSERT OR IGNORE INTO failures (run, page) VALUES (?, ?)", run, failedPageNumber)
+			if err != nil {
+				return nil, err
+			}
+		}
+		migratedFilenames = append(migratedFilenames, failureListFilename)
+	}
+
+	pendingListFilename := filepath.Join(dir, PendingListFilename)
+	if _, err := os.Stat(pendingListFilename); err == nil {
+		pendingPageNumbers, err := ReadPageList(pendingListFilename)
+		if err != nil {
+			return nil, err
+		}
+		for _, pendingPageNumber := range pendingPageNumbers {
+			_, err = tx.Exec("INSERT OR IGNORE INTO pending (page) VALUES (?)", pendingPageNumber)
+			if err != nil {
+				return nil, err
+			}
+		}
+		migratedFilenames = append(migratedFilenames, pendingListFilename)
+	}
+
+	urlMapFilename := filepath.Join(dir, URLMapFilename)
+	file, err := os.Open(urlMapFilename)
+	if os.IsNotExist(err) {
+		return migratedFilenames, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+
+		err = recordURLMapEntry(tx, &URLMapEntry{
+			Filename:      fields[0],
+			OriginalURL:   fields[1],
+			RedirectChain: fields[2:],
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	migratedFilenames = append(migratedFilenames, urlMapFilename)
+
+	return migratedFilenames, nil
+}
+
+// Close closes the state database.
+func (state *State) Close() error {
+	return state.db.Close()
+}
+
+// StartRun records the start of a run, whose failures are recorded separately from the ones of the earlier runs.
+func (state *State) StartRun() (err error) {
+	err = state.db.QueryRow("SELECT IFNULL(MAX(id), 0) FROM runs").Scan(&state.previousRun)
+	if err != nil {
+		return
+	}
+
+	result, err := state.db.Exec("INSERT INTO runs (started_at) VALUES (?)", time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return
+	}
+
+	state.run, err = result.LastInsertId()
+	return
+}
+
+// RecordFailure records that the download of the page failed for the reason during the run which has been started by
+// StartRun.
+func (state *State) RecordFailure(pageNumber uint, kind, reason string) (err error) {
+	if state.run == 0 {
+		return errors.New("no run has been started")
+	}
+
+	_, err = state.db.Exec(`INSERT INTO failures (run, page, kind, reason) VALUES (?, ?, ?, ?)
+ON CONFLICT (run, page) DO UPDATE SET kind = excluded.kind, reason = excluded.reason, carried = 0`, state.run, pageNumber, kind, reason)
+	return
+}
+
+// RecordResourceFailure records that the download of the resource embedded in the page failed for the reason during
+// the run which has been started by StartRun.
+func (state *State) RecordResourceFailure(pageNumber uint, resourceURL, kind, reason string) (err error) {
+	if state.run == 0 {
+		return errors.New("no run has been started")
+	}
+
+	_, err = state.db.Exec("INSERT OR REPLACE INTO resource_failures (run, page, url, kind, reason) VALUES (?, ?, ?, ?, ?)",
+		state.run, pageNumber, resourceURL, kind, reason)
+	return
+}
+
+// CarryOverFailures records the failures of the pages and resources of the previous run into the run which has been
+// started by StartRun, except for the ones of the pages which are fetched again by it, so that they remain to be
+// retried. The carried over failures of the pages are not counted again by CountPageFailures.
+func (state *State) CarryOverFailures(fetchedPageNumbers map[uint]struct{}) (err error) {
+	if state.run == 0 {
+		return errors.New("no run has been started")
+	}
+
+	failures, err := state.runFailures(state.previousRun)
+	if err != nil {
+		return
+	}
+
+	tx, err := state.db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	for _, failure := range failures {
+		if _, ok := fetchedPageNumbers[failure.Page]; ok {
+			continue
+		}
+
+		if failure.URL == "" {
+			_, err = tx.Exec("INSERT OR IGNORE INTO failures (run, page, kind, reason, carried) VALUES (?, ?, ?, ?, 1)",
+				state.run, failure.Page, failure.Kind, failure.Reason)
+		} else {
+			_, err = tx.Exec("INSERT OR IGNORE INTO resource_failures (run, page, url, kind, reason) VALUES (?, ?, ?, ?, ?)",
+				state.run, failure.Page, failure.URL, failure.Kind, failure.Reason)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// runFailures returns the failures of the pages and resources during the run, ordered by page.
+func (state *State) runFailures(run int64) (failures []*Failure, err error) {
+	rows, err := state.db.Query(`SELECT page, '', kind, reason FROM failures WHERE run = ?1
+UNION ALL SELECT page, url, kind, reason FROM resource_failures WHERE run = ?1
+ORDER BY 1, 2`, run)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		failure := &Failure{}
+		err = rows.Scan(&failure.Page, &failure.URL, &failure.Kind, &failure.Reason)
+		if err != nil {
+			return nil, err
+		}
+
+		failures = append(failures, failure)
+	}
+	return failures, rows.Err()
+}
+
+// Failures returns the failures of the pages and resources during the latest run (the one in progress, if any),
+// ordered by page, with the failure of a page itself before the ones of its resources.
+func (state *State) Failures() (failures []*Failure, err error) {
+	var run int64
+	err = state.db.QueryRow("SELECT IFNULL(MAX(id), 0) FROM runs").Scan(&run)
+	if err != nil {
+		return
+	}
+
+	return state.runFailures(run)
+}
+
+// queryPageNumbers returns the page numbers in the first column of the rows returned by the query.
+func (state *State) queryPageNumbers(query string, args ...any) (pageNumbers []uint, err error) {
+	pageNumbers = []uint{}
+
+	rows, err := state.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pageNumber uint
+		err = rows.Scan(&pageNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		pageNumbers = append(pageNumbers, pageNumber)
+	}
+	return pageNumbers, rows.Err()
+}
+
+// FailedPages returns the pages whose download failed during the latest run (the one in progress, if any).
+func (state *State) FailedPages() (pageNumbers []uint, err error) {
+	return state.queryPageNumbers("SELECT page FROM failures WHERE run = (SELECT MAX(id) FROM runs) ORDER BY page")
+}
+
+// CountPageFailures counts how many times the download of each page has failed during all the runs.
+func (state *State) CountPageFailures() (pageFailureCounts map[uint]int, err error) {
+	pageFailureCounts = map[uint]int{}
+
+	rows, err := state.db.Query("SELECT page, COUNT(*) FROM failures WHERE carried = 0 GROUP BY page")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pageNumber uint
+		var failureCount int
+		err = rows.Scan(&pageNumber, &failureCount)
+		if err != nil {
+			return nil, err
+		}
+
+		pageFailureCounts[pageNumber] = failureCount
+	}
+	return pageFailureCounts, rows.Err()
+}
+
+// PendingPages returns the pages which were left pending by the last run.
+func (state *State) PendingPages() (pageNumbers []uint, err error) {
+	return state.queryPageNumbers("SELECT page FROM pending ORDER BY page")
+}
+
+// SetPendingPages replaces the pages which are left pending for the next run.
+func (state *State) SetPendingPages(pageNumbers []uint) (err error) {
+	tx, err := state.db.Begin()
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM pending")
+	if err != nil {
+		return
+	}
+	for _, pageNumber := range pageNumbers {
+		_, err = tx.Exec("INSERT OR IGNORE INTO pending (page) VALUES (?)", pageNumber)
+		if err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+// execer is implemented by both databases and transactions.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// recordURLMapEntry records the entry of the map of original URLs, which takes precedence over the earlier ones.
+func recordURLMapEntry(db execer, entry *URLMapEntry) (err error) {
+	_, err = db.Exec(`INSERT INTO resources (filename, url, redirect_chain, seq)
+VALUES (?, ?, ?, (SELECT IFNULL(MAX(seq), 0) + 1 FROM resources))
+ON CONFLICT (filename) DO UPDATE SET url = excluded.url, redirect_chain = excluded.redirect_chain, seq = excluded.seq`,
+		entry.Filename, entry.OriginalURL, strings.Join(entry.RedirectChain, "\t"))
+	return
+}
+
+// urlMapEntries returns the entries of the map of original URLs in the order in which they were recorded.
+func (state *State) urlMapEntries() (entries []*URLMapEntry, err error) {
+	rows, err := state.db.Query("SELECT filename, url, redirect_chain FROM resources ORDER BY seq")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := &URLMapEntry{}
+		var redirectChain string
+		err = rows.Scan(&entry.Filename, &entry.OriginalURL, &redirectChain)
+		if err != nil {
+			return nil, err
+		}
+		if redirectChain != "" {
+			entry.RedirectChain = strings.Split(redirectChain, "\t")
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,24 @@
+package archive
+
+import (
+	"path/filepath"
+)
+
+// Names of the stylesheets of the archive which are linked from the stored pages into which they have been injected,
+// so that all of them can be restyled by editing the stylesheet alone: the user stylesheet and the print-ready one.
+const (
+	InjectedStylesheetFilename = "injected.css"
+	PrintStylesheetFilename    = "print.css"
+)
+
+// WriteStylesheet stores the stylesheet with the name in the archive in the directory, replacing the one stored before.
+func WriteStylesheet(dir, name string, content []byte) (err error) {
+	file, err := Create(filepath.Join(dir, name))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = file.Write(content)
+	return
+}
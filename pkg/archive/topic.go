@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// TopicSettingsFilename is the name of the file with the settings of the topic of the archive.
+const TopicSettingsFilename = "topic.json"
+
+// TopicSettings are the parameters of the last fetch into an archive, which are reused by the operations on an existing
+// archive.
+type TopicSettings struct {
+	URL         string   `json:"url,omitempty"`
+	PageURLs    []string `json:"pageURLs,omitempty"`
+	PostStep    uint     `json:"postStep"`
+	Engine      string   `json:"engine,omitempty"`
+	PageRanges  []string `json:"pageRanges,omitempty"`
+	Credentials string   `json:"credentials,omitempty"`
+	APIKey      string   `json:"apiKey,omitempty"`
+	APIUsername string   `json:"apiUsername,omitempty"`
+	BearerToken string   `json:"bearerToken,omitempty"`
+	Login       string   `json:"login,omitempty"`
+}
+
+// SaveTopicSettings writes the settings of the topic to the archive in the directory, creating the latter if necessary.
+func SaveTopicSettings(dir string, settings *TopicSettings) (err error) {
+	filename := filepath.Join(dir, TopicSettingsFilename)
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not create target directory %s", dir)
+	}
+
+	file, err := Create(filename)
+	if err != nil {
+		return fmt.Errorf("could not create topic settings file %s", filename)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "\t")
+	err = encoder.Encode(settings)
+	if err != nil {
+		return fmt.Errorf("could not write topic settings file %s", filename)
+	}
+
+	return
+}
+
+// LoadTopicSettings reads the settings of the topic of the archive in the directory.
+func LoadTopicSettings(dir string) (settings *TopicSettings, err error) {
+	filename := filepath.Join(dir, TopicSettingsFilename)
+
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s does not contain an archive of a forum topic: %s not found", dir, TopicSettingsFilename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open topic settings file %s", filename)
+	}
+	defer file.Close()
+
+	settings = &TopicSettings{}
+	err = json.NewDecoder(file).Decode(settings)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse topic settings file %s: %v", filename, err)
+	}
+
+	return
+}
@@ -0,0 +1,166 @@
+package archive
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// URLMapFilename is the name of the legacy map of the files in the archive to their original URLs, which is migrated
+// into the state database (see OpenState).
+const URLMapFilename = "urlmap.tsv"
+
+// URLMapEntry relates a file in the archive to the URL which was requested in order to obtain it and to the URLs
+// through which the request was redirected (the last one of which is the URL the content was actually served from).
+type URLMapEntry struct {
+	Filename      string
+	OriginalURL   string
+	RedirectChain []string
+}
+
+// URLMap is a bidirectional map between the files in the archive and their original URLs, where later entries take
+// precedence over earlier ones. It is persisted in the state database of the archive.
+type URLMap struct {
+	mutex      sync.Mutex
+	dir        string
+	state      *State
+	ownsState  bool
+	byFilename map[string]*URLMapEntry
+	byURL      map[string]*URLMapEntry
+}
+
+// LoadURLMap reads the map of the archive in the directory without opening it for recording.
+func LoadURLMap(dir string) (m *URLMap, err error) {
+	state, err := OpenState(dir)
+	if err != nil {
+		return
+	}
+	defer state.Close()
+
+	m, err = state.URLMap()
+	if err != nil {
+		return
+	}
+	m.state = nil
+	return m, nil
+}
+
+// OpenURLMap reads the map of the archive in the directory and opens it for recording.
+func OpenURLMap(dir string) (m *URLMap, err error) {
+	state, err := OpenState(dir)
+	if err != nil {
+		return
+	}
+
+	m, err = state.URLMap()
+	if err != nil {
+		state.Close()
+		return nil, err
+	}
+	m.ownsState = true
+	return m, nil
+}
+
+// URLMap reads the map of the archive, which records the files into the state.
+func (state *State) URLMap() (m *URLMap, err error) {
+	entries, err := state.urlMapEntries()
+	if err != nil {
+		return
+	}
+
+	m = &URLMap{
+		dir:        state.dir,
+		state:      state,
+		byFilename: map[string]*URLMapEntry{},
+		byURL:      map[string]*URLMapEntry{},
+	}
+	for _, entry := range entries {
+		m.add(entry)
+	}
+	return m, nil
+}
+
+func (m *URLMap) add(entry *URLMapEntry) {
+	m.byFilename[entry.Filename] = entry
+	m.byURL[entry.OriginalURL] = entry
+	for _, redirectURL := range entry.RedirectChain {
+		m.byURL[redirectURL] = entry
+	}
+}
+
+// Record adds the file to which the resource described by the metadata was written to the map and persists the mapping.
+func (m *URLMap) Record(filename string, metadata *ResourceMetadata) (err error) {
+	relativeFilename, err := filepath.Rel(m.dir, filename)
+	if err != nil {
+		return
+	}
+
+	entry := &URLMapEntry{
+		Filename:      filepath.ToSlash(relativeFilename),
+		OriginalURL:   metadata.URL,
+		RedirectChain: metadata.RedirectChain,
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.state == nil {
+		return errors.New("the map of original URLs has not been opened for recording")
+	}
+	m.add(entry)
+	return recordURLMapEntry(m.state.db, entry)
+}
+
+// LookupURL returns the entry for the file with the given path relative to the directory of the archive.
+func (m *URLMap) LookupURL(filename string) (entry *URLMapEntry, ok bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok = m.byFilename[filepath.ToSlash(filename)]
+	return
+}
+
+// LookupFilename returns the entry for the file in which the resource at the given URL (or redirected to it) was stored.
+func (m *URLMap) LookupFilename(urlStr string) (entry *URLMapEntry, ok bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok = m.byURL[urlStr]
+	return
+}
+
+// EntriesUnder returns the entries for the files whose path relative to the directory of the archive lies under the given directory.
+func (m *URLMap) EntriesUnder(dir string) (entries []*URLMapEntry) {
+	prefix := filepath.ToSlash(dir) + "/"
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for filename, entry := range m.byFilename {
+		if strings.HasPrefix(filename, prefix) {
+			entries = append(entries, entry)
+		}
+	}
+	return
+}
+
+// Entries returns the entries for all files in the archive.
+func (m *URLMap) Entries() (entries []*URLMapEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, entry := range m.byFilename {
+		entries = append(entries, entry)
+	}
+	return
+}
+
+// Close closes the state database if the map has been opened by OpenURLMap.
+func (m *URLMap) Close() error {
+	if !m.ownsState {
+		return nil
+	}
+
+	return m.state.Close()
+}
@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WaybackSnapshotsFilename is the name of the manifest of the snapshots of the pages of the archive which were saved in
+// the Wayback Machine of the Internet Archive.
+const WaybackSnapshotsFilename = "wayback.tsv"
+
+// RecordWaybackSnapshot appends the URL of the snapshot of the page at the URL to the manifest of the archive in the
+// directory. The manifest consists of tab-separated lines of the form `page URL  snapshot URL`.
+func RecordWaybackSnapshot(dir, pageURL, snapshotURL string) (err error) {
+	file, err := OpenFile(filepath.Join(dir, WaybackSnapshotsFilename), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\n", pageURL, snapshotURL)
+	return
+}
+
+// LoadWaybackSnapshots reads the manifest of the archive in the directory and returns the URL of the latest snapshot
+// of every page URL; a missing manifest is empty.
+func LoadWaybackSnapshots(dir string) (snapshotURLs map[string]string, err error) {
+	snapshotURLs = map[string]string{}
+
+	file, err := os.Open(filepath.Join(dir, WaybackSnapshotsFilename))
+	if os.IsNotExist(err) {
+		return snapshotURLs, nil
+	}
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		pageURL, snapshotURL, ok := strings.Cut(scanner.Text(), "\t")
+		if ok {
+			snapshotURLs[pageURL] = snapshotURL
+		}
+	}
+
+	err = scanner.Err()
+	return
+}
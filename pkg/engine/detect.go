@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Recognizer is implemented by the engines which can recognize the pages served by the software of their forums, which
+// lets the engine of a forum be detected from one of its pages (see Detect).
+type Recognizer interface {
+	Engine
+	// Recognizes reports whether the document of a page was served by the software of the forum.
+	Recognizes(doc *html.Node) bool
+	// TopicURL returns the base URL of the topic to which the page at the resolved URL belongs, in the form which
+	// PageURL expects.
+	TopicURL(pageURL *url.URL) string
+}
+
+// Detect returns the registered engine which recognizes the document of a page, or false if none does.
+func Detect(doc *html.Node) (engine Recognizer, ok bool) {
+	for _, name := range Names() {
+		registeredEngine, _ := Lookup(name)
+		if engine, ok = registeredEngine.(Recognizer); ok && engine.Recognizes(doc) {
+			return engine, true
+		}
+	}
+	return nil, false
+}
+
+// generator returns the content of the generator meta tag of the document, if any.
+func generator(doc *html.Node) string {
+	meta := find(doc, func(node *html.Node) bool {
+		return node.DataAtom == atom.Meta && withAttr("name", "generator")(node)
+	})
+	if meta == nil {
+		return ""
+	}
+
+	content, _ := getAttr(meta, "content")
+	return content
+}
+
+// Recognizes recognizes phpBB by the ID of the body of the prosilver style and the ones derived from it, or by the
+// generator meta tag, which some styles have.
+func (PhpBB) Recognizes(doc *html.Node) bool {
+	return find(doc, func(node *html.Node) bool {
+		return node.DataAtom == atom.Body && withAttr("id", "phpbb")(node)
+	}) != nil || strings.HasPrefix(generator(doc), "phpBB")
+}
+
+// TopicURL keeps the query parameters which identify the topic, dropping the ones which identify a page or a post, and
+// appends the offset parameter.
+func (PhpBB) TopicURL(pageURL *url.URL) string {
+	topicURL := *pageURL
+	topicURL.Fragment = ""
+	query := topicURL.Query()
+	query.Del("start")
+	if query.Has("t") {
+		query.Del("p")
+	}
+	topicURL.RawQuery = query.Encode()
+
+	separator := "?"
+	if topicURL.RawQuery != "" {
+		separator = "&"
+	}
+	return topicURL.String() + separator + "start="
+}
+
+// Recognizes recognizes XenForo by the ID of the root element, which is `XF` for version 2 and `XenForo` for version 1.
+func (XenForo) Recognizes(doc *html.Node) bool {
+	root := find(doc, withTag(atom.Html))
+	return root != nil && (withAttr("id", "XF")(root) || withAttr("id", "XenForo")(root))
+}
+
+// TopicURL drops the page suffix, the query and the fragment.
+func (XenForo) TopicURL(pageURL *url.URL) string {
+	topicURL := url.URL{Scheme: pageURL.Scheme, Host: pageURL.Host, Path: pageURL.Path}
+	return xenForoThreadURL(Topic{URL: topicURL.String()})
+}
+
+// Recognizes recognizes Discourse by the generator meta tag.
+func (Discourse) Recognizes(doc *html.Node) bool {
+	return strings.HasPrefix(generator(doc), "Discourse")
+}
+
+// TopicURL drops the number of the post to which the URL of a topic may point, the query and the fragment.
+func (Discourse) TopicURL(pageURL *url.URL) string {
+	topicURL := url.URL{Scheme: pageURL.Scheme, Host: pageURL.Host, Path: strings.TrimSuffix(pageURL.Path, "/")}
+
+	// the path of a topic is /t/slug/ID, to which the number of a post may be appended
+	if segments := strings.Split(topicURL.Path, "/"); len(segments) >= 5 && segments[len(segments)-4] == "t" {
+		topicURL.Path = strings.Join(segments[:len(segments)-1], "/")
+	}
+	return topicURL.String()
+}
@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Discourse is the engine for Discourse forums, whose topics are fetched in the view served to clients without
+// JavaScript. The base URL of a topic is the URL of the topic, e.g. `https://forum.example.com/t/title/123`; the post
+// step is not used.
+type Discourse struct{}
+
+func (Discourse) Name() string {
+	return "discourse"
+}
+
+func (Discourse) PageURL(topic Topic, pageNumber uint) string {
+	topicURL, err := url.Parse(topic.URL)
+	if err != nil {
+		return topic.URL
+	}
+
+	query := topicURL.Query()
+	if pageNumber <= 1 {
+		query.Del("page")
+	} else {
+		query.Set("page", strconv.FormatUint(uint64(pageNumber), 10))
+	}
+	topicURL.RawQuery = query.Encode()
+	return topicURL.String()
+}
+
+func (Discourse) DetectLastPage(topic Topic, doc *html.Node, docURL *url.URL) uint {
+	return maxLinkedPageNumber(doc, docURL, func(linkURL *url.URL) (uint, bool) {
+		return Discourse{}.PageNumber(topic, linkURL)
+	})
+}
+
+// PageNumber recognizes the URLs of the pages of the topic by their `page` query parameter, which the first page lacks.
+func (Discourse) PageNumber(topic Topic, pageURL *url.URL) (uint, bool) {
+	topicURL, err := url.Parse(topic.URL)
+	if err != nil || pageURL.Host != topicURL.Host || strings.TrimSuffix(pageURL.Path, "/") != strings.TrimSuffix(topicURL.Path, "/") {
+		return 0, false
+	}
+
+	page := pageURL.Query().Get("page")
+	if page == "" {
+		return 1, true
+	}
+	pageNumber, err := strconv.ParseUint(page, 10, 0)
+	return uint(pageNumber), err == nil
+}
+
+// LinkedTopicURL recognizes the links to the topics by the `t` segment of their path, which is followed by the slug and
+// the ID of the topic (or only by the ID) and possibly by the number of a post.
+func (Discourse) LinkedTopicURL(linkURL *url.URL) (*url.URL, bool) {
+	prefix, rest, ok := strings.Cut(linkURL.Path, "/t/")
+	if !ok {
+		return nil, false
+	}
+
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	if _, err := strconv.ParseUint(segments[0], 10, 0); err == nil {
+		// the slug is optional
+		segments = append([]string{""}, segments...)
+	}
+	if len(segments) < 2 {
+		return nil, false
+	}
+	if _, err := strconv.ParseUint(segments[1], 10, 0); err != nil {
+		return nil, false
+	}
+
+	topicPath := prefix + "/t/" + segments[1]
+	if segments[0] != "" {
+		topicPath = prefix + "/t/" + segments[0] + "/" + segments[1]
+	}
+	return &url.URL{Scheme: linkURL.Scheme, Host: linkURL.Host, Path: topicPath}, true
+}
+
+func (Discourse) PostNodes(doc *html.Node) []*html.Node {
+	return findAll(doc, withClass("crawler-post"))
+}
+
+func (Discourse) ExtractPosts(doc *html.Node) (posts []*Post) {
+	for _, postNode := range (Discourse{}).PostNodes(doc) {
+		post := &Post{}
+		if id, ok := getAttr(postNode, "id"); ok {
+			post.ID = strings.TrimPrefix(id, "post_")
+			post.Anchor = id
+		}
+
+		if authorNode := find(postNode, withAttr("itemprop", "author")); authorNode != nil {
+			post.Author = text(find(authorNode, withAttr("itemprop", "name")))
+		}
+
+		if timeNode := find(postNode, withAttr("itemprop", "datePublished")); timeNode != nil {
+			if datetime, ok := getAttr(timeNode, "content"); ok {
+				post.Time, _ = time.Parse(time.RFC3339, datetime)
+			}
+		}
+
+		contentNode := find(postNode, withAttr("itemprop", "text"))
+		post.Text = postText(contentNode, Discourse{}, nil)
+		post.Links = links(contentNode, nil)
+		post.Images = images(contentNode, Discourse{}, nil)
+		if contentNode != nil {
+			for _, quoteNode := range findAll(contentNode, withClass("quote")) {
+				if author, ok := getAttr(quoteNode, "data-username"); ok && author != "" {
+					post.Quoted = append(post.Quoted, author)
+				}
+			}
+
+			// the uploaded files are linked as attachments and the uploaded images are wrapped for the lightbox
+			post.Attachments = len(findAll(contentNode, func(node *html.Node) bool {
+				return node.DataAtom == atom.A && hasClass(node, "attachment") || hasClass(node, "lightbox-wrapper")
+			}))
+		}
+		posts = append(posts, post)
+	}
+	return
+}
+
+func (Discourse) AttachmentURL(linkURL *url.URL) (*url.URL, bool) {
+	if !strings.HasPrefix(linkURL.Path, "/uploads/") {
+		return nil, false
+	}
+
+	return linkURL, true
+}
+
+// SmileyCode recognizes the images of emoji, whose title is their code, e.g. `:smile:`.
+func (Discourse) SmileyCode(element atom.Atom, attrs []html.Attribute) (string, bool) {
+	return smileyImageCode(element, attrs, []string{"emoji"}, "title", "alt")
+}
+
+// AuthenticateAPIRequest adds the API key to the request; the user name may only be omitted for keys which are
+// restricted to a single user.
+func (Discourse) AuthenticateAPIRequest(header http.Header, key APIKey) {
+	header.Set("Api-Key", key.Key)
+	if key.Username != "" {
+		header.Set("Api-Username", key.Username)
+	}
+}
+
+// DiscourseScroll is the engine for Discourse forums whose topics are fetched through the JSON endpoint from which the
+// posts are loaded as the user scrolls, for when the view served to clients without JavaScript is not available. The
+// base URL of a topic is the URL of the topic, e.g. `https://forum.example.com/t/title/123`; the post step is the
+// number of posts on each archived page.
+type DiscourseScroll struct {
+	Discourse
+}
+
+func (DiscourseScroll) Name() string {
+	return "discourse-scroll"
+}
+
+// discoursePostStream is the part of the response of the posts endpoint of a Discourse topic which is of interest.
+type discoursePostStream struct {
+	PostStream struct {
+		Posts []struct {
+			PostNumber uint      `json:"post_number"`
+			Username   string    `json:"username"`
+			CreatedAt  time.Time `json:"created_at"`
+			Cooked     string    `json:"cooked"`
+		} `json:"posts"`
+	} `json:"post_stream"`
+}
+
+// discourseTopicID returns the ID of the topic, which is the last numeric segment of the path of its URL after /t/.
+func discourseTopicID(topicURL *url.URL) (id string, ok bool) {
+	segments := strings.Split(strings.Trim(topicURL.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] != "t" {
+		return
+	}
+
+	for _, segment := range segments[1:] {
+		if _, err := strconv.ParseUint(segment, 10, 0); err == nil {
+			id, ok = segment, true
+		}
+	}
+	return
+}
+
+// BatchURL returns the URL of the posts following the one whose number is the cursor.
+func (DiscourseScroll) BatchURL(topic Topic, cursor string) string {
+	topicURL, err := url.Parse(topic.URL)
+	if err != nil {
+		return topic.URL
+	}
+
+	id, ok := discourseTopicID(topicURL)
+	if !ok {
+		return topic.URL
+	}
+
+	if cursor == "" {
+		cursor = "0"
+	}
+	batchURL := &url.URL{
+		Scheme: topicURL.Scheme,
+		Host:   topicURL.Host,
+		Path:   "/t/" + id + "/posts.json",
+		RawQuery: url.Values{
+			"post_number":       {cursor},
+			"asc":               {"true"},
+			"include_suggested": {"false"},
+		}.Encode(),
+	}
+	return batchURL.String()
+}
+
+func (DiscourseScroll) ParseBatch(topic Topic, cursor string, content []byte) (batch *Batch, err error) {
+	var stream discoursePostStream
+	err = json.Unmarshal(content, &stream)
+	if err != nil {
+		return
+	}
+
+	batch = &Batch{}
+	for _, streamPost := range stream.PostStream.Posts {
+		number := strconv.FormatUint(uint64(streamPost.PostNumber), 10)
+		batch.Posts = append(batch.Posts, &ScrolledPost{
+			Post: Post{
+				ID:     number,
+				Author: streamPost.Username,
+				Time:   streamPost.CreatedAt,
+				Anchor: "post_" + number,
+			},
+			HTML: streamPost.Cooked,
+		})
+		batch.NextCursor = number
+	}
+	return
+}
+
+func (DiscourseScroll) RenderPage(topic Topic, pageNumber uint, posts []*ScrolledPost) string {
+	return renderCrawlerPage(fmt.Sprintf("Page %d", pageNumber), posts)
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultDuplicateWindow is the longest time between a post and an earlier one of the same author for it to be taken
+// for a duplicate of it.
+const DefaultDuplicateWindow = 10 * time.Minute
+
+// DefaultDuplicateSimilarity is the least similarity of the text of a post to the one of an earlier post of the same
+// author for it to be taken for a duplicate of it.
+const DefaultDuplicateSimilarity = 0.9
+
+// maxRecentPosts is the number of the latest posts which a DuplicateDetector compares the next post with.
+const maxRecentPosts = 20
+
+// recentPost is a post which later ones are compared with.
+type recentPost struct {
+	post      *Post
+	wordCount map[string]int
+}
+
+// DuplicateDetector recognizes double posts and the duplicates which forums create when a post is submitted twice
+// because of a glitch: posts of the same author made shortly after one another with nearly identical text.
+type DuplicateDetector struct {
+	// Window is the longest time between the posts; if the time of either of them is unknown, only a post right after
+	// the original one can be its duplicate.
+	Window time.Duration
+	// Similarity is the least similarity of the texts of the posts, from 0 to 1, as the share of the words which they
+	// have in common.
+	Similarity float64
+
+	recentPosts []*recentPost
+}
+
+// NewDuplicateDetector returns a detector with the default window and similarity.
+func NewDuplicateDetector() *DuplicateDetector {
+	return &DuplicateDetector{
+		Window:     DefaultDuplicateWindow,
+		Similarity: DefaultDuplicateSimilarity,
+	}
+}
+
+// duplicateWordCount returns how many times every word occurs in the text, ignoring case and punctuation.
+func duplicateWordCount(text string) (wordCount map[string]int) {
+	wordCount = map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		wordCount[word]++
+	}
+	return
+}
+
+// similarity returns the number of the occurrences of words which the texts have in common divided by the number of
+// the occurrences of words in either of them.
+func similarity(a, b map[string]int) float64 {
+	var common, total int
+	for word, countA := range a {
+		countB := b[word]
+		common += min(countA, countB)
+		total += max(countA, countB)
+	}
+	for word, countB := range b {
+		if _, ok := a[word]; !ok {
+			total += countB
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(common) / float64(total)
+}
+
+// Check reports whether the post is a duplicate of one of the posts checked before it, which have to be passed in the
+// order in which they appear in the topic, and returns the original post. Posts without an author or text are never
+// taken for duplicates.
+func (d *DuplicateDetector) Check(post *Post) (original *Post, ok bool) {
+	wordCount := duplicateWordCount(post.Text)
+	if post.Author == "" || len(wordCount) == 0 {
+		d.remember(post, wordCount)
+		return
+	}
+
+	for i := len(d.recentPosts) - 1; i >= 0; i-- {
+		recent := d.recentPosts[i]
+		if post.Time.IsZero() || recent.post.Time.IsZero() {
+			if i != len(d.recentPosts)-1 {
+				break
+			}
+		} else if elapsed := post.Time.Sub(recent.post.Time); elapsed < 0 || elapsed > d.Window {
+			continue
+		}
+
+		if recent.post.Author == post.Author && similarity(recent.wordCount, wordCount) >= d.Similarity {
+			return recent.post, true
+		}
+	}
+
+	d.remember(post, wordCount)
+	return
+}
+
+// remember adds the post to the ones which later posts are compared with.
+func (d *DuplicateDetector) remember(post *Post, wordCount map[string]int) {
+	d.recentPosts = append(d.recentPosts, &recentPost{post, wordCount})
+	if len(d.recentPosts) > maxRecentPosts {
+		d.recentPosts = d.recentPosts[len(d.recentPosts)-maxRecentPosts:]
+	}
+}
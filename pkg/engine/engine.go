@@ -0,0 +1,187 @@
+// Package engine adapts the fetching and processing of forum topics to the software which serves the forum. Adapters
+// for some forum engines are built in; others can be added by registering them.
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Topic identifies a forum topic.
+type Topic struct {
+	// URL is the base URL of the pages of the topic, whose meaning depends on the engine.
+	URL string
+	// PostStep is the number of posts contained on a single page.
+	PostStep uint
+}
+
+// Post is a post extracted from a page of a topic.
+type Post struct {
+	// ID identifies the post within the topic; it is empty if the engine does not expose it.
+	ID     string
+	Author string
+	// Time is when the post was made; it is zero if it is unknown.
+	Time time.Time
+	// Text is the plain text of the content of the post with the whitespace collapsed.
+	Text string
+	// Signature is the plain text of the signature which the forum appends to the posts of the author, which is not
+	// part of Text; it is empty if there is none or the engine does not recognize signatures.
+	Signature string
+	// Anchor is the fragment of the URL of the page which links to the post, if any.
+	Anchor string
+	// Links are the links in the content of the post (excluding the signature), in the order in which they appear.
+	Links []*Link
+	// Images are the sources of the images in the content of the post (excluding the signature and the smileys), in
+	// the order in which they appear.
+	Images []string
+	// Quoted are the names of the authors of the posts quoted in the post, in the order in which they appear; it is
+	// empty if the engine does not recognize quotes.
+	Quoted []string
+	// Attachments is the number of files attached to the post.
+	Attachments int
+}
+
+// Link is a link in the content of a post.
+type Link struct {
+	// URL is the target of the link as it appears in the page.
+	URL string
+	// Text is the plain text of the anchor of the link with the whitespace collapsed.
+	Text string
+}
+
+// Engine is an adapter for the software which serves a forum.
+type Engine interface {
+	// Name returns the name under which the engine is registered.
+	Name() string
+	// PageURL returns the URL of the page with the given number, starting from 1.
+	PageURL(topic Topic, pageNumber uint) string
+	// DetectLastPage returns the number of the last page of the topic as it can be determined from the document of one
+	// of its pages, which was fetched from docURL, or 0 if it cannot be determined.
+	DetectLastPage(topic Topic, doc *html.Node, docURL *url.URL) uint
+	// ExtractPosts returns the posts on a page of a topic.
+	ExtractPosts(doc *html.Node) []*Post
+	// AttachmentURL reports whether the resolved URL of a link refers to a file attached to a post, which is then
+	// archived along with the page, and returns the URL from which to fetch it.
+	AttachmentURL(linkURL *url.URL) (attachmentURL *url.URL, ok bool)
+}
+
+// Thumbnailer is implemented by the engines of forums which serve thumbnails of the images attached to posts, which
+// are fetched in place of the images which cannot be fetched themselves.
+type Thumbnailer interface {
+	Engine
+	// ThumbnailURL returns the URL of the thumbnail of the image at the resolved URL, or false if there is none.
+	ThumbnailURL(imageURL *url.URL) (thumbnailURL *url.URL, ok bool)
+}
+
+// PageLocator is implemented by the engines which can tell the number of a page of a topic from its URL, which lets the
+// pages of the topic be discovered among the URLs listed in the sitemaps of the forum.
+type PageLocator interface {
+	Engine
+	// PageNumber returns the number of the page of the topic at the resolved URL, or false if it is not the URL of a
+	// page of the topic.
+	PageNumber(topic Topic, pageURL *url.URL) (pageNumber uint, ok bool)
+}
+
+// TopicLocator is implemented by the engines which can tell the links to the topics of the forum, which lets the topics
+// referenced by the posts be archived along with the topic.
+type TopicLocator interface {
+	Engine
+	// LinkedTopicURL returns the URL of the first page of the topic to which the resolved URL links (or, for the
+	// engines whose links to posts do not tell their topic, the URL of the page of the post), or false if it does not
+	// link to a topic. It is named apart from Recognizer.TopicURL, which the engines implement as well.
+	LinkedTopicURL(linkURL *url.URL) (topicURL *url.URL, ok bool)
+}
+
+// PostFinder is implemented by the engines which can tell the elements of the posts on a page, which lets the posts be
+// removed from the page.
+type PostFinder interface {
+	Engine
+	// PostNodes returns the elements of the posts on a page of a topic, in the order in which they appear.
+	PostNodes(doc *html.Node) []*html.Node
+}
+
+// KeepFirstPost removes the posts after the first one from the document of a page of a topic, and returns false if the
+// engine cannot tell the posts apart.
+func KeepFirstPost(e Engine, doc *html.Node) bool {
+	finder, ok := e.(PostFinder)
+	if !ok {
+		return false
+	}
+
+	postNodes := finder.PostNodes(doc)
+	if len(postNodes) == 0 {
+		return false
+	}
+	for _, postNode := range postNodes[1:] {
+		postNode.Parent.RemoveChild(postNode)
+	}
+	return true
+}
+
+// APIKey is a key with which the JSON endpoints of a forum are accessed on behalf of one of its users.
+type APIKey struct {
+	Key string
+	// Username is the name of the user on whose behalf the requests are made, if the forum needs it along with the key.
+	Username string
+}
+
+// APIAuthenticator is implemented by the engines of forums whose JSON endpoints accept API keys, which many instances
+// restrict to authenticated users.
+type APIAuthenticator interface {
+	Engine
+	// AuthenticateAPIRequest adds the API key to the header of a request to a JSON endpoint of the forum.
+	AuthenticateAPIRequest(header http.Header, key APIKey)
+}
+
+var (
+	enginesMutex sync.RWMutex
+	engines      = map[string]Engine{}
+)
+
+// Register makes an engine available by its name. It panics if an engine with the same name has already been
+// registered.
+func Register(engine Engine) {
+	enginesMutex.Lock()
+	defer enginesMutex.Unlock()
+
+	name := engine.Name()
+	if _, ok := engines[name]; ok {
+		panic(fmt.Sprintf("engine: Register called twice for engine %s", name))
+	}
+	engines[name] = engine
+}
+
+// Lookup returns the engine registered under the name.
+func Lookup(name string) (engine Engine, ok bool) {
+	enginesMutex.RLock()
+	defer enginesMutex.RUnlock()
+
+	engine, ok = engines[name]
+	return
+}
+
+// Names returns the sorted names of the registered engines.
+func Names() (names []string) {
+	enginesMutex.RLock()
+	defer enginesMutex.RUnlock()
+
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+func init() {
+	Register(Generic{})
+	Register(PhpBB{})
+	Register(XenForo{})
+	Register(Discourse{})
+	Register(DiscourseScroll{})
+}
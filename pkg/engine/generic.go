@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Generic is the engine for forums whose topic pages are addressed by appending the offset of the first post on the
+// page to the base URL of the topic. It knows nothing about the structure of the pages.
+type Generic struct{}
+
+func (Generic) Name() string {
+	return "generic"
+}
+
+func (Generic) PageURL(topic Topic, pageNumber uint) string {
+	return offsetPageURL(topic, pageNumber)
+}
+
+// DetectLastPage looks for the link with the largest offset among the links to other pages of the topic.
+func (Generic) DetectLastPage(topic Topic, doc *html.Node, docURL *url.URL) uint {
+	return maxLinkedPageNumber(doc, docURL, func(linkURL *url.URL) (uint, bool) {
+		return offsetPageNumber(topic, linkURL)
+	})
+}
+
+func (Generic) PageNumber(topic Topic, pageURL *url.URL) (uint, bool) {
+	return offsetPageNumber(topic, pageURL)
+}
+
+// ExtractPosts returns the whole text of the body of the page as a single post, whose signature is made of the elements
+// with the class signature, which many forums use for them.
+func (Generic) ExtractPosts(doc *html.Node) []*Post {
+	body := find(doc, withTag(atom.Body))
+	if body == nil {
+		return nil
+	}
+
+	isSignature := withClass("signature")
+	var signatures []string
+	for _, signatureNode := range findAll(body, isSignature) {
+		if signature := postText(signatureNode, Generic{}, nil); signature != "" {
+			signatures = append(signatures, signature)
+		}
+	}
+
+	return []*Post{{
+		Text:      postText(body, Generic{}, isSignature),
+		Signature: strings.Join(signatures, " "),
+		Links:     links(body, isSignature),
+		Images:    images(body, Generic{}, isSignature),
+	}}
+}
+
+func (Generic) AttachmentURL(linkURL *url.URL) (*url.URL, bool) {
+	return nil, false
+}
+
+// SmileyCode recognizes the images of smileys by the classes which most forum software gives them, taking their code
+// from their alternative text.
+func (Generic) SmileyCode(element atom.Atom, attrs []html.Attribute) (string, bool) {
+	return smileyImageCode(element, attrs, []string{"smilies", "smilie", "smiley", "emoji", "emoticon"}, "alt", "title")
+}
+
+func offsetPageURL(topic Topic, pageNumber uint) string {
+	postOffset := topic.PostStep * (pageNumber - 1)
+	return fmt.Sprintf("%s%d", topic.URL, postOffset)
+}
+
+// offsetPageNumber returns the number of the page whose offset is appended to the base URL of the topic; the URL of the
+// first page may also lack the offset along with the query parameter which it is the value of, if any.
+func offsetPageNumber(topic Topic, linkURL *url.URL) (pageNumber uint, ok bool) {
+	linkURLStr := linkURL.String()
+	if index := strings.LastIndexAny(topic.URL, "?&"); index >= 0 && strings.HasSuffix(topic.URL, "=") && linkURLStr == topic.URL[:index] {
+		return 1, true
+	}
+	if !strings.HasPrefix(linkURLStr, topic.URL) || topic.PostStep == 0 {
+		return
+	}
+
+	postOffset, err := strconv.ParseUint(strings.TrimPrefix(linkURLStr, topic.URL), 10, 0)
+	if err != nil {
+		return
+	}
+
+	return uint(postOffset)/topic.PostStep + 1, true
+}
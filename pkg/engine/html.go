@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// getAttr returns the value of the attribute of the element with the given key.
+func getAttr(node *html.Node, key string) (val string, ok bool) {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+
+	return
+}
+
+func hasClass(node *html.Node, class string) bool {
+	classes, _ := getAttr(node, "class")
+	for _, nodeClass := range strings.Fields(classes) {
+		if nodeClass == class {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findAll returns the elements under the node (including itself) which match, without descending into the matching ones.
+func findAll(node *html.Node, matches func(*html.Node) bool) (found []*html.Node) {
+	if node.Type == html.ElementNode && matches(node) {
+		return []*html.Node{node}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		found = append(found, findAll(child, matches)...)
+	}
+	return
+}
+
+// find returns the first element under the node (including itself) which matches, or nil if there is none.
+func find(node *html.Node, matches func(*html.Node) bool) *html.Node {
+	if node.Type == html.ElementNode && matches(node) {
+		return node
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, matches); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func withClass(class string) func(*html.Node) bool {
+	return func(node *html.Node) bool {
+		return hasClass(node, class)
+	}
+}
+
+func withAttr(key, val string) func(*html.Node) bool {
+	return func(node *html.Node) bool {
+		nodeVal, ok := getAttr(node, key)
+		return ok && nodeVal == val
+	}
+}
+
+func withTag(tag atom.Atom) func(*html.Node) bool {
+	return func(node *html.Node) bool {
+		return node.DataAtom == tag
+	}
+}
+
+// text returns the text under the node, except for the content of scripts and stylesheets, with the whitespace collapsed.
+func text(node *html.Node) string {
+	return postText(node, nil, nil)
+}
+
+// postText returns the text under the node like text, except for the content of the elements which are excluded, with
+// the images of the smileys recognized by the engine, if any, replaced with their codes.
+func postText(node *html.Node, smileys SmileyRecognizer, isExcluded func(*html.Node) bool) string {
+	if node == nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			builder.WriteString(node.Data)
+			builder.WriteString(" ")
+		case node.Type == html.ElementNode && (node.DataAtom == atom.Script || node.DataAtom == atom.Style):
+			return
+		case node.Type == html.ElementNode && isExcluded != nil && isExcluded(node):
+			return
+		case node.Type == html.ElementNode && node.DataAtom == atom.Br:
+			builder.WriteString(" ")
+		case node.Type == html.ElementNode && smileys != nil:
+			if code, ok := smileys.SmileyCode(node.DataAtom, node.Attr); ok {
+				builder.WriteString(" ")
+				builder.WriteString(code)
+				builder.WriteString(" ")
+			}
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(node)
+
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// links returns the links under the node, except for the ones in the elements which are excluded.
+func links(node *html.Node, isExcluded func(*html.Node) bool) (found []*Link) {
+	if node == nil {
+		return
+	}
+
+	for _, linkNode := range findAll(node, func(node *html.Node) bool {
+		return node.DataAtom == atom.A || isExcluded != nil && isExcluded(node)
+	}) {
+		if href, ok := getAttr(linkNode, "href"); ok && linkNode.DataAtom == atom.A && strings.TrimSpace(href) != "" {
+			found = append(found, &Link{URL: strings.TrimSpace(href), Text: text(linkNode)})
+		}
+	}
+	return
+}
+
+// images returns the sources of the images under the node, except for the ones in the elements which are excluded and
+// the smileys recognized by the engine, if any.
+func images(node *html.Node, smileys SmileyRecognizer, isExcluded func(*html.Node) bool) (sources []string) {
+	if node == nil {
+		return
+	}
+
+	for _, imageNode := range findAll(node, func(node *html.Node) bool {
+		return node.DataAtom == atom.Img || isExcluded != nil && isExcluded(node)
+	}) {
+		if imageNode.DataAtom != atom.Img {
+			continue
+		}
+		if smileys != nil {
+			if _, ok := smileys.SmileyCode(imageNode.DataAtom, imageNode.Attr); ok {
+				continue
+			}
+		}
+
+		if src, ok := getAttr(imageNode, "src"); ok && strings.TrimSpace(src) != "" {
+			sources = append(sources, strings.TrimSpace(src))
+		}
+	}
+	return
+}
+
+// maxLinkedPageNumber returns the largest page number which pageNumber extracts from the resolved URLs of the links
+// in the document, or 1 if there is none.
+func maxLinkedPageNumber(doc *html.Node, docURL *url.URL, pageNumber func(linkURL *url.URL) (uint, bool)) (lastPageNumber uint) {
+	lastPageNumber = 1
+	for _, link := range findAll(doc, withTag(atom.A)) {
+		href, ok := getAttr(link, "href")
+		if !ok {
+			continue
+		}
+
+		linkURI, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+
+		if number, ok := pageNumber(docURL.ResolveReference(linkURI)); ok && number > lastPageNumber {
+			lastPageNumber = number
+		}
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Loginer is implemented by the engines of forums whose users log in by submitting a form with their user name and
+// password, which is done again whenever the session expires during a run.
+type Loginer interface {
+	Engine
+	// LoginURL returns the URL of the page with the login form of the forum of the topic.
+	LoginURL(topic Topic) string
+	// IsLoginPage reports whether the start of the content of an HTML page is the login form, which the forum serves in
+	// place of the requested page once the session has expired.
+	IsLoginPage(content []byte) bool
+}
+
+// LoginForm finds the form with a password input in the document, which was fetched from docURL, and returns the URL
+// to which it is submitted along with its values, the user name being filled into its first text input. It returns
+// false if there is no such form.
+func LoginForm(doc *html.Node, docURL *url.URL, username, password string) (actionURL *url.URL, values url.Values, ok bool) {
+	for _, form := range findAll(doc, withTag(atom.Form)) {
+		if find(form, withAttr("type", "password")) == nil {
+			continue
+		}
+
+		action, _ := getAttr(form, "action")
+		actionURL, err := docURL.Parse(action)
+		if err != nil {
+			return nil, nil, false
+		}
+
+		values = url.Values{}
+		var usernameInput string
+		hasSubmit := false
+		for _, input := range findAll(form, withTag(atom.Input)) {
+			name, ok := getAttr(input, "name")
+			if !ok || name == "" {
+				continue
+			}
+
+			value, _ := getAttr(input, "value")
+			inputType, _ := getAttr(input, "type")
+			switch strings.ToLower(inputType) {
+			case "password":
+				values.Set(name, password)
+			case "", "text", "email":
+				if usernameInput == "" {
+					usernameInput = name
+				}
+				values.Set(name, value)
+			case "checkbox", "radio":
+				if _, ok := getAttr(input, "checked"); ok {
+					values.Set(name, value)
+				}
+			case "submit":
+				// only the button which submits the form is sent
+				if !hasSubmit {
+					values.Set(name, value)
+					hasSubmit = true
+				}
+			default:
+				values.Set(name, value)
+			}
+		}
+		if usernameInput != "" {
+			values.Set(usernameInput, username)
+		}
+		return actionURL, values, true
+	}
+
+	return nil, nil, false
+}
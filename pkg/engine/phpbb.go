@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PhpBB is the engine for phpBB forums. Like with the generic engine, the base URL of a topic ends with the offset
+// parameter, e.g. `https://forum.example.com/viewtopic.php?t=123&start=`.
+type PhpBB struct{}
+
+func (PhpBB) Name() string {
+	return "phpbb"
+}
+
+func (PhpBB) PageURL(topic Topic, pageNumber uint) string {
+	return offsetPageURL(topic, pageNumber)
+}
+
+func (PhpBB) DetectLastPage(topic Topic, doc *html.Node, docURL *url.URL) uint {
+	return Generic{}.DetectLastPage(topic, doc, docURL)
+}
+
+func (PhpBB) PageNumber(topic Topic, pageURL *url.URL) (uint, bool) {
+	return offsetPageNumber(topic, pageURL)
+}
+
+// LinkedTopicURL recognizes the links to viewtopic.php by their topic parameter or, lacking it, by their post parameter,
+// which phpBB redirects to the page of the post.
+func (PhpBB) LinkedTopicURL(linkURL *url.URL) (*url.URL, bool) {
+	if path.Base(linkURL.Path) != "viewtopic.php" {
+		return nil, false
+	}
+
+	query := linkURL.Query()
+	topicQuery := url.Values{}
+	if topicID := query.Get("t"); topicID != "" {
+		topicQuery.Set("t", topicID)
+	} else if postID := query.Get("p"); postID != "" {
+		topicQuery.Set("p", postID)
+	} else {
+		return nil, false
+	}
+	return &url.URL{Scheme: linkURL.Scheme, Host: linkURL.Host, Path: linkURL.Path, RawQuery: topicQuery.Encode()}, true
+}
+
+// PostNodes finds the posts of the prosilver style and the ones derived from it.
+func (PhpBB) PostNodes(doc *html.Node) []*html.Node {
+	return findAll(doc, withClass("post"))
+}
+
+// ExtractPosts extracts the posts of the prosilver style and the ones derived from it.
+func (PhpBB) ExtractPosts(doc *html.Node) (posts []*Post) {
+	for _, postNode := range (PhpBB{}).PostNodes(doc) {
+		post := &Post{}
+		if id, ok := getAttr(postNode, "id"); ok {
+			post.ID = strings.TrimPrefix(id, "p")
+			post.Anchor = id
+		}
+
+		if authorNode := find(postNode, withClass("author")); authorNode != nil {
+			post.Author = text(find(authorNode, withClass("username")))
+			if post.Author == "" {
+				post.Author = text(find(authorNode, withClass("username-coloured")))
+			}
+
+			if timeNode := find(authorNode, withTag(atom.Time)); timeNode != nil {
+				if datetime, ok := getAttr(timeNode, "datetime"); ok {
+					post.Time, _ = time.Parse(time.RFC3339, datetime)
+				}
+			}
+		}
+
+		contentNode := find(postNode, withClass("content"))
+		post.Text = postText(contentNode, PhpBB{}, nil)
+		post.Links = links(contentNode, nil)
+		post.Images = images(contentNode, PhpBB{}, nil)
+		post.Signature = postText(find(postNode, withClass("signature")), PhpBB{}, nil)
+
+		if contentNode != nil {
+			for _, quoteNode := range findAll(contentNode, withTag(atom.Blockquote)) {
+				if author := phpBBQuotedAuthor(find(quoteNode, withTag(atom.Cite))); author != "" {
+					post.Quoted = append(post.Quoted, author)
+				}
+			}
+		}
+		// both the inline attachments and the ones listed below the post are definition lists
+		post.Attachments = len(findAll(postNode, func(node *html.Node) bool {
+			return node.DataAtom == atom.Dl && (hasClass(node, "file") || hasClass(node, "thumbnail"))
+		}))
+
+		posts = append(posts, post)
+	}
+	return
+}
+
+// phpBBQuotedAuthor returns the name of the author of a quoted post from the citation of the quote, which is either a
+// link to the profile of the author or, in older styles and for guests, the name followed by `wrote:`.
+func phpBBQuotedAuthor(citeNode *html.Node) string {
+	if citeNode == nil {
+		return ""
+	}
+
+	if profileLink := find(citeNode, func(node *html.Node) bool {
+		href, _ := getAttr(node, "href")
+		return node.DataAtom == atom.A && strings.Contains(href, "memberlist.php")
+	}); profileLink != nil {
+		return text(profileLink)
+	}
+
+	author, _, _ := strings.Cut(text(citeNode), " wrote")
+	return strings.TrimSpace(author)
+}
+
+func (PhpBB) AttachmentURL(linkURL *url.URL) (*url.URL, bool) {
+	if path.Base(linkURL.Path) != "file.php" || path.Base(path.Dir(linkURL.Path)) != "download" {
+		return nil, false
+	}
+
+	return linkURL, true
+}
+
+// ThumbnailURL returns the URL of the thumbnail which phpBB generates for an attached image.
+func (PhpBB) ThumbnailURL(imageURL *url.URL) (*url.URL, bool) {
+	if _, ok := (PhpBB{}).AttachmentURL(imageURL); !ok || imageURL.Query().Get("t") == "1" {
+		return nil, false
+	}
+
+	thumbnailURL := *imageURL
+	query := thumbnailURL.Query()
+	query.Set("t", "1")
+	thumbnailURL.RawQuery = query.Encode()
+	return &thumbnailURL, true
+}
+
+// LoginURL returns the URL of the user control panel in login mode, which is next to viewtopic.php.
+func (PhpBB) LoginURL(topic Topic) string {
+	topicURL, err := url.Parse(topic.URL)
+	if err != nil {
+		return ""
+	}
+
+	return topicURL.ResolveReference(&url.URL{Path: "ucp.php", RawQuery: "mode=login"}).String()
+}
+
+// IsLoginPage recognizes the login form which phpBB shows in place of the topics which only users may read.
+func (PhpBB) IsLoginPage(content []byte) bool {
+	return bytes.Contains(content, []byte(`method="post" id="login"`))
+}
+
+// SmileyCode recognizes the images of smileys, whose alternative text is their code.
+func (PhpBB) SmileyCode(element atom.Atom, attrs []html.Attribute) (string, bool) {
+	return smileyImageCode(element, attrs, []string{"smilies"}, "alt")
+}
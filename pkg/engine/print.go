@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"strings"
+)
+
+// PrintLayout tells the parts of the pages of a forum apart for the print-ready stylesheet of the archived pages (see
+// PrintStylesheet). The parts are given as CSS selectors.
+type PrintLayout struct {
+	// Chrome are the navigation bars, headers, footers, sidebars and controls of the forum, which are not printed.
+	Chrome []string
+	// Posts are the posts, each of which is printed starting on a new sheet.
+	Posts []string
+	// Quotes are the quotes in posts, which are printed in full even if the forum collapses the long ones.
+	Quotes []string
+}
+
+// PrintLayouter is implemented by the engines which know the layout of the pages of their forums.
+type PrintLayouter interface {
+	Engine
+	// PrintLayout returns the layout of the pages of the forum.
+	PrintLayout() *PrintLayout
+}
+
+// commonPrintLayout is the layout which the pages of most forums roughly follow, which is used for the engines which do
+// not know better.
+var commonPrintLayout = &PrintLayout{
+	Chrome: []string{"nav", "body > header", "body > footer", "form", "button", "[role=navigation]", ".navbar", ".navigation", ".breadcrumb", ".breadcrumbs", ".pagination", ".pagenav", ".sidebar"},
+	Posts:  []string{".post"},
+	Quotes: []string{"blockquote", ".quote"},
+}
+
+// PrintLayout returns the layout which the pages of most forums roughly follow.
+func (Generic) PrintLayout() *PrintLayout {
+	return commonPrintLayout
+}
+
+func (PhpBB) PrintLayout() *PrintLayout {
+	return &PrintLayout{
+		Chrome: []string{"#page-header", "#page-footer", ".headerbar", ".navbar", ".action-bar", ".pagination", ".post-buttons", ".back2top", "#jumpbox", ".jumpbox", "#quickreply", ".skiplink"},
+		Posts:  []string{".post"},
+		Quotes: []string{"blockquote", ".codebox"},
+	}
+}
+
+func (XenForo) PrintLayout() *PrintLayout {
+	return &PrintLayout{
+		Chrome: []string{".p-header", ".p-navSticky", ".p-nav", ".p-sectionLinks", ".p-breadcrumbs", ".p-footer", ".p-body-sidebar", ".block-outer", ".pageNav", ".message-actionBar", ".message-footer", ".js-quickReply", ".u-bottomFixer"},
+		Posts:  []string{"article.message"},
+		Quotes: []string{".bbCodeBlock", ".bbCodeBlock-expandContent"},
+	}
+}
+
+func (Discourse) PrintLayout() *PrintLayout {
+	return &PrintLayout{
+		Chrome: []string{".d-header", "body > header", "body > footer", "nav", "#topic-footer-buttons", ".topic-map", ".topic-timeline", ".timeline-container", ".post-controls", ".crawler-nav", ".crawler-linkback-list"},
+		Posts:  []string{".topic-post", ".crawler-post"},
+		Quotes: []string{"aside.quote", "aside.quote blockquote"},
+	}
+}
+
+// selectorList returns the selectors joined into a selector list.
+func selectorList(selectors []string) string {
+	return strings.Join(selectors, ",\n")
+}
+
+// PrintStylesheet returns the print-ready stylesheet of the pages of the forum of the engine, which hides the chrome of
+// the forum, prints every post starting on a new sheet, expands the quotes and prints the text in a legible font with
+// the targets of the links spelled out.
+func PrintStylesheet(e Engine) string {
+	layout := commonPrintLayout
+	if layouter, ok := e.(PrintLayouter); ok {
+		layout = layouter.PrintLayout()
+	}
+
+	var stylesheet strings.Builder
+	stylesheet.WriteString(`@media print {
+body {
+	background: #fff !important;
+	color: #000 !important;
+	font: 11pt/1.5 Georgia, "Times New Roman", serif !important;
+}
+
+body * {
+	background: transparent !important;
+	color: #000 !important;
+	box-shadow: none !important;
+	text-shadow: none !important;
+}
+
+img, video, svg {
+	max-width: 100% !important;
+	break-inside: avoid;
+}
+
+pre, code {
+	font-family: "Courier New", monospace !important;
+	white-space: pre-wrap !important;
+}
+
+a[href^="http"]::after {
+	content: " (" attr(href) ")";
+	font-size: 80%;
+	word-break: break-all;
+}
+`)
+	if len(layout.Chrome) > 0 {
+		stylesheet.WriteString("\n" + selectorList(layout.Chrome) + " {\n\tdisplay: none !important;\n}\n")
+	}
+	if len(layout.Posts) > 0 {
+		stylesheet.WriteString("\n" + selectorList(layout.Posts) + " {\n\tbreak-before: page;\n\tpage-break-before: always;\n}\n")
+	}
+	if len(layout.Quotes) > 0 {
+		stylesheet.WriteString("\n" + selectorList(layout.Quotes) + " {\n\tdisplay: block !important;\n\tmax-height: none !important;\n\toverflow: visible !important;\n\tborder-left: 2pt solid #999 !important;\n\tpadding-left: 0.5em !important;\n}\n")
+	}
+	stylesheet.WriteString("}\n")
+
+	return stylesheet.String()
+}
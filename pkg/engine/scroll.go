@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// ScrolledPost is a post loaded from the JSON endpoint of a topic whose posts are loaded as the user scrolls.
+type ScrolledPost struct {
+	Post
+	// HTML is the content of the post as rendered by the forum.
+	HTML string
+}
+
+// Batch is a batch of posts returned by the JSON endpoint of a topic whose posts are loaded as the user scrolls.
+type Batch struct {
+	Posts []*ScrolledPost
+	// NextCursor identifies the batch following this one; it is empty if this one is the last.
+	NextCursor string
+	// PostCount is the number of posts in the topic, or 0 if the endpoint does not tell.
+	PostCount uint
+}
+
+// Scroller is implemented by the engines of forums which load the posts of a topic in batches from a JSON endpoint as
+// the user scrolls instead of serving them in pages. The fetcher walks the endpoint from the start of the topic and
+// materializes archived pages of Topic.PostStep posts each from the results, which PageURL then merely identifies.
+type Scroller interface {
+	Engine
+	// BatchURL returns the URL from which the batch of posts identified by the cursor is loaded; the cursor of the
+	// first batch is empty.
+	BatchURL(topic Topic, cursor string) string
+	// ParseBatch parses the content of a batch of posts, which was loaded from BatchURL with the cursor.
+	ParseBatch(topic Topic, cursor string, content []byte) (*Batch, error)
+	// RenderPage returns the HTML document of the archived page with the given number which consists of the posts, in
+	// such a form that ExtractPosts extracts them back.
+	RenderPage(topic Topic, pageNumber uint, posts []*ScrolledPost) string
+}
+
+// renderCrawlerPage renders the posts in the markup of the view of a Discourse topic served to clients without
+// JavaScript, which Discourse.ExtractPosts understands.
+func renderCrawlerPage(title string, posts []*ScrolledPost) string {
+	var page strings.Builder
+	fmt.Fprintf(&page, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title))
+	for _, post := range posts {
+		fmt.Fprintf(&page, "<div class=\"topic-body crawler-post\" id=\"%s\">\n", html.EscapeString(post.Anchor))
+		fmt.Fprintf(&page, "<div class=\"crawler-post-meta\"><span itemprop=\"author\" itemscope itemtype=\"http://schema.org/Person\"><span itemprop=\"name\">%s</span></span>", html.EscapeString(post.Author))
+		if !post.Time.IsZero() {
+			fmt.Fprintf(&page, " <time itemprop=\"datePublished\" datetime=\"%[1]s\" content=\"%[1]s\">%[2]s</time>", post.Time.Format(time.RFC3339), post.Time.Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintf(&page, "</div>\n<div class=\"post\" itemprop=\"text\">\n%s\n</div>\n</div>\n", post.HTML)
+	}
+	page.WriteString("</body>\n</html>\n")
+	return page.String()
+}
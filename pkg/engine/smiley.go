@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SmileyRecognizer is implemented by the engines of forums which render the emoticons in posts as images, which recur
+// on every page and are stored only once (see fetch.Config.SharedSmileys) and replaced with their textual codes in the
+// text of the posts.
+type SmileyRecognizer interface {
+	Engine
+	// SmileyCode reports whether the element with the given attributes is the image of a smiley and returns the code
+	// which is typed to insert it, e.g. `:)`.
+	SmileyCode(element atom.Atom, attrs []html.Attribute) (code string, ok bool)
+}
+
+// attrValue returns the value of the attribute with the given key.
+func attrValue(attrs []html.Attribute, key string) (val string, ok bool) {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+
+	return
+}
+
+// smileyImageCode returns the code of the smiley whose image has any of the classes, which is taken from the first of
+// the attributes with the given keys which is not empty.
+func smileyImageCode(element atom.Atom, attrs []html.Attribute, classes []string, codeKeys ...string) (code string, ok bool) {
+	if element != atom.Img {
+		return
+	}
+
+	classAttr, _ := attrValue(attrs, "class")
+	isSmiley := false
+	for _, class := range strings.Fields(classAttr) {
+		for _, smileyClass := range classes {
+			if class == smileyClass {
+				isSmiley = true
+			}
+		}
+	}
+	if !isSmiley {
+		return
+	}
+
+	for _, key := range codeKeys {
+		if code, _ = attrValue(attrs, key); strings.TrimSpace(code) != "" {
+			return strings.TrimSpace(code), true
+		}
+	}
+	return
+}
+
+// emoji maps the codes of the default smileys of the supported engines to the Unicode emoji which look like them.
+var emoji = map[string]string{
+	":)": "🙂", ":-)": "🙂", ":smile:": "😄", ":(": "🙁", ":-(": "🙁", ":sad:": "🙁", ";)": "😉", ";-)": "😉",
+	":wink:": "😉", ":D": "😀", ":-D": "😀", ":grin:": "😀", ":grinning:": "😀", ":P": "😛", ":-P": "😛", ":p": "😛",
+	":razz:": "😛", ":stuck_out_tongue:": "😛", ":o": "😮", ":-o": "😮", ":eek:": "😮", ":open_mouth:": "😮",
+	":shock:": "😲", ":astonished:": "😲", ":?": "😕", ":-?": "😕", ":???:": "😕", ":confused:": "😕", "8-)": "😎",
+	":cool:": "😎", ":sunglasses:": "😎", ":x": "😠", ":-x": "😠", ":mad:": "😠", ":angry:": "😠",
+	":|": "😐", ":-|": "😐", ":neutral:": "😐", ":neutral_face:": "😐", ":lol:": "😆", ":laughing:": "😆",
+	":oops:": "😳", ":flushed:": "😳", ":cry:": "😢", ":evil:": "👿", ":imp:": "👿", ":twisted:": "😈",
+	":smiling_imp:": "😈", ":roll:": "🙄", ":rolleyes:": "🙄", ":roll_eyes:": "🙄", ":!:": "❗", ":exclamation:": "❗",
+	":?:": "❓", ":question:": "❓", ":idea:": "💡", ":bulb:": "💡", ":arrow:": "➡️", ":arrow_right:": "➡️",
+	":mrgreen:": "😁", ":geek:": "🤓", ":ugeek:": "🤓", ":nerd_face:": "🤓", ":heart:": "❤️", ":+1:": "👍",
+	":thumbsup:": "👍", ":-1:": "👎", ":thumbsdown:": "👎", ":slight_smile:": "🙂", ":joy:": "😂", ":tada:": "🎉",
+	":thinking:": "🤔", ":frowning:": "😦", ":sob:": "😭", ":smiley:": "😃", ":blush:": "😊",
+}
+
+// emojiForCode returns the Unicode emoji for the code of a smiley, whose letters may be in either case, or false if the
+// code is not a known one.
+func emojiForCode(code string) (found string, ok bool) {
+	found, ok = emoji[code]
+	if !ok {
+		found, ok = emoji[strings.ToLower(code)]
+	}
+	return
+}
+
+// ReplaceSmileyCodes replaces the words of the text which are codes of known smileys, such as the ones which the text of
+// the posts contains in place of their images, with the Unicode emoji which look like them.
+func ReplaceSmileyCodes(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		if found, ok := emojiForCode(word); ok {
+			words[i] = found
+		}
+	}
+
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const xenForoPagePrefix = "page-"
+
+// XenForo is the engine for XenForo forums. The base URL of a topic is the URL of its first page, e.g.
+// `https://forum.example.com/threads/title.123/`; the post step is not used.
+type XenForo struct{}
+
+func (XenForo) Name() string {
+	return "xenforo"
+}
+
+func (XenForo) PageURL(topic Topic, pageNumber uint) string {
+	if pageNumber <= 1 {
+		return topic.URL
+	}
+
+	return fmt.Sprintf("%s%s%d", xenForoThreadURL(topic), xenForoPagePrefix, pageNumber)
+}
+
+func (XenForo) DetectLastPage(topic Topic, doc *html.Node, docURL *url.URL) uint {
+	return maxLinkedPageNumber(doc, docURL, func(linkURL *url.URL) (uint, bool) {
+		return XenForo{}.PageNumber(topic, linkURL)
+	})
+}
+
+// PageNumber recognizes the URLs of the pages of the thread by their page suffix, which the first page lacks.
+func (XenForo) PageNumber(topic Topic, pageURL *url.URL) (uint, bool) {
+	threadURL := xenForoThreadURL(topic)
+	pageURLStr := (&url.URL{Scheme: pageURL.Scheme, Host: pageURL.Host, Path: pageURL.Path}).String()
+	if strings.TrimSuffix(pageURLStr, "/")+"/" == threadURL {
+		return 1, true
+	}
+
+	suffix, ok := strings.CutPrefix(pageURLStr, threadURL+xenForoPagePrefix)
+	if !ok {
+		return 0, false
+	}
+
+	pageNumber, err := strconv.ParseUint(strings.TrimSuffix(suffix, "/"), 10, 0)
+	return uint(pageNumber), err == nil
+}
+
+// LinkedTopicURL recognizes the links to the threads by the `threads` segment of their path, which is followed by the
+// slug and the ID of the thread.
+func (XenForo) LinkedTopicURL(linkURL *url.URL) (*url.URL, bool) {
+	prefix, rest, ok := strings.Cut(linkURL.Path, "/threads/")
+	thread, _, _ := strings.Cut(rest, "/")
+	if !ok || thread == "" {
+		return nil, false
+	}
+
+	return &url.URL{Scheme: linkURL.Scheme, Host: linkURL.Host, Path: prefix + "/threads/" + thread + "/"}, true
+}
+
+func (XenForo) PostNodes(doc *html.Node) []*html.Node {
+	return findAll(doc, func(node *html.Node) bool {
+		return node.DataAtom == atom.Article && hasClass(node, "message")
+	})
+}
+
+func (XenForo) ExtractPosts(doc *html.Node) (posts []*Post) {
+	for _, postNode := range (XenForo{}).PostNodes(doc) {
+		post := &Post{}
+		post.Author, _ = getAttr(postNode, "data-author")
+		if id, ok := getAttr(postNode, "data-content"); ok {
+			post.ID = strings.TrimPrefix(id, "post-")
+			post.Anchor = id
+		}
+
+		if timeNode := find(postNode, withTag(atom.Time)); timeNode != nil {
+			if datetime, ok := getAttr(timeNode, "datetime"); ok {
+				post.Time, _ = time.Parse("2006-01-02T15:04:05-0700", datetime)
+			}
+		}
+
+		// the signature follows the content of the post and has its own bbWrapper
+		contentNode := find(postNode, withClass("bbWrapper"))
+		post.Text = postText(contentNode, XenForo{}, nil)
+		post.Links = links(contentNode, nil)
+		post.Images = images(contentNode, XenForo{}, nil)
+		if contentNode != nil {
+			for _, quoteNode := range findAll(contentNode, withTag(atom.Blockquote)) {
+				if author, ok := getAttr(quoteNode, "data-quote"); ok && author != "" {
+					post.Quoted = append(post.Quoted, author)
+				}
+			}
+		}
+		post.Attachments = len(findAll(postNode, func(node *html.Node) bool {
+			return node.DataAtom == atom.Li && hasClass(node, "attachment")
+		}))
+		post.Signature = postText(find(postNode, withClass("message-signature")), XenForo{}, nil)
+		posts = append(posts, post)
+	}
+	return
+}
+
+func (XenForo) AttachmentURL(linkURL *url.URL) (*url.URL, bool) {
+	if !strings.Contains(linkURL.Path, "/attachments/") {
+		return nil, false
+	}
+
+	return linkURL, true
+}
+
+// xenForoThreadURL returns the URL of the first page of the topic without a page suffix and with a trailing slash.
+func xenForoThreadURL(topic Topic) string {
+	threadURL := topic.URL
+	if index := strings.LastIndex(threadURL, "/"+xenForoPagePrefix); index >= 0 {
+		threadURL = threadURL[:index]
+	}
+	if !strings.HasSuffix(threadURL, "/") {
+		threadURL += "/"
+	}
+	return threadURL
+}
+
+// LoginURL returns the URL of the login page, which is at the root of the installation of XenForo, the directory above
+// the threads.
+func (XenForo) LoginURL(topic Topic) string {
+	topicURL, err := url.Parse(topic.URL)
+	if err != nil {
+		return ""
+	}
+
+	rootPath, _, _ := strings.Cut(topicURL.Path, "/threads/")
+	return topicURL.ResolveReference(&url.URL{Path: rootPath + "/login/"}).String()
+}
+
+// IsLoginPage recognizes the login page by the template with which it is rendered, which XenForo declares on the root
+// element.
+func (XenForo) IsLoginPage(content []byte) bool {
+	return bytes.Contains(content, []byte(`data-template="login"`))
+}
+
+// SmileyCode recognizes the images of smileys, including the emoji which XenForo renders as images, whose code is their
+// short name or else their alternative text.
+func (XenForo) SmileyCode(element atom.Atom, attrs []html.Attribute) (string, bool) {
+	return smileyImageCode(element, attrs, []string{"smilie"}, "data-shortname", "alt")
+}
@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// hostAliasLocation is a location from which assets are served: a host, optionally followed by the path under which
+// they are served.
+type hostAliasLocation struct {
+	host       string
+	pathPrefix string
+}
+
+// contains reports whether the URI is within the location, in which case the path of the URI is returned relative to
+// the one of the location.
+func (location *hostAliasLocation) contains(uri *url.URL) (relativePath string, ok bool) {
+	if !strings.EqualFold(uri.Host, location.host) {
+		return "", false
+	}
+	if location.pathPrefix == "" {
+		return uri.Path, true
+	}
+
+	relativePath, ok = strings.CutPrefix(uri.Path, location.pathPrefix)
+	if !ok || relativePath != "" && !strings.HasPrefix(relativePath, "/") {
+		return "", false
+	}
+	return relativePath, true
+}
+
+func (location *hostAliasLocation) String() string {
+	return location.host + location.pathPrefix
+}
+
+// HostAliases is a group of locations which serve the same assets (e.g. the hosts of a CDN), given as
+// `cdn1.example.com=cdn2.example.com=example.com/static`, where the locations are hosts optionally followed by the
+// path under which the assets are served. The URLs of the assets in any of the locations are replaced with the URLs in
+// the first one, so that every asset is fetched and stored once, under the same name, whichever location it is
+// referenced from.
+type HostAliases struct {
+	locations []*hostAliasLocation
+}
+
+// ParseHostAliases parses a group of at least two locations separated by `=`.
+func ParseHostAliases(value string) (*HostAliases, error) {
+	aliases := &HostAliases{}
+	for _, locationStr := range strings.Split(value, "=") {
+		locationStr = strings.TrimSuffix(strings.TrimSpace(locationStr), "/")
+		host, pathPrefix, _ := strings.Cut(locationStr, "/")
+		if host == "" || strings.Contains(host, "://") {
+			return nil, fmt.Errorf("invalid host alias %q: expected a host optionally followed by a path", locationStr)
+		}
+		if pathPrefix != "" {
+			pathPrefix = "/" + pathPrefix
+		}
+
+		aliases.locations = append(aliases.locations, &hostAliasLocation{host: strings.ToLower(host), pathPrefix: pathPrefix})
+	}
+	if len(aliases.locations) < 2 {
+		return nil, fmt.Errorf("invalid host aliases %q: expected at least two locations separated by =", value)
+	}
+	return aliases, nil
+}
+
+func (aliases *HostAliases) String() string {
+	var locations []string
+	for _, location := range aliases.locations {
+		locations = append(locations, location.String())
+	}
+	return strings.Join(locations, "=")
+}
+
+// canonicalize returns the URI moved from the other locations of the group to the first one, or false if it is in none
+// of them.
+func (aliases *HostAliases) canonicalize(uri *url.URL) (*url.URL, bool) {
+	for _, location := range aliases.locations[1:] {
+		relativePath, ok := location.contains(uri)
+		if !ok {
+			continue
+		}
+
+		canonicalURI := *uri
+		canonicalURI.Host = aliases.locations[0].host
+		canonicalURI.Path = aliases.locations[0].pathPrefix + relativePath
+		canonicalURI.RawPath = ""
+		return &canonicalURI, true
+	}
+	return uri, false
+}
+
+// canonicalizeHost returns the URI moved to the canonical location of the first group of HostAliases in which it is.
+func (f *Fetcher) canonicalizeHost(uri *url.URL) *url.URL {
+	if uri.Opaque != "" {
+		return uri
+	}
+
+	for _, aliases := range f.config.HostAliases {
+		if canonicalURI, ok := aliases.canonicalize(uri); ok {
+			return canonicalURI
+		}
+	}
+	return uri
+}
@@ -0,0 +1,70 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+)
+
+// apiRequestKey is the key of the context value which marks the requests to the JSON endpoints of the forum.
+type apiRequestKey struct{}
+
+// withAPIRequest returns a copy of the context in which the requests are authenticated as requests to the JSON
+// endpoints of the forum with the API key or bearer token of the configuration, if any.
+func withAPIRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, apiRequestKey{}, true)
+}
+
+// authenticateAPIRequest adds the bearer token or, if there is none, the API key of the configuration to a request to
+// a JSON endpoint of the forum, recording the failure to obtain them as an authentication failure. Requests to other
+// hosts are left alone.
+func (f *Fetcher) authenticateAPIRequest(request *http.Request) (err error) {
+	if f.config.BearerToken == "" && f.config.APIKey == "" {
+		return
+	}
+
+	forumURL, err := url.Parse(f.PageURL(MinPageNumber))
+	if err != nil {
+		return nil
+	}
+	if request.URL.Host != asciiHost(forumURL) {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			f.hasAuthFailure.Store(true)
+		}
+	}()
+
+	if f.config.BearerToken != "" {
+		var token string
+		token, err = ResolveSecret(f.config.BearerToken)
+		if err != nil {
+			return
+		}
+
+		request.Header.Set("Authorization", "Bearer "+token)
+		return
+	}
+
+	authenticator, ok := f.engine().(engine.APIAuthenticator)
+	if !ok {
+		err = fmt.Errorf("the %s engine does not support API keys", f.engine().Name())
+		return
+	}
+
+	key, err := ResolveSecret(f.config.APIKey)
+	if err != nil {
+		return
+	}
+
+	authenticator.AuthenticateAPIRequest(request.Header, engine.APIKey{
+		Key:      key,
+		Username: f.config.APIUsername,
+	})
+	return
+}
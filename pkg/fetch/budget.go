@@ -0,0 +1,66 @@
+package fetch
+
+// isBudgetSet reports whether the download budget is limited; if it is, pages are fetched one at a time, so that the
+// budget is checked against the complete usage of the previous pages before a new one is started.
+func (f *Fetcher) isBudgetSet() bool {
+	return f.config.MaxTotalSize > 0 || f.config.MaxResources > 0
+}
+
+// isBudgetExhausted reports whether the download budget of the run has been used up.
+func (f *Fetcher) isBudgetExhausted() bool {
+	bytesDownloaded, resourcesFetched := f.report.Usage()
+
+	return f.config.MaxTotalSize > 0 && bytesDownloaded >= f.config.MaxTotalSize ||
+		f.config.MaxResources > 0 && resourcesFetched >= int(f.config.MaxResources)
+}
+
+// readPendingPages returns the pages which were left pending by the last run due to its budget being exhausted.
+func (f *Fetcher) readPendingPages(state plannedState) (pendingPageNumbers []uint) {
+	pendingPageNumbers, err := state.PendingPages()
+	if err != nil {
+		f.logErrorf("could not read the pages left pending by the last run: %v", err)
+		return nil
+	}
+
+	if len(pendingPageNumbers) > 0 {
+		f.logInfof("Found pages left pending by the last run; will fetch them...")
+	}
+	return
+}
+
+// clearPendingPages forgets the pages left pending by the last run, since the ones left pending by this run will be
+// recorded at its end.
+func (f *Fetcher) clearPendingPages() {
+	err := f.state.SetPendingPages(nil)
+	if err != nil {
+		f.logErrorf("could not clear the pages left pending by the last run: %v", err)
+	}
+}
+
+// recordPendingPages records the pages which were not started because the budget had been exhausted, so that the next
+// run fetches them.
+func (f *Fetcher) recordPendingPages() (err error) {
+	pendingPageNumbers := f.report.PendingPages
+	if len(pendingPageNumbers) == 0 {
+		return
+	}
+
+	if f.config.OnlyRequestedPages {
+		// the pages left pending by the last run have been kept
+		var keptPendingPageNumbers []uint
+		keptPendingPageNumbers, err = f.state.PendingPages()
+		if err != nil {
+			f.logErrorf("could not read the pages left pending by the last run: %v", err)
+			return
+		}
+		pendingPageNumbers = append(keptPendingPageNumbers, pendingPageNumbers...)
+	}
+	err = f.state.SetPendingPages(pendingPageNumbers)
+	if err != nil {
+		f.logErrorf("could not record the pages left pending: %v", err)
+		return
+	}
+
+	f.logWarningf("download budget exhausted; %d pages were left pending and will be fetched by the next run", len(pendingPageNumbers))
+	return
+}
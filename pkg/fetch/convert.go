@@ -0,0 +1,75 @@
+package fetch
+
+import (
+	"io"
+	"mime"
+	"net/url"
+	"slices"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+)
+
+// ImageConverter converts the content of an image of the content type to a format which is supported more widely,
+// returning the content type and the content of the converted image, or an empty content type if it leaves the image
+// as it is.
+type ImageConverter func(contentType string, content []byte) (convertedContentType string, convertedContent []byte, err error)
+
+// WithImageConverter makes the fetcher convert the images of the given content types (e.g. `image/webp`) with the
+// converter before storing them, so that the references to the images point at the converted copies, named after the
+// content type they have been converted to, while the originals are kept next to them under their usual names. The
+// images which cannot be converted are stored as they are.
+func WithImageConverter(convert ImageConverter, contentTypes ...string) Option {
+	return func(f *Fetcher) {
+		f.convertImage = convert
+		f.convertedImageTypes = contentTypes
+	}
+}
+
+// isConvertedImageType reports whether the images of the content type are converted before they are stored.
+func (f *Fetcher) isConvertedImageType(contentType string) bool {
+	if f.convertImage == nil {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && slices.Contains(f.convertedImageTypes, mediaType)
+}
+
+// convertImageResource reads the content of the image and converts it, storing the original, and returns the content
+// and the metadata of the converted image, which are the original ones if it has not been converted.
+func (f *Fetcher) convertImageResource(resourceURL *url.URL, resourceDescription string, contentBody io.Reader, metadata *archive.ResourceMetadata, targetHostDir string) (content []byte, convertedMetadata *archive.ResourceMetadata, err error) {
+	content, err = io.ReadAll(contentBody)
+	if err != nil {
+		f.logErrorf("could not read the content of %s successfully", resourceDescription)
+		return
+	}
+
+	convertedContentType, convertedContent, err := f.convertImage(metadata.ContentType, content)
+	if err != nil {
+		f.logWarningf("could not convert %s, so it is stored as it is: %v", resourceDescription, err)
+		return content, metadata, nil
+	}
+	if convertedContentType == "" {
+		return content, metadata, nil
+	}
+
+	file, name, err := f.openFileForResourceContent(resourceURL, resourceDescription, metadata.ContentType, targetHostDir)
+	if err != nil {
+		return
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		f.checkDiskFull(err)
+		f.logErrorf("could not write the content of %s in file %s successfully", resourceDescription, name)
+		return
+	}
+	f.recordFetchedResource(name, metadata)
+
+	f.logDebugf("Converted %s from %s to %s", resourceDescription, metadata.ContentType, convertedContentType)
+	convertedImageMetadata := *metadata
+	convertedImageMetadata.ContentType = convertedContentType
+	return convertedContent, &convertedImageMetadata, nil
+}
@@ -0,0 +1,112 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"mime"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+)
+
+// Markers of the segments of a JPEG image which are relevant to the stripping of its metadata.
+const (
+	jpegMarkerSOI  = 0xd8 // start of the image
+	jpegMarkerSOS  = 0xda // start of the scan, after which the compressed data follows
+	jpegMarkerAPP1 = 0xe1 // application segment holding the EXIF or XMP metadata
+)
+
+// strippedAPP1Signatures are the signatures at the start of the APP1 segments which are removed from the JPEG images:
+// the EXIF metadata, with its GPS coordinates, camera details and timestamps, and the XMP metadata, which may repeat
+// them.
+var strippedAPP1Signatures = [][]byte{
+	[]byte("Exif\x00\x00"),
+	[]byte("http://ns.adobe.com/xap/1.0/\x00"),
+}
+
+// stripJPEGMetadata returns the JPEG image in the content with its EXIF and XMP segments removed, leaving the image data
+// as it is, or false if the content has none or is not a JPEG image whose segments can be told apart.
+func stripJPEGMetadata(content []byte) (strippedContent []byte, ok bool) {
+	if len(content) < 2 || content[0] != 0xff || content[1] != jpegMarkerSOI {
+		return nil, false
+	}
+
+	var stripped bytes.Buffer
+	stripped.Write(content[:2])
+	offset := 2
+	for {
+		if offset+4 > len(content) || content[offset] != 0xff {
+			return nil, false
+		}
+		marker := content[offset+1]
+		if marker == 0xff {
+			// fill bytes may precede a marker
+			offset++
+			continue
+		}
+		if marker == jpegMarkerSOS {
+			break
+		}
+
+		// the length of a segment includes the two bytes in which it is given, but not the marker
+		segmentEnd := offset + 2 + int(binary.BigEndian.Uint16(content[offset+2:offset+4]))
+		if segmentEnd > len(content) {
+			return nil, false
+		}
+		if marker == jpegMarkerAPP1 && hasAnyPrefix(content[offset+4:segmentEnd], strippedAPP1Signatures) {
+			ok = true
+		} else {
+			stripped.Write(content[offset:segmentEnd])
+		}
+		offset = segmentEnd
+	}
+	if !ok {
+		return nil, false
+	}
+
+	stripped.Write(content[offset:])
+	return stripped.Bytes(), true
+}
+
+func hasAnyPrefix(content []byte, prefixes [][]byte) bool {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(content, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJPEG reports whether the content type is the one of JPEG images.
+func isJPEG(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "image/jpeg" || mediaType == "image/pjpeg")
+}
+
+// stripImageMetadata reads the content of the JPEG image and returns it stripped of its EXIF and XMP metadata, along
+// with the metadata of the resource, in which the stripping is recorded if anything has been removed.
+func (f *Fetcher) stripImageMetadata(resourceDescription string, contentBody io.Reader, metadata *archive.ResourceMetadata) (content []byte, strippedMetadata *archive.ResourceMetadata, err error) {
+	content, err = io.ReadAll(contentBody)
+	if err != nil {
+		f.logErrorf("could not read the content of %s successfully", resourceDescription)
+		return
+	}
+
+	strippedContent, ok := stripJPEGMetadata(content)
+	if !ok {
+		return content, metadata, nil
+	}
+
+	f.logDebugf("Stripped %d bytes of EXIF metadata from %s", len(content)-len(strippedContent), resourceDescription)
+	f.report.recordStrippedImage()
+	strippedImageMetadata := *metadata
+	strippedImageMetadata.StrippedEXIF = true
+	return strippedContent, &strippedImageMetadata, nil
+}
+
+func (report *Report) recordStrippedImage() {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.ImagesStripped++
+}
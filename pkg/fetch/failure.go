@@ -0,0 +1,75 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+)
+
+// The kinds of failures which are recorded along with the failed pages and resources, so that they can be retried
+// selectively.
+const (
+	FailureTimeout     = "timeout"
+	FailureHTTPStatus  = "http-status"
+	FailureNetwork     = "network"
+	FailureAuth        = "auth"
+	FailureChallenge   = "challenge"
+	FailureDiskFull    = "disk-full"
+	FailureInterrupted = "interrupted"
+	FailureOther       = "other"
+)
+
+// FailureKinds are all the kinds of failures.
+var FailureKinds = []string{FailureTimeout, FailureHTTPStatus, FailureNetwork, FailureAuth, FailureChallenge, FailureDiskFull, FailureInterrupted, FailureOther}
+
+// StatusError is returned when the server responds to a request with a status other than OK.
+type StatusError struct {
+	StatusCode int
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("HTTP response received with a non-OK status code (%d %s)", err.StatusCode, http.StatusText(err.StatusCode))
+}
+
+// FailureKind returns the kind of the failure with the error.
+func FailureKind(err error) string {
+	var statusErr *StatusError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		return FailureDiskFull
+	case errors.Is(err, ErrChallenge):
+		return FailureChallenge
+	case errors.Is(err, transport.ErrSessionExpired):
+		return FailureAuth
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusProxyAuthRequired {
+			return FailureAuth
+		}
+		return FailureHTTPStatus
+	case errors.Is(err, ErrPageTimeout), errors.Is(err, context.DeadlineExceeded):
+		return FailureTimeout
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return FailureTimeout
+		}
+		return FailureNetwork
+	case errors.Is(err, ErrInterrupted), errors.Is(err, context.Canceled):
+		return FailureInterrupted
+	}
+	return FailureOther
+}
+
+// recordResourceFailure records the failure to fetch the resource embedded in the page, so that it can be listed and
+// retried later.
+func (f *Fetcher) recordResourceFailure(pageNumber uint, resourceURL string, err error) {
+	recordErr := f.state.RecordResourceFailure(pageNumber, resourceURL, FailureKind(err), err.Error())
+	if recordErr != nil {
+		f.logErrorf("could not record the failed download of %s of page %d: %v", resourceURL, pageNumber, recordErr)
+	}
+}
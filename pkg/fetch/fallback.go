@@ -0,0 +1,97 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// waybackRawURL is the base URL of the snapshots in the Wayback Machine in their original form, without the banner
+// and the rewriting of the links, to which the URL of the resource is appended.
+const waybackRawURL = "https://web.archive.org/web/2id_/"
+
+// imageFallback is a source from which an image which cannot be fetched from its URL is fetched instead.
+type imageFallback struct {
+	source string
+	ctx    context.Context
+	url    string
+}
+
+// imageFallbacks returns the sources from which the image at the URL is fetched in order if it cannot be fetched as
+// usual, given the context in which it was requested: a retry with the Referer sent if it had been omitted and the
+// other way around, its snapshot in the Wayback Machine and the thumbnail of it served by the forum.
+func (f *Fetcher) imageFallbacks(ctx context.Context, imageURL *url.URL, refererURL *url.URL) (fallbacks []*imageFallback) {
+	refererRetryCtx := ctx
+	if f.config.OmitReferer {
+		refererRetryCtx = withReferer(ctx, refererURL)
+	}
+	fallbacks = append(fallbacks, &imageFallback{archive.ResourceSourceRefererRetry, refererRetryCtx, imageURL.String()})
+
+	fallbacks = append(fallbacks, &imageFallback{archive.ResourceSourceWayback, ctx, waybackRawURL + imageURL.String()})
+
+	if thumbnailer, ok := f.engine().(engine.Thumbnailer); ok {
+		if thumbnailURL, ok := thumbnailer.ThumbnailURL(imageURL); ok {
+			thumbnailCtx := ctx
+			if !f.config.OmitReferer {
+				thumbnailCtx = withReferer(ctx, refererURL)
+			}
+			fallbacks = append(fallbacks, &imageFallback{archive.ResourceSourceThumbnail, thumbnailCtx, thumbnailURL.String()})
+		}
+	}
+	return
+}
+
+// getResource fetches the resource at the URL, which is referred to from the context, like Get does. If it is an
+// image which cannot be fetched and ImageFallbacks is enabled, it is fetched from the first of its fallbacks (see
+// imageFallbacks) which serves it instead, which is recorded in its metadata and in the report of the run.
+func (f *Fetcher) getResource(ctx context.Context, resourceURL *url.URL, description string, context *resourceFetcherContext) (contentReader io.ReadCloser, metadata *archive.ResourceMetadata, err error) {
+	requestCtx := ctx
+	if !f.config.OmitReferer {
+		requestCtx = withReferer(ctx, context.baseURL)
+	}
+	contentReader, metadata, err = f.Get(requestCtx, resourceURL.String(), description)
+	if err == nil || isInterruption(err) || !f.config.ImageFallbacks || context.resourceClass != rewrite.ResourceClassImage {
+		return
+	}
+
+	for _, fallback := range f.imageFallbacks(ctx, resourceURL, context.baseURL) {
+		f.logDebugf("Trying to fetch %s from its fallback source %s (%s)", description, fallback.source, fallback.url)
+		fallbackContentReader, fallbackMetadata, fallbackErr := f.Get(fallback.ctx, fallback.url, f.translatef("%s from %s", description, fallback.source))
+		if isInterruption(fallbackErr) {
+			return nil, nil, fallbackErr
+		}
+		if fallbackErr != nil {
+			continue
+		}
+
+		fallbackMetadata.Source = fallback.source
+		fallbackMetadata.SourceURL = fallbackMetadata.URL
+		fallbackMetadata.URL = resourceURL.String()
+		fallbackMetadata.RedirectChain = nil
+		f.logInfof("Fetched %s from its fallback source %s", description, fallback.source)
+		f.report.recordRecoveredResource(resourceURL.String(), fallback.source)
+		return fallbackContentReader, fallbackMetadata, nil
+	}
+	return
+}
+
+// RecoveredResource describes an image which could not be fetched from its URL, but from one of its fallback sources.
+type RecoveredResource struct {
+	URL string `json:"url"`
+	// Source is one of the archive.ResourceSource constants.
+	Source string `json:"source"`
+}
+
+func (report *Report) recordRecoveredResource(resourceURL, source string) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.RecoveredResources = append(report.RecoveredResources, &RecoveredResource{
+		URL:    resourceURL,
+		Source: source,
+	})
+}
@@ -0,0 +1,522 @@
+// Package fetch downloads the pages of a forum topic together with the resources embedded in them into an archive,
+// rewriting the pages and stylesheets so that they refer to the local copies.
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/metrics"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/time/rate"
+)
+
+// MinPageNumber is the number of the first page of a topic.
+const MinPageNumber uint = 1
+
+// tracerName identifies the instrumentation of the fetcher to OpenTelemetry.
+const tracerName = "github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+
+// Config describes the topic to fetch, the archive to fetch it into and how to go about it.
+type Config struct {
+	// URL is the base URL of the pages of the topic, from which the engine derives the URLs of the pages.
+	URL string
+	// PageURLs are the URLs of the pages of the topic, if they are given explicitly instead of being derived from URL.
+	PageURLs []string
+	// PostStep is the number of posts contained on a single page.
+	PostStep uint
+	// Engine is the adapter for the software which serves the forum; nil means engine.Generic, which appends the offset
+	// of the first post of a page to URL.
+	Engine engine.Engine
+	// TargetDir is the directory of the archive.
+	TargetDir string
+
+	// Force enables overwriting of already fetched pages.
+	Force bool
+	// RefreshOlderThan makes already fetched pages whose archived copy is older than it be fetched again.
+	RefreshOlderThan time.Duration
+	// OnlyRequestedPages makes only the requested pages be fetched, all of them again, instead of them along with the
+	// ones which failed or were left pending during the last run; the failures and pending pages of the last run which
+	// have not been requested are kept for the next run.
+	OnlyRequestedPages bool
+	// SaveMetadata enables storing the HTTP response metadata of every page and resource in a sidecar file.
+	SaveMetadata bool
+
+	// Accept and Reject restrict the resources which are fetched: if Accept is not empty, the URL of a resource has to
+	// match one of its patterns, and it must not match any of the patterns in Reject.
+	Accept, Reject []*URLPattern
+	// AllowDomains and BlockDomains restrict the third-party hosts (other than the one of the page) from which
+	// resources are fetched: if AllowDomains is not empty, the resources from hosts which do not match any of its
+	// patterns are left pointing at their original URL, while the references to the resources from hosts which match
+	// any of the patterns in BlockDomains are dropped altogether.
+	AllowDomains, BlockDomains []*DomainPattern
+	// StripParameters are the glob patterns (see path.Match) of the names of the query parameters, such as the ones in
+	// TrackingParameters, which are removed from the URLs of resources before they are fetched and before the
+	// references to them are rewritten, so that the same resource is not stored once for every value of the parameters.
+	// The names are matched case-insensitively.
+	StripParameters []string
+	// HostAliases are the groups of locations which serve the same assets (e.g. the hosts of a CDN); the URLs of the
+	// resources in any of the locations of a group are replaced with the URLs in its first location before they are
+	// fetched and before the references to them are rewritten, so that the same asset is stored once and referenced
+	// consistently whichever location it is linked from.
+	HostAliases []*HostAliases
+	// HardenOffline makes the stored pages never contact the original site when they are opened: a restrictive
+	// Content-Security-Policy (see rewrite.OfflineContentSecurityPolicy) is injected into them and the remaining
+	// external references which the browser would follow on its own are removed (see rewrite.HardenOffline).
+	HardenOffline bool
+	// FileTransferLinks makes the files linked from the pages on FTP and SFTP servers be fetched like attachments, so
+	// the HTTP client must be able to retrieve ftp:// and sftp:// URLs (see transport.FTP).
+	FileTransferLinks bool
+	// LinkPreviews makes a card with the preview of the target of every bare external link (whose text is its URL),
+	// made from the OpenGraph and oEmbed metadata of the target and with its thumbnail stored locally, be put after the
+	// link, so that the archive retains the context of links whose targets later disappear.
+	LinkPreviews bool
+	// SharedSmileys makes the images of the smileys recognized by the engine (see engine.SmileyRecognizer) be stored
+	// only once, in the directory archive.SmileysDirname of the archive, instead of along with every page which uses
+	// them, and recorded with their codes in the map of the smileys of the forum (see archive.LoadSmileys), which
+	// spares the archives of long topics thousands of downloads of the same tiny images.
+	SharedSmileys bool
+	// SkipSiteIcons disables the storing of the icons which browsers and other clients look for at the root of the host
+	// of every page even if the pages do not link to them (favicon.ico, apple-touch-icon.png and browserconfig.xml),
+	// which are otherwise stored once in the directory archive.IconsDirname of the archive and recorded in the map of
+	// original URLs, so that the archive is served and exported with the icons of the site.
+	SkipSiteIcons bool
+	// InjectedStylesheet is the content of a user stylesheet (e.g. a dark theme) which is stored in the file
+	// archive.InjectedStylesheetFilename of the archive and linked from the end of the head of every stored page, after
+	// the stylesheets of the forum; nothing is injected if it is empty.
+	InjectedStylesheet []byte
+	// PrintStylesheet is the content of a print-ready stylesheet (see engine.PrintStylesheet) which is stored in the
+	// file archive.PrintStylesheetFilename of the archive and linked from every stored page for printing alone, after
+	// InjectedStylesheet; nothing is injected if it is empty.
+	PrintStylesheet []byte
+	// OmitReferer disables sending the URL of the page or stylesheet which refers to a resource as the Referer of the
+	// request for it, which is otherwise sent in order to satisfy the hotlink protection of image hosts.
+	OmitReferer bool
+	// ImageFallbacks makes the images which cannot be fetched from their URL be fetched by retrying with the Referer
+	// sent if OmitReferer is set and the other way around, then from their snapshot in the Wayback Machine and finally
+	// as the thumbnail served by the forum, if the engine implements engine.Thumbnailer; the source which was used is
+	// recorded in the metadata of the image and in the report of the run.
+	ImageFallbacks bool
+	// StripEXIF makes the EXIF and XMP metadata, such as the location at which a photo was taken, be removed from the
+	// JPEG images before they are stored, without encoding them again; the stripping is recorded in the metadata of
+	// every image affected and counted in the report of the run. The orientation recorded in the metadata is lost too.
+	StripEXIF bool
+	// TransliterateFilenames makes the names of the local copies of the pages and resources consist only of ASCII
+	// characters (see rewrite.Filenames).
+	TransliterateFilenames bool
+	// NameByTitle makes the stored pages be named after the slug of the title of the topic and their number (see
+	// rewrite.TitledPageFilename) instead of the last segment of their URL, so that the archive can be navigated in a
+	// file manager; the pages whose title cannot be told within their first titleLookahead bytes keep their usual
+	// names.
+	NameByTitle bool
+	// TrackPosts makes the posts extracted from the fetched pages be compared by ID with the ones seen during the
+	// earlier runs, so that their edits (with both versions kept) and deletions are recorded in the change log of the
+	// archive (see archive.State.PostChanges); only the posts which the engine gives an ID are tracked.
+	TrackPosts bool
+	// FirstPostOnly restricts the run to the first page of the topic, from which the posts after the opening one are
+	// removed before it is stored, so that only the opening post is archived along with its images and attachments,
+	// e.g. of the topics of announcement or tutorial boards; it requires an engine which implements engine.PostFinder,
+	// otherwise the whole first page is kept.
+	FirstPostOnly bool
+	// ReferencedTopics is the number of the other topics of the forum linked from the pages whose first page is archived
+	// along with the pages, one hop away (the topics referenced by them are not followed), with the links to them
+	// rewritten to the local copies; it requires an engine which implements engine.TopicLocator. The resources of the
+	// copies are not localized but loaded from the forum, unless HardenOffline is set. 0 disables the archiving of the referenced topics.
+	ReferencedTopics uint
+	// SkipImages, SkipFonts and SkipMedia disable the localization of the respective classes of resources, while
+	// PageRequisitesOnly restricts it to the resources needed to render the pages (stylesheets, scripts, images and
+	// fonts), excluding e.g. media, embedded objects and frames. While any of them is set, the content type of the
+	// resources whose class cannot be told from the link to them is learned through a HEAD request (or a GET request
+	// for their first byte) before their content is downloaded, as is the size of every resource if MaxResourceSize is
+	// set.
+	SkipImages, SkipFonts, SkipMedia, PageRequisitesOnly bool
+
+	// MaxTotalSize and MaxResources limit the number of bytes downloaded and the number of resources fetched; 0 means
+	// unlimited. Once either is exhausted, no more pages are started and the remaining ones are left pending.
+	MaxTotalSize int64
+	MaxResources uint
+	// MaxResourceSize is the size in bytes of the largest resource which is fetched; the references to the larger ones
+	// are left pointing at their original URL. 0 means unlimited.
+	MaxResourceSize int64
+	// PageTimeout is the longest time an attempt to fetch a page, along with its resources, may take; once it has run
+	// out, the requests made on behalf of the page give up with ErrPageTimeout and the page is recorded as failed, so
+	// that a single pathological page (e.g. with a huge attachment or on a tarpit host) cannot stall the run. 0 means
+	// unlimited. A deadline for the whole run is set through the context given to Start.
+	PageTimeout time.Duration
+	// ActiveHours is the daily window of the local time within which the pages are started, so that the forum is only
+	// burdened at quiet hours; the pages being fetched when the window closes are finished, and the others wait for it
+	// to open again. The zero window means any time.
+	ActiveHours TimeWindow
+	// Order is the order in which the pages are started, which decides which of them are fetched if the run is cut
+	// short, e.g. by an interruption or a budget.
+	Order PageOrder
+	// PageConcurrency is the maximum number of pages fetched at once; the pages wait for a free worker in the order in
+	// which they have been scheduled. 0 means DefaultPageConcurrency, which trades the throughput on topics with many
+	// pages for an order which holds.
+	PageConcurrency int
+
+	// Credentials is a reference to the credentials used for HTTP basic authentication with the host of the forum, of
+	// the form `env:NAME` or `keyring:NAME`, where the environment variable or the entry of the keyring of the operating
+	// system `NAME` contains `user:password`.
+	Credentials string
+	// APIKey is a reference of the same form to the API key (e.g. of Discourse) sent along with the requests to the JSON
+	// endpoints of the forum on behalf of the user APIUsername, if the engine supports API keys, while BearerToken is a
+	// reference to an OAuth2 access token sent instead in the Authorization header. Failing to resolve either counts as
+	// an authentication failure.
+	APIKey      string
+	APIUsername string
+	BearerToken string
+
+	// Login is a reference of the same form to the user name and password with which the fetcher logs in to the forum
+	// through its login form, if the engine supports it (see engine.Loginer), before requesting anything from its host.
+	// Whenever the session expires during the run, the fetcher logs in again and retries the affected requests.
+	Login string
+
+	// ClearanceCookies are the cookies obtained by passing the challenge of an anti-bot service (such as the
+	// cf_clearance cookie of Cloudflare) in a browser, which are presented to the host of the forum along with
+	// UserAgent, the User-Agent of that browser; UserAgent is sent to the other hosts too.
+	ClearanceCookies []*http.Cookie
+	UserAgent        string
+
+	// PageRanges are the specifications of the ranges of pages fetched by Topic (see ParsePageRanges).
+	PageRanges []string
+}
+
+// Option customizes how a fetcher goes about its work.
+type Option func(*Fetcher)
+
+// WithClient makes the fetcher issue its requests through the given HTTP client instead of http.DefaultClient. The
+// middleware of the fetcher is wrapped around the transport of the client.
+func WithClient(client *http.Client) Option {
+	return func(f *Fetcher) {
+		f.client = client
+	}
+}
+
+// WithDial makes the fetcher make its connections through the dial function instead of over the network, e.g. to a
+// Unix socket (see transport.UnixSocketDial). The function replaces the one of the transport of the client given by
+// WithClient if that is an *http.Transport, or else of a copy of http.DefaultTransport; no proxy is used.
+func WithDial(dial transport.DialFunc) Option {
+	return func(f *Fetcher) {
+		f.dial = dial
+	}
+}
+
+// clientWithDial returns a copy of the client whose transport makes its connections through the dial function.
+func clientWithDial(client *http.Client, dial transport.DialFunc) *http.Client {
+	baseTransport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		baseTransport = http.DefaultTransport.(*http.Transport)
+	}
+	dialTransport := baseTransport.Clone()
+	dialTransport.DialContext = dial
+	dialTransport.DialTLSContext = nil
+	dialTransport.Proxy = nil
+
+	dialClient := *client
+	dialClient.Transport = dialTransport
+	return &dialClient
+}
+
+// WithRateLimit limits the requests issued by the fetcher to the given number per second; 0 means unlimited, which is
+// the default.
+func WithRateLimit(requestsPerSecond float64) Option {
+	return func(f *Fetcher) {
+		if requestsPerSecond > 0 {
+			f.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+		} else {
+			f.limiter = rate.NewLimiter(rate.Inf, 0)
+		}
+	}
+}
+
+// WithMiddleware wraps the given middleware around the transport of the fetcher in addition to the middleware given
+// before. The first given middleware is the outermost one; all of it is wrapped inside the logging done by the fetcher
+// and around the authentication and rate limiting.
+func WithMiddleware(middleware ...transport.Middleware) Option {
+	return func(f *Fetcher) {
+		f.middleware = append(f.middleware, middleware...)
+	}
+}
+
+// WithStorage makes the fetcher write the content of the pages and resources to the given storage instead of the
+// target directory. The bookkeeping of the archive (the lists of failed and pending pages and the map of original URLs)
+// is still kept in the target directory.
+func WithStorage(storage Storage) Option {
+	return func(f *Fetcher) {
+		f.storage = storage
+	}
+}
+
+// WithRewriter makes the fetcher consult the given rewriter before rewriting every reference to a resource.
+func WithRewriter(rewriter rewrite.Rewriter) Option {
+	return func(f *Fetcher) {
+		f.rewriter = rewriter
+	}
+}
+
+// WithLogger makes the fetcher log the messages about the progress of the fetch to the given logger instead of
+// discarding them, so that an embedding application can route them to its own logging stack through the handler of
+// the logger. The messages are discarded again if the logger is nil.
+func WithLogger(logger *slog.Logger) Option {
+	return func(f *Fetcher) {
+		if logger == nil {
+			logger = discardLogger()
+		}
+		f.logger = logger
+	}
+}
+
+// WithTranslation makes the fetcher translate the format strings of its log messages and the descriptions of the pages
+// and resources in them with the given function (e.g. through the message catalog of an application) before
+// formatting them, so that the messages are logged in the language of the user. The messages are logged in English if
+// the function is nil.
+func WithTranslation(translate func(message string) string) Option {
+	return func(f *Fetcher) {
+		f.translate = translate
+	}
+}
+
+// WithMetrics makes the fetcher report the counts of the pages, resources, requests and bytes it fetches, its failures
+// and the durations of the pages and requests to the given recorder (see package metrics) instead of discarding them,
+// which they are again if the recorder is nil.
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(f *Fetcher) {
+		if recorder == nil {
+			recorder = metrics.Nop{}
+		}
+		f.metrics = recorder
+	}
+}
+
+// WithTracerProvider makes the fetcher record OpenTelemetry spans for the pages, the resources embedded in them, the
+// rewriting of stylesheets, the writing of files and every HTTP request through the given tracer provider instead of
+// not recording them.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(f *Fetcher) {
+		f.tracer = tracerProvider.Tracer(tracerName)
+		f.tracing = transport.Tracing(tracerProvider)
+	}
+}
+
+// Fetcher fetches the pages of a forum topic into an archive.
+type Fetcher struct {
+	config     Config
+	client     *http.Client
+	dial       transport.DialFunc
+	middleware []transport.Middleware
+	logger     *slog.Logger
+	translate  func(message string) string
+	metrics    metrics.Recorder
+	limiter    *rate.Limiter
+	storage    Storage
+	rewriter   rewrite.Rewriter
+	observers  []Observer
+	tracer     trace.Tracer
+	tracing    transport.Middleware
+	report     *Report
+
+	postProcess         PostProcessor
+	postProcessingSlots chan struct{}
+	postProcessors      sync.WaitGroup
+
+	downloadVideo VideoDownloader
+
+	convertImage        ImageConverter
+	convertedImageTypes []string
+
+	makeThumbnail ThumbnailMaker
+
+	state     *archive.State
+	urlMap    *archive.URLMap
+	smileys   smileyState
+	topicSlug topicSlugState
+
+	runContext        context.Context
+	workers           sync.WaitGroup
+	pageQueue         pageQueueState
+	budgetedPageMutex sync.Mutex
+	lastPageNumber    uint
+	scroll            scrollState
+	trackedPages      trackedPageState
+	referencedTopics  referencedTopicState
+	siteIcons         siteIconState
+
+	hasAuthFailure      atomic.Bool
+	hasDiskFullFailure  atomic.Bool
+	hasChallengeFailure atomic.Bool
+	// activeHoursPause is the time until which the pages wait for the active hours, or nil if they do not.
+	activeHoursPause atomic.Pointer[time.Time]
+}
+
+// discardLogger returns a logger which discards all the messages.
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// New returns a fetcher with the given configuration, customized by the options.
+func New(config Config, opts ...Option) *Fetcher {
+	fetcher := &Fetcher{
+		config:  config,
+		client:  http.DefaultClient,
+		logger:  discardLogger(),
+		metrics: metrics.Nop{},
+		limiter: rate.NewLimiter(rate.Inf, 0),
+		storage: DirStorage(config.TargetDir),
+		tracer:  noop.NewTracerProvider().Tracer(tracerName),
+		report:  newReport(),
+	}
+	for _, opt := range opts {
+		opt(fetcher)
+	}
+
+	if fetcher.dial != nil {
+		fetcher.client = clientWithDial(fetcher.client, fetcher.dial)
+	}
+	fetcher.report.metrics = fetcher.metrics
+
+	middleware := []transport.Middleware{transport.Logging(fetcher.logger), transport.Metrics(fetcher.metrics)}
+	middleware = append(middleware, fetcher.middleware...)
+	if config.Login != "" {
+		if session := fetcher.session(); session != nil {
+			middleware = append(middleware, session)
+		}
+	}
+	if config.Credentials != "" {
+		forumURL, err := url.Parse(fetcher.PageURL(MinPageNumber))
+		if err == nil {
+			middleware = append(middleware, transport.BasicAuth(asciiHost(forumURL), fetcher.resolveCredentials))
+		}
+	}
+	if len(config.ClearanceCookies) > 0 || config.UserAgent != "" {
+		forumURL, err := url.Parse(fetcher.PageURL(MinPageNumber))
+		if err == nil {
+			middleware = append(middleware, transport.Clearance(ASCIIHostname(forumURL.Hostname()), config.ClearanceCookies, config.UserAgent))
+		}
+	}
+	middleware = append(middleware, transport.RateLimit(fetcher.limiter))
+	if fetcher.tracing != nil {
+		middleware = append(middleware, fetcher.tracing)
+	}
+
+	client := *fetcher.client
+	client.Transport = transport.Chain(client.Transport, middleware...)
+	fetcher.client = &client
+
+	return fetcher
+}
+
+// Topic fetches the pages in the page ranges of the configuration, as well as the ones which failed or were left
+// pending during the last run into the target directory, and returns the report of the run. Pages which could not be
+// fetched are not treated as an error, but recorded in the report and in the list of failed downloads. Once ctx is
+// cancelled, no more requests are issued, the pages in flight give up and the error of ctx is returned.
+func Topic(ctx context.Context, config Config, opts ...Option) (report *Report, err error) {
+	fetcher := New(config, opts...)
+
+	pageNumbers, err := fetcher.ParsePageRanges(ctx, config.PageRanges)
+	if err != nil {
+		return
+	}
+
+	err = fetcher.FetchPages(ctx, pageNumbers)
+	report = fetcher.Report()
+	if err == nil {
+		err = ctx.Err()
+	}
+	return
+}
+
+// PageURL returns the URL of the page with the given number, or an empty string if the topic has no such page.
+func (f *Fetcher) PageURL(pageNumber uint) string {
+	if f.config.PageURLs != nil {
+		if pageNumber < MinPageNumber || pageNumber > uint(len(f.config.PageURLs)) {
+			return ""
+		}
+
+		return f.config.PageURLs[pageNumber-MinPageNumber]
+	}
+
+	return f.engine().PageURL(f.topic(), pageNumber)
+}
+
+func (f *Fetcher) engine() engine.Engine {
+	if f.config.Engine == nil {
+		return engine.Generic{}
+	}
+
+	return f.config.Engine
+}
+
+func (f *Fetcher) topic() engine.Topic {
+	return engine.Topic{
+		URL:      f.config.URL,
+		PostStep: f.config.PostStep,
+	}
+}
+
+// filenames returns how the paths of the URLs of the pages and resources are mapped to the names of their local copies.
+func (f *Fetcher) filenames() rewrite.Filenames {
+	return rewrite.Filenames{Transliterate: f.config.TransliterateFilenames}
+}
+
+// Report returns the report of the run, which is updated while the pages are being fetched.
+func (f *Fetcher) Report() *Report {
+	return f.report
+}
+
+// HasAuthFailure reports whether the forum rejected the credentials or they could not be obtained.
+func (f *Fetcher) HasAuthFailure() bool {
+	return f.hasAuthFailure.Load()
+}
+
+// HasChallengeFailure reports whether an anti-bot service challenged the fetcher instead of serving a page or resource.
+func (f *Fetcher) HasChallengeFailure() bool {
+	return f.hasChallengeFailure.Load()
+}
+
+// HasDiskFullFailure reports whether writing to the archive failed due to there being no space left on the device.
+func (f *Fetcher) HasDiskFullFailure() bool {
+	return f.hasDiskFullFailure.Load()
+}
+
+// translatef formats the translation of the format string given by WithTranslation, or the format string itself if
+// there is none.
+func (f *Fetcher) translatef(format string, args ...interface{}) string {
+	if f.translate != nil {
+		format = f.translate(format)
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func (f *Fetcher) logErrorf(format string, args ...interface{}) {
+	f.logger.Error(f.translatef(format, args...))
+}
+
+func (f *Fetcher) logWarningf(format string, args ...interface{}) {
+	f.logger.Warn(f.translatef(format, args...))
+}
+
+func (f *Fetcher) logInfof(format string, args ...interface{}) {
+	f.logger.Info(f.translatef(format, args...))
+}
+
+func (f *Fetcher) logDebugf(format string, args ...interface{}) {
+	f.logger.Debug(f.translatef(format, args...))
+}
+
+// logDuration returns an attribute for the time elapsed since the given moment, rounded for readability.
+func logDuration(since time.Time) slog.Attr {
+	return slog.Duration("duration", time.Since(since).Round(time.Millisecond))
+}
@@ -0,0 +1,215 @@
+package fetch
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// URLPatternRegexPrefix marks a resource URL pattern as a regular expression rather than a glob.
+const URLPatternRegexPrefix = "re:"
+
+// URLPattern is matched against the URLs of resources. It is either a glob, where `*` matches any sequence of
+// characters (including `/`), or a regular expression prefixed with `re:`. A glob without `/` is matched against the
+// last element of the path of the URL, so that e.g. `*.mp4` matches every MP4 file; any other pattern is matched
+// against the whole URL.
+type URLPattern struct {
+	source          string
+	regexp          *regexp.Regexp
+	isFilenameMatch bool
+}
+
+// String returns the source of the pattern.
+func (pattern *URLPattern) String() string {
+	return pattern.source
+}
+
+// ParseURLPattern parses a resource URL pattern.
+func ParseURLPattern(source string) (pattern *URLPattern, err error) {
+	pattern = &URLPattern{source: source}
+
+	if expression, isRegex := strings.CutPrefix(source, URLPatternRegexPrefix); isRegex {
+		pattern.regexp, err = regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", expression, err)
+		}
+		return
+	}
+
+	pattern.isFilenameMatch = !strings.Contains(source, "/")
+	pattern.regexp, err = regexp.Compile(globToRegexp(source))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %v", source, err)
+	}
+	return
+}
+
+// globToRegexp translates a glob into an anchored regular expression in which `*` matches any sequence of characters,
+// `?` matches any single character and bracket expressions are kept as character classes.
+func globToRegexp(glob string) string {
+	var expression strings.Builder
+	expression.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			expression.WriteString(".*")
+		case '?':
+			expression.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expression.WriteString("$")
+
+	return expression.String()
+}
+
+// Matches reports whether the URL matches the pattern.
+func (pattern *URLPattern) Matches(urlStr string) bool {
+	if pattern.isFilenameMatch {
+		pathStr := urlStr
+		if end := strings.IndexAny(pathStr, "?#"); end >= 0 {
+			pathStr = pathStr[:end]
+		}
+
+		return pattern.regexp.MatchString(path.Base(pathStr))
+	}
+
+	return pattern.regexp.MatchString(urlStr)
+}
+
+func matchAny(patterns []*URLPattern, urlStr string) bool {
+	for _, pattern := range patterns {
+		if pattern.Matches(urlStr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isResourceURLAccepted reports whether the resource at the given URL should be fetched according to the filters.
+func (f *Fetcher) isResourceURLAccepted(urlStr string) bool {
+	if len(f.config.Accept) > 0 && !matchAny(f.config.Accept, urlStr) {
+		return false
+	}
+
+	return !matchAny(f.config.Reject, urlStr)
+}
+
+// DomainPattern is matched against the hostnames of resources. It is a domain which may contain the wildcards `*`,
+// matching any sequence of characters (including dots), and `?`, matching any single character; it matches the
+// subdomains of the domain as well, and the matching is case-insensitive.
+type DomainPattern struct {
+	source string
+	regexp *regexp.Regexp
+}
+
+// String returns the source of the pattern.
+func (pattern *DomainPattern) String() string {
+	return pattern.source
+}
+
+// ParseDomainPattern parses a resource domain pattern.
+func ParseDomainPattern(source string) (pattern *DomainPattern, err error) {
+	domain := strings.Trim(strings.ToLower(source), ".")
+	if domain == "" || strings.ContainsAny(domain, "/:") {
+		return nil, fmt.Errorf("invalid domain pattern %q", source)
+	}
+
+	// the labels of internationalized domain names are matched in their ASCII form
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if !strings.ContainsAny(label, "*?") {
+			labels[i] = ASCIIHostname(label)
+		}
+	}
+	domain = strings.Join(labels, ".")
+
+	pattern = &DomainPattern{source: source}
+	pattern.regexp, err = regexp.Compile(`^(.*\.)?` + strings.TrimPrefix(globToRegexp(domain), "^"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain pattern %q: %v", source, err)
+	}
+	return
+}
+
+// Matches reports whether the hostname matches the pattern.
+func (pattern *DomainPattern) Matches(hostname string) bool {
+	return pattern.regexp.MatchString(ASCIIHostname(hostname))
+}
+
+func matchAnyDomain(patterns []*DomainPattern, hostname string) bool {
+	for _, pattern := range patterns {
+		if pattern.Matches(hostname) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// droppedResourceReference replaces the references to resources from blocked domains.
+const droppedResourceReference = "about:blank"
+
+// isThirdPartyHostname reports whether the resource at the URL is served by another host than the page from which it
+// is referred to.
+func isThirdPartyHostname(resourceURL *url.URL, pageHostname string) bool {
+	hostname := resourceURL.Hostname()
+	return hostname != "" && !strings.EqualFold(ASCIIHostname(hostname), pageHostname)
+}
+
+// TrackingParameters are the patterns of the names of the query parameters which are commonly added to URLs in order
+// to track the visitors rather than to select the resource.
+var TrackingParameters = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "igshid"}
+
+// isStrippedParameter reports whether the query parameter with the name is removed from the URLs of resources.
+func (f *Fetcher) isStrippedParameter(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range f.config.StripParameters {
+		if isMatch, _ := path.Match(strings.ToLower(pattern), name); isMatch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stripParameters returns the URI without the query parameters whose names match any of the patterns in
+// StripParameters, keeping the order and the encoding of the rest of them.
+func (f *Fetcher) stripParameters(uri *url.URL) *url.URL {
+	if len(f.config.StripParameters) == 0 || uri.RawQuery == "" {
+		return uri
+	}
+
+	var keptParameters []string
+	for _, parameter := range strings.Split(uri.RawQuery, "&") {
+		name, _, _ := strings.Cut(parameter, "=")
+		if unescapedName, err := url.QueryUnescape(name); err == nil {
+			name = unescapedName
+		}
+		if !f.isStrippedParameter(name) {
+			keptParameters = append(keptParameters, parameter)
+		}
+	}
+
+	strippedURI := *uri
+	strippedURI.RawQuery = strings.Join(keptParameters, "&")
+	strippedURI.ForceQuery = false
+	return &strippedURI
+}
@@ -0,0 +1,30 @@
+package fetch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"golang.org/x/net/html"
+)
+
+// firstPostContent returns the content of the page with the posts after the opening one removed (see
+// Config.FirstPostOnly), or with all of them kept if the engine cannot tell them apart.
+func (f *Fetcher) firstPostContent(pageNumber uint, content io.Reader) (io.Reader, error) {
+	doc, err := html.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not read page %d: %w", pageNumber, err)
+	}
+
+	if !engine.KeepFirstPost(f.engine(), doc) {
+		f.logWarningf("could not find the posts on page %d with the %s engine; keeping the whole page", pageNumber, f.engine().Name())
+	}
+
+	var trimmedContent bytes.Buffer
+	err = html.Render(&trimmedContent, doc)
+	if err != nil {
+		return nil, fmt.Errorf("could not render page %d: %w", pageNumber, err)
+	}
+	return &trimmedContent, nil
+}
@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxFrameDepth is the number of levels of frame documents whose resources are localized, beyond which the frame
+// documents are stored as they are, so that frames which refer to each other are not followed forever.
+const maxFrameDepth = 4
+
+// isFallbackContent reports whether the text following the start tag is the content of a `noscript` or `noframes`
+// element, which the tokenizer does not split into tags, so that the resources referenced by it have to be found by
+// tokenizing it on its own.
+func isFallbackContent(prevToken *html.Token) bool {
+	return prevToken != nil && prevToken.Type == html.StartTagToken && (prevToken.DataAtom == atom.Noscript || prevToken.DataAtom == atom.Noframes)
+}
+
+// rewriteFallbackContent returns the content of a `noscript` or `noframes` element with the resources referenced by it
+// localized.
+func (f *Fetcher) rewriteFallbackContent(content string, context *resourceFetcherContext) string {
+	var rewrittenContent strings.Builder
+	// writing to the builder cannot fail, and what could not be tokenized is kept as it is
+	f.rewriteHTML(&rewrittenContent, strings.NewReader(content), context, false)
+	return rewrittenContent.String()
+}
+
+// rewriteHTML writes the HTML content, which is a frame document (if isDocument is set) or the fallback content of a
+// page, with the resources embedded in it localized and the links in it resolved against the base URL of the context.
+// Unlike the pages, a frame document only gets the Content-Security-Policy of HardenOffline injected into it, while
+// the other features of the fetcher which rewrite the pages (e.g. the link previews) are not applied to it.
+func (f *Fetcher) rewriteHTML(w io.Writer, content io.Reader, context *resourceFetcherContext, isDocument bool) (err error) {
+	tokenizer := html.NewTokenizer(content)
+	tokenizer.AllowCDATA(true)
+
+	var prevToken *html.Token
+	isPolicyInjected := !isDocument || !f.config.HardenOffline
+	for tokenizer.Next() != html.ErrorToken {
+		token := tokenizer.Token()
+		f.rewriteTokenResources(&token, prevToken, context)
+		if f.config.HardenOffline {
+			rewrite.HardenOffline(&token)
+		}
+
+		tokenHTML := rewrite.TokenString(&token, prevToken)
+		if !isPolicyInjected && (token.Type == html.StartTagToken || token.Type == html.SelfClosingTagToken) {
+			// the policy goes at the start of the head, which is implied by it if the document lacks one
+			if token.DataAtom == atom.Head {
+				tokenHTML += rewrite.OfflineContentSecurityPolicyTag()
+				isPolicyInjected = true
+			} else if token.DataAtom != atom.Html {
+				tokenHTML = rewrite.OfflineContentSecurityPolicyTag() + tokenHTML
+				isPolicyInjected = true
+			}
+		}
+
+		_, err = io.WriteString(w, tokenHTML)
+		if err != nil {
+			return
+		}
+		prevToken = &token
+	}
+	if err = tokenizer.Err(); err != io.EOF {
+		return
+	}
+	return nil
+}
+
+// rewriteTokenResources localizes the resources referenced by the token of a frame document or of the fallback content
+// of a page and resolves the links to the other pages against the base URL of the context.
+func (f *Fetcher) rewriteTokenResources(token *html.Token, prevToken *html.Token, context *resourceFetcherContext) {
+	if token.Type == html.TextToken && prevToken != nil && prevToken.Type == html.StartTagToken && prevToken.DataAtom == atom.Style {
+		token.Data = string(f.fetchLinkedResourcesInCSS([]byte(token.Data), context))
+		return
+	}
+	if token.Type == html.TextToken && isFallbackContent(prevToken) {
+		token.Data = f.rewriteFallbackContent(token.Data, context)
+		return
+	}
+	if token.Type != html.StartTagToken && token.Type != html.SelfClosingTagToken {
+		return
+	}
+
+	linkAttrs := rewrite.GetLinkAttrs(token)
+	if linkAttrs.HasStyle {
+		token.Attr[linkAttrs.StyleIndex].Val = string(f.fetchLinkedResourcesInCSS([]byte(linkAttrs.Style), context))
+	}
+	if !linkAttrs.HasURI {
+		return
+	}
+
+	linkURI, err := url.Parse(linkAttrs.URI)
+	if err != nil {
+		f.logErrorf("could not parse URL of resource %s", linkAttrs.URI)
+		return
+	}
+
+	if !linkAttrs.IsEmbeddedResource(token) {
+		token.Attr[linkAttrs.URIAttrIndex].Val = context.baseURL.ResolveReference(linkURI).String()
+		return
+	}
+
+	resourceContext := *context
+	resourceContext.resourceClass = rewrite.ElementResourceClass(token, &linkAttrs)
+	resourceContext.replaceResourceReference = func(reference string) {
+		token.Attr[linkAttrs.URIAttrIndex].Val = reference
+	}
+	resourceContext.isFrameDocument = token.DataAtom == atom.Frame
+	f.fetchResourceFromLinkIfNecessary(linkURI, &resourceContext)
+}
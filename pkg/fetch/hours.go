@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily window of the local time, given by the offsets of its start and end from midnight. A window
+// whose end is before its start spans midnight, e.g. 22:00-06:00. The zero window is not a restriction: it contains
+// every time.
+type TimeWindow struct {
+	Start, End time.Duration
+}
+
+// parseTimeOfDay parses a time of the day of the form HH:MM, where 24:00 stands for the end of the day.
+func parseTimeOfDay(value string) (offset time.Duration, err error) {
+	var hours, minutes int
+	_, err = fmt.Sscanf(value, "%d:%d", &hours, &minutes)
+	if err != nil || hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time of the day %q: expected HH:MM", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// ParseTimeWindow parses a time window of the form HH:MM-HH:MM, e.g. 01:00-06:00.
+func ParseTimeWindow(value string) (window TimeWindow, err error) {
+	startStr, endStr, ok := strings.Cut(value, "-")
+	if !ok {
+		return window, fmt.Errorf("invalid time window %q: expected HH:MM-HH:MM", value)
+	}
+	window.Start, err = parseTimeOfDay(strings.TrimSpace(startStr))
+	if err != nil {
+		return
+	}
+	window.End, err = parseTimeOfDay(strings.TrimSpace(endStr))
+	if err != nil {
+		return
+	}
+	if window.Start%(24*time.Hour) == window.End%(24*time.Hour) {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: it starts when it ends", value)
+	}
+	return
+}
+
+// IsZero reports whether the window is the zero one, which is not a restriction.
+func (window *TimeWindow) IsZero() bool {
+	return window.Start == window.End
+}
+
+func formatTimeOfDay(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
+
+func (window *TimeWindow) String() string {
+	if window.IsZero() {
+		return ""
+	}
+	return formatTimeOfDay(window.Start) + "-" + formatTimeOfDay(window.End)
+}
+
+// Set parses the window, so that it can be given as a flag.
+func (window *TimeWindow) Set(value string) (err error) {
+	*window, err = ParseTimeWindow(value)
+	return
+}
+
+// contains reports whether the offset from midnight is within the window.
+func (window *TimeWindow) contains(offset time.Duration) bool {
+	if window.Start < window.End {
+		return offset >= window.Start && offset < window.End
+	}
+	return offset >= window.Start || offset < window.End
+}
+
+// NextOpening returns the time from which on the window is open, which is the time itself if it is within the window.
+func (window *TimeWindow) NextOpening(t time.Time) time.Time {
+	if window.IsZero() {
+		return t
+	}
+
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	if window.contains(t.Sub(midnight)) {
+		return t
+	}
+
+	opening := midnight.Add(window.Start)
+	if opening.Before(t) {
+		opening = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location()).Add(window.Start)
+	}
+	return opening
+}
+
+// waitForActiveHours waits until the active hours of the fetcher have begun, if they have been restricted, and returns
+// false if the context has been done in the meantime. The first page to wait for a given opening logs the pause.
+func (f *Fetcher) waitForActiveHours(ctx context.Context) bool {
+	for {
+		now := time.Now()
+		opening := f.config.ActiveHours.NextOpening(now)
+		if !opening.After(now) {
+			f.activeHoursPause.Store(nil)
+			return true
+		}
+
+		if previousOpening := f.activeHoursPause.Swap(&opening); previousOpening == nil || !previousOpening.Equal(opening) {
+			f.logInfof("Outside the active hours %s; pausing until %s", &f.config.ActiveHours, opening.Format("2006-01-02 15:04"))
+		}
+
+		timer := time.NewTimer(time.Until(opening))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// IsPaused reports whether the fetcher is waiting for its active hours to begin before fetching any more pages.
+func (f *Fetcher) IsPaused() bool {
+	return f.activeHoursPause.Load() != nil
+}
@@ -0,0 +1,256 @@
+package fetch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+	"github.com/zalando/go-keyring"
+)
+
+// ErrInterrupted is returned instead of issuing requests once the context of the fetch has been cancelled.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrPageTimeout is returned instead of issuing requests on behalf of a page once the time allowed for its fetching
+// (see Config.PageTimeout) has run out.
+var ErrPageTimeout = errors.New("page timed out")
+
+// interruption returns the error with which the requests made with the context give up once it is done.
+func interruption(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), ErrPageTimeout) {
+		return ErrPageTimeout
+	}
+	return ErrInterrupted
+}
+
+// isInterruption reports whether the error is the one with which the requests give up once their context is done.
+func isInterruption(err error) bool {
+	return err == ErrInterrupted || err == ErrPageTimeout
+}
+
+// ErrChallenge is returned when an anti-bot service serves its challenge instead of the requested page or resource.
+var ErrChallenge = errors.New("challenged by an anti-bot service")
+
+// challengeSniffLength is the length of the start of HTML content which is searched for the markers of a challenge.
+const challengeSniffLength = 4096
+
+// sniffedReadCloser reads the body of a response through the reader which has consumed the part of it from which its
+// content type was detected.
+type sniffedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// KeyringService is the service under which the secrets referenced as `keyring:NAME` are stored in the keyring of the
+// operating system (the Secret Service on Linux, the Keychain on macOS and the Credential Manager on Windows), with
+// NAME as the user.
+const KeyringService = "fetch-forum-topic"
+
+// lookupReference obtains the value referenced by a reference of the form `env:NAME`, which names an environment
+// variable, or `keyring:NAME`, which names an entry of the keyring of the operating system. The kind of the value is
+// only used in the messages.
+func lookupReference(reference, kind string) (value string, err error) {
+	scheme, name, ok := strings.Cut(reference, ":")
+	if !ok {
+		err = fmt.Errorf("invalid %s reference %q: expected `scheme:name`", kind, reference)
+		return
+	}
+
+	switch scheme {
+	case "env":
+		value, ok = os.LookupEnv(name)
+		if !ok {
+			err = fmt.Errorf("environment variable %s referenced by the %s is not set", name, kind)
+		}
+		return
+	case "keyring":
+		value, err = keyring.Get(KeyringService, name)
+		if err == keyring.ErrNotFound {
+			err = fmt.Errorf("keyring entry %s referenced by the %s does not exist", name, kind)
+		} else if err != nil {
+			err = fmt.Errorf("could not read keyring entry %s referenced by the %s: %v", name, kind, err)
+		}
+		return
+	}
+
+	err = fmt.Errorf("unsupported %s reference scheme: %s", kind, scheme)
+	return
+}
+
+// ResolveSecret obtains a secret, such as an API key or a token, from a reference of the form `env:NAME` or
+// `keyring:NAME`.
+func ResolveSecret(reference string) (secret string, err error) {
+	return lookupReference(reference, "secret")
+}
+
+// ResolveCredentials obtains the user name and password from a credentials reference of the form `env:NAME` or
+// `keyring:NAME`, whose value is of the form `user:password`.
+func ResolveCredentials(reference string) (username, password string, err error) {
+	value, err := lookupReference(reference, "credentials")
+	if err != nil {
+		return
+	}
+
+	username, password, ok := strings.Cut(value, ":")
+	if !ok {
+		err = fmt.Errorf("the value referenced by the credentials %s does not have the form `user:password`", reference)
+	}
+	return
+}
+
+// resolveCredentials obtains the credentials for the forum, recording the failure to do so as an authentication failure.
+func (f *Fetcher) resolveCredentials() (username, password string, err error) {
+	username, password, err = ResolveCredentials(f.config.Credentials)
+	if err != nil {
+		f.hasAuthFailure.Store(true)
+	}
+	return
+}
+
+// refererKey is the key of the context value which holds the URL sent as the Referer of the requests.
+type refererKey struct{}
+
+// withReferer returns a copy of the context in which the requests are sent with the URL of the referring page or
+// stylesheet as their Referer, without its credentials and fragment, as browsers do; many image hosts protect against
+// hotlinking by rejecting requests whose Referer is not one of the sites allowed to embed their images.
+func withReferer(ctx context.Context, refererURL *url.URL) context.Context {
+	referer := *refererURL
+	referer.User = nil
+	referer.Fragment, referer.RawFragment = "", ""
+	return context.WithValue(ctx, refererKey{}, referer.String())
+}
+
+// doRequest issues an HTTP request to the given URL through the middleware of the fetcher.
+func (f *Fetcher) doRequest(ctx context.Context, method, urlStr string) (response *http.Response, err error) {
+	if ctx.Err() != nil {
+		return nil, interruption(ctx)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
+	if err != nil {
+		return
+	}
+	request.URL.Host = asciiHost(request.URL)
+	request.Host = request.URL.Host
+	if referer, ok := ctx.Value(refererKey{}).(string); ok {
+		request.Header.Set("Referer", referer)
+	}
+	if byteRange, ok := ctx.Value(byteRangeKey{}).(string); ok {
+		request.Header.Set("Range", byteRange)
+	}
+	if ctx.Value(apiRequestKey{}) != nil {
+		err = f.authenticateAPIRequest(request)
+		if err != nil {
+			return
+		}
+	}
+
+	response, err = f.client.Do(request)
+	if err != nil && ctx.Err() != nil {
+		return nil, interruption(ctx)
+	}
+	return
+}
+
+// ResourceSize returns the size of the resource at the given URL as reported in response to a HEAD request, or -1 if
+// it is unknown.
+func (f *Fetcher) ResourceSize(ctx context.Context, urlStr string) (size int64, err error) {
+	response, err := f.doRequest(ctx, http.MethodHead, urlStr)
+	if err != nil {
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = &StatusError{StatusCode: response.StatusCode}
+		return
+	}
+
+	size = response.ContentLength
+	return
+}
+
+// LinkStatus returns the status code with which the target of the link at the given URL responds, after following
+// redirects, to a HEAD request or, if the server does not allow them, a GET request for its first byte, without
+// downloading its content.
+func (f *Fetcher) LinkStatus(ctx context.Context, urlStr string) (statusCode int, err error) {
+	response, err := f.doRequest(ctx, http.MethodHead, urlStr)
+	if err != nil {
+		return
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed && response.StatusCode != http.StatusNotImplemented {
+		return response.StatusCode, nil
+	}
+
+	response, err = f.doRequest(withByteRange(ctx, "bytes=0-0"), http.MethodGet, urlStr)
+	if err != nil {
+		return
+	}
+	response.Body.Close()
+	return response.StatusCode, nil
+}
+
+// Get fetches the resource at the given URL, which is referred to by the description in log messages, and returns its
+// content along with its metadata; the bytes read from the content are counted in the report of the run. If the server
+// did not declare a specific content type, the one in the metadata is detected from the start of the content.
+func (f *Fetcher) Get(ctx context.Context, urlStr, description string) (contentReader io.ReadCloser, metadata *archive.ResourceMetadata, err error) {
+	response, err := f.doRequest(ctx, http.MethodGet, urlStr)
+	if err != nil {
+		if errors.Is(err, transport.ErrSessionExpired) {
+			f.hasAuthFailure.Store(true)
+		}
+		f.logger.Error(f.translatef("could not fetch %s: HTTP GET request failed", description), slog.String("url", urlStr), slog.Any("err", err))
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusProxyAuthRequired {
+			f.hasAuthFailure.Store(true)
+		}
+		if transport.IsChallenge(response) {
+			f.hasChallengeFailure.Store(true)
+			err = ErrChallenge
+			f.logger.Error(f.translatef("could not fetch %s: %v", description, err), slog.String("url", urlStr), slog.Int("status", response.StatusCode))
+			return
+		}
+
+		err = &StatusError{StatusCode: response.StatusCode}
+		f.logger.Error(f.translatef("could not fetch %s: %v", description, err), slog.String("url", urlStr), slog.Int("status", response.StatusCode))
+		return
+	}
+
+	metadata = archive.NewResourceMetadata(response)
+	reader := metadata.SniffContentType(countingReadCloser{response.Body, f.report})
+	if mediaType, _, _ := mime.ParseMediaType(metadata.ContentType); mediaType == "text/html" {
+		// some anti-bot services serve their challenge with an OK status; it must not be archived in place of the page
+		bufferedReader := bufio.NewReaderSize(reader, challengeSniffLength)
+		content, _ := bufferedReader.Peek(challengeSniffLength)
+		if transport.IsChallengePage(content) {
+			response.Body.Close()
+			f.hasChallengeFailure.Store(true)
+			err = ErrChallenge
+			f.logger.Error(f.translatef("could not fetch %s: %v", description, err), slog.String("url", urlStr))
+			return nil, nil, err
+		}
+
+		reader = bufferedReader
+	}
+
+	contentReader = sniffedReadCloser{
+		Reader: reader,
+		Closer: response.Body,
+	}
+
+	return
+}
@@ -0,0 +1,138 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// siteIcon is a file at the root of a site which browsers and other clients look for on their own, along with the
+// media types which it is expected to have, so that the error pages served in its place by some forums are not stored,
+// and the class of resources to which it belongs, so that it is filtered like the resources embedded in the pages.
+type siteIcon struct {
+	path       string
+	mediaTypes []string
+	class      rewrite.ResourceClass
+}
+
+var siteIcons = []*siteIcon{
+	{path: "/favicon.ico", mediaTypes: []string{"image/"}, class: rewrite.ResourceClassImage},
+	{path: "/apple-touch-icon.png", mediaTypes: []string{"image/"}, class: rewrite.ResourceClassImage},
+	{path: "/browserconfig.xml", mediaTypes: []string{"application/xml", "text/xml"}, class: rewrite.ResourceClassOther},
+}
+
+// hasMediaType reports whether the content type is one of the media types expected of the icon, where a media type
+// ending with a slash stands for all of its subtypes.
+func (icon *siteIcon) hasMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, expectedMediaType := range icon.mediaTypes {
+		if mediaType == expectedMediaType || strings.HasSuffix(expectedMediaType, "/") && strings.HasPrefix(mediaType, expectedMediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// siteIconState keeps track of the hosts whose icons have been looked for during the run.
+type siteIconState struct {
+	mutex sync.Mutex
+	hosts map[string]*sync.Once
+}
+
+// fetchSiteIcons stores the icons at the root of the host of the page in the directory of the icons of the archive,
+// once per run for every host, unless they have been stored during an earlier run. The icons which the host does not
+// serve are skipped silently.
+func (f *Fetcher) fetchSiteIcons(ctx context.Context, pageURL *url.URL) {
+	hostname := ASCIIHostname(pageURL.Hostname())
+
+	f.siteIcons.mutex.Lock()
+	if f.siteIcons.hosts == nil {
+		f.siteIcons.hosts = map[string]*sync.Once{}
+	}
+	once, ok := f.siteIcons.hosts[hostname]
+	if !ok {
+		once = &sync.Once{}
+		f.siteIcons.hosts[hostname] = once
+	}
+	f.siteIcons.mutex.Unlock()
+
+	once.Do(func() {
+		for _, icon := range siteIcons {
+			if ctx.Err() != nil {
+				return
+			}
+
+			iconURL := &url.URL{Scheme: pageURL.Scheme, Host: pageURL.Host, Path: icon.path}
+			f.fetchSiteIcon(ctx, iconURL, icon, path.Join(archive.IconsDirname, hostname))
+		}
+	})
+}
+
+// lookupStoredIcon looks for the icon at the URL in the map of the original URLs of the files stored in the archive.
+func (f *Fetcher) lookupStoredIcon(iconURL *url.URL) (*archive.URLMapEntry, bool) {
+	if f.urlMap == nil {
+		return nil, false
+	}
+
+	return f.urlMap.LookupFilename(iconURL.String())
+}
+
+func (f *Fetcher) fetchSiteIcon(ctx context.Context, iconURL *url.URL, icon *siteIcon, targetHostDir string) {
+	iconDescription := f.translatef("site icon %s", iconURL)
+	if _, ok := f.lookupStoredIcon(iconURL); ok && !f.config.Force {
+		f.logDebugf("Skipping %s, which has already been stored", iconDescription)
+		return
+	}
+	// the icons are subject to the same filters and budget as the resources embedded in the pages
+	if matchAnyDomain(f.config.BlockDomains, iconURL.Hostname()) || !f.isResourceURLAccepted(iconURL.String()) ||
+		!f.isResourceClassWanted(icon.class) || f.isBudgetExhausted() {
+		f.logDebugf("Skipping %s, which is filtered out or beyond the download budget", iconDescription)
+		return
+	}
+
+	// the host is asked about the icon first, so that the missing ones are not reported as failures
+	contentType, _ := f.preflightResource(ctx, iconURL)
+	if !icon.hasMediaType(contentType) {
+		f.logDebugf("Skipping %s, which the host does not serve", iconDescription)
+		return
+	}
+
+	contentBody, metadata, err := f.Get(ctx, iconURL.String(), iconDescription)
+	if err != nil {
+		return
+	}
+	defer contentBody.Close()
+	if !icon.hasMediaType(metadata.ContentType) {
+		f.logDebugf("Skipping %s, which the host does not serve", iconDescription)
+		return
+	}
+
+	file, name, err := f.openFileForResourceContent(iconURL, iconDescription, metadata.ContentType, targetHostDir)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(file, contentBody)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		f.checkDiskFull(err)
+		f.logErrorf("could not write the content of %s in file %s successfully", iconDescription, name)
+		return
+	}
+
+	f.recordFetchedResource(name, metadata)
+	f.report.recordResource(nil)
+	f.logDebugf("Stored %s in file %s", iconDescription, name)
+}
@@ -0,0 +1,57 @@
+package fetch
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ASCIIHostname returns the ASCII (punycode) form of the hostname, which may be an internationalized domain name, so
+// that the same host is always requested and stored under the same name however it is written. Hostnames which are
+// not valid domain names, such as IP addresses, are merely lowercased.
+func ASCIIHostname(hostname string) string {
+	if net.ParseIP(hostname) == nil {
+		if asciiHostname, err := idna.Lookup.ToASCII(hostname); err == nil {
+			return asciiHostname
+		}
+	}
+
+	return strings.ToLower(hostname)
+}
+
+// asciiHost returns the host of the URL with its hostname converted by ASCIIHostname and its port kept.
+func asciiHost(uri *url.URL) string {
+	hostname := uri.Hostname()
+	asciiHostname := ASCIIHostname(hostname)
+	if asciiHostname == hostname {
+		return uri.Host
+	}
+
+	if strings.Contains(asciiHostname, ":") {
+		// IPv6 addresses are enclosed in brackets
+		asciiHostname = "[" + asciiHostname + "]"
+	}
+	if port := uri.Port(); port != "" {
+		return asciiHostname + ":" + port
+	}
+	return asciiHostname
+}
+
+// ASCIIURL returns the URL with its host converted to the ASCII form by ASCIIHostname, e.g. for a URL with a Unicode
+// hostname given on the command line. The URL is returned as it is if it is invalid or its host is already in ASCII.
+func ASCIIURL(urlStr string) string {
+	uri, err := url.Parse(urlStr)
+	if err != nil {
+		return urlStr
+	}
+
+	host := asciiHost(uri)
+	if host == uri.Host {
+		return urlStr
+	}
+
+	uri.Host = host
+	return uri.String()
+}
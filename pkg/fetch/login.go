@@ -0,0 +1,120 @@
+package fetch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+	"golang.org/x/net/html"
+)
+
+// loginSniffLength is the length of the start of the response to the submission of the login form which is searched
+// for the form itself, whose presence means that the login failed.
+const loginSniffLength = 64 << 10
+
+// session returns the middleware which logs in to the forum with the login credentials of the configuration and renews
+// the session whenever it expires, or nil if the engine does not support logging in.
+func (f *Fetcher) session() transport.Middleware {
+	loginer, ok := f.engine().(engine.Loginer)
+	if !ok {
+		f.logWarningf("the %s engine does not support logging in; the login credentials are ignored", f.engine().Name())
+		return nil
+	}
+
+	loginURL, err := url.Parse(loginer.LoginURL(f.topic()))
+	if err != nil || loginURL.Host == "" {
+		f.logWarningf("could not determine the URL of the login page of the forum; the login credentials are ignored")
+		return nil
+	}
+	loginURL.Host = asciiHost(loginURL)
+
+	loginCount := 0
+	login := func(ctx context.Context, roundTripper http.RoundTripper) (cookies []*http.Cookie, err error) {
+		if loginCount > 0 {
+			f.logInfof("The session has expired; logging in to %s again...", loginURL.Host)
+		}
+
+		cookies, err = f.logIn(ctx, roundTripper, loginer, loginURL)
+		if err != nil {
+			f.hasAuthFailure.Store(true)
+			f.logErrorf("could not log in to %s: %v", loginURL.Host, err)
+			return
+		}
+
+		loginCount++
+		return
+	}
+
+	isExpired := func(response *http.Response, content []byte) bool {
+		if location, err := response.Location(); err == nil {
+			return location.Host == loginURL.Host && location.Path == loginURL.Path
+		}
+
+		return loginer.IsLoginPage(content)
+	}
+
+	return transport.Session(loginURL.Host, login, isExpired)
+}
+
+// logIn submits the login form at the login URL with the login credentials of the configuration through the round
+// tripper and returns the cookies of the session.
+func (f *Fetcher) logIn(ctx context.Context, roundTripper http.RoundTripper, loginer engine.Loginer, loginURL *url.URL) (cookies []*http.Cookie, err error) {
+	username, password, err := ResolveCredentials(f.config.Login)
+	if err != nil {
+		return
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Transport: roundTripper, Jar: jar}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, loginURL.String(), nil)
+	if err != nil {
+		return
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return
+	}
+	doc, err := html.Parse(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return
+	}
+
+	actionURL, values, ok := engine.LoginForm(doc, response.Request.URL, username, password)
+	if !ok {
+		err = fmt.Errorf("there is no login form at %s", loginURL)
+		return
+	}
+
+	request, err = http.NewRequestWithContext(ctx, http.MethodPost, actionURL.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Referer", response.Request.URL.String())
+	response, err = client.Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	content, _ := bufio.NewReaderSize(response.Body, loginSniffLength).Peek(loginSniffLength)
+	if response.StatusCode >= http.StatusBadRequest || loginer.IsLoginPage(content) {
+		err = fmt.Errorf("the forum did not accept the login credentials")
+		return
+	}
+
+	cookies = jar.Cookies(loginURL)
+	f.logInfof("Logged in to %s as %s.", loginURL.Host, username)
+	return
+}
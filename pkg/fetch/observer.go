@@ -0,0 +1,96 @@
+package fetch
+
+import "time"
+
+// PageEvent describes the fetching of a page.
+type PageEvent struct {
+	Number  uint
+	URL     string
+	Attempt int
+	// Duration is the time the fetching of the page took; it is only set when the page is done.
+	Duration time.Duration
+	// Err is the reason why the fetching of the page failed; it is only set when the page is done.
+	Err error
+}
+
+// ResourceEvent describes the fetching of a resource embedded in a page.
+type ResourceEvent struct {
+	PageNumber uint
+	URL        string
+	// Filename is the slash-separated path of the local copy of the resource in the storage.
+	Filename    string
+	ContentType string
+}
+
+// ErrorEvent describes a failure to fetch a page or a resource embedded in it.
+type ErrorEvent struct {
+	PageNumber uint
+	URL        string
+	Err        error
+}
+
+// Observer is notified about the progress of the fetch. Its methods are called concurrently by the pages being fetched,
+// so they have to be safe for concurrent use and should return quickly.
+type Observer interface {
+	// OnPageStart is called when the fetching of a page starts.
+	OnPageStart(event *PageEvent)
+	// OnPageDone is called when the fetching of a page has finished, successfully or not.
+	OnPageDone(event *PageEvent)
+	// OnResourceFetched is called when a resource embedded in a page has been stored successfully.
+	OnResourceFetched(event *ResourceEvent)
+	// OnRetry is called before OnPageStart when a page whose fetching has failed before is attempted again.
+	OnRetry(event *PageEvent)
+	// OnError is called when a page or a resource embedded in it could not be fetched.
+	OnError(event *ErrorEvent)
+}
+
+// NopObserver ignores all events; it can be embedded in order to implement only some of the methods of Observer.
+type NopObserver struct{}
+
+func (NopObserver) OnPageStart(event *PageEvent)           {}
+func (NopObserver) OnPageDone(event *PageEvent)            {}
+func (NopObserver) OnResourceFetched(event *ResourceEvent) {}
+func (NopObserver) OnRetry(event *PageEvent)               {}
+func (NopObserver) OnError(event *ErrorEvent)              {}
+
+// WithObserver makes the fetcher notify the given observer about the progress of the fetch in addition to the ones
+// given before.
+func WithObserver(observer Observer) Option {
+	return func(f *Fetcher) {
+		f.observers = append(f.observers, observer)
+	}
+}
+
+func (f *Fetcher) notifyPageStart(event *PageEvent) {
+	for _, observer := range f.observers {
+		if event.Attempt > 1 {
+			observer.OnRetry(event)
+		}
+		observer.OnPageStart(event)
+	}
+}
+
+func (f *Fetcher) notifyPageDone(event *PageEvent) {
+	for _, observer := range f.observers {
+		if event.Err != nil {
+			observer.OnError(&ErrorEvent{
+				PageNumber: event.Number,
+				URL:        event.URL,
+				Err:        event.Err,
+			})
+		}
+		observer.OnPageDone(event)
+	}
+}
+
+func (f *Fetcher) notifyResourceFetched(event *ResourceEvent) {
+	for _, observer := range f.observers {
+		observer.OnResourceFetched(event)
+	}
+}
+
+func (f *Fetcher) notifyError(event *ErrorEvent) {
+	for _, observer := range f.observers {
+		observer.OnError(event)
+	}
+}
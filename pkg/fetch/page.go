@@ -0,0 +1,434 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// pageNumberKey is the key of the context value with the number of the page on whose behalf the requests are made.
+type pageNumberKey struct{}
+
+// PageNumberFromContext returns the number of the page on whose behalf a request with the context is made, e.g. for
+// routing the requests of different pages differently, or false if the request is not made for any page.
+func PageNumberFromContext(ctx context.Context) (pageNumber uint, ok bool) {
+	pageNumber, ok = ctx.Value(pageNumberKey{}).(uint)
+	return
+}
+
+func (f *Fetcher) fetchPage(ctx context.Context, pageNumber uint, attempt int) {
+	if f.isBudgetSet() {
+		f.budgetedPageMutex.Lock()
+		defer f.budgetedPageMutex.Unlock()
+
+		if f.isBudgetExhausted() {
+			f.logDebugf("Leaving page %d pending since the download budget has been exhausted", pageNumber)
+			f.report.recordPendingPage(pageNumber)
+			f.workers.Done()
+			return
+		}
+	}
+
+	if !f.waitForActiveHours(ctx) {
+		f.logDebugf("Leaving page %d pending since the run ended outside the active hours", pageNumber)
+		f.report.recordPendingPage(pageNumber)
+		f.workers.Done()
+		return
+	}
+
+	startTime := time.Now()
+	pageURLStr := f.PageURL(pageNumber)
+	pageLogger := f.logger.With(slog.Uint64("page", uint64(pageNumber)), slog.String("url", pageURLStr), slog.Int("attempt", attempt))
+
+	ctx = context.WithValue(ctx, pageNumberKey{}, pageNumber)
+	if f.config.PageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, f.config.PageTimeout, ErrPageTimeout)
+		defer cancel()
+	}
+	ctx, span := f.tracer.Start(ctx, "fetch.page", trace.WithAttributes(
+		attribute.Int64("page.number", int64(pageNumber)),
+		attribute.String("url.full", redact.URLString(pageURLStr)),
+		attribute.Int("page.attempt", attempt),
+	))
+
+	var err error
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		if err != nil {
+			if recordErr := f.state.RecordFailure(pageNumber, FailureKind(err), err.Error()); recordErr != nil {
+				f.logErrorf("could not record the failed download of page %d: %v", pageNumber, recordErr)
+			}
+
+			pageLogger.Warn(f.translatef("fetching of page %d failed", pageNumber), logDuration(startTime))
+		}
+		f.report.recordPage(pageNumber, attempt, startTime, err)
+		f.notifyPageDone(&PageEvent{
+			Number:   pageNumber,
+			URL:      pageURLStr,
+			Attempt:  attempt,
+			Duration: time.Since(startTime),
+			Err:      err,
+		})
+
+		f.workers.Done()
+	}()
+
+	pageLogger.Debug(f.translatef("Starting the fetching of page %d...", pageNumber))
+	f.notifyPageStart(&PageEvent{
+		Number:  pageNumber,
+		URL:     pageURLStr,
+		Attempt: attempt,
+	})
+
+	pageURL, err := url.Parse(pageURLStr)
+	if err != nil {
+		f.logErrorf("could not parse URL of page %d", pageNumber)
+		return
+	}
+
+	targetHostDir := path.Join(fmt.Sprint(pageNumber), ASCIIHostname(pageURL.Hostname()))
+
+	if !f.config.SkipSiteIcons {
+		f.fetchSiteIcons(ctx, pageURL)
+	}
+
+	pageDescription := f.translatef("page %d", pageNumber)
+
+	contentReader, metadata, err := f.getPage(ctx, pageNumber, pageURL.String(), pageDescription)
+	if err != nil {
+		return
+	}
+	defer contentReader.Close()
+
+	var content io.Reader = contentReader
+	var contentName string
+	if f.config.NameByTitle {
+		// the title has to be known before the page is written, so it is looked for in the start of the page
+		bufferedContentReader := bufio.NewReaderSize(contentReader, titleLookahead)
+		head, _ := bufferedContentReader.Peek(titleLookahead)
+		contentName = f.titledPageName(pageURL, pageNumber, metadata.ContentType, targetHostDir, head)
+		content = bufferedContentReader
+	}
+	// the posts are extracted from the original content once it has been written
+	var trackedContent bytes.Buffer
+	if f.config.TrackPosts {
+		content = io.TeeReader(content, &trackedContent)
+	}
+	if f.config.FirstPostOnly {
+		content, err = f.firstPostContent(pageNumber, content)
+		if err != nil {
+			return
+		}
+	}
+
+	contentTokenizer := html.NewTokenizer(content)
+	contentTokenizer.AllowCDATA(true)
+
+	var contentFile io.WriteCloser
+	if contentName != "" {
+		contentFile, err = f.createFileForResourceContent(contentName, pageDescription)
+	} else {
+		contentFile, contentName, err = f.openFileForResourceContent(pageURL, pageDescription, metadata.ContentType, targetHostDir)
+	}
+	if err != nil {
+		return
+	}
+	defer f.recordFetchedResource(contentName, metadata)
+
+	pageDirpath := filepath.Dir(filepath.FromSlash(pageURL.Path))
+
+	fetchedResources := map[string]string{}
+
+	var prevToken *html.Token
+	isPolicyInjected := !f.config.HardenOffline
+	// the end tag of the frame of an embedded video which has been replaced with a `video` element is replaced too
+	isFrameReplaced := false
+	// the external link being written, after which a preview card is put if it turns out to be bare
+	var link *bareLink
+	linkPreviewCards := map[string]string{}
+	// the thumbnails of the images embedded in the page and the number of links within which the token being written is,
+	// in which case the thumbnails are not wrapped in links of their own
+	var thumbnails map[string]string
+	if f.makeThumbnail != nil {
+		thumbnails = map[string]string{}
+	}
+	linkDepth := 0
+	// the links to the injected stylesheets, until they have been injected
+	injectedStylesheetTags := f.injectedStylesheetTags(&resourceFetcherContext{pageNumber: pageNumber, targetHostDir: targetHostDir, dirpath: pageDirpath})
+
+	for contentTokenizer.Next() != html.ErrorToken {
+		func() {
+			token := contentTokenizer.Token()
+			// prependedHTML is written before the token and appendedHTML after it
+			var prependedHTML, appendedHTML string
+
+			defer func() {
+				if f.config.HardenOffline {
+					rewrite.HardenOffline(&token)
+				}
+				tokenHTML := prependedHTML + rewrite.TokenString(&token, prevToken) + appendedHTML
+				if !isPolicyInjected && (token.Type == html.StartTagToken || token.Type == html.SelfClosingTagToken) {
+					// the policy goes at the start of the head, which is implied by it if the page lacks one
+					if token.DataAtom == atom.Head {
+						tokenHTML += rewrite.OfflineContentSecurityPolicyTag()
+						isPolicyInjected = true
+					} else if token.DataAtom != atom.Html {
+						tokenHTML = rewrite.OfflineContentSecurityPolicyTag() + tokenHTML
+						isPolicyInjected = true
+					}
+				}
+				if injectedStylesheetTags != "" && rewrite.IsStylesheetInjectionPoint(&token) {
+					tokenHTML = injectedStylesheetTags + tokenHTML
+					injectedStylesheetTags = ""
+				}
+
+				_, err := io.WriteString(contentFile, tokenHTML)
+				if err != nil {
+					f.checkDiskFull(err)
+					f.logErrorf("could not write part of the content of page %d in file %s successfully", pageNumber, contentName)
+				}
+				prevToken = &token
+			}()
+
+			if token.DataAtom == atom.A {
+				if token.Type == html.StartTagToken {
+					linkDepth++
+				} else if token.Type == html.EndTagToken && linkDepth > 0 {
+					linkDepth--
+				}
+			}
+
+			if token.Type == html.TextToken && prevToken != nil && prevToken.Type == html.StartTagToken && prevToken.DataAtom == atom.Style {
+				context := &resourceFetcherContext{
+					ctx:              ctx,
+					pageNumber:       pageNumber,
+					baseURL:          pageURL,
+					targetHostDir:    targetHostDir,
+					dirpath:          pageDirpath,
+					fetchedResources: fetchedResources,
+				}
+				token.Data = string(f.fetchLinkedResourcesInCSS([]byte(token.Data), context))
+			} else if token.Type == html.TextToken && isFallbackContent(prevToken) {
+				context := &resourceFetcherContext{
+					ctx:              ctx,
+					pageNumber:       pageNumber,
+					baseURL:          pageURL,
+					targetHostDir:    targetHostDir,
+					dirpath:          pageDirpath,
+					fetchedResources: fetchedResources,
+				}
+				token.Data = f.rewriteFallbackContent(token.Data, context)
+			} else if token.Type == html.EndTagToken && token.DataAtom == atom.Iframe && isFrameReplaced {
+				token = html.Token{Type: html.EndTagToken, DataAtom: atom.Video, Data: "video"}
+				isFrameReplaced = false
+			} else if token.Type == html.TextToken && link != nil {
+				link.text.WriteString(token.Data)
+			} else if token.Type == html.EndTagToken && token.DataAtom == atom.A && link != nil {
+				if link.isBare() {
+					card, wasPreviewCaptured := linkPreviewCards[link.url.String()]
+					if !wasPreviewCaptured {
+						context := &resourceFetcherContext{
+							ctx:              ctx,
+							pageNumber:       pageNumber,
+							baseURL:          pageURL,
+							targetHostDir:    targetHostDir,
+							dirpath:          pageDirpath,
+							fetchedResources: fetchedResources,
+						}
+						card = f.linkPreviewCard(link.url, context)
+						linkPreviewCards[link.url.String()] = card
+					}
+					appendedHTML = card
+				}
+				link = nil
+			} else if token.Type == html.SelfClosingTagToken || token.Type == html.StartTagToken {
+				if link != nil {
+					link.hasElements = true
+				}
+
+				linkAttrs := rewrite.GetLinkAttrs(&token)
+
+				if linkAttrs.HasStyle {
+					context := &resourceFetcherContext{
+						ctx:              ctx,
+						pageNumber:       pageNumber,
+						baseURL:          pageURL,
+						targetHostDir:    targetHostDir,
+						dirpath:          pageDirpath,
+						fetchedResources: fetchedResources,
+					}
+					token.Attr[linkAttrs.StyleIndex].Val = string(f.fetchLinkedResourcesInCSS([]byte(linkAttrs.Style), context))
+				}
+
+				if !linkAttrs.HasURI {
+					return
+				}
+
+				linkURI, err := url.Parse(linkAttrs.URI)
+				if err != nil {
+					f.logErrorf("could not parse URL of resource %s", linkAttrs.URI)
+					return
+				}
+
+				if f.downloadVideo != nil && token.DataAtom == atom.Iframe && token.Type == html.StartTagToken {
+					if video, ok := rewrite.GetEmbeddedVideo(&token, pageURL.ResolveReference(linkURI)); ok {
+						context := &resourceFetcherContext{
+							ctx:              ctx,
+							pageNumber:       pageNumber,
+							baseURL:          pageURL,
+							targetHostDir:    targetHostDir,
+							dirpath:          pageDirpath,
+							fetchedResources: fetchedResources,
+							resourceClass:    rewrite.ResourceClassMedia,
+							replaceResourceReference: func(reference string) {
+								token = rewrite.VideoTag(&token, reference)
+							},
+						}
+						if f.fetchEmbeddedVideo(video, context) {
+							isFrameReplaced = true
+							return
+						}
+					}
+				}
+
+				if linkAttrs.IsEmbeddedResource(&token) {
+					context := &resourceFetcherContext{
+						ctx:              ctx,
+						pageNumber:       pageNumber,
+						baseURL:          pageURL,
+						targetHostDir:    targetHostDir,
+						dirpath:          pageDirpath,
+						fetchedResources: fetchedResources,
+						resourceClass:    rewrite.ElementResourceClass(&token, &linkAttrs),
+						replaceResourceReference: func(reference string) {
+							token.Attr[linkAttrs.URIAttrIndex].Val = reference
+						},
+						isFrameDocument: token.DataAtom == atom.Frame,
+					}
+					if token.DataAtom == atom.Img && linkAttrs.URIAttrAtom == atom.Src {
+						context.thumbnails = thumbnails
+						context.replaceThumbnailReference = func(thumbnailReference, fullSizeReference string) {
+							token.Attr[linkAttrs.URIAttrIndex].Val = thumbnailReference
+							// the candidates of other sizes would be shown instead of the thumbnail
+							token.Attr = slices.DeleteFunc(token.Attr, func(attr html.Attribute) bool {
+								return attr.Namespace == "" && (attr.Key == "srcset" || attr.Key == "sizes")
+							})
+							if linkDepth == 0 {
+								prependedHTML, appendedHTML = thumbnailLinkTags(fullSizeReference)
+							}
+						}
+					}
+					if code, ok := f.smileyCode(&token, &linkAttrs); ok && f.fetchSharedSmiley(linkURI, code, context) {
+						return
+					}
+					f.fetchResourceFromLinkIfNecessary(linkURI, context)
+				} else {
+					linkURI = pageURL.ResolveReference(linkURI)
+
+					token.Attr[linkAttrs.URIAttrIndex].Val = linkURI.String()
+
+					if f.config.LinkPreviews && token.DataAtom == atom.A && token.Type == html.StartTagToken {
+						link = newBareLink(linkURI, ASCIIHostname(pageURL.Hostname()))
+					}
+
+					if attachmentURL, ok := f.engine().AttachmentURL(linkURI); ok {
+						context := &resourceFetcherContext{
+							ctx:              ctx,
+							pageNumber:       pageNumber,
+							baseURL:          pageURL,
+							targetHostDir:    targetHostDir,
+							dirpath:          pageDirpath,
+							fetchedResources: fetchedResources,
+							resourceClass:    rewrite.ResourceClassUnknown,
+							replaceResourceReference: func(reference string) {
+								token.Attr[linkAttrs.URIAttrIndex].Val = reference
+							},
+						}
+						f.fetchResourceFromLinkIfNecessary(attachmentURL, context)
+					} else if f.config.FileTransferLinks && isFileTransferURL(linkURI) {
+						context := &resourceFetcherContext{
+							ctx:              ctx,
+							pageNumber:       pageNumber,
+							baseURL:          pageURL,
+							targetHostDir:    targetHostDir,
+							dirpath:          pageDirpath,
+							fetchedResources: fetchedResources,
+							resourceClass:    rewrite.ResourceClassUnknown,
+							replaceResourceReference: func(reference string) {
+								token.Attr[linkAttrs.URIAttrIndex].Val = reference
+							},
+						}
+						f.fetchResourceFromLinkIfNecessary(linkURI, context)
+					} else if topicURL, ok := f.referencedTopicURL(linkURI); ok {
+						context := &resourceFetcherContext{
+							ctx:              ctx,
+							pageNumber:       pageNumber,
+							baseURL:          pageURL,
+							targetHostDir:    targetHostDir,
+							dirpath:          pageDirpath,
+							fetchedResources: fetchedResources,
+							resourceClass:    rewrite.ResourceClassUnknown,
+							replaceResourceReference: func(reference string) {
+								if reference == topicURL.String() {
+									// the topic has not been archived, so the link is left as it was
+									return
+								}
+								// the link may point at a post within the page
+								if linkURI.Fragment != "" {
+									reference += "#" + linkURI.Fragment
+								}
+								token.Attr[linkAttrs.URIAttrIndex].Val = reference
+							},
+							isReferencedTopic: true,
+						}
+						f.fetchResourceFromLinkIfNecessary(topicURL, context)
+					}
+				}
+			}
+		}()
+	}
+
+	if injectedStylesheetTags != "" {
+		_, err = io.WriteString(contentFile, injectedStylesheetTags)
+		if err != nil {
+			f.checkDiskFull(err)
+			f.logErrorf("could not write part of the content of page %d in file %s successfully", pageNumber, contentName)
+		}
+	}
+
+	contentFile.Close()
+	// the content of a page which ran out of time is incomplete, even if it was cut short without an error
+	if context.Cause(ctx) == ErrPageTimeout {
+		f.logErrorf("page %d did not finish within %s", pageNumber, f.config.PageTimeout)
+		err = ErrPageTimeout
+		return
+	}
+	if f.config.TrackPosts {
+		f.trackPosts(pageNumber, &trackedContent)
+	}
+	f.postProcessFile(ctx, contentName)
+
+	pageLogger.Debug(f.translatef("Finished the fetching of page %d.", pageNumber), logDuration(startTime))
+}
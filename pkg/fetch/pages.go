@@ -0,0 +1,528 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"golang.org/x/net/html"
+)
+
+// ErrNoPages is returned when no pages have been requested and there are no failed or pending ones to fetch again.
+var ErrNoPages = errors.New("no range of forum topic pages specified")
+
+// PageAction is what is going to be done with a page during a run.
+type PageAction int
+
+const (
+	PageActionFetch PageAction = iota
+	PageActionRetry
+	PageActionRefresh
+	PageActionOverwrite
+	PageActionSkip
+)
+
+func (action PageAction) String() string {
+	switch action {
+	case PageActionFetch:
+		return "fetch"
+	case PageActionRetry:
+		return "retry"
+	case PageActionRefresh:
+		return "refresh"
+	case PageActionOverwrite:
+		return "overwrite"
+	case PageActionSkip:
+		return "skip"
+	}
+
+	return "unknown"
+}
+
+// PageOrder is the order in which the pages of a run are started.
+type PageOrder string
+
+const (
+	// OldestFirst starts the pages from the first one on; it is the order of the zero PageOrder.
+	OldestFirst PageOrder = "oldest-first"
+	// NewestFirst starts the pages from the last one back, so that the most recent posts of an active topic, which are
+	// the likeliest to be edited or deleted, are archived first if the run is cut short (e.g. by an interruption or a
+	// budget).
+	NewestFirst PageOrder = "newest-first"
+)
+
+// PageOrders are the orders in which the pages can be started.
+var PageOrders = []string{string(OldestFirst), string(NewestFirst)}
+
+func (order *PageOrder) String() string {
+	if *order == "" {
+		return string(OldestFirst)
+	}
+	return string(*order)
+}
+
+// Set checks that the order is one of PageOrders, so that it can be given as a flag.
+func (order *PageOrder) Set(value string) error {
+	if !slices.Contains(PageOrders, value) {
+		return fmt.Errorf("invalid page order %q: it has to be one of: %s", value, strings.Join(PageOrders, ", "))
+	}
+	*order = PageOrder(value)
+	return nil
+}
+
+// PagePlan describes what is going to be done with a page during a run.
+type PagePlan struct {
+	Number  uint
+	URL     string
+	Action  PageAction
+	Attempt int
+}
+
+// openState opens the state database of the archive, unless it is open already, or returns nil if the target
+// directory does not exist yet, in which case nothing has been recorded.
+func (f *Fetcher) openState() (state *archive.State, err error) {
+	if f.state != nil {
+		return f.state, nil
+	}
+	if _, err := os.Stat(f.config.TargetDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	f.state, err = archive.OpenState(f.config.TargetDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the state database (%s): %w", filepath.Join(f.config.TargetDir, archive.StateFilename), err)
+	}
+	return f.state, nil
+}
+
+// plannedState is the bookkeeping of the last runs which is read in order to plan a run: either the state database or
+// the legacy lists of an archive which has not been migrated to it yet.
+type plannedState interface {
+	FailedPages() ([]uint, error)
+	PendingPages() ([]uint, error)
+	CountPageFailures() (map[uint]int, error)
+}
+
+// openPlannedState opens the bookkeeping of the archive for planning a run without changing anything: the state
+// database opened by openState or else opened read-only, so that it is neither created nor migrated, or the legacy
+// lists if it does not exist. It returns nil if the target directory does not exist yet, in which case nothing has
+// been recorded; the returned function releases the bookkeeping.
+func (f *Fetcher) openPlannedState() (state plannedState, release func(), err error) {
+	release = func() {}
+	if f.state != nil {
+		return f.state, release, nil
+	}
+	if _, err := os.Stat(f.config.TargetDir); os.IsNotExist(err) {
+		return nil, release, nil
+	}
+
+	readOnlyState, err := archive.OpenStateReadOnly(f.config.TargetDir)
+	if err != nil {
+		return nil, release, fmt.Errorf("could not open the state database (%s): %w", filepath.Join(f.config.TargetDir, archive.StateFilename), err)
+	}
+	if readOnlyState == nil {
+		return archive.ReadLegacyState(f.config.TargetDir), release, nil
+	}
+	return readOnlyState, func() { readOnlyState.Close() }, nil
+}
+
+func (f *Fetcher) getFailedDownloads(state plannedState) (failedPageNumbers []uint) {
+	failedPageNumbers, err := state.FailedPages()
+	if err != nil {
+		f.logErrorf("could not read the failed downloads of the last run: %v", err)
+		return nil
+	}
+
+	if len(failedPageNumbers) > 0 {
+		failedPageNumberStrs := make([]string, len(failedPageNumbers))
+		for i, failedPageNumber := range failedPageNumbers {
+			failedPageNumberStrs[i] = fmt.Sprint(failedPageNumber)
+		}
+
+		f.logInfof("Found failed downloads of the last run; will reattempt them...")
+		f.logInfof("Pages for which download will be reattempted: %s", strings.Join(failedPageNumberStrs, ", "))
+	}
+
+	return
+}
+
+func (f *Fetcher) isArchivedPageStale(pageNumber uint, pageTargetDir string, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+
+	modTime, err := archive.PageModTime(pageTargetDir)
+	if err != nil {
+		f.logErrorf("could not determine the age of the archived copy of page %d in %s", pageNumber, pageTargetDir)
+		return false
+	}
+
+	isStale := time.Since(modTime) > maxAge
+	if isStale {
+		f.logDebugf("Archived copy of page %d was last updated at %s; will refresh it...", pageNumber, modTime.Format(time.RFC3339))
+	}
+	return isStale
+}
+
+func (f *Fetcher) getPageAction(pageNumber uint, pageTargetDir string, isFailedPage bool) (action PageAction, err error) {
+	pageTargetDirStat, err := os.Stat(pageTargetDir)
+	if os.IsNotExist(err) {
+		return PageActionFetch, nil
+	}
+	if err != nil {
+		return
+	}
+	if !pageTargetDirStat.IsDir() {
+		return PageActionFetch, nil
+	}
+
+	switch {
+	case isFailedPage:
+		action = PageActionRetry
+	case f.config.Force:
+		action = PageActionOverwrite
+	case f.isArchivedPageStale(pageNumber, pageTargetDir, f.config.RefreshOlderThan):
+		action = PageActionRefresh
+	default:
+		action = PageActionSkip
+	}
+	return
+}
+
+// Plan determines what is going to be done with every requested page and every page which failed or was left pending
+// during the last run (unless Config.OnlyRequestedPages is set), without changing anything. The pages are planned in
+// the order in which they are started (see Config.Order).
+func (f *Fetcher) Plan(requestedPageNumbers map[uint]struct{}) (plan []*PagePlan, err error) {
+	failedPageNumbers := map[uint]struct{}{}
+	if f.config.OnlyRequestedPages {
+		// the requested pages are the ones to be retried
+		for pageNumber := range requestedPageNumbers {
+			failedPageNumbers[pageNumber] = struct{}{}
+		}
+	}
+	pageFailureCounts := map[uint]int{}
+	state, releaseState, err := f.openPlannedState()
+	if err != nil {
+		f.logErrorf("%v", err)
+	}
+	if state != nil {
+		if !f.config.OnlyRequestedPages {
+			for _, failedPageNumber := range f.getFailedDownloads(state) {
+				failedPageNumbers[failedPageNumber] = struct{}{}
+			}
+			for _, pendingPageNumber := range f.readPendingPages(state) {
+				failedPageNumbers[pendingPageNumber] = struct{}{}
+			}
+		}
+
+		pageFailureCounts, err = state.CountPageFailures()
+		if err != nil {
+			f.logErrorf("could not count the failed downloads of the pages: %v", err)
+			pageFailureCounts = map[uint]int{}
+		}
+	}
+	releaseState()
+
+	pageNumbers := map[uint]struct{}{}
+	for failedPageNumber := range failedPageNumbers {
+		pageNumbers[failedPageNumber] = struct{}{}
+	}
+	for pageNumber := range requestedPageNumbers {
+		pageNumbers[pageNumber] = struct{}{}
+	}
+	if f.config.FirstPostOnly {
+		// the opening post is on the first page, whatever else has been requested
+		pageNumbers = map[uint]struct{}{MinPageNumber: {}}
+	}
+
+	if len(pageNumbers) == 0 {
+		return nil, ErrNoPages
+	}
+
+	for _, pageNumber := range SortPageNumbers(pageNumbers) {
+		pageTargetDir := archive.PageDir(f.config.TargetDir, pageNumber)
+
+		_, isFailedPage := failedPageNumbers[pageNumber]
+		action, err := f.getPageAction(pageNumber, pageTargetDir, isFailedPage)
+		if err != nil {
+			f.logErrorf("could not stat target directory %s for page %d", pageTargetDir, pageNumber)
+			continue
+		}
+
+		plan = append(plan, &PagePlan{
+			Number:  pageNumber,
+			URL:     f.PageURL(pageNumber),
+			Action:  action,
+			Attempt: pageFailureCounts[pageNumber] + 1,
+		})
+	}
+	if f.config.Order == NewestFirst {
+		slices.Reverse(plan)
+	}
+	return plan, nil
+}
+
+// Start starts fetching the requested pages as well as the ones which failed or were left pending during the last run,
+// skipping the ones which have already been fetched, and returns the number of pages which have been scheduled.
+// The pages are handed to at most Config.PageConcurrency workers at once in the order of the plan. Wait has to be
+// called afterwards in order to wait for them to be fetched. Once ctx is cancelled, no more requests are issued.
+func (f *Fetcher) Start(ctx context.Context, requestedPageNumbers map[uint]struct{}) (scheduledPageCount int, err error) {
+	plan, err := f.Plan(requestedPageNumbers)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(f.config.TargetDir, os.ModePerm)
+	if err != nil {
+		return 0, fmt.Errorf("could not create target directory %s", f.config.TargetDir)
+	}
+
+	err = f.storeInjectedStylesheets()
+	if err != nil {
+		return
+	}
+
+	state, err := f.openState()
+	if err != nil {
+		return
+	}
+	err = state.StartRun()
+	if err != nil {
+		return 0, fmt.Errorf("could not record the start of the run in the state database: %w", err)
+	}
+	if !f.config.OnlyRequestedPages {
+		f.clearPendingPages()
+	}
+
+	f.urlMap, err = state.URLMap()
+	if err != nil {
+		return 0, fmt.Errorf("could not read the map of original URLs from the state database: %w", err)
+	}
+	f.loadSmileys()
+
+	f.runContext = ctx
+	scheduledPageNumbers := map[uint]struct{}{}
+	for _, page := range plan {
+		if page.Action == PageActionSkip {
+			f.report.recordSkippedPage()
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if f.isBudgetExhausted() {
+			f.report.recordPendingPage(page.Number)
+			continue
+		}
+
+		f.Requeue(page.Number, page.Attempt)
+		scheduledPageNumbers[page.Number] = struct{}{}
+		scheduledPageCount++
+	}
+
+	err = state.CarryOverFailures(scheduledPageNumbers)
+	if err != nil {
+		f.logErrorf("could not carry over the failed downloads of the last run: %v", err)
+		err = nil
+	}
+	return
+}
+
+// Requeue schedules the fetching of a page in addition to the ones which have been started, after the ones which are
+// still waiting for a worker.
+func (f *Fetcher) Requeue(pageNumber uint, attempt int) {
+	f.workers.Add(1)
+	f.enqueuePage(pageNumber, attempt)
+}
+
+// Wait waits for the scheduled pages to be fetched, records the posts which have vanished from the tracked pages,
+// finishes the report of the run and records the pages which were left pending.
+func (f *Fetcher) Wait() (err error) {
+	f.workers.Wait()
+	f.postProcessors.Wait()
+
+	if f.config.TrackPosts {
+		f.recordDeletedPosts()
+	}
+	f.report.Finish()
+
+	err = f.recordPendingPages()
+	f.state.Close()
+	f.state = nil
+	return
+}
+
+// FetchPages fetches the requested pages as well as the ones which failed or were left pending during the last run.
+func (f *Fetcher) FetchPages(ctx context.Context, requestedPageNumbers map[uint]struct{}) (err error) {
+	_, err = f.Start(ctx, requestedPageNumbers)
+	if err != nil {
+		return
+	}
+
+	return f.Wait()
+}
+
+const pageRangeEndAll = "all"
+
+// LastPageNumber detects the number of the last page of the forum topic from its first page, as the engine of the
+// forum sees fit.
+func (f *Fetcher) LastPageNumber(ctx context.Context) (uint, error) {
+	if f.lastPageNumber != 0 {
+		return f.lastPageNumber, nil
+	}
+	if f.config.PageURLs != nil {
+		return uint(len(f.config.PageURLs)), nil
+	}
+	if scroller, ok := f.scroller(); ok {
+		pageCount, err := f.scrolledPageCount(ctx, scroller)
+		if err != nil {
+			return 0, fmt.Errorf("could not load the posts of the topic to detect the last page")
+		}
+
+		f.lastPageNumber = max(pageCount, MinPageNumber)
+		f.logDebugf("Detected last page: %d", f.lastPageNumber)
+		return f.lastPageNumber, nil
+	}
+
+	pageURLStr := f.PageURL(MinPageNumber)
+	pageURL, err := url.Parse(pageURLStr)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse URL of page %d", MinPageNumber)
+	}
+
+	contentReader, _, err := f.Get(ctx, pageURLStr, f.translatef("page %d", MinPageNumber))
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch page %d to detect the last page", MinPageNumber)
+	}
+	defer contentReader.Close()
+
+	doc, err := html.Parse(contentReader)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse page %d to detect the last page", MinPageNumber)
+	}
+
+	f.lastPageNumber = max(f.engine().DetectLastPage(f.topic(), doc, pageURL), MinPageNumber)
+	f.logDebugf("Detected last page: %d", f.lastPageNumber)
+	return f.lastPageNumber, nil
+}
+
+// parsePageNumber parses a page number in a page range specification, which is either a positive number, a negative number
+// counting from the end of the topic (`-1` being the last page) or `all` for the last page.
+func (f *Fetcher) parsePageNumber(ctx context.Context, pageNumberStr string) (pageNumber uint, err error) {
+	if pageNumberStr == pageRangeEndAll {
+		return f.LastPageNumber(ctx)
+	}
+
+	number, err := strconv.ParseInt(pageNumberStr, 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a page number", pageNumberStr)
+	}
+	if number == 0 {
+		return 0, fmt.Errorf("page numbers start from %d", MinPageNumber)
+	}
+	if number > 0 {
+		return uint(number), nil
+	}
+
+	last, err := f.LastPageNumber(ctx)
+	if err != nil {
+		return
+	}
+	if uint(-number) > last {
+		return 0, fmt.Errorf("the topic has only %d pages", last)
+	}
+	return last - uint(-number) + 1, nil
+}
+
+// parsePageRange parses a single page range specification of the form `[first]..[last][:step]` or `page` and adds
+// the pages in it to the given set. If isLone is set, the specification makes up a whole argument, in which case a
+// single page number `last` stands for the pages from the first one up to `last` as it always has.
+func (f *Fetcher) parsePageRange(ctx context.Context, pageRange string, isLone bool, pageNumbers map[uint]struct{}) (err error) {
+	var step uint = 1
+	if bounds, stepStr, hasStep := strings.Cut(pageRange, ":"); hasStep {
+		parsedStep, err := strconv.ParseUint(stepStr, 10, 0)
+		if err != nil || parsedStep == 0 {
+			return fmt.Errorf("step %q is not a positive number", stepStr)
+		}
+
+		pageRange, step = bounds, uint(parsedStep)
+	}
+
+	startStr, endStr, isRange := strings.Cut(pageRange, "..")
+	if !isRange {
+		if isLone {
+			startStr, endStr = "", startStr
+		} else {
+			endStr = startStr
+		}
+	}
+	if startStr == "" && endStr == "" {
+		return fmt.Errorf("at least one of the bounds of the range must be given")
+	}
+
+	start := MinPageNumber
+	if startStr != "" {
+		start, err = f.parsePageNumber(ctx, startStr)
+		if err != nil {
+			return
+		}
+	}
+
+	var end uint
+	if endStr == "" {
+		end, err = f.LastPageNumber(ctx)
+	} else {
+		end, err = f.parsePageNumber(ctx, endStr)
+	}
+	if err != nil {
+		return
+	}
+
+	if start > end {
+		return fmt.Errorf("the first page (%d) comes after the last one (%d)", start, end)
+	}
+
+	for pageNumber := start; pageNumber <= end; pageNumber += step {
+		pageNumbers[pageNumber] = struct{}{}
+	}
+	return
+}
+
+// ParsePageRanges parses the page range specifications, each of which may be a comma-separated list of ranges of the
+// form `[first]..[last][:step]` or single page numbers; negative page numbers count from the end of the topic and `all`
+// stands for its last page. A specification consisting of a single page number `last` alone means `1..last`.
+func (f *Fetcher) ParsePageRanges(ctx context.Context, pageRanges []string) (pageNumbers map[uint]struct{}, err error) {
+	pageNumbers = map[uint]struct{}{}
+
+	for _, pageRangeList := range pageRanges {
+		pageRangesInList := strings.Split(pageRangeList, ",")
+		for _, pageRange := range pageRangesInList {
+			err = f.parsePageRange(ctx, strings.TrimSpace(pageRange), len(pageRangesInList) == 1, pageNumbers)
+			if err != nil {
+				err = fmt.Errorf("invalid page range specification %q: %v", pageRange, err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// SortPageNumbers returns the page numbers in the set in ascending order.
+func SortPageNumbers(pageNumbers map[uint]struct{}) (sortedPageNumbers []uint) {
+	for pageNumber := range pageNumbers {
+		sortedPageNumbers = append(sortedPageNumbers, pageNumber)
+	}
+	sort.Slice(sortedPageNumbers, func(i, j int) bool {
+		return sortedPageNumbers[i] < sortedPageNumbers[j]
+	})
+	return
+}
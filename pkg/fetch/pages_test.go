@@ -0,0 +1,142 @@
+package fetch_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// pageURLs are the URLs of the pages of a topic with 10 pages, so that its last page is known without fetching
+// anything.
+var pageURLs = func() (pageURLs []string) {
+	for pageNumber := 1; pageNumber <= 10; pageNumber++ {
+		pageURLs = append(pageURLs, fmt.Sprintf("http://forum.test/viewtopic.php?page=%d", pageNumber))
+	}
+	return
+}()
+
+func TestParsePageRanges(t *testing.T) {
+	tests := []struct {
+		pageRanges []string
+		want       []uint
+		wantErr    bool
+	}{
+		{pageRanges: nil, want: nil},
+		{pageRanges: []string{"3"}, want: []uint{1, 2, 3}},
+		{pageRanges: []string{"3", "5"}, want: []uint{1, 2, 3, 4, 5}},
+		{pageRanges: []string{"3,7"}, want: []uint{3, 7}},
+		{pageRanges: []string{"2..4, 9"}, want: []uint{2, 3, 4, 9}},
+		{pageRanges: []string{"3..3"}, want: []uint{3}},
+		{pageRanges: []string{"2..4"}, want: []uint{2, 3, 4}},
+		{pageRanges: []string{"8.."}, want: []uint{8, 9, 10}},
+		{pageRanges: []string{"..2"}, want: []uint{1, 2}},
+		{pageRanges: []string{"-2.."}, want: []uint{9, 10}},
+		{pageRanges: []string{"-1"}, want: []uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{pageRanges: []string{"1,-1"}, want: []uint{1, 10}},
+		{pageRanges: []string{"5..all"}, want: []uint{5, 6, 7, 8, 9, 10}},
+		{pageRanges: []string{"1..10:3"}, want: []uint{1, 4, 7, 10}},
+		{pageRanges: []string{"..10:4"}, want: []uint{1, 5, 9}},
+		{pageRanges: []string{"0"}, wantErr: true},
+		{pageRanges: []string{"1,0"}, wantErr: true},
+		{pageRanges: []string{"5..3"}, wantErr: true},
+		{pageRanges: []string{".."}, wantErr: true},
+		{pageRanges: []string{"1..5:0"}, wantErr: true},
+		{pageRanges: []string{"-11.."}, wantErr: true},
+		{pageRanges: []string{"three"}, wantErr: true},
+		{pageRanges: []string{"1,,2"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q", test.pageRanges), func(t *testing.T) {
+			fetcher := fetch.New(fetch.Config{PageURLs: pageURLs})
+			pageNumbers, err := fetcher.ParsePageRanges(context.Background(), test.pageRanges)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("got pages %v; want an error", fetch.SortPageNumbers(pageNumbers))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v; want pages %v", err, test.want)
+			}
+
+			if got := fetch.SortPageNumbers(pageNumbers); !slices.Equal(got, test.want) {
+				t.Errorf("got pages %v; want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// readDirNames returns the names of the entries of the directory.
+func readDirNames(t *testing.T, dir string) (names []string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return
+}
+
+func TestPlanDoesNotWrite(t *testing.T) {
+	requestedPageNumbers := map[uint]struct{}{1: {}}
+	wantPageNumbers := []uint{1, 2, 4, 5}
+
+	dir := t.TempDir()
+	for filename, content := range map[string]string{
+		archive.FailureListFilename:        "2\n4\n",
+		archive.FailureListFilename + ".0": "2\n",
+		archive.PendingListFilename:        "5\n",
+	} {
+		err := os.WriteFile(filepath.Join(dir, filename), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkPlan := func(wantAttempts map[uint]int) {
+		t.Helper()
+
+		namesBefore := readDirNames(t, dir)
+		plan, err := fetch.New(fetch.Config{PageURLs: pageURLs, TargetDir: dir}).Plan(requestedPageNumbers)
+		if err != nil {
+			t.Fatalf("planning failed: %v", err)
+		}
+		if namesAfter := readDirNames(t, dir); !slices.Equal(namesAfter, namesBefore) {
+			t.Errorf("got files %v after planning; want %v", namesAfter, namesBefore)
+		}
+
+		var pageNumbers []uint
+		for _, page := range plan {
+			pageNumbers = append(pageNumbers, page.Number)
+			if page.Attempt != wantAttempts[page.Number] {
+				t.Errorf("got attempt %d of page %d; want %d", page.Attempt, page.Number, wantAttempts[page.Number])
+			}
+		}
+		if !slices.Equal(pageNumbers, wantPageNumbers) {
+			t.Errorf("got planned pages %v; want %v", pageNumbers, wantPageNumbers)
+		}
+	}
+
+	// the legacy lists are read as they are, without being migrated
+	checkPlan(map[uint]int{1: 1, 2: 3, 4: 2, 5: 1})
+
+	// the state database is read without being changed
+	state, err := archive.OpenState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = state.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlan(map[uint]int{1: 1, 2: 3, 4: 2, 5: 1})
+}
@@ -0,0 +1,75 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+)
+
+// PostProcessor is run on every file of a page or resource once it has been stored, e.g. in order to scan or optimize
+// it; name is the slash-separated path of the file in the storage.
+type PostProcessor func(ctx context.Context, name string) error
+
+// WithPostProcessor makes the fetcher run the post-processor on every stored file in the background, at most the
+// given number of times at once (at least once). The fetcher waits for the post-processing in Wait, and its failures
+// are recorded in the report of the run.
+func WithPostProcessor(process PostProcessor, concurrency int) Option {
+	return func(f *Fetcher) {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+
+		f.postProcess = process
+		f.postProcessingSlots = make(chan struct{}, concurrency)
+	}
+}
+
+// postProcessFile starts the post-processing of the stored file, if a post-processor has been given, waiting for one of
+// the slots for it to become free.
+func (f *Fetcher) postProcessFile(ctx context.Context, name string) {
+	if f.postProcess == nil {
+		return
+	}
+
+	f.postProcessingSlots <- struct{}{}
+	f.postProcessors.Add(1)
+	// the post-processing outlives the page, so it is not cut short by the deadline of the page, only by the end of the
+	// run
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(f.runContext, cancel)
+	go func() {
+		defer func() {
+			stop()
+			cancel()
+			<-f.postProcessingSlots
+			f.postProcessors.Done()
+		}()
+
+		err := f.postProcess(ctx, name)
+		if err != nil {
+			f.logErrorf("post-processing of file %s failed: %v", name, err)
+		}
+		f.report.recordPostProcessing(name, err)
+	}()
+}
+
+// PostProcessingFailure describes a stored file whose post-processing failed.
+type PostProcessingFailure struct {
+	Filename string `json:"filename"`
+	Error    string `json:"error"`
+}
+
+func (report *Report) recordPostProcessing(name string, err error) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	if err != nil {
+		report.PostProcessingFailures = append(report.PostProcessingFailures, &PostProcessingFailure{
+			Filename: name,
+			Error:    redact.Text(fmt.Sprint(err)),
+		})
+	} else {
+		report.FilesPostProcessed++
+	}
+}
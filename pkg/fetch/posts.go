@@ -0,0 +1,96 @@
+package fetch
+
+import (
+	"io"
+	"sync"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"golang.org/x/net/html"
+)
+
+// trackedPageState holds the numbers of the pages whose posts have been tracked during the run, from which the posts
+// which have vanished are told at its end.
+type trackedPageState struct {
+	mutex       sync.Mutex
+	pageNumbers map[uint]struct{}
+}
+
+// trackPosts extracts the posts from the content of the page and records them in the state of the archive, logging
+// the edits and deletions which have been noticed.
+func (f *Fetcher) trackPosts(pageNumber uint, content io.Reader) {
+	doc, err := html.Parse(content)
+	if err != nil {
+		f.logWarningf("could not parse page %d to track its posts: %v", pageNumber, err)
+		return
+	}
+
+	var posts []*archive.TrackedPost
+	for _, post := range f.engine().ExtractPosts(doc) {
+		if post.ID == "" {
+			continue
+		}
+		posts = append(posts, &archive.TrackedPost{ID: post.ID, Author: post.Author, Text: post.Text})
+	}
+	if len(posts) == 0 {
+		return
+	}
+
+	changes, err := f.state.RecordPosts(pageNumber, posts)
+	if err != nil {
+		f.logErrorf("could not record the posts of page %d: %v", pageNumber, err)
+		return
+	}
+	f.logPostChanges(changes)
+
+	f.trackedPages.mutex.Lock()
+	defer f.trackedPages.mutex.Unlock()
+	if f.trackedPages.pageNumbers == nil {
+		f.trackedPages.pageNumbers = map[uint]struct{}{}
+	}
+	f.trackedPages.pageNumbers[pageNumber] = struct{}{}
+}
+
+// recordDeletedPosts records the deletions of the posts which have not been seen on any of the pages tracked during
+// the run, although they would have been.
+func (f *Fetcher) recordDeletedPosts() {
+	f.trackedPages.mutex.Lock()
+	pageNumbers := f.trackedPages.pageNumbers
+	f.trackedPages.pageNumbers = nil
+	f.trackedPages.mutex.Unlock()
+	if len(pageNumbers) == 0 {
+		return
+	}
+
+	changes, err := f.state.RecordDeletedPosts(pageNumbers)
+	if err != nil {
+		f.logErrorf("could not record the deleted posts: %v", err)
+		return
+	}
+	f.logPostChanges(changes)
+}
+
+func (f *Fetcher) logPostChanges(changes []*archive.PostChange) {
+	for _, change := range changes {
+		f.report.recordPostChange(change)
+		switch change.Kind {
+		case archive.PostEdited:
+			f.logInfof("Post %s by %s on page %d has been edited", change.Post, change.Author, change.Page)
+		case archive.PostDeleted:
+			f.logInfof("Post %s by %s on page %d has been deleted", change.Post, change.Author, change.Page)
+		case archive.PostRestored:
+			f.logInfof("Post %s by %s on page %d has been restored", change.Post, change.Author, change.Page)
+		}
+	}
+}
+
+func (report *Report) recordPostChange(change *archive.PostChange) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	switch change.Kind {
+	case archive.PostEdited:
+		report.PostsEdited++
+	case archive.PostDeleted:
+		report.PostsDeleted++
+	}
+}
@@ -0,0 +1,111 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// errResourceTooLarge is returned instead of fetching a resource which is larger than MaxResourceSize.
+var errResourceTooLarge = errors.New("resource too large")
+
+// isResourceSkipped reports whether the error means that the resource was deliberately not fetched, in which case the
+// reference to it is left pointing at its original URL.
+func isResourceSkipped(err error) bool {
+	return err == errResourceClassSkipped || err == errResourceTooLarge
+}
+
+// byteRangeKey is the key of the context value with the byte range requested in the Range header.
+type byteRangeKey struct{}
+
+// withByteRange returns a copy of the context in which the requests are for the given range of bytes, e.g. `bytes=0-0`.
+func withByteRange(ctx context.Context, byteRange string) context.Context {
+	return context.WithValue(ctx, byteRangeKey{}, byteRange)
+}
+
+// isAnyResourceClassSkipped reports whether the localization of any class of resources has been disabled.
+func (f *Fetcher) isAnyResourceClassSkipped() bool {
+	return f.config.SkipImages || f.config.SkipFonts || f.config.SkipMedia || f.config.PageRequisitesOnly
+}
+
+// needsPreflight reports whether the content type or size of the resource of the given class has to be known before it
+// is fetched in order to decide whether to fetch it.
+func (f *Fetcher) needsPreflight(class rewrite.ResourceClass) bool {
+	return f.config.MaxResourceSize > 0 || class == rewrite.ResourceClassUnknown && f.isAnyResourceClassSkipped()
+}
+
+// checkResourceAllowed returns errResourceClassSkipped or errResourceTooLarge if the content type or the size (-1 if
+// it is unknown) of the resource of the given class shows that it must not be fetched.
+func (f *Fetcher) checkResourceAllowed(class rewrite.ResourceClass, contentType string, size int64, resourceDescription string) error {
+	if class == rewrite.ResourceClassUnknown {
+		class = rewrite.ContentTypeResourceClass(contentType)
+		if !f.isResourceClassWanted(class) {
+			f.logDebugf("skipping %s of class %s", resourceDescription, class)
+			return errResourceClassSkipped
+		}
+	}
+	if f.config.MaxResourceSize > 0 && size > f.config.MaxResourceSize {
+		f.logDebugf("skipping %s of %d bytes, which is larger than the maximum size of resources", resourceDescription, size)
+		return errResourceTooLarge
+	}
+
+	return nil
+}
+
+// declaredSize returns the size of the content of the resource declared by the server, or -1 if it is unknown.
+func declaredSize(metadata *archive.ResourceMetadata) int64 {
+	size, err := strconv.ParseInt(metadata.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return size
+}
+
+// preflightResource learns the content type and size of the resource (-1 if it is unknown) without downloading its
+// content, through a HEAD request or, if the server does not allow them, a GET request for its first byte. It returns
+// an empty content type if neither succeeds, in which case the decision is left to the actual request.
+func (f *Fetcher) preflightResource(ctx context.Context, resourceURL *url.URL) (contentType string, size int64) {
+	size = -1
+
+	response, err := f.doRequest(ctx, http.MethodHead, resourceURL.String())
+	if err == nil {
+		response.Body.Close()
+		if response.StatusCode == http.StatusOK {
+			return response.Header.Get("Content-Type"), response.ContentLength
+		}
+		if response.StatusCode != http.StatusMethodNotAllowed && response.StatusCode != http.StatusNotImplemented {
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	response, err = f.doRequest(withByteRange(ctx, "bytes=0-0"), http.MethodGet, resourceURL.String())
+	if err != nil {
+		return
+	}
+	response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		// the total size follows the slash in `bytes 0-0/size`
+		if _, totalSize, ok := strings.Cut(response.Header.Get("Content-Range"), "/"); ok {
+			if parsedSize, err := strconv.ParseInt(totalSize, 10, 64); err == nil {
+				size = parsedSize
+			}
+		}
+	case http.StatusOK:
+		size = response.ContentLength
+	default:
+		return
+	}
+	return response.Header.Get("Content-Type"), size
+}
@@ -0,0 +1,129 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/preview"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"golang.org/x/net/html"
+)
+
+// maxPreviewContentLength is the number of bytes of a linked page or oEmbed response beyond which the rest is not read,
+// since the metadata of a page is at its start.
+const maxPreviewContentLength = 512 * 1024
+
+// bareLink is an external link in a page whose content is being written, which is bare if its text is its URL (perhaps
+// shortened by the forum) rather than a description of its target.
+type bareLink struct {
+	url         *url.URL
+	text        strings.Builder
+	hasElements bool
+}
+
+// newBareLink returns the link to the resolved URL if it points at another site over HTTP(S), or nil otherwise.
+func newBareLink(linkURL *url.URL, pageHostname string) *bareLink {
+	if linkURL.Scheme != "http" && linkURL.Scheme != "https" || !isThirdPartyHostname(linkURL, pageHostname) {
+		return nil
+	}
+
+	return &bareLink{url: linkURL}
+}
+
+// normalizeLinkText strips the parts of a URL which forums commonly omit from the text of bare links.
+func normalizeLinkText(text string) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "https://"), "http://")
+	text = strings.TrimPrefix(text, "www.")
+	return strings.TrimSuffix(text, "/")
+}
+
+// isBare reports whether the text of the link is its URL.
+func (link *bareLink) isBare() bool {
+	if link.hasElements {
+		return false
+	}
+
+	text := normalizeLinkText(link.text.String())
+	linkURLStr := normalizeLinkText(link.url.String())
+	if unescapedURLStr, err := url.PathUnescape(linkURLStr); err == nil {
+		linkURLStr = unescapedURLStr
+	}
+
+	for _, ellipsis := range []string{"…", "..."} {
+		if shortenedText, ok := strings.CutSuffix(text, ellipsis); ok {
+			return shortenedText != "" && strings.HasPrefix(linkURLStr, shortenedText)
+		}
+	}
+	return text != "" && text == linkURLStr
+}
+
+// getPreviewContent fetches the start of the content of the linked page or of its oEmbed response. Since links in old
+// topics are expected to be dead, failures are only logged as debug messages.
+func (f *Fetcher) getPreviewContent(ctx context.Context, urlStr string) (content []byte, mediaType string, err error) {
+	response, err := f.doRequest(ctx, http.MethodGet, urlStr)
+	if err != nil {
+		f.logDebugf("could not fetch %s for its preview: %v", urlStr, err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = &StatusError{StatusCode: response.StatusCode}
+		f.logDebugf("could not fetch %s for its preview: %v", urlStr, err)
+		return
+	}
+
+	mediaType, _, _ = mime.ParseMediaType(response.Header.Get("Content-Type"))
+	content, err = io.ReadAll(io.LimitReader(countingReadCloser{response.Body, f.report}, maxPreviewContentLength))
+	if err != nil {
+		f.logDebugf("could not read %s for its preview: %v", urlStr, err)
+	}
+	return
+}
+
+// linkPreviewCard returns the HTML of the preview card of the linked page, with its thumbnail fetched locally, or an
+// empty string if it has no preview.
+func (f *Fetcher) linkPreviewCard(linkURL *url.URL, context *resourceFetcherContext) string {
+	content, mediaType, err := f.getPreviewContent(context.ctx, linkURL.String())
+	if err != nil || mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return ""
+	}
+
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return ""
+	}
+
+	linkPreview := preview.Parse(doc, linkURL)
+	if linkPreview.OEmbedURL != "" {
+		content, _, err := f.getPreviewContent(context.ctx, linkPreview.OEmbedURL)
+		if err == nil {
+			err = linkPreview.MergeOEmbed(content)
+			if err != nil {
+				f.logDebugf("could not parse oEmbed response %s: %v", linkPreview.OEmbedURL, err)
+			}
+		}
+	}
+	if linkPreview.IsEmpty() {
+		return ""
+	}
+
+	var imageReference string
+	if imageURL, err := url.Parse(linkPreview.ImageURL); err == nil && linkPreview.ImageURL != "" {
+		imageContext := *context
+		imageContext.resourceClass = rewrite.ResourceClassImage
+		imageContext.replaceResourceReference = func(reference string) {
+			imageReference = reference
+		}
+		f.fetchResourceFromLinkIfNecessary(imageURL, &imageContext)
+	}
+
+	f.logDebugf("Captured preview of %s", linkURL)
+	return linkPreview.Card(imageReference)
+}
@@ -0,0 +1,69 @@
+package fetch
+
+import "sync"
+
+// DefaultPageConcurrency is the number of pages fetched at once if Config.PageConcurrency is not set.
+const DefaultPageConcurrency = 4
+
+// pageQueueState holds the pages which have been scheduled but not started yet, which are handed to at most
+// Config.PageConcurrency workers at once in the order in which they have been scheduled, so that the order of the plan
+// holds however many pages there are.
+type pageQueueState struct {
+	mutex   sync.Mutex
+	pending []queuedPage
+	running int
+}
+
+// queuedPage is an attempt to fetch a page which waits in the queue for a worker.
+type queuedPage struct {
+	number  uint
+	attempt int
+}
+
+// pageConcurrency returns the maximum number of pages fetched at once.
+func (f *Fetcher) pageConcurrency() int {
+	if f.config.PageConcurrency < 1 {
+		return DefaultPageConcurrency
+	}
+
+	return f.config.PageConcurrency
+}
+
+// enqueuePage appends an attempt to fetch a page to the queue and starts it right away if a worker is free. The page
+// has to have been added to f.workers already.
+func (f *Fetcher) enqueuePage(pageNumber uint, attempt int) {
+	f.pageQueue.mutex.Lock()
+	defer f.pageQueue.mutex.Unlock()
+
+	f.pageQueue.pending = append(f.pageQueue.pending, queuedPage{number: pageNumber, attempt: attempt})
+	f.dispatchPages()
+}
+
+// dispatchPages starts the pages at the head of the queue while there are free workers for them. The mutex of the
+// queue has to be held.
+func (f *Fetcher) dispatchPages() {
+	for f.pageQueue.running < f.pageConcurrency() && len(f.pageQueue.pending) > 0 {
+		page := f.pageQueue.pending[0]
+		f.pageQueue.pending = f.pageQueue.pending[1:]
+		f.pageQueue.running++
+		go f.runQueuedPage(page)
+	}
+}
+
+// runQueuedPage fetches a page taken from the queue, unless the run has ended in the meantime, in which case the page
+// is left pending, and then hands the worker over to the next page in the queue.
+func (f *Fetcher) runQueuedPage(page queuedPage) {
+	if f.runContext.Err() != nil {
+		f.logDebugf("Leaving page %d pending since the run has ended", page.number)
+		f.report.recordPendingPage(page.number)
+		f.workers.Done()
+	} else {
+		f.fetchPage(f.runContext, page.number, page.attempt)
+	}
+
+	f.pageQueue.mutex.Lock()
+	defer f.pageQueue.mutex.Unlock()
+
+	f.pageQueue.running--
+	f.dispatchPages()
+}
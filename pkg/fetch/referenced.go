@@ -0,0 +1,95 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// referencedTopicState holds the topics referenced by the posts whose first page is archived during the run, of which
+// there are at most Config.ReferencedTopics.
+type referencedTopicState struct {
+	mutex     sync.Mutex
+	topicURLs map[string]struct{}
+	isCapped  bool
+}
+
+// referencedTopicURL returns the URL of the first page of the other topic of the forum to which the resolved URL links,
+// if the topics referenced by the posts are archived and either the topic has already been referenced or the number of
+// the referenced topics has not reached its cap yet.
+func (f *Fetcher) referencedTopicURL(linkURL *url.URL) (*url.URL, bool) {
+	if f.config.ReferencedTopics == 0 {
+		return nil, false
+	}
+	locator, ok := f.engine().(engine.TopicLocator)
+	if !ok {
+		return nil, false
+	}
+
+	topicURL, ok := locator.LinkedTopicURL(linkURL)
+	if !ok {
+		return nil, false
+	}
+	firstPageURL, err := url.Parse(f.PageURL(MinPageNumber))
+	if err != nil || topicURL.Host != firstPageURL.Host {
+		return nil, false
+	}
+	if ownTopicURL, ok := locator.LinkedTopicURL(firstPageURL); ok && ownTopicURL.String() == topicURL.String() {
+		return nil, false
+	}
+	if pageLocator, ok := f.engine().(engine.PageLocator); ok {
+		if _, ok := pageLocator.PageNumber(f.topic(), linkURL); ok {
+			return nil, false
+		}
+	}
+
+	f.referencedTopics.mutex.Lock()
+	defer f.referencedTopics.mutex.Unlock()
+
+	if _, ok := f.referencedTopics.topicURLs[topicURL.String()]; ok {
+		return topicURL, true
+	}
+	if uint(len(f.referencedTopics.topicURLs)) >= f.config.ReferencedTopics {
+		if !f.referencedTopics.isCapped {
+			f.referencedTopics.isCapped = true
+			f.logInfof("Archived %d referenced topics; linking to the others as they are", len(f.referencedTopics.topicURLs))
+		}
+		return nil, false
+	}
+	if f.referencedTopics.topicURLs == nil {
+		f.referencedTopics.topicURLs = map[string]struct{}{}
+	}
+	f.referencedTopics.topicURLs[topicURL.String()] = struct{}{}
+	return topicURL, true
+}
+
+// writeReferencedTopicPage writes the content of the first page of a referenced topic with a `base` element added to
+// its head, so that the resources of the archived copy, which are not localized, are loaded from the forum.
+func writeReferencedTopicPage(w io.Writer, content io.Reader, pageURL *url.URL) (err error) {
+	tokenizer := html.NewTokenizer(content)
+	isBaseInserted := false
+	for tokenizer.Next() != html.ErrorToken {
+		_, err = w.Write(tokenizer.Raw())
+		if err != nil {
+			return
+		}
+
+		token := tokenizer.Token()
+		if !isBaseInserted && token.Type == html.StartTagToken && token.DataAtom == atom.Head {
+			_, err = fmt.Fprintf(w, `<base href="%s">`, html.EscapeString(pageURL.String()))
+			if err != nil {
+				return
+			}
+			isBaseInserted = true
+		}
+	}
+	if err = tokenizer.Err(); err != io.EOF {
+		return
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package fetch
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/metrics"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+)
+
+const (
+	pageStatusSucceeded = "succeeded"
+	pageStatusFailed    = "failed"
+)
+
+// PageReport describes the outcome of the fetching of a page.
+type PageReport struct {
+	Number          uint    `json:"number"`
+	Status          string  `json:"status"`
+	Attempt         int     `json:"attempt"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Report summarizes the outcome of a run in a form which can be consumed by wrapper scripts.
+type Report struct {
+	mutex sync.Mutex
+	// metrics receives the counts as they are recorded (see WithMetrics)
+	metrics metrics.Recorder
+
+	StartedAt        time.Time     `json:"startedAt"`
+	FinishedAt       time.Time     `json:"finishedAt"`
+	DurationSeconds  float64       `json:"durationSeconds"`
+	PagesAttempted   int           `json:"pagesAttempted"`
+	PagesSucceeded   int           `json:"pagesSucceeded"`
+	PagesFailed      int           `json:"pagesFailed"`
+	PagesSkipped     int           `json:"pagesSkipped"`
+	PagesPending     int           `json:"pagesPending"`
+	Retries          int           `json:"retries"`
+	ResourcesFetched int           `json:"resourcesFetched"`
+	ResourcesFailed  int           `json:"resourcesFailed"`
+	BytesDownloaded  int64         `json:"bytesDownloaded"`
+	FailedPages      []uint        `json:"failedPages"`
+	PendingPages     []uint        `json:"pendingPages"`
+	Pages            []*PageReport `json:"pages"`
+	// FilesPostProcessed and PostProcessingFailures describe the outcome of the post-processing of the stored files;
+	// they are only set if a post-processor has been given.
+	FilesPostProcessed     int                      `json:"filesPostProcessed,omitempty"`
+	PostProcessingFailures []*PostProcessingFailure `json:"postProcessingFailures,omitempty"`
+	// RecoveredResources are the images which were fetched from one of their fallback sources; they are only set if
+	// the fallbacks are enabled.
+	RecoveredResources []*RecoveredResource `json:"recoveredResources,omitempty"`
+	// PostsEdited and PostsDeleted count the edits and deletions of the posts noticed during the run; they are only
+	// set if the posts are tracked.
+	PostsEdited  int `json:"postsEdited,omitempty"`
+	PostsDeleted int `json:"postsDeleted,omitempty"`
+	// ImagesStripped counts the JPEG images whose EXIF metadata has been removed before they were stored; it is only
+	// set if the metadata is stripped.
+	ImagesStripped int `json:"imagesStripped,omitempty"`
+}
+
+func newReport() *Report {
+	return &Report{
+		metrics:      metrics.Nop{},
+		StartedAt:    time.Now(),
+		FailedPages:  []uint{},
+		PendingPages: []uint{},
+		Pages:        []*PageReport{},
+	}
+}
+
+func (report *Report) recordPage(pageNumber uint, attempt int, startTime time.Time, err error) {
+	page := &PageReport{
+		Number:          pageNumber,
+		Status:          pageStatusSucceeded,
+		Attempt:         attempt,
+		DurationSeconds: time.Since(startTime).Seconds(),
+	}
+
+	report.metrics.Observe(metrics.PageDuration, page.DurationSeconds)
+
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.PagesAttempted++
+	if attempt > 1 {
+		report.Retries++
+		report.metrics.Add(metrics.PageRetries, 1)
+	}
+	if err != nil {
+		page.Status = pageStatusFailed
+		page.Error = redact.Text(err.Error())
+		report.PagesFailed++
+		report.FailedPages = append(report.FailedPages, pageNumber)
+		report.metrics.Add(metrics.PagesFailed, 1)
+	} else {
+		report.PagesSucceeded++
+		report.metrics.Add(metrics.PagesFetched, 1)
+	}
+	report.Pages = append(report.Pages, page)
+}
+
+func (report *Report) recordSkippedPage() {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.PagesSkipped++
+	report.metrics.Add(metrics.PagesSkipped, 1)
+}
+
+// recordPendingPage records a page which has not been started because the download budget had been exhausted or the
+// run ended while the page was waiting for the active hours or for a worker.
+func (report *Report) recordPendingPage(pageNumber uint) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.PagesPending++
+	report.PendingPages = append(report.PendingPages, pageNumber)
+}
+
+func (report *Report) recordResource(err error) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	if err != nil {
+		report.ResourcesFailed++
+		report.metrics.Add(metrics.ResourcesFailed, 1)
+	} else {
+		report.ResourcesFetched++
+		report.metrics.Add(metrics.ResourcesFetched, 1)
+	}
+}
+
+func (report *Report) addDownloadedBytes(count int64) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.BytesDownloaded += count
+	report.metrics.Add(metrics.BytesDownloaded, float64(count))
+}
+
+// Usage returns the number of bytes downloaded and the number of resources fetched so far.
+func (report *Report) Usage() (bytesDownloaded int64, resourcesFetched int) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	return report.BytesDownloaded, report.ResourcesFetched
+}
+
+// Finish records the end of the run and sorts the pages in the report.
+func (report *Report) Finish() {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	report.FinishedAt = time.Now()
+	report.DurationSeconds = report.FinishedAt.Sub(report.StartedAt).Seconds()
+	sort.Slice(report.FailedPages, func(i, j int) bool {
+		return report.FailedPages[i] < report.FailedPages[j]
+	})
+	sort.Slice(report.PendingPages, func(i, j int) bool {
+		return report.PendingPages[i] < report.PendingPages[j]
+	})
+	sort.Slice(report.Pages, func(i, j int) bool {
+		return report.Pages[i].Number < report.Pages[j].Number
+	})
+}
+
+// countingReadCloser adds the number of bytes read through it to the report.
+type countingReadCloser struct {
+	io.ReadCloser
+	report *Report
+}
+
+func (reader countingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = reader.ReadCloser.Read(p)
+	reader.report.addDownloadedBytes(int64(n))
+	return
+}
@@ -0,0 +1,411 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type resourceFetcherContext struct {
+	ctx                      context.Context
+	pageNumber               uint
+	baseURL                  *url.URL
+	targetHostDir            string // slash-separated path of the directory of the host in the storage
+	dirpath                  string
+	fetchedResources         map[string]string     // map from the resource URI to the content type of the resource
+	resourceClass            rewrite.ResourceClass // class of the linked resource as determined from the context of the link
+	replaceResourceReference func(reference string)
+	isReferencedTopic        bool // whether the resource is the first page of a topic referenced by the page
+	isFrameDocument          bool // whether the resource is the document of a frame of the page
+	frameDepth               int  // number of the frame documents within which the resource is referenced
+	// map from the URI of an image embedded in the page to the name of its thumbnail, if the thumbnails of the images
+	// of the resource are wanted
+	thumbnails                map[string]string
+	replaceThumbnailReference func(thumbnailReference, fullSizeReference string)
+}
+
+// pageHostname returns the hostname of the page from which the resources are referred to.
+func (context *resourceFetcherContext) pageHostname() string {
+	return path.Base(context.targetHostDir)
+}
+
+// errResourceClassSkipped is returned instead of fetching a resource whose class has been disabled.
+var errResourceClassSkipped = errors.New("resource class skipped")
+
+// isResourceClassWanted reports whether resources of the given class should be localized; unknown ones are wanted
+// until their content type is known.
+func (f *Fetcher) isResourceClassWanted(class rewrite.ResourceClass) bool {
+	switch class {
+	case rewrite.ResourceClassImage:
+		return !f.config.SkipImages
+	case rewrite.ResourceClassFont:
+		return !f.config.SkipFonts
+	case rewrite.ResourceClassMedia:
+		return !f.config.SkipMedia && !f.config.PageRequisitesOnly
+	case rewrite.ResourceClassOther:
+		return !f.config.PageRequisitesOnly
+	}
+
+	return true
+}
+
+// isFileTransferURL reports whether the URL refers to a file (rather than a directory) on an FTP or SFTP server.
+func isFileTransferURL(linkURL *url.URL) bool {
+	return (linkURL.Scheme == "ftp" || linkURL.Scheme == "sftp") && linkURL.Path != "" && !strings.HasSuffix(linkURL.Path, "/")
+}
+
+// checkDiskFull records whether the error indicates that there is no space left on the device.
+func (f *Fetcher) checkDiskFull(err error) {
+	if errors.Is(err, syscall.ENOSPC) {
+		f.hasDiskFullFailure.Store(true)
+	}
+}
+
+func (f *Fetcher) openFileForResourceContent(resourceURI *url.URL, resourceDescription, contentType, targetHostDir string) (file io.WriteCloser, name string, err error) {
+	name = path.Join(targetHostDir, f.filenames().LocalPath(resourceURI, contentType))
+	file, err = f.createFileForResourceContent(name, resourceDescription)
+	return
+}
+
+func (f *Fetcher) createFileForResourceContent(name, resourceDescription string) (file io.WriteCloser, err error) {
+	file, err = f.storage.Create(name)
+	if errors.Is(err, ErrPathOutsideStorage) || errors.Is(err, archive.ErrSymlink) {
+		f.logErrorf("refusing to write the content of %s: %v", resourceDescription, err)
+		return
+	}
+	if err != nil {
+		f.checkDiskFull(err)
+		f.logErrorf("could not create file %s in which to write the content of %s", name, resourceDescription)
+		return
+	}
+
+	return
+}
+
+func (f *Fetcher) recordFetchedResource(resourceName string, metadata *archive.ResourceMetadata) {
+	if f.config.SaveMetadata {
+		metadataName := resourceName + archive.MetadataFilenameSuffix
+		metadataFile, err := f.storage.Create(metadataName)
+		if err != nil {
+			f.logErrorf("could not create metadata file %s", metadataName)
+		} else {
+			err = archive.WriteMetadata(metadataFile, metadata)
+			metadataFile.Close()
+			if err != nil {
+				f.logErrorf("could not write metadata file %s", metadataName)
+			}
+		}
+	}
+
+	if f.urlMap != nil {
+		err := f.urlMap.Record(filepath.Join(f.config.TargetDir, filepath.FromSlash(resourceName)), metadata)
+		if err != nil {
+			f.logErrorf("could not record the original URL of %s", resourceName)
+		}
+	}
+}
+
+func (f *Fetcher) fetchResourceFromLinkIfNecessary(linkURI *url.URL, context *resourceFetcherContext) (ok bool) {
+	originalReference := linkURI.String()
+	resourceDescription := f.translatef("resource %s", originalReference)
+
+	if linkURI.Opaque == "" {
+		if linkURI.Path == "" {
+			return
+		}
+
+		linkURI = context.baseURL.ResolveReference(linkURI)
+	}
+	linkURI = f.stripParameters(linkURI)
+	linkURI = f.canonicalizeHost(linkURI)
+
+	if isThirdPartyHostname(linkURI, context.pageHostname()) {
+		if matchAnyDomain(f.config.BlockDomains, linkURI.Hostname()) {
+			f.logDebugf("dropping %s from a domain blocked by -block-domain", resourceDescription)
+			context.replaceResourceReference(droppedResourceReference)
+			return true
+		}
+		if len(f.config.AllowDomains) > 0 && !matchAnyDomain(f.config.AllowDomains, linkURI.Hostname()) {
+			f.logDebugf("skipping %s from a domain not allowed by -allow-domain", resourceDescription)
+			context.replaceResourceReference(linkURI.String())
+			return true
+		}
+	}
+	if !f.isResourceURLAccepted(linkURI.String()) {
+		f.logDebugf("skipping %s filtered out by -accept or -reject", resourceDescription)
+		if linkURI.Opaque != "" {
+			return
+		}
+
+		context.replaceResourceReference(linkURI.String())
+		return true
+	}
+	if !f.isResourceClassWanted(context.resourceClass) {
+		f.logDebugf("skipping %s of class %s", resourceDescription, context.resourceClass)
+		if linkURI.Opaque != "" {
+			return
+		}
+
+		context.replaceResourceReference(linkURI.String())
+		return true
+	}
+
+	contentType, wasResourceFetched := context.fetchedResources[linkURI.String()]
+	if !wasResourceFetched {
+		var err error
+		contentType, err = f.getAndWriteResourceToFile(linkURI, resourceDescription, context)
+		if isResourceSkipped(err) && linkURI.Opaque == "" {
+			context.replaceResourceReference(linkURI.String())
+			return true
+		}
+		if err != nil {
+			return
+		}
+
+		context.fetchedResources[linkURI.String()] = contentType
+	}
+
+	relativeReference, ok := f.filenames().RelativeReference(linkURI, context.dirpath, contentType)
+	if !ok {
+		f.logErrorf("could not determine relative path to resource %s", linkURI.String())
+		return
+	}
+
+	reference := f.rewriteReference(originalReference, linkURI, relativeReference, resourceDescription)
+	context.replaceResourceReference(reference)
+	if thumbnailName, ok := context.thumbnails[linkURI.String()]; ok {
+		thumbnailReference, err := f.referenceFromPage(thumbnailName, context)
+		if err != nil {
+			f.logErrorf("could not determine relative path to the thumbnail of %s", linkURI.String())
+			return true
+		}
+
+		context.replaceThumbnailReference(thumbnailReference, reference)
+	}
+	return true
+}
+
+// referenceFromPage returns the reference to the file at the slash-separated path relative to the directory of the
+// archive from the page of the context.
+func (f *Fetcher) referenceFromPage(name string, context *resourceFetcherContext) (reference string, err error) {
+	pageDir := path.Join(context.targetHostDir, f.filenames().LocalPath(&url.URL{Path: filepath.ToSlash(context.dirpath)}, ""))
+	relativePath, err := filepath.Rel(filepath.FromSlash(pageDir), filepath.FromSlash(name))
+	if err != nil {
+		return
+	}
+
+	return (&url.URL{Path: filepath.ToSlash(relativePath)}).String(), nil
+}
+
+// rewriteReference returns the reference which replaces the original one to the resource at the resolved URL, which is
+// the relative reference to its local copy unless the rewriter decides otherwise.
+func (f *Fetcher) rewriteReference(originalReference string, resolvedURL *url.URL, relativeReference, resourceDescription string) string {
+	if f.rewriter == nil {
+		return relativeReference
+	}
+
+	replacement, ok := f.rewriter.Rewrite(&rewrite.Reference{
+		Original:  originalReference,
+		Resolved:  resolvedURL,
+		LocalPath: relativeReference,
+	})
+	if !ok {
+		f.logDebugf("rewrite of the reference to %s vetoed", resourceDescription)
+		replacement = resolvedURL.String()
+	}
+	return replacement
+}
+
+func (f *Fetcher) fetchLinkedResourcesInCSS(css []byte, context *resourceFetcherContext) (rewrittenCSS []byte) {
+	return rewrite.CSS(css, func(linkURIStr string) (reference string, ok bool) {
+		linkURI, err := url.Parse(linkURIStr)
+		if err != nil {
+			f.logErrorf("could not parse URL of resource %s", linkURIStr)
+			return
+		}
+
+		fullContext := *context
+		fullContext.replaceResourceReference = func(replacement string) {
+			reference = replacement
+		}
+		ok = f.fetchResourceFromLinkIfNecessary(linkURI, &fullContext)
+		return
+	})
+}
+
+func (f *Fetcher) getAndWriteResourceToFile(resourceURL *url.URL, resourceDescription string, parentContext *resourceFetcherContext) (contentType string, err error) {
+	ctx, span := f.tracer.Start(parentContext.ctx, "fetch.resource", trace.WithAttributes(
+		attribute.Int64("page.number", int64(parentContext.pageNumber)),
+		attribute.String("url.full", redact.URL(resourceURL).String()),
+	))
+
+	var name string
+	defer func() {
+		if err != nil && !isResourceSkipped(err) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.String("http.response.header.content-type", contentType))
+		span.End()
+
+		if isResourceSkipped(err) {
+			return
+		}
+		f.report.recordResource(err)
+		if err != nil {
+			f.recordResourceFailure(parentContext.pageNumber, resourceURL.String(), err)
+			f.notifyError(&ErrorEvent{
+				PageNumber: parentContext.pageNumber,
+				URL:        resourceURL.String(),
+				Err:        err,
+			})
+			return
+		}
+
+		f.notifyResourceFetched(&ResourceEvent{
+			PageNumber:  parentContext.pageNumber,
+			URL:         resourceURL.String(),
+			Filename:    name,
+			ContentType: contentType,
+		})
+		f.postProcessFile(ctx, name)
+	}()
+
+	targetHostDir := parentContext.targetHostDir
+
+	if f.needsPreflight(parentContext.resourceClass) {
+		preflightCtx := ctx
+		if !f.config.OmitReferer {
+			preflightCtx = withReferer(ctx, parentContext.baseURL)
+		}
+		// the resource is skipped before its content is downloaded if its content type and size are already known
+		if preflightContentType, size := f.preflightResource(preflightCtx, resourceURL); preflightContentType != "" || size >= 0 {
+			err = f.checkResourceAllowed(parentContext.resourceClass, preflightContentType, size, resourceDescription)
+			if err != nil {
+				return preflightContentType, err
+			}
+		}
+	}
+
+	contentBody, metadata, err := f.getResource(ctx, resourceURL, resourceDescription, parentContext)
+	if err != nil {
+		return
+	}
+	defer contentBody.Close()
+
+	contentType = metadata.ContentType
+	err = f.checkResourceAllowed(parentContext.resourceClass, contentType, declaredSize(metadata), resourceDescription)
+	if err != nil {
+		return
+	}
+	if f.isConvertedImageType(contentType) {
+		var content []byte
+		content, metadata, err = f.convertImageResource(resourceURL, resourceDescription, contentBody, metadata, targetHostDir)
+		if err != nil {
+			return
+		}
+		// the references to the image are rewritten to the converted copy, which is named after its content type
+		contentType = metadata.ContentType
+		contentBody = io.NopCloser(bytes.NewReader(content))
+	}
+	if f.config.StripEXIF && isJPEG(contentType) {
+		var content []byte
+		content, metadata, err = f.stripImageMetadata(resourceDescription, contentBody, metadata)
+		if err != nil {
+			return
+		}
+		contentBody = io.NopCloser(bytes.NewReader(content))
+	}
+
+	var file io.WriteCloser
+	file, name, err = f.openFileForResourceContent(resourceURL, resourceDescription, contentType, targetHostDir)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	defer f.recordFetchedResource(name, metadata)
+
+	if strings.HasPrefix(contentType, "text/css") {
+		var content []byte
+		content, err = io.ReadAll(contentBody)
+		if err != nil {
+			f.logErrorf("could not read the content of %s successfully", resourceDescription)
+			return
+		}
+
+		rewriteCtx, rewriteSpan := f.tracer.Start(ctx, "fetch.rewrite")
+		context := &resourceFetcherContext{
+			ctx:              rewriteCtx,
+			pageNumber:       parentContext.pageNumber,
+			baseURL:          resourceURL,
+			targetHostDir:    targetHostDir,
+			dirpath:          filepath.Dir(filepath.FromSlash(resourceURL.Path)),
+			fetchedResources: parentContext.fetchedResources,
+		}
+		content = f.fetchLinkedResourcesInCSS(content, context)
+		rewriteSpan.End()
+
+		_, writeSpan := f.tracer.Start(ctx, "fetch.write")
+		_, err = file.Write(content)
+		writeSpan.End()
+	} else if parentContext.isFrameDocument && parentContext.frameDepth < maxFrameDepth && strings.HasPrefix(contentType, "text/html") {
+		rewriteCtx, rewriteSpan := f.tracer.Start(ctx, "fetch.rewrite")
+		context := &resourceFetcherContext{
+			ctx:              rewriteCtx,
+			pageNumber:       parentContext.pageNumber,
+			baseURL:          resourceURL,
+			targetHostDir:    targetHostDir,
+			dirpath:          filepath.Dir(filepath.FromSlash(resourceURL.Path)),
+			fetchedResources: parentContext.fetchedResources,
+			frameDepth:       parentContext.frameDepth + 1,
+		}
+		// the frame document is rewritten while it is being written
+		err = f.rewriteHTML(file, contentBody, context, true)
+		rewriteSpan.End()
+	} else if parentContext.isReferencedTopic && !f.config.HardenOffline && strings.HasPrefix(contentType, "text/html") {
+		_, writeSpan := f.tracer.Start(ctx, "fetch.write")
+		err = writeReferencedTopicPage(file, contentBody, resourceURL)
+		writeSpan.End()
+	} else {
+		// the content of an image which may need a thumbnail is kept while it is being written
+		var thumbnailedContent bytes.Buffer
+		var contentReader io.Reader = contentBody
+		isThumbnailed := f.makeThumbnail != nil && parentContext.thumbnails != nil && strings.HasPrefix(contentType, "image/")
+		if isThumbnailed {
+			contentReader = io.TeeReader(contentBody, &thumbnailedContent)
+		}
+
+		// the body is read while it is being written, so the span includes the download of the rest of it
+		_, writeSpan := f.tracer.Start(ctx, "fetch.write")
+		contentBodyReader := bufio.NewReader(contentReader)
+		_, err = contentBodyReader.WriteTo(file)
+		writeSpan.End()
+
+		if err == nil && isThumbnailed {
+			if thumbnailName := f.writeThumbnail(name, resourceDescription, thumbnailedContent.Bytes()); thumbnailName != "" {
+				parentContext.thumbnails[resourceURL.String()] = thumbnailName
+			}
+		}
+	}
+	if err != nil {
+		f.checkDiskFull(err)
+		f.logErrorf("could not write the content of %s in file %s successfully", resourceDescription, name)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,131 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+)
+
+// scrollState holds the posts of a topic which have been loaded so far from the JSON endpoint of an engine which
+// implements engine.Scroller, which is walked in sequence however the pages are scheduled.
+type scrollState struct {
+	mutex       sync.Mutex
+	posts       []*engine.ScrolledPost
+	cursor      string
+	isExhausted bool
+	postCount   uint
+}
+
+// scroller returns the engine of the forum as an engine.Scroller if it loads the posts of topics as the user scrolls.
+func (f *Fetcher) scroller() (scroller engine.Scroller, ok bool) {
+	if f.config.PageURLs != nil {
+		return
+	}
+
+	scroller, ok = f.engine().(engine.Scroller)
+	return
+}
+
+// loadScrolledPosts loads batches of posts until at least count of them have been loaded or the topic is exhausted,
+// and returns the posts loaded so far along with the number of posts in the topic, or 0 if it is not known yet.
+func (f *Fetcher) loadScrolledPosts(ctx context.Context, scroller engine.Scroller, count uint) (posts []*engine.ScrolledPost, postCount uint, err error) {
+	f.scroll.mutex.Lock()
+	defer f.scroll.mutex.Unlock()
+
+	for !f.scroll.isExhausted && uint(len(f.scroll.posts)) < count {
+		batchURL := scroller.BatchURL(f.topic(), f.scroll.cursor)
+		contentReader, _, err := f.Get(withAPIRequest(ctx), batchURL, f.translatef("batch of posts"))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		content, err := io.ReadAll(contentReader)
+		contentReader.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not read batch of posts from %s: %w", batchURL, err)
+		}
+
+		batch, err := scroller.ParseBatch(f.topic(), f.scroll.cursor, content)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not parse batch of posts from %s: %w", batchURL, err)
+		}
+
+		f.scroll.posts = append(f.scroll.posts, batch.Posts...)
+		f.scroll.cursor = batch.NextCursor
+		f.scroll.isExhausted = batch.NextCursor == ""
+		if batch.PostCount != 0 {
+			f.scroll.postCount = batch.PostCount
+		}
+		f.logDebugf("Loaded %d posts of the topic so far", len(f.scroll.posts))
+	}
+
+	postCount = f.scroll.postCount
+	if f.scroll.isExhausted {
+		postCount = uint(len(f.scroll.posts))
+	}
+	return f.scroll.posts, postCount, nil
+}
+
+// scrolledPageCount returns the number of pages materialized from the posts of the topic, loading all of them unless
+// the endpoint tells how many there are.
+func (f *Fetcher) scrolledPageCount(ctx context.Context, scroller engine.Scroller) (pageCount uint, err error) {
+	postStep := max(f.config.PostStep, 1)
+	_, postCount, err := f.loadScrolledPosts(ctx, scroller, 1)
+	if err != nil {
+		return
+	}
+
+	if postCount == 0 {
+		_, postCount, err = f.loadScrolledPosts(ctx, scroller, ^uint(0))
+		if err != nil {
+			return
+		}
+	}
+
+	return (postCount + postStep - 1) / postStep, nil
+}
+
+// getScrolledPage materializes the page with the given number from the posts of the topic, of which it contains
+// PostStep, and returns its content along with metadata describing it as an HTML document.
+func (f *Fetcher) getScrolledPage(ctx context.Context, scroller engine.Scroller, pageNumber uint, pageURLStr string) (contentReader io.ReadCloser, metadata *archive.ResourceMetadata, err error) {
+	postStep := max(f.config.PostStep, 1)
+	firstPostIndex := (pageNumber - MinPageNumber) * postStep
+	posts, _, err := f.loadScrolledPosts(ctx, scroller, firstPostIndex+postStep)
+	if err != nil {
+		f.logErrorf("could not load the posts of page %d: %v", pageNumber, err)
+		return
+	}
+	if firstPostIndex >= uint(len(posts)) {
+		err = fmt.Errorf("the topic has only %d posts", len(posts))
+		f.logErrorf("could not materialize page %d: %v", pageNumber, err)
+		return
+	}
+
+	content := scroller.RenderPage(f.topic(), pageNumber, posts[firstPostIndex:min(firstPostIndex+postStep, uint(len(posts)))])
+	contentReader = io.NopCloser(strings.NewReader(content))
+	metadata = &archive.ResourceMetadata{
+		URL:         pageURLStr,
+		Status:      http.StatusOK,
+		ContentType: "text/html; charset=utf-8",
+		FetchedAt:   time.Now().UTC(),
+		Header:      http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+	}
+	return
+}
+
+// getPage fetches the page with the given number from its URL or, if the engine loads the posts as the user scrolls,
+// materializes it from them.
+func (f *Fetcher) getPage(ctx context.Context, pageNumber uint, pageURLStr, description string) (contentReader io.ReadCloser, metadata *archive.ResourceMetadata, err error) {
+	if scroller, ok := f.scroller(); ok {
+		return f.getScrolledPage(ctx, scroller, pageNumber, pageURLStr)
+	}
+
+	return f.Get(ctx, pageURLStr, description)
+}
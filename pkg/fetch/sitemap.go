@@ -0,0 +1,171 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+)
+
+// maxSitemaps is the largest number of sitemaps which are read while discovering the pages of a topic, as the sitemap
+// indexes of big forums list thousands of them.
+const maxSitemaps = 500
+
+// sitemap is either a set of URLs or an index of other sitemaps (see https://www.sitemaps.org/protocol.html).
+type sitemap struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// ErrNoPagesInSitemaps is returned when the sitemaps of a forum do not list any page of the topic.
+var ErrNoPagesInSitemaps = errors.New("the sitemaps of the forum do not list any page of the topic")
+
+// sitemapURLs returns the URLs of the sitemaps of the host of the topic: the ones declared in its robots.txt or else
+// the conventional one at its root.
+func (f *Fetcher) sitemapURLs(ctx context.Context) (sitemapURLs []string, err error) {
+	topicURL, err := url.Parse(f.config.URL)
+	if err != nil || topicURL.Host == "" {
+		return nil, fmt.Errorf("could not parse the URL of the topic %s", f.config.URL)
+	}
+	rootURL := &url.URL{Scheme: topicURL.Scheme, Host: topicURL.Host, Path: "/"}
+
+	contentReader, _, err := f.Get(ctx, rootURL.ResolveReference(&url.URL{Path: "robots.txt"}).String(), "robots.txt")
+	if err == nil {
+		defer contentReader.Close()
+
+		scanner := bufio.NewScanner(contentReader)
+		for scanner.Scan() {
+			name, value, ok := strings.Cut(scanner.Text(), ":")
+			if ok && strings.EqualFold(strings.TrimSpace(name), "sitemap") && strings.TrimSpace(value) != "" {
+				sitemapURLs = append(sitemapURLs, strings.TrimSpace(value))
+			}
+		}
+	}
+	if len(sitemapURLs) == 0 {
+		sitemapURLs = []string{rootURL.ResolveReference(&url.URL{Path: "sitemap.xml"}).String()}
+	}
+	return sitemapURLs, nil
+}
+
+// readSitemap fetches and parses the sitemap at the URL, which may be compressed with gzip.
+func (f *Fetcher) readSitemap(ctx context.Context, sitemapURL string) (parsedSitemap *sitemap, err error) {
+	contentReader, _, err := f.Get(ctx, sitemapURL, f.translatef("sitemap %s", sitemapURL))
+	if err != nil {
+		return
+	}
+	defer contentReader.Close()
+
+	content, err := io.ReadAll(contentReader)
+	if err != nil {
+		return
+	}
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		content, err = io.ReadAll(gzipReader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	parsedSitemap = &sitemap{}
+	err = xml.Unmarshal(content, parsedSitemap)
+	return
+}
+
+// DiscoverPageURLs returns the URLs of the pages of the topic in order, as they are listed in the sitemaps of the forum
+// (the given ones, or else the ones declared by its robots.txt or the one at the root of its host) and recognized by
+// the engine, so that the pages are found even if their addresses are irregular. The sitemap indexes are followed
+// until maxSitemaps sitemaps have been read. If several URLs are recognized as the same page, the first one is taken.
+func (f *Fetcher) DiscoverPageURLs(ctx context.Context, sitemapURLs []string) (pageURLs []string, err error) {
+	if _, ok := f.engine().(engine.Scroller); ok {
+		return nil, fmt.Errorf("the pages of topics served by the %s engine cannot be discovered from sitemaps", f.engine().Name())
+	}
+	locator, ok := f.engine().(engine.PageLocator)
+	if !ok {
+		return nil, fmt.Errorf("the %s engine cannot recognize the pages of the topic in sitemaps", f.engine().Name())
+	}
+
+	if len(sitemapURLs) == 0 {
+		sitemapURLs, err = f.sitemapURLs(ctx)
+		if err != nil {
+			return
+		}
+	}
+
+	pages := map[uint]string{}
+	visitedSitemaps := map[string]struct{}{}
+	for len(sitemapURLs) > 0 && ctx.Err() == nil {
+		sitemapURL := sitemapURLs[0]
+		sitemapURLs = sitemapURLs[1:]
+		if _, ok := visitedSitemaps[sitemapURL]; ok {
+			continue
+		}
+		if len(visitedSitemaps) == maxSitemaps {
+			f.logErrorf("stopped discovering the pages of the topic after reading %d sitemaps", maxSitemaps)
+			break
+		}
+		visitedSitemaps[sitemapURL] = struct{}{}
+
+		parsedSitemap, err := f.readSitemap(ctx, sitemapURL)
+		if err != nil {
+			f.logErrorf("could not read sitemap %s: %v", sitemapURL, err)
+			continue
+		}
+
+		for _, child := range parsedSitemap.Sitemaps {
+			sitemapURLs = append(sitemapURLs, strings.TrimSpace(child.Loc))
+		}
+		for _, entry := range parsedSitemap.URLs {
+			pageURL, err := url.Parse(strings.TrimSpace(entry.Loc))
+			if err != nil {
+				continue
+			}
+
+			pageNumber, ok := locator.PageNumber(f.topic(), pageURL)
+			if !ok || pageNumber < MinPageNumber {
+				continue
+			}
+			if _, ok := pages[pageNumber]; !ok {
+				pages[pageNumber] = pageURL.String()
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(pages) == 0 {
+		return nil, ErrNoPagesInSitemaps
+	}
+
+	var lastPageNumber uint
+	for pageNumber := range pages {
+		lastPageNumber = max(lastPageNumber, pageNumber)
+	}
+
+	// the pages which the sitemaps skip are taken to be at their regular URLs
+	for pageNumber := MinPageNumber; pageNumber <= lastPageNumber; pageNumber++ {
+		pageURL, ok := pages[pageNumber]
+		if !ok {
+			pageURL = f.engine().PageURL(f.topic(), pageNumber)
+			f.logDebugf("page %d is not listed in the sitemaps; assuming %s", pageNumber, pageURL)
+		}
+		pageURLs = append(pageURLs, pageURL)
+	}
+	f.logDebugf("Discovered %d pages of the topic in %d sitemaps.", len(pages), len(visitedSitemaps))
+	return
+}
@@ -0,0 +1,129 @@
+package fetch
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// sharedSmiley is the image of a smiley which is stored once for all the pages of the archive.
+type sharedSmiley struct {
+	once sync.Once
+	// name is the slash-separated path of the stored image in the storage, or empty if it could not be stored.
+	name string
+}
+
+// smileyState keeps track of the smileys which have been stored in the archive, during this run or earlier ones.
+type smileyState struct {
+	mutex   sync.Mutex
+	smileys map[string]*sharedSmiley
+}
+
+// loadSmileys reads the map of the smileys stored in the archive during earlier runs, unless they are to be fetched
+// again.
+func (f *Fetcher) loadSmileys() {
+	f.smileys.smileys = map[string]*sharedSmiley{}
+	if !f.config.SharedSmileys || f.config.Force {
+		return
+	}
+
+	storedSmileys, err := archive.LoadSmileys(f.config.TargetDir)
+	if err != nil {
+		f.logErrorf("could not read the map of smileys (%s): %v", filepath.Join(f.config.TargetDir, archive.SmileyMapFilename), err)
+		return
+	}
+	for urlStr, storedSmiley := range storedSmileys {
+		smiley := &sharedSmiley{name: storedSmiley.Filename}
+		smiley.once.Do(func() {})
+		f.smileys.smileys[urlStr] = smiley
+	}
+}
+
+// sharedSmiley returns the image of the smiley at the URL, which has yet to be stored if it is new.
+func (f *Fetcher) sharedSmiley(urlStr string) *sharedSmiley {
+	f.smileys.mutex.Lock()
+	defer f.smileys.mutex.Unlock()
+
+	if f.smileys.smileys == nil {
+		f.smileys.smileys = map[string]*sharedSmiley{}
+	}
+	smiley, ok := f.smileys.smileys[urlStr]
+	if !ok {
+		smiley = &sharedSmiley{}
+		f.smileys.smileys[urlStr] = smiley
+	}
+	return smiley
+}
+
+// smileyCode reports whether the image of the token is a smiley which is to be stored once for all the pages, as the
+// engine recognizes it, and returns its code.
+func (f *Fetcher) smileyCode(token *html.Token, linkAttrs *rewrite.LinkAttrs) (code string, ok bool) {
+	if !f.config.SharedSmileys || linkAttrs.URIAttrAtom != atom.Src {
+		return
+	}
+	recognizer, ok := f.engine().(engine.SmileyRecognizer)
+	if !ok {
+		return
+	}
+
+	return recognizer.SmileyCode(token.DataAtom, token.Attr)
+}
+
+// fetchSharedSmiley stores the image of the smiley with the given code in the directory of the smileys, unless it has
+// already been stored, records it in the map of the smileys and replaces the reference to it with one to the stored
+// image. It returns false if the image is to be handled like any other one, because it is filtered out or could not be
+// stored.
+func (f *Fetcher) fetchSharedSmiley(linkURI *url.URL, code string, context *resourceFetcherContext) (ok bool) {
+	originalReference := linkURI.String()
+	if linkURI.Opaque != "" || linkURI.Path == "" {
+		return
+	}
+	linkURI = f.stripParameters(context.baseURL.ResolveReference(linkURI))
+
+	if isThirdPartyHostname(linkURI, context.pageHostname()) && (len(f.config.AllowDomains) > 0 || len(f.config.BlockDomains) > 0) ||
+		!f.isResourceURLAccepted(linkURI.String()) || !f.isResourceClassWanted(rewrite.ResourceClassImage) {
+		return
+	}
+
+	smiley := f.sharedSmiley(linkURI.String())
+	smiley.once.Do(func() {
+		smileyContext := *context
+		smileyContext.targetHostDir = path.Join(archive.SmileysDirname, context.pageHostname())
+		contentType, err := f.getAndWriteResourceToFile(linkURI, f.translatef("smiley %s", linkURI), &smileyContext)
+		if err != nil {
+			return
+		}
+
+		smiley.name = path.Join(smileyContext.targetHostDir, f.filenames().LocalPath(linkURI, contentType))
+
+		f.smileys.mutex.Lock()
+		defer f.smileys.mutex.Unlock()
+		err = archive.RecordSmiley(f.config.TargetDir, &archive.Smiley{
+			URL:      linkURI.String(),
+			Code:     strings.Join(strings.Fields(code), " "),
+			Filename: smiley.name,
+		})
+		if err != nil {
+			f.logErrorf("could not record smiley %s in the map of smileys", linkURI.String())
+		}
+	})
+	if smiley.name == "" {
+		return
+	}
+
+	relativeReference, err := f.referenceFromPage(smiley.name, context)
+	if err != nil {
+		f.logErrorf("could not determine relative path to smiley %s", linkURI.String())
+		return
+	}
+	context.replaceResourceReference(f.rewriteReference(originalReference, linkURI, relativeReference, f.translatef("smiley %s", linkURI)))
+	return true
+}
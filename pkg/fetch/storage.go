@@ -0,0 +1,97 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+)
+
+// ErrPathOutsideStorage is returned instead of creating a file whose path would not be contained in the storage, which
+// may happen for hostile or malformed URLs.
+var ErrPathOutsideStorage = errors.New("path outside of the storage")
+
+// Storage receives the content of the fetched pages and resources.
+type Storage interface {
+	// Create creates the file with the given slash-separated path, relative to the root of the storage, for writing,
+	// creating its parent directories if necessary.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirStorage stores the files in the directory with the given path.
+type DirStorage string
+
+// resolve returns the path of the file with the given name after verifying that it is contained in the directory: the
+// name must be relative and must neither contain NUL bytes nor climb out of the directory with `..`.
+func (dir DirStorage) resolve(name string) (filename string, err error) {
+	localName := filepath.Clean(filepath.FromSlash(name))
+	if strings.ContainsRune(name, 0) || !filepath.IsLocal(localName) {
+		return "", fmt.Errorf("%w: %q", ErrPathOutsideStorage, name)
+	}
+
+	return filepath.Join(string(dir), localName), nil
+}
+
+// makeParentDirs creates the missing parent directories of the file one at a time, refusing to descend into any which
+// is a symbolic link, so that a tampered target directory cannot redirect the writes elsewhere on the system.
+func (dir DirStorage) makeParentDirs(filename string) (err error) {
+	relativeDirPath, err := filepath.Rel(string(dir), filepath.Dir(filename))
+	if err != nil || relativeDirPath == "." {
+		return
+	}
+
+	dirpath := string(dir)
+	for _, component := range strings.Split(relativeDirPath, string(filepath.Separator)) {
+		dirpath = filepath.Join(dirpath, component)
+
+		fileInfo, err := os.Lstat(dirpath)
+		if os.IsNotExist(err) {
+			err = os.Mkdir(dirpath, os.ModePerm)
+			if err != nil && !os.IsExist(err) {
+				return err
+			}
+
+			fileInfo, err = os.Lstat(dirpath)
+		}
+		if err != nil {
+			return err
+		}
+
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s", archive.ErrSymlink, dirpath)
+		}
+		if !fileInfo.IsDir() {
+			return fmt.Errorf("%s is not a directory", dirpath)
+		}
+	}
+
+	return
+}
+
+func (dir DirStorage) Create(name string) (file io.WriteCloser, err error) {
+	filename, err := dir.resolve(name)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(string(dir), os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	err = dir.makeParentDirs(filename)
+	if err != nil {
+		return
+	}
+
+	osFile, err := archive.Create(filename)
+	if err != nil {
+		return
+	}
+
+	return osFile, nil
+}
@@ -0,0 +1,155 @@
+package fetch_test
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// newDirStorage returns a storage in a directory of its own within a temporary directory, which is returned as well
+// so that the test can check that nothing has been written beside the storage.
+func newDirStorage(t *testing.T) (storage fetch.DirStorage, parentDir string) {
+	t.Helper()
+
+	parentDir = t.TempDir()
+	return fetch.DirStorage(filepath.Join(parentDir, "archive")), parentDir
+}
+
+// checkNothingBeside fails the test if anything other than the storage has been created in its parent directory.
+func checkNothingBeside(t *testing.T, parentDir string) {
+	t.Helper()
+
+	for _, name := range readDirNames(t, parentDir) {
+		if name != "archive" {
+			t.Errorf("got %s created beside the storage", name)
+		}
+	}
+}
+
+func TestDirStorageRefusesPathsOutside(t *testing.T) {
+	storage, parentDir := newDirStorage(t)
+
+	for _, name := range []string{
+		"../escaped",
+		"1/../../escaped",
+		"1/forum.test/../../../escaped",
+		filepath.ToSlash(filepath.Join(parentDir, "escaped")),
+		"/escaped",
+		"1/forum.test/escaped\x00.png",
+	} {
+		file, err := storage.Create(name)
+		if err == nil {
+			file.Close()
+		}
+		if !errors.Is(err, fetch.ErrPathOutsideStorage) {
+			t.Errorf("got error %v creating %q; want %v", err, name, fetch.ErrPathOutsideStorage)
+		}
+	}
+
+	checkNothingBeside(t, parentDir)
+}
+
+func TestDirStorageKeepsPercentEncodedDotSegmentsInside(t *testing.T) {
+	storage, parentDir := newDirStorage(t)
+
+	for _, rawURL := range []string{
+		"http://forum.test/%2e%2e/%2e%2e/%2e%2e/escaped.png",
+		"http://forum.test/images/%2E%2E/%2e%2E/%2e%2e/escaped.png",
+		"http://forum.test/..%2f..%2f..%2fescaped.png",
+		"http://forum.test/images/%2e%2e%5c%2e%2e%5cescaped.png",
+	} {
+		resourceURL, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the resources are stored the way the fetcher stores them
+		name := path.Join("1", "forum.test", rewrite.LocalPath(resourceURL, "image/png"))
+		file, err := storage.Create(name)
+		if err != nil {
+			t.Errorf("got error %v storing %s as %q; want it stored within the directory of its host", err, rawURL, name)
+			continue
+		}
+		file.Close()
+
+		if !strings.HasPrefix(name, "1/forum.test/") || strings.Contains(name, "/../") {
+			t.Errorf("got %s stored as %q; want it stored within the directory of its host", rawURL, name)
+		}
+	}
+
+	checkNothingBeside(t, parentDir)
+}
+
+// symlink creates a symbolic link at linkname pointing at target, along with the parent directories of the link, or
+// skips the test if symbolic links cannot be created, e.g. without the privilege to create them on Windows.
+func symlink(t *testing.T, target, linkname string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(linkname), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink(target, linkname)
+	if err != nil {
+		t.Skipf("cannot create symbolic links: %v", err)
+	}
+}
+
+func TestDirStorageRefusesSymlinks(t *testing.T) {
+	tests := []struct {
+		name string
+		// link is the slash-separated path of the symbolic link within the storage, which points at the target
+		// outside of it
+		link       string
+		targetFile bool
+	}{
+		{name: "page directory", link: "1"},
+		{name: "host directory", link: "1/forum.test"},
+		{name: "nested directory", link: "1/forum.test/images"},
+		{name: "target file", link: "1/forum.test/images/avatar.png", targetFile: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storage, parentDir := newDirStorage(t)
+
+			target := filepath.Join(parentDir, "outside")
+			err := os.Mkdir(target, os.ModePerm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.targetFile {
+				target = filepath.Join(target, "file")
+				err = os.WriteFile(target, []byte("outside"), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			symlink(t, target, filepath.Join(string(storage), filepath.FromSlash(test.link)))
+
+			file, err := storage.Create("1/forum.test/images/avatar.png")
+			if err == nil {
+				file.Close()
+			}
+			if !errors.Is(err, archive.ErrSymlink) {
+				t.Errorf("got error %v; want %v", err, archive.ErrSymlink)
+			}
+
+			if test.targetFile {
+				content, err := os.ReadFile(target)
+				if err != nil || string(content) != "outside" {
+					t.Errorf("got the target of the link changed to %q (error %v)", content, err)
+				}
+			} else if names := readDirNames(t, target); len(names) > 0 {
+				t.Errorf("got %v written through the link", names)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package fetch
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// injectedStylesheet is a stylesheet which is stored in the archive and linked from every stored page.
+type injectedStylesheet struct {
+	name    string
+	kind    rewrite.StylesheetKind
+	content []byte
+}
+
+// injectedStylesheets returns the stylesheets which are injected into the stored pages, in the order in which they are
+// linked.
+func (f *Fetcher) injectedStylesheets() (stylesheets []*injectedStylesheet) {
+	if len(f.config.InjectedStylesheet) > 0 {
+		stylesheets = append(stylesheets, &injectedStylesheet{archive.InjectedStylesheetFilename, rewrite.UserStylesheet, f.config.InjectedStylesheet})
+	}
+	if len(f.config.PrintStylesheet) > 0 {
+		stylesheets = append(stylesheets, &injectedStylesheet{archive.PrintStylesheetFilename, rewrite.PrintStylesheet, f.config.PrintStylesheet})
+	}
+	return
+}
+
+// storeInjectedStylesheets stores the stylesheets which are injected into the stored pages in the archive.
+func (f *Fetcher) storeInjectedStylesheets() error {
+	for _, stylesheet := range f.injectedStylesheets() {
+		err := archive.WriteStylesheet(f.config.TargetDir, stylesheet.name, stylesheet.content)
+		if err != nil {
+			return fmt.Errorf("could not store the injected stylesheet (%s)", filepath.Join(f.config.TargetDir, stylesheet.name))
+		}
+	}
+	return nil
+}
+
+// injectedStylesheetTags returns the HTML of the links to the injected stylesheets from the page of the context.
+func (f *Fetcher) injectedStylesheetTags(context *resourceFetcherContext) (tags string) {
+	for _, stylesheet := range f.injectedStylesheets() {
+		reference, err := f.referenceFromPage(stylesheet.name, context)
+		if err != nil {
+			f.logErrorf("could not determine relative path to the injected stylesheet %s from page %d", stylesheet.name, context.pageNumber)
+			continue
+		}
+		tags += rewrite.InjectedStylesheetTag(reference, stylesheet.kind)
+	}
+	return
+}
@@ -0,0 +1,54 @@
+package fetch
+
+import (
+	"golang.org/x/net/html"
+)
+
+// ThumbnailFilenameSuffix is appended to the names of the stored images to form the names of their thumbnails.
+const ThumbnailFilenameSuffix = ".thumb.jpg"
+
+// ThumbnailMaker returns the JPEG thumbnail of the content of an image, or false if the image is not large enough to
+// need one or cannot be decoded.
+type ThumbnailMaker func(content []byte) (thumbnail []byte, ok bool)
+
+// WithThumbnails makes the fetcher store a thumbnail made by the maker next to every image embedded in a page which
+// needs one and rewrite the `img` element to show the thumbnail, linking to the full-size copy unless it is already
+// within a link, so that the pages with many large images are quick to open.
+func WithThumbnails(makeThumbnail ThumbnailMaker) Option {
+	return func(f *Fetcher) {
+		f.makeThumbnail = makeThumbnail
+	}
+}
+
+// writeThumbnail makes the thumbnail of the content of the image stored as the resource of the given name and stores it
+// next to the image, returning the name of the thumbnail, or an empty one if the image has none.
+func (f *Fetcher) writeThumbnail(name, resourceDescription string, content []byte) (thumbnailName string) {
+	thumbnail, ok := f.makeThumbnail(content)
+	if !ok {
+		return ""
+	}
+
+	thumbnailName = name + ThumbnailFilenameSuffix
+	file, err := f.createFileForResourceContent(thumbnailName, f.translatef("the thumbnail of %s", resourceDescription))
+	if err != nil {
+		return ""
+	}
+	_, err = file.Write(thumbnail)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		f.checkDiskFull(err)
+		f.logErrorf("could not write the thumbnail of %s in file %s successfully", resourceDescription, thumbnailName)
+		return ""
+	}
+
+	f.logDebugf("Stored the thumbnail of %s in file %s", resourceDescription, thumbnailName)
+	return thumbnailName
+}
+
+// thumbnailLinkTags returns the tags with which the thumbnail of an image is wrapped so that it links to the full-size
+// copy at the reference.
+func thumbnailLinkTags(fullSizeReference string) (startTag, endTag string) {
+	return `<a href="` + html.EscapeString(fullSizeReference) + `">`, "</a>"
+}
@@ -0,0 +1,68 @@
+package fetch
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// titleLookahead is the number of bytes at the start of a page in which its title is looked for when the pages are
+// named after the title of the topic (see Config.NameByTitle).
+const titleLookahead = 64 << 10
+
+// topicSlugState keeps the slug of the title of the topic once it has been learned from the first fetched page, so that
+// all the pages are named alike even if their titles differ in more than their number.
+type topicSlugState struct {
+	mutex sync.Mutex
+	slug  string
+}
+
+// pageTitle returns the text of the title of the HTML document which starts with the content, with the whitespace
+// collapsed, or an empty one if the title is not within the content.
+func pageTitle(content []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if tagName, _ := tokenizer.TagName(); atom.Lookup(tagName) != atom.Title {
+				continue
+			}
+			if tokenizer.Next() != html.TextToken {
+				return ""
+			}
+			return strings.Join(strings.Fields(html.UnescapeString(string(tokenizer.Text()))), " ")
+		case html.EndTagToken:
+			// the title of a document is in its head
+			if tagName, _ := tokenizer.TagName(); atom.Lookup(tagName) == atom.Head {
+				return ""
+			}
+		}
+	}
+}
+
+// titledPageName returns the slash-separated path in the storage of the page with the number, which starts with the
+// content, named after the slug of the title of the topic, or an empty one if the slug cannot be told. The page is
+// stored in the same directory as it would be otherwise, so that the references to its resources are the same.
+func (f *Fetcher) titledPageName(pageURL *url.URL, pageNumber uint, contentType, targetHostDir string, content []byte) (name string) {
+	f.topicSlug.mutex.Lock()
+	defer f.topicSlug.mutex.Unlock()
+
+	if f.topicSlug.slug == "" {
+		f.topicSlug.slug = rewrite.Slug(rewrite.TopicTitle(pageTitle(content)))
+		if f.topicSlug.slug == "" {
+			f.logDebugf("could not tell the title of the topic from page %d; keeping its usual name", pageNumber)
+			return ""
+		}
+	}
+
+	dir := path.Dir(path.Join(targetHostDir, f.filenames().LocalPath(pageURL, contentType)))
+	return path.Join(dir, rewrite.TitledPageFilename(f.topicSlug.slug, pageNumber))
+}
@@ -0,0 +1,158 @@
+package fetch_test
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/internal/forumtest"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+var update = flag.Bool("update", false, "replace the golden copies of the archives with the fetched ones")
+
+// goldenDir is the golden copy of the archive of forumtest.DefaultTopic.
+var goldenDir = filepath.Join("testdata", "golden", "default")
+
+// fetchTopic fetches the pages in the page ranges of the topic served by the mock forum into dir.
+func fetchTopic(t *testing.T, server *forumtest.Server, dir string, pageRanges ...string) *fetch.Report {
+	t.Helper()
+
+	report, err := fetch.Topic(context.Background(), fetch.Config{
+		URL:        server.TopicURL(),
+		PostStep:   server.Topic.PostStep,
+		TargetDir:  dir,
+		PageRanges: pageRanges,
+	})
+	if err != nil {
+		t.Fatalf("fetching the topic failed: %v", err)
+	}
+	return report
+}
+
+// compareGolden fails the test if the archive in dir differs from the golden copy of the archive of the topic.
+func compareGolden(t *testing.T, server *forumtest.Server, dir string) {
+	t.Helper()
+
+	mismatches, err := server.CompareGolden(dir, goldenDir, *update)
+	if err != nil {
+		t.Fatalf("comparing the archive with the golden copy failed: %v", err)
+	}
+	for _, mismatch := range mismatches {
+		t.Error(mismatch)
+	}
+}
+
+func TestTopicMatchesGolden(t *testing.T) {
+	server := forumtest.NewServer(forumtest.DefaultTopic)
+	defer server.Close()
+
+	dir := t.TempDir()
+	report := fetchTopic(t, server, dir, "1..")
+	if report.PagesSucceeded != int(server.Topic.Pages) || report.PagesFailed != 0 || report.ResourcesFailed != 0 {
+		t.Errorf("got %d pages succeeded, %d failed and %d resources failed; want %d, 0 and 0",
+			report.PagesSucceeded, report.PagesFailed, report.ResourcesFailed, server.Topic.Pages)
+	}
+
+	compareGolden(t, server, dir)
+}
+
+func TestTopicFollowsRedirects(t *testing.T) {
+	server := forumtest.NewServer(forumtest.DefaultTopic)
+	defer server.Close()
+
+	dir := t.TempDir()
+	report := fetchTopic(t, server, dir, "1..")
+	if report.ResourcesFailed != 0 {
+		t.Errorf("got %d resources failed; want 0", report.ResourcesFailed)
+	}
+	if server.Requests("/images/logo") == 0 {
+		t.Error("the redirecting image has not been requested")
+	}
+	if server.Requests("/images/avatar.png") == 0 {
+		t.Error("the target of the redirect has not been requested")
+	}
+
+	compareGolden(t, server, dir)
+}
+
+func TestTopicRetriesFailedPages(t *testing.T) {
+	server := forumtest.NewServer(forumtest.DefaultTopic)
+	defer server.Close()
+
+	// the first page is requested once to detect the last page and once to fetch it
+	server.FailNext(forumtest.TopicPath, 1, http.StatusInternalServerError)
+
+	dir := t.TempDir()
+	report := fetchTopic(t, server, dir, "2..2")
+	if !slices.Equal(report.FailedPages, []uint{2}) {
+		t.Fatalf("got failed pages %v; want [2]", report.FailedPages)
+	}
+
+	report = fetchTopic(t, server, dir, "1..")
+	if report.PagesFailed != 0 {
+		t.Errorf("got failed pages %v after retrying; want none", report.FailedPages)
+	}
+	if report.PagesSucceeded != int(server.Topic.Pages) {
+		t.Errorf("got %d pages succeeded after retrying; want %d", report.PagesSucceeded, server.Topic.Pages)
+	}
+
+	compareGolden(t, server, dir)
+}
+
+func TestTopicRecordsFailedResources(t *testing.T) {
+	server := forumtest.NewServer(forumtest.DefaultTopic)
+	defer server.Close()
+
+	server.FailNext("/styles/background.png", 1, http.StatusServiceUnavailable)
+
+	dir := t.TempDir()
+	report := fetchTopic(t, server, dir, "1..1")
+	if report.ResourcesFailed != 1 {
+		t.Errorf("got %d resources failed; want 1", report.ResourcesFailed)
+	}
+	if report.PagesSucceeded != 1 {
+		t.Errorf("got %d pages succeeded; want 1, since the failure of a resource does not fail the page", report.PagesSucceeded)
+	}
+}
+
+func TestTopicFiltersSiteIcons(t *testing.T) {
+	rejectIcons, err := fetch.ParseURLPattern("*.ico")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		config        fetch.Config
+		wantRequested bool
+	}{
+		{name: "default", wantRequested: true},
+		{name: "skip site icons", config: fetch.Config{SkipSiteIcons: true}},
+		{name: "no images", config: fetch.Config{SkipImages: true}},
+		{name: "rejected", config: fetch.Config{Reject: []*fetch.URLPattern{rejectIcons}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := forumtest.NewServer(forumtest.DefaultTopic)
+			defer server.Close()
+
+			config := test.config
+			config.URL = server.TopicURL()
+			config.PostStep = server.Topic.PostStep
+			config.TargetDir = t.TempDir()
+			config.PageRanges = []string{"1..1"}
+			_, err := fetch.Topic(context.Background(), config)
+			if err != nil {
+				t.Fatalf("fetching the topic failed: %v", err)
+			}
+
+			if isRequested := server.Requests("/favicon.ico") > 0; isRequested != test.wantRequested {
+				t.Errorf("got the site icon requested: %t; want %t", isRequested, test.wantRequested)
+			}
+		})
+	}
+}
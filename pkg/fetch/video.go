@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// VideoDownloader downloads the video whose page is at the URL into the directory with the given path, which is empty
+// and removed afterwards, e.g. by running yt-dlp, and returns the path of the downloaded file.
+type VideoDownloader func(ctx context.Context, videoURL, dir string) (filename string, err error)
+
+// WithVideoDownloader makes the fetcher download the videos embedded in the pages through the players of YouTube,
+// Vimeo and Streamable (see rewrite.GetEmbeddedVideo) with the given downloader and replace the frames of the players
+// with `video` elements which play the local copies. Videos which cannot be downloaded are left embedded.
+func WithVideoDownloader(download VideoDownloader) Option {
+	return func(f *Fetcher) {
+		f.downloadVideo = download
+	}
+}
+
+// fetchEmbeddedVideo downloads the embedded video, unless it has already been downloaded for the page, stores it and
+// replaces the reference to it with the one to its local copy.
+func (f *Fetcher) fetchEmbeddedVideo(video *rewrite.EmbeddedVideo, context *resourceFetcherContext) (ok bool) {
+	if !f.isResourceClassWanted(rewrite.ResourceClassMedia) {
+		return
+	}
+
+	localURL := video.LocalURL()
+	contentType, wasVideoFetched := context.fetchedResources[localURL.String()]
+	if !wasVideoFetched {
+		var err error
+		contentType, err = f.downloadAndWriteVideoToFile(video, localURL, context)
+		if err != nil {
+			return
+		}
+
+		context.fetchedResources[localURL.String()] = contentType
+	}
+
+	reference, ok := f.filenames().RelativeReference(localURL, context.dirpath, contentType)
+	if !ok {
+		f.logErrorf("could not determine relative path to video %s", video.URL)
+		return
+	}
+
+	context.replaceResourceReference(reference)
+	return true
+}
+
+func (f *Fetcher) downloadAndWriteVideoToFile(video *rewrite.EmbeddedVideo, localURL *url.URL, parentContext *resourceFetcherContext) (contentType string, err error) {
+	ctx, span := f.tracer.Start(parentContext.ctx, "fetch.video", trace.WithAttributes(
+		attribute.Int64("page.number", int64(parentContext.pageNumber)),
+		attribute.String("url.full", redact.URLString(video.URL)),
+	))
+
+	videoDescription := f.translatef("video %s", video.URL)
+	var name string
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		f.report.recordResource(err)
+		if err != nil {
+			f.recordResourceFailure(parentContext.pageNumber, video.URL, err)
+			f.notifyError(&ErrorEvent{
+				PageNumber: parentContext.pageNumber,
+				URL:        video.URL,
+				Err:        err,
+			})
+			return
+		}
+
+		f.notifyResourceFetched(&ResourceEvent{
+			PageNumber:  parentContext.pageNumber,
+			URL:         video.URL,
+			Filename:    name,
+			ContentType: contentType,
+		})
+		f.postProcessFile(ctx, name)
+	}()
+
+	downloadDir, err := os.MkdirTemp("", "fetch-forum-topic-video-")
+	if err != nil {
+		f.logErrorf("could not create temporary directory for %s", videoDescription)
+		return
+	}
+	defer os.RemoveAll(downloadDir)
+
+	f.logDebugf("Downloading %s...", videoDescription)
+	filename, err := f.downloadVideo(ctx, video.URL, downloadDir)
+	if err != nil {
+		f.logErrorf("could not download %s: %v", videoDescription, err)
+		return
+	}
+
+	contentType, ok := rewrite.VideoMediaTypes[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		err = fmt.Errorf("unsupported video file type %q", filepath.Ext(filename))
+		f.logErrorf("could not store %s: %v", videoDescription, err)
+		return
+	}
+
+	downloadedFile, err := os.Open(filename)
+	if err != nil {
+		f.logErrorf("could not open the downloaded %s", videoDescription)
+		return
+	}
+	defer downloadedFile.Close()
+
+	var file io.WriteCloser
+	file, name, err = f.openFileForResourceContent(localURL, videoDescription, contentType, parentContext.targetHostDir)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	defer f.recordFetchedResource(name, &archive.ResourceMetadata{
+		URL:         video.URL,
+		Status:      http.StatusOK,
+		ContentType: contentType,
+		FetchedAt:   time.Now().UTC(),
+		Header:      http.Header{"Content-Type": {contentType}},
+	})
+
+	_, writeSpan := f.tracer.Start(ctx, "fetch.write")
+	_, err = bufio.NewReader(countingReadCloser{downloadedFile, f.report}).WriteTo(file)
+	writeSpan.End()
+	if err != nil {
+		f.checkDiskFull(err)
+		f.logErrorf("could not write the content of %s in file %s successfully", videoDescription, name)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"expvar"
+)
+
+// Expvar publishes the metrics through the expvar package, i.e. at /debug/vars of the servers which serve
+// http.DefaultServeMux, as the values of a map. Every histogram is published as the count and the sum of the recorded
+// values, under its name suffixed with `_count` and `_sum`.
+type Expvar struct {
+	values *expvar.Map
+}
+
+// NewExpvar returns a recorder which publishes the metrics in a map with the name, e.g. `fetch_forum_topic`. Like
+// expvar.NewMap, it panics if a variable with the name has already been published.
+func NewExpvar(name string) *Expvar {
+	return &Expvar{values: expvar.NewMap(name)}
+}
+
+func (e *Expvar) Add(name string, delta float64) {
+	e.values.AddFloat(name, delta)
+}
+
+func (e *Expvar) Observe(name string, value float64) {
+	e.values.AddFloat(name+"_count", 1)
+	e.values.AddFloat(name+"_sum", value)
+}
@@ -0,0 +1,60 @@
+// Package metrics provides the counters and histograms into which the fetcher reports the progress of a fetch, so that
+// applications embedding the library can observe it without scraping its logs. Recorders for Prometheus and expvar are
+// included; others only have to implement Recorder.
+package metrics
+
+// The names of the metrics which the fetcher reports. The counters end with `_total` and the histograms with their unit.
+const (
+	// PagesFetched counts the pages which were fetched successfully.
+	PagesFetched = "pages_fetched_total"
+	// PagesFailed counts the attempts to fetch a page which failed.
+	PagesFailed = "pages_failed_total"
+	// PagesSkipped counts the pages which were not fetched because they had already been archived.
+	PagesSkipped = "pages_skipped_total"
+	// PageRetries counts the attempts to fetch a page which had failed before.
+	PageRetries = "page_retries_total"
+	// ResourcesFetched counts the resources embedded in the pages which were stored successfully.
+	ResourcesFetched = "resources_fetched_total"
+	// ResourcesFailed counts the resources embedded in the pages which could not be fetched.
+	ResourcesFailed = "resources_failed_total"
+	// BytesDownloaded counts the bytes of the content of the pages and resources which were downloaded.
+	BytesDownloaded = "downloaded_bytes_total"
+	// Requests counts the HTTP requests which were issued.
+	Requests = "requests_total"
+	// RequestsFailed counts the HTTP requests which failed without a response or with an error status.
+	RequestsFailed = "requests_failed_total"
+	// PageDuration is the histogram of the times the attempts to fetch a page took, in seconds.
+	PageDuration = "page_duration_seconds"
+	// RequestDuration is the histogram of the times the HTTP requests took until their response arrived, in seconds.
+	RequestDuration = "request_duration_seconds"
+)
+
+// Descriptions are the descriptions of the metrics, e.g. for the help of the exported metrics.
+var Descriptions = map[string]string{
+	PagesFetched:     "Number of pages of the topic which were fetched successfully.",
+	PagesFailed:      "Number of attempts to fetch a page of the topic which failed.",
+	PagesSkipped:     "Number of pages of the topic which were skipped because they had already been archived.",
+	PageRetries:      "Number of attempts to fetch a page of the topic which had failed before.",
+	ResourcesFetched: "Number of resources embedded in the pages which were stored successfully.",
+	ResourcesFailed:  "Number of resources embedded in the pages which could not be fetched.",
+	BytesDownloaded:  "Number of bytes of the content of the pages and resources which were downloaded.",
+	Requests:         "Number of HTTP requests which were issued.",
+	RequestsFailed:   "Number of HTTP requests which failed without a response or with an error status.",
+	PageDuration:     "Time the attempts to fetch a page of the topic took, in seconds.",
+	RequestDuration:  "Time the HTTP requests took until their response arrived, in seconds.",
+}
+
+// Recorder receives the metrics of a fetch. Its methods are called concurrently by the pages being fetched, so they
+// have to be safe for concurrent use and should return quickly.
+type Recorder interface {
+	// Add adds the delta to the counter with the name.
+	Add(name string, delta float64)
+	// Observe records the value in the histogram with the name.
+	Observe(name string, value float64)
+}
+
+// Nop discards all metrics.
+type Nop struct{}
+
+func (Nop) Add(name string, delta float64)     {}
+func (Nop) Observe(name string, value float64) {}
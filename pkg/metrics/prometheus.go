@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// histogramBuckets are the upper bounds of the buckets of the histograms, which are sized for the durations of whole
+// pages (which include their resources) and of single requests respectively.
+var histogramBuckets = map[string][]float64{
+	PageDuration:    prometheus.ExponentialBuckets(0.25, 2, 12),
+	RequestDuration: prometheus.DefBuckets,
+}
+
+// Prometheus records the metrics as Prometheus counters and histograms, which are registered with the registerer the
+// first time they are reported.
+type Prometheus struct {
+	registerer prometheus.Registerer
+	namespace  string
+
+	mutex      sync.Mutex
+	counters   map[string]prometheus.Counter
+	histograms map[string]prometheus.Histogram
+}
+
+// NewPrometheus returns a recorder which registers the metrics with the registerer (e.g. prometheus.DefaultRegisterer)
+// under the namespace, e.g. `fetch_forum_topic`, which may be empty.
+func NewPrometheus(registerer prometheus.Registerer, namespace string) *Prometheus {
+	return &Prometheus{
+		registerer: registerer,
+		namespace:  namespace,
+		counters:   map[string]prometheus.Counter{},
+		histograms: map[string]prometheus.Histogram{},
+	}
+}
+
+// register registers the collector, or returns the one which is already registered under its name, e.g. by another
+// recorder with the same registerer.
+func (p *Prometheus) register(collector prometheus.Collector) prometheus.Collector {
+	err := p.registerer.Register(collector)
+	var alreadyRegisteredError prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegisteredError) {
+		return alreadyRegisteredError.ExistingCollector
+	}
+	return collector
+}
+
+func (p *Prometheus) Add(name string, delta float64) {
+	p.mutex.Lock()
+	counter, ok := p.counters[name]
+	if !ok {
+		counter, ok = p.register(prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: p.namespace,
+			Name:      name,
+			Help:      Descriptions[name],
+		})).(prometheus.Counter)
+		if ok {
+			p.counters[name] = counter
+		}
+	}
+	p.mutex.Unlock()
+
+	if ok {
+		counter.Add(delta)
+	}
+}
+
+func (p *Prometheus) Observe(name string, value float64) {
+	p.mutex.Lock()
+	histogram, ok := p.histograms[name]
+	if !ok {
+		histogram, ok = p.register(prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: p.namespace,
+			Name:      name,
+			Help:      Descriptions[name],
+			Buckets:   histogramBuckets[name],
+		})).(prometheus.Histogram)
+		if ok {
+			p.histograms[name] = histogram
+		}
+	}
+	p.mutex.Unlock()
+
+	if ok {
+		histogram.Observe(value)
+	}
+}
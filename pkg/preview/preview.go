@@ -0,0 +1,179 @@
+// Package preview extracts the OpenGraph and oEmbed metadata of linked pages and renders it as preview cards, so that
+// an archive retains the context of links whose targets later disappear.
+package preview
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Preview is the metadata of a linked page which is shown on its preview card.
+type Preview struct {
+	// URL is the canonical URL of the page, or the URL of the link if the page does not declare one.
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+	// ImageURL is the absolute URL of the thumbnail of the page, if any.
+	ImageURL string `json:"imageURL,omitempty"`
+	// OEmbedURL is the absolute URL of the JSON oEmbed endpoint which the page advertises, if any.
+	OEmbedURL string `json:"oembedURL,omitempty"`
+}
+
+// IsEmpty reports whether the preview has nothing to show beside the URL.
+func (preview *Preview) IsEmpty() bool {
+	return preview.Title == "" && preview.Description == "" && preview.ImageURL == ""
+}
+
+// resolve returns the absolute form of the reference relative to the base URL, or an empty string if it is invalid or
+// not an HTTP(S) URL.
+func resolve(baseURL *url.URL, reference string) string {
+	referenceURL, err := url.Parse(strings.TrimSpace(reference))
+	if err != nil {
+		return ""
+	}
+
+	resolvedURL := baseURL.ResolveReference(referenceURL)
+	if resolvedURL.Scheme != "http" && resolvedURL.Scheme != "https" {
+		return ""
+	}
+	return resolvedURL.String()
+}
+
+func getAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
+
+// Parse extracts the preview of the page from its document, which was fetched from docURL, preferring its OpenGraph
+// properties and falling back to its title and description.
+func Parse(doc *html.Node, docURL *url.URL) *Preview {
+	preview := &Preview{URL: docURL.String()}
+	var title, description string
+
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			switch node.DataAtom {
+			case atom.Body:
+				// the metadata is in the head
+				return
+			case atom.Title:
+				if node.FirstChild != nil && title == "" {
+					title = strings.Join(strings.Fields(node.FirstChild.Data), " ")
+				}
+			case atom.Meta:
+				content := strings.TrimSpace(getAttr(node, "content"))
+				switch strings.ToLower(getAttr(node, "property")) {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "og:site_name":
+					preview.SiteName = content
+				case "og:image", "og:image:url", "og:image:secure_url":
+					if preview.ImageURL == "" {
+						preview.ImageURL = resolve(docURL, content)
+					}
+				case "og:url":
+					if canonicalURL := resolve(docURL, content); canonicalURL != "" {
+						preview.URL = canonicalURL
+					}
+				}
+				if strings.EqualFold(getAttr(node, "name"), "description") {
+					description = content
+				}
+			case atom.Link:
+				rel := strings.Fields(strings.ToLower(getAttr(node, "rel")))
+				if len(rel) == 1 && rel[0] == "alternate" && strings.EqualFold(getAttr(node, "type"), "application/json+oembed") {
+					preview.OEmbedURL = resolve(docURL, getAttr(node, "href"))
+				}
+			}
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(doc)
+
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+	return preview
+}
+
+// oEmbed is the part of an oEmbed response which is of interest.
+type oEmbed struct {
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// MergeOEmbed fills in the fields of the preview which the page did not declare from the JSON oEmbed response, which
+// was fetched from the OEmbedURL of the preview.
+func (preview *Preview) MergeOEmbed(content []byte) error {
+	var response oEmbed
+	err := json.Unmarshal(content, &response)
+	if err != nil {
+		return err
+	}
+
+	if preview.Title == "" {
+		preview.Title = response.Title
+	}
+	if preview.Description == "" && response.AuthorName != "" {
+		preview.Description = "by " + response.AuthorName
+	}
+	if preview.SiteName == "" {
+		preview.SiteName = response.ProviderName
+	}
+	if preview.ImageURL == "" {
+		if oEmbedURL, err := url.Parse(preview.OEmbedURL); err == nil {
+			preview.ImageURL = resolve(oEmbedURL, response.ThumbnailURL)
+		}
+	}
+	return nil
+}
+
+// maxDescriptionLength is the number of characters of the description beyond which it is truncated on the card.
+const maxDescriptionLength = 300
+
+var cardTemplate = template.Must(template.New("card").Parse(`<aside class="link-preview" style="display:flex;gap:.75em;max-width:36em;margin:.5em 0;padding:.5em;border:1px solid #ccc;border-radius:4px;font-size:.9em">` +
+	`{{if .ImageReference}}<img src="{{.ImageReference}}" alt="" style="width:6em;height:6em;object-fit:cover;flex:none">{{end}}` +
+	`<div style="min-width:0">` +
+	`{{if .SiteName}}<div style="color:#666">{{.SiteName}}</div>{{end}}` +
+	`<div><a href="{{.URL}}"><strong>{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</strong></a></div>` +
+	`{{if .Description}}<div>{{.Description}}</div>{{end}}` +
+	`</div></aside>`))
+
+// Card returns the HTML of the card which shows the preview, with the thumbnail referred to by imageReference (which is
+// typically the reference to its local copy), or without one if it is empty.
+func (preview *Preview) Card(imageReference string) string {
+	description := []rune(preview.Description)
+	if len(description) > maxDescriptionLength {
+		description = append(description[:maxDescriptionLength], '…')
+	}
+
+	var card strings.Builder
+	cardTemplate.Execute(&card, struct {
+		*Preview
+		Description    string
+		ImageReference template.URL
+	}{preview, string(description), template.URL(imageReference)})
+	return card.String()
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// Authority is a certificate authority which issues certificates for the intercepted hosts.
+type Authority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	// leafKey is the key of all certificates issued by the authority.
+	leafKey *ecdsa.PrivateKey
+}
+
+// LoadOrCreateAuthority reads the certificate and the key of the authority from the PEM files with the given names,
+// creating a new authority and writing it to them if the certificate file does not exist. The certificate has to be
+// trusted by the browser used with the proxy.
+func LoadOrCreateAuthority(certificateFilename, keyFilename string) (authority *Authority, err error) {
+	certificatePEM, err := os.ReadFile(certificateFilename)
+	if os.IsNotExist(err) {
+		return createAuthority(certificateFilename, keyFilename)
+	}
+	if err != nil {
+		return
+	}
+
+	keyPEM, err := os.ReadFile(keyFilename)
+	if err != nil {
+		return
+	}
+
+	certificateBlock, _ := pem.Decode(certificatePEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certificateBlock == nil || keyBlock == nil {
+		return nil, errors.New("invalid certificate authority files")
+	}
+
+	authority = &Authority{}
+	authority.certificate, err = x509.ParseCertificate(certificateBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	authority.key, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	authority.leafKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return
+}
+
+func createAuthority(certificateFilename, keyFilename string) (authority *Authority, err error) {
+	authority = &Authority{}
+	authority.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: "fetch-forum-topic recording proxy"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, template, &authority.key.PublicKey, authority.key)
+	if err != nil {
+		return nil, err
+	}
+	authority.certificate, err = x509.ParseCertificate(certificateDER)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(authority.key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(keyFilename, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(certificateFilename, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateDER}), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	authority.leafKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return
+}
+
+// Issue returns a certificate for the host signed by the authority.
+func (a *Authority) Issue(host string) (certificate *tls.Certificate, err error) {
+	template := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(0, 0, 30),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &a.leafKey.PublicKey, a.key)
+	if err != nil {
+		return
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{certificateDER, a.certificate.Raw},
+		PrivateKey:  a.leafKey,
+	}, nil
+}
+
+func newSerialNumber() *big.Int {
+	serialNumber, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return serialNumber
+}
@@ -0,0 +1,259 @@
+// Package proxy implements an HTTP(S) proxy which passes the responses to the requests made through it for the hosts
+// in its scope to a recorder. HTTPS connections to those hosts are intercepted with certificates issued on the fly by
+// a local certificate authority, which the browser has to trust; connections to other hosts are tunneled untouched.
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordFunc is called with every successful response to a GET request for a host in the scope of the proxy, together
+// with the complete content of its body. The body of the response must not be read.
+type RecordFunc func(response *http.Response, content []byte)
+
+// hopByHopHeaders are the headers which apply only to a single connection and are not forwarded.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Proxy is a recording HTTP(S) proxy.
+type Proxy struct {
+	hosts     map[string]bool
+	authority *Authority
+	record    RecordFunc
+	transport http.RoundTripper
+	logger    *slog.Logger
+
+	certificatesMutex sync.Mutex
+	certificates      map[string]*tls.Certificate
+}
+
+// New returns a proxy which records the responses from the given hosts. HTTPS connections are only intercepted if the
+// authority is not nil.
+func New(hosts []string, authority *Authority, record RecordFunc, logger *slog.Logger) *Proxy {
+	proxy := &Proxy{
+		hosts:        map[string]bool{},
+		authority:    authority,
+		record:       record,
+		transport:    http.DefaultTransport,
+		logger:       logger,
+		certificates: map[string]*tls.Certificate{},
+	}
+	for _, host := range hosts {
+		proxy.hosts[strings.ToLower(host)] = true
+	}
+	return proxy
+}
+
+// IsInScope reports whether the responses from the host (with an optional port) are recorded.
+func (p *Proxy) IsInScope(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	return p.hosts[strings.ToLower(host)]
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		if p.authority != nil && p.IsInScope(r.Host) {
+			p.intercept(w, r)
+		} else {
+			p.tunnel(w, r)
+		}
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		http.Error(w, "this is a proxy; requests have to be made with absolute URLs", http.StatusBadRequest)
+		return
+	}
+
+	response, err := p.forward(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+
+	removeHopByHopHeaders(response.Header)
+	for key, values := range response.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(response.StatusCode)
+	io.Copy(w, response.Body)
+}
+
+// forward issues the request to the origin server and returns the response, recording it if necessary, in which case
+// its body has already been read into memory.
+func (p *Proxy) forward(r *http.Request) (response *http.Response, err error) {
+	outgoingRequest := r.Clone(r.Context())
+	outgoingRequest.RequestURI = ""
+	removeHopByHopHeaders(outgoingRequest.Header)
+	if p.IsInScope(r.URL.Host) {
+		// let the transport negotiate the compression so that the recorded content is decompressed
+		outgoingRequest.Header.Del("Accept-Encoding")
+	}
+
+	response, err = p.transport.RoundTrip(outgoingRequest)
+	if err != nil {
+		p.logger.Warn("could not forward request", slog.String("url", r.URL.String()), slog.Any("error", err))
+		return
+	}
+
+	if r.Method != http.MethodGet || response.StatusCode != http.StatusOK || !p.IsInScope(r.URL.Host) {
+		return
+	}
+
+	content, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		p.logger.Warn("could not read response", slog.String("url", r.URL.String()), slog.Any("error", err))
+		return nil, err
+	}
+
+	response.ContentLength = int64(len(content))
+	response.Header.Set("Content-Length", strconv.Itoa(len(content)))
+	response.TransferEncoding = nil
+	response.Body = io.NopCloser(bytes.NewReader(content))
+	p.record(response, content)
+	return
+}
+
+// tunnel relays the bytes of the connection to the target host without looking at them.
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	targetConnection, err := net.DialTimeout("tcp", r.Host, 30*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	clientConnection, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		targetConnection.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = io.WriteString(clientConnection, "HTTP/1.1 200 Connection Established\r\n\r\n")
+	if err != nil {
+		clientConnection.Close()
+		targetConnection.Close()
+		return
+	}
+
+	go func() {
+		io.Copy(targetConnection, clientConnection)
+		targetConnection.Close()
+	}()
+	io.Copy(clientConnection, targetConnection)
+	clientConnection.Close()
+}
+
+// intercept terminates the TLS connection to the target host with a certificate issued by the authority and serves the
+// requests made over it.
+func (p *Proxy) intercept(w http.ResponseWriter, r *http.Request) {
+	clientConnection, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer clientConnection.Close()
+
+	_, err = io.WriteString(clientConnection, "HTTP/1.1 200 Connection Established\r\n\r\n")
+	if err != nil {
+		return
+	}
+
+	targetHost := r.Host
+	tlsConnection := tls.Server(clientConnection, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			hostname := hello.ServerName
+			if hostname == "" {
+				hostname, _, _ = net.SplitHostPort(targetHost)
+			}
+			return p.certificate(hostname)
+		},
+	})
+	defer tlsConnection.Close()
+
+	reader := bufio.NewReader(tlsConnection)
+	for {
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			if err != io.EOF {
+				p.logger.Debug("could not read intercepted request", slog.String("host", targetHost), slog.Any("error", err))
+			}
+			return
+		}
+
+		request.URL.Scheme = "https"
+		request.URL.Host = targetHost
+		if strings.HasSuffix(targetHost, ":443") {
+			request.URL.Host = strings.TrimSuffix(targetHost, ":443")
+		}
+
+		response, err := p.forward(request)
+		if err != nil {
+			message := err.Error()
+			response = &http.Response{
+				StatusCode:    http.StatusBadGateway,
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{},
+				ContentLength: int64(len(message)),
+				Body:          io.NopCloser(strings.NewReader(message)),
+			}
+		}
+
+		removeHopByHopHeaders(response.Header)
+		err = response.Write(tlsConnection)
+		response.Body.Close()
+		if err != nil || request.Close || response.Close {
+			return
+		}
+	}
+}
+
+// certificate returns the certificate for the host issued by the authority, issuing it if necessary.
+func (p *Proxy) certificate(host string) (certificate *tls.Certificate, err error) {
+	p.certificatesMutex.Lock()
+	defer p.certificatesMutex.Unlock()
+
+	certificate, ok := p.certificates[host]
+	if ok {
+		return
+	}
+
+	certificate, err = p.authority.Issue(host)
+	if err != nil {
+		return
+	}
+
+	p.certificates[host] = certificate
+	return
+}
+
+func removeHopByHopHeaders(header http.Header) {
+	for _, key := range hopByHopHeaders {
+		header.Del(key)
+	}
+}
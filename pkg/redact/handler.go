@@ -0,0 +1,73 @@
+package redact
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// handler scrubs the credentials from the message and the attributes of the log records before passing them on.
+type handler struct {
+	handler slog.Handler
+}
+
+// Handler returns a log handler which scrubs the credentials from the message and the attributes of every record
+// (strings as Text does, errors through their message, URLs and HTTP headers) before passing it on to the handler.
+func Handler(next slog.Handler) slog.Handler {
+	return &handler{next}
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	redactedRecord := slog.NewRecord(record.Time, record.Level, Text(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redactedRecord.AddAttrs(Attr(attr))
+		return true
+	})
+	return h.handler.Handle(ctx, redactedRecord)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redactedAttrs[i] = Attr(attr)
+	}
+	return &handler{h.handler.WithAttrs(redactedAttrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{h.handler.WithGroup(name)}
+}
+
+// Attr returns the log attribute with the credentials in its value scrubbed.
+func Attr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Text(value.String()))
+
+	case slog.KindGroup:
+		groupAttrs := value.Group()
+		redactedGroupAttrs := make([]any, len(groupAttrs))
+		for i, groupAttr := range groupAttrs {
+			redactedGroupAttrs[i] = Attr(groupAttr)
+		}
+		return slog.Group(attr.Key, redactedGroupAttrs...)
+
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			return slog.String(attr.Key, Text(v.Error()))
+		case *url.URL:
+			return slog.String(attr.Key, URL(v).String())
+		case http.Header:
+			return slog.Any(attr.Key, Header(v))
+		}
+	}
+
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,110 @@
+// Package redact scrubs credentials (passwords in URLs, authorization and cookie headers and query parameters which
+// carry secrets or session identifiers) from the URLs, HTTP headers and text which end up in logs, reports and
+// notifications.
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces the redacted values.
+const Mask = "REDACTED"
+
+// sensitiveHeaders are the canonical names of the HTTP headers whose values are credentials.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Api-Key", "X-Auth-Token", "X-Csrf-Token"}
+
+// sensitiveParameters are the names of the query parameters whose values are credentials or session identifiers.
+var sensitiveParameters = map[string]bool{
+	"pass":          true,
+	"passwd":        true,
+	"pwd":           true,
+	"auth":          true,
+	"key":           true,
+	"apikey":        true,
+	"api_key":       true,
+	"sid":           true,
+	"session":       true,
+	"sessionid":     true,
+	"session_id":    true,
+	"phpsessid":     true,
+	"jsessionid":    true,
+	"signature":     true,
+	"sig":           true,
+	"code":          true,
+	"client_secret": true,
+}
+
+// isSensitiveParameter reports whether the value of the query parameter with the name has to be redacted.
+func isSensitiveParameter(name string) bool {
+	name = strings.ToLower(name)
+	return sensitiveParameters[name] || strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
+
+// Query returns the raw query with the values of the sensitive parameters masked, keeping the order and the encoding
+// of the rest of them.
+func Query(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+
+	parameters := strings.Split(rawQuery, "&")
+	for i, parameter := range parameters {
+		name, _, hasValue := strings.Cut(parameter, "=")
+		unescapedName, err := url.QueryUnescape(name)
+		if err != nil {
+			unescapedName = name
+		}
+		if hasValue && isSensitiveParameter(unescapedName) {
+			parameters[i] = name + "=" + Mask
+		}
+	}
+	return strings.Join(parameters, "&")
+}
+
+// URL returns a copy of the URL with the password in its user information and the values of the sensitive query
+// parameters masked.
+func URL(uri *url.URL) *url.URL {
+	redactedURI := *uri
+	if _, hasPassword := uri.User.Password(); hasPassword {
+		redactedURI.User = url.UserPassword(uri.User.Username(), Mask)
+	}
+	redactedURI.RawQuery = Query(uri.RawQuery)
+	return &redactedURI
+}
+
+// URLString returns the URL with the credentials in it masked as URL does; strings which cannot be parsed as URLs are
+// scrubbed as text.
+func URLString(urlStr string) string {
+	uri, err := url.Parse(urlStr)
+	if err != nil {
+		return Text(urlStr)
+	}
+
+	return URL(uri).String()
+}
+
+// Header returns a copy of the header with the values of the headers which carry credentials masked.
+func Header(header http.Header) http.Header {
+	redactedHeader := header.Clone()
+	for _, key := range sensitiveHeaders {
+		values := redactedHeader.Values(key)
+		for i := range values {
+			values[i] = Mask
+		}
+	}
+	return redactedHeader
+}
+
+var (
+	urlRegexp    = regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+	headerRegexp = regexp.MustCompile(`(?i)\b(authorization|proxy-authorization|cookie|set-cookie)(\s*[:=]\s*)[^\r\n]+`)
+)
+
+// Text returns the text with the credentials in the URLs and the header lines in it masked.
+func Text(text string) string {
+	text = urlRegexp.ReplaceAllStringFunc(text, URLString)
+	return headerRegexp.ReplaceAllString(text, "${1}${2}"+Mask)
+}
@@ -0,0 +1,32 @@
+package rewrite
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var cssURLMatcher = regexp.MustCompile(`(url\s*\(["'])(.*?)(["']\))`)
+
+// CSS passes the URI of every quoted `url()` reference in the stylesheet to replace and substitutes the reference
+// which it returns, unless it reports that the reference should be left as it is.
+func CSS(css []byte, replace func(uriStr string) (reference string, ok bool)) (rewrittenCSS []byte) {
+	var rewrittenCSSBuffer bytes.Buffer
+
+	for urlMatch := cssURLMatcher.FindSubmatchIndex(css); urlMatch != nil; urlMatch = cssURLMatcher.FindSubmatchIndex(css) {
+		rewrittenCSSBuffer.Write(css[:urlMatch[0]])
+
+		reference, ok := replace(string(css[urlMatch[4]:urlMatch[5]]))
+		if ok {
+			rewrittenCSSBuffer.Write(css[urlMatch[2]:urlMatch[3]])
+			rewrittenCSSBuffer.WriteString(reference)
+			rewrittenCSSBuffer.Write(css[urlMatch[6]:urlMatch[7]])
+		} else {
+			rewrittenCSSBuffer.Write(css[urlMatch[0]:urlMatch[1]])
+		}
+		css = css[urlMatch[1]:]
+	}
+
+	rewrittenCSSBuffer.Write(css)
+	rewrittenCSS = rewrittenCSSBuffer.Bytes()
+	return
+}
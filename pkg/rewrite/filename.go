@@ -0,0 +1,168 @@
+package rewrite
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Filenames determines how the paths of the URLs of the resources are mapped to the names of their local copies. The
+// segments of the paths are percent-decoded unless they would decode to invalid UTF-8, and normalized to NFC, so that
+// an archive looks the same on every system (macOS, for one, decomposes names to NFD otherwise), while the characters
+// which some systems do not allow in filenames (control characters and any of `/<>:"\|*?`) are kept percent-encoded.
+type Filenames struct {
+	// Transliterate replaces the letters of the Latin, Greek and Cyrillic scripts with their closest ASCII equivalent
+	// and percent-encodes the rest of the non-ASCII characters, so that the names consist only of ASCII characters.
+	Transliterate bool
+}
+
+// LocalPath returns the path, relative to the directory of its host, under which the resource at the URI is stored.
+func LocalPath(uri *url.URL, contentType string) string {
+	return Filenames{}.LocalPath(uri, contentType)
+}
+
+// RelativeReference returns the reference to the local copy of the resource at the URI from a document in the directory
+// with the given path (of the URL of the document), or false if there is none.
+func RelativeReference(uri *url.URL, dirpath, contentType string) (relativeReference string, ok bool) {
+	return Filenames{}.RelativeReference(uri, dirpath, contentType)
+}
+
+// isUnsafeFilenameRune reports whether the character is not allowed in filenames by some systems.
+func isUnsafeFilenameRune(c rune) bool {
+	return c < 0x20 || c == 0x7f || strings.ContainsRune(`/<>:"\|*?`, c)
+}
+
+// percentEncode appends the percent-encoded UTF-8 form of the character to the builder.
+func percentEncode(builder *strings.Builder, c rune) {
+	var encoded [utf8.UTFMax]byte
+	for _, b := range encoded[:utf8.EncodeRune(encoded[:], c)] {
+		fmt.Fprintf(builder, "%%%02X", b)
+	}
+}
+
+// segment returns the name of the file or directory for a segment of the path of a URL, which has been decoded.
+func (filenames Filenames) segment(decodedSegment string) string {
+	decodedSegment = norm.NFC.String(decodedSegment)
+
+	var builder strings.Builder
+	for _, c := range decodedSegment {
+		switch {
+		case isUnsafeFilenameRune(c):
+			percentEncode(&builder, c)
+		case c < utf8.RuneSelf || !filenames.Transliterate:
+			builder.WriteRune(c)
+		default:
+			if transliteration, ok := transliterate(c); ok {
+				builder.WriteString(transliteration)
+			} else {
+				percentEncode(&builder, c)
+			}
+		}
+	}
+	return builder.String()
+}
+
+// path returns the slash-separated local path for the escaped path of a URL, which is made canonical by canonicalPath
+// after the segments have been decoded.
+func (filenames Filenames) path(escapedPath string) string {
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		decodedSegment, err := url.PathUnescape(segment)
+		if err != nil || !utf8.ValidString(decodedSegment) {
+			// the segment is left as it is if it cannot be decoded into text
+			continue
+		}
+
+		segments[i] = filenames.segment(decodedSegment)
+	}
+	return canonicalPath(strings.Join(segments, "/"))
+}
+
+// LocalPath returns the path, relative to the directory of its host, under which the resource at the URI is stored.
+func (filenames Filenames) LocalPath(uri *url.URL, contentType string) (relativeReference string) {
+	if uri.Opaque != "" {
+		relativeReference = canonicalPath(uri.Opaque)
+	} else {
+		relativeReference = filenames.path(uri.EscapedPath())
+	}
+	if uri.RawQuery != "" {
+		relativeReference += "?" + uri.RawQuery
+	}
+
+	return AdjustExtension(relativeReference, contentType)
+}
+
+// RelativeReference returns the reference to the local copy of the resource at the URI from a document in the directory
+// with the given path (of the URL of the document), or false if there is none. Unlike the path returned by LocalPath,
+// the reference is percent-encoded, so that it refers to the local copy even if its name contains `%` or `?`.
+func (filenames Filenames) RelativeReference(uri *url.URL, dirpath, contentType string) (relativeReference string, ok bool) {
+	if uri.Opaque != "" {
+		relativeReference = uri.Opaque
+	} else {
+		localDirpath := filepath.FromSlash(filenames.path((&url.URL{Path: filepath.ToSlash(dirpath)}).EscapedPath()))
+		relativeLinkPath, err := filepath.Rel(localDirpath, filepath.FromSlash(filenames.path(uri.EscapedPath())))
+		if err != nil {
+			return
+		}
+
+		relativeReference = (&url.URL{Path: filepath.ToSlash(relativeLinkPath)}).String()
+	}
+	if uri.RawQuery != "" {
+		relativeReference += url.PathEscape("?" + uri.RawQuery)
+	}
+
+	return AdjustExtension(relativeReference, contentType), true
+}
+
+// transliterations maps the letters which do not decompose into an ASCII letter and combining marks to their closest
+// ASCII equivalent.
+var transliterations = map[rune]string{
+	'ß': "ss", 'Æ': "AE", 'æ': "ae", 'Œ': "OE", 'œ': "oe", 'Ø': "O", 'ø': "o", 'Đ': "D", 'đ': "d", 'Ł': "L", 'ł': "l",
+	'Þ': "Th", 'þ': "th", 'Ð': "D", 'ð': "d", 'ı': "i",
+
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "Yo", 'Ж': "Zh", 'З': "Z", 'И': "I", 'Й': "Y",
+	'К': "K", 'Л': "L", 'М': "M", 'Н': "N", 'О': "O", 'П': "P", 'Р': "R", 'С': "S", 'Т': "T", 'У': "U", 'Ф': "F",
+	'Х': "Kh", 'Ц': "Ts", 'Ч': "Ch", 'Ш': "Sh", 'Щ': "Shch", 'Ъ': "", 'Ы': "Y", 'Ь': "", 'Э': "E", 'Ю': "Yu", 'Я': "Ya",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'Є': "Ye", 'І': "I", 'Ї': "Yi", 'Ґ': "G", 'Ђ': "Dj", 'Ј': "J", 'Љ': "Lj", 'Њ': "Nj", 'Ћ': "C", 'Џ': "Dz",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz",
+
+	'Α': "A", 'Β': "V", 'Γ': "G", 'Δ': "D", 'Ε': "E", 'Ζ': "Z", 'Η': "I", 'Θ': "Th", 'Ι': "I", 'Κ': "K", 'Λ': "L",
+	'Μ': "M", 'Ν': "N", 'Ξ': "X", 'Ο': "O", 'Π': "P", 'Ρ': "R", 'Σ': "S", 'Τ': "T", 'Υ': "Y", 'Φ': "F", 'Χ': "Ch",
+	'Ψ': "Ps", 'Ω': "O",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l",
+	'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f",
+	'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// transliterate returns the closest ASCII equivalent of the character, or false if there is none.
+func transliterate(c rune) (transliteration string, ok bool) {
+	if transliteration, ok := transliterations[c]; ok {
+		return transliteration, true
+	}
+
+	// letters with diacritics decompose into the base letter followed by combining marks, which are dropped
+	var builder strings.Builder
+	for _, decomposedRune := range norm.NFD.String(string(c)) {
+		if unicode.Is(unicode.Mn, decomposedRune) {
+			continue
+		}
+
+		if decomposedRune >= utf8.RuneSelf {
+			if transliteration, ok := transliterations[decomposedRune]; ok {
+				builder.WriteString(transliteration)
+				continue
+			}
+			return "", false
+		}
+		builder.WriteRune(decomposedRune)
+	}
+	return builder.String(), true
+}
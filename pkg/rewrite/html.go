@@ -0,0 +1,139 @@
+// Package rewrite transforms the HTML and CSS of archived pages so that they refer to local copies of their resources.
+package rewrite
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type writer interface {
+	io.Writer
+	io.ByteWriter
+	WriteString(string) (int, error)
+}
+
+const escapedChars = "&'<>\"\r"
+
+// shamelessly stolen from "golang.org/x/net/html"
+func escape(w writer, s string) error {
+	i := strings.IndexAny(s, escapedChars)
+	for i != -1 {
+		if _, err := w.WriteString(s[:i]); err != nil {
+			return err
+		}
+		var esc string
+		switch s[i] {
+		case '&':
+			esc = "&amp;"
+		case '\'':
+			// "&#39;" is shorter than "&apos;" and apos was not in HTML until HTML5.
+			esc = "&#39;"
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '"':
+			// "&#34;" is shorter than "&quot;".
+			esc = "&#34;"
+		case '\r':
+			esc = "&#13;"
+		default:
+			panic("unrecognized escape character")
+		}
+		s = s[i+1:]
+		if _, err := w.WriteString(esc); err != nil {
+			return err
+		}
+		i = strings.IndexAny(s, escapedChars)
+	}
+	_, err := w.WriteString(s)
+	return err
+}
+
+func tagStringWithStyleDataPreserved(token *html.Token) string {
+	if len(token.Attr) == 0 {
+		return token.Data
+	}
+	buffer := bytes.NewBufferString(token.Data)
+	for _, attr := range token.Attr {
+		buffer.WriteByte(' ')
+		buffer.WriteString(attr.Key)
+		buffer.WriteString(`="`)
+		if atom.Lookup([]byte(attr.Key)) == atom.Style || strings.HasPrefix(attr.Key, "on") {
+			buffer.WriteString(attr.Val)
+		} else {
+			escape(buffer, attr.Val)
+		}
+		buffer.WriteByte('"')
+	}
+	return buffer.String()
+}
+
+// TokenString returns the HTML for the token like token.String does, except that the content of scripts, stylesheets
+// and `noscript` and `noframes` elements (whose start tag is passed as the previous token) and of `style` and event
+// handler attributes is left unescaped, so that it keeps working after rewriting.
+func TokenString(token *html.Token, prevToken *html.Token) string {
+	switch token.Type {
+	case html.TextToken:
+		if prevToken != nil && prevToken.Type == html.StartTagToken {
+			switch prevToken.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Noframes:
+				return token.Data
+			}
+		}
+	case html.StartTagToken:
+		return "<" + tagStringWithStyleDataPreserved(token) + ">"
+	case html.SelfClosingTagToken:
+		return "<" + tagStringWithStyleDataPreserved(token) + "/>"
+	}
+
+	return token.String()
+}
+
+// LinkAttrs describes the attributes of a start tag which may reference other resources.
+type LinkAttrs struct {
+	URIAttrAtom              atom.Atom
+	URIAttrIndex, StyleIndex int
+	URI, Rel, Style          string
+	HasURI, HasRel, HasStyle bool
+}
+
+// GetLinkAttrs finds the attributes of the tag which may reference other resources.
+func GetLinkAttrs(token *html.Token) (attrs LinkAttrs) {
+	for index, attr := range token.Attr {
+		if attrs.HasURI && attrs.HasRel {
+			break
+		}
+
+		attrKeyAtom := atom.Lookup([]byte(attr.Key))
+		switch attrKeyAtom {
+		case atom.Action, atom.Code, atom.Cite, atom.Data, atom.Formaction, atom.Href, atom.Icon, atom.Manifest, atom.Poster, atom.Src, atom.Srcset, atom.Usemap:
+			attrs.URIAttrAtom, attrs.URIAttrIndex, attrs.URI, attrs.HasURI = attrKeyAtom, index, attr.Val, true
+
+		case atom.Rel:
+			attrs.Rel, attrs.HasRel = attr.Val, true
+
+		case atom.Style:
+			attrs.StyleIndex, attrs.Style, attrs.HasStyle = index, attr.Val, true
+
+		default:
+			switch attr.Key {
+			case "archive", "background", "codebase", "classid", "lowsrc", "longdesc", "profile":
+				attrs.URIAttrIndex, attrs.URI, attrs.HasURI = index, attr.Val, true
+			}
+		}
+	}
+
+	return
+}
+
+// IsEmbeddedResource reports whether the link of the tag refers to a resource which is needed to display the page
+// (and should hence be fetched locally) rather than to a page which is only navigated to.
+func (attrs *LinkAttrs) IsEmbeddedResource(token *html.Token) bool {
+	isRelInline := strings.Contains(attrs.Rel, "stylesheet") || strings.Contains(attrs.Rel, "icon") || strings.Contains(attrs.Rel, "shortcut")
+	return attrs.URIAttrAtom != atom.Action && attrs.URIAttrAtom != atom.Formaction && (attrs.URIAttrAtom != atom.Href || token.DataAtom != atom.A && token.DataAtom != atom.Area && token.DataAtom != atom.Embed && (token.DataAtom != atom.Link || attrs.HasRel && isRelInline))
+}
@@ -0,0 +1,90 @@
+package rewrite
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// OfflineContentSecurityPolicy only lets an archived page load the resources stored along with it (as well as inline
+// and data: ones), and forbids connections (including XMLHttpRequest, fetch and websockets), form submissions and
+// base URLs, so that opening the archive never contacts the original site.
+const OfflineContentSecurityPolicy = "default-src 'self' data: blob: 'unsafe-inline' 'unsafe-eval'; connect-src 'none'; form-action 'none'; base-uri 'none'"
+
+// OfflineContentSecurityPolicyTag returns the HTML of the meta tag which applies OfflineContentSecurityPolicy to the
+// page in which it is put.
+func OfflineContentSecurityPolicyTag() string {
+	token := &html.Token{
+		Type:     html.StartTagToken,
+		DataAtom: atom.Meta,
+		Data:     "meta",
+		Attr: []html.Attribute{
+			{Key: "http-equiv", Val: "Content-Security-Policy"},
+			{Key: "content", Val: OfflineContentSecurityPolicy},
+		},
+	}
+	return token.String()
+}
+
+// isExternalReference reports whether the reference points at another site rather than at a stored file.
+func isExternalReference(reference string) bool {
+	reference = strings.TrimSpace(reference)
+	if strings.HasPrefix(reference, "//") {
+		return true
+	}
+
+	uri, err := url.Parse(reference)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(uri.Scheme) {
+	case "http", "https", "ws", "wss", "ftp":
+		return true
+	}
+	return false
+}
+
+// isResourceHint reports whether the value of a `rel` attribute asks the browser to connect to the linked host ahead
+// of time.
+func isResourceHint(rel string) bool {
+	for _, relation := range strings.Fields(strings.ToLower(rel)) {
+		switch relation {
+		case "dns-prefetch", "preconnect", "prefetch", "prerender", "preload", "modulepreload":
+			return true
+		}
+	}
+	return false
+}
+
+// HardenOffline removes the attributes of the tag which would make the browser contact another site on its own when
+// the archived page is opened: the external targets of forms, the sources of external scripts, external resource
+// hints and base URLs, and hyperlink auditing pings.
+func HardenOffline(token *html.Token) {
+	if token.Type != html.StartTagToken && token.Type != html.SelfClosingTagToken {
+		return
+	}
+
+	rel, _ := getAttrVal(token, "rel")
+	attrs := token.Attr[:0]
+	for _, attr := range token.Attr {
+		isRemoved := false
+		switch atom.Lookup([]byte(attr.Key)) {
+		case atom.Action, atom.Formaction:
+			isRemoved = isExternalReference(attr.Val)
+		case atom.Src:
+			isRemoved = token.DataAtom == atom.Script && isExternalReference(attr.Val)
+		case atom.Href:
+			isRemoved = token.DataAtom == atom.Base || token.DataAtom == atom.Link && isResourceHint(rel) && isExternalReference(attr.Val)
+		case atom.Ping:
+			isRemoved = true
+		}
+
+		if !isRemoved {
+			attrs = append(attrs, attr)
+		}
+	}
+	token.Attr = attrs
+}
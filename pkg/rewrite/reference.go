@@ -0,0 +1,85 @@
+package rewrite
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+// extensionsByMediaType lists the extensions of the files of every media type which is known to be opened by browsers
+// or served by the serve subcommand according to its extension; the first one is appended to files without any.
+var extensionsByMediaType = map[string][]string{
+	"text/html":             {".html", ".htm"},
+	"application/xhtml+xml": {".html", ".htm", ".xhtml"},
+	"text/css":              {".css"},
+	"application/atom+xml":  {".atom"},
+	"application/rss+xml":   {".rss"},
+
+	"text/javascript":          {".js", ".mjs"},
+	"application/javascript":   {".js", ".mjs"},
+	"application/x-javascript": {".js", ".mjs"},
+	"application/json":         {".json"},
+
+	"image/png":                {".png"},
+	"image/jpeg":               {".jpg", ".jpeg", ".jpe"},
+	"image/gif":                {".gif"},
+	"image/webp":               {".webp"},
+	"image/avif":               {".avif"},
+	"image/svg+xml":            {".svg"},
+	"image/bmp":                {".bmp"},
+	"image/x-icon":             {".ico"},
+	"image/vnd.microsoft.icon": {".ico"},
+
+	"font/woff":                     {".woff"},
+	"font/woff2":                    {".woff2"},
+	"font/ttf":                      {".ttf"},
+	"font/otf":                      {".otf"},
+	"application/font-woff":         {".woff"},
+	"application/font-woff2":        {".woff2"},
+	"application/x-font-ttf":        {".ttf"},
+	"application/x-font-otf":        {".otf"},
+	"application/vnd.ms-fontobject": {".eot"},
+
+	"video/mp4":        {".mp4", ".m4v"},
+	"video/webm":       {".webm"},
+	"video/ogg":        {".ogv"},
+	"video/quicktime":  {".mov"},
+	"video/x-matroska": {".mkv"},
+}
+
+// AdjustExtension appends the extension which corresponds to the content type to the filename if it does not already
+// have it, so that the archived file is opened correctly by browsers.
+func AdjustExtension(filename, contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return filename
+	}
+
+	extensions, ok := extensionsByMediaType[mediaType]
+	if !ok {
+		return filename
+	}
+
+	lowercaseFilename := strings.ToLower(filename)
+	for _, extension := range extensions {
+		if strings.HasSuffix(lowercaseFilename, extension) {
+			return filename
+		}
+	}
+
+	return filename + extensions[0]
+}
+
+// canonicalPath removes the dot segments (including the ones which were percent-encoded in the URL) and duplicate
+// slashes from the path, treating it as rooted so that it cannot climb above the directory of its host.
+func canonicalPath(uriPath string) string {
+	if uriPath == "" {
+		return ""
+	}
+
+	canonicalURIPath := path.Clean("/" + uriPath)
+	if strings.HasSuffix(uriPath, "/") && canonicalURIPath != "/" {
+		canonicalURIPath += "/"
+	}
+	return canonicalURIPath
+}
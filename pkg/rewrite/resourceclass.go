@@ -0,0 +1,124 @@
+package rewrite
+
+import (
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ResourceClass is the kind of an embedded resource, which determines whether it is localized.
+type ResourceClass int
+
+const (
+	// ResourceClassUnknown means that the class has to be determined from the content type of the resource.
+	ResourceClassUnknown ResourceClass = iota
+	ResourceClassImage
+	ResourceClassFont
+	ResourceClassMedia
+	ResourceClassStylesheet
+	ResourceClassScript
+	ResourceClassOther
+)
+
+var resourceClassNames = []string{"unknown", "image", "font", "media", "stylesheet", "script", "other"}
+
+func (class ResourceClass) String() string {
+	return resourceClassNames[class]
+}
+
+func getAttrVal(token *html.Token, key string) (val string, ok bool) {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+
+	return
+}
+
+// ElementResourceClass determines the class of the resource linked from the tag from the context of the element,
+// if it is unambiguous.
+func ElementResourceClass(token *html.Token, attrs *LinkAttrs) ResourceClass {
+	switch attrs.URIAttrAtom {
+	case atom.Poster, atom.Srcset:
+		return ResourceClassImage
+	}
+	switch token.Attr[attrs.URIAttrIndex].Key {
+	case "background", "lowsrc":
+		return ResourceClassImage
+	}
+
+	switch token.DataAtom {
+	case atom.Img:
+		return ResourceClassImage
+	case atom.Input:
+		if inputType, _ := getAttrVal(token, "type"); strings.EqualFold(inputType, "image") {
+			return ResourceClassImage
+		}
+	case atom.Video, atom.Audio, atom.Track:
+		return ResourceClassMedia
+	case atom.Source:
+		if sourceType, ok := getAttrVal(token, "type"); ok {
+			return ContentTypeResourceClass(sourceType)
+		}
+		return ResourceClassMedia
+	case atom.Script:
+		return ResourceClassScript
+	case atom.Object, atom.Embed, atom.Applet, atom.Iframe, atom.Frame:
+		return ResourceClassOther
+	case atom.Link:
+		switch {
+		case strings.Contains(attrs.Rel, "stylesheet"):
+			return ResourceClassStylesheet
+		case strings.Contains(attrs.Rel, "icon"):
+			return ResourceClassImage
+		}
+
+		switch as, _ := getAttrVal(token, "as"); strings.ToLower(as) {
+		case "font":
+			return ResourceClassFont
+		case "image":
+			return ResourceClassImage
+		case "audio", "video", "track":
+			return ResourceClassMedia
+		case "style":
+			return ResourceClassStylesheet
+		case "script":
+			return ResourceClassScript
+		}
+	}
+
+	return ResourceClassUnknown
+}
+
+// ContentTypeResourceClass determines the class of a resource from its content type.
+func ContentTypeResourceClass(contentType string) ResourceClass {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	switch mediaType {
+	case "":
+		return ResourceClassUnknown
+	case "text/css":
+		return ResourceClassStylesheet
+	case "text/javascript", "application/javascript", "application/x-javascript", "application/ecmascript", "text/ecmascript":
+		return ResourceClassScript
+	case "application/vnd.ms-fontobject", "application/x-font-ttf", "application/x-font-otf", "application/x-font-woff", "application/font-woff", "application/font-woff2", "application/font-sfnt":
+		return ResourceClassFont
+	}
+
+	switch topLevelType, _, _ := strings.Cut(mediaType, "/"); topLevelType {
+	case "image":
+		return ResourceClassImage
+	case "font":
+		return ResourceClassFont
+	case "audio", "video":
+		return ResourceClassMedia
+	}
+
+	return ResourceClassOther
+}
@@ -0,0 +1,30 @@
+package rewrite
+
+import "net/url"
+
+// Reference describes a reference to a resource which is about to be rewritten to refer to the local copy of the
+// resource.
+type Reference struct {
+	// Original is the reference as it appears in the page or stylesheet.
+	Original string
+	// Resolved is the absolute URL of the resource.
+	Resolved *url.URL
+	// LocalPath is the reference to the local copy of the resource, relative to the referring page or stylesheet,
+	// which is proposed to replace the original one.
+	LocalPath string
+}
+
+// Rewriter is consulted before every rewrite of a reference to a resource. It returns the reference which replaces the
+// original one, which may be the proposed local path, the resolved URL (leaving the reference absolute) or anything
+// else, e.g. the URL of a mirror. Returning false vetoes the rewrite, in which case the reference is replaced with the
+// resolved URL, so that it keeps referring to the original resource.
+type Rewriter interface {
+	Rewrite(reference *Reference) (replacement string, ok bool)
+}
+
+// RewriterFunc adapts an ordinary function to the Rewriter interface.
+type RewriterFunc func(reference *Reference) (replacement string, ok bool)
+
+func (rewriter RewriterFunc) Rewrite(reference *Reference) (replacement string, ok bool) {
+	return rewriter(reference)
+}
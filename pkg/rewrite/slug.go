@@ -0,0 +1,69 @@
+package rewrite
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxSlugLength is the largest number of characters of the slug of a title (see Slug).
+const MaxSlugLength = 60
+
+// pageTitleFragmentPattern matches the parts of the titles of the pages of a topic which tell the pages apart, such as
+// `Page 17`, `- Page 17 of 40` or `(page 3)`.
+var pageTitleFragmentPattern = regexp.MustCompile(`(?i)\s*[-–—|:,]?\s*\(?\s*page\s+\d+(\s*(of|/)\s*\d+)?\s*\)?`)
+
+// TopicTitle returns the title of the topic from the title of one of its pages, without the number of the page.
+func TopicTitle(pageTitle string) string {
+	return strings.Join(strings.Fields(pageTitleFragmentPattern.ReplaceAllString(pageTitle, " ")), " ")
+}
+
+// Slug returns the title in lowercase ASCII letters and digits, with the words separated by hyphens and the letters of
+// the Latin, Greek and Cyrillic scripts transliterated, cut at a word boundary to at most MaxSlugLength characters. It
+// returns an empty slug if the title has no letters or digits which can be written in ASCII.
+func Slug(title string) string {
+	var words []string
+	var word strings.Builder
+	endWord := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, c := range strings.ToLower(title) {
+		switch {
+		case c < utf8.RuneSelf && (unicode.IsLetter(c) || unicode.IsDigit(c)):
+			word.WriteRune(c)
+		case c >= utf8.RuneSelf && (unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.Is(unicode.Mn, c)):
+			if transliteration, ok := transliterate(c); ok {
+				word.WriteString(strings.ToLower(transliteration))
+			}
+		default:
+			endWord()
+		}
+	}
+	endWord()
+
+	var slug strings.Builder
+	for _, word := range words {
+		if slug.Len() > 0 && slug.Len()+1+len(word) > MaxSlugLength {
+			break
+		}
+		if slug.Len() > 0 {
+			slug.WriteByte('-')
+		}
+		slug.WriteString(word)
+	}
+	if slug.Len() > MaxSlugLength {
+		return slug.String()[:MaxSlugLength]
+	}
+	return slug.String()
+}
+
+// TitledPageFilename returns the name of the file of the page with the number of the topic with the slug of its title,
+// e.g. `great-debate-p017.html`.
+func TitledPageFilename(slug string, pageNumber uint) string {
+	return fmt.Sprintf("%s-p%03d.html", slug, pageNumber)
+}
@@ -0,0 +1,73 @@
+package rewrite
+
+import (
+	"bytes"
+	"regexp"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// InjectedStylesheetAttr marks the links to the stylesheets which are injected into a page, with the kind of the
+// stylesheet as its value, so that every kind is injected only once.
+const InjectedStylesheetAttr = "data-injected-stylesheet"
+
+// StylesheetKind is the kind of a stylesheet which is injected into a page.
+type StylesheetKind string
+
+const (
+	// UserStylesheet is a stylesheet of the user (e.g. a dark theme), which applies to all media.
+	UserStylesheet StylesheetKind = ""
+	// PrintStylesheet is a print-ready stylesheet, which applies only when the page is printed.
+	PrintStylesheet StylesheetKind = "print"
+)
+
+// InjectedStylesheetTag returns the HTML of the link to the stylesheet of the kind at the reference.
+func InjectedStylesheetTag(reference string, kind StylesheetKind) string {
+	token := &html.Token{
+		Type:     html.SelfClosingTagToken,
+		DataAtom: atom.Link,
+		Data:     "link",
+		Attr: []html.Attribute{
+			{Key: "rel", Val: "stylesheet"},
+			{Key: "href", Val: reference},
+		},
+	}
+	if kind == PrintStylesheet {
+		token.Attr = append(token.Attr, html.Attribute{Key: "media", Val: "print"})
+	}
+	token.Attr = append(token.Attr, html.Attribute{Key: InjectedStylesheetAttr, Val: string(kind)})
+	return token.String()
+}
+
+// IsStylesheetInjectionPoint reports whether the links to the injected stylesheets go before the token: the end tag of
+// the head, so that the injected stylesheets override the ones of the page, or the start tag of the body if the head is
+// not closed explicitly.
+func IsStylesheetInjectionPoint(token *html.Token) bool {
+	return token.Type == html.EndTagToken && token.DataAtom == atom.Head ||
+		(token.Type == html.StartTagToken || token.Type == html.SelfClosingTagToken) && token.DataAtom == atom.Body
+}
+
+// stylesheetInjectionPointPattern matches the end tag of the head or the start tag of the body of an HTML document.
+var stylesheetInjectionPointPattern = regexp.MustCompile(`(?i)</head\s*>|<body[\s/>]`)
+
+// InjectStylesheet returns the content of the HTML document with the link to the stylesheet of the kind at the
+// reference inserted at the injection point (see IsStylesheetInjectionPoint), or at its end if it has none, leaving the
+// rest of it intact. It returns false if a stylesheet of the kind has already been injected into the document.
+func InjectStylesheet(content []byte, reference string, kind StylesheetKind) (injectedContent []byte, ok bool) {
+	if bytes.Contains(content, []byte(InjectedStylesheetAttr+`="`+string(kind)+`"`)) {
+		return content, false
+	}
+
+	tag := []byte(InjectedStylesheetTag(reference, kind))
+	offset := len(content)
+	if location := stylesheetInjectionPointPattern.FindIndex(content); location != nil {
+		offset = location[0]
+	}
+
+	injectedContent = make([]byte, 0, len(content)+len(tag))
+	injectedContent = append(injectedContent, content[:offset]...)
+	injectedContent = append(injectedContent, tag...)
+	injectedContent = append(injectedContent, content[offset:]...)
+	return injectedContent, true
+}
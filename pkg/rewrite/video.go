@@ -0,0 +1,96 @@
+package rewrite
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EmbeddedVideo describes a video embedded in a page through the player of a video hosting service.
+type EmbeddedVideo struct {
+	// Service is the name of the hosting service, e.g. `youtube`.
+	Service string
+	// ID identifies the video within the service; it consists only of letters, digits, `-` and `_`.
+	ID string
+	// URL is the URL of the page of the video, from which it can be downloaded.
+	URL string
+}
+
+// LocalURL returns the URL, relative to the host of the page in which the video is embedded, under which the local
+// copy of the video is stored.
+func (video *EmbeddedVideo) LocalURL() *url.URL {
+	return &url.URL{Path: path.Join("/videos", video.Service, video.ID)}
+}
+
+// VideoMediaTypes maps the extensions of the video files which browsers play to their media type.
+var VideoMediaTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".mov":  "video/quicktime",
+	".mkv":  "video/x-matroska",
+}
+
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// GetEmbeddedVideo reports whether the tag is the frame of the player of a video hosted by YouTube, Vimeo or
+// Streamable, whose source has been resolved to srcURL, and describes the video.
+func GetEmbeddedVideo(token *html.Token, srcURL *url.URL) (video *EmbeddedVideo, ok bool) {
+	if token.DataAtom != atom.Iframe {
+		return
+	}
+
+	segments := strings.Split(strings.Trim(srcURL.Path, "/"), "/")
+	if len(segments) != 2 || !videoIDPattern.MatchString(segments[1]) {
+		return
+	}
+
+	id := segments[1]
+	switch strings.TrimPrefix(strings.ToLower(srcURL.Hostname()), "www.") {
+	case "youtube.com", "m.youtube.com", "youtube-nocookie.com":
+		if segments[0] == "embed" {
+			video = &EmbeddedVideo{Service: "youtube", ID: id, URL: "https://www.youtube.com/watch?v=" + id}
+		}
+	case "player.vimeo.com":
+		if segments[0] == "video" {
+			video = &EmbeddedVideo{Service: "vimeo", ID: id, URL: "https://vimeo.com/" + id}
+			// unlisted videos can only be accessed along with their hash
+			if hash := srcURL.Query().Get("h"); videoIDPattern.MatchString(hash) {
+				video.URL += "/" + hash
+			}
+		}
+	case "streamable.com":
+		if segments[0] == "e" || segments[0] == "o" || segments[0] == "s" {
+			video = &EmbeddedVideo{Service: "streamable", ID: id, URL: "https://streamable.com/" + id}
+		}
+	}
+
+	return video, video != nil
+}
+
+// VideoTag returns the start tag of the `video` element which replaces the frame of the player of an embedded video,
+// playing the local copy of the video at the reference instead; the size, title and styling of the frame are kept.
+func VideoTag(frameToken *html.Token, reference string) html.Token {
+	token := html.Token{
+		Type:     html.StartTagToken,
+		DataAtom: atom.Video,
+		Data:     "video",
+		Attr: []html.Attribute{
+			{Key: "src", Val: reference},
+			{Key: "controls"},
+			{Key: "preload", Val: "metadata"},
+		},
+	}
+	for _, attr := range frameToken.Attr {
+		switch attr.Key {
+		case "width", "height", "title", "class", "id", "style":
+			token.Attr = append(token.Attr, attr)
+		}
+	}
+	return token
+}
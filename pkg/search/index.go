@@ -0,0 +1,205 @@
+// Package search maintains a full-text index of the posts in an archived forum topic.
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"golang.org/x/net/html"
+)
+
+// IndexFilename is the name of the file in which the index of an archive is stored.
+const IndexFilename = "searchindex.json"
+
+// snippetRadius is the number of characters of context shown on either side of the first match in a snippet.
+const snippetRadius = 80
+
+// Document is an indexed post.
+type Document struct {
+	Page   uint   `json:"page"`
+	PostID string `json:"postID,omitempty"`
+	Anchor string `json:"anchor,omitempty"`
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"`
+}
+
+// Result is a post which matches a query.
+type Result struct {
+	*Document
+	// Score is the number of occurrences of the terms of the query in the post.
+	Score int
+	// Snippet is the part of the text of the post around the first match.
+	Snippet string
+}
+
+// Options determine the text of the posts which is indexed.
+type Options struct {
+	// StripSignatures leaves the signatures of the posts out of the indexed text.
+	StripSignatures bool `json:"stripSignatures,omitempty"`
+	// Emoji replaces the codes of the smileys in the indexed text with the Unicode emoji which look like them (see
+	// engine.ReplaceSmileyCodes).
+	Emoji bool `json:"emoji,omitempty"`
+}
+
+// Index is an inverted index of the posts in an archive.
+type Index struct {
+	Documents []*Document `json:"documents"`
+	// Postings maps every term to the indices of the documents containing it, once for every occurrence.
+	Postings map[string][]int `json:"postings"`
+	Options
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{
+		Postings: map[string][]int{},
+	}
+}
+
+// Tokenize splits the text into lowercase terms consisting of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Add indexes the document.
+func (index *Index) Add(document *Document) {
+	documentIndex := len(index.Documents)
+	index.Documents = append(index.Documents, document)
+	for _, term := range Tokenize(document.Author + " " + document.Text) {
+		index.Postings[term] = append(index.Postings[term], documentIndex)
+	}
+}
+
+// AddPage indexes the posts on the page with the given number, as extracted by the engine of the forum.
+func (index *Index) AddPage(pageNumber uint, doc *html.Node, forumEngine engine.Engine) {
+	for _, post := range forumEngine.ExtractPosts(doc) {
+		text := post.Text
+		if post.Signature != "" && !index.StripSignatures {
+			text += " " + post.Signature
+		}
+		if index.Emoji {
+			text = engine.ReplaceSmileyCodes(text)
+		}
+
+		index.Add(&Document{
+			Page:   pageNumber,
+			PostID: post.ID,
+			Anchor: post.Anchor,
+			Author: post.Author,
+			Text:   text,
+		})
+	}
+}
+
+// Search returns the documents which contain all terms of the query, ordered by descending score and then by their
+// position in the topic.
+func (index *Index) Search(query string) (results []*Result) {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return
+	}
+
+	var scores map[int]int
+	for _, term := range terms {
+		termScores := map[int]int{}
+		for _, documentIndex := range index.Postings[term] {
+			if scores == nil || scores[documentIndex] > 0 {
+				termScores[documentIndex]++
+			}
+		}
+		for documentIndex := range termScores {
+			if scores != nil {
+				termScores[documentIndex] += scores[documentIndex]
+			}
+		}
+		scores = termScores
+	}
+
+	for documentIndex, score := range scores {
+		document := index.Documents[documentIndex]
+		results = append(results, &Result{
+			Document: document,
+			Score:    score,
+			Snippet:  snippet(document.Text, terms),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Page < results[j].Page
+	})
+	return
+}
+
+// snippet returns the part of the text around the first occurrence of any of the terms.
+func snippet(text string, terms []string) string {
+	lowerText := strings.ToLower(text)
+	start := -1
+	for _, term := range terms {
+		if index := strings.Index(lowerText, term); index >= 0 && (start < 0 || index < start) {
+			start = index
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	runes := []rune(text)
+	runeStart := len([]rune(lowerText[:start]))
+	from := max(runeStart-snippetRadius, 0)
+	to := min(runeStart+snippetRadius, len(runes))
+
+	snippet := string(runes[from:to])
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// Save writes the index to the archive in the directory.
+func (index *Index) Save(dir string) (err error) {
+	filename := filepath.Join(dir, IndexFilename)
+	file, err := archive.Create(filename)
+	if err != nil {
+		return fmt.Errorf("could not create search index %s", filename)
+	}
+	defer file.Close()
+
+	err = json.NewEncoder(file).Encode(index)
+	if err != nil {
+		return fmt.Errorf("could not write search index %s", filename)
+	}
+
+	return
+}
+
+// Load reads the index of the archive in the directory.
+func Load(dir string) (index *Index, err error) {
+	filename := filepath.Join(dir, IndexFilename)
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	index = NewIndex()
+	err = json.NewDecoder(file).Decode(index)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse search index %s: %v", filename, err)
+	}
+
+	return
+}
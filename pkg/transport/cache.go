@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+type cachedResponse struct {
+	response *http.Response
+	body     []byte
+}
+
+// Cache keeps the successful responses to GET requests whose body is at most maxBodySize bytes long in memory and serves
+// the requests for the same URL from it afterwards. It spares the fetching of the resources which appear on many pages
+// (e.g. the images and stylesheets of the theme of the forum) over and over again.
+func Cache(maxBodySize int64) Middleware {
+	var mutex sync.Mutex
+	responses := map[string]*cachedResponse{}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (response *http.Response, err error) {
+			if request.Method != http.MethodGet || request.Header.Get("Range") != "" {
+				return next.RoundTrip(request)
+			}
+
+			key := request.URL.String()
+			mutex.Lock()
+			cached, ok := responses[key]
+			mutex.Unlock()
+			if ok {
+				response = new(http.Response)
+				*response = *cached.response
+				response.Header = cached.response.Header.Clone()
+				response.Body = io.NopCloser(bytes.NewReader(cached.body))
+				response.Request = request
+				return
+			}
+
+			response, err = next.RoundTrip(request)
+			if err != nil || response.StatusCode != http.StatusOK || response.ContentLength < 0 || response.ContentLength > maxBodySize {
+				return
+			}
+
+			body, err := io.ReadAll(response.Body)
+			response.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			cachedCopy := new(http.Response)
+			*cachedCopy = *response
+			cachedCopy.Header = response.Header.Clone()
+			cachedCopy.Body = nil
+			mutex.Lock()
+			responses[key] = &cachedResponse{cachedCopy, body}
+			mutex.Unlock()
+
+			response.Body = io.NopCloser(bytes.NewReader(body))
+			return
+		})
+	}
+}
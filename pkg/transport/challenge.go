@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// challengeServers are the values of the Server header of the anti-bot services whose interstitials are recognized.
+var challengeServers = []string{"cloudflare", "ddos-guard"}
+
+// challengeMarkers are fragments of the HTML of the interstitial pages of the anti-bot services, which are served in
+// place of the requested page until the browser has run their JavaScript.
+var challengeMarkers = [][]byte{
+	[]byte("<title>Just a moment...</title>"),
+	[]byte("/cdn-cgi/challenge-platform/"),
+	[]byte("cf_chl_opt"),
+	[]byte("cf-browser-verification"),
+	[]byte("<title>DDoS-Guard</title>"),
+}
+
+// IsChallenge reports whether the response is the interstitial of an anti-bot service (such as Cloudflare) challenging
+// the client to prove that it is a browser, rather than a genuine error of the server.
+func IsChallenge(response *http.Response) bool {
+	if response.Header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+
+	switch response.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		server := strings.ToLower(response.Header.Get("Server"))
+		for _, challengeServer := range challengeServers {
+			if strings.Contains(server, challengeServer) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsChallengePage reports whether the start of the content of an HTML page is that of the interstitial of an anti-bot
+// service, which some of them serve with an OK status.
+func IsChallengePage(content []byte) bool {
+	for _, marker := range challengeMarkers {
+		if bytes.Contains(content, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Clearance presents the clearance cookies obtained by passing the challenge of an anti-bot service in a browser with
+// the requests directed to the given host or its subdomains, along with the User-Agent of that browser, to which the
+// cookies are bound. The cookies which the host sets or refreshes are kept for the rest of the session. Requests to
+// other hosts are only given the User-Agent, if any.
+func Clearance(host string, cookies []*http.Cookie, userAgent string) Middleware {
+	var mutex sync.Mutex
+	jar := make(map[string]*http.Cookie)
+	for _, cookie := range cookies {
+		jar[cookie.Name] = cookie
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			isClearedHost := request.URL.Hostname() == host || strings.HasSuffix(request.URL.Hostname(), "."+host)
+			if !isClearedHost && userAgent == "" {
+				return next.RoundTrip(request)
+			}
+
+			// a round tripper must not modify the request
+			request = request.Clone(request.Context())
+			if userAgent != "" {
+				request.Header.Set("User-Agent", userAgent)
+			}
+			if !isClearedHost {
+				return next.RoundTrip(request)
+			}
+
+			mutex.Lock()
+			cookies := make([]*http.Cookie, 0, len(jar))
+			for _, cookie := range jar {
+				cookies = append(cookies, cookie)
+			}
+			mutex.Unlock()
+			setCookies(request, cookies)
+
+			response, err := next.RoundTrip(request)
+			if err != nil {
+				return nil, err
+			}
+
+			mutex.Lock()
+			for _, cookie := range response.Cookies() {
+				if cookie.MaxAge < 0 {
+					delete(jar, cookie.Name)
+				} else {
+					jar[cookie.Name] = &http.Cookie{Name: cookie.Name, Value: cookie.Value}
+				}
+			}
+			mutex.Unlock()
+
+			return response, nil
+		})
+	}
+}
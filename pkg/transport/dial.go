@@ -0,0 +1,16 @@
+package transport
+
+import (
+	"context"
+	"net"
+)
+
+// UnixSocketDial returns a dial function which connects to the Unix socket at the path whatever the address, so that
+// all the connections are served by what listens on the socket, e.g. a local reverse proxy, a socket forwarded over
+// SSH or a test server.
+func UnixSocketDial(path string) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", path)
+	}
+}
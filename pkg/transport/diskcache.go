@@ -0,0 +1,331 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diskCacheEntry is the stored metadata of a response in the disk cache, whose body is stored next to it.
+type diskCacheEntry struct {
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	// RequestTime and ResponseTime are when the request for the response was sent and when the response was received,
+	// from which its age is calculated.
+	RequestTime  time.Time `json:"requestTime"`
+	ResponseTime time.Time `json:"responseTime"`
+}
+
+const (
+	diskCacheEntrySuffix = ".json"
+	diskCacheBodySuffix  = ".body"
+)
+
+// parseCacheControl returns the directives of the Cache-Control header with their arguments, if any.
+func parseCacheControl(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, argument, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(argument, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// freshnessLifetime returns how long the response stays fresh after it was generated: as long as its max-age, the time
+// until it expires or, if it has neither, a tenth of the time since it was last modified.
+func (entry *diskCacheEntry) freshnessLifetime() time.Duration {
+	if maxAge, ok := parseCacheControl(entry.Header)["max-age"]; ok {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(entry.Header.Get("Date"))
+	if err != nil {
+		date = entry.ResponseTime
+	}
+	if expires := entry.Header.Get("Expires"); expires != "" {
+		expirationTime, err := http.ParseTime(expires)
+		if err != nil {
+			// an invalid date, such as 0, means that the response has already expired
+			return 0
+		}
+		return expirationTime.Sub(date)
+	}
+	if lastModified, err := http.ParseTime(entry.Header.Get("Last-Modified")); err == nil && lastModified.Before(date) {
+		return date.Sub(lastModified) / 10
+	}
+
+	return 0
+}
+
+// age returns the time since the response was generated by the server.
+func (entry *diskCacheEntry) age(now time.Time) time.Duration {
+	apparentAge := time.Duration(0)
+	if date, err := http.ParseTime(entry.Header.Get("Date")); err == nil && entry.ResponseTime.After(date) {
+		apparentAge = entry.ResponseTime.Sub(date)
+	}
+	if ageSeconds, err := strconv.ParseInt(entry.Header.Get("Age"), 10, 64); err == nil {
+		apparentAge = max(apparentAge, time.Duration(ageSeconds)*time.Second+entry.ResponseTime.Sub(entry.RequestTime))
+	}
+
+	return apparentAge + now.Sub(entry.ResponseTime)
+}
+
+// isFresh reports whether the response may be served from the cache without revalidating it with the server.
+func (entry *diskCacheEntry) isFresh(now time.Time) bool {
+	if _, ok := parseCacheControl(entry.Header)["no-cache"]; ok {
+		return false
+	}
+
+	return entry.age(now) < entry.freshnessLifetime()
+}
+
+// isStorable reports whether the response to the request may be stored in the disk cache: it must be a complete
+// successful response to an unauthenticated GET request, it must not be an HTML page (the pages of the topic are kept
+// up to date by other means), neither the request nor the response may forbid storing it and it must vary at most by
+// the encoding, which the client negotiates by itself.
+func isStorable(request *http.Request, response *http.Response) bool {
+	if response.StatusCode != http.StatusOK || request.Header.Get("Authorization") != "" {
+		return false
+	}
+	if _, ok := parseCacheControl(request.Header)["no-store"]; ok {
+		return false
+	}
+	directives := parseCacheControl(response.Header)
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType == "text/html" {
+		return false
+	}
+	for _, vary := range response.Header.Values("Vary") {
+		for _, field := range strings.Split(vary, ",") {
+			if field = strings.TrimSpace(field); field != "" && !strings.EqualFold(field, "Accept-Encoding") {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// cachingBody is the body of a response which is written to the disk cache while it is being read; the entry is
+// stored once the body has been read completely.
+type cachingBody struct {
+	io.ReadCloser
+	file       *os.File
+	entry      *diskCacheEntry
+	entryPath  string
+	isComplete bool
+	err        error
+}
+
+func (body *cachingBody) Read(p []byte) (n int, err error) {
+	n, err = body.ReadCloser.Read(p)
+	if n > 0 && body.err == nil {
+		_, body.err = body.file.Write(p[:n])
+	}
+	if err == io.EOF {
+		body.isComplete = true
+	}
+	return
+}
+
+func (body *cachingBody) Close() error {
+	err := body.ReadCloser.Close()
+
+	bodyTempPath := body.file.Name()
+	closeErr := body.file.Close()
+	if !body.isComplete || body.err != nil || closeErr != nil || storeDiskCacheEntry(body.entryPath, body.entry, bodyTempPath) != nil {
+		os.Remove(bodyTempPath)
+	}
+	return err
+}
+
+// storeDiskCacheEntry moves the body of the response from the temporary file into place and writes the metadata of the
+// response next to it.
+func storeDiskCacheEntry(entryPath string, entry *diskCacheEntry, bodyTempPath string) error {
+	err := os.Rename(bodyTempPath, entryPath+diskCacheBodySuffix)
+	if err != nil {
+		return err
+	}
+
+	return writeDiskCacheEntry(entryPath, entry)
+}
+
+// writeDiskCacheEntry writes the metadata of the response to a temporary file, which then replaces the one in the
+// cache, so that a concurrent reader never sees a partial entry.
+func writeDiskCacheEntry(entryPath string, entry *diskCacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(entryPath), filepath.Base(entryPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), entryPath+diskCacheEntrySuffix)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// readDiskCacheEntry reads the metadata of the response to the request for the URL stored at the path, or returns
+// false if there is none.
+func readDiskCacheEntry(entryPath, urlStr string) (entry *diskCacheEntry, ok bool) {
+	content, err := os.ReadFile(entryPath + diskCacheEntrySuffix)
+	if err != nil {
+		return
+	}
+
+	entry = &diskCacheEntry{}
+	if json.Unmarshal(content, entry) != nil || entry.URL != urlStr {
+		return nil, false
+	}
+	return entry, true
+}
+
+// cachedResponseFromDisk returns the response stored in the entry at the path in reply to the request, or false if its
+// body cannot be read.
+func cachedResponseFromDisk(request *http.Request, entryPath string, entry *diskCacheEntry) (response *http.Response, ok bool) {
+	body, err := os.Open(entryPath + diskCacheBodySuffix)
+	if err != nil {
+		return
+	}
+
+	contentLength := int64(-1)
+	if info, err := body.Stat(); err == nil {
+		contentLength = info.Size()
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(entry.Status) + " " + http.StatusText(entry.Status),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          body,
+		ContentLength: contentLength,
+		Request:       request,
+	}, true
+}
+
+// DiskCache keeps the responses to the GET requests for resources other than HTML pages in the directory and serves
+// the requests for the same URLs from it for as long as the responses stay fresh according to their Cache-Control and
+// Expires headers (or, lacking both, a tenth of the time since they were last modified), following RFC 7234 as a
+// private cache. Stale responses with a validator are revalidated with a conditional request, and the cached body is
+// served if the server replies that it has not been modified. Unlike Cache, the responses are kept across runs and
+// shared by the archives of all the topics of a forum, so that its theme is not downloaded again for every topic.
+// Requests which are already conditional or which ask for a range are passed through.
+func DiskCache(dir string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.Method != http.MethodGet || request.URL.Scheme != "http" && request.URL.Scheme != "https" || request.Header.Get("Range") != "" ||
+				request.Header.Get("If-None-Match") != "" || request.Header.Get("If-Modified-Since") != "" {
+				return next.RoundTrip(request)
+			}
+
+			urlStr := request.URL.String()
+			hash := sha256.Sum256([]byte(urlStr))
+			key := hex.EncodeToString(hash[:])
+			entryPath := filepath.Join(dir, key[:2], key)
+
+			_, isRevalidationRequired := parseCacheControl(request.Header)["no-cache"]
+			entry, isCached := readDiskCacheEntry(entryPath, urlStr)
+			if isCached && !isRevalidationRequired && entry.isFresh(time.Now()) {
+				if response, ok := cachedResponseFromDisk(request, entryPath, entry); ok {
+					return response, nil
+				}
+				isCached = false
+			}
+
+			forwardedRequest := request
+			if isCached {
+				etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+				if etag != "" || lastModified != "" {
+					// a round tripper must not modify the request
+					forwardedRequest = request.Clone(request.Context())
+					if etag != "" {
+						forwardedRequest.Header.Set("If-None-Match", etag)
+					}
+					if lastModified != "" {
+						forwardedRequest.Header.Set("If-Modified-Since", lastModified)
+					}
+				}
+			}
+
+			requestTime := time.Now()
+			response, err := next.RoundTrip(forwardedRequest)
+			if err != nil {
+				return nil, err
+			}
+			responseTime := time.Now()
+
+			if isCached && response.StatusCode == http.StatusNotModified {
+				// the headers of the 304 response update the stored ones
+				for name, values := range response.Header {
+					entry.Header[name] = values
+				}
+				entry.RequestTime, entry.ResponseTime = requestTime, responseTime
+				if cachedResponse, ok := cachedResponseFromDisk(request, entryPath, entry); ok {
+					response.Body.Close()
+					writeDiskCacheEntry(entryPath, entry)
+					return cachedResponse, nil
+				}
+			}
+			if !isStorable(request, response) {
+				return response, nil
+			}
+
+			err = os.MkdirAll(filepath.Dir(entryPath), os.ModePerm)
+			if err != nil {
+				return response, nil
+			}
+			file, err := os.CreateTemp(filepath.Dir(entryPath), key+".*.tmp")
+			if err != nil {
+				return response, nil
+			}
+
+			header := response.Header.Clone()
+			header.Del("Set-Cookie")
+			response.Body = &cachingBody{
+				ReadCloser: response.Body,
+				file:       file,
+				entry: &diskCacheEntry{
+					URL:          urlStr,
+					Status:       response.StatusCode,
+					Header:       header,
+					RequestTime:  requestTime,
+					ResponseTime: responseTime,
+				},
+				entryPath: entryPath,
+			}
+			return response, nil
+		})
+	}
+}
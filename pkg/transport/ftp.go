@@ -0,0 +1,264 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// DialFunc connects to the address on the network, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+const (
+	defaultFTPPort  = "21"
+	defaultSFTPPort = "22"
+	// anonymousFTPUser is the user as which the FTP servers are logged in to if the URL does not name one.
+	anonymousFTPUser = "anonymous"
+)
+
+// fileResponseBody is the content of a file retrieved from an FTP or SFTP server, closing which also closes the
+// connection to the server.
+type fileResponseBody struct {
+	io.ReadCloser
+	conn io.Closer
+}
+
+func (body fileResponseBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.conn.Close()
+	return err
+}
+
+// newFileResponse returns an OK response to the request with the content of the file at the path, whose content type
+// is guessed from its extension.
+func newFileResponse(request *http.Request, filePath string, body io.ReadCloser, size int64) *http.Response {
+	header := http.Header{}
+	if contentType := mime.TypeByExtension(path.Ext(filePath)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: size,
+		Request:       request,
+	}
+}
+
+// hostAddress returns the address of the host of the URL, with the given port if the URL has none.
+func hostAddress(serverURL *url.URL, defaultPort string) string {
+	port := serverURL.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(serverURL.Hostname(), port)
+}
+
+// getFTPFile retrieves the file (or, for a HEAD request, only its size) at the URL of the request from an FTP server,
+// as the user in the URL or anonymously.
+func getFTPFile(request *http.Request, dial DialFunc) (*http.Response, error) {
+	ctx := request.Context()
+	conn, err := ftp.Dial(hostAddress(request.URL, defaultFTPPort),
+		ftp.DialWithContext(ctx),
+		ftp.DialWithDialFunc(func(network, address string) (net.Conn, error) {
+			return dial(ctx, network, address)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	username, password := anonymousFTPUser, anonymousFTPUser
+	if request.URL.User != nil {
+		username = request.URL.User.Username()
+		password, _ = request.URL.User.Password()
+	}
+	err = conn.Login(username, password)
+	if err != nil {
+		conn.Quit()
+		return nil, err
+	}
+
+	size, err := conn.FileSize(request.URL.Path)
+	if err != nil {
+		// not every server supports the SIZE command
+		size = -1
+	}
+	if request.Method == http.MethodHead {
+		conn.Quit()
+		return newFileResponse(request, request.URL.Path, http.NoBody, size), nil
+	}
+
+	file, err := conn.Retr(request.URL.Path)
+	if err != nil {
+		conn.Quit()
+		return nil, err
+	}
+
+	return newFileResponse(request, request.URL.Path, fileResponseBody{file, ftpConnCloser{conn}}, size), nil
+}
+
+// ftpConnCloser logs out of the FTP server when it is closed.
+type ftpConnCloser struct {
+	conn *ftp.ServerConn
+}
+
+func (closer ftpConnCloser) Close() error {
+	return closer.conn.Quit()
+}
+
+// sshAuthMethods returns the ways in which the user authenticates with an SSH server: with the password in the URL, if
+// any, and with the keys held by the SSH agent, if it is running.
+func sshAuthMethods(serverURL *url.URL) (authMethods []ssh.AuthMethod, agentConn net.Conn) {
+	if password, ok := serverURL.User.Password(); ok {
+		authMethods = append(authMethods, ssh.Password(password))
+	}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		conn, err := net.Dial("unix", socket)
+		if err == nil {
+			authMethods = append(authMethods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			agentConn = conn
+		}
+	}
+	return
+}
+
+// SFTPClient is a session with an SFTP server, closing which also closes the SSH connection underneath it.
+type SFTPClient struct {
+	*sftp.Client
+	sshClient *ssh.Client
+}
+
+func (client *SFTPClient) Close() error {
+	client.Client.Close()
+	return client.sshClient.Close()
+}
+
+// DialSFTP connects to the SFTP server at the URL through dial (or directly if it is nil) as the user in the URL with
+// its password or the keys of the SSH agent, verifying the key of the server against the known_hosts file of the user.
+func DialSFTP(ctx context.Context, serverURL *url.URL, dial DialFunc) (*SFTPClient, error) {
+	if serverURL.User == nil || serverURL.User.Username() == "" {
+		return nil, fmt.Errorf("no user name in SFTP URL")
+	}
+	if dial == nil {
+		var dialer net.Dialer
+		dial = dialer.DialContext
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := knownhosts.New(filepath.Join(homeDir, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the known hosts: %v", err)
+	}
+
+	authMethods, agentConn := sshAuthMethods(serverURL)
+	if agentConn != nil {
+		defer agentConn.Close()
+	}
+
+	address := hostAddress(serverURL, defaultSFTPPort)
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, &ssh.ClientConfig{
+		User:            serverURL.User.Username(),
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, channels, requests)
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	return &SFTPClient{client, sshClient}, nil
+}
+
+// getSFTPFile retrieves the file (or, for a HEAD request, only its size) at the URL of the request from an SFTP server
+// as the user in the URL, verifying the key of the server against the known_hosts file of the user.
+func getSFTPFile(request *http.Request, dial DialFunc) (*http.Response, error) {
+	client, err := DialSFTP(request.Context(), request.URL, dial)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Method == http.MethodHead {
+		defer client.Close()
+		info, err := client.Stat(request.URL.Path)
+		if err != nil {
+			return nil, err
+		}
+		return newFileResponse(request, request.URL.Path, http.NoBody, info.Size()), nil
+	}
+
+	file, err := client.Open(request.URL.Path)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	size := int64(-1)
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	return newFileResponse(request, request.URL.Path, fileResponseBody{file, client}, size), nil
+}
+
+// FTP serves the GET and HEAD requests for ftp:// and sftp:// URLs itself by retrieving the files from the servers,
+// connecting to them through dial (or directly if it is nil), and passes the other requests on. FTP servers are logged
+// in to as the user in the URL or anonymously, while SFTP servers are authenticated with as the user in the URL with
+// its password or the keys of the SSH agent, and their keys must be in the known_hosts file of the user. The responses
+// are always OK; failures are returned as errors.
+func FTP(dial DialFunc) Middleware {
+	if dial == nil {
+		var dialer net.Dialer
+		dial = dialer.DialContext
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.URL.Scheme != "ftp" && request.URL.Scheme != "sftp" {
+				return next.RoundTrip(request)
+			}
+			if request.Method != http.MethodGet && request.Method != http.MethodHead {
+				return nil, fmt.Errorf("unsupported method for %s: %s", request.URL.Scheme, request.Method)
+			}
+
+			if request.URL.Scheme == "sftp" {
+				return getSFTPFile(request, dial)
+			}
+			return getFTPFile(request, dial)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+const (
+	// defaultAltSvcMaxAge is how long an alternative service is remembered if its advertisement does not say.
+	defaultAltSvcMaxAge = 24 * time.Hour
+	// http3BrokenDuration is how long a host whose HTTP/3 request has failed is reached over HTTP/1.1 or HTTP/2 again.
+	http3BrokenDuration = 10 * time.Minute
+)
+
+// http3Endpoint is the state of HTTP/3 for a host.
+type http3Endpoint struct {
+	// address is the address (`host:port`) at which the host serves HTTP/3, as advertised by the host.
+	address string
+	// expiration is when the advertisement expires.
+	expiration time.Time
+	// brokenUntil is the time until which the host is not reached over HTTP/3 since a request over it has failed.
+	brokenUntil time.Time
+}
+
+// http3RoundTripper sends the requests to the hosts which have advertised HTTP/3 over QUIC and the others through the
+// fallback round tripper.
+type http3RoundTripper struct {
+	fallback http.RoundTripper
+	resolver *Resolver
+	quic     *http3.Transport
+
+	mutex     sync.Mutex
+	endpoints map[string]*http3Endpoint
+}
+
+// HTTP3 returns a round tripper which sends the requests to the hosts that have advertised HTTP/3 in the Alt-Svc header
+// of a response to them over QUIC, and the others through the fallback round tripper (over HTTP/1.1 or HTTP/2). Only
+// requests without a body are sent over HTTP/3, so that the ones which fail can be sent again through the fallback, and
+// a host whose HTTP/3 request has failed is reached through the fallback for the next 10 minutes. The hostnames are
+// resolved with the resolver, if it is not nil.
+func HTTP3(fallback http.RoundTripper, resolver *Resolver) http.RoundTripper {
+	if fallback == nil {
+		fallback = http.DefaultTransport
+	}
+
+	roundTripper := &http3RoundTripper{
+		fallback:  fallback,
+		resolver:  resolver,
+		endpoints: make(map[string]*http3Endpoint),
+	}
+	roundTripper.quic = &http3.Transport{Dial: roundTripper.dial}
+	return roundTripper
+}
+
+// canonicalAddress returns the host of the URL of the request with its port.
+func canonicalAddress(request *http.Request) string {
+	port := request.URL.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(request.URL.Hostname(), port)
+}
+
+// dial connects over QUIC to the address at which the host at the address serves HTTP/3.
+func (rt *http3RoundTripper) dial(ctx context.Context, address string, tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+	rt.mutex.Lock()
+	if endpoint, ok := rt.endpoints[address]; ok {
+		address = endpoint.address
+	}
+	rt.mutex.Unlock()
+
+	if rt.resolver != nil {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		addresses, err := rt.resolver.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addresses) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		address = net.JoinHostPort(addresses[0], port)
+	}
+	return quic.DialAddrEarly(ctx, address, tlsConfig, quicConfig)
+}
+
+// isHTTP3Available reports whether the host at the address has advertised HTTP/3 and has not failed over it lately.
+func (rt *http3RoundTripper) isHTTP3Available(address string) bool {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	endpoint, ok := rt.endpoints[address]
+	now := time.Now()
+	return ok && now.Before(endpoint.expiration) && !now.Before(endpoint.brokenUntil)
+}
+
+func (rt *http3RoundTripper) markBroken(address string) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	if endpoint, ok := rt.endpoints[address]; ok {
+		endpoint.brokenUntil = time.Now().Add(http3BrokenDuration)
+	}
+}
+
+// recordAltSvc records the HTTP/3 endpoint advertised in the value of the Alt-Svc header of a response from the host
+// at the address, e.g. `h3=":443"; ma=86400`, or forgets it if the advertisement is `clear`.
+func (rt *http3RoundTripper) recordAltSvc(address, altSvc string) {
+	if altSvc == "" {
+		return
+	}
+
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	if strings.TrimSpace(altSvc) == "clear" {
+		delete(rt.endpoints, address)
+		return
+	}
+	for _, service := range strings.Split(altSvc, ",") {
+		params := strings.Split(service, ";")
+		protocol, authority, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !ok || protocol != "h3" {
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil {
+			continue
+		}
+		if host == "" {
+			host, _, _ = net.SplitHostPort(address)
+		}
+		maxAge := defaultAltSvcMaxAge
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if seconds, err := strconv.Atoi(value); name == "ma" && err == nil {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+
+		endpoint, ok := rt.endpoints[address]
+		if !ok {
+			endpoint = &http3Endpoint{}
+			rt.endpoints[address] = endpoint
+		}
+		endpoint.address = net.JoinHostPort(host, port)
+		endpoint.expiration = time.Now().Add(maxAge)
+		return
+	}
+}
+
+func (rt *http3RoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Scheme != "https" {
+		return rt.fallback.RoundTrip(request)
+	}
+
+	address := canonicalAddress(request)
+	if (request.Body == nil || request.Body == http.NoBody) && rt.isHTTP3Available(address) {
+		response, err := rt.quic.RoundTrip(request)
+		if err == nil {
+			return response, nil
+		}
+		if request.Context().Err() != nil {
+			return nil, err
+		}
+		rt.markBroken(address)
+	}
+
+	response, err := rt.fallback.RoundTrip(request)
+	if err == nil {
+		rt.recordAltSvc(address, response.Header.Get("Alt-Svc"))
+	}
+	return response, err
+}
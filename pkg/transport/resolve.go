@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDNSPort is the port of the DNS server if it is given without one.
+const defaultDNSPort = "53"
+
+// resolvedHost is a cached resolution of a hostname.
+type resolvedHost struct {
+	addresses []string
+	expires   time.Time
+}
+
+// Resolver resolves the hostnames of the hosts to which the connections of the HTTP client are made, in place of the
+// resolver of the system: the hostnames in Overrides are never resolved, others are looked up at Server, if given, and
+// the addresses are kept for CacheTTL. If a lookup fails, the cached addresses of the hostname are used even if they
+// have expired, so that a flaky DNS server does not interrupt a long run.
+type Resolver struct {
+	// Overrides maps the (lowercase ASCII) hostnames to the IP addresses to which the connections are made instead,
+	// like the /etc/hosts file. The requests are still made for the hostname, so TLS certificates are verified for it.
+	Overrides map[string]string
+	// Server is the address (`host[:port]`) of the DNS server at which the hostnames are looked up; empty means the one
+	// of the system.
+	Server string
+	// CacheTTL is how long the addresses of a hostname are reused before it is looked up again; 0 means that every
+	// connection looks it up.
+	CacheTTL time.Duration
+
+	mutex sync.Mutex
+	cache map[string]*resolvedHost
+}
+
+// lookup returns the IP addresses of the hostname.
+func (resolver *Resolver) lookup(ctx context.Context, hostname string) (addresses []string, err error) {
+	if address, ok := resolver.Overrides[strings.ToLower(hostname)]; ok {
+		return []string{address}, nil
+	}
+
+	resolver.mutex.Lock()
+	cached, isCached := resolver.cache[hostname]
+	resolver.mutex.Unlock()
+	if isCached && time.Now().Before(cached.expires) {
+		return cached.addresses, nil
+	}
+
+	netResolver := net.DefaultResolver
+	if resolver.Server != "" {
+		server := resolver.Server
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, defaultDNSPort)
+		}
+
+		var dialer net.Dialer
+		netResolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	addresses, err = netResolver.LookupHost(ctx, hostname)
+	if err != nil {
+		if isCached && ctx.Err() == nil {
+			return cached.addresses, nil
+		}
+		return
+	}
+
+	if resolver.CacheTTL > 0 || isCached {
+		resolver.mutex.Lock()
+		if resolver.cache == nil {
+			resolver.cache = make(map[string]*resolvedHost)
+		}
+		resolver.cache[hostname] = &resolvedHost{addresses, time.Now().Add(resolver.CacheTTL)}
+		resolver.mutex.Unlock()
+	}
+	return
+}
+
+// DialContext connects to the address (`host:port`) on the network, resolving the host with the resolver and trying
+// its addresses in turn.
+func (resolver *Resolver) DialContext(ctx context.Context, network, address string) (conn net.Conn, err error) {
+	var dialer net.Dialer
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	addresses, err := resolver.lookup(ctx, host)
+	if err != nil {
+		return
+	}
+	if len(addresses) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	var errs []error
+	for _, ip := range addresses {
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Transport returns a copy of http.DefaultTransport which connects through the resolver.
+func (resolver *Resolver) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = resolver.DialContext
+	return transport
+}
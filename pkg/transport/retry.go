@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"net/http"
+	"time"
+)
+
+// isRetriableStatus reports whether a response with the status code indicates a transient condition on the server.
+func isRetriableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// Retry retries the requests with idempotent methods and no body which fail due to a network error or a transient
+// condition on the server (429 or 5xx status) up to maxRetries times, waiting for backoff before the first retry and
+// twice as long before every next one. The challenges of anti-bot services are not retried, since they do not go away
+// by themselves.
+func Retry(maxRetries int, backoff time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (response *http.Response, err error) {
+			isRetriable := (request.Method == http.MethodGet || request.Method == http.MethodHead) && request.Body == nil
+
+			delay := backoff
+			for retry := 0; ; retry++ {
+				response, err = next.RoundTrip(request)
+				if !isRetriable || retry >= maxRetries || err == nil && (!isRetriableStatus(response.StatusCode) || IsChallenge(response)) {
+					return
+				}
+				if err == nil {
+					response.Body.Close()
+				}
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-request.Context().Done():
+					timer.Stop()
+					return nil, request.Context().Err()
+				case <-timer.C:
+				}
+				delay *= 2
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+)
+
+// sessionSniffLength is the length of the start of HTML content in which the signs of an expired session are looked
+// for, which may come after a lengthy header of the page.
+const sessionSniffLength = 64 << 10
+
+// ErrSessionExpired is returned for the requests whose responses still show that the session has expired after the
+// user has been logged in again.
+var ErrSessionExpired = errors.New("the session has expired and could not be renewed")
+
+// LoginFunc logs the user in to a host, issuing the requests through the given round tripper, and returns the cookies
+// of the new session.
+type LoginFunc func(ctx context.Context, roundTripper http.RoundTripper) ([]*http.Cookie, error)
+
+// setCookies sets the cookies in the Cookie header of the request, replacing the ones with the same names.
+func setCookies(request *http.Request, cookies []*http.Cookie) {
+	names := make(map[string]bool, len(cookies))
+	for _, cookie := range cookies {
+		names[cookie.Name] = true
+	}
+
+	existingCookies := request.Cookies()
+	request.Header.Del("Cookie")
+	for _, cookie := range existingCookies {
+		if !names[cookie.Name] {
+			request.AddCookie(cookie)
+		}
+	}
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+}
+
+// sniffedBody is the body of a response whose start has been read ahead.
+type sniffedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// Session keeps the session of a user logged in to the given host and presents its cookies with the requests directed
+// to the host, logging in with login before the first such request. Whenever isExpired reports that a response shows
+// that the session has expired (e.g. it redirects to the login page or is the login form itself, the start of its
+// content being given for HTML responses), the user is logged in again, once for all the requests which were sent with
+// the expired session, and the request is retried with the new one. If logging in fails, so do the requests.
+func Session(host string, login LoginFunc, isExpired func(response *http.Response, content []byte) bool) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		var mutex sync.Mutex
+		jar := make(map[string]*http.Cookie)
+		// generation counts the logins, so that a session is renewed only by the first request which finds it expired
+		generation := 0
+
+		renew := func(ctx context.Context, expiredGeneration int) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if generation != expiredGeneration {
+				return nil
+			}
+
+			cookies, err := login(ctx, next)
+			if err != nil {
+				return err
+			}
+
+			clear(jar)
+			for _, cookie := range cookies {
+				jar[cookie.Name] = &http.Cookie{Name: cookie.Name, Value: cookie.Value}
+			}
+			generation++
+			return nil
+		}
+
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.URL.Host != host {
+				return next.RoundTrip(request)
+			}
+
+			for attempt := 0; ; attempt++ {
+				mutex.Lock()
+				currentGeneration := generation
+				cookies := make([]*http.Cookie, 0, len(jar))
+				for _, cookie := range jar {
+					cookies = append(cookies, cookie)
+				}
+				mutex.Unlock()
+
+				if currentGeneration == 0 {
+					err := renew(request.Context(), currentGeneration)
+					if err != nil {
+						return nil, err
+					}
+					continue
+				}
+
+				// a round tripper must not modify the request
+				sessionRequest := request.Clone(request.Context())
+				setCookies(sessionRequest, cookies)
+				response, err := next.RoundTrip(sessionRequest)
+				if err != nil {
+					return nil, err
+				}
+
+				var content []byte
+				if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType == "text/html" {
+					bufferedReader := bufio.NewReaderSize(response.Body, sessionSniffLength)
+					content, _ = bufferedReader.Peek(sessionSniffLength)
+					response.Body = sniffedBody{Reader: bufferedReader, Closer: response.Body}
+				}
+				if !isExpired(response, content) {
+					mutex.Lock()
+					for _, cookie := range response.Cookies() {
+						if cookie.MaxAge < 0 {
+							delete(jar, cookie.Name)
+						} else {
+							jar[cookie.Name] = &http.Cookie{Name: cookie.Name, Value: cookie.Value}
+						}
+					}
+					mutex.Unlock()
+
+					return response, nil
+				}
+
+				response.Body.Close()
+				if attempt > 0 || request.Body != nil {
+					return nil, ErrSessionExpired
+				}
+
+				err = renew(request.Context(), currentGeneration)
+				if err != nil {
+					return nil, err
+				}
+			}
+		})
+	}
+}
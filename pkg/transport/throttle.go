@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// minThrottleDelay is the delay between the requests to a host with which throttling starts; once the delay has
+	// decreased below it, the host is no longer throttled.
+	minThrottleDelay = 250 * time.Millisecond
+	// maxThrottleDelay is the longest delay between the requests to a host, however strained it is.
+	maxThrottleDelay = 30 * time.Second
+	// slowResponseFactor is how many times slower than usual a response must be for the host to be considered strained.
+	slowResponseFactor = 4
+	// latencySmoothing is the weight of the latest latency in the moving average of the latencies of a host.
+	latencySmoothing = 0.2
+)
+
+// hostThrottle is the state of the throttling of the requests to a host.
+type hostThrottle struct {
+	mutex sync.Mutex
+	// delay is the time between the requests to the host; 0 means the host is not throttled.
+	delay time.Duration
+	// nextRequestTime is the earliest time when the next request may be sent to the host.
+	nextRequestTime time.Time
+	// averageLatency is the moving average of the latencies of the healthy responses of the host.
+	averageLatency time.Duration
+}
+
+// isStrained reports whether the outcome of a request, which took the given time, shows that the host is strained.
+func (throttle *hostThrottle) isStrained(response *http.Response, err error, latency time.Duration) bool {
+	if err != nil {
+		return true
+	}
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+
+	return throttle.averageLatency > 0 && latency > slowResponseFactor*throttle.averageLatency
+}
+
+// Throttle adapts the pace of the requests to every host to the health of the host, independently of any static rate
+// limit: when the host responds with errors (429 or 5xx), fails to respond or responds several times slower than
+// usual, the delay between the requests to it is doubled (up to 30 seconds), and every healthy response shortens it by
+// a quarter until the host is no longer throttled. The changes of the pace are logged at info level.
+func Throttle(logger *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		var mutex sync.Mutex
+		throttles := make(map[string]*hostThrottle)
+
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			mutex.Lock()
+			throttle, ok := throttles[request.URL.Host]
+			if !ok {
+				throttle = &hostThrottle{}
+				throttles[request.URL.Host] = throttle
+			}
+			mutex.Unlock()
+
+			throttle.mutex.Lock()
+			now := time.Now()
+			requestTime := throttle.nextRequestTime
+			if requestTime.Before(now) {
+				requestTime = now
+			}
+			throttle.nextRequestTime = requestTime.Add(throttle.delay)
+			throttle.mutex.Unlock()
+
+			if wait := time.Until(requestTime); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-request.Context().Done():
+					timer.Stop()
+					return nil, request.Context().Err()
+				case <-timer.C:
+				}
+			}
+
+			startTime := time.Now()
+			response, err := next.RoundTrip(request)
+			if err != nil && request.Context().Err() != nil {
+				return response, err
+			}
+			latency := time.Since(startTime)
+
+			throttle.mutex.Lock()
+			defer throttle.mutex.Unlock()
+
+			previousDelay := throttle.delay
+			if throttle.isStrained(response, err, latency) {
+				throttle.delay = min(max(2*throttle.delay, minThrottleDelay), maxThrottleDelay)
+			} else {
+				if throttle.averageLatency == 0 {
+					throttle.averageLatency = latency
+				} else {
+					throttle.averageLatency += time.Duration(latencySmoothing * float64(latency-throttle.averageLatency))
+				}
+
+				throttle.delay -= throttle.delay / 4
+				if throttle.delay < minThrottleDelay {
+					throttle.delay = 0
+				}
+			}
+
+			switch {
+			case throttle.delay > previousDelay:
+				logger.Info(fmt.Sprintf("%s shows signs of strain; slowing down to one request every %s", request.URL.Host, throttle.delay.Round(time.Millisecond)))
+			case throttle.delay == 0 && previousDelay > 0:
+				logger.Info(fmt.Sprintf("%s has recovered; no longer slowing down the requests to it", request.URL.Host))
+			}
+			return response, err
+		})
+	}
+}
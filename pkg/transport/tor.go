@@ -0,0 +1,27 @@
+package transport
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// DefaultTorProxyAddress is the address of the SOCKS5 proxy of a Tor daemon running with the default configuration.
+const DefaultTorProxyAddress = "127.0.0.1:9050"
+
+// TorTransport returns a copy of http.DefaultTransport which makes all its connections through the SOCKS5 proxy of the
+// Tor daemon at the address. The proxy is given the hostnames, which it resolves itself, so that neither the lookups
+// nor the address of the client leak, and onion services can be reached. Tor isolates the streams with different SOCKS
+// credentials from each other, so the requests for which isolationKey returns different keys (e.g. the ones made for
+// different pages) travel over different circuits; an empty key is shared by all the requests which have one.
+func TorTransport(address string, isolationKey func(request *http.Request) string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(request *http.Request) (*url.URL, error) {
+		proxyURL := &url.URL{Scheme: "socks5", Host: address}
+		if key := isolationKey(request); key != "" {
+			// Tor accepts any credentials; the connections are pooled per proxy URL, so they are not shared either
+			proxyURL.User = url.UserPassword(key, "isolation")
+		}
+		return proxyURL, nil
+	}
+	return transport
+}
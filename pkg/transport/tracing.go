@@ -0,0 +1,48 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation of the transport to OpenTelemetry.
+const tracerName = "github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/transport"
+
+// Tracing records a client span for every request, which is a child of the span in the context of the request, if any.
+// It should be the innermost middleware so that every attempt made by Retry gets a span of its own which does not
+// include the time spent waiting for RateLimit.
+func Tracing(tracerProvider trace.TracerProvider) Middleware {
+	tracer := tracerProvider.Tracer(tracerName)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (response *http.Response, err error) {
+			ctx, span := tracer.Start(request.Context(), fmt.Sprintf("HTTP %s", request.Method),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("http.request.method", request.Method),
+					attribute.String("url.full", redact.URL(request.URL).String()),
+					attribute.String("server.address", request.URL.Hostname()),
+				),
+			)
+			defer span.End()
+
+			response, err = next.RoundTrip(request.WithContext(ctx))
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return
+			}
+
+			span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+			if response.StatusCode >= 400 {
+				span.SetStatus(codes.Error, response.Status)
+			}
+			return
+		})
+	}
+}
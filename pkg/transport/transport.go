@@ -0,0 +1,110 @@
+// Package transport provides middleware for HTTP round trippers, from which the HTTP client of the fetcher is composed:
+// logging, retrying, rate limiting, authentication and caching are separate layers, to which users of the library may
+// add their own.
+package transport
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/metrics"
+	"golang.org/x/time/rate"
+)
+
+// Middleware wraps a round tripper in another one which does something before and/or after passing the requests on.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to the http.RoundTripper interface.
+type RoundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (roundTrip RoundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return roundTrip(request)
+}
+
+// Chain wraps the base round tripper in the middleware, the first of which is the outermost one, i.e. the first to see
+// a request and the last to see its response.
+func Chain(base http.RoundTripper, middleware ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	roundTripper := base
+	for i := len(middleware) - 1; i >= 0; i-- {
+		roundTripper = middleware[i](roundTripper)
+	}
+	return roundTripper
+}
+
+// Logging logs every request along with the status of its response (or the error) and its duration at debug level.
+func Logging(logger *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (response *http.Response, err error) {
+			startTime := time.Now()
+			response, err = next.RoundTrip(request)
+
+			attrs := []any{slog.String("url", request.URL.String()), slog.Duration("duration", time.Since(startTime).Round(time.Millisecond))}
+			if err != nil {
+				logger.Debug(fmt.Sprintf("%s request failed", request.Method), append(attrs, slog.Any("err", err))...)
+			} else {
+				logger.Debug(fmt.Sprintf("%s request done", request.Method), append(attrs, slog.Int("status", response.StatusCode))...)
+			}
+			return
+		})
+	}
+}
+
+// Metrics reports every request, whether it failed (without a response or with a status of 400 or above) and the time
+// until its response arrived to the recorder.
+func Metrics(recorder metrics.Recorder) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (response *http.Response, err error) {
+			startTime := time.Now()
+			response, err = next.RoundTrip(request)
+
+			recorder.Add(metrics.Requests, 1)
+			if err != nil || response.StatusCode >= http.StatusBadRequest {
+				recorder.Add(metrics.RequestsFailed, 1)
+			}
+			recorder.Observe(metrics.RequestDuration, time.Since(startTime).Seconds())
+			return
+		})
+	}
+}
+
+// RateLimit delays every request until the limiter allows it.
+func RateLimit(limiter *rate.Limiter) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			err := limiter.Wait(request.Context())
+			if err != nil {
+				return nil, err
+			}
+
+			return next.RoundTrip(request)
+		})
+	}
+}
+
+// BasicAuth authenticates the requests directed to the given host with the user name and password obtained from
+// credentials, which is called for every such request; if it fails, so does the request.
+func BasicAuth(host string, credentials func() (username, password string, err error)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.URL.Host != host {
+				return next.RoundTrip(request)
+			}
+
+			username, password, err := credentials()
+			if err != nil {
+				return nil, err
+			}
+
+			// a round tripper must not modify the request
+			request = request.Clone(request.Context())
+			request.SetBasicAuth(username, password)
+			return next.RoundTrip(request)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// postProcessingCommand is the command which is run on every stored file, with `{}` standing for its path, and
+// postProcessingConcurrency is the maximum number of instances of it running at once.
+var postProcessingCommand string
+var postProcessingConcurrency int
+
+// postProcessingPlaceholder stands for the path of the stored file in the post-processing command.
+const postProcessingPlaceholder = "{}"
+
+// splitCommandLine splits the command line into its arguments at unquoted whitespace, honoring single quotes, double
+// quotes and backslash escapes (except within single quotes) the way a POSIX shell does, without expanding anything.
+func splitCommandLine(commandLine string) (args []string, err error) {
+	var arg strings.Builder
+	isInArg := false
+	var quote rune
+	isEscaped := false
+	for _, c := range commandLine {
+		switch {
+		case isEscaped:
+			arg.WriteRune(c)
+			isEscaped = false
+		case c == '\\' && quote != '\'':
+			isEscaped, isInArg = true, true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote, isInArg = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if isInArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				isInArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			isInArg = true
+		}
+	}
+	if quote != 0 || isEscaped {
+		return nil, errorf("unterminated quote or escape in command %q", commandLine)
+	}
+	if isInArg {
+		args = append(args, arg.String())
+	}
+
+	return
+}
+
+// newFilePostProcessor returns a post-processor which runs the command on every stored file, substituting its path
+// for every `{}` in the arguments or, if there is none, appending it to them.
+func newFilePostProcessor(commandLine string) (process fetch.PostProcessor, err error) {
+	commandArgs, err := splitCommandLine(commandLine)
+	if err != nil {
+		return nil, usageErrorf("%v", err)
+	}
+	if len(commandArgs) == 0 {
+		return nil, usageErrorf("empty post-processing command")
+	}
+
+	hasPlaceholder := false
+	for _, arg := range commandArgs {
+		if strings.Contains(arg, postProcessingPlaceholder) {
+			hasPlaceholder = true
+		}
+	}
+
+	return func(ctx context.Context, name string) error {
+		filename := filepath.Join(targetDir, filepath.FromSlash(name))
+		args := make([]string, 0, len(commandArgs)+1)
+		for _, arg := range commandArgs[1:] {
+			args = append(args, strings.ReplaceAll(arg, postProcessingPlaceholder, filename))
+		}
+		if !hasPlaceholder {
+			args = append(args, filename)
+		}
+
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, commandArgs[0], args...)
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		err := cmd.Run()
+		if err != nil {
+			if message := strings.TrimSpace(output.String()); message != "" {
+				return errorf("%v: %s", err, message)
+			}
+			return err
+		}
+
+		logDebugf("Post-processed %s", filename)
+		return nil
+	}, nil
+}
+
+// chainPostProcessors returns a post-processor which runs the post-processors on every stored file one after another,
+// stopping at the first one which fails.
+func chainPostProcessors(processors ...fetch.PostProcessor) fetch.PostProcessor {
+	return func(ctx context.Context, name string) error {
+		for _, process := range processors {
+			err := process(ctx, name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
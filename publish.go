@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/nats-io/nats.go"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+)
+
+// eventBrokerURL is the URL of the MQTT or NATS broker to which the events of the run are published, and eventTopic is
+// the MQTT topic or NATS subject to which they are published.
+var eventBrokerURL string
+var eventTopic string
+
+// getRedactedEventBrokerURL returns the URL of the broker with the password, if any, masked for use in messages.
+func getRedactedEventBrokerURL() string {
+	return redact.URLString(eventBrokerURL)
+}
+
+// eventBrokerTimeout bounds connecting to the broker and flushing the published events at the end of the run.
+const eventBrokerTimeout = 10 * time.Second
+
+// eventBroker is a message broker to which events are published.
+type eventBroker interface {
+	publish(topic string, payload []byte) error
+	close()
+}
+
+type natsBroker struct {
+	connection *nats.Conn
+}
+
+func (broker *natsBroker) publish(topic string, payload []byte) error {
+	return broker.connection.Publish(topic, payload)
+}
+
+func (broker *natsBroker) close() {
+	err := broker.connection.FlushTimeout(eventBrokerTimeout)
+	if err != nil {
+		logErrorf("could not flush the events published to %s: %v", getRedactedEventBrokerURL(), err)
+	}
+	broker.connection.Close()
+}
+
+type mqttBroker struct {
+	client mqtt.Client
+}
+
+func (broker *mqttBroker) publish(topic string, payload []byte) error {
+	// the delivery is not waited for, since observers have to return quickly; disconnecting waits for it instead
+	return broker.client.Publish(topic, 1, false, payload).Error()
+}
+
+func (broker *mqttBroker) close() {
+	broker.client.Disconnect(uint(eventBrokerTimeout / time.Millisecond))
+}
+
+// connectEventBroker connects to the broker at the URL, whose scheme determines the protocol: nats or tls for NATS, and
+// mqtt, mqtts, tcp, ssl, ws or wss for MQTT.
+func connectEventBroker(brokerURLStr string) (broker eventBroker, err error) {
+	brokerURL, err := url.Parse(brokerURLStr)
+	if err != nil {
+		return nil, errorf("invalid broker URL %s", brokerURLStr)
+	}
+
+	switch brokerURL.Scheme {
+	case "nats", "tls":
+		connection, err := nats.Connect(brokerURLStr, nats.Name("fetch-forum-topic"), nats.Timeout(eventBrokerTimeout))
+		if err != nil {
+			return nil, err
+		}
+
+		return &natsBroker{connection}, nil
+
+	case "mqtt", "mqtts", "tcp", "ssl", "ws", "wss":
+		switch brokerURL.Scheme {
+		case "mqtt":
+			brokerURL.Scheme = "tcp"
+		case "mqtts":
+			brokerURL.Scheme = "ssl"
+		}
+
+		options := mqtt.NewClientOptions().
+			AddBroker(brokerURL.String()).
+			SetClientID(fmt.Sprintf("fetch-forum-topic-%d", os.Getpid())).
+			SetConnectTimeout(eventBrokerTimeout)
+		if brokerURL.User != nil {
+			options.SetUsername(brokerURL.User.Username())
+			password, _ := brokerURL.User.Password()
+			options.SetPassword(password)
+		}
+
+		client := mqtt.NewClient(options)
+		token := client.Connect()
+		if !token.WaitTimeout(eventBrokerTimeout) {
+			return nil, errorf("timed out connecting to %s", redact.URL(brokerURL))
+		}
+		if token.Error() != nil {
+			return nil, token.Error()
+		}
+
+		return &mqttBroker{client}, nil
+	}
+
+	return nil, errorf("unsupported broker URL scheme %s (use nats, tls, mqtt, mqtts, tcp, ssl, ws or wss)", brokerURL.Scheme)
+}
+
+// eventPublisher publishes an event whenever a page is done and when the run completes, with the same JSON payloads
+// as the webhook notifications.
+type eventPublisher struct {
+	fetch.NopObserver
+	broker eventBroker
+}
+
+func (publisher *eventPublisher) publishEvent(payload interface{}) error {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return publisher.broker.publish(eventTopic, content)
+}
+
+func (publisher *eventPublisher) OnPageDone(event *fetch.PageEvent) {
+	err := publisher.publishEvent(newPageNotification(event))
+	if err != nil {
+		logErrorf("could not publish the event about page %d to %s: %v", event.Number, getRedactedEventBrokerURL(), err)
+	}
+}
+
+// publishRunCompleted publishes the report of the run and disconnects from the broker.
+func (publisher *eventPublisher) publishRunCompleted(report *fetch.Report) {
+	err := publisher.publishEvent(newRunCompletedNotification(report))
+	if err != nil {
+		logErrorf("could not publish the completion of the run to %s: %v", getRedactedEventBrokerURL(), err)
+	}
+
+	publisher.broker.close()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+)
+
+// shouldRecompressImages enables the recompression of the stored PNG and JPEG images, jpegQualityCap is the quality at
+// which the JPEG images are encoded again and shouldKeepOriginalImages keeps the original images next to the
+// recompressed ones.
+var shouldRecompressImages bool
+var jpegQualityCap int
+var shouldKeepOriginalImages bool
+
+const defaultJPEGQualityCap = 85
+
+// originalImageFilenameSuffix is appended to the names of the original images which are kept next to the recompressed
+// ones.
+const originalImageFilenameSuffix = ".orig"
+
+// recompressImage encodes the PNG or JPEG image in the content again, losslessly with the best compression for PNG
+// and at the quality cap for JPEG, and returns the result, or nil if the content is not such an image, cannot be
+// encoded again without losing anything but the compression (animated PNG images and JPEG images with EXIF metadata,
+// which may hold their orientation) or would not shrink.
+func recompressImage(content []byte) (recompressedContent []byte, err error) {
+	var recompressed bytes.Buffer
+	switch http.DetectContentType(content) {
+	case "image/png":
+		if bytes.Contains(content, []byte("acTL")) {
+			return nil, nil
+		}
+		var img image.Image
+		img, err = png.Decode(bytes.NewReader(content))
+		if err != nil {
+			return
+		}
+		encoder := &png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&recompressed, img)
+	case "image/jpeg":
+		if bytes.Contains(content, []byte("Exif\x00\x00")) {
+			return nil, nil
+		}
+		var img image.Image
+		img, err = jpeg.Decode(bytes.NewReader(content))
+		if err != nil {
+			return
+		}
+		err = jpeg.Encode(&recompressed, img, &jpeg.Options{Quality: jpegQualityCap})
+	default:
+		return nil, nil
+	}
+	if err != nil || recompressed.Len() >= len(content) {
+		return nil, err
+	}
+	return recompressed.Bytes(), nil
+}
+
+// recompressStoredImage recompresses the stored file if it is a PNG or JPEG image which shrinks thereby, keeping the
+// original next to it if -keep-original-images has been given.
+func recompressStoredImage(ctx context.Context, name string) (err error) {
+	filename := filepath.Join(targetDir, filepath.FromSlash(name))
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	recompressedContent, err := recompressImage(content)
+	if err != nil || recompressedContent == nil {
+		// the images which cannot be decoded are left as they are
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// the recompressed image replaces the original only once it has been written completely
+	temporaryFilename := filename + ".tmp"
+	file, err := archive.Create(temporaryFilename)
+	if err != nil {
+		return
+	}
+	_, err = file.Write(recompressedContent)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && shouldKeepOriginalImages {
+		err = os.Rename(filename, filename+originalImageFilenameSuffix)
+	}
+	if err == nil {
+		err = os.Rename(temporaryFilename, filename)
+	}
+	if err != nil {
+		os.Remove(temporaryFilename)
+		return
+	}
+
+	logDebugf("Recompressed %s from %d to %d bytes", filename, len(content), len(recompressedContent))
+	return
+}
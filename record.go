@@ -0,0 +1,273 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/proxy"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+const (
+	recordingProxyCACertificateFilename = "proxy-ca.pem"
+	recordingProxyCAKeyFilename         = "proxy-ca-key.pem"
+)
+
+// getRecordingProxyCADir returns the directory in which the certificate authority of the recording proxy is kept, which
+// is not the target directory so that its key does not end up in exports of the archive.
+func getRecordingProxyCADir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return targetDir
+	}
+
+	return filepath.Join(configDir, "fetch-forum-topic")
+}
+
+var recordingProxyListenAddress string
+var recordingProxyHosts string
+var shouldInterceptHTTPS bool
+
+// proxyRecorder stores the pages and resources recorded by the proxy into the archive in the target directory. Every
+// recorded HTML document becomes a page of the topic, numbered in the order of browsing, and the other resources are
+// stored along with the page which referred to them.
+type proxyRecorder struct {
+	mutex      sync.Mutex
+	storage    fetch.DirStorage
+	urlMap     *archive.URLMap
+	settings   *archive.TopicSettings
+	pageByURL  map[string]uint
+	lastPage   uint
+	pagesCount int
+}
+
+func newProxyRecorder() (recorder *proxyRecorder, err error) {
+	settings, err := archive.LoadTopicSettings(targetDir)
+	if err != nil {
+		settings = &archive.TopicSettings{}
+	} else if settings.URL != "" {
+		return nil, errorf("the archive in %s was fetched from a URL template; record into another directory", targetDir)
+	}
+	settings.Engine = forumEngine
+
+	recorder = &proxyRecorder{
+		storage:   fetch.DirStorage(targetDir),
+		settings:  settings,
+		pageByURL: map[string]uint{},
+	}
+	for i, pageURL := range settings.PageURLs {
+		recorder.pageByURL[pageURL] = fetch.MinPageNumber + uint(i)
+	}
+
+	err = archive.SaveTopicSettings(targetDir, settings)
+	if err != nil {
+		return
+	}
+
+	recorder.urlMap, err = archive.OpenURLMap(targetDir)
+	if err != nil {
+		return nil, errorf("could not open the map of original URLs (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+
+	return
+}
+
+func isHTMLContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// pageOf returns the number of the page to which the recorded response with the content type belongs, adding a new
+// page to the topic for HTML documents which have not been recorded before.
+func (r *proxyRecorder) pageOf(response *http.Response, contentType string) (pageNumber uint, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	urlStr := response.Request.URL.String()
+	if !isHTMLContentType(contentType) {
+		if pageNumber, ok := r.pageByURL[response.Request.Header.Get("Referer")]; ok {
+			return pageNumber, nil
+		}
+		if r.lastPage == 0 {
+			return 0, errorf("no page has been recorded yet")
+		}
+		return r.lastPage, nil
+	}
+
+	pageNumber, ok := r.pageByURL[urlStr]
+	if !ok {
+		r.settings.PageURLs = append(r.settings.PageURLs, urlStr)
+		pageNumber = uint(len(r.settings.PageURLs))
+		r.pageByURL[urlStr] = pageNumber
+
+		err = archive.SaveTopicSettings(targetDir, r.settings)
+		if err != nil {
+			return
+		}
+	}
+
+	r.lastPage = pageNumber
+	r.pagesCount++
+	return
+}
+
+func (r *proxyRecorder) record(response *http.Response, content []byte) {
+	urlStr := response.Request.URL.String()
+	metadata := archive.NewResourceMetadata(response)
+	metadata.DetectContentType(content)
+	contentType := metadata.ContentType
+	pageNumber, err := r.pageOf(response, contentType)
+	if err != nil {
+		logWarningf("not recording %s: %v", urlStr, err)
+		return
+	}
+
+	name := path.Join(fmt.Sprint(pageNumber), fetch.ASCIIHostname(response.Request.URL.Hostname()), rewrite.LocalPath(response.Request.URL, contentType))
+	file, err := r.storage.Create(name)
+	if err != nil {
+		logErrorf("could not create file %s in which to record %s", name, urlStr)
+		return
+	}
+
+	_, err = file.Write(content)
+	file.Close()
+	if err != nil {
+		logErrorf("could not record %s in file %s", urlStr, name)
+		return
+	}
+
+	if shouldSaveResourceMetadata {
+		metadataName := name + archive.MetadataFilenameSuffix
+		metadataFile, err := r.storage.Create(metadataName)
+		if err == nil {
+			err = archive.WriteMetadata(metadataFile, metadata)
+			metadataFile.Close()
+		}
+		if err != nil {
+			logErrorf("could not write metadata file %s", metadataName)
+		}
+	}
+
+	err = r.urlMap.Record(filepath.Join(targetDir, filepath.FromSlash(name)), metadata)
+	if err != nil {
+		logErrorf("could not record the original URL of %s", name)
+	}
+
+	if isHTMLContentType(contentType) {
+		logSuccessf("Recorded page %d (%s)", pageNumber, urlStr)
+	} else {
+		logDebugf("Recorded %s for page %d", urlStr, pageNumber)
+	}
+}
+
+var recordCommand = &command{
+	name:      "record",
+	synopsis:  "archive the pages browsed through a local proxy",
+	arguments: "",
+	description: "Run an HTTP(S) proxy and archive every page and resource from the given hosts which is browsed through it into the target directory, " +
+		"for forums whose pages cannot be derived from a URL template. Every HTML document becomes a page of the archive, numbered in the order of browsing, " +
+		"and the other resources are stored along with the page which referred to them. The archive can be viewed with the serve subcommand.\n" +
+		"HTTPS connections to the given hosts are intercepted with certificates issued by a local certificate authority, " +
+		"which is created next to the default configuration file (" + recordingProxyCACertificateFilename + ") on first use and has to be trusted by the browser; " +
+		"connections to other hosts are passed through untouched",
+	setFlags: func(flags *flag.FlagSet) {
+		recordingProxyListenAddress = "localhost:8081"
+		flags.StringVar(&recordingProxyListenAddress, "listen", recordingProxyListenAddress, "`address` on which to listen for proxy requests")
+
+		recordingProxyHosts = ""
+		flags.StringVar(&recordingProxyHosts, "hosts", recordingProxyHosts, "comma-separated `list` of the hosts whose pages and resources are archived")
+
+		shouldInterceptHTTPS = true
+		flags.BoolVar(&shouldInterceptHTTPS, "intercept-https", shouldInterceptHTTPS, "intercept HTTPS connections to the given hosts in order to archive them")
+
+		forumEngine = "generic"
+		flags.StringVar(&forumEngine, "engine", forumEngine, translatef("`name` of the forum software which serves the topic, which determines how the posts on the pages are extracted for searching (one of: %s)", strings.Join(engine.Names(), ", ")))
+
+		shouldSaveResourceMetadata = false
+		flags.BoolVar(&shouldSaveResourceMetadata, "save-metadata", shouldSaveResourceMetadata, "store the HTTP response metadata of every recorded page and resource in a sidecar .meta.json file next to it")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+		if recordingProxyHosts == "" {
+			return usageErrorf("no hosts to record specified")
+		}
+		err := validateForumEngine()
+		if err != nil {
+			return err
+		}
+
+		recorder, err := newProxyRecorder()
+		if err != nil {
+			return err
+		}
+		defer recorder.urlMap.Close()
+
+		var authority *proxy.Authority
+		if shouldInterceptHTTPS {
+			caDir := getRecordingProxyCADir()
+			err = os.MkdirAll(caDir, os.ModePerm)
+			if err == nil {
+				authority, err = proxy.LoadOrCreateAuthority(filepath.Join(caDir, recordingProxyCACertificateFilename), filepath.Join(caDir, recordingProxyCAKeyFilename))
+			}
+			if err != nil {
+				return errorf("could not set up the certificate authority of the proxy: %v", err)
+			}
+		}
+
+		hosts := strings.Split(recordingProxyHosts, ",")
+		for i := range hosts {
+			hosts[i] = strings.TrimSpace(hosts[i])
+		}
+		server := &http.Server{
+			Addr:    recordingProxyListenAddress,
+			Handler: proxy.New(hosts, authority, recorder.record, slog.New(currentLogHandler{})),
+		}
+		go func() {
+			<-interruptContext.Done()
+			server.Close()
+		}()
+
+		logInfof("Recording %s through the proxy on %s...", strings.Join(hosts, ", "), recordingProxyListenAddress)
+		if authority != nil {
+			logInfof("Trust %s in the browser to record HTTPS pages.", filepath.Join(getRecordingProxyCADir(), recordingProxyCACertificateFilename))
+		}
+		listener, err := net.Listen("tcp", recordingProxyListenAddress)
+		if err != nil {
+			return err
+		}
+		sdNotify("READY=1")
+		superviseUnderSystemd(interruptContext, func() string {
+			recorder.mutex.Lock()
+			defer recorder.mutex.Unlock()
+			return fmt.Sprintf("recorded %d pages", recorder.pagesCount)
+		}, nil)
+
+		err = server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		sdNotify("STOPPING=1")
+
+		if recorder.pagesCount > 0 {
+			forumTopicPageURLs = recorder.settings.PageURLs
+			forumTopicFetcher = nil
+			updateSearchIndex()
+		}
+		logInfof("Recorded %d pages.", recorder.pagesCount)
+		return nil
+	},
+}
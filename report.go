@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// runReportFilename is the path of the file in which to write the run report as JSON; `-` stands for the standard output.
+var runReportFilename string
+
+// writeRunReport outputs the report as JSON to the report file, if one has been requested, and logs a one-line summary of it.
+func writeRunReport(report *fetch.Report) (err error) {
+	logSummaryf := logInfof
+	if report.PagesFailed == 0 && report.ResourcesFailed == 0 && report.PagesPending == 0 {
+		logSummaryf = logSuccessf
+	}
+	logSummaryf("Pages: %d attempted, %d succeeded, %d failed, %d skipped, %d pending; resources: %d fetched, %d failed; %s downloaded in %s.",
+		report.PagesAttempted, report.PagesSucceeded, report.PagesFailed, report.PagesSkipped, report.PagesPending,
+		report.ResourcesFetched, report.ResourcesFailed, formatByteCount(report.BytesDownloaded),
+		time.Duration(report.DurationSeconds*float64(time.Second)).Round(time.Millisecond))
+
+	if postProcessedFileCount := report.FilesPostProcessed + len(report.PostProcessingFailures); postProcessedFileCount > 0 {
+		logPostProcessingSummaryf := logInfof
+		if len(report.PostProcessingFailures) == 0 {
+			logPostProcessingSummaryf = logSuccessf
+		}
+		logPostProcessingSummaryf("Post-processing: %d files processed, %d failed.", postProcessedFileCount, len(report.PostProcessingFailures))
+	}
+
+	if len(report.RecoveredResources) > 0 {
+		sourceCounts := map[string]int{}
+		for _, resource := range report.RecoveredResources {
+			sourceCounts[resource.Source]++
+		}
+		logInfof("Images recovered from fallback sources: %d by retrying with the Referer changed, %d from the Wayback Machine, %d as thumbnails.",
+			sourceCounts[archive.ResourceSourceRefererRetry], sourceCounts[archive.ResourceSourceWayback], sourceCounts[archive.ResourceSourceThumbnail])
+	}
+
+	if report.ImagesStripped > 0 {
+		logInfof("EXIF metadata stripped from %d images.", report.ImagesStripped)
+	}
+
+	if runReportFilename == "" {
+		return
+	}
+
+	var output io.Writer = os.Stdout
+	if runReportFilename != "-" {
+		file, err := os.Create(runReportFilename)
+		if err != nil {
+			return errorf("could not create report file %s", runReportFilename)
+		}
+		defer file.Close()
+
+		output = file
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "\t")
+	err = encoder.Encode(report)
+	if err != nil {
+		return errorf("could not write report: %v", err)
+	}
+
+	return
+}
@@ -0,0 +1,9 @@
+package main
+
+// shouldSkipImages, shouldSkipFonts and shouldSkipMedia disable the localization of the respective classes of resources,
+// while isPageRequisitesOnlyMode restricts it to the resources needed to render the page (stylesheets, scripts, images
+// and fonts), excluding e.g. media, embedded objects and frames.
+var shouldSkipImages bool
+var shouldSkipFonts bool
+var shouldSkipMedia bool
+var isPageRequisitesOnlyMode bool
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/redact"
+)
+
+// shouldFetchOnlyRequestedPages makes the fetch leave the failures and pending pages of the last run which have not
+// been requested for the next run (see fetch.Config.OnlyRequestedPages).
+var shouldFetchOnlyRequestedPages bool
+
+// retryFailureKinds is a comma-separated list of the kinds of the failures which are retried.
+var retryFailureKinds string
+
+var shouldOnlyListFailures bool
+
+// readFailures returns the failures of the pages and resources during the last run in the target directory.
+func readFailures() (failures []*archive.Failure, err error) {
+	state, err := archive.OpenState(targetDir)
+	if err != nil {
+		return nil, errorf("could not open the state database (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+	defer state.Close()
+
+	failures, err = state.Failures()
+	if err != nil {
+		return nil, errorf("could not read the failed downloads of the last run: %v", err)
+	}
+	return
+}
+
+// selectFailures returns the failures which are of the kinds given by -kind, if any, and which are of the pages in the
+// page ranges or of the resources with the URLs given as the arguments, if any.
+func selectFailures(failures []*archive.Failure, args []string) (selectedFailures []*archive.Failure, err error) {
+	var kinds []string
+	for _, kind := range strings.Split(retryFailureKinds, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !slices.Contains(fetch.FailureKinds, kind) {
+			return nil, usageErrorf("unknown kind of failure %q (it has to be one of: %s)", kind, strings.Join(fetch.FailureKinds, ", "))
+		}
+
+		kinds = append(kinds, kind)
+	}
+
+	var pageRanges []string
+	resourceURLs := map[string]struct{}{}
+	for _, arg := range args {
+		if strings.Contains(arg, "://") {
+			resourceURLs[arg] = struct{}{}
+		} else {
+			pageRanges = append(pageRanges, arg)
+		}
+	}
+	var pageNumbers map[uint]struct{}
+	if len(pageRanges) > 0 {
+		pageNumbers, err = getFetcher().ParsePageRanges(interruptContext, pageRanges)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, failure := range failures {
+		if len(kinds) > 0 && !slices.Contains(kinds, failure.Kind) {
+			continue
+		}
+		if len(args) > 0 {
+			_, isPageSelected := pageNumbers[failure.Page]
+			_, isResourceSelected := resourceURLs[failure.URL]
+			if !isPageSelected && !isResourceSelected {
+				continue
+			}
+		}
+
+		selectedFailures = append(selectedFailures, failure)
+	}
+	return
+}
+
+func printFailure(failure *archive.Failure) {
+	kind := failure.Kind
+	if kind == "" {
+		// the failures recorded before their kinds were
+		kind = "unknown"
+	}
+
+	if failure.URL == "" {
+		fmt.Println(translatef("page %d: %s: %s", failure.Page, kind, redact.Text(failure.Reason)))
+	} else {
+		fmt.Println(translatef("page %d: resource %s: %s: %s", failure.Page, redact.URLString(failure.URL), kind, redact.Text(failure.Reason)))
+	}
+}
+
+var retryCommand = &command{
+	name:      "retry",
+	synopsis:  "list the failures of the last run and fetch again the selected ones",
+	arguments: "[page ranges|resource URL]...",
+	description: "List the pages and the resources embedded in them which could not be fetched during the last run in the target directory, " +
+		"along with the kinds of and reasons for the failures, and fetch again the pages which failed or whose resources failed: " +
+		"all of them, only the ones whose failures are of the kinds given by -kind, or only the given pages and the pages of the given resources. " +
+		"Unlike resume, which retries every failed page, the failures which have not been selected are kept for a later run",
+	setFlags: func(flags *flag.FlagSet) {
+		setPageFetchingFlags(flags)
+
+		retryFailureKinds = ""
+		flags.StringVar(&retryFailureKinds, "kind", retryFailureKinds, translatef("only retry the failures of the comma-separated `kinds` (any of: %s)", strings.Join(fetch.FailureKinds, ", ")))
+
+		shouldOnlyListFailures = false
+		flags.BoolVar(&shouldOnlyListFailures, "list", shouldOnlyListFailures, "only list the selected failures without fetching them again")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		settings, err := loadTopicSettings()
+		if err != nil {
+			return err
+		}
+		applyTopicCredentials(settings)
+
+		failures, err := readFailures()
+		if err != nil {
+			return err
+		}
+		selectedFailures, err := selectFailures(failures, args)
+		if err != nil {
+			return err
+		}
+
+		selectedPageNumbers := map[uint]struct{}{}
+		for _, failure := range selectedFailures {
+			printFailure(failure)
+			selectedPageNumbers[failure.Page] = struct{}{}
+		}
+		if len(selectedFailures) == 0 {
+			logSuccessf("No failures to retry.")
+			return nil
+		}
+		if shouldOnlyListFailures {
+			return nil
+		}
+
+		logInfof("Retrying %d pages...", len(selectedPageNumbers))
+		shouldFetchOnlyRequestedPages = true
+		return fetchForumTopicPages(selectedPageNumbers, false, 0)
+	},
+}
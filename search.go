@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/search"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// extractText returns the non-blank runs of text in an HTML document, except for the content of scripts and stylesheets.
+func extractText(reader io.Reader) (texts []string, err error) {
+	tokenizer := html.NewTokenizer(reader)
+	isInRawTextElement := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if tokenizer.Err() != io.EOF {
+				err = tokenizer.Err()
+			}
+			return
+
+		case html.StartTagToken:
+			tagName, _ := tokenizer.TagName()
+			switch atom.Lookup(tagName) {
+			case atom.Script, atom.Style:
+				isInRawTextElement = true
+			}
+
+		case html.EndTagToken:
+			isInRawTextElement = false
+
+		case html.TextToken:
+			if isInRawTextElement {
+				continue
+			}
+
+			text := strings.Join(strings.Fields(html.UnescapeString(string(tokenizer.Text()))), " ")
+			if text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+}
+
+// walkArchivedPages parses the archived pages of the topic in the target directory, whose settings have to be loaded,
+// in order and visits every one which could be parsed, logging the ones which could not. It returns the map of the
+// original URLs of the archive.
+func walkArchivedPages(visit func(pageNumber uint, filename string, doc *html.Node)) (urlMap *archive.URLMap, err error) {
+	urlMap, err = archive.LoadURLMap(targetDir)
+	if err != nil {
+		return nil, errorf("could not read the map of original URLs (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+	archivedPageNumbers, err := archive.PageNumbers(targetDir)
+	if err != nil {
+		return nil, errorf("could not list the contents of target directory %s", targetDir)
+	}
+
+	for _, pageNumber := range fetch.SortPageNumbers(archivedPageNumbers) {
+		entry, ok := urlMap.LookupFilename(getFetcher().PageURL(pageNumber))
+		if !ok {
+			continue
+		}
+
+		filename := filepath.Join(targetDir, filepath.FromSlash(entry.Filename))
+		file, err := os.Open(filename)
+		if err != nil {
+			logErrorf("could not open archived page %s", filename)
+			continue
+		}
+
+		doc, err := html.Parse(file)
+		file.Close()
+		if err != nil {
+			logErrorf("could not parse archived page %s", filename)
+			continue
+		}
+
+		visit(pageNumber, filename, doc)
+	}
+
+	return
+}
+
+// buildSearchIndex indexes the posts on all archived pages of the topic in the target directory, whose settings have
+// to be loaded, with the given options, and saves the index to it.
+func buildSearchIndex(options search.Options) (index *search.Index, err error) {
+	index = search.NewIndex()
+	index.Options = options
+	_, err = walkArchivedPages(func(pageNumber uint, filename string, doc *html.Node) {
+		index.AddPage(pageNumber, doc, getForumEngine())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = index.Save(targetDir)
+	return
+}
+
+// getSearchIndexOptions returns the options of the search index given by the flags.
+func getSearchIndexOptions() search.Options {
+	return search.Options{
+		StripSignatures: shouldStripSignatures,
+		Emoji:           shouldReplaceSmileysWithEmoji,
+	}
+}
+
+// loadSearchIndex reads the search index of the archive in the target directory, whose settings have to be loaded,
+// building it first if it does not exist yet, if rebuild is set or if it was built with other options than the ones
+// given by the flags.
+func loadSearchIndex(rebuild bool) (index *search.Index, err error) {
+	if !rebuild {
+		index, err = search.Load(targetDir)
+		if err == nil && index.Options == getSearchIndexOptions() || err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+
+	logInfof("Building the search index of %s...", targetDir)
+	return buildSearchIndex(getSearchIndexOptions())
+}
+
+// updateSearchIndex rebuilds the search index of the archive in the target directory after a fetch into it, with the
+// options with which it was built before.
+func updateSearchIndex() {
+	var options search.Options
+	if index, err := search.Load(targetDir); err == nil {
+		options = index.Options
+	}
+
+	_, err := buildSearchIndex(options)
+	if err != nil {
+		logErrorf("could not update the search index: %v", err)
+	}
+}
+
+var isSearchCaseInsensitive bool
+var isSearchRegexpMode bool
+var shouldRebuildSearchIndex bool
+
+// shouldStripSignatures leaves the signatures of the posts out of the search index and the posts served by the API.
+var shouldStripSignatures bool
+
+// shouldReplaceSmileysWithEmoji replaces the codes of the smileys in the text of the search index and the posts served
+// by the API with the Unicode emoji which look like them.
+var shouldReplaceSmileysWithEmoji bool
+
+// searchArchiveText prints the runs of text on the archived pages which the regular expression matches.
+func searchArchiveText(matcher *regexp.Regexp) error {
+	urlMap, err := archive.LoadURLMap(targetDir)
+	if err != nil {
+		return errorf("could not read the map of original URLs (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+	archivedPageNumbers, err := archive.PageNumbers(targetDir)
+	if err != nil {
+		return errorf("could not list the contents of target directory %s", targetDir)
+	}
+
+	for _, pageNumber := range fetch.SortPageNumbers(archivedPageNumbers) {
+		entry, ok := urlMap.LookupFilename(getFetcher().PageURL(pageNumber))
+		if !ok {
+			continue
+		}
+
+		filename := filepath.Join(targetDir, filepath.FromSlash(entry.Filename))
+		file, err := os.Open(filename)
+		if err != nil {
+			logErrorf("could not open archived page %s", filename)
+			continue
+		}
+
+		texts, err := extractText(file)
+		file.Close()
+		if err != nil {
+			logErrorf("could not read archived page %s", filename)
+		}
+
+		for _, text := range texts {
+			if matcher.MatchString(text) {
+				fmt.Println(translatef("page %d: %s", pageNumber, text))
+			}
+		}
+	}
+
+	return nil
+}
+
+// describeSearchResult returns where the post in the result is located and who wrote it.
+func describeSearchResult(result *search.Result) (description string) {
+	description = translatef("page %d", result.Page)
+	if result.PostID != "" {
+		description += translatef(", post %s", result.PostID)
+	}
+	if result.Author != "" {
+		description += translatef(" by %s", result.Author)
+	}
+	return
+}
+
+var searchCommand = &command{
+	name:      "search",
+	synopsis:  "search the posts on the archived pages",
+	arguments: "query",
+	description: "Print the posts on the archived pages in the target directory which contain all words of the query, best matches first. " +
+		"The posts are looked up in a search index, which is built on the first search and updated after every fetch into the archive.\n" +
+		"With -regexp, the query is a regular expression instead, and the runs of text on the archived pages which it matches are printed in order",
+	setFlags: func(flags *flag.FlagSet) {
+		isSearchRegexpMode = false
+		flags.BoolVar(&isSearchRegexpMode, "regexp", isSearchRegexpMode, "treat the query as a regular expression matched against the text of the pages")
+
+		isSearchCaseInsensitive = false
+		flags.BoolVar(&isSearchCaseInsensitive, "i", isSearchCaseInsensitive, "match the regular expression case-insensitively (word searches always are)")
+
+		shouldRebuildSearchIndex = false
+		flags.BoolVar(&shouldRebuildSearchIndex, "reindex", shouldRebuildSearchIndex, "rebuild the search index before searching")
+
+		shouldStripSignatures = false
+		flags.BoolVar(&shouldStripSignatures, "strip-signatures", shouldStripSignatures, "leave the signatures of the posts, as recognized by the forum engine, out of the searched text (the index is rebuilt if it was built otherwise)")
+
+		shouldReplaceSmileysWithEmoji = false
+		flags.BoolVar(&shouldReplaceSmileysWithEmoji, "emoji", shouldReplaceSmileysWithEmoji, "replace the codes of the smileys in the searched text, including the ones of the images of smileys recognized by the forum engine, with the Unicode emoji which look like them (the index is rebuilt if it was built otherwise)")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("exactly one search query must be specified")
+		}
+
+		_, err := loadTopicSettings()
+		if err != nil {
+			return err
+		}
+
+		if isSearchRegexpMode {
+			pattern := args[0]
+			if isSearchCaseInsensitive {
+				pattern = "(?i)" + pattern
+			}
+			matcher, err := regexp.Compile(pattern)
+			if err != nil {
+				return usageErrorf("invalid search pattern: %v", err)
+			}
+
+			return searchArchiveText(matcher)
+		}
+
+		index, err := loadSearchIndex(shouldRebuildSearchIndex)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range index.Search(args[0]) {
+			fmt.Printf("%s: %s\n", describeSearchResult(result), result.Snippet)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selfUpdateRepository is the GitHub repository whose releases the program is updated from.
+const selfUpdateRepository = "rgeorgiev583/fetch-forum-topic-ng"
+
+// The names of the assets of a release besides the binaries, which are named fetch-forum-topic_GOOS_GOARCH (with the
+// .exe extension on Windows): the SHA-256 checksums of the binaries in the format of sha256sum and the Ed25519
+// signature of the checksums.
+const (
+	selfUpdateChecksumsAssetName = "checksums.txt"
+	selfUpdateSignatureAssetName = "checksums.txt.sig"
+)
+
+// selfUpdatePublicKey is the base64-encoded Ed25519 public key with which the checksums of the releases are signed,
+// which is set when building a release with `-ldflags "-X main.selfUpdatePublicKey=..."`. Without it, the downloaded
+// binary is only verified against the checksums of the release.
+var selfUpdatePublicKey = ""
+
+// maxSelfUpdateAssetSize is the size of an asset of a release beyond which it is not downloaded.
+const maxSelfUpdateAssetSize = 256 << 20
+
+var selfUpdateClient = &http.Client{Timeout: 5 * time.Minute}
+
+var shouldOnlyCheckForUpdate bool
+var shouldForceSelfUpdate bool
+
+type gitHubReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+type gitHubRelease struct {
+	TagName string                `json:"tag_name"`
+	HTMLURL string                `json:"html_url"`
+	Assets  []*gitHubReleaseAsset `json:"assets"`
+}
+
+func (release *gitHubRelease) asset(name string) *gitHubReleaseAsset {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset
+		}
+	}
+	return nil
+}
+
+// getSelfUpdateAssetName returns the name of the asset with the binary for the current platform.
+func getSelfUpdateAssetName() string {
+	name := fmt.Sprintf("fetch-forum-topic_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// parseVersion returns the numeric components of a version of the form vX.Y.Z, ignoring any pre-release or build
+// suffix, or false if it is not of that form.
+func parseVersion(version string) (components [3]int, ok bool) {
+	version, _, _ = strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	version, _, _ = strings.Cut(version, "+")
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return components, false
+	}
+	for i, part := range parts {
+		component, err := strconv.Atoi(part)
+		if err != nil {
+			return components, false
+		}
+		components[i] = component
+	}
+	return components, true
+}
+
+// isNewerVersion reports whether the version of the release is newer than the current one.
+func isNewerVersion(releaseVersion, currentVersion string) bool {
+	release, ok := parseVersion(releaseVersion)
+	if !ok {
+		return false
+	}
+	current, ok := parseVersion(currentVersion)
+	if !ok {
+		return true
+	}
+
+	for i := range release {
+		if release[i] != current[i] {
+			return release[i] > current[i]
+		}
+	}
+	return false
+}
+
+func selfUpdateGet(ctx context.Context, urlStr string) (content []byte, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return
+	}
+	request.Header.Set("User-Agent", "fetch-forum-topic/"+getBuildInfo().Version)
+
+	response, err := selfUpdateClient.Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errorf("%s responded with status %s", urlStr, response.Status)
+	}
+	content, err = io.ReadAll(io.LimitReader(response.Body, maxSelfUpdateAssetSize+1))
+	if err == nil && len(content) > maxSelfUpdateAssetSize {
+		err = errorf("%s is larger than %d bytes", urlStr, maxSelfUpdateAssetSize)
+	}
+	return
+}
+
+// getLatestRelease returns the latest release of the program on GitHub.
+func getLatestRelease(ctx context.Context) (release *gitHubRelease, err error) {
+	content, err := selfUpdateGet(ctx, "https://api.github.com/repos/"+selfUpdateRepository+"/releases/latest")
+	if err != nil {
+		return
+	}
+
+	release = &gitHubRelease{}
+	err = json.Unmarshal(content, release)
+	return
+}
+
+// findChecksum returns the SHA-256 checksum of the asset with the name from the checksums in the format of sha256sum.
+func findChecksum(checksums []byte, name string) (checksum []byte, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+
+		return hex.DecodeString(fields[0])
+	}
+	return nil, errorf("no checksum of %s in %s", name, selfUpdateChecksumsAssetName)
+}
+
+// downloadVerifiedRelease downloads the binary for the current platform from the release and verifies it against the
+// checksums of the release, along with verifying the signature of the checksums if the program has been built with
+// the public key of the releases.
+func downloadVerifiedRelease(ctx context.Context, release *gitHubRelease) (binary []byte, err error) {
+	assetName := getSelfUpdateAssetName()
+	binaryAsset := release.asset(assetName)
+	if binaryAsset == nil {
+		return nil, errorf("release %s has no binary for %s/%s (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, assetName)
+	}
+	checksumsAsset := release.asset(selfUpdateChecksumsAssetName)
+	if checksumsAsset == nil {
+		return nil, errorf("release %s has no checksums (%s); refusing to install an unverified binary", release.TagName, selfUpdateChecksumsAssetName)
+	}
+
+	checksums, err := selfUpdateGet(ctx, checksumsAsset.URL)
+	if err != nil {
+		return
+	}
+	if selfUpdatePublicKey != "" {
+		publicKey, err := base64.StdEncoding.DecodeString(selfUpdatePublicKey)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, errorf("the public key of the releases built into the program is invalid")
+		}
+		signatureAsset := release.asset(selfUpdateSignatureAssetName)
+		if signatureAsset == nil {
+			return nil, errorf("release %s has no signature (%s); refusing to install an unverified binary", release.TagName, selfUpdateSignatureAssetName)
+		}
+		signature, err := selfUpdateGet(ctx, signatureAsset.URL)
+		if err != nil {
+			return nil, err
+		}
+		// the signature may be given raw or base64-encoded
+		if decodedSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature))); err == nil {
+			signature = decodedSignature
+		}
+		if !ed25519.Verify(publicKey, checksums, signature) {
+			return nil, errorf("the signature of the checksums of release %s is invalid", release.TagName)
+		}
+	} else {
+		logWarningf("the program has been built without the public key of the releases; only verifying the checksum of the binary")
+	}
+
+	checksum, err := findChecksum(checksums, assetName)
+	if err != nil {
+		return
+	}
+	binary, err = selfUpdateGet(ctx, binaryAsset.URL)
+	if err != nil {
+		return
+	}
+	if actualChecksum := sha256.Sum256(binary); !bytes.Equal(actualChecksum[:], checksum) {
+		return nil, errorf("the checksum of %s does not match the one in %s", assetName, selfUpdateChecksumsAssetName)
+	}
+	return
+}
+
+// replaceExecutable replaces the executable of the running program with the binary.
+func replaceExecutable(binary []byte) (executable string, err error) {
+	executable, err = os.Executable()
+	if err != nil {
+		return "", errorf("could not determine the path of the executable: %v", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return "", errorf("could not resolve the path of the executable: %v", err)
+	}
+
+	// the new binary is written next to the executable, so that it can be renamed over it
+	file, err := os.CreateTemp(filepath.Dir(executable), ".fetch-forum-topic-update-*")
+	if err != nil {
+		return "", errorf("could not create the new executable in %s: %v", filepath.Dir(executable), err)
+	}
+	newExecutable := file.Name()
+	defer os.Remove(newExecutable)
+
+	_, err = file.Write(binary)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(newExecutable, 0755)
+	}
+	if err != nil {
+		return "", errorf("could not write the new executable %s: %v", newExecutable, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		// the executable of a running program cannot be replaced on Windows, but it can be moved out of the way
+		oldExecutable := executable + ".old"
+		os.Remove(oldExecutable)
+		err = os.Rename(executable, oldExecutable)
+		if err != nil {
+			return "", errorf("could not move the executable %s out of the way: %v", executable, err)
+		}
+	}
+	err = os.Rename(newExecutable, executable)
+	if err != nil {
+		return "", errorf("could not replace the executable %s: %v", executable, err)
+	}
+	return
+}
+
+var selfUpdateCommand = &command{
+	name:      "self-update",
+	synopsis:  "update the program to its latest release",
+	arguments: "",
+	description: "Check the releases of the program on GitHub and, if a newer one than the running version is available, download its binary for the current platform, " +
+		"verify it against the SHA-256 checksums of the release (and the Ed25519 signature of the checksums, if the program has been built with the public key of the releases) " +
+		"and replace the executable with it",
+	setFlags: func(flags *flag.FlagSet) {
+		shouldOnlyCheckForUpdate = false
+		flags.BoolVar(&shouldOnlyCheckForUpdate, "check", shouldOnlyCheckForUpdate, "only report whether a newer release is available, without installing it")
+
+		shouldForceSelfUpdate = false
+		flags.BoolVar(&shouldForceSelfUpdate, "force", shouldForceSelfUpdate, "install the latest release even if it is not newer than the running version or the running version is unknown")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		currentVersion := getBuildInfo().Version
+		release, err := getLatestRelease(interruptContext)
+		if err != nil {
+			return errorf("could not check the latest release of %s: %v", selfUpdateRepository, err)
+		}
+
+		isNewer := isNewerVersion(release.TagName, currentVersion)
+		if shouldOnlyCheckForUpdate {
+			if !isNewer {
+				logSuccessf("fetch-forum-topic %s is up to date (the latest release is %s).", currentVersion, release.TagName)
+				return nil
+			}
+			logInfof("fetch-forum-topic %s is available (running %s): %s", release.TagName, currentVersion, release.HTMLURL)
+			return nil
+		}
+
+		if _, ok := parseVersion(currentVersion); !ok && !shouldForceSelfUpdate {
+			return errorf("the running version (%s) is unknown, so it cannot be compared with the latest release %s; pass -force to install it anyway", currentVersion, release.TagName)
+		}
+		if !isNewer && !shouldForceSelfUpdate {
+			logSuccessf("fetch-forum-topic %s is up to date (the latest release is %s).", currentVersion, release.TagName)
+			return nil
+		}
+
+		logInfof("Downloading fetch-forum-topic %s...", release.TagName)
+		binary, err := downloadVerifiedRelease(interruptContext, release)
+		if err != nil {
+			return err
+		}
+		executable, err := replaceExecutable(binary)
+		if err != nil {
+			return err
+		}
+		logSuccessf("Updated %s from %s to %s.", executable, currentVersion, release.TagName)
+		return nil
+	},
+}
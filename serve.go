@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/search"
+)
+
+var serveListenAddress string
+
+// archiveServer serves the files of the archive in the target directory both under their paths in the archive and under
+// the paths (and query strings) of their original URLs.
+type archiveServer struct {
+	urlMap *archive.URLMap
+	// filenamesByRequestURI maps the path and query string of the original URL of every file to its path in the archive.
+	filenamesByRequestURI map[string]string
+	settings              *archive.TopicSettings
+	searchIndex           *search.Index
+	apiHandler            http.Handler
+}
+
+func newArchiveServer() (server *archiveServer, err error) {
+	urlMap, err := archive.LoadURLMap(targetDir)
+	if err != nil {
+		return
+	}
+
+	server = &archiveServer{
+		urlMap:                urlMap,
+		filenamesByRequestURI: map[string]string{},
+	}
+	for _, entry := range urlMap.Entries() {
+		for _, urlStr := range append([]string{entry.OriginalURL}, entry.RedirectChain...) {
+			originalURL, err := url.Parse(urlStr)
+			if err != nil {
+				continue
+			}
+
+			server.filenamesByRequestURI[originalURL.RequestURI()] = entry.Filename
+		}
+	}
+
+	settings, err := loadTopicSettings()
+	if err != nil {
+		logWarningf("%v; the topic index will not link to the pages", err)
+		err = nil
+	} else {
+		server.settings = settings
+
+		server.searchIndex, err = loadSearchIndex(false)
+		if err != nil {
+			logWarningf("%v; searching will not be available", err)
+			err = nil
+		}
+	}
+
+	server.apiHandler = server.newAPIHandler()
+	return
+}
+
+func (s *archiveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" && r.URL.RawQuery == "" {
+		s.serveIndex(w, r)
+		return
+	}
+	if r.URL.Path == "/" && r.URL.Query().Has(searchQueryParameter) {
+		s.serveSearchResults(w, r)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, apiPathPrefix) {
+		s.apiHandler.ServeHTTP(w, r)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	if info, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(name))); err == nil && info.Mode().IsRegular() {
+		s.serveFile(w, r, name)
+		return
+	}
+
+	if name, ok := s.filenamesByRequestURI[r.URL.RequestURI()]; ok {
+		s.serveFile(w, r, name)
+		return
+	}
+
+	if info, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(name))); err == nil && info.IsDir() {
+		http.FileServer(http.Dir(targetDir)).ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// serveFile serves the file with the slash-separated path relative to the archive with the content type under which
+// it was originally served, if it is known, or else the one derived from its extension or content.
+func (s *archiveServer) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	filename := filepath.Join(targetDir, filepath.FromSlash(name))
+	file, err := os.Open(filename)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "could not read file", http.StatusInternalServerError)
+		return
+	}
+
+	if metadataContent, err := os.ReadFile(filename + archive.MetadataFilenameSuffix); err == nil {
+		metadata := &archive.ResourceMetadata{}
+		if json.Unmarshal(metadataContent, metadata) == nil && metadata.ContentType != "" {
+			w.Header().Set("Content-Type", metadata.ContentType)
+		}
+	}
+
+	http.ServeContent(w, r, filepath.Base(filename), info.ModTime(), file)
+}
+
+// searchQueryParameter is the query parameter of the root with which the search index is queried.
+const searchQueryParameter = "q"
+
+type topicIndexPage struct {
+	Number  uint
+	Link    string
+	URL     string
+	ModTime time.Time
+}
+
+type topicIndex struct {
+	TargetDir   string
+	URL         string
+	Searchable  bool
+	Query       string
+	Pages       []*topicIndexPage
+	Results     []*searchResultLink
+	IsSearching bool
+}
+
+type searchResultLink struct {
+	*search.Result
+	Description string
+	Link        string
+}
+
+var topicIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Archive of {{if .URL}}{{.URL}}{{else}}{{.TargetDir}}{{end}}</title>
+</head>
+<body>
+<h1>Archive of {{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{else}}{{.TargetDir}}{{end}}</h1>
+{{if .Searchable}}<form action="/" method="get">
+<input type="search" name="q" value="{{.Query}}" placeholder="Search posts">
+<button type="submit">Search</button>
+</form>
+{{end}}{{if .IsSearching}}<p>{{len .Results}} matching posts. <a href="/">Back to the list of pages</a></p>
+<ol>
+{{range .Results}}<li><a href="{{.Link}}">{{.Description}}</a>: {{.Snippet}}</li>
+{{end}}</ol>
+{{else}}<table>
+<tr><th>Page</th><th>Original URL</th><th>Fetched</th></tr>
+{{range .Pages}}<tr><td><a href="{{.Link}}">{{.Number}}</a></td><td>{{.URL}}</td><td>{{.ModTime.Format "2006-01-02 15:04"}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+func (s *archiveServer) newTopicIndex() *topicIndex {
+	index := &topicIndex{
+		TargetDir:  targetDir,
+		Searchable: s.searchIndex != nil,
+	}
+	if s.settings != nil {
+		index.URL = getFetcher().PageURL(fetch.MinPageNumber)
+	}
+	return index
+}
+
+// pageLink returns the path under which the archived page with the given number is served.
+func (s *archiveServer) pageLink(pageNumber uint) string {
+	if s.settings != nil {
+		if entry, ok := s.urlMap.LookupFilename(getFetcher().PageURL(pageNumber)); ok {
+			return escapeArchivePath(entry.Filename)
+		}
+	}
+
+	return "/" + url.PathEscape(filepath.Base(archive.PageDir(targetDir, pageNumber))) + "/"
+}
+
+func (s *archiveServer) renderTopicIndex(w http.ResponseWriter, index *topicIndex) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := topicIndexTemplate.Execute(w, index)
+	if err != nil {
+		logErrorf("could not render the topic index: %v", err)
+	}
+}
+
+// serveIndex lists the archived pages of the topic with links to them.
+func (s *archiveServer) serveIndex(w http.ResponseWriter, r *http.Request) {
+	pageNumbers, err := archive.PageNumbers(targetDir)
+	if err != nil {
+		http.Error(w, "could not list the archived pages", http.StatusInternalServerError)
+		return
+	}
+
+	index := s.newTopicIndex()
+	for _, pageNumber := range fetch.SortPageNumbers(pageNumbers) {
+		page := &topicIndexPage{
+			Number: pageNumber,
+			Link:   s.pageLink(pageNumber),
+		}
+		page.ModTime, _ = archive.PageModTime(archive.PageDir(targetDir, pageNumber))
+		if s.settings != nil {
+			page.URL = getFetcher().PageURL(pageNumber)
+		}
+
+		index.Pages = append(index.Pages, page)
+	}
+
+	s.renderTopicIndex(w, index)
+}
+
+// serveSearchResults lists the posts matching the query with links to them.
+func (s *archiveServer) serveSearchResults(w http.ResponseWriter, r *http.Request) {
+	if s.searchIndex == nil {
+		http.Error(w, "the archive has no search index", http.StatusNotFound)
+		return
+	}
+
+	index := s.newTopicIndex()
+	index.IsSearching = true
+	index.Query = r.URL.Query().Get(searchQueryParameter)
+	for _, result := range s.searchIndex.Search(index.Query) {
+		index.Results = append(index.Results, &searchResultLink{
+			Result:      result,
+			Description: describeSearchResult(result),
+			Link:        s.postLink(result.Page, result.Anchor),
+		})
+	}
+
+	s.renderTopicIndex(w, index)
+}
+
+// escapeArchivePath returns the absolute URL path of the file with the slash-separated path relative to the archive.
+func escapeArchivePath(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+var serveCommand = &command{
+	name:      "serve",
+	synopsis:  "serve the archive over HTTP",
+	arguments: "",
+	description: "Serve the archive in the target directory over HTTP. The root lists the archived pages of the topic and offers searching their posts. " +
+		"Files are served both under their paths in the archive and under the paths and query strings of their original URLs, " +
+		"with the content types under which they were originally served if their metadata has been saved.\n" +
+		"A read-only JSON API is served as well: /api/topic describes the topic and its archived pages, " +
+		"/api/pages/{n}/posts lists the posts on page n and /api/search?q=query searches them",
+	setFlags: func(flags *flag.FlagSet) {
+		serveListenAddress = "localhost:8080"
+		flags.StringVar(&serveListenAddress, "listen", serveListenAddress, "`address` on which to listen for HTTP requests")
+
+		shouldStripSignatures = false
+		flags.BoolVar(&shouldStripSignatures, "strip-signatures", shouldStripSignatures, "leave the signatures of the posts, as recognized by the forum engine, out of the posts served by the API and the searched text")
+
+		shouldReplaceSmileysWithEmoji = false
+		flags.BoolVar(&shouldReplaceSmileysWithEmoji, "emoji", shouldReplaceSmileysWithEmoji, "replace the codes of the smileys in the posts served by the API and the searched text, including the ones of the images of smileys recognized by the forum engine, with the Unicode emoji which look like them")
+
+		setDuplicatePostsFlag(flags)
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+		err := checkDuplicatePostsMode()
+		if err != nil {
+			return err
+		}
+
+		server, err := newArchiveServer()
+		if err != nil {
+			return err
+		}
+
+		listener, err := net.Listen("tcp", serveListenAddress)
+		if err != nil {
+			return err
+		}
+		sdNotify("READY=1")
+		superviseUnderSystemd(interruptContext, nil, nil)
+
+		logInfof("Serving %s on http://%s/...", targetDir, serveListenAddress)
+		return http.Serve(listener, server)
+	},
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// topicStatisticsMonthLayout is the layout of the months over which the activity in the topic is counted.
+const topicStatisticsMonthLayout = "2006-01"
+
+// maxTopicStatisticsRankLength is the number of authors in the rankings of the report.
+const maxTopicStatisticsRankLength = 50
+
+// authorCount is the number of posts of an author or of quotes of them.
+type authorCount struct {
+	Author string `json:"author"`
+	Count  int    `json:"count"`
+}
+
+// monthCount is the number of posts made during a month.
+type monthCount struct {
+	Month string `json:"month"`
+	Posts int    `json:"posts"`
+}
+
+// topicStatistics is the analytics report of the posts in the archive.
+type topicStatistics struct {
+	Pages   int `json:"pages"`
+	Posts   int `json:"posts"`
+	Authors int `json:"authors"`
+	// FirstPostTime and LastPostTime are the times of the earliest and latest posts whose time is known.
+	FirstPostTime *time.Time `json:"firstPostTime,omitempty"`
+	LastPostTime  *time.Time `json:"lastPostTime,omitempty"`
+	// AveragePostCharacters and AveragePostWords are the average length of the text of the posts, including quotes.
+	AveragePostCharacters float64 `json:"averagePostCharacters"`
+	AveragePostWords      float64 `json:"averagePostWords"`
+	Attachments           int     `json:"attachments"`
+	// DuplicatePosts is the number of posts which duplicate earlier ones, unless they are kept like any other post;
+	// the collapsed ones are not counted in the rest of the report.
+	DuplicatePosts int `json:"duplicatePosts,omitempty"`
+	// PostsPerAuthor and MostQuoted are the authors with the most posts and the ones whose posts were quoted the most,
+	// in descending order.
+	PostsPerAuthor []*authorCount `json:"postsPerAuthor"`
+	MostQuoted     []*authorCount `json:"mostQuoted"`
+	// PostsPerMonth is the activity in the topic over time, for every month from the first post to the last one.
+	PostsPerMonth []*monthCount `json:"postsPerMonth"`
+}
+
+// rankAuthors returns the authors in descending order of their counts and then by name, up to the maximum length of
+// the rankings.
+func rankAuthors(counts map[string]int) (ranking []*authorCount) {
+	ranking = []*authorCount{}
+	for author, count := range counts {
+		ranking = append(ranking, &authorCount{author, count})
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].Count != ranking[j].Count {
+			return ranking[i].Count > ranking[j].Count
+		}
+		return ranking[i].Author < ranking[j].Author
+	})
+
+	if len(ranking) > maxTopicStatisticsRankLength {
+		ranking = ranking[:maxTopicStatisticsRankLength]
+	}
+	return
+}
+
+// computeTopicStatistics analyzes the posts on the archived pages of the topic in the target directory, whose settings
+// have to be loaded, as extracted by the engine of the forum.
+func computeTopicStatistics() (statistics *topicStatistics, err error) {
+	statistics = &topicStatistics{}
+	postCounts := map[string]int{}
+	quoteCounts := map[string]int{}
+	monthCounts := map[string]int{}
+	var characterCount, wordCount int
+
+	deduplicator := newPostDeduplicator()
+	_, err = walkArchivedPages(func(pageNumber uint, filename string, doc *html.Node) {
+		statistics.Pages++
+		posts, originals := deduplicator.filter(getForumEngine().ExtractPosts(doc))
+		statistics.DuplicatePosts += len(originals)
+		for _, post := range posts {
+			statistics.Posts++
+			statistics.Attachments += post.Attachments
+			characterCount += utf8.RuneCountInString(post.Text)
+			wordCount += len(strings.Fields(post.Text))
+
+			if post.Author != "" {
+				postCounts[post.Author]++
+			}
+			for _, author := range post.Quoted {
+				quoteCounts[author]++
+			}
+
+			if !post.Time.IsZero() {
+				postTime := post.Time
+				if statistics.FirstPostTime == nil || postTime.Before(*statistics.FirstPostTime) {
+					statistics.FirstPostTime = &postTime
+				}
+				if statistics.LastPostTime == nil || postTime.After(*statistics.LastPostTime) {
+					statistics.LastPostTime = &postTime
+				}
+				monthCounts[postTime.UTC().Format(topicStatisticsMonthLayout)]++
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statistics.Authors = len(postCounts)
+	if statistics.Posts > 0 {
+		statistics.AveragePostCharacters = float64(characterCount) / float64(statistics.Posts)
+		statistics.AveragePostWords = float64(wordCount) / float64(statistics.Posts)
+	}
+	statistics.PostsPerAuthor = rankAuthors(postCounts)
+	statistics.MostQuoted = rankAuthors(quoteCounts)
+
+	// the months without posts are included, so that the activity can be plotted as it is
+	statistics.PostsPerMonth = []*monthCount{}
+	if statistics.FirstPostTime != nil {
+		firstPostTime := statistics.FirstPostTime.UTC()
+		lastMonth := statistics.LastPostTime.UTC().Format(topicStatisticsMonthLayout)
+		for month := time.Date(firstPostTime.Year(), firstPostTime.Month(), 1, 0, 0, 0, 0, time.UTC); ; month = month.AddDate(0, 1, 0) {
+			monthString := month.Format(topicStatisticsMonthLayout)
+			statistics.PostsPerMonth = append(statistics.PostsPerMonth, &monthCount{monthString, monthCounts[monthString]})
+			if monthString == lastMonth {
+				break
+			}
+		}
+	}
+
+	return
+}
+
+var topicStatisticsTemplate = template.Must(template.New("statistics").Funcs(template.FuncMap{
+	"percent": func(count, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(count) / float64(total)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+{{.DublinCore}}<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.2em 0.6em; text-align: left; }
+td.count { text-align: right; }
+.bar { background: #4a7bb7; height: 0.8em; }
+td.bar-cell { width: 20em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- with .Statistics}}
+<table>
+<tr><th>Pages</th><td class="count">{{.Pages}}</td></tr>
+<tr><th>Posts</th><td class="count">{{.Posts}}</td></tr>
+<tr><th>Authors</th><td class="count">{{.Authors}}</td></tr>
+{{- if .FirstPostTime}}
+<tr><th>First post</th><td>{{.FirstPostTime.Format "2006-01-02 15:04"}}</td></tr>
+<tr><th>Last post</th><td>{{.LastPostTime.Format "2006-01-02 15:04"}}</td></tr>
+{{- end}}
+<tr><th>Average post length</th><td class="count">{{printf "%.0f" .AveragePostWords}} words ({{printf "%.0f" .AveragePostCharacters}} characters)</td></tr>
+<tr><th>Attachments</th><td class="count">{{.Attachments}}</td></tr>
+{{- if .DuplicatePosts}}
+<tr><th>Duplicate posts</th><td class="count">{{.DuplicatePosts}}</td></tr>
+{{- end}}
+</table>
+{{- $posts := .Posts}}
+<h2>Posts per author</h2>
+<table>
+{{- range .PostsPerAuthor}}
+<tr><td>{{.Author}}</td><td class="count">{{.Count}}</td><td class="bar-cell"><div class="bar" style="width: {{percent .Count $posts}}%"></div></td></tr>
+{{- end}}
+</table>
+{{- if .MostQuoted}}
+<h2>Most quoted authors</h2>
+<table>
+{{- range .MostQuoted}}
+<tr><td>{{.Author}}</td><td class="count">{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .PostsPerMonth}}
+<h2>Activity over time</h2>
+<table>
+{{- range .PostsPerMonth}}
+<tr><td>{{.Month}}</td><td class="count">{{.Posts}}</td><td class="bar-cell"><div class="bar" style="width: {{percent .Posts $posts}}%"></div></td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+var isTopicStatisticsHTML bool
+var topicStatisticsOutputPath string
+
+var statsCommand = &command{
+	name:      "stats",
+	synopsis:  "report statistics about the posts in the archive",
+	arguments: "",
+	description: "Report statistics about the posts in the archive in the target directory as extracted by the forum engine " +
+		"(posts per author, activity over time, average post length, most quoted authors and attachment counts) as JSON or HTML",
+	setFlags: func(flags *flag.FlagSet) {
+		isTopicStatisticsHTML = false
+		flags.BoolVar(&isTopicStatisticsHTML, "html", isTopicStatisticsHTML, "write the report as an HTML page with tables and bar charts instead of JSON")
+
+		topicStatisticsOutputPath = "-"
+		flags.StringVar(&topicStatisticsOutputPath, "o", topicStatisticsOutputPath, "`path` of the file to which to write the report; - stands for the standard output")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		err := checkDuplicatePostsMode()
+		if err != nil {
+			return err
+		}
+
+		_, err = loadTopicSettings()
+		if err != nil {
+			return err
+		}
+
+		statistics, err := computeTopicStatistics()
+		if err != nil {
+			return err
+		}
+
+		var output io.Writer = os.Stdout
+		if topicStatisticsOutputPath != "-" {
+			file, err := os.Create(topicStatisticsOutputPath)
+			if err != nil {
+				return errorf("could not create report file %s", topicStatisticsOutputPath)
+			}
+			defer file.Close()
+
+			output = file
+		}
+
+		if isTopicStatisticsHTML {
+			var metadata *exportMetadata
+			metadata, err = collectExportMetadata()
+			if err != nil {
+				return err
+			}
+
+			err = topicStatisticsTemplate.Execute(output, struct {
+				Title      string
+				DublinCore template.HTML
+				Statistics *topicStatistics
+			}{
+				Title:      "Statistics of " + metadata.Title,
+				DublinCore: metadata.dublinCoreHTML(),
+				Statistics: statistics,
+			})
+		} else {
+			encoder := json.NewEncoder(output)
+			encoder.SetIndent("", "\t")
+			err = encoder.Encode(statistics)
+		}
+		if err != nil {
+			return errorf("could not write report: %v", err)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/engine"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// injectedStylesheetPresets are the built-in user stylesheets which can be injected into the archived pages by name
+// instead of a file.
+var injectedStylesheetPresets = map[string]string{
+	// dark inverts the lightness of the page while keeping its hues, and inverts the images and videos back, which
+	// works regardless of how the theme of the forum is put together
+	"dark": `html {
+	background: #fff;
+	filter: invert(90%) hue-rotate(180deg);
+}
+
+img, video, picture, iframe, object, embed, svg, canvas, [style*="background-image"] {
+	filter: invert(100%) hue-rotate(180deg);
+}
+`,
+	// reader drops the backgrounds and colors of the theme for dark text on a sepia background in a legible font
+	"reader": `html, body {
+	background: #f4ecd8 !important;
+}
+
+body {
+	color: #333 !important;
+	font: 17px/1.6 Georgia, "Times New Roman", serif !important;
+}
+
+body * {
+	background-color: transparent !important;
+	background-image: none !important;
+	color: inherit !important;
+	border-color: #d8cbb0 !important;
+}
+
+a, a * {
+	color: #1a4f8b !important;
+}
+
+img, video, iframe {
+	max-width: 100%;
+	height: auto;
+}
+`,
+}
+
+func getInjectedStylesheetPresetNames() (names []string) {
+	for name := range injectedStylesheetPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// injectedStylesheetSource is the name of the preset or the path of the file of the user stylesheet which is injected
+// into the fetched pages, and injectedStylesheet is its content once it has been loaded.
+var injectedStylesheetSource string
+var injectedStylesheet []byte
+
+// loadInjectedStylesheet returns the content of the built-in user stylesheet with the name or else of the file at the
+// path.
+func loadInjectedStylesheet(source string) (content []byte, err error) {
+	if preset, ok := injectedStylesheetPresets[source]; ok {
+		return []byte(preset), nil
+	}
+
+	content, err = os.ReadFile(source)
+	if err != nil {
+		return nil, usageErrorf("could not read stylesheet %s to inject (nor is it one of the presets: %s)", source, strings.Join(getInjectedStylesheetPresetNames(), ", "))
+	}
+	return
+}
+
+// shouldInjectPrintStylesheet makes a print-ready stylesheet for the forum engine be stored in the archive and linked
+// from the pages.
+var shouldInjectPrintStylesheet bool
+
+// getPrintStylesheet returns the print-ready stylesheet for the pages of the forum, if it is to be injected.
+func getPrintStylesheet() []byte {
+	if !shouldInjectPrintStylesheet {
+		return nil
+	}
+	return []byte(engine.PrintStylesheet(getForumEngine()))
+}
+
+// injectStylesheetIntoArchivedPages links the stylesheet of the kind with the name in the archive in the target
+// directory, whose settings have to be loaded, from the end of the head of every archived page which does not link a
+// stylesheet of the kind yet, and returns the number of pages which were changed.
+func injectStylesheetIntoArchivedPages(name string, kind rewrite.StylesheetKind) (injectedPageCount int, err error) {
+	urlMap, err := archive.LoadURLMap(targetDir)
+	if err != nil {
+		return 0, errorf("could not read the map of original URLs (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+	archivedPageNumbers, err := archive.PageNumbers(targetDir)
+	if err != nil {
+		return 0, errorf("could not list the contents of target directory %s", targetDir)
+	}
+
+	stylesheetFilename := filepath.Join(targetDir, name)
+	for _, pageNumber := range fetch.SortPageNumbers(archivedPageNumbers) {
+		entry, ok := urlMap.LookupFilename(getFetcher().PageURL(pageNumber))
+		if !ok {
+			continue
+		}
+
+		filename := filepath.Join(targetDir, filepath.FromSlash(entry.Filename))
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			logErrorf("could not read archived page %s", filename)
+			continue
+		}
+
+		relativePath, err := filepath.Rel(filepath.Dir(filename), stylesheetFilename)
+		if err != nil {
+			logErrorf("could not determine relative path to the injected stylesheet %s from page %d", name, pageNumber)
+			continue
+		}
+		content, ok = rewrite.InjectStylesheet(content, (&url.URL{Path: filepath.ToSlash(relativePath)}).String(), kind)
+		if !ok {
+			logDebugf("page %d already links an injected stylesheet like %s", pageNumber, name)
+			continue
+		}
+
+		file, err := archive.Create(filename)
+		if err != nil {
+			logErrorf("could not write archived page %s", filename)
+			continue
+		}
+		_, err = file.Write(content)
+		file.Close()
+		if err != nil {
+			logErrorf("could not write archived page %s", filename)
+			continue
+		}
+
+		injectedPageCount++
+	}
+
+	return
+}
+
+var injectCSSCommand = &command{
+	name:      "inject-css",
+	synopsis:  "link a user or print-ready stylesheet from the archived pages",
+	arguments: "[file.css|preset]",
+	description: "Store the given user stylesheet, or the built-in one with the given name (one of: " + strings.Join(getInjectedStylesheetPresetNames(), ", ") + "), " +
+		"as " + archive.InjectedStylesheetFilename + " in the archive in the target directory and link it from the end of the head of every archived page, " +
+		"after the stylesheets of the forum, so that e.g. a dark theme can be applied to all of them. " +
+		"The pages are changed only once: afterwards, restyling them only takes replacing the stylesheet, e.g. by running the command again. " +
+		"Without an argument, the stylesheet which is already stored is linked, unless only the print-ready stylesheet is injected with -print. " +
+		"Pages fetched with -inject-css and -print-css link the stylesheets from the start",
+	setFlags: func(flags *flag.FlagSet) {
+		shouldInjectPrintStylesheet = false
+		flags.BoolVar(&shouldInjectPrintStylesheet, "print", shouldInjectPrintStylesheet, "generate a print-ready stylesheet for the forum engine (which hides the navigation, prints every post on a new sheet, expands the quotes and uses a legible font) as "+archive.PrintStylesheetFilename+" and link it from the pages for printing")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 1 {
+			return usageErrorf("unexpected arguments: %v", args[1:])
+		}
+
+		_, err := loadTopicSettings()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			content, err := loadInjectedStylesheet(args[0])
+			if err != nil {
+				return err
+			}
+
+			err = archive.WriteStylesheet(targetDir, archive.InjectedStylesheetFilename, content)
+			if err != nil {
+				return errorf("could not store the injected stylesheet (%s)", filepath.Join(targetDir, archive.InjectedStylesheetFilename))
+			}
+		} else if _, err := os.Stat(filepath.Join(targetDir, archive.InjectedStylesheetFilename)); err != nil && !shouldInjectPrintStylesheet {
+			return usageErrorf("no stylesheet specified and none is stored in the archive yet")
+		}
+
+		if len(args) == 1 || !shouldInjectPrintStylesheet {
+			injectedPageCount, err := injectStylesheetIntoArchivedPages(archive.InjectedStylesheetFilename, rewrite.UserStylesheet)
+			if err != nil {
+				return err
+			}
+			logSuccessf("Linked the injected stylesheet from %d pages.", injectedPageCount)
+		}
+
+		if shouldInjectPrintStylesheet {
+			err = archive.WriteStylesheet(targetDir, archive.PrintStylesheetFilename, getPrintStylesheet())
+			if err != nil {
+				return errorf("could not store the print-ready stylesheet (%s)", filepath.Join(targetDir, archive.PrintStylesheetFilename))
+			}
+
+			injectedPageCount, err := injectStylesheetIntoArchivedPages(archive.PrintStylesheetFilename, rewrite.PrintStylesheet)
+			if err != nil {
+				return err
+			}
+			logSuccessf("Linked the print-ready stylesheet from %d pages.", injectedPageCount)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/rewrite"
+)
+
+// systemdStatusInterval is how often the status of the process is reported to systemd if its watchdog is disabled.
+const systemdStatusInterval = 10 * time.Second
+
+// sdNotify sends the state (e.g. `READY=1`) to systemd if the process has been started by it with a notification
+// socket (see sd_notify(3)), and does nothing otherwise.
+func sdNotify(state string) {
+	socketName := os.Getenv("NOTIFY_SOCKET")
+	if socketName == "" {
+		return
+	}
+	// abstract sockets are given with a leading @ instead of the NUL byte
+	if strings.HasPrefix(socketName, "@") {
+		socketName = "\x00" + socketName[1:]
+	}
+
+	connection, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketName, Net: "unixgram"})
+	if err != nil {
+		logDebugf("could not connect to the notification socket of systemd: %v", err)
+		return
+	}
+	defer connection.Close()
+
+	_, err = connection.Write([]byte(state))
+	if err != nil {
+		logDebugf("could not notify systemd: %v", err)
+	}
+}
+
+// getSystemdWatchdogInterval returns the interval within which systemd expects to be pinged by the process, if its
+// watchdog is enabled for the process.
+func getSystemdWatchdogInterval() (interval time.Duration, ok bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// superviseUnderSystemd reports the status of the process to systemd and pings its watchdog, if the process has been
+// started by it, until ctx is done. The status is the one returned by status, if it is given, while the watchdog is
+// only pinged as long as isHealthy, if it is given, reports that the process is making progress, so that systemd
+// restarts a process which has stalled.
+func superviseUnderSystemd(ctx context.Context, status func() string, isHealthy func() bool) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+
+	watchdogInterval, isWatchdogEnabled := getSystemdWatchdogInterval()
+	interval := systemdStatusInterval
+	if isWatchdogEnabled {
+		interval = watchdogInterval / 2
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var states []string
+			if isWatchdogEnabled && (isHealthy == nil || isHealthy()) {
+				states = append(states, "WATCHDOG=1")
+			}
+			if status != nil {
+				states = append(states, "STATUS="+status())
+			}
+			if len(states) > 0 {
+				sdNotify(strings.Join(states, "\n"))
+			}
+		}
+	}()
+}
+
+// isFetchMakingProgress returns a function which reports whether the fetcher has downloaded anything within the
+// interval of the watchdog of systemd.
+func isFetchMakingProgress(fetcher *fetch.Fetcher) func() bool {
+	watchdogInterval, _ := getSystemdWatchdogInterval()
+	var lastBytesDownloaded int64
+	lastProgressTime := time.Now()
+	return func() bool {
+		bytesDownloaded, _ := fetcher.Report().Usage()
+		// a fetch waiting for its active hours is idle on purpose
+		if bytesDownloaded != lastBytesDownloaded || fetcher.IsPaused() {
+			lastBytesDownloaded = bytesDownloaded
+			lastProgressTime = time.Now()
+		}
+		return time.Since(lastProgressTime) < watchdogInterval
+	}
+}
+
+// systemdServiceWatchdogIntervals are the intervals of the watchdogs of the generated units for the commands, within
+// which the commands ping them; the fetches only do so as long as they are making progress.
+var systemdServiceWatchdogIntervals = map[string]string{
+	"fetch":  "10min",
+	"resume": "10min",
+	"record": "1min",
+	"serve":  "1min",
+}
+
+// systemdHardeningDirectives are the directives of the generated units which restrict what the service can do to
+// what it needs: connecting to the forum and writing to the target directory.
+var systemdHardeningDirectives = []string{
+	"NoNewPrivileges=yes",
+	"CapabilityBoundingSet=",
+	"PrivateTmp=yes",
+	"PrivateDevices=yes",
+	"ProtectSystem=strict",
+	"ProtectHome=read-only",
+	"ProtectKernelTunables=yes",
+	"ProtectKernelModules=yes",
+	"ProtectKernelLogs=yes",
+	"ProtectControlGroups=yes",
+	"ProtectClock=yes",
+	"ProtectHostname=yes",
+	"RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6",
+	"RestrictNamespaces=yes",
+	"RestrictRealtime=yes",
+	"RestrictSUIDSGID=yes",
+	"LockPersonality=yes",
+	"SystemCallArchitectures=native",
+	"SystemCallFilter=@system-service",
+	"SystemCallFilter=~@privileged @resources",
+	"UMask=0022",
+}
+
+// quoteSystemdArgument returns the argument of a command line of a unit quoted as needed, with the specifiers and
+// variables escaped, so that systemd passes it to the command as it is.
+func quoteSystemdArgument(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+		return arg
+	}
+	return strconv.Quote(arg)
+}
+
+var systemdUnitOnCalendar string
+var systemdUnitEnvironmentFile string
+var systemdUnitOutputDir string
+
+// generateSystemdUnits returns the name of the service (without the suffix) which runs the command line in the target
+// directory and the units of the service and, if it is run on a schedule, of its timer.
+func generateSystemdUnits(commandArgs []string) (name, service, timer string, err error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", "", "", errorf("could not determine the path of the executable: %v", err)
+	}
+	absTargetDir, err := filepath.Abs(targetDir)
+	if err != nil {
+		return
+	}
+
+	name = "fetch-forum-topic"
+	if slug := rewrite.Slug(filepath.Base(absTargetDir)); slug != "" {
+		name += "-" + slug
+	}
+
+	execStartArgs := []string{executable, "-t", absTargetDir}
+	if isFlagSet(flag.CommandLine, "config") {
+		absConfigFilename, err := filepath.Abs(configFilename)
+		if err != nil {
+			return "", "", "", err
+		}
+		execStartArgs = append(execStartArgs, "-config", absConfigFilename)
+	}
+	if isFlagSet(flag.CommandLine, "log-level") {
+		execStartArgs = append(execStartArgs, "-log-level", currentLogLevel.String())
+	}
+	execStartArgs = append(execStartArgs, commandArgs...)
+	for i, arg := range execStartArgs {
+		execStartArgs[i] = quoteSystemdArgument(arg)
+	}
+
+	description := "Archive of the forum topic in " + absTargetDir
+	if settings, err := loadTopicSettings(); err == nil && settings.URL != "" {
+		description = "Archive of the forum topic " + settings.URL
+	}
+
+	var unit strings.Builder
+	fmt.Fprintf(&unit, "[Unit]\nDescription=%s\nWants=network-online.target\nAfter=network-online.target\n\n", strings.NewReplacer("%", "%%").Replace(description))
+	unit.WriteString("[Service]\nType=notify\nNotifyAccess=main\n")
+	fmt.Fprintf(&unit, "ExecStart=%s\n", strings.Join(execStartArgs, " "))
+	fmt.Fprintf(&unit, "WorkingDirectory=%s\n", quoteSystemdArgument(absTargetDir))
+	if currentUser, err := user.Current(); err == nil && currentUser.Uid != "0" {
+		fmt.Fprintf(&unit, "User=%s\n", currentUser.Username)
+	}
+	if systemdUnitEnvironmentFile != "" {
+		absEnvironmentFilename, err := filepath.Abs(systemdUnitEnvironmentFile)
+		if err != nil {
+			return "", "", "", err
+		}
+		fmt.Fprintf(&unit, "EnvironmentFile=%s\n", quoteSystemdArgument(absEnvironmentFilename))
+	}
+	fmt.Fprintf(&unit, "WatchdogSec=%s\n", systemdServiceWatchdogIntervals[commandArgs[0]])
+	switch commandArgs[0] {
+	case "fetch", "resume":
+		// the fetch reports readiness once the pages have been planned, which may take the detection of the last page
+		unit.WriteString("TimeoutStartSec=10min\n")
+		// the pages which failed are fetched again by the next run
+		fmt.Fprintf(&unit, "SuccessExitStatus=%d\n", exitCodePartialFailure)
+	default:
+		unit.WriteString("Restart=on-failure\nRestartSec=30s\n")
+	}
+	fmt.Fprintf(&unit, "ReadWritePaths=%s\n", quoteSystemdArgument(absTargetDir))
+	unit.WriteString(strings.Join(systemdHardeningDirectives, "\n") + "\n")
+	if systemdUnitOnCalendar == "" {
+		unit.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	}
+	service = unit.String()
+
+	if systemdUnitOnCalendar != "" {
+		timer = fmt.Sprintf("[Unit]\nDescription=Schedule of the %s\n\n[Timer]\nOnCalendar=%s\nPersistent=yes\nRandomizedDelaySec=5min\n\n[Install]\nWantedBy=timers.target\n",
+			strings.NewReplacer("%", "%%").Replace(strings.ToLower(description[:1])+description[1:]), systemdUnitOnCalendar)
+	}
+	return
+}
+
+var systemdUnitCommand = &command{
+	name:      "systemd-unit",
+	synopsis:  "generate a hardened systemd service unit running a command",
+	arguments: "[command [flags] [arguments]]",
+	description: "Print a systemd service unit which runs the given command line (resume by default) of this program in the target directory, " +
+		"along with the global flags -config and -log-level if they have been set, for unattended archiving. " +
+		"The service is sandboxed so that it can only connect to the network and write to the target directory, " +
+		"and it notifies systemd when it is ready, reports its progress as its status and pings the watchdog of systemd, " +
+		"which restarts the long-running commands (record and serve) if they stop responding and stops the fetches which stop making progress. " +
+		"With -on-calendar, a timer unit which runs the service on the given schedule is generated as well",
+	setFlags: func(flags *flag.FlagSet) {
+		systemdUnitOnCalendar = ""
+		flags.StringVar(&systemdUnitOnCalendar, "on-calendar", systemdUnitOnCalendar, "also generate a timer unit which runs the service on the `schedule` given as a calendar event of systemd (e.g. daily or Mon *-*-* 03:00), instead of starting it at boot")
+
+		systemdUnitEnvironmentFile = ""
+		flags.StringVar(&systemdUnitEnvironmentFile, "environment-file", systemdUnitEnvironmentFile, "`file` with the environment variables referenced by the credentials (e.g. -credentials env:NAME) which is read by the service")
+
+		systemdUnitOutputDir = ""
+		flags.StringVar(&systemdUnitOutputDir, "o", systemdUnitOutputDir, "write the units to files named after the target directory in `directory` (e.g. /etc/systemd/system) instead of printing them")
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) == 0 {
+			args = []string{"resume"}
+		}
+		if _, ok := systemdServiceWatchdogIntervals[args[0]]; !ok {
+			return usageErrorf("cannot generate a service running command %s (it has to be one of: fetch, resume, record, serve)", args[0])
+		}
+
+		name, service, timer, err := generateSystemdUnits(args)
+		if err != nil {
+			return err
+		}
+
+		if systemdUnitOutputDir == "" {
+			fmt.Printf("# %s.service\n%s", name, service)
+			if timer != "" {
+				fmt.Printf("\n# %s.timer\n%s", name, timer)
+			}
+			return nil
+		}
+
+		units := map[string]string{name + ".service": service}
+		if timer != "" {
+			units[name+".timer"] = timer
+		}
+		for filename, content := range units {
+			filename = filepath.Join(systemdUnitOutputDir, filename)
+			err = os.WriteFile(filename, []byte(content), 0644)
+			if err != nil {
+				return errorf("could not write unit %s: %v", filename, err)
+			}
+			logSuccessf("Wrote unit %s.", filename)
+		}
+		if timer != "" {
+			logInfof("Enable it with: systemctl daemon-reload && systemctl enable --now %s.timer", name)
+		} else {
+			logInfof("Enable it with: systemctl daemon-reload && systemctl enable --now %s.service", name)
+		}
+		return nil
+	},
+}
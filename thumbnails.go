@@ -0,0 +1,21 @@
+package main
+
+import (
+	"bytes"
+	"image"
+)
+
+// pageThumbnailSize is the largest width and height of the thumbnails which replace the larger images embedded in the
+// pages (0 means that the images are shown as they are).
+var pageThumbnailSize int
+
+// makePageThumbnail returns the thumbnail which replaces the image in the pages, or false if the image fits
+// pageThumbnailSize or cannot be decoded.
+func makePageThumbnail(content []byte) (thumbnail []byte, ok bool) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || max(config.Width, config.Height) <= pageThumbnailSize {
+		return nil, false
+	}
+
+	return makeThumbnail(content, pageThumbnailSize)
+}
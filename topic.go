@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// loadTopicSettings reads the parameters of the last fetch in the target directory and makes them current.
+func loadTopicSettings() (settings *archive.TopicSettings, err error) {
+	settings, err = archive.LoadTopicSettings(targetDir)
+	if err != nil {
+		return
+	}
+
+	forumTopicPageURLBase = settings.URL
+	forumTopicPageURLs = settings.PageURLs
+	forumTopicPostStep = settings.PostStep
+	forumEngine = settings.Engine
+	if forumEngine == "" {
+		forumEngine = "generic"
+	}
+	err = validateForumEngine()
+	return
+}
+
+// pageURLListFromStdin is passed instead of the base URL in order to read the URLs of the pages from the standard input.
+const pageURLListFromStdin = "-"
+
+// readForumTopicPageURLs reads the URLs of the pages of the topic, one per line; blank lines and lines starting with `#` are ignored.
+func readForumTopicPageURLs(reader io.Reader) (err error) {
+	forumTopicPageURLs = []string{}
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pageURL, err := url.Parse(line)
+		if err != nil || !pageURL.IsAbs() {
+			return usageErrorf("line %d of the standard input is not an absolute URL: %s", lineNumber, line)
+		}
+
+		forumTopicPageURLs = append(forumTopicPageURLs, fetch.ASCIIURL(line))
+	}
+	if err = scanner.Err(); err != nil {
+		return errorf("could not read page URLs from the standard input: %v", err)
+	}
+
+	if len(forumTopicPageURLs) == 0 {
+		return usageErrorf("no page URLs given on the standard input")
+	}
+	return
+}
+
+// sitemapSourceAuto is passed to -sitemap in order to discover the pages of the topic in the sitemaps declared by the
+// robots.txt of the forum or else the one at the root of its host.
+const sitemapSourceAuto = "auto"
+
+// sitemapSource is either sitemapSourceAuto or the comma-separated URLs of the sitemaps in which the pages of the topic
+// are discovered, or empty if the URLs of the pages are derived from the base URL.
+var sitemapSource string
+
+// discoverForumTopicPageURLs replaces the URLs of the pages derived from the base URL of the topic with the ones
+// discovered in the sitemaps of the forum.
+func discoverForumTopicPageURLs() error {
+	if forumTopicPageURLs != nil {
+		return usageErrorf("the pages of the topic cannot be both listed and discovered in sitemaps")
+	}
+
+	var sitemapURLs []string
+	if sitemapSource != sitemapSourceAuto {
+		for _, sitemapURL := range strings.Split(sitemapSource, ",") {
+			if sitemapURL = strings.TrimSpace(sitemapURL); sitemapURL != "" {
+				sitemapURLs = append(sitemapURLs, fetch.ASCIIURL(sitemapURL))
+			}
+		}
+	}
+
+	logInfof("Discovering the pages of the topic in the sitemaps of the forum...")
+	pageURLs, err := getFetcher().DiscoverPageURLs(interruptContext, sitemapURLs)
+	if err != nil {
+		return errorf("could not discover the pages of the topic: %v", err)
+	}
+
+	forumTopicPageURLs = pageURLs
+	forumTopicFetcher = nil
+	logInfof("Discovered %d pages.", len(pageURLs))
+	return nil
+}
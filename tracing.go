@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// tracingServiceName is the name under which the spans of the fetch are exported.
+const tracingServiceName = "fetch-forum-topic"
+
+// tracingShutdownTimeout is how long the export of the remaining spans may take at the end of the run.
+const tracingShutdownTimeout = 10 * time.Second
+
+// otlpEndpointURL is the URL of the OTLP/HTTP endpoint to which the traces of the fetch are exported.
+var otlpEndpointURL string
+
+// startTracing sets up the export of traces if an OTLP endpoint has been given and returns the fetcher option which
+// enables recording them, along with the function which flushes the remaining spans; both are nil otherwise.
+func startTracing() (option fetch.Option, shutdown func(), err error) {
+	if otlpEndpointURL == "" {
+		return
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(otlpEndpointURL))
+	if err != nil {
+		return
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", tracingServiceName),
+			attribute.String("fetch.target_dir", targetDir),
+		)),
+	)
+
+	shutdown = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+
+		err := tracerProvider.Shutdown(ctx)
+		if err != nil {
+			logErrorf("could not export the remaining traces to %s: %v", otlpEndpointURL, err)
+		}
+	}
+	return fetch.WithTracerProvider(tracerProvider), shutdown, nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"golang.org/x/term"
+)
+
+const (
+	pageStateRunning = "running"
+	pageStateDone    = "done"
+	pageStateFailed  = "failed"
+)
+
+const (
+	tuiRefreshInterval = 250 * time.Millisecond
+	tuiLogLineCount    = 6
+)
+
+var isTUIMode bool
+
+type pageProgress struct {
+	pageNumber uint
+	attempt    int
+	state      string
+	resources  int
+	startTime  time.Time
+	endTime    time.Time
+	err        error
+}
+
+// fetchProgress tracks the state of every page of the run for the terminal UI.
+type fetchProgress struct {
+	fetch.NopObserver
+
+	mutex sync.Mutex
+	pages map[uint]*pageProgress
+}
+
+func (progress *fetchProgress) OnPageStart(event *fetch.PageEvent) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	progress.pages[event.Number] = &pageProgress{
+		pageNumber: event.Number,
+		attempt:    event.Attempt,
+		state:      pageStateRunning,
+		startTime:  time.Now(),
+	}
+}
+
+func (progress *fetchProgress) OnResourceFetched(event *fetch.ResourceEvent) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	if page, ok := progress.pages[event.PageNumber]; ok {
+		page.resources++
+	}
+}
+
+func (progress *fetchProgress) OnPageDone(event *fetch.PageEvent) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	page, ok := progress.pages[event.Number]
+	if !ok {
+		return
+	}
+
+	page.endTime = time.Now()
+	page.err = event.Err
+	if event.Err != nil {
+		page.state = pageStateFailed
+	} else {
+		page.state = pageStateDone
+	}
+}
+
+// logRingBuffer keeps the last few lines written to it, so that log messages can be shown inside the terminal UI.
+type logRingBuffer struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (buffer *logRingBuffer) Write(p []byte) (n int, err error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		buffer.lines = append(buffer.lines, line)
+	}
+	if len(buffer.lines) > tuiLogLineCount {
+		buffer.lines = buffer.lines[len(buffer.lines)-tuiLogLineCount:]
+	}
+	return len(p), nil
+}
+
+func (buffer *logRingBuffer) getLines() []string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return append([]string(nil), buffer.lines...)
+}
+
+type terminalUI struct {
+	url                string
+	report             *fetch.Report
+	scheduledPageCount int
+	startTime          time.Time
+	selectedFailure    int
+	isQuitRequested    bool
+	logBuffer          *logRingBuffer
+	progress           *fetchProgress
+	width              int
+}
+
+func startTUI() (ui *terminalUI, err error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, errorf("the terminal UI requires both the standard input and the standard output to be a terminal")
+	}
+
+	ui = &terminalUI{
+		startTime: time.Now(),
+		logBuffer: &logRingBuffer{},
+	}
+	ui.progress = &fetchProgress{pages: map[uint]*pageProgress{}}
+
+	logOutput = ui.logBuffer
+	err = setUpLogger()
+	return
+}
+
+// getSortedPages returns the pages which are being fetched and the ones which have failed, each sorted by page number.
+func (progress *fetchProgress) getSortedPages() (runningPages, failedPages []pageProgress, donePageCount int) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	for _, page := range progress.pages {
+		switch page.state {
+		case pageStateRunning:
+			runningPages = append(runningPages, *page)
+		case pageStateFailed:
+			failedPages = append(failedPages, *page)
+		case pageStateDone:
+			donePageCount++
+		}
+	}
+
+	sort.Slice(runningPages, func(i, j int) bool { return runningPages[i].pageNumber < runningPages[j].pageNumber })
+	sort.Slice(failedPages, func(i, j int) bool { return failedPages[i].pageNumber < failedPages[j].pageNumber })
+	return
+}
+
+// truncate cuts the line to the width of the terminal, counting the runes rather than the bytes so that no rune is
+// split.
+func (ui *terminalUI) truncate(line string) string {
+	if ui.width > 0 && utf8.RuneCountInString(line) > ui.width {
+		return string([]rune(line)[:ui.width])
+	}
+
+	return line
+}
+
+func (ui *terminalUI) draw(runningPages, failedPages []pageProgress, donePageCount int) {
+	var screen bytes.Buffer
+	line := func(format string, args ...interface{}) {
+		screen.WriteString(ui.truncate(translatef(format, args...)))
+		screen.WriteString("\x1b[K\r\n")
+	}
+
+	elapsed := time.Since(ui.startTime)
+	bytesDownloaded, resourcesFetched := ui.report.Usage()
+
+	screen.WriteString("\x1b[H")
+	line("%s", ui.url)
+	line("elapsed %s | pages: %d/%d done, %d running, %d failed | resources: %d | %s (%s/s)",
+		elapsed.Round(time.Second), donePageCount, ui.scheduledPageCount, len(runningPages), len(failedPages),
+		resourcesFetched, formatByteCount(bytesDownloaded), formatByteCount(int64(float64(bytesDownloaded)/elapsed.Seconds())))
+	line("")
+	// the columns are as wide as their translated headings, if these are wider than the values
+	headings := []string{translate("PAGE"), translate("ATTEMPT"), translate("RESOURCES"), translate("ELAPSED")}
+	widths := []int{6, 7, 9, 8}
+	for i, heading := range headings {
+		widths[i] = max(widths[i], utf8.RuneCountInString(heading))
+	}
+	line("%*s  %*s  %*s  %*s", widths[0], headings[0], widths[1], headings[1], widths[2], headings[2], widths[3], headings[3])
+	for _, page := range runningPages {
+		line("%*d  %*d  %*d  %*s", widths[0], page.pageNumber, widths[1], page.attempt, widths[2], page.resources,
+			widths[3], time.Since(page.startTime).Round(100*time.Millisecond))
+	}
+	line("")
+
+	if ui.selectedFailure >= len(failedPages) {
+		ui.selectedFailure = len(failedPages) - 1
+	}
+	if ui.selectedFailure < 0 {
+		ui.selectedFailure = 0
+	}
+	line("Failures (j/k: select, r: requeue selected, R: requeue all, q: quit):")
+	for index, page := range failedPages {
+		cursor := " "
+		if index == ui.selectedFailure {
+			cursor = ">"
+		}
+		line("%s %6d  attempt %d: %v", cursor, page.pageNumber, page.attempt, page.err)
+	}
+	line("")
+
+	if ui.isQuitRequested && len(runningPages) > 0 {
+		line("Waiting for %d pages to finish...", len(runningPages))
+	}
+	line("Log:")
+	for _, logLine := range ui.logBuffer.getLines() {
+		line("  %s", logLine)
+	}
+	screen.WriteString("\x1b[J")
+
+	os.Stdout.Write(screen.Bytes())
+}
+
+// stop makes the messages be logged to the standard error again and outputs the ones logged while the UI was active.
+func (ui *terminalUI) stop() {
+	logOutput = os.Stderr
+	setUpLogger()
+	for _, logLine := range ui.logBuffer.getLines() {
+		fmt.Fprintln(os.Stderr, logLine)
+	}
+}
+
+// run shows the progress of the fetch and handles the keys pressed by the user until all pages have finished
+// and the user has requested to quit.
+func (ui *terminalUI) run(fetcher *fetch.Fetcher, scheduledPageCount int) (err error) {
+	ui.url = fetcher.PageURL(fetch.MinPageNumber)
+	ui.report = fetcher.Report()
+	ui.scheduledPageCount = scheduledPageCount
+
+	stdinFd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return errorf("could not put the terminal into raw mode: %v", err)
+	}
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+		term.Restore(stdinFd, oldState)
+		ui.stop()
+	}()
+
+	keys := make(chan byte)
+	go func() {
+		key := make([]byte, 1)
+		for {
+			n, err := os.Stdin.Read(key)
+			if err != nil {
+				close(keys)
+				return
+			}
+			if n == 1 {
+				keys <- key[0]
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		ui.width, _, _ = term.GetSize(int(os.Stdout.Fd()))
+		runningPages, failedPages, donePageCount := ui.progress.getSortedPages()
+		ui.draw(runningPages, failedPages, donePageCount)
+		if ui.isQuitRequested && len(runningPages) == 0 {
+			return
+		}
+
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				ui.isQuitRequested = true
+				continue
+			}
+
+			switch key {
+			case 'q', 3: // Ctrl+C is not turned into a signal in raw mode
+				ui.isQuitRequested = true
+			case 'j', 'B':
+				ui.selectedFailure++
+			case 'k', 'A':
+				ui.selectedFailure--
+			case 'r':
+				if ui.selectedFailure < len(failedPages) {
+					page := failedPages[ui.selectedFailure]
+					fetcher.Requeue(page.pageNumber, page.attempt+1)
+				}
+			case 'R':
+				for _, page := range failedPages {
+					fetcher.Requeue(page.pageNumber, page.attempt+1)
+				}
+			}
+
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+func checkRegularFileIsNotEmpty(filename string) error {
+	stat, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return errorf("%s is missing", filename)
+	}
+	if err != nil {
+		return errorf("could not stat %s", filename)
+	}
+	if !stat.Mode().IsRegular() {
+		return errorf("%s is not a regular file", filename)
+	}
+	if stat.Size() == 0 {
+		return errorf("%s is empty", filename)
+	}
+
+	return nil
+}
+
+// verifyArchive checks every page which is either in the ranges of the last fetch or present in the target directory
+// and returns the problems found with each of them.
+func verifyArchive() (pageProblems map[uint][]string, err error) {
+	settings, err := loadTopicSettings()
+	if err != nil {
+		return
+	}
+
+	urlMap, err := archive.LoadURLMap(targetDir)
+	if err != nil {
+		return nil, errorf("could not read the map of original URLs (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+
+	pageNumbers, err := getFetcher().ParsePageRanges(interruptContext, settings.PageRanges)
+	if err != nil {
+		return
+	}
+	archivedPageNumbers, err := archive.PageNumbers(targetDir)
+	if err != nil {
+		return nil, errorf("could not list the contents of target directory %s", targetDir)
+	}
+	for pageNumber := range archivedPageNumbers {
+		pageNumbers[pageNumber] = struct{}{}
+	}
+
+	state, err := archive.OpenState(targetDir)
+	if err != nil {
+		return nil, errorf("could not open the state database (%s)", filepath.Join(targetDir, archive.StateFilename))
+	}
+	failureList, err := state.FailedPages()
+	state.Close()
+	if err != nil {
+		return nil, errorf("could not read the failed downloads of the last run: %v", err)
+	}
+	failedPageNumbers := map[uint]struct{}{}
+	for _, failedPageNumber := range failureList {
+		failedPageNumbers[failedPageNumber] = struct{}{}
+	}
+
+	pageProblems = map[uint][]string{}
+	for pageNumber := range pageNumbers {
+		var problems []string
+
+		if _, ok := failedPageNumbers[pageNumber]; ok {
+			problems = append(problems, translate("download failed during the last run"))
+		}
+
+		pageDir := fmt.Sprint(pageNumber)
+		if _, ok := archivedPageNumbers[pageNumber]; !ok {
+			problems = append(problems, translate("page has not been fetched"))
+			pageProblems[pageNumber] = problems
+			continue
+		}
+
+		if _, ok := urlMap.LookupFilename(getFetcher().PageURL(pageNumber)); !ok {
+			problems = append(problems, translate("page is not recorded in the map of original URLs"))
+		}
+
+		for _, entry := range urlMap.EntriesUnder(pageDir) {
+			err := checkRegularFileIsNotEmpty(filepath.Join(targetDir, filepath.FromSlash(entry.Filename)))
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+
+		if len(problems) > 0 {
+			pageProblems[pageNumber] = problems
+		}
+	}
+
+	return pageProblems, nil
+}
+
+var verifyCommand = &command{
+	name:        "verify",
+	synopsis:    "check the integrity of the archive",
+	arguments:   "",
+	description: "Check that every page of the archive in the target directory and every resource it references have been stored successfully",
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		pageProblems, err := verifyArchive()
+		if err != nil {
+			return err
+		}
+
+		for _, pageNumber := range fetch.SortPageNumbers(toPageNumberSet(pageProblems)) {
+			for _, problem := range pageProblems[pageNumber] {
+				fmt.Println(translatef("page %d: %s", pageNumber, problem))
+			}
+		}
+
+		if len(pageProblems) > 0 {
+			return errorf("%d pages are broken; run the `repair` command to fetch them again", len(pageProblems))
+		}
+		logSuccessf("All pages are OK.")
+		return nil
+	},
+}
+
+var repairCommand = &command{
+	name:        "repair",
+	synopsis:    "fetch again the broken pages of the archive",
+	arguments:   "",
+	description: "Fetch again every page of the archive in the target directory for which the `verify` command reports problems",
+	setFlags: func(flags *flag.FlagSet) {
+		setPageFetchingFlags(flags)
+	},
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		pageProblems, err := verifyArchive()
+		if err != nil {
+			return err
+		}
+		if len(pageProblems) == 0 {
+			logSuccessf("All pages are OK; nothing to repair.")
+			return nil
+		}
+
+		return fetchForumTopicPages(toPageNumberSet(pageProblems), true, 0)
+	},
+}
+
+func toPageNumberSet(pageProblems map[uint][]string) (pageNumbers map[uint]struct{}) {
+	pageNumbers = map[uint]struct{}{}
+	for pageNumber := range pageProblems {
+		pageNumbers[pageNumber] = struct{}{}
+	}
+	return
+}
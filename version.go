@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// version is the version of the program, which is set when building a release with
+// `-ldflags "-X main.version=vX.Y.Z"`; otherwise, the version of the module is used, if it has been installed with
+// `go install`.
+var version = ""
+
+// unknownVersion is the version of the builds which are not releases.
+const unknownVersion = "(devel)"
+
+// buildInfo describes the build of the program.
+type buildInfo struct {
+	Version   string
+	Commit    string
+	Time      string
+	Modified  bool
+	GoVersion string
+	Platform  string
+}
+
+// getBuildInfo returns the description of the build of the program, made from its version and the build information
+// embedded by the Go toolchain.
+func getBuildInfo() *buildInfo {
+	build := &buildInfo{
+		Version:   version,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if ok {
+		if build.Version == "" {
+			build.Version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				build.Commit = setting.Value
+			case "vcs.time":
+				build.Time = setting.Value
+			case "vcs.modified":
+				build.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if build.Version == "" {
+		build.Version = unknownVersion
+	}
+	return build
+}
+
+var versionCommand = &command{
+	name:        "version",
+	synopsis:    "print the version of the program",
+	arguments:   "",
+	description: "Print the version of the program along with the commit it has been built from, the time of the commit, and the version of Go and the platform it has been built with",
+	run: func(flags *flag.FlagSet, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments: %v", args)
+		}
+
+		build := getBuildInfo()
+		fmt.Printf("fetch-forum-topic %s\n", build.Version)
+		if build.Commit != "" {
+			commit := build.Commit
+			if build.Modified {
+				commit += " (modified)"
+			}
+			fmt.Printf("commit: %s\n", commit)
+		}
+		if build.Time != "" {
+			fmt.Printf("commit time: %s\n", build.Time)
+		}
+		fmt.Printf("go: %s %s\n", build.GoVersion, build.Platform)
+		return nil
+	},
+}
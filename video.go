@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+)
+
+// shouldDownloadVideos enables the download of the videos embedded in the pages with videoDownloadCommand, which is
+// the command line of yt-dlp or a compatible program.
+var shouldDownloadVideos bool
+var videoDownloadCommand string
+
+// defaultVideoDownloadCommand prefers the formats which browsers play and which do not need to be merged by ffmpeg.
+const defaultVideoDownloadCommand = `yt-dlp -f "b[ext=mp4]/b[ext=webm]/b"`
+
+// videoOutputTemplate is the yt-dlp output template of the downloaded video within the temporary directory.
+const videoOutputTemplate = "video.%(ext)s"
+
+// newVideoDownloader returns a video downloader which runs the yt-dlp command line, to whose arguments the options
+// which make it download the single video at the URL quietly into the directory are appended.
+func newVideoDownloader(commandLine string) (download fetch.VideoDownloader, err error) {
+	commandArgs, err := splitCommandLine(commandLine)
+	if err != nil {
+		return nil, usageErrorf("%v", err)
+	}
+	if len(commandArgs) == 0 {
+		return nil, usageErrorf("empty video download command")
+	}
+
+	return func(ctx context.Context, videoURL, dir string) (filename string, err error) {
+		args := append(commandArgs[1:len(commandArgs):len(commandArgs)],
+			"--no-playlist", "--quiet", "--no-progress", "--no-part",
+			"-o", filepath.Join(dir, videoOutputTemplate),
+		)
+		if shouldUseTor {
+			// with socks5h, the proxy resolves the name of the host
+			args = append(args, "--proxy", "socks5h://"+torProxyAddress)
+		}
+		args = append(args, "--", videoURL)
+
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, commandArgs[0], args...)
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		err = cmd.Run()
+		if err != nil {
+			if message := strings.TrimSpace(output.String()); message != "" {
+				return "", errorf("%v: %s", err, message)
+			}
+			return "", err
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), "video.") {
+				filename = filepath.Join(dir, entry.Name())
+				logDebugf("Downloaded %s into %s", videoURL, filename)
+				return
+			}
+		}
+
+		return "", errorf("%s did not download any file", commandArgs[0])
+	}, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/archive"
+	"github.com/rgeorgiev583/fetch-forum-topic-ng/pkg/fetch"
+	"golang.org/x/time/rate"
+)
+
+// waybackSaveURL is the endpoint of the Save Page Now API of the Wayback Machine, to which the URL to save is appended.
+const waybackSaveURL = "https://web.archive.org/save/"
+
+// waybackURL is the base URL of the snapshots in the Wayback Machine.
+const waybackURL = "https://web.archive.org"
+
+var shouldSubmitToWayback bool
+var waybackSubmissionInterval time.Duration
+
+var waybackClient = &http.Client{Timeout: 2 * time.Minute}
+
+// waybackSubmitter submits the URL of every successfully fetched page to the Wayback Machine in the background, one at
+// a time and no more often than the submission interval, and records the URLs of the resulting snapshots in the
+// archive.
+type waybackSubmitter struct {
+	fetch.NopObserver
+
+	mutex        sync.Mutex
+	pending      []string
+	isClosed     bool
+	wake         chan struct{}
+	done         chan struct{}
+	limiter      *rate.Limiter
+	submittedNum int
+}
+
+func startWaybackSubmitter() *waybackSubmitter {
+	submitter := &waybackSubmitter{
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		limiter: rate.NewLimiter(rate.Every(waybackSubmissionInterval), 1),
+	}
+	go submitter.run()
+	return submitter
+}
+
+func (s *waybackSubmitter) OnPageDone(event *fetch.PageEvent) {
+	if event.Err != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	s.pending = append(s.pending, event.URL)
+	s.mutex.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next URL to submit, waiting for one to be queued, or false once there are no more.
+func (s *waybackSubmitter) next() (pageURL string, ok bool) {
+	for {
+		s.mutex.Lock()
+		if len(s.pending) > 0 {
+			pageURL = s.pending[0]
+			s.pending = s.pending[1:]
+			s.mutex.Unlock()
+			return pageURL, true
+		}
+		isClosed := s.isClosed
+		s.mutex.Unlock()
+
+		if isClosed {
+			return
+		}
+
+		select {
+		case <-s.wake:
+		case <-interruptContext.Done():
+			return
+		}
+	}
+}
+
+func (s *waybackSubmitter) run() {
+	defer close(s.done)
+
+	for {
+		pageURL, ok := s.next()
+		if !ok {
+			return
+		}
+
+		err := s.limiter.Wait(interruptContext)
+		if err != nil {
+			return
+		}
+
+		snapshotURL, err := submitToWayback(interruptContext, pageURL)
+		if err != nil {
+			logWarningf("could not save %s in the Wayback Machine: %v", pageURL, err)
+			continue
+		}
+
+		s.submittedNum++
+		logDebugf("Saved %s in the Wayback Machine as %s", pageURL, snapshotURL)
+		err = archive.RecordWaybackSnapshot(targetDir, pageURL, snapshotURL)
+		if err != nil {
+			logErrorf("could not record the Wayback Machine snapshot of %s: %v", pageURL, err)
+		}
+	}
+}
+
+// wait waits for the queued URLs to be submitted, unless the run is interrupted.
+func (s *waybackSubmitter) wait() {
+	s.mutex.Lock()
+	s.isClosed = true
+	pendingNum := len(s.pending)
+	s.mutex.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	if pendingNum > 0 {
+		logInfof("Waiting for %d pages to be saved in the Wayback Machine...", pendingNum)
+	}
+	<-s.done
+	logInfof("Saved %d pages in the Wayback Machine.", s.submittedNum)
+}
+
+// submitToWayback asks the Wayback Machine to save the page at the URL and returns the URL of the snapshot.
+func submitToWayback(ctx context.Context, pageURL string) (snapshotURL string, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, waybackSaveURL+pageURL, nil)
+	if err != nil {
+		return
+	}
+
+	response, err := waybackClient.Do(request)
+	if err != nil {
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", errorf("Save Page Now responded with status %s", response.Status)
+	}
+
+	if location := response.Header.Get("Content-Location"); location != "" {
+		return waybackURL + location, nil
+	}
+	if strings.HasPrefix(response.Request.URL.Path, "/web/") {
+		return response.Request.URL.String(), nil
+	}
+
+	return "", errorf("Save Page Now did not report the URL of the snapshot")
+}